@@ -0,0 +1,129 @@
+package psnr
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// Aggregator collects PSNR results and summarizes them with consistent
+// rules. Identical images (+Inf) are counted separately: they are excluded
+// from the arithmetic mean, count as MSE 0 in MSEPSNR and sort above every
+// finite value for the median and percentiles. An Aggregator is safe for
+// concurrent use; the zero value is ready to use.
+type Aggregator struct {
+	mu     sync.Mutex
+	values []float64
+	mses   []float64
+	sorted bool
+}
+
+// Summary describes the results added to an Aggregator.
+type Summary struct {
+	// Count is the number of results, including identical ones.
+	Count int
+	// InfCount is the number of +Inf results.
+	InfCount int
+	// Mean is the arithmetic mean of the finite PSNR values, or +Inf when
+	// every result is +Inf.
+	Mean float64
+	// MSEPSNR is the PSNR of the mean MSE over all results, as ffmpeg reports
+	// its average. Unlike Mean it is dominated by the worst results, and it
+	// stays finite unless every result is +Inf.
+	MSEPSNR float64
+	// Min, Max and Median are taken over all PSNR values.
+	Min, Max, Median float64
+}
+
+// Add records a detailed result.
+func (a *Aggregator) Add(r *Result) {
+	a.add(r.PSNR, r.MSE)
+}
+
+// AddPSNR records a PSNR value measured against a peak of 255, deriving its
+// MSE.
+func (a *Aggregator) AddPSNR(psnr float64) {
+	mse := 0.0
+	if !math.IsInf(psnr, 1) {
+		mse = 65025 / math.Pow(10, psnr/10)
+	}
+	a.add(psnr, mse)
+}
+
+func (a *Aggregator) add(psnr, mse float64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.values = append(a.values, psnr)
+	a.mses = append(a.mses, mse)
+	a.sorted = false
+}
+
+// sortValues sorts the values; the MSEs keep insertion order as only their
+// sum is used. The caller holds a.mu.
+func (a *Aggregator) sortValues() {
+	if !a.sorted {
+		sort.Float64s(a.values)
+		a.sorted = true
+	}
+}
+
+// Summary returns the statistics of the results added so far. All fields
+// except Count are zero when nothing was added.
+func (a *Aggregator) Summary() Summary {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s := Summary{Count: len(a.values)}
+	if s.Count == 0 {
+		return s
+	}
+	a.sortValues()
+
+	var sum, mseSum float64
+	for _, v := range a.values {
+		if math.IsInf(v, 1) {
+			s.InfCount++
+		} else {
+			sum += v
+		}
+	}
+	for _, mse := range a.mses {
+		mseSum += mse
+	}
+
+	if finite := s.Count - s.InfCount; finite > 0 {
+		s.Mean = sum / float64(finite)
+	} else {
+		s.Mean = math.Inf(1)
+	}
+	s.MSEPSNR = psnrFromMSE(mseSum / float64(s.Count))
+	s.Min = a.values[0]
+	s.Max = a.values[s.Count-1]
+	s.Median = percentile(a.values, 50)
+	return s
+}
+
+// Percentile returns the p-th percentile (0-100) of the PSNR values,
+// interpolating linearly between the closest ranks. It returns NaN when
+// nothing was added.
+func (a *Aggregator) Percentile(p float64) float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.values) == 0 {
+		return math.NaN()
+	}
+	a.sortValues()
+	return percentile(a.values, p)
+}
+
+// percentile interpolates the p-th percentile of sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	p = math.Max(0, math.Min(100, p))
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower == upper || sorted[lower] == sorted[upper] {
+		return sorted[lower]
+	}
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
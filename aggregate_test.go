@@ -0,0 +1,60 @@
+package psnr
+
+import (
+	"math"
+	"sync"
+	"testing"
+)
+
+func TestAggregator(t *testing.T) {
+	var a Aggregator
+	if s := a.Summary(); s.Count != 0 || s.Mean != 0 {
+		t.Errorf("Expected an empty summary, got %+v", s)
+	}
+	if !math.IsNaN(a.Percentile(50)) {
+		t.Error("Expected NaN percentile without results")
+	}
+
+	var wg sync.WaitGroup
+	for _, psnr := range []float64{30, 40, 50, 20, math.Inf(1)} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.AddPSNR(psnr)
+		}()
+	}
+	wg.Wait()
+
+	s := a.Summary()
+	if s.Count != 5 || s.InfCount != 1 {
+		t.Errorf("Expected 5 results with 1 Inf, got %+v", s)
+	}
+	if s.Mean != 35 {
+		t.Errorf("Expected mean 35 over finite values, got %f", s.Mean)
+	}
+	if s.Min != 20 || !math.IsInf(s.Max, 1) || s.Median != 40 {
+		t.Errorf("Expected min 20, max Inf, median 40, got %+v", s)
+	}
+
+	// The mean MSE is dominated by the 20 dB result: (650.25+65.025+6.5025+0.65025+0)/5
+	wantMSEPSNR := psnrFromMSE((650.25 + 65.025 + 6.5025 + 0.65025) / 5)
+	if math.Abs(s.MSEPSNR-wantMSEPSNR) > 1e-9 {
+		t.Errorf("Expected MSE-derived PSNR %.6f, got %.6f", wantMSEPSNR, s.MSEPSNR)
+	}
+
+	if got := a.Percentile(25); got != 30 {
+		t.Errorf("Expected 25th percentile 30, got %f", got)
+	}
+	if got := a.Percentile(12.5); got != 25 {
+		t.Errorf("Expected interpolated percentile 25, got %f", got)
+	}
+	if got := a.Percentile(100); !math.IsInf(got, 1) {
+		t.Errorf("Expected Inf at the 100th percentile, got %f", got)
+	}
+
+	var results Aggregator
+	results.Add(&Result{PSNR: math.Inf(1)})
+	if s := results.Summary(); !math.IsInf(s.Mean, 1) || !math.IsInf(s.MSEPSNR, 1) {
+		t.Errorf("Expected Inf aggregates for identical images only, got %+v", s)
+	}
+}
@@ -0,0 +1,91 @@
+package psnr
+
+import (
+	"image"
+	"math"
+)
+
+// minAlignLevelSize is the smallest edge length a downscaled search level may
+// have; below it the coarse search becomes unreliable.
+const minAlignLevelSize = 32
+
+// compareAligned finds the translation within ±maxShift that minimizes the
+// MSE over the overlapping region and reports the PSNR at that placement.
+func compareAligned(img1, img2 *image.RGBA, hasAlpha bool, maxShift int) *Result {
+	width := img1.Rect.Dx()
+	height := img1.Rect.Dy()
+
+	// Keep at least half of each axis overlapping
+	if limit := min(width, height) / 2; maxShift > limit {
+		maxShift = limit
+	}
+
+	// Search on a box-downscaled copy first so large ranges stay cheap
+	factor := 1
+	for maxShift/factor > 4 && min(width, height)/(factor*2) >= minAlignLevelSize {
+		factor *= 2
+	}
+
+	center := image.Point{}
+	radius := maxShift
+	if factor > 1 {
+		coarse1 := downscaleRGBA(img1, factor)
+		coarse2 := downscaleRGBA(img2, factor)
+		coarseRange := (maxShift + factor - 1) / factor
+		best, _, _ := searchOffset(coarse1, coarse2, hasAlpha, image.Point{}, coarseRange, coarseRange)
+		center = best.Mul(factor)
+		radius = factor
+	}
+
+	best, sumSquaredDiff, samples := searchOffset(img1, img2, hasAlpha, center, radius, maxShift)
+	result := newResult(sumSquaredDiff, samples)
+	result.Offset = best
+	return result
+}
+
+// searchOffset evaluates every offset within radius of center, clamped to
+// ±limit, and returns the one with the lowest MSE along with its squared
+// difference sum and sample count.
+func searchOffset(img1, img2 *image.RGBA, hasAlpha bool, center image.Point, radius, limit int) (image.Point, uint64, uint64) {
+	channelCount := uint64(3)
+	if hasAlpha {
+		channelCount = 4
+	}
+
+	var best image.Point
+	var bestSum, bestSamples uint64
+	bestMSE := math.Inf(1)
+
+	for dy := max(center.Y-radius, -limit); dy <= min(center.Y+radius, limit); dy++ {
+		for dx := max(center.X-radius, -limit); dx <= min(center.X+radius, limit); dx++ {
+			offset := image.Pt(dx, dy)
+			overlap := img1.Rect.Intersect(img2.Rect.Sub(offset))
+			if overlap.Empty() {
+				continue
+			}
+
+			sum := blockSSD(img1, img2, overlap, overlap.Min.Add(offset), hasAlpha)
+			samples := uint64(overlap.Dx()*overlap.Dy()) * channelCount
+			mse := float64(sum) / float64(samples)
+
+			// Prefer the smallest shift on ties so identical images stay at zero
+			if mse < bestMSE || (mse == bestMSE && shiftLength(offset) < shiftLength(best)) {
+				best, bestSum, bestSamples, bestMSE = offset, sum, samples, mse
+			}
+		}
+	}
+
+	return best, bestSum, bestSamples
+}
+
+// shiftLength returns the Manhattan length of an offset.
+func shiftLength(p image.Point) int {
+	return abs(p.X) + abs(p.Y)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
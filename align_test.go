@@ -0,0 +1,83 @@
+package psnr
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// shiftedPattern renders a smooth, non-repeating test pattern translated by
+// (dx, dy).
+func shiftedPattern(width, height, dx, dy int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			fx := float64(x + dx)
+			fy := float64(y + dy)
+			v := 128 + 60*math.Sin(fx/9) + 50*math.Cos(fy/13) + 15*math.Sin((fx*fy)/400)
+			img.SetRGBA(x, y, color.RGBA{uint8(v), uint8(255 - v), uint8(v / 2), 255})
+		}
+	}
+	return img
+}
+
+func TestComputeDetailedAlignment(t *testing.T) {
+	tests := []struct {
+		name   string
+		size   int
+		shift  image.Point
+		search int
+	}{
+		{name: "small image brute force", size: 48, shift: image.Pt(2, -1), search: 3},
+		{name: "large image coarse to fine", size: 256, shift: image.Pt(-7, 5), search: 12},
+		{name: "no shift", size: 64, shift: image.Pt(0, 0), search: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data1 := encodePNG(t, shiftedPattern(tt.size, tt.size, 0, 0))
+			data2 := encodePNG(t, shiftedPattern(tt.size, tt.size, -tt.shift.X, -tt.shift.Y))
+
+			result, err := ComputeDetailed(data1, data2, WithAlignment(tt.search))
+			if err != nil {
+				t.Fatalf("Error computing PSNR: %v", err)
+			}
+
+			if result.Offset != tt.shift {
+				t.Errorf("Expected offset %v, got %v", tt.shift, result.Offset)
+			}
+			if !math.IsInf(result.PSNR, 1) {
+				t.Errorf("Expected Inf for aligned overlap, got %f", result.PSNR)
+			}
+		})
+	}
+}
+
+func TestComputeDetailedAlignmentImprovesPSNR(t *testing.T) {
+	data1 := encodePNG(t, shiftedPattern(96, 96, 0, 0))
+	data2 := encodePNG(t, shiftedPattern(96, 96, 1, 2))
+
+	raw, err := ComputeDetailed(data1, data2)
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+
+	aligned, err := ComputeDetailed(data1, data2, WithAlignment(2))
+	if err != nil {
+		t.Fatalf("Error computing aligned PSNR: %v", err)
+	}
+
+	t.Logf("raw PSNR = %.2f dB, aligned PSNR = %.2f dB, offset = %v", raw.PSNR, aligned.PSNR, aligned.Offset)
+
+	if aligned.PSNR <= raw.PSNR {
+		t.Errorf("Expected alignment to improve PSNR, got %.2f <= %.2f", aligned.PSNR, raw.PSNR)
+	}
+}
+
+func TestWithAlignmentInvalid(t *testing.T) {
+	data := encodePNG(t, shiftedPattern(16, 16, 0, 0))
+	if _, err := ComputeDetailed(data, data, WithAlignment(-1)); err == nil {
+		t.Error("Expected error for negative search range")
+	}
+}
@@ -0,0 +1,75 @@
+package psnr
+
+import "image"
+
+// AlphaMode selects how color samples of translucent pixels are compared.
+type AlphaMode int
+
+const (
+	// AlphaAuto compares images in their decoded representation: straight
+	// samples when both are *image.NRGBA (PNGs with alpha), and premultiplied
+	// samples otherwise, including every mixed pair.
+	AlphaAuto AlphaMode = iota
+	// AlphaPremultiplied compares color samples multiplied by alpha, so
+	// differences in fully transparent pixels do not count and differences
+	// in translucent pixels count in proportion to their opacity.
+	AlphaPremultiplied
+	// AlphaStraight compares color samples as stored in straight-alpha
+	// formats, so the hidden colors of transparent pixels count like visible
+	// ones. Premultiplied inputs are un-premultiplied, which cannot restore
+	// the colors of fully transparent pixels.
+	AlphaStraight
+)
+
+// normalizeAlpha converts an image to the representation of mode, keeping
+// it when no conversion is needed.
+func normalizeAlpha(img image.Image, mode AlphaMode) image.Image {
+	switch mode {
+	case AlphaPremultiplied:
+		return toRGBA(img)
+	case AlphaStraight:
+		return toNRGBA(img)
+	}
+	return img
+}
+
+// straightRGBA returns the straight samples of img in an *image.RGBA for
+// the passes that work on RGBA buffers. The result is not a valid
+// premultiplied image and must only be read as bytes.
+func straightRGBA(img image.Image) *image.RGBA {
+	nrgba := toNRGBA(img)
+	return &image.RGBA{Pix: nrgba.Pix, Stride: nrgba.Stride, Rect: nrgba.Rect}
+}
+
+// comparisonImage returns the image whose samples are those of a buffer
+// from comparisonRGBA: an *image.NRGBA over the same bytes when they are
+// straight, or the premultiplied buffer itself.
+func comparisonImage(rgba *image.RGBA, straight bool) image.Image {
+	if straight {
+		return &image.NRGBA{Pix: rgba.Pix, Stride: rgba.Stride, Rect: rgba.Rect}
+	}
+	return rgba
+}
+
+// comparesStraight reports whether the MSE kernels compare img1 and img2,
+// as returned by normalizeAlpha for mode, in straight samples: always under
+// AlphaStraight, and under AlphaAuto when both are *image.NRGBA.
+func comparesStraight(img1, img2 image.Image, mode AlphaMode) bool {
+	if mode == AlphaAuto {
+		_, ok1 := img1.(*image.NRGBA)
+		_, ok2 := img2.(*image.NRGBA)
+		return ok1 && ok2
+	}
+	return mode == AlphaStraight
+}
+
+// comparisonRGBA returns RGBA buffers holding the samples the MSE kernels
+// compare for img1 and img2 under mode, so every pass on RGBA buffers agrees
+// with Result.PSNR. Straight samples are returned as by straightRGBA.
+func comparisonRGBA(img1, img2 image.Image, mode AlphaMode) (*image.RGBA, *image.RGBA) {
+	img1, img2 = normalizeAlpha(img1, mode), normalizeAlpha(img2, mode)
+	if comparesStraight(img1, img2, mode) {
+		return straightRGBA(img1), straightRGBA(img2)
+	}
+	return toRGBA(img1), toRGBA(img2)
+}
@@ -0,0 +1,131 @@
+package psnr
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// translucentPair returns two translucent NRGBA gradients whose straight
+// colors differ by 60 in green, so comparing them straight and
+// premultiplied gives different results.
+func translucentPair(width, height int) (*image.NRGBA, *image.NRGBA) {
+	img1 := image.NewNRGBA(image.Rect(0, 0, width, height))
+	img2 := image.NewNRGBA(img1.Rect)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.NRGBA{uint8(x * 5), uint8(y * 9), uint8(x * y % 256), uint8(60 + x*4)}
+			img1.SetNRGBA(x, y, c)
+			c.G += 60
+			img2.SetNRGBA(x, y, c)
+		}
+	}
+	return img1, img2
+}
+
+func TestWithAlphaMode(t *testing.T) {
+	// Identical visible pixels; the fully transparent half hides different
+	// colors
+	img1 := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	img2 := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			if x < 8 {
+				img1.SetNRGBA(x, y, color.NRGBA{R: 90, G: 120, B: 150, A: 255})
+				img2.SetNRGBA(x, y, color.NRGBA{R: 90, G: 120, B: 150, A: 255})
+			} else {
+				img1.SetNRGBA(x, y, color.NRGBA{R: 255, A: 0})
+				img2.SetNRGBA(x, y, color.NRGBA{B: 255, A: 0})
+			}
+		}
+	}
+	data1, data2 := encodePNG(t, img1), encodePNG(t, img2)
+
+	auto, err := ComputeDetailed(data1, data2)
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	straight, err := ComputeDetailed(data1, data2, WithAlphaMode(AlphaStraight))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	premultiplied, err := ComputeDetailed(data1, data2, WithAlphaMode(AlphaPremultiplied))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+
+	// Two PNGs with alpha are compared straight by default
+	if math.IsInf(auto.PSNR, 1) || auto.MSE != straight.MSE {
+		t.Errorf("Expected hidden colors to count by default, got auto %v, straight %v", auto.MSE, straight.MSE)
+	}
+	if !math.IsInf(premultiplied.PSNR, 1) {
+		t.Errorf("Expected identical premultiplied images, got %.2f dB", premultiplied.PSNR)
+	}
+
+	// Passes on RGBA buffers follow the mode too
+	histogram, err := ComputeDetailed(data1, data2, WithAlphaMode(AlphaStraight), WithErrorHistogram())
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if histogram.MSE != straight.MSE {
+		t.Errorf("Expected MSE %v with a histogram, got %v", straight.MSE, histogram.MSE)
+	}
+
+	// A mixed pair is compared premultiplied by default
+	rgba := image.NewRGBA(img2.Rect)
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			rgba.Set(x, y, img2.At(x, y))
+		}
+	}
+	mixed, err := CompareImages(context.Background(), img1, rgba)
+	if err != nil {
+		t.Fatalf("CompareImages failed: %v", err)
+	}
+	if !math.IsInf(mixed.PSNR, 1) {
+		t.Errorf("Expected a premultiplied comparison of a mixed pair, got %.2f dB", mixed.PSNR)
+	}
+
+	deterministic, err := ComputeDetailed(data1, data2, WithAlphaMode(AlphaStraight), WithDeterministic())
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if deterministic.MSE != straight.MSE {
+		t.Errorf("Expected MSE %v in deterministic mode, got %v", straight.MSE, deterministic.MSE)
+	}
+	if _, err := ComputeDetailed(data1, data2, WithAlphaMode(AlphaMode(7))); err == nil {
+		t.Error("Expected error for an unknown alpha mode")
+	}
+}
+
+func TestAlphaAutoVisitors(t *testing.T) {
+	// Translucent NRGBA pixels, which the NRGBA kernel compares straight
+	img1 := image.NewNRGBA(image.Rect(0, 0, 32, 32))
+	img2 := image.NewNRGBA(image.Rect(0, 0, 32, 32))
+	for i := range img1.Pix {
+		img1.Pix[i] = uint8(i * 7)
+		img2.Pix[i] = uint8(i*7 + i%5)
+	}
+	data1, data2 := encodePNG(t, img1), encodePNG(t, img2)
+
+	plain, err := ComputeDetailed(data1, data2)
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	for name, opt := range map[string]Option{
+		"metrics":       WithMetrics("test_max_abs_diff"),
+		"histogram":     WithErrorHistogram(),
+		"components":    WithComponentPSNR(),
+		"deterministic": WithDeterministic(),
+	} {
+		result, err := ComputeDetailed(data1, data2, opt)
+		if err != nil {
+			t.Fatalf("%s: error computing PSNR: %v", name, err)
+		}
+		if math.Float64bits(result.PSNR) != math.Float64bits(plain.PSNR) {
+			t.Errorf("%s: expected PSNR %.17g, got %.17g", name, plain.PSNR, result.PSNR)
+		}
+	}
+}
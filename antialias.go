@@ -0,0 +1,148 @@
+package psnr
+
+import "image"
+
+// AntiAliasingMode selects what WithAntiAliasing does with differing pixels
+// classified as anti-aliasing.
+type AntiAliasingMode int
+
+const (
+	// AntiAliasingOff skips the classification (the default).
+	AntiAliasingOff AntiAliasingMode = iota
+	// AntiAliasingCount classifies the differing pixels and counts each
+	// category in Result.AntiAliasing.
+	AntiAliasingCount
+	// AntiAliasingExclude also leaves the anti-aliased pixels out of the MSE
+	// and PSNR.
+	AntiAliasingExclude
+)
+
+// AntiAliasingResult counts the differing pixels classified by
+// WithAntiAliasing.
+type AntiAliasingResult struct {
+	// Changed is the number of differing pixels that are real changes.
+	Changed int
+	// AntiAliased is the number of differing pixels that look like
+	// anti-aliasing artifacts.
+	AntiAliased int
+}
+
+// aaRegion is the region of an image being classified, in coordinates
+// relative to its origin.
+type aaRegion struct {
+	img           *image.RGBA
+	origin        image.Point
+	width, height int
+}
+
+// pix returns the samples of pixel (x, y).
+func (r aaRegion) pix(x, y int) []uint8 {
+	i := r.img.PixOffset(r.origin.X+x, r.origin.Y+y)
+	return r.img.Pix[i : i+4 : i+4]
+}
+
+// brightness returns the YIQ luma of pixel (x, y) blended over white, as
+// pixelmatch measures it.
+func (r aaRegion) brightness(x, y int) float64 {
+	p := r.pix(x, y)
+	// Premultiplied samples blend over white by adding the uncovered part
+	white := float64(255 - p[3])
+	return (float64(p[0])+white)*0.29889531 + (float64(p[1])+white)*0.58662247 + (float64(p[2])+white)*0.11448223
+}
+
+// neighbourhood returns the 3x3 window around (x, y) clipped to the region,
+// and 1 when it was clipped, as pixelmatch counts the border as a sibling.
+func (r aaRegion) neighbourhood(x, y int) (x0, y0, x1, y1, border int) {
+	x0, y0 = max(x-1, 0), max(y-1, 0)
+	x1, y1 = min(x+1, r.width-1), min(y+1, r.height-1)
+	if x == x0 || x == x1 || y == y0 || y == y1 {
+		border = 1
+	}
+	return x0, y0, x1, y1, border
+}
+
+// hasManySiblings reports whether more than two neighbours of (x, y) are
+// identical to it, i.e. it lies in a flat area.
+func (r aaRegion) hasManySiblings(x, y int) bool {
+	x0, y0, x1, y1, zeroes := r.neighbourhood(x, y)
+	center := r.pix(x, y)
+	for ny := y0; ny <= y1; ny++ {
+		for nx := x0; nx <= x1; nx++ {
+			if nx == x && ny == y {
+				continue
+			}
+			if p := r.pix(nx, ny); p[0] == center[0] && p[1] == center[1] && p[2] == center[2] && p[3] == center[3] {
+				zeroes++
+				if zeroes > 2 {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// antiAliased reports whether pixel (x, y) of a looks like anti-aliasing
+// in the way pixelmatch detects it: at most two neighbours share its
+// brightness, it lies between a darker and a brighter neighbour, and one of
+// those extremes lies in a flat area of both a and b.
+func antiAliased(a, b aaRegion, x, y int) bool {
+	x0, y0, x1, y1, zeroes := a.neighbourhood(x, y)
+	center := a.brightness(x, y)
+	var darkest, brightest float64
+	var darkX, darkY, brightX, brightY int
+	for ny := y0; ny <= y1; ny++ {
+		for nx := x0; nx <= x1; nx++ {
+			if nx == x && ny == y {
+				continue
+			}
+			delta := a.brightness(nx, ny) - center
+			switch {
+			case delta == 0:
+				zeroes++
+				if zeroes > 2 {
+					return false
+				}
+			case delta < darkest:
+				darkest, darkX, darkY = delta, nx, ny
+			case delta > brightest:
+				brightest, brightX, brightY = delta, nx, ny
+			}
+		}
+	}
+	if darkest == 0 || brightest == 0 {
+		return false
+	}
+	return (a.hasManySiblings(darkX, darkY) && b.hasManySiblings(darkX, darkY)) ||
+		(a.hasManySiblings(brightX, brightY) && b.hasManySiblings(brightX, brightY))
+}
+
+// classifyAntiAliasing classifies the differing pixels of the region r of
+// img1 and the same-sized region of img2 at origin2, skipping the pixels
+// already excluded, and returns the mask of the anti-aliased pixels in
+// row-major order.
+func classifyAntiAliasing(img1, img2 *image.RGBA, r image.Rectangle, origin2 image.Point, excluded []bool) (*AntiAliasingResult, []bool) {
+	a := aaRegion{img: img1, origin: r.Min, width: r.Dx(), height: r.Dy()}
+	b := aaRegion{img: img2, origin: origin2, width: r.Dx(), height: r.Dy()}
+
+	result := &AntiAliasingResult{}
+	aliased := make([]bool, a.width*a.height)
+	for y := 0; y < a.height; y++ {
+		for x := 0; x < a.width; x++ {
+			i := y*a.width + x
+			if excluded != nil && excluded[i] {
+				continue
+			}
+			if p1, p2 := a.pix(x, y), b.pix(x, y); p1[0] == p2[0] && p1[1] == p2[1] && p1[2] == p2[2] && p1[3] == p2[3] {
+				continue
+			}
+			if antiAliased(a, b, x, y) || antiAliased(b, a, x, y) {
+				aliased[i] = true
+				result.AntiAliased++
+			} else {
+				result.Changed++
+			}
+		}
+	}
+	return result, aliased
+}
@@ -0,0 +1,83 @@
+package psnr
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+)
+
+// edgeImages returns a white-to-black edge and a copy whose edge column
+// is softened to gray, as anti-aliasing renders it.
+func edgeImages() (*image.RGBA, *image.RGBA) {
+	img1 := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	draw.Draw(img1, img1.Rect, image.White, image.Point{}, draw.Src)
+	draw.Draw(img1, image.Rect(5, 0, 10, 10), image.Black, image.Point{}, draw.Src)
+	img2 := image.NewRGBA(img1.Rect)
+	copy(img2.Pix, img1.Pix)
+	draw.Draw(img2, image.Rect(5, 0, 6, 10), image.NewUniform(color.Gray{Y: 128}), image.Point{}, draw.Src)
+	return img1, img2
+}
+
+func TestWithAntiAliasing(t *testing.T) {
+	ctx := context.Background()
+	img1, img2 := edgeImages()
+
+	counted, err := CompareImages(ctx, img1, img2, WithAntiAliasing(AntiAliasingCount))
+	if err != nil {
+		t.Fatalf("CompareImages failed: %v", err)
+	}
+	if counted.AntiAliasing == nil || *counted.AntiAliasing != (AntiAliasingResult{AntiAliased: 10}) {
+		t.Fatalf("Expected 10 anti-aliased pixels, got %+v", counted.AntiAliasing)
+	}
+	plain, err := CompareImages(ctx, img1, img2)
+	if err != nil {
+		t.Fatalf("CompareImages failed: %v", err)
+	}
+	if counted.PSNR != plain.PSNR || plain.AntiAliasing != nil {
+		t.Errorf("Expected counting to keep PSNR %v, got %v", plain.PSNR, counted.PSNR)
+	}
+
+	excluded, err := CompareImages(ctx, img1, img2, WithAntiAliasing(AntiAliasingExclude))
+	if err != nil {
+		t.Fatalf("CompareImages failed: %v", err)
+	}
+	if !math.IsInf(excluded.PSNR, 1) || excluded.MSE != 0 {
+		t.Errorf("Expected only anti-aliasing differences to be excluded, got %v dB", excluded.PSNR)
+	}
+
+	// A red block in the white area is a real change
+	draw.Draw(img2, image.Rect(1, 1, 4, 4), image.NewUniform(color.RGBA{255, 0, 0, 255}), image.Point{}, draw.Src)
+	excluded, err = CompareImages(ctx, img1, img2, WithAntiAliasing(AntiAliasingExclude))
+	if err != nil {
+		t.Fatalf("CompareImages failed: %v", err)
+	}
+	if *excluded.AntiAliasing != (AntiAliasingResult{Changed: 9, AntiAliased: 10}) {
+		t.Errorf("Expected 9 changed pixels, got %+v", excluded.AntiAliasing)
+	}
+	// Two of three channels differ by 255 in 9 of the 90 kept pixels
+	if want := 2 * 255.0 * 255 * 9 / (90 * 3); math.Abs(excluded.MSE-want) > 1e-9 {
+		t.Errorf("Expected MSE %v, got %v", want, excluded.MSE)
+	}
+
+	if _, err := CompareImages(ctx, img1, img2, WithAntiAliasing(AntiAliasingExclude+1)); err == nil {
+		t.Error("Expected error for an unknown mode")
+	}
+	if _, err := CompareImages(ctx, img1, img2, WithAntiAliasing(AntiAliasingCount), WithCompatibility(CompatibilityFFmpeg)); err == nil {
+		t.Error("Expected error with a compatibility mode")
+	}
+}
+
+func TestAntiAliasedFlatAreas(t *testing.T) {
+	img1, img2 := edgeImages()
+	a := aaRegion{img: img1, width: 10, height: 10}
+	b := aaRegion{img: img2, width: 10, height: 10}
+	if !antiAliased(b, a, 5, 5) {
+		t.Error("Expected the gray edge pixel to be anti-aliased")
+	}
+	if antiAliased(a, b, 2, 5) || antiAliased(b, a, 8, 5) {
+		t.Error("Expected pixels in flat areas not to be anti-aliased")
+	}
+}
@@ -0,0 +1,54 @@
+package psnr
+
+import (
+	"errors"
+	"fmt"
+	"image"
+)
+
+// maxAVIFSpeed is the fastest libavif encoder speed.
+const maxAVIFSpeed = 10
+
+// ErrAVIFUnsupported is returned by AVIFEncoder and SearchAVIFQuality when no
+// AVIF encoder was built in.
+var ErrAVIFUnsupported = errors.New("AVIF encoding requires building with -tags libavif")
+
+// encodeAVIF encodes img as a lossy AVIF at a quality from 0 to 100 and a
+// speed from 0 to 10 with the encoder compiled in, and is nil when there is
+// none.
+var encodeAVIF func(img image.Image, quality, speed int) ([]byte, error)
+
+// AVIFEncoder returns an Encoder that encodes lossy 4:2:0 AVIF with libavif
+// at the given speed, from 0 (slowest, smallest files) to 10 (fastest),
+// using the parameter as quality (0-100), which libavif maps to the AV1
+// quantizer. It requires libavif and the libavif build tag, which also
+// registers an AVIF decoder with the image package; without it, the
+// encoder returns ErrAVIFUnsupported.
+func AVIFEncoder(speed int) Encoder {
+	return EncoderFunc(func(img image.Image, quality int) ([]byte, error) {
+		if encodeAVIF == nil {
+			return nil, ErrAVIFUnsupported
+		}
+		if speed < 0 || speed > maxAVIFSpeed {
+			return nil, fmt.Errorf("invalid AVIF speed: %d", speed)
+		}
+		return encodeAVIF(img, quality, speed)
+	})
+}
+
+// SearchAVIFQuality binary-searches the AVIF quality for the lowest one whose
+// output at the given encoder speed (0-10) still has at least targetPSNR
+// against original, and returns it with the encoded bytes, the achieved
+// PSNR and the values of any metrics requested with WithMetrics. Slower
+// speeds reach the target at lower qualities and smaller files, at a much
+// higher encoding cost per iteration. opts configure each PSNR evaluation.
+// It returns ErrAVIFUnsupported unless built with the libavif tag.
+func SearchAVIFQuality(original []byte, targetPSNR float64, speed int, opts ...Option) (*QualityResult, error) {
+	if encodeAVIF == nil {
+		return nil, ErrAVIFUnsupported
+	}
+	if speed < 0 || speed > maxAVIFSpeed {
+		return nil, fmt.Errorf("invalid AVIF speed: %d", speed)
+	}
+	return SearchQuality(original, AVIFEncoder(speed), targetPSNR, SearchOptions{Options: opts})
+}
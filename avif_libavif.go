@@ -0,0 +1,180 @@
+//go:build libavif && cgo
+
+package psnr
+
+/*
+#cgo LDFLAGS: -lavif
+#include <stdlib.h>
+#include <string.h>
+#include <avif/avif.h>
+
+// psnr_encode_avif encodes 8-bit straight RGBA as lossy 4:2:0 AVIF with
+// lossless alpha. On failure it returns the libavif error message.
+static const char *psnr_encode_avif(uint8_t *pixels, int width, int height, int stride, int quality, int speed, avifRWData *output) {
+	avifImage *image = avifImageCreate(width, height, 8, AVIF_PIXEL_FORMAT_YUV420);
+	if (image == NULL) {
+		return avifResultToString(AVIF_RESULT_OUT_OF_MEMORY);
+	}
+	avifRGBImage rgb;
+	avifRGBImageSetDefaults(&rgb, image);
+	rgb.format = AVIF_RGB_FORMAT_RGBA;
+	rgb.depth = 8;
+	rgb.pixels = pixels;
+	rgb.rowBytes = stride;
+
+	avifResult result = avifImageRGBToYUV(image, &rgb);
+	if (result == AVIF_RESULT_OK) {
+		avifEncoder *encoder = avifEncoderCreate();
+		if (encoder == NULL) {
+			result = AVIF_RESULT_OUT_OF_MEMORY;
+		} else {
+			encoder->quality = quality;
+			encoder->qualityAlpha = AVIF_QUALITY_LOSSLESS;
+			encoder->speed = speed;
+			result = avifEncoderWrite(encoder, image, output);
+			avifEncoderDestroy(encoder);
+		}
+	}
+	avifImageDestroy(image);
+	return result == AVIF_RESULT_OK ? NULL : avifResultToString(result);
+}
+
+// psnr_decode_avif decodes the first image of an AVIF file to straight RGBA
+// in a malloc'ed buffer without padding, or only reads its dimensions and
+// bit depth when pixels is NULL. Images deeper than 8 bits are decoded to
+// 16-bit samples in native byte order. On failure it returns the libavif
+// error message.
+static const char *psnr_decode_avif(uint8_t *data, size_t size, uint8_t **pixels, int *width, int *height, int *depth) {
+	avifDecoder *decoder = avifDecoderCreate();
+	if (decoder == NULL) {
+		return avifResultToString(AVIF_RESULT_OUT_OF_MEMORY);
+	}
+	avifResult result = avifDecoderSetIOMemory(decoder, data, size);
+	if (result == AVIF_RESULT_OK) {
+		result = avifDecoderParse(decoder);
+	}
+	if (result == AVIF_RESULT_OK) {
+		*width = decoder->image->width;
+		*height = decoder->image->height;
+		*depth = decoder->image->depth > 8 ? 16 : 8;
+	}
+	if (result == AVIF_RESULT_OK && pixels != NULL) {
+		result = avifDecoderNextImage(decoder);
+		avifRGBImage rgb;
+		memset(&rgb, 0, sizeof(rgb));
+		if (result == AVIF_RESULT_OK) {
+			avifRGBImageSetDefaults(&rgb, decoder->image);
+			rgb.format = AVIF_RGB_FORMAT_RGBA;
+			rgb.depth = *depth;
+			result = avifRGBImageAllocatePixels(&rgb);
+		}
+		if (result == AVIF_RESULT_OK) {
+			result = avifImageYUVToRGB(decoder->image, &rgb);
+		}
+		if (result == AVIF_RESULT_OK) {
+			size_t row = (size_t)rgb.width * 4 * (*depth / 8);
+			*pixels = malloc(row * rgb.height);
+			if (*pixels == NULL) {
+				result = AVIF_RESULT_OUT_OF_MEMORY;
+			} else {
+				for (uint32_t y = 0; y < rgb.height; y++) {
+					memcpy(*pixels + y * row, rgb.pixels + y * rgb.rowBytes, row);
+				}
+			}
+		}
+		avifRGBImageFreePixels(&rgb);
+	}
+	avifDecoderDestroy(decoder);
+	return result == AVIF_RESULT_OK ? NULL : avifResultToString(result);
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"unsafe"
+)
+
+func init() {
+	encodeAVIF = encodeLibavif
+	image.RegisterFormat("avif", "????ftypavif", decodeLibavif, decodeLibavifConfig)
+}
+
+// encodeLibavif encodes img as a lossy AVIF with libavif.
+func encodeLibavif(img image.Image, quality, speed int) ([]byte, error) {
+	if quality < 0 || quality > 100 {
+		return nil, errors.New("libavif: quality must be between 0 and 100")
+	}
+	nrgba := toNRGBA(img)
+	width, height := nrgba.Rect.Dx(), nrgba.Rect.Dy()
+	if width == 0 || height == 0 {
+		return nil, errors.New("libavif: empty image")
+	}
+
+	pixels := C.CBytes(nrgba.Pix)
+	defer C.free(pixels)
+	var output C.avifRWData
+	defer C.avifRWDataFree(&output)
+	if msg := C.psnr_encode_avif((*C.uint8_t)(pixels), C.int(width), C.int(height), C.int(nrgba.Stride), C.int(quality), C.int(speed), &output); msg != nil {
+		return nil, fmt.Errorf("libavif: %s", C.GoString(msg))
+	}
+	return C.GoBytes(unsafe.Pointer(output.data), C.int(output.size)), nil
+}
+
+// decodeLibavif decodes the first image of an AVIF file to straight RGBA,
+// as an *image.NRGBA64 when its bit depth exceeds 8 so 10- and 12-bit
+// images keep their precision.
+func decodeLibavif(r io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	input := C.CBytes(data)
+	defer C.free(input)
+
+	var pixels *C.uint8_t
+	var width, height, depth C.int
+	if msg := C.psnr_decode_avif((*C.uint8_t)(input), C.size_t(len(data)), &pixels, &width, &height, &depth); msg != nil {
+		return nil, fmt.Errorf("libavif: %s", C.GoString(msg))
+	}
+	defer C.free(unsafe.Pointer(pixels))
+
+	rect := image.Rect(0, 0, int(width), int(height))
+	if depth == 8 {
+		img := image.NewNRGBA(rect)
+		copy(img.Pix, unsafe.Slice((*uint8)(unsafe.Pointer(pixels)), len(img.Pix)))
+		return img, nil
+	}
+	// NRGBA64 stores big-endian samples; libavif returns native uint16s
+	img := image.NewNRGBA64(rect)
+	samples := unsafe.Slice((*uint16)(unsafe.Pointer(pixels)), len(img.Pix)/2)
+	for i, v := range samples {
+		img.Pix[2*i] = uint8(v >> 8)
+		img.Pix[2*i+1] = uint8(v)
+	}
+	return img, nil
+}
+
+// decodeLibavifConfig reads the dimensions and bit depth of an AVIF file.
+func decodeLibavifConfig(r io.Reader) (image.Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	input := C.CBytes(data)
+	defer C.free(input)
+
+	var width, height, depth C.int
+	if msg := C.psnr_decode_avif((*C.uint8_t)(input), C.size_t(len(data)), nil, &width, &height, &depth); msg != nil {
+		return image.Config{}, fmt.Errorf("libavif: %s", C.GoString(msg))
+	}
+	model := color.NRGBAModel
+	if depth > 8 {
+		model = color.NRGBA64Model
+	}
+	return image.Config{ColorModel: model, Width: int(width), Height: int(height)}, nil
+}
@@ -0,0 +1,52 @@
+//go:build libavif && cgo
+
+package psnr
+
+import (
+	"bytes"
+	"image"
+	"os"
+	"testing"
+)
+
+func TestLibavifRoundTrip(t *testing.T) {
+	original, err := os.ReadFile("testdata/test_image.png")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+	img := decodeTestImage(t, original)
+
+	data, err := AVIFEncoder(8).Encode(img, 80)
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	decoded, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil || format != "avif" {
+		t.Fatalf("Failed to decode AVIF: %q, %v", format, err)
+	}
+	if decoded.Bounds().Size() != img.Bounds().Size() {
+		t.Errorf("Expected size %v, got %v", img.Bounds().Size(), decoded.Bounds().Size())
+	}
+	if _, err := AVIFEncoder(11).Encode(img, 80); err == nil {
+		t.Error("Expected error for an invalid speed")
+	}
+}
+
+func TestSearchAVIFQuality(t *testing.T) {
+	original, err := os.ReadFile("testdata/test_image.png")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+
+	result, err := SearchAVIFQuality(original, 36, 8)
+	if err != nil {
+		t.Fatalf("Error searching quality: %v", err)
+	}
+	t.Logf("quality %d: %.4f dB, %d bytes", result.Quality, result.PSNR, len(result.Data))
+	if result.PSNR < 36 {
+		t.Errorf("Expected at least 36 dB, got %.4f", result.PSNR)
+	}
+	if got, err := Compute(original, result.Data); err != nil || got != result.PSNR {
+		t.Errorf("Expected the returned bytes to measure %.6f, got %.6f, %v", result.PSNR, got, err)
+	}
+}
@@ -0,0 +1,24 @@
+package psnr
+
+import (
+	"errors"
+	"image"
+	"os"
+	"testing"
+)
+
+func TestAVIFUnsupported(t *testing.T) {
+	if encodeAVIF != nil {
+		t.Skip("built with an AVIF encoder")
+	}
+	original, err := os.ReadFile("testdata/test_image.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SearchAVIFQuality(original, 38, 6); !errors.Is(err, ErrAVIFUnsupported) {
+		t.Errorf("Expected ErrAVIFUnsupported, got %v", err)
+	}
+	if _, err := AVIFEncoder(6).Encode(image.NewRGBA(image.Rect(0, 0, 1, 1)), 60); !errors.Is(err, ErrAVIFUnsupported) {
+		t.Errorf("Expected ErrAVIFUnsupported from the encoder, got %v", err)
+	}
+}
@@ -0,0 +1,230 @@
+package psnr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+)
+
+// ErrBatchAborted is reported for the pairs a FailFast batch skipped or
+// interrupted after an earlier pair failed.
+var ErrBatchAborted = errors.New("batch aborted after an earlier failure")
+
+// Pair identifies two images to compare in a batch, either by content or by
+// file path. Data takes precedence over the path when both are set.
+type Pair struct {
+	Path1, Path2 string
+	Data1, Data2 []byte
+}
+
+// BatchOptions configures ComputeBatch, ComputeBatchStream and CompareDirs.
+type BatchOptions struct {
+	// Workers is the number of comparisons run concurrently. It defaults to
+	// runtime.GOMAXPROCS(0).
+	Workers int
+	// Options are applied to every comparison.
+	Options []Option
+	// Cache, when set, returns stored results for pairs whose contents and
+	// options were compared before and stores new results, so repeated runs
+	// only recompute changed pairs.
+	Cache Cache
+	// FailFast stops the batch at the first failing pair: comparisons in
+	// progress are canceled and every pair not yet compared reports
+	// ErrBatchAborted. By default a failing pair does not affect the others.
+	FailFast bool
+	// MemoryBudget, when positive, is a soft limit in bytes on the images
+	// decoded at once. Each pair's footprint is estimated from the image
+	// headers, and comparisons wait for running ones to finish rather than
+	// exceed the budget, so a batch of large images runs on fewer workers.
+	// A pair larger than the budget, or one whose headers cannot be read,
+	// still runs, alone.
+	MemoryBudget int64
+}
+
+// BatchResult is the outcome of one pair in a batch.
+type BatchResult struct {
+	// Index is the position of the pair in the input slice.
+	Index  int
+	Result *Result
+	// Err is the error for this pair; other pairs are unaffected by it.
+	Err error
+}
+
+// BatchSummary counts the outcomes of a batch.
+type BatchSummary struct {
+	// Pairs is the number of pairs in the batch.
+	Pairs int
+	// Succeeded is the number of pairs compared without error.
+	Succeeded int
+	// Failed is the number of pairs whose comparison returned an error.
+	Failed int
+	// Skipped is the number of pairs that were not compared, or did not
+	// finish, because the context was done or a FailFast batch was aborted.
+	Skipped int
+}
+
+// SummarizeBatch counts the successes, failures and skipped pairs of a
+// batch, e.g. to log one line per batch or to decide whether to retry it.
+func SummarizeBatch(results []BatchResult) BatchSummary {
+	summary := BatchSummary{Pairs: len(results)}
+	for _, r := range results {
+		switch {
+		case r.Err == nil:
+			summary.Succeeded++
+		case errors.Is(r.Err, ErrBatchAborted), errors.Is(r.Err, context.Canceled), errors.Is(r.Err, context.DeadlineExceeded):
+			summary.Skipped++
+		default:
+			summary.Failed++
+		}
+	}
+	return summary
+}
+
+// ComputeBatch compares all pairs on a bounded worker pool and returns the
+// results in input order. A failing pair, such as a corrupt or missing
+// file, records its error in its BatchResult without aborting the batch
+// unless opts.FailFast is set. When ctx is canceled, pairs that have not
+// finished report the context's error.
+func ComputeBatch(ctx context.Context, pairs []Pair, opts BatchOptions) []BatchResult {
+	return collectBatch(ctx, len(pairs), ComputeBatchStream(ctx, pairs, opts))
+}
+
+// collectBatch gathers the results of a batch stream of n pairs in input
+// order. Pairs whose results the stream dropped after ctx was canceled
+// report the context's error.
+func collectBatch(ctx context.Context, n int, stream <-chan BatchResult) []BatchResult {
+	results := make([]BatchResult, n)
+	reported := make([]bool, n)
+	for r := range stream {
+		results[r.Index] = r
+		reported[r.Index] = true
+	}
+	for i, ok := range reported {
+		if !ok {
+			results[i] = BatchResult{Index: i, Err: ctx.Err()}
+		}
+	}
+	return results
+}
+
+// ComputeBatchStream is like ComputeBatch but delivers each result on the
+// returned channel as soon as it completes, in completion order. The channel
+// is closed after every pair has been reported or, once ctx is canceled, as
+// soon as the workers stop; results not yet received are then dropped, so
+// callers may stop reading after canceling ctx.
+func ComputeBatchStream(ctx context.Context, pairs []Pair, opts BatchOptions) <-chan BatchResult {
+	budget := newMemoryBudget(opts.MemoryBudget)
+	return batchStream(ctx, pairs, opts.Workers, opts.FailFast, func(ctx context.Context, pair Pair) (*Result, error) {
+		return computePair(ctx, pair, opts, budget)
+	})
+}
+
+// batchStream runs compute for every pair on a pool of workers, defaulting
+// to runtime.GOMAXPROCS(0), and reports the results in completion order.
+// With failFast, the first failure cancels the context passed to compute.
+func batchStream(parent context.Context, pairs []Pair, workers int, failFast bool, compute func(context.Context, Pair) (*Result, error)) <-chan BatchResult {
+	// Sends give up on the caller's context only: a FailFast abort cancels
+	// ctx, but the pairs it interrupts are still reported
+	ctx, abort := context.WithCancelCause(parent)
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, len(pairs))
+
+	indexes := make(chan int)
+	results := make(chan BatchResult, workers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				result, err := compute(ctx, pairs[index])
+				if err != nil && failFast {
+					err = abortBatch(ctx, abort, err)
+				}
+				select {
+				case results <- BatchResult{Index: index, Result: result, Err: err}:
+				case <-parent.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+	feed:
+		for index := range pairs {
+			select {
+			case indexes <- index:
+			case <-parent.Done():
+				break feed
+			}
+		}
+		close(indexes)
+		wg.Wait()
+		abort(nil)
+		close(results)
+	}()
+
+	return results
+}
+
+// abortBatch aborts a FailFast batch on its first failure and returns the
+// error to report for a failed pair: ErrBatchAborted when the pair was only
+// interrupted by the abort, and err otherwise.
+func abortBatch(ctx context.Context, abort context.CancelCauseFunc, err error) error {
+	if errors.Is(err, context.Canceled) && context.Cause(ctx) == ErrBatchAborted {
+		return ErrBatchAborted
+	}
+	abort(ErrBatchAborted)
+	return err
+}
+
+// computePair loads and compares one pair within budget, failing fast once
+// ctx is done.
+func computePair(ctx context.Context, pair Pair, opts BatchOptions, budget *memoryBudget) (*Result, error) {
+	data1, data2, err := loadPair(ctx, pair)
+	if err != nil {
+		return nil, err
+	}
+	return budget.compareWithin(ctx, data1, data2, func() (*Result, error) {
+		return computeCached(ctx, data1, data2, opts.Options, opts.Cache)
+	})
+}
+
+// loadPair returns the contents of both images of a pair, or the context's
+// error once ctx is done.
+func loadPair(ctx context.Context, pair Pair) ([]byte, []byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	data1, err := pairData(pair.Data1, pair.Path1)
+	if err != nil {
+		return nil, nil, err
+	}
+	data2, err := pairData(pair.Data2, pair.Path2)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data1, data2, nil
+}
+
+// pairData returns data if set and otherwise reads path.
+func pairData(data []byte, path string) ([]byte, error) {
+	if data != nil {
+		return data, nil
+	}
+	if path == "" {
+		return nil, fmt.Errorf("pair has neither data nor path")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,144 @@
+package psnr
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestComputeBatch(t *testing.T) {
+	original, err := os.ReadFile("testdata/test_original.jpg")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+
+	pairs := []Pair{
+		{Path1: "testdata/test_original.jpg", Path2: "testdata/quality_50.jpg"},
+		{Data1: original, Data2: original},
+		{Path1: "testdata/test_original.jpg", Path2: "testdata/missing.jpg"},
+		{Path1: "testdata/size1.jpg", Path2: "testdata/size2.jpg"},
+		{Path1: "testdata/test_image.png", Path2: "testdata/test_image_q95.jpg"},
+	}
+
+	results := ComputeBatch(context.Background(), pairs, BatchOptions{Workers: 2})
+	if len(results) != len(pairs) {
+		t.Fatalf("Expected %d results, got %d", len(pairs), len(results))
+	}
+
+	for i, r := range results {
+		if r.Index != i {
+			t.Errorf("Result %d has index %d", i, r.Index)
+		}
+	}
+
+	if results[0].Err != nil {
+		t.Errorf("Unexpected error for pair 0: %v", results[0].Err)
+	} else if want, _ := ComputeFiles(pairs[0].Path1, pairs[0].Path2); results[0].Result.PSNR != want {
+		t.Errorf("Pair 0: expected %.6f, got %.6f", want, results[0].Result.PSNR)
+	}
+	if results[1].Err != nil || !math.IsInf(results[1].Result.PSNR, 1) {
+		t.Errorf("Expected Inf for identical pair, got %+v", results[1])
+	}
+	if results[2].Err == nil || results[3].Err == nil {
+		t.Error("Expected per-pair errors for missing file and size mismatch")
+	}
+	if results[4].Err != nil {
+		t.Errorf("Expected the batch to continue after errors, got %v", results[4].Err)
+	}
+	if summary := SummarizeBatch(results); summary != (BatchSummary{Pairs: 5, Succeeded: 3, Failed: 2}) {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+}
+
+func TestComputeBatchFailFast(t *testing.T) {
+	pairs := []Pair{
+		{Path1: "testdata/test_original.jpg", Path2: "testdata/missing.jpg"},
+		{Path1: "testdata/test_original.jpg", Path2: "testdata/quality_50.jpg"},
+		{Path1: "testdata/test_original.jpg", Path2: "testdata/quality_50.jpg"},
+	}
+
+	results := ComputeBatch(context.Background(), pairs, BatchOptions{Workers: 1, FailFast: true})
+	if results[0].Err == nil || errors.Is(results[0].Err, ErrBatchAborted) {
+		t.Errorf("Expected the failing pair to keep its error, got %v", results[0].Err)
+	}
+	for _, r := range results[1:] {
+		if !errors.Is(r.Err, ErrBatchAborted) {
+			t.Errorf("Expected ErrBatchAborted for pair %d, got %v", r.Index, r.Err)
+		}
+	}
+	if summary := SummarizeBatch(results); summary != (BatchSummary{Pairs: 3, Failed: 1, Skipped: 2}) {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+}
+
+func TestComputeBatchStream(t *testing.T) {
+	pairs := make([]Pair, 6)
+	for i := range pairs {
+		pairs[i] = Pair{Path1: "testdata/test_original.jpg", Path2: "testdata/quality_50.jpg"}
+	}
+
+	seen := make(map[int]bool)
+	for r := range ComputeBatchStream(context.Background(), pairs, BatchOptions{Workers: 3}) {
+		if r.Err != nil {
+			t.Errorf("Unexpected error for pair %d: %v", r.Index, r.Err)
+		}
+		if seen[r.Index] {
+			t.Errorf("Pair %d reported twice", r.Index)
+		}
+		seen[r.Index] = true
+	}
+	if len(seen) != len(pairs) {
+		t.Errorf("Expected %d results, got %d", len(pairs), len(seen))
+	}
+}
+
+func TestComputeBatchCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	pairs := []Pair{{Path1: "testdata/test_original.jpg", Path2: "testdata/quality_50.jpg"}}
+	results := ComputeBatch(ctx, pairs, BatchOptions{})
+	if !errors.Is(results[0].Err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", results[0].Err)
+	}
+	if summary := SummarizeBatch(results); summary.Skipped != 1 {
+		t.Errorf("Expected the pair to be skipped, got %+v", summary)
+	}
+
+	if results := ComputeBatch(context.Background(), nil, BatchOptions{}); len(results) != 0 {
+		t.Errorf("Expected no results for an empty batch, got %d", len(results))
+	}
+}
+
+func TestComputeBatchStreamCanceledNoLeak(t *testing.T) {
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	pairs := make([]Pair, 100)
+	stream := batchStream(ctx, pairs, 4, false, func(context.Context, Pair) (*Result, error) {
+		return &Result{}, nil
+	})
+
+	// Read one result, then abandon the stream
+	<-stream
+	cancel()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the workers to exit, %d goroutines left of %d", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// ComputeBatch still reports every pair
+	results := ComputeBatch(ctx, []Pair{{Path1: "testdata/test_original.jpg", Path2: "testdata/quality_50.jpg"}, {}}, BatchOptions{})
+	for i, r := range results {
+		if r.Index != i || !errors.Is(r.Err, context.Canceled) {
+			t.Errorf("Expected pair %d to report context.Canceled, got %+v", i, r)
+		}
+	}
+}
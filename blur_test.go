@@ -0,0 +1,57 @@
+package psnr
+
+import (
+	"image"
+	"math/rand"
+	"testing"
+)
+
+func TestWithGaussianBlur(t *testing.T) {
+	// The same gradient with independent noise in each image
+	rng := rand.New(rand.NewSource(1))
+	img1 := image.NewGray(image.Rect(0, 0, 64, 64))
+	img2 := image.NewGray(img1.Rect)
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			base := 64 + 2*x
+			img1.Pix[y*64+x] = uint8(base + rng.Intn(21) - 10)
+			img2.Pix[y*64+x] = uint8(base + rng.Intn(21) - 10)
+		}
+	}
+	data1, data2 := encodePNG(t, img1), encodePNG(t, img2)
+
+	raw, err := ComputeDetailed(data1, data2)
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	blurred, err := ComputeDetailed(data1, data2, WithGaussianBlur(1.5))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	t.Logf("raw PSNR = %.2f dB, blurred PSNR = %.2f dB", raw.PSNR, blurred.PSNR)
+	if blurred.PSNR < raw.PSNR+6 {
+		t.Errorf("Expected the blur to suppress noise, got %.2f dB vs %.2f dB", blurred.PSNR, raw.PSNR)
+	}
+	if blurred.BlurSigma != 1.5 || raw.BlurSigma != 0 {
+		t.Errorf("Expected the sigma to be reported, got %g and %g", blurred.BlurSigma, raw.BlurSigma)
+	}
+
+	for _, sigma := range []float64{-1, 17} {
+		if _, err := ComputeDetailed(data1, data2, WithGaussianBlur(sigma)); err == nil {
+			t.Errorf("Expected error for sigma %g", sigma)
+		}
+	}
+}
+
+func TestGaussianBlurKeepsFlatImages(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 7, 5))
+	for i := range img.Pix {
+		img.Pix[i] = 77
+	}
+	blurred := gaussianBlur(img, 2)
+	for i, v := range blurred.Pix {
+		if v != 77 {
+			t.Fatalf("Expected 77 at %d, got %d", i, v)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package psnr
+
+import (
+	"image"
+)
+
+const (
+	// borderTolerance is the largest difference per sample from the color of
+	// a border at which a pixel still belongs to it, so compression noise in
+	// the bars does not end them early.
+	borderTolerance = 24
+	// borderOutliers is the fraction of the pixels of a row or column that
+	// may differ from the border color while it still counts as border.
+	borderOutliers = 0.01
+)
+
+// Margins holds the width in pixels of the border along each edge of an
+// image.
+type Margins struct {
+	Top, Right, Bottom, Left int
+}
+
+// IsZero reports whether all margins are zero.
+func (m Margins) IsZero() bool {
+	return m == Margins{}
+}
+
+// union returns the larger margin of m and n along each edge.
+func (m Margins) union(n Margins) Margins {
+	return Margins{max(m.Top, n.Top), max(m.Right, n.Right), max(m.Bottom, n.Bottom), max(m.Left, n.Left)}
+}
+
+// inset returns r shrunk by the margins.
+func (m Margins) inset(r image.Rectangle) image.Rectangle {
+	return image.Rect(r.Min.X+m.Left, r.Min.Y+m.Top, r.Max.X-m.Right, r.Max.Y-m.Bottom)
+}
+
+// DetectBorders measures the uniform bars along the edges of img, such as the
+// black bars of a letterboxed or pillarboxed video frame. Each edge is
+// scanned inwards for rows or columns of the color of its outermost line,
+// which may be any color, not just black. A bar must end at a line that is
+// not uniform, so gradients and stripes are not mistaken for bars. An image
+// of one color has no borders.
+func DetectBorders(img image.Image) Margins {
+	rgba := toRGBA(img)
+	width, height := rgba.Rect.Dx(), rgba.Rect.Dy()
+	if width == 0 || height == 0 {
+		return Margins{}
+	}
+
+	pixel := func(x, y int) []uint8 {
+		return rgba.Pix[rgba.PixOffset(x, y):][:4]
+	}
+	// line reports whether count pixels from (x, y) stepping by (dx, dy)
+	// match the color ref
+	line := func(x, y, dx, dy, count int, ref []uint8) bool {
+		allowed := int(float64(count) * borderOutliers)
+		for i := 0; i < count; i++ {
+			p := pixel(x+i*dx, y+i*dy)
+			for c := range p {
+				if abs(int(p[c])-int(ref[c])) > borderTolerance {
+					allowed--
+					break
+				}
+			}
+			if allowed < 0 {
+				return false
+			}
+		}
+		return true
+	}
+	// scan counts the border lines from one edge, stopping before limit.
+	// Line i starts at start(i) and runs count pixels along (dx, dy).
+	scan := func(limit int, start func(i int) (int, int), dx, dy, count int) int {
+		x, y := start(0)
+		ref := pixel(x, y)
+		n := 0
+		for n < limit {
+			if x, y := start(n); !line(x, y, dx, dy, count, ref) {
+				break
+			}
+			n++
+		}
+		if n > 0 && n < limit {
+			// A uniform line of another color past the bar continues a
+			// gradient or stripes
+			if x, y := start(n); line(x, y, dx, dy, count, pixel(x, y)) {
+				return 0
+			}
+		}
+		return n
+	}
+
+	var m Margins
+	m.Top = scan(height, func(i int) (int, int) { return 0, i }, 1, 0, width)
+	if m.Top == height {
+		return Margins{}
+	}
+	m.Bottom = scan(height-m.Top, func(i int) (int, int) { return 0, height - 1 - i }, 1, 0, width)
+	inner := height - m.Top - m.Bottom
+	m.Left = scan(width, func(i int) (int, int) { return i, m.Top }, 0, 1, inner)
+	m.Right = scan(width-m.Left, func(i int) (int, int) { return width - 1 - i, m.Top }, 0, 1, inner)
+	if m.Left+m.Right >= width {
+		return Margins{}
+	}
+	return m
+}
+
+// cropBorders removes the borders detected in either image. Images of the
+// same size are both cut to the area inside the borders of both, so they
+// stay aligned; images of different sizes each lose their own borders. The
+// margins detected in the first and second image are returned.
+func cropBorders(d1, d2 *decoded) (*decoded, *decoded, []Margins) {
+	m1, m2 := DetectBorders(d1.img), DetectBorders(d2.img)
+	crop1, crop2 := m1, m2
+	if d1.img.Bounds().Size() == d2.img.Bounds().Size() {
+		crop1 = m1.union(m2)
+		crop2 = crop1
+		if inner := crop1.inset(d1.img.Bounds()); inner.Dx() <= 0 || inner.Dy() <= 0 {
+			// The bars of one image cover the content of the other
+			crop1, crop2 = Margins{}, Margins{}
+		}
+	}
+
+	crop := func(d *decoded, m Margins) *decoded {
+		if m.IsZero() {
+			return d
+		}
+		return d.withImage(subImage(d.img, m.inset(d.img.Bounds())))
+	}
+	return crop(d1, crop1), crop(d2, crop2), []Margins{m1, m2}
+}
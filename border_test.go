@@ -0,0 +1,110 @@
+package psnr
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+)
+
+// boxed returns content framed by bars of color c with the given margins.
+func boxed(content *image.RGBA, m Margins, c color.Color) *image.RGBA {
+	size := content.Rect.Size().Add(image.Pt(m.Left+m.Right, m.Top+m.Bottom))
+	img := SolidImage(image.Rectangle{Max: size}, c)
+	draw.Draw(img, content.Rect.Add(image.Pt(m.Left, m.Top)), content, image.Point{}, draw.Src)
+	return img
+}
+
+// framed returns content centered on a background whose rows are filled
+// with the colors of row, extending 8 rows above and below the content.
+func framed(content *image.RGBA, row func(y int) color.Color) *image.RGBA {
+	size := content.Rect.Size().Add(image.Pt(16, 16))
+	img := image.NewRGBA(image.Rectangle{Max: size})
+	for y := 0; y < size.Y; y++ {
+		draw.Draw(img, image.Rect(0, y, size.X, y+1), image.NewUniform(row(y)), image.Point{}, draw.Src)
+	}
+	draw.Draw(img, content.Rect.Add(image.Pt(8, 8)), content, image.Point{}, draw.Src)
+	return img
+}
+
+func TestDetectBorders(t *testing.T) {
+	content := orientationTestImage(40, 24)
+	tests := []struct {
+		name string
+		img  image.Image
+		want Margins
+	}{
+		{"letterbox", boxed(content, Margins{Top: 6, Bottom: 6}, color.Black), Margins{Top: 6, Bottom: 6}},
+		{"pillarbox", boxed(content, Margins{Left: 5, Right: 3}, color.White), Margins{Left: 5, Right: 3}},
+		{"noisy bars", NoisyImage(boxed(content, Margins{Top: 4, Right: 2, Bottom: 4, Left: 2}, color.Gray{16}), 3, 1), Margins{Top: 4, Right: 2, Bottom: 4, Left: 2}},
+		{"none", content, Margins{}},
+		{"gradient", framed(content, func(y int) color.Color { return color.Gray{uint8(y * 4)} }), Margins{}},
+		{"stripes", framed(content, func(y int) color.Color { return color.Gray{uint8(y % 2 * 64)} }), Margins{}},
+		{"solid", SolidImage(image.Rect(0, 0, 10, 10), color.Black), Margins{}},
+	}
+	for _, tt := range tests {
+		if got := DetectBorders(tt.img); got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWithBorderCrop(t *testing.T) {
+	ctx := context.Background()
+	content := orientationTestImage(40, 24)
+	black := boxed(content, Margins{Top: 6, Bottom: 6}, color.Black)
+	gray := boxed(content, Margins{Top: 6, Bottom: 6}, color.Gray{40})
+
+	plain, err := CompareImages(ctx, black, gray)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.IsInf(plain.PSNR, 1) || plain.Borders != nil {
+		t.Errorf("Expected the bars to differ without cropping, got %+v", plain)
+	}
+
+	result, err := CompareImages(ctx, black, gray, WithBorderCrop())
+	if err != nil {
+		t.Fatalf("CompareImages failed: %v", err)
+	}
+	if !math.IsInf(result.PSNR, 1) {
+		t.Errorf("Expected +Inf after cropping the bars, got %v", result.PSNR)
+	}
+	want := []Margins{{Top: 6, Bottom: 6}, {Top: 6, Bottom: 6}}
+	if len(result.Borders) != 2 || result.Borders[0] != want[0] || result.Borders[1] != want[1] {
+		t.Errorf("Expected margins %+v, got %+v", want, result.Borders)
+	}
+
+	// Images of different sizes each lose their own bars
+	result, err = CompareImages(ctx, content, black, WithBorderCrop())
+	if err != nil {
+		t.Fatalf("CompareImages failed: %v", err)
+	}
+	if !math.IsInf(result.PSNR, 1) || !result.Borders[0].IsZero() {
+		t.Errorf("Expected +Inf with no bars in the first image, got %+v", result)
+	}
+}
+
+func TestWithBorderCropStraight(t *testing.T) {
+	ctx := context.Background()
+	content1, content2 := translucentPair(40, 24)
+	letterbox := func(content *image.NRGBA) *image.NRGBA {
+		img := image.NewNRGBA(image.Rect(0, 0, 40, 36))
+		draw.Draw(img, img.Rect, image.Black, image.Point{}, draw.Src)
+		draw.Draw(img, content.Rect.Add(image.Pt(0, 6)), content, image.Point{}, draw.Src)
+		return img
+	}
+	want, err := CompareImages(ctx, content1, content2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := CompareImages(ctx, letterbox(content1), letterbox(content2), WithBorderCrop())
+	if err != nil {
+		t.Fatalf("CompareImages failed: %v", err)
+	}
+	if result.Borders[0] != (Margins{Top: 6, Bottom: 6}) || result.PSNR != want.PSNR {
+		t.Errorf("Expected the content compared straight at %v dB, got %+v at %v dB", want.PSNR, result.Borders, result.PSNR)
+	}
+}
@@ -0,0 +1,94 @@
+package psnr
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"sync"
+)
+
+// memoryBudget throttles the comparisons of a batch so the estimated
+// footprint of the images decoded at once stays under a limit. It is soft:
+// a pair larger than the whole budget still runs, alone.
+type memoryBudget struct {
+	mu    sync.Mutex
+	limit int64
+	used  int64
+	// freed is closed and replaced whenever memory is released.
+	freed chan struct{}
+}
+
+// newMemoryBudget returns a budget of limit bytes, or nil for no budget.
+func newMemoryBudget(limit int64) *memoryBudget {
+	if limit <= 0 {
+		return nil
+	}
+	return &memoryBudget{limit: limit, freed: make(chan struct{})}
+}
+
+// acquire waits until n bytes fit in the budget, or returns the context's
+// error once ctx is done.
+func (b *memoryBudget) acquire(ctx context.Context, n int64) error {
+	for {
+		b.mu.Lock()
+		if b.used == 0 || b.used+n <= b.limit {
+			b.used += n
+			b.mu.Unlock()
+			return nil
+		}
+		freed := b.freed
+		b.mu.Unlock()
+
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// release returns n bytes to the budget and wakes the waiting comparisons.
+func (b *memoryBudget) release(n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.used -= n
+	close(b.freed)
+	b.freed = make(chan struct{})
+}
+
+// compareWithin runs compare once the estimated footprint of comparing data1
+// with data2 fits in the budget. A pair whose footprint cannot be estimated
+// takes the whole budget and runs alone. A nil budget runs compare
+// immediately.
+func (b *memoryBudget) compareWithin(ctx context.Context, data1, data2 []byte, compare func() (*Result, error)) (*Result, error) {
+	if b == nil {
+		return compare()
+	}
+	n, ok := pairFootprint(data1, data2)
+	if !ok {
+		n = max(n, b.limit)
+	}
+	if err := b.acquire(ctx, n); err != nil {
+		return nil, err
+	}
+	defer b.release(n)
+	return compare()
+}
+
+// pairFootprint estimates the bytes held while comparing two encoded images
+// from their headers: each decoded image plus its 8-bit RGBA working copy.
+// It returns false when a header cannot be parsed, such as that of a RAW
+// file handled by a sniffed backend, leaving that image out of the estimate.
+func pairFootprint(data1, data2 []byte) (int64, bool) {
+	var total int64
+	known := true
+	for _, data := range [][]byte{data1, data2} {
+		config, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			known = false
+			continue
+		}
+		total += decodeMemory(config) + int64(config.Width)*int64(config.Height)*4
+	}
+	return total, known
+}
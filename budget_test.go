@@ -0,0 +1,102 @@
+package psnr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMemoryBudget(t *testing.T) {
+	ctx := context.Background()
+	b := newMemoryBudget(100)
+	if err := b.acquire(ctx, 60); err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan error)
+	go func() { acquired <- b.acquire(ctx, 60) }()
+	select {
+	case <-acquired:
+		t.Fatal("Expected the second acquire to wait for the budget")
+	case <-time.After(20 * time.Millisecond):
+	}
+	b.release(60)
+	if err := <-acquired; err != nil {
+		t.Fatalf("Expected the second acquire to succeed, got %v", err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := b.acquire(canceled, 60); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled while waiting, got %v", err)
+	}
+	b.release(60)
+
+	// A pair larger than the whole budget runs alone
+	if err := b.acquire(ctx, 500); err != nil {
+		t.Errorf("Expected an oversized acquire on an idle budget to succeed, got %v", err)
+	}
+
+	if newMemoryBudget(0) != nil || newMemoryBudget(-1) != nil {
+		t.Error("Expected no budget for a non-positive limit")
+	}
+}
+
+func TestPairFootprint(t *testing.T) {
+	data, err := os.ReadFile("testdata/test_original.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	one := decodeMemory(config) + int64(config.Width)*int64(config.Height)*4
+	if got, ok := pairFootprint(data, data); got != 2*one || !ok {
+		t.Errorf("Expected %d bytes, got %d (%v)", 2*one, got, ok)
+	}
+	if got, ok := pairFootprint(data, []byte("not an image")); got != one || ok {
+		t.Errorf("Expected an unparsable image to be reported and left out, got %d (%v)", got, ok)
+	}
+
+	// A pair that cannot be estimated waits for the whole budget
+	ctx := context.Background()
+	b := newMemoryBudget(2 * one)
+	if err := b.acquire(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	ran := make(chan struct{})
+	go b.compareWithin(ctx, data, []byte("not an image"), func() (*Result, error) {
+		close(ran)
+		return nil, nil
+	})
+	select {
+	case <-ran:
+		t.Fatal("Expected the unestimated pair to wait for the budget")
+	case <-time.After(20 * time.Millisecond):
+	}
+	b.release(1)
+	<-ran
+}
+
+func TestComputeBatchMemoryBudget(t *testing.T) {
+	pairs := make([]Pair, 4)
+	for i := range pairs {
+		pairs[i] = Pair{Path1: "testdata/test_original.jpg", Path2: "testdata/quality_50.jpg"}
+	}
+	want, err := ComputeFiles(pairs[0].Path1, pairs[0].Path2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A one-byte budget runs the pairs one at a time
+	for _, r := range ComputeBatch(context.Background(), pairs, BatchOptions{Workers: 4, MemoryBudget: 1}) {
+		if r.Err != nil || r.Result.PSNR != want {
+			t.Errorf("Unexpected result for pair %d: %+v", r.Index, r)
+		}
+	}
+}
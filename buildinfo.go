@@ -0,0 +1,82 @@
+package psnr
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// modulePath is the path of this module in the build information.
+const modulePath = "github.com/ideamans/go-psnr"
+
+// Build describes how the library was built, for bug reports and logs.
+type Build struct {
+	// Version is the module version recorded in the binary, such as
+	// "v1.4.0", "(devel)" when built inside the module or from a local
+	// replacement, or "unknown".
+	Version string
+	// GoVersion is the Go release that built the binary.
+	GoVersion string
+	// Cgo reports whether cgo was enabled. The pixel kernels are pure Go
+	// either way; the cgo-backed decoders and the PDF renderer need it.
+	Cgo bool
+	// SIMD is the vector instruction set used by the pixel kernels: "none",
+	// as they are portable Go on every platform.
+	SIMD string
+	// Decoders lists the decoder backends registered with RegisterDecoder,
+	// e.g. "libjpeg" in builds with the libjpeg tag.
+	Decoders []string
+	// Metrics lists the registered metrics.
+	Metrics []string
+	// PDF reports whether ComparePDFs has a renderer.
+	PDF bool
+	// WebP reports whether WebPEncoder has an encoder.
+	WebP bool
+	// AVIF reports whether AVIFEncoder has an encoder.
+	AVIF bool
+}
+
+// BuildInfo reports the library version and the backends compiled in.
+func BuildInfo() Build {
+	return Build{
+		Version:   moduleVersion(),
+		GoVersion: runtime.Version(),
+		Cgo:       cgoEnabled,
+		SIMD:      "none",
+		Decoders:  RegisteredDecoders(),
+		Metrics:   RegisteredMetrics(),
+		PDF:       openPDF != nil,
+		WebP:      encodeWebP != nil,
+		AVIF:      encodeAVIF != nil,
+	}
+}
+
+// moduleVersion returns the version of this module from the build
+// information of the running binary.
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	return versionFrom(info)
+}
+
+// versionFrom returns the version of this module recorded in info. A
+// replacement by a local directory has no version, so it counts as a
+// development build.
+func versionFrom(info *debug.BuildInfo) string {
+	if info.Main.Path == modulePath {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			if dep.Replace == nil {
+				return dep.Version
+			}
+			if dep.Replace.Version == "" {
+				return "(devel)"
+			}
+			return dep.Replace.Version
+		}
+	}
+	return "unknown"
+}
@@ -0,0 +1,48 @@
+package psnr
+
+import (
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"testing"
+)
+
+func TestBuildInfo(t *testing.T) {
+	info := BuildInfo()
+	if info.Version == "" || info.GoVersion != runtime.Version() || info.SIMD != "none" {
+		t.Errorf("Unexpected build info: %+v", info)
+	}
+	if !slices.Equal(info.Decoders, RegisteredDecoders()) || !slices.Equal(info.Metrics, RegisteredMetrics()) {
+		t.Errorf("Expected the registered backends, got %+v", info)
+	}
+	if info.PDF != (openPDF != nil) {
+		t.Errorf("Expected PDF %v, got %v", openPDF != nil, info.PDF)
+	}
+	if info.WebP != (encodeWebP != nil) {
+		t.Errorf("Expected WebP %v, got %v", encodeWebP != nil, info.WebP)
+	}
+	if info.AVIF != (encodeAVIF != nil) {
+		t.Errorf("Expected AVIF %v, got %v", encodeAVIF != nil, info.AVIF)
+	}
+}
+
+func TestVersionFrom(t *testing.T) {
+	dep := func(replace *debug.Module) *debug.BuildInfo {
+		return &debug.BuildInfo{Deps: []*debug.Module{{Path: modulePath, Version: "v1.4.0", Replace: replace}}}
+	}
+	tests := []struct {
+		info *debug.BuildInfo
+		want string
+	}{
+		{&debug.BuildInfo{Main: debug.Module{Path: modulePath, Version: "(devel)"}}, "(devel)"},
+		{dep(nil), "v1.4.0"},
+		{dep(&debug.Module{Path: "example.com/fork", Version: "v1.4.1"}), "v1.4.1"},
+		{dep(&debug.Module{Path: "../go-psnr"}), "(devel)"},
+		{&debug.BuildInfo{}, "unknown"},
+	}
+	for _, tt := range tests {
+		if got := versionFrom(tt.info); got != tt.want {
+			t.Errorf("Expected %q, got %q", tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package psnr
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// cacheVersion is part of every cache key, so results stored by an
+// incompatible release are never returned.
+const cacheVersion = "psnr-cache-v1"
+
+// Cache stores encoded results for ComputeBatch, ComputeBatchStream and
+// CompareDirs. Keys are hex strings derived from the contents of both images
+// and the comparison options, so a hit is valid regardless of file names or
+// timestamps. Implementations must be safe for concurrent use; Get reports
+// false for a missing key.
+type Cache interface {
+	Get(key string) ([]byte, bool, error)
+	Set(key string, value []byte) error
+}
+
+// FileCache is a Cache that keeps one file per result under a directory.
+type FileCache struct {
+	dir string
+}
+
+// NewFileCache returns a FileCache storing results under dir, which is
+// created on first write.
+func NewFileCache(dir string) *FileCache {
+	return &FileCache{dir: dir}
+}
+
+// Get reads the result stored for key.
+func (c *FileCache) Get(key string) ([]byte, bool, error) {
+	data, err := os.ReadFile(c.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// Set stores value for key. The file is written under a temporary name and
+// renamed, so concurrent readers never see a partial result.
+func (c *FileCache) Set(key string, value []byte) error {
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// path spreads entries over subdirectories named after the key prefix.
+func (c *FileCache) path(key string) string {
+	if len(key) < 2 {
+		return filepath.Join(c.dir, key)
+	}
+	return filepath.Join(c.dir, key[:2], key)
+}
+
+// computeCached is ComputeContext backed by cache. Only successful results
+// are stored, and cache failures fall back to computing the result. Options
+// that cannot be fingerprinted, such as a saliency map or a preprocessing
+// function, bypass the cache. Cache hits report the work of the lookup in
+// Result.Stats rather than that of the stored comparison.
+func computeCached(ctx context.Context, data1, data2 []byte, opts []Option, cache Cache) (*Result, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return computeCachedWith(ctx, data1, data2, o, cache)
+}
+
+// computeCachedWith is computeCached with validated options.
+func computeCachedWith(ctx context.Context, data1, data2 []byte, o *options, cache Cache) (*Result, error) {
+	if cache == nil {
+		return computeWith(ctx, data1, data2, o)
+	}
+	fingerprint, ok := o.fingerprint()
+	if !ok {
+		return computeWith(ctx, data1, data2, o)
+	}
+	var allocated uint64
+	if o.stats {
+		allocated = allocatedBytes()
+	}
+	start := time.Now()
+	key := cacheKey(data1, data2, fingerprint)
+
+	if value, found, err := cache.Get(key); err == nil && found {
+		var result Result
+		if err := gob.NewDecoder(bytes.NewReader(value)).Decode(&result); err == nil {
+			o.debug("cache hit", "key", key)
+			if o.stats {
+				result.Stats = cachedStats(result.Stats, time.Since(start), allocatedBytes()-allocated)
+			}
+			return &result, nil
+		}
+	}
+
+	result, err := computeWith(ctx, data1, data2, o)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(result); err == nil {
+		if err := cache.Set(key, buf.Bytes()); err != nil {
+			o.debug("cache write failed", "key", key, "error", err)
+		}
+	}
+	return result, nil
+}
+
+// cacheKey hashes both images and the options fingerprint into a key.
+func cacheKey(data1, data2 []byte, fingerprint string) string {
+	hash1 := sha256.Sum256(data1)
+	hash2 := sha256.Sum256(data2)
+	h := sha256.New()
+	h.Write([]byte(cacheVersion))
+	h.Write(hash1[:])
+	h.Write(hash2[:])
+	h.Write([]byte(fingerprint))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// fingerprint describes every setting that can change a result, and
+// reports false when a setting cannot be described by value. Logging,
+// progress reporting and the timeout do not affect results and are left out.
+func (o *options) fingerprint() (string, bool) {
+	if o.saliency != nil || o.preprocess != nil {
+		return "", false
+	}
+	var hash, background, hdr, toneMap, ignore string
+	if o.hashFilter != nil {
+		hash = fmt.Sprintf("%d/%d", o.hashFilter.kind, o.hashFilter.maxDistance)
+	}
+	if o.background != nil {
+		r, g, b, _ := o.background.RGBA()
+		background = fmt.Sprintf("%04x%04x%04x", r, g, b)
+	}
+	if o.hdr != nil {
+		hdr = fmt.Sprintf("%+v", *o.hdr)
+	}
+	if o.toneMap != nil {
+		toneMap = fmt.Sprintf("%+v", *o.toneMap)
+	}
+	if o.ignoreColor != nil {
+		ignore = fmt.Sprintf("%+v", *o.ignoreColor)
+	}
+	return fmt.Sprintf("shift=%d normalize=%t edge=%t spherical=%t metrics=%q compat=%d decoder=%q matrix=%d range=%d peak=%d/%g deterministic=%t limits=%+v tolerant=%t hash=%s diff=%t histogram=%t components=%t blur=%g crop=%t flatten=%s alpha=%d dither=%d colorspace=%d hdr=%s tonemap=%s metadata=%t aa=%d ignore=%s regions=%+v inputs=%t eval=%d orientation=%t borders=%t frequency=%g strict=%t stats=%t",
+		o.maxShift, o.normalize, o.edgeWeighting, o.spherical, o.metrics, o.compat, o.decoderName,
+		o.matrix, o.colorRange, o.peak.kind, o.peak.value, o.deterministic, o.limits, o.tolerant, hash, o.diff, o.histogram, o.components, o.blurSigma, o.cropSearch, background, o.alphaMode, o.ditherBox, o.colorSpace, hdr, toneMap, o.metadata, o.antiAliasing, ignore, o.regions, o.inputInfo, o.evalLongEdge, o.orientation, o.borderCrop, o.frequency, o.strict, o.stats), true
+}
+
+// cachedStats returns the Stats of a result served from the cache: the
+// lookup's time and allocations under the "cached" path, keeping the pixel
+// count of the stored comparison.
+func cachedStats(stored *Stats, elapsed time.Duration, allocated uint64) *Stats {
+	stats := &Stats{Path: pathCached, CompareTime: elapsed, AllocatedBytes: allocated}
+	if stored != nil {
+		stats.Pixels = stored.Pixels
+	}
+	if seconds := elapsed.Seconds(); seconds > 0 {
+		stats.PixelsPerSecond = float64(stats.Pixels) / seconds
+	}
+	return stats
+}
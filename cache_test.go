@@ -0,0 +1,103 @@
+package psnr
+
+import (
+	"context"
+	"image"
+	"math"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// countingCache wraps a Cache and counts hits and writes.
+type countingCache struct {
+	Cache
+	mu           sync.Mutex
+	hits, writes int
+}
+
+func (c *countingCache) Get(key string) ([]byte, bool, error) {
+	value, found, err := c.Cache.Get(key)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if found {
+		c.hits++
+	}
+	return value, found, err
+}
+
+func (c *countingCache) Set(key string, value []byte) error {
+	c.mu.Lock()
+	c.writes++
+	c.mu.Unlock()
+	return c.Cache.Set(key, value)
+}
+
+func TestBatchCache(t *testing.T) {
+	pairs := []Pair{
+		{Path1: "testdata/test_original.jpg", Path2: "testdata/quality_50.jpg"},
+		{Path1: "testdata/test_original.jpg", Path2: "testdata/test_original.jpg"},
+		{Path1: "testdata/size1.jpg", Path2: "testdata/size2.jpg"},
+	}
+	cache := &countingCache{Cache: NewFileCache(t.TempDir())}
+
+	first := ComputeBatch(context.Background(), pairs, BatchOptions{Cache: cache})
+	if cache.hits != 0 || cache.writes != 2 {
+		t.Errorf("Expected 0 hits and 2 writes on the first run, got %d and %d", cache.hits, cache.writes)
+	}
+
+	second := ComputeBatch(context.Background(), pairs, BatchOptions{Cache: cache})
+	if cache.hits != 2 || cache.writes != 2 {
+		t.Errorf("Expected 2 hits and no new writes on the second run, got %d and %d", cache.hits, cache.writes)
+	}
+	if first[0].Err != nil || second[0].Err != nil || !reflect.DeepEqual(first[0].Result, second[0].Result) {
+		t.Errorf("Expected the cached result to match, got %+v and %+v", first[0], second[0])
+	}
+	if second[1].Err != nil || !math.IsInf(second[1].Result.PSNR, 1) {
+		t.Errorf("Expected a cached Inf, got %+v", second[1])
+	}
+	if second[2].Err == nil {
+		t.Error("Expected errors to be recomputed rather than cached")
+	}
+
+	// Different options are a different key
+	ComputeBatch(context.Background(), pairs[:1], BatchOptions{Cache: cache, Options: []Option{WithNormalization()}})
+	if cache.hits != 2 || cache.writes != 3 {
+		t.Errorf("Expected a miss for new options, got %d hits and %d writes", cache.hits, cache.writes)
+	}
+
+	// Options without a fingerprint bypass the cache
+	saliency := image.NewGray(image.Rect(0, 0, 1, 1))
+	ComputeBatch(context.Background(), pairs[:1], BatchOptions{Cache: cache, Options: []Option{WithSaliencyMap(saliency)}})
+	if cache.writes != 3 {
+		t.Errorf("Expected a saliency map to bypass the cache, got %d writes", cache.writes)
+	}
+
+	// Cache hits report the lookup, not the stored comparison
+	stats := []Option{WithStats()}
+	computed := ComputeBatch(context.Background(), pairs[:1], BatchOptions{Cache: cache, Options: stats})
+	cached := ComputeBatch(context.Background(), pairs[:1], BatchOptions{Cache: cache, Options: stats})
+	if computed[0].Err != nil || cached[0].Err != nil {
+		t.Fatalf("Unexpected errors: %v, %v", computed[0].Err, cached[0].Err)
+	}
+	if st := cached[0].Result.Stats; st == nil || st.Path != pathCached || st.DecodeTime != 0 || st.Pixels != computed[0].Result.Stats.Pixels {
+		t.Errorf("Expected cached stats, got %+v", cached[0].Result.Stats)
+	}
+	if cached[0].Result.PSNR != computed[0].Result.PSNR {
+		t.Errorf("Expected the cached PSNR %f, got %f", computed[0].Result.PSNR, cached[0].Result.PSNR)
+	}
+}
+
+func TestFileCache(t *testing.T) {
+	cache := NewFileCache(t.TempDir())
+	if _, found, err := cache.Get("abcdef"); found || err != nil {
+		t.Errorf("Expected a miss, got %v, %v", found, err)
+	}
+	if err := cache.Set("abcdef", []byte("value")); err != nil {
+		t.Fatalf("Error writing cache: %v", err)
+	}
+	value, found, err := cache.Get("abcdef")
+	if err != nil || !found || string(value) != "value" {
+		t.Errorf("Expected the stored value, got %q, %v, %v", value, found, err)
+	}
+}
@@ -0,0 +1,30 @@
+package psnr
+
+import "bytes"
+
+// cameraRawFormat is the format name reported for camera RAW files.
+const cameraRawFormat = "raw"
+
+// cameraRawSignatures are the leading bytes of camera RAW containers. DNG,
+// CR2, NEF, ARW and most others are TIFF files; ORF and RW2 use
+// TIFF variants with their own magic numbers.
+var cameraRawSignatures = [][]byte{
+	[]byte("II*\x00"),          // little-endian TIFF (DNG, CR2, NEF, ARW, PEF…)
+	[]byte("MM\x00*"),          // big-endian TIFF (DNG, NEF from some bodies)
+	[]byte("IIRO"),             // Olympus ORF
+	[]byte("IIRS"),             // Olympus ORF
+	[]byte("MMOR"),             // Olympus ORF
+	[]byte("IIU\x00"),          // Panasonic RW2
+	[]byte("FUJIFILMCCD-RAW "), // Fujifilm RAF
+}
+
+// isCameraRaw reports whether data starts like a camera RAW file. Canon CR3
+// is an ISO base media file and is recognized by its "crx " brand.
+func isCameraRaw(data []byte) bool {
+	for _, signature := range cameraRawSignatures {
+		if bytes.HasPrefix(data, signature) {
+			return true
+		}
+	}
+	return len(data) >= 12 && bytes.Equal(data[4:12], []byte("ftypcrx "))
+}
@@ -0,0 +1,61 @@
+package psnr
+
+import (
+	"image"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestIsCameraRaw(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want bool
+	}{
+		{"DNG/CR2/NEF", "II*\x00\x08\x00\x00\x00", true},
+		{"big-endian TIFF", "MM\x00*\x00\x00\x00\x08", true},
+		{"ORF", "IIRO\x08\x00\x00\x00", true},
+		{"RW2", "IIU\x00\x08\x00\x00\x00", true},
+		{"RAF", "FUJIFILMCCD-RAW 0201", true},
+		{"CR3", "\x00\x00\x00\x18ftypcrx \x00\x00\x00\x01", true},
+		{"JPEG", "\xff\xd8\xff\xe0", false},
+		{"PNG", "\x89PNG\r\n\x1a\n", false},
+		{"HEIF", "\x00\x00\x00\x18ftypheic", false},
+	}
+	for _, tt := range tests {
+		if got := isCameraRaw([]byte(tt.data)); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestSniffedDecoder(t *testing.T) {
+	// A fake RAW developer that renders every file as a black 4x4 image
+	registerSniffedDecoder("test_raw", cameraRawFormat, isCameraRaw, func(data []byte) (image.Image, error) {
+		return image.NewRGBA(image.Rect(0, 0, 4, 4)), nil
+	})
+	raw := []byte("II*\x00 not really a DNG")
+	black := encodePNG(t, image.NewGray(image.Rect(0, 0, 4, 4)))
+
+	result, err := ComputeDetailed(raw, black, WithDecoder("test_raw"), WithCompatibility(CompatibilityImageMagick))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if !math.IsInf(result.PSNR, 1) {
+		t.Errorf("Expected Inf against a black PNG, got %f", result.PSNR)
+	}
+
+	// Other formats keep using the standard decoders
+	jpeg, err := os.ReadFile("testdata/test_original.jpg")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+	if _, err := ComputeDetailed(jpeg, jpeg, WithDecoder("test_raw"), WithNormalization()); err != nil {
+		t.Errorf("Expected JPEGs to decode normally, got %v", err)
+	}
+
+	if _, err := ComputeDetailed(raw, black); err == nil {
+		t.Error("Expected RAW files to fail without the decoder")
+	}
+}
@@ -0,0 +1,43 @@
+package psnr
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// ignoreColor is the configuration of WithIgnoreColor.
+type ignoreColor struct {
+	key       color.RGBA
+	tolerance int
+}
+
+// validate reports an invalid tolerance.
+func (k *ignoreColor) validate() error {
+	if k == nil {
+		return nil
+	}
+	if k.tolerance < 0 || k.tolerance > 255 {
+		return fmt.Errorf("invalid ignore color tolerance: %d", k.tolerance)
+	}
+	return nil
+}
+
+// keyMask marks the pixels of the region r of img whose premultiplied
+// samples are all within the tolerance of the key, in row-major order.
+func keyMask(img *image.RGBA, r image.Rectangle, k *ignoreColor) []bool {
+	key := [4]uint8{k.key.R, k.key.G, k.key.B, k.key.A}
+	width := r.Dx()
+	mask := make([]bool, width*r.Dy())
+	for y := 0; y < r.Dy(); y++ {
+		row := img.Pix[img.PixOffset(r.Min.X, r.Min.Y+y):]
+		for x := 0; x < width; x++ {
+			matches := true
+			for c := 0; c < 4 && matches; c++ {
+				matches = absDiff(row[x*4+c], key[c]) <= uint8(k.tolerance)
+			}
+			mask[y*width+x] = matches
+		}
+	}
+	return mask
+}
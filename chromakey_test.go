@@ -0,0 +1,63 @@
+package psnr
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+)
+
+func TestWithIgnoreColor(t *testing.T) {
+	ctx := context.Background()
+	magenta := color.RGBA{255, 0, 255, 255}
+	// A template with a placeholder area and a rendering that fills it
+	template := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	draw.Draw(template, template.Rect, image.White, image.Point{}, draw.Src)
+	draw.Draw(template, image.Rect(2, 2, 6, 6), image.NewUniform(magenta), image.Point{}, draw.Src)
+	template.SetRGBA(5, 5, color.RGBA{250, 4, 255, 255})
+	rendered := image.NewRGBA(template.Rect)
+	draw.Draw(rendered, rendered.Rect, image.White, image.Point{}, draw.Src)
+	draw.Draw(rendered, image.Rect(2, 2, 6, 6), image.Black, image.Point{}, draw.Src)
+
+	result, err := CompareImages(ctx, template, rendered, WithIgnoreColor(magenta, 5))
+	if err != nil {
+		t.Fatalf("CompareImages failed: %v", err)
+	}
+	if !math.IsInf(result.PSNR, 1) || result.IgnoredPixels != 16 {
+		t.Errorf("Expected the placeholder to be ignored, got %v dB and %d pixels", result.PSNR, result.IgnoredPixels)
+	}
+
+	// Without tolerance the off-key pixel is compared
+	result, err = CompareImages(ctx, template, rendered, WithIgnoreColor(magenta, 0))
+	if err != nil {
+		t.Fatalf("CompareImages failed: %v", err)
+	}
+	if result.IgnoredPixels != 15 {
+		t.Errorf("Expected 15 ignored pixels, got %d", result.IgnoredPixels)
+	}
+	if want := (250.0*250 + 4*4 + 255*255) / (85 * 3); math.Abs(result.MSE-want) > 1e-9 {
+		t.Errorf("Expected MSE %v over the remaining pixels, got %v", want, result.MSE)
+	}
+
+	plain, err := CompareImages(ctx, template, rendered)
+	if err != nil {
+		t.Fatalf("CompareImages failed: %v", err)
+	}
+	if math.IsInf(plain.PSNR, 1) || plain.IgnoredPixels != 0 {
+		t.Errorf("Expected the placeholder to be compared by default, got %v dB", plain.PSNR)
+	}
+
+	// A template that is all placeholder leaves nothing to measure
+	key := SolidImage(template.Rect, magenta)
+	if _, err := CompareImages(ctx, key, rendered, WithIgnoreColor(magenta, 0)); err == nil {
+		t.Error("Expected error with every pixel ignored")
+	}
+
+	for _, tolerance := range []int{-1, 256} {
+		if _, err := CompareImages(ctx, template, rendered, WithIgnoreColor(magenta, tolerance)); err == nil {
+			t.Errorf("Expected error for tolerance %d", tolerance)
+		}
+	}
+}
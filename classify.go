@@ -0,0 +1,96 @@
+package psnr
+
+import "math"
+
+// Label is a categorical interpretation of a quality score for readers who
+// are not familiar with dB values.
+type Label string
+
+// Labels from best to worst.
+const (
+	LabelExcellent  Label = "excellent"
+	LabelGood       Label = "good"
+	LabelAcceptable Label = "acceptable"
+	LabelPoor       Label = "poor"
+)
+
+// Breakpoints are the lowest scores that still earn each label; anything
+// below Acceptable is LabelPoor.
+type Breakpoints struct {
+	Excellent  float64
+	Good       float64
+	Acceptable float64
+}
+
+// label returns the label a score earns.
+func (b Breakpoints) label(score float64) Label {
+	switch {
+	case score >= b.Excellent:
+		return LabelExcellent
+	case score >= b.Good:
+		return LabelGood
+	case score >= b.Acceptable:
+		return LabelAcceptable
+	default:
+		return LabelPoor
+	}
+}
+
+// Classifier maps PSNR and, optionally, SSIM values to labels.
+type Classifier struct {
+	// PSNR holds the breakpoints in dB.
+	PSNR Breakpoints
+	// SSIM holds the breakpoints on SSIM's 0-1 scale.
+	SSIM Breakpoints
+}
+
+// DefaultClassifier uses breakpoints common for lossy web images: 40 dB and
+// above is visually indistinguishable for most content, while below 30 dB
+// artifacts are usually obvious.
+var DefaultClassifier = Classifier{
+	PSNR: Breakpoints{Excellent: 40, Good: 35, Acceptable: 30},
+	SSIM: Breakpoints{Excellent: 0.98, Good: 0.95, Acceptable: 0.90},
+}
+
+// Classify returns the label of a PSNR value. Identical images (+Inf) are
+// LabelExcellent and NaN is LabelPoor.
+func (c Classifier) Classify(psnr float64) Label {
+	if math.IsNaN(psnr) {
+		return LabelPoor
+	}
+	return c.PSNR.label(psnr)
+}
+
+// ClassifyWithSSIM returns the worse of the labels earned by a PSNR and an
+// SSIM value of the same pair, so a good score on one cannot hide a poor
+// score on the other.
+func (c Classifier) ClassifyWithSSIM(psnr, ssim float64) Label {
+	byPSNR := c.Classify(psnr)
+	bySSIM := LabelPoor
+	if !math.IsNaN(ssim) {
+		bySSIM = c.SSIM.label(ssim)
+	}
+	if labelRank(bySSIM) < labelRank(byPSNR) {
+		return bySSIM
+	}
+	return byPSNR
+}
+
+// Classify returns the label of a PSNR value using DefaultClassifier.
+func Classify(psnr float64) Label {
+	return DefaultClassifier.Classify(psnr)
+}
+
+// labelRank orders labels from poor (0) to excellent (3).
+func labelRank(l Label) int {
+	switch l {
+	case LabelExcellent:
+		return 3
+	case LabelGood:
+		return 2
+	case LabelAcceptable:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,49 @@
+package psnr
+
+import (
+	"math"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		psnr float64
+		want Label
+	}{
+		{math.Inf(1), LabelExcellent},
+		{40, LabelExcellent},
+		{39.99, LabelGood},
+		{35, LabelGood},
+		{32, LabelAcceptable},
+		{29.9, LabelPoor},
+		{math.NaN(), LabelPoor},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.psnr); got != tt.want {
+			t.Errorf("Classify(%f) = %s, want %s", tt.psnr, got, tt.want)
+		}
+	}
+
+	strict := Classifier{PSNR: Breakpoints{Excellent: 50, Good: 45, Acceptable: 40}}
+	if got := strict.Classify(42); got != LabelAcceptable {
+		t.Errorf("Expected custom breakpoints to apply, got %s", got)
+	}
+}
+
+func TestClassifyWithSSIM(t *testing.T) {
+	tests := []struct {
+		psnr, ssim float64
+		want       Label
+	}{
+		{45, 0.99, LabelExcellent},
+		{45, 0.96, LabelGood},
+		{31, 0.99, LabelAcceptable},
+		{45, 0.5, LabelPoor},
+		{45, math.NaN(), LabelPoor},
+	}
+	for _, tt := range tests {
+		if got := DefaultClassifier.ClassifyWithSSIM(tt.psnr, tt.ssim); got != tt.want {
+			t.Errorf("ClassifyWithSSIM(%f, %f) = %s, want %s", tt.psnr, tt.ssim, got, tt.want)
+		}
+	}
+}
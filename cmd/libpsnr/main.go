@@ -0,0 +1,75 @@
+// Command libpsnr builds a C shared library exposing PSNR computation, so
+// C, C++, Python or Rust callers can link the Go implementation directly:
+//
+//	go build -buildmode=c-shared -o libpsnr.so ./cmd/libpsnr
+//
+// See psnr.h for the interface.
+package main
+
+/*
+#include "psnr.h"
+*/
+import "C"
+
+import (
+	"errors"
+	"io/fs"
+	"unsafe"
+
+	psnr "github.com/ideamans/go-psnr"
+)
+
+// statusMessages are allocated once and never freed, so callers may keep
+// the pointers returned by psnr_status_string.
+var statusMessages = []*C.char{
+	C.CString("ok"),
+	C.CString("invalid argument"),
+	C.CString("failed to read input file"),
+	C.CString("failed to decode or compare images"),
+}
+
+//export psnr_compute
+func psnr_compute(buf1 *C.uint8_t, len1 C.size_t, buf2 *C.uint8_t, len2 C.size_t, out *C.double) C.int {
+	if buf1 == nil || buf2 == nil || out == nil {
+		return C.PSNR_EINVAL
+	}
+
+	// The slices alias C memory and must not outlive this call
+	data1 := unsafe.Slice((*byte)(unsafe.Pointer(buf1)), int(len1))
+	data2 := unsafe.Slice((*byte)(unsafe.Pointer(buf2)), int(len2))
+
+	value, err := psnr.Compute(data1, data2)
+	if err != nil {
+		return C.PSNR_ECOMPUTE
+	}
+	*out = C.double(value)
+	return C.PSNR_OK
+}
+
+//export psnr_compute_files
+func psnr_compute_files(path1, path2 *C.char, out *C.double) C.int {
+	if path1 == nil || path2 == nil || out == nil {
+		return C.PSNR_EINVAL
+	}
+
+	value, err := psnr.ComputeFiles(C.GoString(path1), C.GoString(path2))
+	if err != nil {
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			return C.PSNR_EIO
+		}
+		return C.PSNR_ECOMPUTE
+	}
+	*out = C.double(value)
+	return C.PSNR_OK
+}
+
+//export psnr_status_string
+func psnr_status_string(status C.int) *C.char {
+	if status > 0 || status < C.PSNR_ECOMPUTE {
+		return nil
+	}
+	return statusMessages[-status]
+}
+
+func main() {}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"math"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	psnr "github.com/ideamans/go-psnr"
+)
+
+func TestCSharedLibrary(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping c-shared build in short mode")
+	}
+	if runtime.GOOS != "linux" {
+		t.Skip("the C example build is only wired up for Linux")
+	}
+	if out, err := exec.Command("go", "env", "CGO_ENABLED").Output(); err != nil || strings.TrimSpace(string(out)) != "1" {
+		t.Skip("c-shared builds need cgo")
+	}
+	cc, err := exec.LookPath("cc")
+	if err != nil {
+		t.Skip("no C compiler available")
+	}
+
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "libpsnr.so")
+	if out, err := exec.Command("go", "build", "-buildmode=c-shared", "-o", lib, ".").CombinedOutput(); err != nil {
+		t.Fatalf("Failed to build shared library: %v\n%s", err, out)
+	}
+
+	bin := filepath.Join(dir, "example")
+	build := exec.Command(cc, "-o", bin, "testdata/example.c", "-I.", "-L"+dir, "-lpsnr", "-Wl,-rpath,"+dir)
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to build C example: %v\n%s", err, out)
+	}
+
+	file1 := "../../testdata/test_original.jpg"
+	file2 := "../../testdata/quality_50.jpg"
+	out, err := exec.Command(bin, file1, file2).CombinedOutput()
+	if err != nil {
+		t.Fatalf("C example failed: %v\n%s", err, out)
+	}
+
+	got, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+	if err != nil {
+		t.Fatalf("Unexpected output %q: %v", out, err)
+	}
+	want, err := psnr.ComputeFiles(file1, file2)
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if math.Abs(got-want) > 1e-6 {
+		t.Errorf("C library returned %.6f, Go returned %.6f", got, want)
+	}
+}
@@ -0,0 +1,42 @@
+/*
+ * psnr.h - C interface to github.com/ideamans/go-psnr.
+ *
+ * Build the shared library with:
+ *
+ *     go build -buildmode=c-shared -o libpsnr.so ./cmd/libpsnr
+ *
+ * All functions return PSNR_OK on success or a negative status code. On
+ * success the PSNR in dB is stored in *out; identical images yield +Inf.
+ */
+#ifndef GO_PSNR_H
+#define GO_PSNR_H
+
+#include <stddef.h>
+#include <stdint.h>
+
+#define PSNR_OK 0
+/* A required pointer argument was NULL. */
+#define PSNR_EINVAL -1
+/* An input file could not be read. */
+#define PSNR_EIO -2
+/* The images could not be decoded or compared (e.g. different sizes). */
+#define PSNR_ECOMPUTE -3
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+/* psnr_compute compares two encoded images (JPEG or PNG) held in memory. */
+extern int psnr_compute(uint8_t* buf1, size_t len1, uint8_t* buf2, size_t len2, double* out);
+
+/* psnr_compute_files compares two image files given by NUL-terminated paths. */
+extern int psnr_compute_files(char* path1, char* path2, double* out);
+
+/* psnr_status_string returns a static description of a status code. */
+extern char* psnr_status_string(int status);
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif /* GO_PSNR_H */
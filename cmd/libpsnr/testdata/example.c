@@ -0,0 +1,33 @@
+#include <math.h>
+#include <stdio.h>
+#include "psnr.h"
+
+int main(int argc, char** argv) {
+	double value;
+	int status;
+
+	if (argc != 3) {
+		fprintf(stderr, "usage: %s <image1> <image2>\n", argv[0]);
+		return 2;
+	}
+
+	status = psnr_compute_files(argv[1], argv[2], &value);
+	if (status != PSNR_OK) {
+		fprintf(stderr, "error: %s\n", psnr_status_string(status));
+		return 1;
+	}
+	printf("%.6f\n", value);
+
+	status = psnr_compute_files(argv[1], "does-not-exist.png", &value);
+	if (status != PSNR_EIO) {
+		fprintf(stderr, "expected PSNR_EIO, got %d\n", status);
+		return 1;
+	}
+
+	status = psnr_compute(NULL, 0, NULL, 0, &value);
+	if (status != PSNR_EINVAL) {
+		fprintf(stderr, "expected PSNR_EINVAL, got %d\n", status);
+		return 1;
+	}
+	return 0;
+}
@@ -0,0 +1,189 @@
+// Command psnr computes PSNR between two images.
+//
+// Usage:
+//
+//	psnr [-json] [-metadata] [-inputs] [-stats] <image1> <image2>
+//	psnr [-json] [-metadata] [-inputs] [-stats] <dir1> <dir2>
+//	psnr serve --stdio
+//	psnr -version
+//
+// Results include a label (excellent, good, acceptable or poor) from
+// psnr.Classify. With -metadata they also report which EXIF, XMP and ICC
+// metadata the second image dropped, added or changed. With -inputs they
+// describe both inputs: format, dimensions, bit depth, color model, JPEG
+// chroma subsampling and size in bytes.
+//
+// Given two directories, psnr compares every JPEG and PNG file in the first
+// with the file at the same relative path in the second and prints one line
+// per file (one JSON object per line with -json).
+//
+// In serve mode, psnr reads newline-delimited JSON jobs such as
+// {"id": 1, "a": "a.png", "b": "b.png"} from stdin and writes one JSON
+// result line per job to stdout, so a parent process can keep a single warm
+// process instead of spawning the CLI per pair.
+//
+// -version prints the library version and the backends compiled in, as
+// reported by psnr.BuildInfo, for bug reports.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	psnr "github.com/ideamans/go-psnr"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command line and returns the process exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 0 && args[0] == "serve" {
+		return runServe(args[1:], stdin, stdout, stderr)
+	}
+	return runCompare(args, stdout, stderr)
+}
+
+func runCompare(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("psnr", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	jsonOutput := fs.Bool("json", false, "print the result as JSON")
+	metadata := fs.Bool("metadata", false, "report dropped, added and changed EXIF, XMP and ICC metadata")
+	inputs := fs.Bool("inputs", false, "report the format, dimensions, bit depth, color model, subsampling and size of both inputs")
+	stats := fs.Bool("stats", false, "report decode and compare times, decoders, kernel, throughput and bytes allocated")
+	version := fs.Bool("version", false, "print the library version and backends, then exit")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: psnr [-json] [-metadata] [-inputs] [-stats] <image1> <image2>\n       psnr [-json] [-metadata] [-inputs] [-stats] <dir1> <dir2>\n       psnr serve --stdio\n       psnr -version\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *version {
+		return printVersion(*jsonOutput, stdout, stderr)
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	var opts []psnr.Option
+	if *metadata {
+		opts = append(opts, psnr.WithMetadataDiff())
+	}
+	if *inputs {
+		opts = append(opts, psnr.WithInputInfo())
+	}
+	if *stats {
+		opts = append(opts, psnr.WithStats())
+	}
+
+	if isDir(fs.Arg(0)) && isDir(fs.Arg(1)) {
+		return runDirs(fs.Arg(0), fs.Arg(1), *jsonOutput, opts, stdout, stderr)
+	}
+
+	result := compareFiles(fs.Arg(0), fs.Arg(1), opts...)
+	if *jsonOutput {
+		if err := json.NewEncoder(stdout).Encode(result); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		if result.Error != "" {
+			return 1
+		}
+		return 0
+	}
+
+	if result.Error != "" {
+		fmt.Fprintln(stderr, result.Error)
+		return 1
+	}
+	fmt.Fprintln(stdout, result.text())
+	return 0
+}
+
+// runDirs compares two directory trees file by file.
+func runDirs(dir1, dir2 string, jsonOutput bool, opts []psnr.Option, stdout, stderr io.Writer) int {
+	results, err := psnr.CompareDirs(context.Background(), os.DirFS(dir1), os.DirFS(dir2), nil, psnr.BatchOptions{Options: opts})
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	code := 0
+	encoder := json.NewEncoder(stdout)
+	for _, r := range results {
+		out := newJSONResult(r.Result, r.Err)
+		out.File = r.Path
+		if out.Error != "" {
+			code = 1
+		}
+
+		switch {
+		case jsonOutput:
+			if err := encoder.Encode(out); err != nil {
+				fmt.Fprintln(stderr, err)
+				return 1
+			}
+		case out.Error != "":
+			fmt.Fprintf(stderr, "%s: %s\n", r.Path, out.Error)
+		default:
+			fmt.Fprintf(stdout, "%s: %s\n", r.Path, out.text())
+		}
+	}
+	return code
+}
+
+// isDir reports whether path names an existing directory.
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func runServe(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("psnr serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	stdio := fs.Bool("stdio", false, "serve newline-delimited JSON jobs over stdin/stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if !*stdio {
+		fmt.Fprintln(stderr, "psnr serve: only --stdio is supported")
+		return 2
+	}
+
+	if err := serve(stdin, stdout); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// printVersion prints psnr.BuildInfo as text or JSON.
+func printVersion(jsonOutput bool, stdout, stderr io.Writer) int {
+	info := psnr.BuildInfo()
+	if jsonOutput {
+		if err := json.NewEncoder(stdout).Encode(info); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		return 0
+	}
+	fmt.Fprintf(stdout, "psnr %s (%s, cgo %t, simd %s, pdf %t, webp %t, avif %t)\ndecoders: %s\nmetrics: %s\n",
+		info.Version, info.GoVersion, info.Cgo, info.SIMD, info.PDF, info.WebP, info.AVIF, names(info.Decoders), names(info.Metrics))
+	return 0
+}
+
+// names joins a list of backend names, or returns "none".
+func names(list []string) string {
+	if len(list) == 0 {
+		return "none"
+	}
+	return strings.Join(list, ", ")
+}
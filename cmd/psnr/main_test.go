@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	psnr "github.com/ideamans/go-psnr"
+)
+
+const (
+	testOriginal = "../../testdata/test_original.jpg"
+	testQuality  = "../../testdata/quality_50.jpg"
+)
+
+func TestRunCompare(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{testOriginal, testQuality}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if !strings.HasPrefix(stdout.String(), "PSNR: ") || !strings.HasSuffix(stdout.String(), ")\n") {
+		t.Errorf("Unexpected output: %q", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := run([]string{"-json", testOriginal, testOriginal}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	var result jsonResult
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		t.Fatalf("Invalid JSON output %q: %v", stdout.String(), err)
+	}
+	if !result.Identical || result.PSNR != nil || result.Label != psnr.LabelExcellent {
+		t.Errorf("Expected identical result, got %s", stdout.String())
+	}
+
+	if code := run([]string{testOriginal}, nil, &stdout, &stderr); code != 2 {
+		t.Errorf("Expected exit code 2 for missing argument, got %d", code)
+	}
+}
+
+func TestRunCompareMetadata(t *testing.T) {
+	data, err := os.ReadFile(testOriginal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Insert an EXIF segment after SOI
+	exif := []byte("\xff\xe1\x00\x0eExif\x00\x00MM\x00\x2a\x00\x00")
+	tagged := filepath.Join(t.TempDir(), "tagged.jpg")
+	if err := os.WriteFile(tagged, append(append(append([]byte{}, data[:2]...), exif...), data[2:]...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-metadata", tagged, testOriginal}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if got := stdout.String(); got != "PSNR: inf dB (excellent), dropped exif\n" {
+		t.Errorf("Unexpected output: %q", got)
+	}
+
+	stdout.Reset()
+	if code := run([]string{"-json", "-metadata", testOriginal, tagged}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	var result jsonResult
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		t.Fatalf("Invalid JSON output %q: %v", stdout.String(), err)
+	}
+	if result.Metadata == nil || len(result.Metadata.Added) != 1 || result.Metadata.Added[0] != psnr.MetadataEXIF {
+		t.Errorf("Expected added EXIF metadata, got %s", stdout.String())
+	}
+}
+
+func TestRunVersion(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-version"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if got := stdout.String(); !strings.HasPrefix(got, "psnr ") || !strings.Contains(got, "\ndecoders: ") {
+		t.Errorf("Unexpected output: %q", got)
+	}
+
+	stdout.Reset()
+	if code := run([]string{"-json", "-version"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	var info psnr.Build
+	if err := json.Unmarshal(stdout.Bytes(), &info); err != nil || info.GoVersion == "" {
+		t.Errorf("Invalid JSON output %q: %v", stdout.String(), err)
+	}
+}
+
+func TestServe(t *testing.T) {
+	input := strings.Join([]string{
+		`{"id": 1, "a": "` + testOriginal + `", "b": "` + testQuality + `"}`,
+		``,
+		`{"id": "same", "a": "` + testOriginal + `", "b": "` + testOriginal + `"}`,
+		`{"id": 3, "a": "missing.png", "b": "` + testOriginal + `"}`,
+		`{"id": 4, "a": "` + testOriginal + `"}`,
+		`not json`,
+	}, "\n")
+
+	var stdout bytes.Buffer
+	if err := serve(strings.NewReader(input), &stdout); err != nil {
+		t.Fatalf("serve failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("Expected 5 result lines, got %d:\n%s", len(lines), stdout.String())
+	}
+
+	results := make([]jsonResult, len(lines))
+	for i, line := range lines {
+		if err := json.Unmarshal([]byte(line), &results[i]); err != nil {
+			t.Fatalf("Invalid result line %q: %v", line, err)
+		}
+	}
+
+	if string(results[0].ID) != "1" || results[0].PSNR == nil || results[0].Error != "" {
+		t.Errorf("Unexpected first result: %s", lines[0])
+	}
+	if string(results[1].ID) != `"same"` || !results[1].Identical {
+		t.Errorf("Unexpected identical result: %s", lines[1])
+	}
+	if string(results[2].ID) != "3" || results[2].Error == "" {
+		t.Errorf("Expected read error, got %s", lines[2])
+	}
+	if string(results[3].ID) != "4" || results[3].Error == "" {
+		t.Errorf("Expected missing field error, got %s", lines[3])
+	}
+	if results[4].Error == "" {
+		t.Errorf("Expected parse error, got %s", lines[4])
+	}
+}
+
+func TestRunServeRequiresStdio(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"serve"}, strings.NewReader(""), &stdout, &stderr); code != 2 {
+		t.Errorf("Expected exit code 2 without --stdio, got %d", code)
+	}
+}
+
+func TestRunDirs(t *testing.T) {
+	dir1, dir2 := t.TempDir(), t.TempDir()
+	copyFile(t, testOriginal, filepath.Join(dir1, "a.jpg"))
+	copyFile(t, testOriginal, filepath.Join(dir2, "a.jpg"))
+	copyFile(t, testOriginal, filepath.Join(dir1, "b.jpg"))
+	copyFile(t, testQuality, filepath.Join(dir2, "b.jpg"))
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{dir1, dir2}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 2 || lines[0] != "a.jpg: PSNR: inf dB (excellent)" || !strings.HasPrefix(lines[1], "b.jpg: PSNR: ") {
+		t.Errorf("Unexpected output: %q", stdout.String())
+	}
+
+	copyFile(t, testOriginal, filepath.Join(dir1, "c.jpg"))
+	stdout.Reset()
+	if code := run([]string{"-json", dir1, dir2}, nil, &stdout, &stderr); code != 1 {
+		t.Errorf("Expected exit code 1 for a missing counterpart, got %d", code)
+	}
+	var results []jsonResult
+	decoder := json.NewDecoder(&stdout)
+	for decoder.More() {
+		var r jsonResult
+		if err := decoder.Decode(&r); err != nil {
+			t.Fatalf("Invalid JSON output: %v", err)
+		}
+		results = append(results, r)
+	}
+	if len(results) != 3 || results[2].File != "c.jpg" || results[2].Error == "" {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+}
+
+// copyFile copies src to dst for tests that build directory trees.
+func copyFile(t *testing.T, src, dst string) {
+	t.Helper()
+	data, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", src, err)
+	}
+	if err := os.WriteFile(dst, data, 0o644); err != nil {
+		t.Fatalf("Failed to write %s: %v", dst, err)
+	}
+}
+
+func TestRunCompareInputs(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-json", "-inputs", testOriginal, testQuality}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	var result jsonResult
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		t.Fatalf("Invalid JSON output %q: %v", stdout.String(), err)
+	}
+	info, err := os.Stat(testQuality)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Inputs) != 2 || result.Inputs[1].Format != "jpeg" || result.Inputs[1].Subsampling == "" || int64(result.Inputs[1].Bytes) != info.Size() {
+		t.Errorf("Unexpected inputs: %s", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := run([]string{"-inputs", testOriginal, testQuality}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), fmt.Sprintf("%d bytes\n", info.Size())) {
+		t.Errorf("Expected the input sizes in %q", stdout.String())
+	}
+}
+
+func TestRunCompareStats(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-json", "-stats", testOriginal, testQuality}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	var result jsonResult
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		t.Fatalf("Invalid JSON output %q: %v", stdout.String(), err)
+	}
+	if st := result.Stats; st == nil || st.Decoders[1] == "" || st.Path == "" || st.Pixels == 0 {
+		t.Errorf("Unexpected stats: %s", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := run([]string{"-stats", testOriginal, testQuality}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "bytes allocated") {
+		t.Errorf("Expected the stats in %q", stdout.String())
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	psnr "github.com/ideamans/go-psnr"
+)
+
+// jsonResult is the JSON representation of one comparison. JSON cannot
+// represent +Inf, so identical images omit psnr and set identical instead.
+type jsonResult struct {
+	ID        json.RawMessage `json:"id,omitempty"`
+	File      string          `json:"file,omitempty"`
+	PSNR      *float64        `json:"psnr,omitempty"`
+	MSE       *float64        `json:"mse,omitempty"`
+	Identical bool            `json:"identical,omitempty"`
+	Label     psnr.Label      `json:"label,omitempty"`
+	Metadata  *jsonMetadata   `json:"metadata,omitempty"`
+	Inputs    []jsonInput     `json:"inputs,omitempty"`
+	Warnings  []jsonWarning   `json:"warnings,omitempty"`
+	Stats     *jsonStats      `json:"stats,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// jsonMetadata is the JSON representation of a psnr.MetadataDiff.
+type jsonMetadata struct {
+	Dropped []string `json:"dropped,omitempty"`
+	Added   []string `json:"added,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+	Kept    []string `json:"kept,omitempty"`
+}
+
+// jsonInput is the JSON representation of a psnr.InputInfo.
+type jsonInput struct {
+	Format      string `json:"format"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	BitDepth    int    `json:"bit_depth,omitempty"`
+	ColorModel  string `json:"color_model"`
+	Subsampling string `json:"subsampling,omitempty"`
+	Quality     int    `json:"quality,omitempty"`
+	PaletteSize int    `json:"palette_size,omitempty"`
+	Bytes       int    `json:"bytes"`
+}
+
+// jsonWarning is the JSON representation of a psnr.Warning.
+type jsonWarning struct {
+	Code    psnr.WarningCode `json:"code"`
+	Message string           `json:"message"`
+}
+
+// jsonStats is the JSON representation of a psnr.Stats, with times in
+// milliseconds.
+type jsonStats struct {
+	DecodeMS        float64   `json:"decode_ms"`
+	CompareMS       float64   `json:"compare_ms"`
+	Decoders        [2]string `json:"decoders"`
+	Path            string    `json:"path"`
+	Pixels          int       `json:"pixels"`
+	PixelsPerSecond float64   `json:"pixels_per_second"`
+	AllocatedBytes  uint64    `json:"allocated_bytes"`
+}
+
+// compareFiles compares two image files and converts the outcome, including
+// any error, into a jsonResult.
+func compareFiles(path1, path2 string, opts ...psnr.Option) *jsonResult {
+	return newJSONResult(psnr.ComputeFilesDetailed(path1, path2, opts...))
+}
+
+// newJSONResult converts the outcome of a comparison into a jsonResult.
+func newJSONResult(result *psnr.Result, err error) *jsonResult {
+	if err != nil {
+		return &jsonResult{Error: err.Error()}
+	}
+
+	out := &jsonResult{MSE: &result.MSE, Label: psnr.Classify(result.PSNR)}
+	if m := result.Metadata; m != nil {
+		out.Metadata = &jsonMetadata{Dropped: m.Dropped, Added: m.Added, Changed: m.Changed, Kept: m.Kept}
+	}
+	for _, in := range result.Inputs {
+		out.Inputs = append(out.Inputs, jsonInput(in))
+	}
+	for _, w := range result.Warnings {
+		out.Warnings = append(out.Warnings, jsonWarning(w))
+	}
+	if st := result.Stats; st != nil {
+		out.Stats = &jsonStats{
+			DecodeMS:        milliseconds(st.DecodeTime),
+			CompareMS:       milliseconds(st.CompareTime),
+			Decoders:        st.Decoders,
+			Path:            st.Path,
+			Pixels:          st.Pixels,
+			PixelsPerSecond: st.PixelsPerSecond,
+			AllocatedBytes:  st.AllocatedBytes,
+		}
+	}
+	if math.IsInf(result.PSNR, 1) {
+		out.Identical = true
+	} else {
+		out.PSNR = &result.PSNR
+	}
+	return out
+}
+
+// text formats a successful result for the plain-text output.
+func (r *jsonResult) text() string {
+	text := fmt.Sprintf("PSNR: inf dB (%s)", r.Label)
+	if !r.Identical {
+		text = fmt.Sprintf("PSNR: %.2f dB (%s)", *r.PSNR, r.Label)
+	}
+	if m := r.Metadata; m != nil {
+		for _, group := range []struct {
+			name  string
+			kinds []string
+		}{{"dropped", m.Dropped}, {"added", m.Added}, {"changed", m.Changed}} {
+			if len(group.kinds) > 0 {
+				text += fmt.Sprintf(", %s %s", group.name, strings.Join(group.kinds, " "))
+			}
+		}
+	}
+	if len(r.Inputs) == 2 {
+		text += fmt.Sprintf(", %s vs %s", r.Inputs[0].text(), r.Inputs[1].text())
+	}
+	if st := r.Stats; st != nil {
+		text += fmt.Sprintf(", decode %.1f ms, compare %.1f ms (%s, %.1f Mpx/s), %d bytes allocated",
+			st.DecodeMS, st.CompareMS, st.Path, st.PixelsPerSecond/1e6, st.AllocatedBytes)
+	}
+	return text
+}
+
+// milliseconds converts d to fractional milliseconds.
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// text formats an input description, e.g. "jpeg 640x480 8-bit ycbcr 4:2:0
+// q85 51234 bytes".
+func (in jsonInput) text() string {
+	fields := []string{in.Format, fmt.Sprintf("%dx%d", in.Width, in.Height)}
+	if in.BitDepth > 0 {
+		fields = append(fields, fmt.Sprintf("%d-bit", in.BitDepth))
+	}
+	fields = append(fields, in.ColorModel)
+	if in.Subsampling != "" {
+		fields = append(fields, in.Subsampling)
+	}
+	if in.Quality > 0 {
+		fields = append(fields, fmt.Sprintf("q%d", in.Quality))
+	}
+	if in.PaletteSize > 0 {
+		fields = append(fields, fmt.Sprintf("%d-color", in.PaletteSize))
+	}
+	return strings.Join(append(fields, fmt.Sprintf("%d bytes", in.Bytes)), " ")
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// maxJobLineSize bounds a single job line; jobs reference files by path, so
+// lines are short.
+const maxJobLineSize = 1 << 20
+
+// job is one comparison request read in serve mode.
+type job struct {
+	ID json.RawMessage `json:"id"`
+	A  string          `json:"a"`
+	B  string          `json:"b"`
+}
+
+// serve reads newline-delimited JSON jobs from r and writes one result line
+// per job to w, flushing after each so the parent sees results immediately.
+// Malformed jobs produce an error line rather than stopping the loop.
+func serve(r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxJobLineSize)
+	out := bufio.NewWriter(w)
+	enc := json.NewEncoder(out)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var j job
+		var result *jsonResult
+		if err := json.Unmarshal(line, &j); err != nil {
+			result = &jsonResult{Error: fmt.Sprintf("invalid job: %v", err)}
+		} else if j.A == "" || j.B == "" {
+			result = &jsonResult{ID: j.ID, Error: `invalid job: "a" and "b" are required`}
+		} else {
+			result = compareFiles(j.A, j.B)
+			result.ID = j.ID
+		}
+
+		if err := enc.Encode(result); err != nil {
+			return err
+		}
+		if err := out.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
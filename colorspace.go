@@ -0,0 +1,99 @@
+package psnr
+
+import (
+	"image"
+	"math"
+)
+
+// ColorMatrix is the matrix used to convert Y'CbCr images to RGB before
+// comparison.
+type ColorMatrix int
+
+const (
+	// MatrixBT601 is the SD matrix that JPEG (JFIF) mandates and
+	// image/jpeg assumes.
+	MatrixBT601 ColorMatrix = iota
+	// MatrixBT709 is the HD matrix.
+	MatrixBT709
+	// MatrixBT2020 is the UHD non-constant-luminance matrix.
+	MatrixBT2020
+)
+
+// ColorRange is the quantization range of Y'CbCr samples.
+type ColorRange int
+
+const (
+	// RangeFull uses all code values 0-255 (JPEG, "pc" range).
+	RangeFull ColorRange = iota
+	// RangeLimited uses 16-235 for luma and 16-240 for chroma (video, "tv"
+	// range).
+	RangeLimited
+)
+
+// lumaCoefficients returns Kr and Kb of the matrix.
+func (m ColorMatrix) lumaCoefficients() (kr, kb float64) {
+	switch m {
+	case MatrixBT709:
+		return 0.2126, 0.0722
+	case MatrixBT2020:
+		return 0.2627, 0.0593
+	default:
+		return 0.299, 0.114
+	}
+}
+
+// convertsYCbCr reports whether Y'CbCr images need a conversion other than
+// the standard library's BT.601 full-range one.
+func (o *options) convertsYCbCr() bool {
+	return o.matrix != MatrixBT601 || o.colorRange != RangeFull
+}
+
+// convertYCbCr returns d with a Y'CbCr image converted to RGB using the
+// configured matrix and range; other images are returned unchanged.
+func convertYCbCr(d *decoded, o *options) *decoded {
+	img, ok := d.img.(*image.YCbCr)
+	if !ok {
+		return d
+	}
+	return d.withImage(ycbcrToRGBA(img, o.matrix, o.colorRange))
+}
+
+// ycbcrToRGBA converts img to RGB with the given matrix and range.
+func ycbcrToRGBA(img *image.YCbCr, m ColorMatrix, r ColorRange) *image.RGBA {
+	kr, kb := m.lumaCoefficients()
+	kg := 1 - kr - kb
+	crToR := 2 * (1 - kr)
+	cbToB := 2 * (1 - kb)
+	crToG := -crToR * kr / kg
+	cbToG := -cbToB * kb / kg
+
+	lumaOffset, lumaScale, chromaScale := 0.0, 1.0, 1.0
+	if r == RangeLimited {
+		lumaOffset, lumaScale, chromaScale = 16, 255.0/219, 255.0/224
+	}
+
+	bounds := img.Rect
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		row := dst.Pix[(y-bounds.Min.Y)*dst.Stride:]
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			yi := img.YOffset(x, y)
+			ci := img.COffset(x, y)
+			luma := (float64(img.Y[yi]) - lumaOffset) * lumaScale
+			cb := (float64(img.Cb[ci]) - 128) * chromaScale
+			cr := (float64(img.Cr[ci]) - 128) * chromaScale
+
+			i := (x - bounds.Min.X) * 4
+			row[i] = clampToByte(luma + crToR*cr)
+			row[i+1] = clampToByte(luma + cbToG*cb + crToG*cr)
+			row[i+2] = clampToByte(luma + cbToB*cb)
+			row[i+3] = 0xff
+		}
+	}
+	return dst
+}
+
+// clampToByte rounds v to the nearest 8-bit value.
+func clampToByte(v float64) uint8 {
+	return uint8(math.Max(0, math.Min(255, math.Round(v))))
+}
@@ -0,0 +1,99 @@
+package psnr
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"testing"
+)
+
+func TestYCbCrToRGBA(t *testing.T) {
+	tests := []struct {
+		name       string
+		matrix     ColorMatrix
+		colorRange ColorRange
+		y, cb, cr  uint8
+		want       [3]uint8
+	}{
+		{"BT.601 full gray", MatrixBT601, RangeFull, 128, 128, 128, [3]uint8{128, 128, 128}},
+		{"limited black", MatrixBT709, RangeLimited, 16, 128, 128, [3]uint8{0, 0, 0}},
+		{"limited white", MatrixBT709, RangeLimited, 235, 128, 128, [3]uint8{255, 255, 255}},
+		{"BT.709 limited red", MatrixBT709, RangeLimited, 63, 102, 240, [3]uint8{255, 0, 0}},
+		{"BT.709 full green", MatrixBT709, RangeFull, 182, 30, 12, [3]uint8{0, 255, 0}},
+		{"BT.2020 full blue", MatrixBT2020, RangeFull, 15, 255, 118, [3]uint8{0, 0, 255}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewYCbCr(image.Rect(0, 0, 1, 1), image.YCbCrSubsampleRatio444)
+			img.Y[0], img.Cb[0], img.Cr[0] = tt.y, tt.cb, tt.cr
+
+			got := ycbcrToRGBA(img, tt.matrix, tt.colorRange).Pix[:3]
+			for c := range got {
+				if d := int(got[c]) - int(tt.want[c]); d < -2 || d > 2 {
+					t.Errorf("Expected %v, got %v", tt.want, got)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestYCbCrToRGBAMatchesStandardLibrary(t *testing.T) {
+	img := image.NewYCbCr(image.Rect(0, 0, 16, 16), image.YCbCrSubsampleRatio420)
+	for i := range img.Y {
+		img.Y[i] = uint8(i * 7)
+	}
+	for i := range img.Cb {
+		img.Cb[i] = uint8(i * 13)
+		img.Cr[i] = uint8(255 - i*11)
+	}
+
+	// BT.601 full range is the standard library's own conversion, which
+	// differs only by fixed-point rounding
+	got := ycbcrToRGBA(img, MatrixBT601, RangeFull)
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			want := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+			c := got.RGBAAt(x, y)
+			for _, d := range []int{int(c.R) - int(want.R), int(c.G) - int(want.G), int(c.B) - int(want.B)} {
+				if d < -1 || d > 1 {
+					t.Fatalf("At (%d, %d): expected %v, got %v", x, y, want, c)
+				}
+			}
+		}
+	}
+}
+
+func TestWithColorMatrix(t *testing.T) {
+	data1, err := os.ReadFile("testdata/test_original.jpg")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+	data2, err := os.ReadFile("testdata/quality_50.jpg")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+
+	standard, err := ComputeDetailed(data1, data2)
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	result, err := ComputeDetailed(data1, data2, WithColorMatrix(MatrixBT709), WithColorRange(RangeLimited))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	t.Logf("BT.601 full: %.4f dB, BT.709 limited: %.4f dB", standard.PSNR, result.PSNR)
+
+	// Limited range expands the code values, which amplifies the error
+	if result.PSNR >= standard.PSNR {
+		t.Errorf("Expected lower PSNR in limited range, got %.4f >= %.4f", result.PSNR, standard.PSNR)
+	}
+
+	if _, err := ComputeDetailed(data1, data2, WithColorMatrix(ColorMatrix(42))); err == nil {
+		t.Error("Expected error for unknown color matrix")
+	}
+	if _, err := ComputeDetailed(data1, data2, WithColorRange(ColorRange(-1))); err == nil {
+		t.Error("Expected error for unknown color range")
+	}
+}
@@ -0,0 +1,112 @@
+package psnr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"os"
+)
+
+// Comparer compares images with a fixed configuration. The options are
+// applied, validated and the decoder backend resolved once by New instead
+// of on every call, and the configuration cannot change afterwards, so a
+// Comparer can be shared, e.g. injected into the services that use it.
+//
+// A Comparer is safe for concurrent use by multiple goroutines: every call
+// works on its own buffers and only reads the configuration. Callbacks
+// passed as options, such as WithProgress or WithPreprocess functions and
+// registered metrics, are then called concurrently too and must allow it.
+type Comparer struct {
+	o   *options
+	err error
+}
+
+// New returns a Comparer configured with opts. Invalid options are
+// reported by Err and returned by every comparison.
+func New(opts ...Option) *Comparer {
+	o, err := newOptions(opts)
+	return &Comparer{o: o, err: err}
+}
+
+// Err returns the error of invalid options passed to New, or nil.
+func (c *Comparer) Err() error {
+	return c.err
+}
+
+// Compute compares two encoded images like ComputeContext.
+func (c *Comparer) Compute(ctx context.Context, image1Bytes, image2Bytes []byte) (*Result, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return computeWith(ctx, image1Bytes, image2Bytes, c.o)
+}
+
+// ComputeFiles reads and compares two image files like
+// ComputeFilesDetailed.
+func (c *Comparer) ComputeFiles(ctx context.Context, path1, path2 string) (*Result, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	data1, err := os.ReadFile(path1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path1, err)
+	}
+	data2, err := os.ReadFile(path2)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path2, err)
+	}
+	return computeWith(ctx, data1, data2, c.o)
+}
+
+// CompareImages compares two decoded images like the CompareImages
+// function.
+func (c *Comparer) CompareImages(ctx context.Context, img1, img2 image.Image) (*Result, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return compareImagesWith(ctx, img1, img2, c.o)
+}
+
+// CompareFrames compares two frames returned by Decode like the
+// CompareFrames function.
+func (c *Comparer) CompareFrames(ctx context.Context, f1, f2 *Frame) (*Result, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return compareFramesWith(ctx, f1, f2, c.o)
+}
+
+// ComputeBatch compares all pairs on a bounded worker pool and returns the
+// results in input order like the ComputeBatch function, honoring the
+// workers, cache, FailFast and MemoryBudget settings of opts. The Comparer's
+// configuration applies to every pair, so opts.Options must be empty. As
+// comparisons share no state, throughput scales with the workers up to the
+// available CPUs.
+func (c *Comparer) ComputeBatch(ctx context.Context, pairs []Pair, opts BatchOptions) []BatchResult {
+	return collectBatch(ctx, len(pairs), c.ComputeBatchStream(ctx, pairs, opts))
+}
+
+// ComputeBatchStream is like ComputeBatch but delivers each result on the
+// returned channel as soon as it completes, in completion order, so progress
+// can be shown and failures acted on while the batch runs. Like the
+// ComputeBatchStream function, it stops early when ctx is canceled.
+func (c *Comparer) ComputeBatchStream(ctx context.Context, pairs []Pair, opts BatchOptions) <-chan BatchResult {
+	err := c.err
+	if err == nil && len(opts.Options) > 0 {
+		err = errors.New("BatchOptions.Options cannot be used with a Comparer")
+	}
+	budget := newMemoryBudget(opts.MemoryBudget)
+	return batchStream(ctx, pairs, opts.Workers, opts.FailFast, func(ctx context.Context, pair Pair) (*Result, error) {
+		if err != nil {
+			return nil, err
+		}
+		data1, data2, err := loadPair(ctx, pair)
+		if err != nil {
+			return nil, err
+		}
+		return budget.compareWithin(ctx, data1, data2, func() (*Result, error) {
+			return computeCachedWith(ctx, data1, data2, c.o, opts.Cache)
+		})
+	})
+}
@@ -0,0 +1,208 @@
+package psnr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestComparer(t *testing.T) {
+	ctx := context.Background()
+	c := New(WithComponentPSNR())
+	if err := c.Err(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data1, err := os.ReadFile("testdata/test_original.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data2, err := os.ReadFile("testdata/test_quality_85.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.Compute(ctx, data1, data2)
+	if err != nil {
+		t.Fatalf("Compute failed: %v", err)
+	}
+	want, err := ComputeDetailed(data1, data2, WithComponentPSNR())
+	if err != nil {
+		t.Fatalf("ComputeDetailed failed: %v", err)
+	}
+	if got.PSNR != want.PSNR || got.Components == nil {
+		t.Errorf("Expected %v dB with components, got %+v", want.PSNR, got)
+	}
+
+	files, err := c.ComputeFiles(ctx, "testdata/test_original.png", "testdata/test_quality_85.png")
+	if err != nil {
+		t.Fatalf("ComputeFiles failed: %v", err)
+	}
+	if files.PSNR != want.PSNR {
+		t.Errorf("Expected %v dB from files, got %v", want.PSNR, files.PSNR)
+	}
+	if _, err := c.ComputeFiles(ctx, "testdata/missing.png", "testdata/test_original.png"); err == nil {
+		t.Error("Expected error for a missing file")
+	}
+
+	img1 := image.NewGray(image.Rect(0, 0, 4, 4))
+	img2 := image.NewGray(image.Rect(0, 0, 4, 4))
+	img2.SetGray(0, 0, color.Gray{Y: 16})
+	images, err := c.CompareImages(ctx, img1, img2)
+	if err != nil {
+		t.Fatalf("CompareImages failed: %v", err)
+	}
+	if want := 10 * math.Log10(65025/16.0); math.Abs(images.PSNR-want) > 1e-9 {
+		t.Errorf("Expected %v dB, got %v", want, images.PSNR)
+	}
+}
+
+func TestComparerInvalidOptions(t *testing.T) {
+	c := New(WithAlignment(-1))
+	if c.Err() == nil {
+		t.Fatal("Expected error for invalid options")
+	}
+	if _, err := c.Compute(context.Background(), nil, nil); err != c.Err() {
+		t.Errorf("Expected the options error, got %v", err)
+	}
+	if _, err := c.CompareImages(context.Background(), image.NewGray(image.Rect(0, 0, 1, 1)), image.NewGray(image.Rect(0, 0, 1, 1))); err != c.Err() {
+		t.Errorf("Expected the options error, got %v", err)
+	}
+}
+
+func TestComparerConcurrent(t *testing.T) {
+	data1, err := os.ReadFile("testdata/test_original.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data2, err := os.ReadFile("testdata/test_quality_85.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := New(WithErrorHistogram(), WithRegions(Region{Label: "corner", Rect: image.Rect(0, 0, 8, 8)}))
+	want, err := c.Compute(context.Background(), data1, data2)
+	if err != nil {
+		t.Fatalf("Compute failed: %v", err)
+	}
+
+	// Many goroutines sharing one Comparer, as a service would
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := c.Compute(context.Background(), data1, data2)
+			if err == nil && (got.PSNR != want.PSNR || got.Regions[0] != want.Regions[0]) {
+				err = fmt.Errorf("got %v dB, want %v dB", got.PSNR, want.PSNR)
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Concurrent Compute failed: %v", err)
+		}
+	}
+
+	pairs := []Pair{{Data1: data1, Data2: data2}, {Path1: "testdata/missing.png", Path2: "testdata/test_original.png"}, {Data1: data2, Data2: data2}}
+	results := c.ComputeBatch(context.Background(), pairs, BatchOptions{Workers: 2})
+	if results[0].Err != nil || results[0].Result.PSNR != want.PSNR {
+		t.Errorf("Unexpected first result: %+v", results[0])
+	}
+	if results[1].Err == nil || results[1].Index != 1 {
+		t.Errorf("Expected an error for a missing file, got %+v", results[1])
+	}
+	if results[2].Err != nil || !math.IsInf(results[2].Result.PSNR, 1) {
+		t.Errorf("Unexpected third result: %+v", results[2])
+	}
+
+	invalid := New(WithAlignment(-1)).ComputeBatch(context.Background(), pairs[:1], BatchOptions{Workers: 1})
+	if invalid[0].Err == nil {
+		t.Error("Expected the options error for every pair")
+	}
+
+	seen := make(map[int]bool)
+	for r := range c.ComputeBatchStream(context.Background(), pairs, BatchOptions{Workers: 2}) {
+		if seen[r.Index] {
+			t.Errorf("Pair %d reported twice", r.Index)
+		}
+		seen[r.Index] = true
+		if (r.Err != nil) != (r.Index == 1) {
+			t.Errorf("Unexpected streamed result: %+v", r)
+		}
+	}
+	if len(seen) != len(pairs) {
+		t.Errorf("Expected %d streamed results, got %d", len(pairs), len(seen))
+	}
+}
+
+func TestComparerComputeBatchOptions(t *testing.T) {
+	pairs := []Pair{
+		{Path1: "testdata/test_original.jpg", Path2: "testdata/quality_50.jpg"},
+		{Path1: "testdata/missing.png", Path2: "testdata/test_original.png"},
+		{Path1: "testdata/test_original.jpg", Path2: "testdata/test_original.jpg"},
+	}
+	c := New(WithNormalization())
+	cache := &countingCache{Cache: NewFileCache(t.TempDir())}
+
+	first := c.ComputeBatch(context.Background(), pairs, BatchOptions{Cache: cache, MemoryBudget: 1})
+	second := c.ComputeBatch(context.Background(), pairs, BatchOptions{Cache: cache})
+	if cache.hits != 2 || cache.writes != 2 {
+		t.Errorf("Expected 2 hits and 2 writes, got %d and %d", cache.hits, cache.writes)
+	}
+	want, err := ComputeFilesDetailed(pairs[0].Path1, pairs[0].Path2, WithNormalization())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first[0].Err != nil || second[0].Err != nil || first[0].Result.PSNR != want.PSNR || second[0].Result.PSNR != want.PSNR {
+		t.Errorf("Expected %v dB from both runs, got %+v and %+v", want.PSNR, first[0], second[0])
+	}
+
+	aborted := c.ComputeBatch(context.Background(), pairs, BatchOptions{Workers: 1, FailFast: true})
+	if aborted[1].Err == nil || !errors.Is(aborted[2].Err, ErrBatchAborted) {
+		t.Errorf("Expected FailFast to abort after the missing file, got %+v", aborted)
+	}
+
+	rejected := c.ComputeBatch(context.Background(), pairs[:1], BatchOptions{Options: []Option{WithAlignment(2)}})
+	if rejected[0].Err == nil {
+		t.Error("Expected BatchOptions.Options to be rejected")
+	}
+}
+
+// BenchmarkComparerComputeBatch measures the throughput of one shared
+// Comparer as workers are added; run with -benchtime and compare ns/op
+// across the worker counts to check the scaling.
+func BenchmarkComparerComputeBatch(b *testing.B) {
+	data1, err := os.ReadFile("testdata/test_original.png")
+	if err != nil {
+		b.Fatal(err)
+	}
+	data2, err := os.ReadFile("testdata/test_quality_85.png")
+	if err != nil {
+		b.Fatal(err)
+	}
+	pairs := make([]Pair, 64)
+	for i := range pairs {
+		pairs[i] = Pair{Data1: data1, Data2: data2}
+	}
+	c := New()
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, r := range c.ComputeBatch(context.Background(), pairs, BatchOptions{Workers: workers}) {
+					if r.Err != nil {
+						b.Fatal(r.Err)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package psnr
+
+import (
+	"context"
+	"image"
+	"math"
+)
+
+// Compatibility selects whose PSNR definition a computation reproduces.
+type Compatibility int
+
+const (
+	// CompatibilityDefault uses this package's own definition: integer
+	// accumulation over RGB, plus alpha when either image has visible
+	// transparency.
+	CompatibilityDefault Compatibility = iota
+	// CompatibilityImageMagick reproduces `magick compare -metric PSNR`:
+	// floating-point accumulation on the 0-1 scale, color channels weighted
+	// by each image's alpha, alpha compared only when both images have an
+	// alpha channel, and the total divided by the channel count of the first
+	// image. The formula is reproduced exactly, so the result agrees with
+	// ImageMagick as far as the decoded pixels do: PNGs and other lossless
+	// inputs match, while JPEGs decoded with image/jpeg round differently
+	// from libjpeg and typically land within about 1.5%.
+	CompatibilityImageMagick
+	// CompatibilityFFmpeg reproduces ffmpeg's psnr filter: the MSE is
+	// measured separately on each plane (Result.Planes), and Result.PSNR is
+	// psnr_avg, computed from the plane MSEs weighted by plane size. Two
+	// JPEGs with the same chroma subsampling are compared on their decoded
+	// planes as ffmpeg does; other inputs are compared losslessly on their
+	// straight R, G and B planes (gbrp), named "r", "g" and "b". Alpha is
+	// ignored.
+	CompatibilityFFmpeg
+	// CompatibilityOpenCV reproduces cv::PSNR on images loaded with
+	// cv::imread's default flags: 3 interleaved color channels with alpha
+	// dropped (not composited), R=255 and 20*log10(R/(sqrt(MSE)+DBL_EPSILON)).
+	// Identical images therefore report about 361.2 dB instead of +Inf.
+	CompatibilityOpenCV
+)
+
+// imageMagickEpsilon is ImageMagick's MagickEpsilon; smaller distortions are
+// reported as infinite PSNR.
+const imageMagickEpsilon = 1e-12
+
+// dblEpsilon is C's DBL_EPSILON, which cv::PSNR adds to the RMS error.
+const dblEpsilon = 2.220446049250313e-16
+
+// hasAlphaChannel reports whether an image declares an alpha channel, which
+// is what ImageMagick's alpha trait reflects, regardless of whether any
+// pixel is actually transparent.
+func hasAlphaChannel(d *decoded) bool {
+	switch d.format {
+	case "png":
+		if d.data != nil {
+			return pngHasAlphaChannel(d.data)
+		}
+	case "jpeg", "raw":
+		return false
+	}
+
+	switch img := d.img.(type) {
+	case *image.YCbCr, *image.Gray, *image.Gray16, *image.CMYK:
+		return false
+	case *image.Paletted:
+		for _, c := range img.Palette {
+			if _, _, _, a := c.RGBA(); a != 0xffff {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// compareImageMagick implements CompatibilityImageMagick.
+func compareImageMagick(ctx context.Context, d1, d2 *decoded, o *options) (*Result, error) {
+	img1 := toNRGBA(d1.img)
+	img2 := toNRGBA(d2.img)
+	alpha1 := hasAlphaChannel(d1)
+	alpha2 := hasAlphaChannel(d2)
+
+	width := img1.Rect.Dx()
+	height := img1.Rect.Dy()
+	const scale = 1.0 / 255
+
+	var distortion compensatedSum
+	for band := 0; band < height; band += bandHeight {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		end := min(band+bandHeight, height)
+
+		for y := band; y < end; y++ {
+			row1 := img1.Pix[img1.PixOffset(0, y):]
+			row2 := img2.Pix[img2.PixOffset(0, y):]
+			var rowDistortion float64
+			for i := 0; i < width*4; i += 4 {
+				sa, da := 1.0, 1.0
+				if alpha1 {
+					sa = scale * float64(row1[i+3])
+				}
+				if alpha2 {
+					da = scale * float64(row2[i+3])
+				}
+				for c := 0; c < 3; c++ {
+					d := scale * (sa*float64(row1[i+c]) - da*float64(row2[i+c]))
+					rowDistortion += d * d
+				}
+				if alpha1 && alpha2 {
+					d := scale * (float64(row1[i+3]) - float64(row2[i+3]))
+					rowDistortion += d * d
+				}
+			}
+			distortion.add(rowDistortion)
+		}
+
+		if o.progress != nil {
+			o.progress(end, height)
+		}
+	}
+
+	channelCount := 3.0
+	if alpha1 {
+		channelCount = 4
+	}
+	mse := distortion.value() / float64(width*height) / channelCount
+
+	if math.Abs(mse) < imageMagickEpsilon {
+		return &Result{PSNR: math.Inf(1), Peak: maxSampleValue}, nil
+	}
+	return &Result{PSNR: -10 * math.Log10(mse), MSE: mse * 255 * 255, Peak: maxSampleValue}, nil
+}
+
+// yuvPlanes is an image split into three planes, Y, U and V or R, G and B.
+type yuvPlanes struct {
+	pix    [3][]uint8
+	stride [3]int
+	width  [3]int
+	height [3]int
+}
+
+// nativeYUVPlanes returns the decoded planes of two YCbCr images with the
+// same subsampling, and false otherwise.
+func nativeYUVPlanes(img1, img2 image.Image) (*yuvPlanes, *yuvPlanes, bool) {
+	y1, ok1 := img1.(*image.YCbCr)
+	y2, ok2 := img2.(*image.YCbCr)
+	if !ok1 || !ok2 || y1.SubsampleRatio != y2.SubsampleRatio {
+		return nil, nil, false
+	}
+	return ycbcrPlanes(y1), ycbcrPlanes(y2), true
+}
+
+func ycbcrPlanes(img *image.YCbCr) *yuvPlanes {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	cw, ch := w, h
+	switch img.SubsampleRatio {
+	case image.YCbCrSubsampleRatio422:
+		cw = (w + 1) / 2
+	case image.YCbCrSubsampleRatio420:
+		cw, ch = (w+1)/2, (h+1)/2
+	case image.YCbCrSubsampleRatio440:
+		ch = (h + 1) / 2
+	case image.YCbCrSubsampleRatio411:
+		cw = (w + 3) / 4
+	case image.YCbCrSubsampleRatio410:
+		cw, ch = (w+3)/4, (h+1)/2
+	}
+
+	y0 := img.YOffset(img.Rect.Min.X, img.Rect.Min.Y)
+	c0 := img.COffset(img.Rect.Min.X, img.Rect.Min.Y)
+	return &yuvPlanes{
+		pix:    [3][]uint8{img.Y[y0:], img.Cb[c0:], img.Cr[c0:]},
+		stride: [3]int{img.YStride, img.CStride, img.CStride},
+		width:  [3]int{w, cw, cw},
+		height: [3]int{h, ch, ch},
+	}
+}
+
+// rgbPlanes splits img into straight R, G and B planes, the samples ffmpeg
+// compares when its inputs negotiate a planar RGB format (gbrp).
+func rgbPlanes(img image.Image) *yuvPlanes {
+	nrgba := toNRGBA(img)
+	w, h := nrgba.Rect.Dx(), nrgba.Rect.Dy()
+	p := &yuvPlanes{}
+	for c := range p.pix {
+		p.pix[c] = make([]uint8, w*h)
+		p.stride[c] = w
+		p.width[c] = w
+		p.height[c] = h
+	}
+	for y := 0; y < h; y++ {
+		row := nrgba.Pix[y*nrgba.Stride:]
+		for x := 0; x < w; x++ {
+			i := x * 4
+			p.pix[0][y*w+x] = row[i]
+			p.pix[1][y*w+x] = row[i+1]
+			p.pix[2][y*w+x] = row[i+2]
+		}
+	}
+	return p
+}
+
+// compareFFmpeg implements CompatibilityFFmpeg.
+func compareFFmpeg(ctx context.Context, img1, img2 image.Image, o *options) (*Result, error) {
+	names := [3]string{"y", "u", "v"}
+	p1, p2, ok := nativeYUVPlanes(img1, img2)
+	if !ok {
+		names = [3]string{"r", "g", "b"}
+		p1, p2 = rgbPlanes(img1), rgbPlanes(img2)
+	}
+
+	result := &Result{Planes: make([]PlaneResult, 3), Peak: maxSampleValue}
+	total, rows := 0, 0
+	for c := range names {
+		total += p1.width[c] * p1.height[c]
+		rows += p1.height[c]
+	}
+
+	var mse float64
+	done := 0
+	for c, name := range names {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var sum uint64
+		w, h := p1.width[c], p1.height[c]
+		for y := 0; y < h; y++ {
+			row1 := p1.pix[c][y*p1.stride[c]:]
+			row2 := p2.pix[c][y*p2.stride[c]:]
+			for x := 0; x < w; x++ {
+				d := int32(row1[x]) - int32(row2[x])
+				sum += uint64(d * d)
+			}
+		}
+
+		size := float64(w * h)
+		planeMSE := float64(sum) / size
+		result.Planes[c] = PlaneResult{Name: name, PSNR: psnrFromMSE(planeMSE), MSE: planeMSE}
+		mse += planeMSE * size / float64(total)
+
+		done += h
+		if o.progress != nil {
+			o.progress(done, rows)
+		}
+	}
+
+	result.MSE = mse
+	result.PSNR = psnrFromMSE(mse)
+	return result, nil
+}
+
+// compareOpenCV implements CompatibilityOpenCV.
+func compareOpenCV(ctx context.Context, img1, img2 image.Image, o *options) (*Result, error) {
+	n1 := toNRGBA(img1)
+	n2 := toNRGBA(img2)
+	width := n1.Rect.Dx()
+	height := n1.Rect.Dy()
+
+	var sum uint64
+	for band := 0; band < height; band += bandHeight {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		end := min(band+bandHeight, height)
+
+		for y := band; y < end; y++ {
+			row1 := n1.Pix[n1.PixOffset(0, y):]
+			row2 := n2.Pix[n2.PixOffset(0, y):]
+			for i := 0; i < width*4; i += 4 {
+				for c := 0; c < 3; c++ {
+					d := int32(row1[i+c]) - int32(row2[i+c])
+					sum += uint64(d * d)
+				}
+			}
+		}
+
+		if o.progress != nil {
+			o.progress(end, height)
+		}
+	}
+
+	mse := float64(sum) / float64(width*height*3)
+	return &Result{PSNR: 20 * math.Log10(255/(math.Sqrt(mse)+dblEpsilon)), MSE: mse, Peak: maxSampleValue}, nil
+}
@@ -0,0 +1,251 @@
+package psnr
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestImageMagickCompatibility(t *testing.T) {
+	data1, err := os.ReadFile("testdata/test_original.jpg")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+	data2, err := os.ReadFile("testdata/quality_50.jpg")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+
+	result, err := ComputeDetailed(data1, data2, WithCompatibility(CompatibilityImageMagick))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+
+	// The formula is exact (see the alpha tests below), but Go's JPEG decoder
+	// rounds differently from the libjpeg ImageMagick used, which moves this
+	// pair by about 1.1%; the tolerance covers only that decoder difference
+	const imageMagick = 42.518275
+	if diff := math.Abs(result.PSNR-imageMagick) / imageMagick * 100; diff > 1.5 {
+		t.Errorf("PSNR %.6f differs from ImageMagick by %.4f%%", result.PSNR, diff)
+	}
+
+	// Opaque images without alpha have the same definition in both modes
+	want, err := ComputeDetailed(data1, data2)
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if math.Abs(result.PSNR-want.PSNR) > 1e-9 {
+		t.Errorf("Expected %.9f for opaque images, got %.9f", want.PSNR, result.PSNR)
+	}
+}
+
+func TestImageMagickCompatibilityAlpha(t *testing.T) {
+	img1 := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img1.SetNRGBA(0, 0, color.NRGBA{255, 0, 0, 128})
+	img1.SetNRGBA(1, 0, color.NRGBA{0, 0, 255, 255})
+	img2 := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img2.SetNRGBA(0, 0, color.NRGBA{255, 0, 0, 255})
+	img2.SetNRGBA(1, 0, color.NRGBA{0, 0, 255, 255})
+
+	result, err := ComputeDetailed(encodePNG(t, img1), encodePNG(t, img2), WithCompatibility(CompatibilityImageMagick))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+
+	// The opaque second image is encoded without an alpha channel, so alpha
+	// is not compared; red differs by Sa*p - Da*q = 128/255 - 1 and the sum
+	// is divided by 2 pixels and the first image's 4 channels
+	d := 128.0/255 - 1
+	want := -10 * math.Log10(d*d/8)
+	if math.Abs(result.PSNR-want) > 1e-9 {
+		t.Errorf("Expected %.9f, got %.9f", want, result.PSNR)
+	}
+
+	// With alpha on both sides the alpha difference counts as a fourth channel
+	img2.SetNRGBA(1, 0, color.NRGBA{0, 0, 255, 64})
+	result, err = ComputeDetailed(encodePNG(t, img1), encodePNG(t, img2), WithCompatibility(CompatibilityImageMagick))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	blue := 1 - 64.0/255
+	want = -10 * math.Log10((2*d*d+2*blue*blue)/8)
+	if math.Abs(result.PSNR-want) > 1e-9 {
+		t.Errorf("Expected %.9f, got %.9f", want, result.PSNR)
+	}
+	img2.SetNRGBA(1, 0, color.NRGBA{0, 0, 255, 255})
+
+	opaque := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	for x := 0; x < 2; x++ {
+		opaque.Set(x, 0, img2.At(x, 0))
+	}
+	identical, err := ComputeDetailed(encodePNG(t, img2), encodePNG(t, opaque), WithCompatibility(CompatibilityImageMagick))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if !math.IsInf(identical.PSNR, 1) {
+		t.Errorf("Expected Inf for identical pixels, got %f", identical.PSNR)
+	}
+}
+
+func TestCompatibilityRejectsOtherOptions(t *testing.T) {
+	data, err := os.ReadFile("testdata/test_original.png")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+	if _, err := ComputeDetailed(data, data, WithCompatibility(CompatibilityImageMagick), WithAlignment(1)); err == nil {
+		t.Error("Expected error when combining compatibility mode with alignment")
+	}
+}
+
+func TestFFmpegCompatibility(t *testing.T) {
+	rect := image.Rect(0, 0, 4, 4)
+	img1 := image.NewYCbCr(rect, image.YCbCrSubsampleRatio420)
+	img2 := image.NewYCbCr(rect, image.YCbCrSubsampleRatio420)
+	for i := range img1.Y {
+		img1.Y[i], img2.Y[i] = 100, 102
+	}
+	for i := range img1.Cb {
+		img1.Cb[i], img2.Cb[i] = 128, 128
+		img1.Cr[i], img2.Cr[i] = 120, 124
+	}
+
+	o, err := newOptions([]Option{WithCompatibility(CompatibilityFFmpeg)})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	result, err := compareFFmpeg(context.Background(), img1, img2, o)
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+
+	wantPlanes := []PlaneResult{
+		{Name: "y", MSE: 4, PSNR: psnrFromMSE(4)},
+		{Name: "u", MSE: 0, PSNR: math.Inf(1)},
+		{Name: "v", MSE: 16, PSNR: psnrFromMSE(16)},
+	}
+	for i, want := range wantPlanes {
+		if got := result.Planes[i]; got != want {
+			t.Errorf("Plane %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+
+	// psnr_avg weights the plane MSEs by plane size: 16 luma and 4+4 chroma samples
+	wantMSE := (4*16 + 0*4 + 16*4) / 24.0
+	if math.Abs(result.MSE-wantMSE) > 1e-12 || math.Abs(result.PSNR-psnrFromMSE(wantMSE)) > 1e-12 {
+		t.Errorf("Expected average MSE %.6f, got %.6f (PSNR %.6f)", wantMSE, result.MSE, result.PSNR)
+	}
+}
+
+func TestFFmpegCompatibilityRGB(t *testing.T) {
+	img1 := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img2 := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img1.SetNRGBA(0, 0, color.NRGBA{100, 50, 200, 128})
+	img1.SetNRGBA(1, 0, color.NRGBA{10, 20, 30, 255})
+	img2.SetNRGBA(0, 0, color.NRGBA{104, 50, 200, 128})
+	img2.SetNRGBA(1, 0, color.NRGBA{10, 22, 30, 255})
+
+	result, err := ComputeDetailed(encodePNG(t, img1), encodePNG(t, img2), WithCompatibility(CompatibilityFFmpeg))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+
+	// Non-JPEG inputs are compared on their straight RGB samples without a
+	// lossy YUV conversion, so each plane sees exactly the encoded difference
+	wantPlanes := []PlaneResult{
+		{Name: "r", MSE: 8, PSNR: psnrFromMSE(8)},
+		{Name: "g", MSE: 2, PSNR: psnrFromMSE(2)},
+		{Name: "b", MSE: 0, PSNR: math.Inf(1)},
+	}
+	for i, want := range wantPlanes {
+		if got := result.Planes[i]; got != want {
+			t.Errorf("Plane %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+	if wantMSE := 10 / 3.0; math.Abs(result.MSE-wantMSE) > 1e-12 {
+		t.Errorf("Expected average MSE %.6f, got %.6f", wantMSE, result.MSE)
+	}
+}
+
+func TestFFmpegCompatibilityFiles(t *testing.T) {
+	tests := []struct {
+		file1, file2 string
+	}{
+		{"testdata/test_original.jpg", "testdata/quality_50.jpg"},
+		{"testdata/test_original.png", "testdata/test_original.png"},
+		{"testdata/chroma_444.jpg", "testdata/chroma_420.jpg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file2, func(t *testing.T) {
+			data1, err := os.ReadFile(tt.file1)
+			if err != nil {
+				t.Fatalf("Failed to read %s: %v", tt.file1, err)
+			}
+			data2, err := os.ReadFile(tt.file2)
+			if err != nil {
+				t.Fatalf("Failed to read %s: %v", tt.file2, err)
+			}
+
+			result, err := ComputeDetailed(data1, data2, WithCompatibility(CompatibilityFFmpeg))
+			if err != nil {
+				t.Fatalf("Error computing PSNR: %v", err)
+			}
+			if len(result.Planes) != 3 {
+				t.Fatalf("Expected 3 planes, got %d", len(result.Planes))
+			}
+			t.Logf("y=%.4f u=%.4f v=%.4f avg=%.4f", result.Planes[0].PSNR, result.Planes[1].PSNR, result.Planes[2].PSNR, result.PSNR)
+
+			if tt.file1 == tt.file2 && !math.IsInf(result.PSNR, 1) {
+				t.Errorf("Expected Inf for identical images, got %f", result.PSNR)
+			}
+		})
+	}
+}
+
+func TestOpenCVCompatibility(t *testing.T) {
+	data1, err := os.ReadFile("testdata/test_original.jpg")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+	data2, err := os.ReadFile("testdata/quality_50.jpg")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+
+	result, err := ComputeDetailed(data1, data2, WithCompatibility(CompatibilityOpenCV))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	want, err := ComputeDetailed(data1, data2)
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if math.Abs(result.PSNR-want.PSNR) > 1e-9 {
+		t.Errorf("Expected %.9f for opaque images, got %.9f", want.PSNR, result.PSNR)
+	}
+
+	// cv::PSNR never returns infinity
+	identical, err := ComputeDetailed(data1, data1, WithCompatibility(CompatibilityOpenCV))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if math.Abs(identical.PSNR-361.2020) > 1e-3 {
+		t.Errorf("Expected 361.2020 for identical images, got %.4f", identical.PSNR)
+	}
+
+	// Alpha is dropped, so differences hidden under transparency still count
+	img1 := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	img1.SetNRGBA(0, 0, color.NRGBA{30, 60, 90, 0})
+	img2 := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	img2.SetNRGBA(0, 0, color.NRGBA{30, 60, 96, 255})
+	result, err = ComputeDetailed(encodePNG(t, img1), encodePNG(t, img2), WithCompatibility(CompatibilityOpenCV))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if result.MSE != 12 {
+		t.Errorf("Expected MSE 12, got %f", result.MSE)
+	}
+}
@@ -0,0 +1,62 @@
+package psnr
+
+// ComponentResult splits the error into luma, chroma and alpha, as requested
+// with WithComponentPSNR. Chroma subsampling and alpha quantization
+// regressions are otherwise masked by the luma-dominated aggregate.
+type ComponentResult struct {
+	// Luma is the error of Y'.
+	Luma PlaneResult
+	// Chroma is the error of Cb and Cr combined.
+	Chroma PlaneResult
+	// Alpha is the error of the alpha channel alone; it is +Inf for opaque
+	// images.
+	Alpha PlaneResult
+}
+
+// componentVisitor accumulates Y', Cb, Cr and alpha squared errors from the
+// fused pass. The color samples are converted with the configured matrix in
+// full range, in floating point so that equal RGB samples never differ.
+type componentVisitor struct {
+	kr, kg, kb float64
+	sums       *componentSums
+}
+
+// componentSums holds the running sums of a componentVisitor.
+type componentSums struct {
+	luma, chroma, alpha float64
+	pixels              int
+}
+
+func newComponentVisitor(m ColorMatrix) componentVisitor {
+	kr, kb := m.lumaCoefficients()
+	return componentVisitor{kr: kr, kg: 1 - kr - kb, kb: kb, sums: &componentSums{}}
+}
+
+func (v componentVisitor) visit(_, _ int, p1, p2 []uint8) {
+	diffR := float64(p1[0]) - float64(p2[0])
+	diffG := float64(p1[1]) - float64(p2[1])
+	diffB := float64(p1[2]) - float64(p2[2])
+	diffA := float64(p1[3]) - float64(p2[3])
+
+	diffY := v.kr*diffR + v.kg*diffG + v.kb*diffB
+	diffCb := (diffB - diffY) / (2 * (1 - v.kb))
+	diffCr := (diffR - diffY) / (2 * (1 - v.kr))
+
+	v.sums.luma += diffY * diffY
+	v.sums.chroma += diffCb*diffCb + diffCr*diffCr
+	v.sums.alpha += diffA * diffA
+	v.sums.pixels++
+}
+
+// result converts the sums into per-component PSNR values.
+func (v componentVisitor) result() *ComponentResult {
+	pixels := float64(max(v.sums.pixels, 1))
+	plane := func(name string, mse float64) PlaneResult {
+		return PlaneResult{Name: name, PSNR: psnrFromMSE(mse), MSE: mse}
+	}
+	return &ComponentResult{
+		Luma:   plane("y", v.sums.luma/pixels),
+		Chroma: plane("cbcr", v.sums.chroma/(2*pixels)),
+		Alpha:  plane("a", v.sums.alpha/pixels),
+	}
+}
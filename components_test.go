@@ -0,0 +1,69 @@
+package psnr
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"testing"
+)
+
+func TestWithComponentPSNR(t *testing.T) {
+	encode := func(img image.Image) []byte {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			t.Fatalf("Failed to encode image: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	// A checkerboard of two colors of nearly equal luma loses almost only
+	// chroma in image/jpeg's 4:2:0 encoding
+	checker := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			c := color.RGBA{200, 100, 100, 255}
+			if (x+y)%2 == 1 {
+				c = color.RGBA{64, 143, 143, 255}
+			}
+			checker.SetRGBA(x, y, c)
+		}
+	}
+	var jpg bytes.Buffer
+	if err := jpeg.Encode(&jpg, checker, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatalf("Failed to encode image: %v", err)
+	}
+
+	result, err := ComputeDetailed(encode(checker), jpg.Bytes(), WithComponentPSNR())
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	c := result.Components
+	if c == nil {
+		t.Fatal("Expected component results")
+	}
+	if c.Chroma.PSNR > c.Luma.PSNR-10 {
+		t.Errorf("Expected chroma to dominate the loss, got luma %.2f dB and chroma %.2f dB", c.Luma.PSNR, c.Chroma.PSNR)
+	}
+	if !math.IsInf(c.Alpha.PSNR, 1) {
+		t.Errorf("Expected Inf alpha PSNR for opaque images, got %f", c.Alpha.PSNR)
+	}
+
+	// Transparent black pixels whose alpha changes differ in alpha only
+	img1 := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	img2 := image.NewNRGBA(img1.Rect)
+	for i := 3; i < len(img1.Pix); i += 4 {
+		img1.Pix[i] = 200
+		img2.Pix[i] = 190
+	}
+	result, err = ComputeDetailed(encode(img1), encode(img2), WithComponentPSNR())
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	c = result.Components
+	if !math.IsInf(c.Luma.PSNR, 1) || !math.IsInf(c.Chroma.PSNR, 1) || c.Alpha.MSE != 100 {
+		t.Errorf("Expected an alpha-only error of 100, got %+v", c)
+	}
+}
@@ -0,0 +1,116 @@
+package psnr
+
+import (
+	"fmt"
+	"image"
+	"sort"
+)
+
+const (
+	// minCropLevelSize is the smallest edge length the cropped image may
+	// have on the coarsest search level.
+	minCropLevelSize = 8
+	// cropCandidates is the number of placements refined on each finer level,
+	// so a repetitive pattern cannot trap the search in a wrong minimum.
+	cropCandidates = 4
+	// cropRefineRadius is the search radius around each candidate on a finer
+	// level, in pixels of that level.
+	cropRefineRadius = 2
+)
+
+// cropPlacement is a candidate position of the smaller image inside the
+// larger one.
+type cropPlacement struct {
+	offset image.Point
+	mse    float64
+}
+
+// matchCrop locates the smaller of two images inside the larger one and
+// returns both cut to the matched region, together with the offset in the
+// convention of Result.Offset. One image must fit inside the other.
+func matchCrop(d1, d2 *decoded) (*decoded, *decoded, image.Point, error) {
+	b1, b2 := d1.img.Bounds(), d2.img.Bounds()
+	firstSmaller := b1.Dx() <= b2.Dx() && b1.Dy() <= b2.Dy()
+	if !firstSmaller && !(b2.Dx() <= b1.Dx() && b2.Dy() <= b1.Dy()) {
+		return nil, nil, image.Point{}, fmt.Errorf("neither image fits inside the other: %dx%d vs %dx%d",
+			b1.Dx(), b1.Dy(), b2.Dx(), b2.Dy())
+	}
+
+	small, large := d1, d2
+	if !firstSmaller {
+		small, large = d2, d1
+	}
+	smallRGBA, largeRGBA := toRGBA(small.img), toRGBA(large.img)
+	position := searchCrop(largeRGBA, smallRGBA)
+
+	// The copies are only searched; the crop keeps the decoded type
+	region := smallRGBA.Rect.Add(position).Add(large.img.Bounds().Min)
+	cropped := large.withImage(subImage(large.img, region))
+	if firstSmaller {
+		return d1, cropped, position, nil
+	}
+	return cropped, d2, image.Point{}.Sub(position), nil
+}
+
+// searchCrop returns the top-left position in large at which small has the
+// lowest MSE. It searches every position on a box-downscaled pyramid level
+// and refines the best candidates level by level up to full resolution.
+// Alpha is always compared; it is equal for opaque images.
+func searchCrop(large, small *image.RGBA) image.Point {
+	factor := 1
+	for min(small.Rect.Dx(), small.Rect.Dy())/(factor*2) >= minCropLevelSize {
+		factor *= 2
+	}
+
+	candidates := []image.Point{{}}
+	exhaustive := true
+	for ; factor >= 1; factor /= 2 {
+		levelLarge, levelSmall := large, small
+		if factor > 1 {
+			levelLarge, levelSmall = downscaleRGBA(large, factor), downscaleRGBA(small, factor)
+		}
+		limit := levelLarge.Rect.Size().Sub(levelSmall.Rect.Size())
+
+		seen := make(map[image.Point]bool)
+		var placements []cropPlacement
+		evaluate := func(p image.Point) {
+			if p.X < 0 || p.Y < 0 || p.X > limit.X || p.Y > limit.Y || seen[p] {
+				return
+			}
+			seen[p] = true
+			sum := blockSSD(levelSmall, levelLarge, levelSmall.Rect, p, true)
+			placements = append(placements, cropPlacement{p, float64(sum) / float64(levelSmall.Rect.Dx()*levelSmall.Rect.Dy())})
+		}
+
+		if exhaustive {
+			for y := 0; y <= limit.Y; y++ {
+				for x := 0; x <= limit.X; x++ {
+					evaluate(image.Pt(x, y))
+				}
+			}
+			exhaustive = false
+		} else {
+			for _, c := range candidates {
+				center := c.Mul(2)
+				for dy := -cropRefineRadius; dy <= cropRefineRadius; dy++ {
+					for dx := -cropRefineRadius; dx <= cropRefineRadius; dx++ {
+						evaluate(center.Add(image.Pt(dx, dy)))
+					}
+				}
+			}
+		}
+
+		sort.SliceStable(placements, func(i, j int) bool {
+			return placements[i].mse < placements[j].mse
+		})
+		candidates = candidates[:0]
+		for _, p := range placements[:min(cropCandidates, len(placements))] {
+			candidates = append(candidates, p.offset)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return image.Point{}
+	}
+	return candidates[0]
+}
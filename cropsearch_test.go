@@ -0,0 +1,99 @@
+package psnr
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestWithCropSearch(t *testing.T) {
+	// Random 4x4 blocks over a gradient, so every placement looks different
+	rng := rand.New(rand.NewSource(1))
+	full := image.NewRGBA(image.Rect(0, 0, 640, 480))
+	for by := 0; by < 480; by += 4 {
+		for bx := 0; bx < 640; bx += 4 {
+			c := color.RGBA{uint8(rng.Intn(128) + bx/5), uint8(rng.Intn(128) + by/4), uint8(rng.Intn(256)), 255}
+			for y := by; y < by+4; y++ {
+				for x := bx; x < bx+4; x++ {
+					full.SetRGBA(x, y, c)
+				}
+			}
+		}
+	}
+	data := encodePNG(t, full)
+
+	for _, region := range []image.Rectangle{
+		image.Rect(137, 61, 437, 261),
+		image.Rect(0, 0, 200, 480),
+		image.Rect(601, 457, 640, 480),
+	} {
+		crop := encodePNG(t, full.SubImage(region))
+		lossless := data
+
+		result, err := ComputeDetailed(crop, lossless, WithCropSearch())
+		if err != nil {
+			t.Fatalf("Error computing PSNR: %v", err)
+		}
+		if result.Offset != region.Min || !math.IsInf(result.PSNR, 1) {
+			t.Errorf("Expected Inf at %v, got %.2f dB at %v", region.Min, result.PSNR, result.Offset)
+		}
+
+		// The larger image first gives the opposite offset
+		result, err = ComputeDetailed(lossless, crop, WithCropSearch())
+		if err != nil {
+			t.Fatalf("Error computing PSNR: %v", err)
+		}
+		if want := region.Min.Mul(-1); result.Offset != want {
+			t.Errorf("Expected offset %v, got %v", want, result.Offset)
+		}
+
+		// A lossy crop is still found
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, full.SubImage(region), &jpeg.Options{Quality: 75}); err != nil {
+			t.Fatalf("Failed to encode crop: %v", err)
+		}
+		result, err = ComputeDetailed(buf.Bytes(), lossless, WithCropSearch())
+		if err != nil {
+			t.Fatalf("Error computing PSNR: %v", err)
+		}
+		if result.Offset != region.Min {
+			t.Errorf("Expected the lossy crop at %v, got %.2f dB at %v", region.Min, result.PSNR, result.Offset)
+		}
+	}
+
+	tall := encodePNG(t, image.NewRGBA(image.Rect(0, 0, 10, 1000)))
+	if _, err := ComputeDetailed(tall, data, WithCropSearch()); err == nil {
+		t.Error("Expected error when neither image fits inside the other")
+	}
+	if _, err := ComputeDetailed(data, data, WithCropSearch(), WithAlignment(2)); err == nil {
+		t.Error("Expected error when combined with alignment")
+	}
+}
+
+func TestWithCropSearchStraight(t *testing.T) {
+	ctx := context.Background()
+	content1, content2 := translucentPair(40, 24)
+	want, err := CompareImages(ctx, content1, content2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	large := image.NewNRGBA(image.Rect(0, 0, 56, 40))
+	for i := range large.Pix {
+		large.Pix[i] = uint8(i * 7)
+	}
+	draw.Draw(large, content1.Rect.Add(image.Pt(9, 5)), content1, image.Point{}, draw.Src)
+
+	result, err := CompareImages(ctx, large, content2, WithCropSearch())
+	if err != nil {
+		t.Fatalf("CompareImages failed: %v", err)
+	}
+	if result.Offset != image.Pt(-9, -5) || result.PSNR != want.PSNR {
+		t.Errorf("Expected offset (-9,-5) at %v dB, got %v at %v dB", want.PSNR, result.Offset, result.PSNR)
+	}
+}
@@ -0,0 +1,140 @@
+package psnr
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"sort"
+	"sync"
+)
+
+// DecodeFunc decodes an encoded image.
+type DecodeFunc func(data []byte) (image.Image, error)
+
+// decoderBackend is an alternative decoder for a single image format.
+type decoderBackend struct {
+	format string
+	decode DecodeFunc
+	// sniff recognizes the backend's format when the standard library has no
+	// decoder registered for it, and is nil otherwise.
+	sniff func(data []byte) bool
+}
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = make(map[string]decoderBackend)
+)
+
+// RegisterDecoder makes an alternative decoder for format (the name used by
+// image.RegisterFormat, such as "jpeg") available to WithDecoder under name.
+// Backends built on C libraries register themselves from an init function
+// when their build tag is enabled. Registering a name twice replaces the
+// earlier decoder.
+func RegisterDecoder(name, format string, decode DecodeFunc) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	decoders[name] = decoderBackend{format: format, decode: decode}
+}
+
+// registerSniffedDecoder registers a backend for a format the standard
+// library cannot recognize, identified by sniff instead.
+func registerSniffedDecoder(name, format string, sniff func(data []byte) bool, decode DecodeFunc) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	decoders[name] = decoderBackend{format: format, decode: decode, sniff: sniff}
+}
+
+// RegisteredDecoders returns the sorted names of all registered decoders.
+func RegisteredDecoders() []string {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	names := make([]string, 0, len(decoders))
+	for name := range decoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupDecoder returns the named decoder, or nil for the empty name.
+func lookupDecoder(name string) (*decoderBackend, error) {
+	if name == "" {
+		return nil, nil
+	}
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	backend, ok := decoders[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown decoder: %s (C backends require their build tag, e.g. -tags %s)", name, name)
+	}
+	return &backend, nil
+}
+
+// DecodeError is returned when an input cannot be decoded, including when
+// it exceeds the decode limits.
+type DecodeError struct {
+	// Input names the input, such as "first image".
+	Input string
+	Err   error
+}
+
+// Error returns the input and the decoder's error.
+func (e *DecodeError) Error() string {
+	return "failed to decode " + e.Input + ": " + e.Err.Error()
+}
+
+// Unwrap returns the decoder's error.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// decode checks data against the decode limits, then decodes it with the
+// selected backend when it handles the data's format and with the standard
+// library decoders otherwise. With WithTolerantDecode, data that fails to
+// decode is retried as a truncated image.
+func (o *options) decode(data []byte) (*decoded, error) {
+	if err := o.limits.check(data); err != nil {
+		return nil, err
+	}
+
+	var img image.Image
+	var format string
+	var err error
+	if o.decoder != nil {
+		if o.decoder.sniff != nil {
+			if o.decoder.sniff(data) {
+				img, err = o.decoder.decode(data)
+				format = o.decoder.format
+			}
+		} else if _, f, cerr := image.DecodeConfig(bytes.NewReader(data)); cerr == nil && f == o.decoder.format {
+			img, err = o.decoder.decode(data)
+			format = f
+		}
+	}
+	standard := img == nil && err == nil
+	if standard {
+		img, format, err = image.Decode(bytes.NewReader(data))
+	}
+
+	if err != nil && o.tolerant {
+		partial, partialFormat, rows, terr := tolerantDecode(data)
+		if terr == nil {
+			o.debug("recovered truncated image", "format", partialFormat, "rows", rows, "error", err)
+			if err := o.limits.checkImage(partial); err != nil {
+				return nil, err
+			}
+			return &decoded{img: partial, format: partialFormat, data: data, partialRows: rows, grayAlpha: isGrayAlphaPNG(data), backend: "tolerant"}, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := o.limits.checkImage(img); err != nil {
+		return nil, err
+	}
+	backend := o.decoderName
+	if standard {
+		backend = "image/" + format
+	}
+	return &decoded{img: img, format: format, data: data, grayAlpha: standard && isGrayAlphaPNG(data), backend: backend}, nil
+}
@@ -0,0 +1,116 @@
+//go:build libjpeg && cgo
+
+package psnr
+
+/*
+#cgo LDFLAGS: -ljpeg
+#include <stdio.h>
+#include <stdlib.h>
+#include <setjmp.h>
+#include <jpeglib.h>
+
+struct psnr_jpeg_error {
+	struct jpeg_error_mgr pub;
+	jmp_buf jump;
+	char message[JMSG_LENGTH_MAX];
+};
+
+static void psnr_jpeg_error_exit(j_common_ptr cinfo) {
+	struct psnr_jpeg_error *err = (struct psnr_jpeg_error *)cinfo->err;
+	(*cinfo->err->format_message)(cinfo, err->message);
+	longjmp(err->jump, 1);
+}
+
+static void psnr_jpeg_output_message(j_common_ptr cinfo) {
+}
+
+// psnr_decode_jpeg decodes data to interleaved RGB in a malloc'ed buffer.
+// On failure it returns NULL and copies the libjpeg message to message.
+static unsigned char *psnr_decode_jpeg(unsigned char *data, unsigned long size,
+		int *width, int *height, char *message, size_t message_len) {
+	struct jpeg_decompress_struct cinfo;
+	struct psnr_jpeg_error err;
+	unsigned char *volatile pixels = NULL;
+
+	cinfo.err = jpeg_std_error(&err.pub);
+	err.pub.error_exit = psnr_jpeg_error_exit;
+	err.pub.output_message = psnr_jpeg_output_message;
+	if (setjmp(err.jump)) {
+		snprintf(message, message_len, "%s", err.message);
+		jpeg_destroy_decompress(&cinfo);
+		free(pixels);
+		return NULL;
+	}
+
+	jpeg_create_decompress(&cinfo);
+	jpeg_mem_src(&cinfo, data, size);
+	jpeg_read_header(&cinfo, TRUE);
+	if (cinfo.jpeg_color_space == JCS_CMYK || cinfo.jpeg_color_space == JCS_YCCK) {
+		snprintf(message, message_len, "CMYK JPEGs are not supported");
+		jpeg_destroy_decompress(&cinfo);
+		return NULL;
+	}
+	cinfo.out_color_space = JCS_RGB;
+	jpeg_start_decompress(&cinfo);
+
+	size_t stride = (size_t)cinfo.output_width * 3;
+	pixels = malloc(stride * cinfo.output_height);
+	if (pixels == NULL) {
+		snprintf(message, message_len, "out of memory");
+		jpeg_destroy_decompress(&cinfo);
+		return NULL;
+	}
+	while (cinfo.output_scanline < cinfo.output_height) {
+		JSAMPROW row = pixels + stride * cinfo.output_scanline;
+		jpeg_read_scanlines(&cinfo, &row, 1);
+	}
+
+	*width = cinfo.output_width;
+	*height = cinfo.output_height;
+	jpeg_finish_decompress(&cinfo);
+	jpeg_destroy_decompress(&cinfo);
+	return pixels;
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"image"
+	"unsafe"
+)
+
+func init() {
+	RegisterDecoder("libjpeg", "jpeg", decodeLibjpeg)
+}
+
+// decodeLibjpeg decodes a JPEG with the system libjpeg (or libjpeg-turbo)
+// using its default settings, which is what ImageMagick, ffmpeg and most
+// other tools do.
+func decodeLibjpeg(data []byte) (image.Image, error) {
+	if len(data) == 0 {
+		return nil, errors.New("libjpeg: empty input")
+	}
+
+	input := C.CBytes(data)
+	defer C.free(input)
+
+	var width, height C.int
+	var message [C.JMSG_LENGTH_MAX]C.char
+	pixels := C.psnr_decode_jpeg((*C.uchar)(input), C.ulong(len(data)), &width, &height, &message[0], C.size_t(len(message)))
+	if pixels == nil {
+		return nil, errors.New("libjpeg: " + C.GoString(&message[0]))
+	}
+	defer C.free(unsafe.Pointer(pixels))
+
+	w, h := int(width), int(height)
+	rgb := unsafe.Slice((*uint8)(unsafe.Pointer(pixels)), w*h*3)
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i, j := 0, 0; i < len(rgb); i, j = i+3, j+4 {
+		img.Pix[j] = rgb[i]
+		img.Pix[j+1] = rgb[i+1]
+		img.Pix[j+2] = rgb[i+2]
+		img.Pix[j+3] = 0xff
+	}
+	return img, nil
+}
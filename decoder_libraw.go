@@ -0,0 +1,114 @@
+//go:build libraw && cgo
+
+package psnr
+
+/*
+#cgo LDFLAGS: -lraw
+#include <stdlib.h>
+#include <string.h>
+#include <libraw/libraw.h>
+
+// psnr_decode_raw develops a camera RAW file to 8-bit sRGB in a malloc'ed
+// buffer with fixed settings, so results do not depend on LibRaw's
+// defaults. On failure it returns NULL and sets *code to the LibRaw error.
+static unsigned char *psnr_decode_raw(void *data, size_t size, int *width, int *height, int *code) {
+	libraw_data_t *raw = libraw_init(0);
+	if (raw == NULL) {
+		*code = LIBRAW_UNSUFFICIENT_MEMORY;
+		return NULL;
+	}
+
+	raw->params.use_camera_wb = 1;
+	raw->params.use_auto_wb = 0;
+	raw->params.no_auto_bright = 1;
+	raw->params.user_qual = 3;
+	raw->params.output_color = 1;
+	raw->params.output_bps = 8;
+	raw->params.gamm[0] = 1 / 2.4;
+	raw->params.gamm[1] = 12.92;
+	raw->params.half_size = 0;
+
+	int ret = libraw_open_buffer(raw, data, size);
+	if (ret == LIBRAW_SUCCESS) {
+		ret = libraw_unpack(raw);
+	}
+	if (ret == LIBRAW_SUCCESS) {
+		ret = libraw_dcraw_process(raw);
+	}
+	libraw_processed_image_t *image = NULL;
+	if (ret == LIBRAW_SUCCESS) {
+		image = libraw_dcraw_make_mem_image(raw, &ret);
+	}
+	if (image == NULL || ret != LIBRAW_SUCCESS) {
+		*code = ret;
+		if (image != NULL) {
+			libraw_dcraw_clear_mem(image);
+		}
+		libraw_close(raw);
+		return NULL;
+	}
+	if (image->type != LIBRAW_IMAGE_BITMAP || image->colors != 3 || image->bits != 8) {
+		*code = LIBRAW_UNSUPPORTED_THUMBNAIL;
+		libraw_dcraw_clear_mem(image);
+		libraw_close(raw);
+		return NULL;
+	}
+
+	size_t bytes = (size_t)image->width * image->height * 3;
+	unsigned char *pixels = malloc(bytes);
+	if (pixels != NULL) {
+		memcpy(pixels, image->data, bytes);
+		*width = image->width;
+		*height = image->height;
+	} else {
+		*code = LIBRAW_UNSUFFICIENT_MEMORY;
+	}
+	libraw_dcraw_clear_mem(image);
+	libraw_close(raw);
+	return pixels;
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"image"
+	"unsafe"
+)
+
+func init() {
+	registerSniffedDecoder("libraw", cameraRawFormat, isCameraRaw, decodeLibraw)
+}
+
+// decodeLibraw develops a camera RAW file (DNG, CR2, CR3, NEF, ARW, ORF,
+// RW2, RAF…) with LibRaw using fixed settings: the camera's white balance,
+// no automatic brightening, AHD demosaicing, sRGB primaries and the sRGB
+// tone curve at 8 bits, oriented as the camera recorded. The output is
+// reproducible for a given LibRaw version, but it is not the camera's own
+// rendering, so compare against processed JPEGs with a tolerance.
+func decodeLibraw(data []byte) (image.Image, error) {
+	if len(data) == 0 {
+		return nil, errors.New("libraw: empty input")
+	}
+
+	input := C.CBytes(data)
+	defer C.free(input)
+
+	var width, height, code C.int
+	pixels := C.psnr_decode_raw(input, C.size_t(len(data)), &width, &height, &code)
+	if pixels == nil {
+		return nil, errors.New("libraw: " + C.GoString(C.libraw_strerror(code)))
+	}
+	defer C.free(unsafe.Pointer(pixels))
+
+	w, h := int(width), int(height)
+	rgb := unsafe.Slice((*uint8)(unsafe.Pointer(pixels)), w*h*3)
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i, j := 0, 0; i < len(rgb); i, j = i+3, j+4 {
+		img.Pix[j] = rgb[i]
+		img.Pix[j+1] = rgb[i+1]
+		img.Pix[j+2] = rgb[i+2]
+		img.Pix[j+3] = 0xff
+	}
+	return img, nil
+}
@@ -0,0 +1,89 @@
+package psnr
+
+import (
+	"errors"
+	"image"
+	"math"
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestWithDecoder(t *testing.T) {
+	data1, err := os.ReadFile("testdata/test_original.jpg")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+	jpeg2, err := os.ReadFile("testdata/quality_50.jpg")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+	png1, err := os.ReadFile("testdata/test_original.png")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+	png2, err := os.ReadFile("testdata/test_quality_85.png")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+
+	// A backend that renders every JPEG black, so its use is observable
+	calls := 0
+	RegisterDecoder("test_black_jpeg", "jpeg", func(data []byte) (image.Image, error) {
+		calls++
+		return image.NewRGBA(image.Rect(0, 0, 1, 1)), nil
+	})
+	if !slices.Contains(RegisteredDecoders(), "test_black_jpeg") {
+		t.Errorf("Expected test_black_jpeg in %v", RegisteredDecoders())
+	}
+
+	black, err := ComputeDetailed(data1, jpeg2, WithDecoder("test_black_jpeg"))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected the backend to decode both JPEGs, got %d calls", calls)
+	}
+	if !math.IsInf(black.PSNR, 1) {
+		t.Errorf("Expected Inf for two black images, got %f", black.PSNR)
+	}
+
+	// PNG inputs keep using the standard decoder
+	calls = 0
+	if _, err := ComputeDetailed(png1, png2, WithDecoder("test_black_jpeg")); err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if calls != 0 {
+		t.Errorf("Expected the backend to skip PNGs, got %d calls", calls)
+	}
+
+	// Backend errors carry the image position
+	RegisterDecoder("test_failing_jpeg", "jpeg", func(data []byte) (image.Image, error) {
+		return nil, errors.New("boom")
+	})
+	if _, err := ComputeDetailed(data1, jpeg2, WithDecoder("test_failing_jpeg")); err == nil {
+		t.Error("Expected decode error")
+	}
+
+	if _, err := ComputeDetailed(data1, data1, WithDecoder("no_such_decoder")); err == nil {
+		t.Error("Expected error for unknown decoder")
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	original, err := os.ReadFile("testdata/test_original.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ComputeDetailed(original, []byte("not an image"))
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.Input != "second image" || !errors.Is(err, image.ErrFormat) {
+		t.Errorf("Expected a DecodeError for the second image wrapping image.ErrFormat, got %v", err)
+	}
+
+	small := encodePNG(t, image.NewRGBA(image.Rect(0, 0, 4, 4)))
+	if _, err := ComputeDetailed(encodePNG(t, image.NewRGBA(image.Rect(0, 0, 8, 8))), small); !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("Expected ErrDimensionMismatch, got %v", err)
+	}
+}
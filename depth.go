@@ -0,0 +1,104 @@
+package psnr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// DepthOptions configures CompareDepth.
+type DepthOptions struct {
+	// IgnoreZero excludes pixels with a depth of zero in either map, which
+	// most sensors and stereo matchers use for "no measurement".
+	IgnoreZero bool
+	// Mask, when set, holds one flag per pixel in row order; only pixels
+	// whose flag is true are compared.
+	Mask []bool
+	// Peak is the peak signal value. Zero selects the depth range, the
+	// largest minus the smallest valid depth of the reference.
+	Peak float64
+}
+
+// DepthResult is the comparison of two depth or disparity maps.
+type DepthResult struct {
+	// PSNR and MSE cover the valid pixels, with MSE in squared depth units.
+	PSNR float64
+	MSE  float64
+	// Peak is the peak signal value PSNR is measured against.
+	Peak float64
+	// ValidPixels is the number of pixels compared, and InvalidPixels the
+	// number excluded as NaN, infinite, zero or masked.
+	ValidPixels   int
+	InvalidPixels int
+}
+
+// CompareDepth calculates PSNR between a reference depth or disparity map and
+// a test map, each a single-plane PlanarImage, as produced by vision
+// pipelines. Pixels that are NaN or infinite in either map are never
+// compared, and opts can exclude zero depths and masked pixels. Since depth
+// maps have no fixed white level, PSNR is measured against the reference's
+// depth range unless opts.Peak is set; a flat reference has a range of 0
+// and reports -Inf for any difference.
+func CompareDepth[T PlanarSample](ctx context.Context, reference, test *PlanarImage[T], opts DepthOptions) (*DepthResult, error) {
+	if err := checkPlanar(reference, test); err != nil {
+		return nil, err
+	}
+	if len(reference.Planes) != 1 {
+		return nil, fmt.Errorf("depth maps must have one plane, got %d", len(reference.Planes))
+	}
+	size := reference.Width * reference.Height
+	if opts.Mask != nil && len(opts.Mask) != size {
+		return nil, fmt.Errorf("depth mask has %d entries, want %d", len(opts.Mask), size)
+	}
+	if opts.Peak < 0 || math.IsInf(opts.Peak, 0) || math.IsNaN(opts.Peak) {
+		return nil, fmt.Errorf("invalid peak: %v", opts.Peak)
+	}
+
+	plane1, plane2 := reference.Planes[0], test.Planes[0]
+	valid := func(i int) bool {
+		d1, d2 := float64(plane1[i]), float64(plane2[i])
+		switch {
+		case math.IsNaN(d1) || math.IsNaN(d2) || math.IsInf(d1, 0) || math.IsInf(d2, 0):
+			return false
+		case opts.IgnoreZero && (d1 == 0 || d2 == 0):
+			return false
+		case opts.Mask != nil && !opts.Mask[i]:
+			return false
+		}
+		return true
+	}
+
+	result := &DepthResult{}
+	var sum compensatedSum
+	low, high := math.Inf(1), math.Inf(-1)
+	for y := 0; y < reference.Height; y++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var rowSum float64
+		for i := y * reference.Width; i < (y+1)*reference.Width; i++ {
+			if !valid(i) {
+				result.InvalidPixels++
+				continue
+			}
+			d1, d2 := float64(plane1[i]), float64(plane2[i])
+			diff := d1 - d2
+			rowSum += diff * diff
+			low, high = min(low, d1), max(high, d1)
+			result.ValidPixels++
+		}
+		sum.add(rowSum)
+	}
+	if result.ValidPixels == 0 {
+		return nil, errors.New("depth maps have no valid pixels")
+	}
+
+	result.Peak = opts.Peak
+	if result.Peak == 0 {
+		result.Peak = high - low
+	}
+	result.MSE = sum.value() / float64(result.ValidPixels)
+	result.PSNR = planarPSNR(result.MSE, result.Peak)
+	return result, nil
+}
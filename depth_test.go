@@ -0,0 +1,60 @@
+package psnr
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestCompareDepth(t *testing.T) {
+	ctx := context.Background()
+	nan := float32(math.NaN())
+	reference := &PlanarImage[float32]{Width: 3, Height: 2, Planes: [][]float32{{1, 2, 3, 4, 0, nan}}}
+	test := &PlanarImage[float32]{Width: 3, Height: 2, Planes: [][]float32{{1, 2, 3, 5, 9, 9}}}
+
+	// The NaN pixel is always ignored, the zero one only with IgnoreZero
+	result, err := CompareDepth(ctx, reference, test, DepthOptions{IgnoreZero: true})
+	if err != nil {
+		t.Fatalf("CompareDepth failed: %v", err)
+	}
+	if result.ValidPixels != 4 || result.InvalidPixels != 2 || result.MSE != 0.25 || result.Peak != 3 {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+	if want := 10 * math.Log10(9/0.25); math.Abs(result.PSNR-want) > 1e-9 {
+		t.Errorf("Expected %v dB, got %v", want, result.PSNR)
+	}
+
+	result, err = CompareDepth(ctx, reference, test, DepthOptions{})
+	if err != nil {
+		t.Fatalf("CompareDepth failed: %v", err)
+	}
+	if result.ValidPixels != 5 || result.Peak != 4 || result.MSE != 82.0/5 {
+		t.Errorf("Expected the zero depth to count, got %+v", result)
+	}
+
+	mask := []bool{true, true, true, false, true, true}
+	result, err = CompareDepth(ctx, reference, test, DepthOptions{IgnoreZero: true, Mask: mask, Peak: 10})
+	if err != nil {
+		t.Fatalf("CompareDepth failed: %v", err)
+	}
+	if result.ValidPixels != 3 || !math.IsInf(result.PSNR, 1) || result.Peak != 10 {
+		t.Errorf("Expected three identical masked pixels, got %+v", result)
+	}
+
+	millimeters := &PlanarImage[uint16]{Width: 2, Height: 1, Planes: [][]uint16{{1000, 1000}}}
+	noisy := &PlanarImage[uint16]{Width: 2, Height: 1, Planes: [][]uint16{{1000, 1010}}}
+	if result, err := CompareDepth(ctx, millimeters, noisy, DepthOptions{}); err != nil || !math.IsInf(result.PSNR, -1) {
+		t.Errorf("Expected -Inf against a flat reference, got %+v, %v", result, err)
+	}
+
+	invalid := []DepthOptions{{Mask: []bool{true}}, {Peak: -1}, {Mask: make([]bool, 6)}}
+	for i, opts := range invalid {
+		if _, err := CompareDepth(ctx, reference, test, opts); err == nil {
+			t.Errorf("Expected error for options %d", i)
+		}
+	}
+	twoPlanes := &PlanarImage[float32]{Width: 1, Height: 1, Planes: [][]float32{{1}, {1}}}
+	if _, err := CompareDepth(ctx, twoPlanes, twoPlanes, DepthOptions{}); err == nil {
+		t.Error("Expected error for a multi-plane map")
+	}
+}
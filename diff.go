@@ -0,0 +1,64 @@
+package psnr
+
+import "image"
+
+// DiffReport locates the pixels that differ between two images, as
+// requested with WithDiffReport. Coordinates are relative to the top-left
+// corner of the images.
+type DiffReport struct {
+	// Count is the number of pixels with at least one differing sample.
+	Count int
+	// First is the first differing pixel in row-major order. It is only
+	// meaningful when Count is non-zero.
+	First image.Point
+	// Bounds is the smallest rectangle containing every differing pixel, or
+	// the empty rectangle when the images are identical.
+	Bounds image.Rectangle
+}
+
+// diffReport compares two decoded images of the same size pixel by pixel,
+// in the samples the MSE kernels compare under mode, so a pixel differs
+// exactly when it adds to the MSE. Rows the kernel finds equal are skipped.
+// Alpha is only compared when either image has an alpha channel.
+func diffReport(img1, img2 image.Image, mode AlphaMode, hasAlpha bool) *DiffReport {
+	rgba1, rgba2 := comparisonRGBA(img1, img2, mode)
+	kernel, _ := selectKernel(rgba1, rgba2, hasAlpha)
+	width, height := rgba1.Rect.Dx(), rgba1.Rect.Dy()
+	channelCount := 3
+	if hasAlpha {
+		channelCount = 4
+	}
+
+	report := &DiffReport{}
+	for y := 0; y < height; y++ {
+		if kernel(y, y+1) == 0 {
+			continue
+		}
+		row1 := rgba1.Pix[rgba1.PixOffset(0, y):]
+		row2 := rgba2.Pix[rgba2.PixOffset(0, y):]
+		for x := 0; x < width; x++ {
+			i := x * 4
+			differs := false
+			for c := 0; c < channelCount; c++ {
+				if row1[i+c] != row2[i+c] {
+					differs = true
+					break
+				}
+			}
+			if !differs {
+				continue
+			}
+
+			pixel := image.Rect(x, y, x+1, y+1)
+			if report.Count == 0 {
+				report.First = pixel.Min
+				report.Bounds = pixel
+			} else {
+				report.Bounds = report.Bounds.Union(pixel)
+			}
+			report.Count++
+		}
+	}
+
+	return report
+}
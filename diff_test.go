@@ -0,0 +1,102 @@
+package psnr
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestWithDiffReport(t *testing.T) {
+	encode := func(img image.Image) []byte {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			t.Fatalf("Failed to encode image: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	base := image.NewRGBA(image.Rect(0, 0, 16, 12))
+	for i := range base.Pix {
+		base.Pix[i] = uint8(i)
+	}
+	for i := 3; i < len(base.Pix); i += 4 {
+		base.Pix[i] = 255
+	}
+	changed := image.NewRGBA(base.Rect)
+	copy(changed.Pix, base.Pix)
+	for _, p := range []image.Point{{5, 3}, {10, 7}, {2, 9}} {
+		changed.SetRGBA(p.X, p.Y, color.RGBA{0, 0, 0, 255})
+	}
+
+	result, err := ComputeDetailed(encode(base), encode(changed), WithDiffReport())
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	want := DiffReport{Count: 3, First: image.Pt(5, 3), Bounds: image.Rect(2, 3, 11, 10)}
+	if result.Diff == nil || *result.Diff != want {
+		t.Errorf("Expected %+v, got %+v", want, result.Diff)
+	}
+
+	// The same pixels in a different encoding are lossless
+	gray := image.NewGray(image.Rect(0, 0, 4, 4))
+	rgba := image.NewRGBA(gray.Rect)
+	for i := range gray.Pix {
+		gray.Pix[i] = uint8(i * 16)
+		rgba.SetRGBA(i%4, i/4, color.RGBA{gray.Pix[i], gray.Pix[i], gray.Pix[i], 255})
+	}
+	for _, pair := range [][2][]byte{
+		{encode(gray), encode(rgba)},
+		{encode(gray), encode(gray)},
+	} {
+		result, err := ComputeDetailed(pair[0], pair[1], WithDiffReport())
+		if err != nil {
+			t.Fatalf("Error computing PSNR: %v", err)
+		}
+		if result.Diff == nil || *result.Diff != (DiffReport{}) {
+			t.Errorf("Expected an empty report for identical pixels, got %+v", result.Diff)
+		}
+	}
+
+	result, err = ComputeDetailed(encode(base), encode(changed))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if result.Diff != nil {
+		t.Errorf("Expected no report without the option, got %+v", result.Diff)
+	}
+}
+
+func TestDiffReportAlphaMode(t *testing.T) {
+	// The hidden color of a transparent pixel counts in straight alpha only
+	img1 := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	img2 := image.NewNRGBA(img1.Rect)
+	for i := range img1.Pix {
+		img1.Pix[i], img2.Pix[i] = 200, 200
+	}
+	img1.SetNRGBA(1, 1, color.NRGBA{10, 20, 30, 0})
+	img2.SetNRGBA(1, 1, color.NRGBA{90, 20, 30, 0})
+
+	for _, tt := range []struct {
+		mode AlphaMode
+		want DiffReport
+	}{
+		{AlphaAuto, DiffReport{Count: 1, First: image.Pt(1, 1), Bounds: image.Rect(1, 1, 2, 2)}},
+		{AlphaStraight, DiffReport{Count: 1, First: image.Pt(1, 1), Bounds: image.Rect(1, 1, 2, 2)}},
+		{AlphaPremultiplied, DiffReport{}},
+	} {
+		result, err := CompareImages(context.Background(), img1, img2, WithDiffReport(), WithAlphaMode(tt.mode))
+		if err != nil {
+			t.Fatalf("Error computing PSNR: %v", err)
+		}
+		if *result.Diff != tt.want {
+			t.Errorf("Mode %v: expected %+v, got %+v", tt.mode, tt.want, *result.Diff)
+		}
+		// A pixel is reported exactly when it adds to the MSE
+		if identical := result.MSE == 0; identical != (result.Diff.Count == 0) {
+			t.Errorf("Mode %v: MSE %f disagrees with %d differing pixels", tt.mode, result.MSE, result.Diff.Count)
+		}
+	}
+}
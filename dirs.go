@@ -0,0 +1,107 @@
+package psnr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"runtime"
+	"strings"
+)
+
+// ErrNoMatch is reported by CompareDirs for a file that has no counterpart
+// in the second filesystem.
+var ErrNoMatch = errors.New("no matching file")
+
+// DirResult is the outcome for one file of CompareDirs.
+type DirResult struct {
+	// Path is the file's path in the first filesystem.
+	Path string
+	// MatchedPath is the path in the second filesystem it was compared with.
+	MatchedPath string
+	Result      *Result
+	// Err is the error for this file, wrapping ErrNoMatch when the matched
+	// path does not exist; other files are unaffected by it unless
+	// BatchOptions.FailFast is set.
+	Err error
+}
+
+// SamePath is the default CompareDirs matcher: it pairs each JPEG or PNG
+// file with the file at the same relative path and skips everything else.
+func SamePath(name string) string {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".jpg", ".jpeg", ".png":
+		return name
+	}
+	return ""
+}
+
+// CompareDirs walks fs1 and compares every file for which matcher returns a
+// path with that file in fs2, e.g. to verify a directory of optimized images
+// against their originals. matcher defaults to SamePath; returning "" skips
+// a file. Files are compared on opts.Workers goroutines and the results are
+// returned in lexical order of Path. The error is only non-nil when fs1
+// cannot be walked.
+func CompareDirs(ctx context.Context, fs1, fs2 fs.FS, matcher func(path string) string, opts BatchOptions) ([]DirResult, error) {
+	if matcher == nil {
+		matcher = SamePath
+	}
+
+	var results []DirResult
+	err := fs.WalkDir(fs1, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		if matched := matcher(name); matched != "" {
+			results = append(results, DirResult{Path: name, MatchedPath: matched})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, abort := context.WithCancelCause(ctx)
+	defer abort(nil)
+	budget := newMemoryBudget(opts.MemoryBudget)
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	parallel(min(workers, max(len(results), 1)), len(results), func(i int) {
+		r := &results[i]
+		r.Result, r.Err = compareDirFile(ctx, fs1, fs2, r.Path, r.MatchedPath, opts, budget)
+		if r.Err != nil && opts.FailFast {
+			r.Err = abortBatch(ctx, abort, r.Err)
+		}
+	})
+	return results, nil
+}
+
+// compareDirFile loads and compares one file pair within budget, failing
+// fast once ctx is done.
+func compareDirFile(ctx context.Context, fs1, fs2 fs.FS, path1, path2 string, opts BatchOptions, budget *memoryBudget) (*Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	data1, err := fs.ReadFile(fs1, path1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path1, err)
+	}
+	data2, err := fs.ReadFile(fs2, path2)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNoMatch, path2)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path2, err)
+	}
+
+	return budget.compareWithin(ctx, data1, data2, func() (*Result, error) {
+		return computeCached(ctx, data1, data2, opts.Options, opts.Cache)
+	})
+}
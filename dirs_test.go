@@ -0,0 +1,93 @@
+package psnr
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCompareDirs(t *testing.T) {
+	original, err := os.ReadFile("testdata/test_original.jpg")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+	compressed, err := os.ReadFile("testdata/quality_50.jpg")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+
+	originals := fstest.MapFS{
+		"a.jpg":          {Data: original},
+		"sub/b.jpg":      {Data: original},
+		"missing.jpg":    {Data: original},
+		"notes.txt":      {Data: []byte("not an image")},
+		"sub/broken.png": {Data: []byte("not a png")},
+	}
+	optimized := fstest.MapFS{
+		"a.jpg":          {Data: original},
+		"sub/b.jpg":      {Data: compressed},
+		"sub/broken.png": {Data: []byte("not a png")},
+	}
+
+	results, err := CompareDirs(context.Background(), originals, optimized, nil, BatchOptions{Workers: 2})
+	if err != nil {
+		t.Fatalf("Error comparing directories: %v", err)
+	}
+
+	var paths []string
+	for _, r := range results {
+		paths = append(paths, r.Path)
+	}
+	if got := strings.Join(paths, ","); got != "a.jpg,missing.jpg,sub/b.jpg,sub/broken.png" {
+		t.Fatalf("Unexpected files: %s", got)
+	}
+	if results[0].Err != nil || !math.IsInf(results[0].Result.PSNR, 1) {
+		t.Errorf("Expected identical a.jpg, got %+v", results[0])
+	}
+	if !errors.Is(results[1].Err, ErrNoMatch) {
+		t.Errorf("Expected ErrNoMatch for missing.jpg, got %v", results[1].Err)
+	}
+	want, err := Compute(original, compressed)
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if results[2].Err != nil || results[2].Result.PSNR != want {
+		t.Errorf("Expected %.6f for sub/b.jpg, got %+v", want, results[2])
+	}
+	if results[3].Err == nil {
+		t.Error("Expected decode error for sub/broken.png")
+	}
+
+	// FailFast skips the files after the first failure
+	results, err = CompareDirs(context.Background(), originals, optimized, nil, BatchOptions{Workers: 1, FailFast: true})
+	if err != nil {
+		t.Fatalf("Error comparing directories: %v", err)
+	}
+	if results[0].Err != nil || !errors.Is(results[1].Err, ErrNoMatch) {
+		t.Errorf("Expected a.jpg to pass and missing.jpg to fail, got %+v", results[:2])
+	}
+	for _, r := range results[2:] {
+		if !errors.Is(r.Err, ErrBatchAborted) {
+			t.Errorf("Expected ErrBatchAborted for %s, got %v", r.Path, r.Err)
+		}
+	}
+
+	// A custom matcher maps originals to renamed outputs
+	renamed := fstest.MapFS{"out/a.opt.jpg": {Data: compressed}}
+	results, err = CompareDirs(context.Background(), originals, renamed, func(p string) string {
+		if p != "a.jpg" {
+			return ""
+		}
+		return "out/a.opt.jpg"
+	}, BatchOptions{})
+	if err != nil {
+		t.Fatalf("Error comparing directories: %v", err)
+	}
+	if len(results) != 1 || results[0].MatchedPath != "out/a.opt.jpg" || results[0].Result.PSNR != want {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+}
@@ -0,0 +1,93 @@
+package psnr
+
+import "image"
+
+// maxDitherBox bounds WithDitherTolerance; larger boxes erase the detail
+// being compared.
+const maxDitherBox = 8
+
+// DitherResult is the error measured by WithDitherTolerance.
+type DitherResult struct {
+	// Box is the side of the box filter in pixels.
+	Box int
+	// PSNR and MSE compare the box-filtered images.
+	PSNR float64
+	MSE  float64
+}
+
+// ditherResult compares the box averages of every box x box window that
+// lies within the region r of img1 and the same-sized region of img2 at
+// origin2. Averaging the window sums in floating point keeps the fractional
+// error of smoothed dither patterns instead of rounding it away.
+func ditherResult(img1, img2 *image.RGBA, r image.Rectangle, origin2 image.Point, hasAlpha bool, box int) *DitherResult {
+	channels := 3
+	if hasAlpha {
+		channels = 4
+	}
+	width, height := r.Dx()-box+1, r.Dy()-box+1
+	if width <= 0 || height <= 0 {
+		// Regions smaller than the box are compared as a single average
+		box = min(r.Dx(), r.Dy())
+		width, height = r.Dx()-box+1, r.Dy()-box+1
+	}
+
+	sums1 := boxSums(img1, r.Min, r.Dx(), r.Dy(), box)
+	sums2 := boxSums(img2, origin2, r.Dx(), r.Dy(), box)
+	area := float64(box * box)
+	var sum float64
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			i := (y*width + x) * 4
+			for c := 0; c < channels; c++ {
+				diff := float64(int64(sums1[i+c])-int64(sums2[i+c])) / area
+				sum += diff * diff
+			}
+		}
+	}
+
+	result := &DitherResult{Box: box}
+	result.MSE = sum / float64(width*height*channels)
+	result.PSNR = psnrFromMSE(result.MSE)
+	return result
+}
+
+// boxSums returns the per-channel sums of every box x box window of the
+// width x height region of img at origin, in row-major order with four
+// sums per window.
+func boxSums(img *image.RGBA, origin image.Point, width, height, box int) []uint32 {
+	// Horizontal running sums, then vertical running sums of those
+	rowWidth := width - box + 1
+	rows := make([]uint32, rowWidth*height*4)
+	for y := 0; y < height; y++ {
+		pix := img.Pix[img.PixOffset(origin.X, origin.Y+y):]
+		out := rows[y*rowWidth*4:]
+		var acc [4]uint32
+		for x := 0; x < width; x++ {
+			for c := 0; c < 4; c++ {
+				acc[c] += uint32(pix[x*4+c])
+				if x >= box {
+					acc[c] -= uint32(pix[(x-box)*4+c])
+				}
+			}
+			if x >= box-1 {
+				copy(out[(x-box+1)*4:], acc[:])
+			}
+		}
+	}
+
+	outHeight := height - box + 1
+	sums := make([]uint32, rowWidth*outHeight*4)
+	for x := 0; x < rowWidth*4; x++ {
+		var acc uint32
+		for y := 0; y < height; y++ {
+			acc += rows[y*rowWidth*4+x]
+			if y >= box {
+				acc -= rows[(y-box)*rowWidth*4+x]
+			}
+			if y >= box-1 {
+				sums[(y-box+1)*rowWidth*4+x] = acc
+			}
+		}
+	}
+	return sums
+}
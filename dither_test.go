@@ -0,0 +1,67 @@
+package psnr
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestWithDitherTolerance(t *testing.T) {
+	// Mid gray against its 1-bit checkerboard dither
+	gray := image.NewGray(image.Rect(0, 0, 32, 32))
+	dithered := image.NewGray(gray.Rect)
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			gray.SetGray(x, y, color.Gray{Y: 128})
+			if (x+y)%2 == 0 {
+				dithered.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	data1, data2 := encodePNG(t, gray), encodePNG(t, dithered)
+
+	result, err := ComputeDetailed(data1, data2, WithDitherTolerance(2))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if result.PSNR > 10 {
+		t.Errorf("Expected a low raw PSNR, got %.2f dB", result.PSNR)
+	}
+	// Every 2x2 window of the checkerboard averages 127.5
+	if result.Dither == nil || result.Dither.Box != 2 || result.Dither.MSE != 0.25 {
+		t.Fatalf("Unexpected dither result: %+v", result.Dither)
+	}
+	if want := psnrFromMSE(0.25); math.Abs(result.Dither.PSNR-want) > 1e-9 {
+		t.Errorf("Expected %.2f dB after filtering, got %.2f dB", want, result.Dither.PSNR)
+	}
+
+	plain, err := ComputeDetailed(data1, data2)
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if plain.Dither != nil || plain.PSNR != result.PSNR {
+		t.Errorf("Expected the raw PSNR to be unchanged, got %+v", plain)
+	}
+
+	for _, box := range []int{1, 9, -2} {
+		if _, err := ComputeDetailed(data1, data2, WithDitherTolerance(box)); err == nil {
+			t.Errorf("Expected error for box size %d", box)
+		}
+	}
+}
+
+func TestBoxSums(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 3))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i / 4)
+	}
+	sums := boxSums(img, image.Point{}, 4, 3, 2)
+	// Windows are 3x2; pixel values are their index 0..11
+	want := []uint32{0 + 1 + 4 + 5, 1 + 2 + 5 + 6, 2 + 3 + 6 + 7, 4 + 5 + 8 + 9, 5 + 6 + 9 + 10, 6 + 7 + 10 + 11}
+	for i, w := range want {
+		if sums[i*4] != w || sums[i*4+3] != w {
+			t.Errorf("Window %d: got %d, want %d", i, sums[i*4], w)
+		}
+	}
+}
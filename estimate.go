@@ -0,0 +1,214 @@
+package psnr
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// jpegBlockSize is the size of the DCT blocks whose edges JPEG compression
+// makes visible.
+const jpegBlockSize = 8
+
+// EstimateBlockiness decodes data and returns EstimateImageBlockiness of the
+// image. Options select how data is decoded, such as WithDecoder,
+// WithDecodeLimits or WithTolerantDecode; comparison options are ignored.
+func EstimateBlockiness(data []byte, opts ...Option) (float64, error) {
+	img, err := decodeForEstimate(data, opts)
+	if err != nil {
+		return 0, err
+	}
+	return EstimateImageBlockiness(img), nil
+}
+
+// EstimateBlur decodes data and returns EstimateImageBlur of the image.
+// Options select how data is decoded, as with EstimateBlockiness.
+func EstimateBlur(data []byte, opts ...Option) (float64, error) {
+	img, err := decodeForEstimate(data, opts)
+	if err != nil {
+		return 0, err
+	}
+	return EstimateImageBlur(img), nil
+}
+
+// EstimateNoise decodes both images and returns EstimateImageNoise of them.
+// Options select how data is decoded, as with EstimateBlockiness.
+func EstimateNoise(data1, data2 []byte, opts ...Option) (float64, error) {
+	img1, err := decodeForEstimate(data1, opts)
+	if err != nil {
+		return 0, err
+	}
+	img2, err := decodeForEstimate(data2, opts)
+	if err != nil {
+		return 0, err
+	}
+	return EstimateImageNoise(img1, img2)
+}
+
+// EstimateImageBlockiness estimates the JPEG blocking artifacts of a single
+// image without a reference. It returns the mean absolute luma step across
+// the edges of the 8x8 grid divided by the mean step between other
+// neighbouring pixels: about 1 for images without blocking, growing as block
+// edges become visible. A flat image gives 1, and one whose only steps lie
+// on block edges gives +Inf. Images at most one block (8 pixels) wide and
+// high have no block edge to measure and also give 1, whatever their
+// content.
+func EstimateImageBlockiness(img image.Image) float64 {
+	if size := img.Bounds().Size(); size.X <= jpegBlockSize && size.Y <= jpegBlockSize {
+		return 1
+	}
+	rgba := toRGBA(img)
+	width, height := rgba.Rect.Dx(), rgba.Rect.Dy()
+	luma := lumaPlane(rgba)
+
+	var edge, inner float64
+	var edges, inners int
+	step := func(a, b float64, onEdge bool) {
+		if onEdge {
+			edge += math.Abs(a - b)
+			edges++
+		} else {
+			inner += math.Abs(a - b)
+			inners++
+		}
+	}
+	for y := 0; y < height; y++ {
+		row := luma[y*width : (y+1)*width]
+		for x := 1; x < width; x++ {
+			step(row[x-1], row[x], x%jpegBlockSize == 0)
+		}
+	}
+	for y := 1; y < height; y++ {
+		above, row := luma[(y-1)*width:y*width], luma[y*width:(y+1)*width]
+		onEdge := y%jpegBlockSize == 0
+		for x := 0; x < width; x++ {
+			step(above[x], row[x], onEdge)
+		}
+	}
+
+	switch {
+	case edge == 0 && inner == 0:
+		return 1
+	case inner == 0:
+		return math.Inf(1)
+	}
+	return (edge / float64(edges)) / (inner / float64(inners))
+}
+
+// EstimateImageBlur estimates the sharpness of a single image without a
+// reference as the variance of the Laplacian of its luma. Low values
+// indicate blur: detail and edges give a strong Laplacian response that
+// blurring flattens. The scale depends on content and resolution, so
+// thresholds are best tuned on known-good images of the same kind. Pixels
+// at the border are repeated beyond it, and a flat image gives 0.
+func EstimateImageBlur(img image.Image) float64 {
+	rgba := toRGBA(img)
+	width, height := rgba.Rect.Dx(), rgba.Rect.Dy()
+	if width == 0 || height == 0 {
+		return 0
+	}
+	luma := lumaPlane(rgba)
+	at := func(x, y int) float64 {
+		x = min(max(x, 0), width-1)
+		y = min(max(y, 0), height-1)
+		return luma[y*width+x]
+	}
+
+	var sum, sumSquares float64
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			laplacian := at(x-1, y) + at(x+1, y) + at(x, y-1) + at(x, y+1) - 4*at(x, y)
+			sum += laplacian
+			sumSquares += laplacian * laplacian
+		}
+	}
+	n := float64(width * height)
+	mean := sum / n
+	return max(sumSquares/n-mean*mean, 0)
+}
+
+// noiseFlatness is the largest luma step, on the 0-255 scale, between a
+// pixel of the reference and its neighbours for the pixel to count as flat
+// in EstimateImageNoise.
+const noiseFlatness = 4
+
+// EstimateImageNoise estimates the standard deviation, on the 0-255 scale,
+// of additive noise in img2 relative to the reference img1, e.g. to evaluate
+// a denoiser in more interpretable terms than PSNR. It is the root mean
+// squared R, G and B error over the flat pixels of the reference, those
+// whose luma differs from all eight neighbours by at most 4, since errors
+// on edges and texture mostly come from resampling or blurring rather than
+// noise. Without any flat pixel, all pixels are used.
+func EstimateImageNoise(img1, img2 image.Image) (float64, error) {
+	if err := checkDimensions(img1, img2); err != nil {
+		return 0, err
+	}
+	rgba1, rgba2 := toRGBA(img1), toRGBA(img2)
+	width, height := rgba1.Rect.Dx(), rgba1.Rect.Dy()
+	if width == 0 || height == 0 {
+		return 0, nil
+	}
+	luma := lumaPlane(rgba1)
+	flat := func(x, y int) bool {
+		center := luma[y*width+x]
+		for ny := max(y-1, 0); ny <= min(y+1, height-1); ny++ {
+			for nx := max(x-1, 0); nx <= min(x+1, width-1); nx++ {
+				if math.Abs(luma[ny*width+nx]-center) > noiseFlatness {
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	var flatSum, allSum compensatedSum
+	var flatCount int
+	for y := 0; y < height; y++ {
+		var flatRow, allRow float64
+		for x := 0; x < width; x++ {
+			i, j := rgba1.PixOffset(x, y), rgba2.PixOffset(x, y)
+			var squared float64
+			for c := 0; c < 3; c++ {
+				diff := float64(rgba1.Pix[i+c]) - float64(rgba2.Pix[j+c])
+				squared += diff * diff
+			}
+			allRow += squared
+			if flat(x, y) {
+				flatRow += squared
+				flatCount++
+			}
+		}
+		flatSum.add(flatRow)
+		allSum.add(allRow)
+	}
+	if flatCount == 0 {
+		return math.Sqrt(allSum.value() / float64(width*height*3)), nil
+	}
+	return math.Sqrt(flatSum.value() / float64(flatCount*3)), nil
+}
+
+// decodeForEstimate decodes a single image for the no-reference estimators.
+func decodeForEstimate(data []byte, opts []Option) (image.Image, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	d, err := o.decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return d.img, nil
+}
+
+// lumaPlane returns the BT.601 luma of each pixel of img, row by row.
+func lumaPlane(img *image.RGBA) []float64 {
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	luma := make([]float64, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			i := img.PixOffset(x, y)
+			luma[y*width+x] = 0.299*float64(img.Pix[i]) + 0.587*float64(img.Pix[i+1]) + 0.114*float64(img.Pix[i+2])
+		}
+	}
+	return luma
+}
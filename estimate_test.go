@@ -0,0 +1,150 @@
+package psnr
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestEstimateBlockiness(t *testing.T) {
+	data, err := os.ReadFile("testdata/test_original.png")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+	img := decodeTestImage(t, data)
+
+	original, err := EstimateBlockiness(data)
+	if err != nil {
+		t.Fatalf("EstimateBlockiness failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 5}); err != nil {
+		t.Fatal(err)
+	}
+	compressed, err := EstimateBlockiness(buf.Bytes())
+	if err != nil {
+		t.Fatalf("EstimateBlockiness failed: %v", err)
+	}
+	t.Logf("original %.3f, quality 5 %.3f", original, compressed)
+	if original > 1.3 {
+		t.Errorf("Expected no blocking in the original, got %f", original)
+	}
+	if compressed <= original+0.3 {
+		t.Errorf("Expected stronger blocking at quality 5, got %f vs %f", compressed, original)
+	}
+
+	if _, err := EstimateBlockiness([]byte("not an image")); err == nil {
+		t.Error("Expected error for invalid data")
+	}
+}
+
+func TestEstimateImageBlockinessSynthetic(t *testing.T) {
+	flat := image.NewGray(image.Rect(0, 0, 32, 32))
+	if got := EstimateImageBlockiness(flat); got != 1 {
+		t.Errorf("Expected 1 for a flat image, got %f", got)
+	}
+
+	// Uniform 8x8 tiles only change at block edges
+	tiles := image.NewGray(image.Rect(0, 0, 32, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			tiles.SetGray(x, y, color.Gray{Y: uint8((x/8 + y/8) % 2 * 100)})
+		}
+	}
+	if got := EstimateImageBlockiness(tiles); !math.IsInf(got, 1) {
+		t.Errorf("Expected +Inf for tiles, got %f", got)
+	}
+
+	if got := EstimateImageBlockiness(image.NewGray(image.Rect(0, 0, 6, 6))); got != 1 {
+		t.Errorf("Expected 1 for an image smaller than a block, got %f", got)
+	}
+	// A single block has no edge, even with content
+	if got := EstimateImageBlockiness(tiles.SubImage(image.Rect(4, 4, 12, 12))); got != 1 {
+		t.Errorf("Expected 1 for an image of one block, got %f", got)
+	}
+	// One pixel more crosses a vertical edge, which is measured
+	if got := EstimateImageBlockiness(tiles.SubImage(image.Rect(0, 0, 9, 8))); !math.IsInf(got, 1) {
+		t.Errorf("Expected +Inf for a tile edge, got %f", got)
+	}
+}
+
+func TestEstimateBlur(t *testing.T) {
+	data, err := os.ReadFile("testdata/test_original.png")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+	img := decodeTestImage(t, data)
+
+	sharp, err := EstimateBlur(data)
+	if err != nil {
+		t.Fatalf("EstimateBlur failed: %v", err)
+	}
+	blurred := EstimateImageBlur(gaussianBlur(img, 2))
+	t.Logf("sharp %.1f, blurred %.1f", sharp, blurred)
+	if blurred >= sharp/2 {
+		t.Errorf("Expected blurring to lower the estimate, got %f vs %f", blurred, sharp)
+	}
+
+	if got := EstimateImageBlur(image.NewGray(image.Rect(0, 0, 16, 16))); got != 0 {
+		t.Errorf("Expected 0 for a flat image, got %f", got)
+	}
+}
+
+func TestEstimateImageNoise(t *testing.T) {
+	// Left half flat gray, right half a checkerboard
+	img := SolidImage(image.Rect(0, 0, 128, 128), color.Gray{Y: 128})
+	for y := 0; y < 128; y++ {
+		for x := 64; x < 128; x++ {
+			if (x+y)%2 == 0 {
+				img.SetRGBA(x, y, color.RGBA{255, 255, 255, 255})
+			}
+		}
+	}
+	noisy := NoisyImage(img, 3, 7)
+	// Inverting the checkerboard is not noise and must not count
+	for y := 0; y < 128; y++ {
+		for x := 64; x < 128; x++ {
+			i := noisy.PixOffset(x, y)
+			for c := 0; c < 3; c++ {
+				noisy.Pix[i+c] = 255 - img.Pix[i+c]
+			}
+		}
+	}
+
+	sigma, err := EstimateImageNoise(img, noisy)
+	if err != nil {
+		t.Fatalf("EstimateImageNoise failed: %v", err)
+	}
+	if want := math.Sqrt(9 + 1.0/12); math.Abs(sigma-want)/want > 0.05 {
+		t.Errorf("Expected sigma near %v, got %v", want, sigma)
+	}
+	result, err := CompareImages(context.Background(), img, noisy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Sqrt(result.MSE) < 10*sigma {
+		t.Errorf("Expected the edges to dominate the RMSE, got %v vs sigma %v", math.Sqrt(result.MSE), sigma)
+	}
+
+	// Identical images have no noise, flat or not
+	if sigma, err := EstimateImageNoise(noisy, noisy); err != nil || sigma != 0 {
+		t.Errorf("Expected 0 for identical images, got %v, %v", sigma, err)
+	}
+	if _, err := EstimateImageNoise(img, image.NewRGBA(image.Rect(0, 0, 8, 8))); err == nil {
+		t.Error("Expected error for mismatched dimensions")
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	if sigma, err := EstimateNoise(buf.Bytes(), buf.Bytes()); err != nil || sigma != 0 {
+		t.Errorf("Expected 0 for identical files, got %v, %v", sigma, err)
+	}
+}
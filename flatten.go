@@ -0,0 +1,17 @@
+package psnr
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// flatten composites a decoded image over an opaque background. The result
+// is opaque, so alpha detection leaves the alpha channel out.
+func flatten(d *decoded, background color.Color) *decoded {
+	bounds := d.img.Bounds()
+	flat := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(flat, flat.Rect, image.NewUniform(background), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Rect, d.img, bounds.Min, draw.Over)
+	return d.withImage(flat)
+}
@@ -0,0 +1,72 @@
+package psnr
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+)
+
+func TestWithFlattenOver(t *testing.T) {
+	// A translucent red square on a transparent background, and the same
+	// image as an editor exports it to an opaque format over white
+	transparent := image.NewNRGBA(image.Rect(0, 0, 32, 32))
+	for y := 8; y < 24; y++ {
+		for x := 8; x < 24; x++ {
+			transparent.SetNRGBA(x, y, color.NRGBA{R: 200, G: 30, B: 30, A: 160})
+		}
+	}
+	exported := image.NewRGBA(transparent.Rect)
+	draw.Draw(exported, exported.Rect, image.White, image.Point{}, draw.Src)
+	draw.Draw(exported, exported.Rect, transparent, image.Point{}, draw.Over)
+	data1, data2 := encodePNG(t, transparent), encodePNG(t, exported)
+
+	raw, err := ComputeDetailed(data1, data2)
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if raw.PSNR > 10 {
+		t.Errorf("Expected the background difference to dominate, got %.2f dB", raw.PSNR)
+	}
+
+	flat, err := ComputeDetailed(data1, data2, WithFlattenOver(color.White))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if !math.IsInf(flat.PSNR, 1) {
+		t.Errorf("Expected identical images over white, got %.2f dB", flat.PSNR)
+	}
+
+	black, err := ComputeDetailed(data1, data2, WithFlattenOver(color.Black))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if black.PSNR > 10 {
+		t.Errorf("Expected a large difference over the wrong background, got %.2f dB", black.PSNR)
+	}
+
+	if _, err := ComputeDetailed(data1, data2, WithFlattenOver(color.Transparent)); err == nil {
+		t.Error("Expected error for a translucent background")
+	}
+	if _, err := ComputeDetailed(data1, data2, WithFlattenOver(color.White), WithCompatibility(CompatibilityImageMagick)); err == nil {
+		t.Error("Expected error when combined with a compatibility mode")
+	}
+}
+
+func TestFlattenKeepsDecoderFields(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 8, 4))
+	d := &decoded{img: img, format: "png", data: []byte{1}, partialRows: 3, grayAlpha: true, backend: "tolerant"}
+
+	for name, got := range map[string]*decoded{
+		"flatten":   flatten(d, color.White),
+		"downscale": downscaleDecoded(d, 4),
+	} {
+		if got.img == d.img {
+			t.Errorf("%s: expected a new image", name)
+		}
+		if got.format != d.format || got.partialRows != d.partialRows || got.grayAlpha != d.grayAlpha || got.backend != d.backend {
+			t.Errorf("%s: expected the decoder fields of %+v, got %+v", name, *d, *got)
+		}
+	}
+}
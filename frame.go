@@ -0,0 +1,59 @@
+package psnr
+
+import (
+	"context"
+	"image"
+)
+
+// Frame is a decoded image returned by Decode. Comparing frames skips
+// decoding, so an image compared many times, against several candidates or
+// with several option sets, is decoded once. A Frame is immutable and safe
+// for concurrent use.
+type Frame struct {
+	d *decoded
+}
+
+// Decode decodes an encoded image into a Frame. Options that control
+// decoding, such as WithDecoder, WithTolerantDecode and the decode limits,
+// apply here; the other options are given to CompareFrames.
+func Decode(data []byte, opts ...Option) (*Frame, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	d, err := o.decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return &Frame{d: d}, nil
+}
+
+// Image returns the decoded image. It must not be modified.
+func (f *Frame) Image() image.Image {
+	return f.d.img
+}
+
+// Format returns the name of the format the frame was decoded from, such as
+// "jpeg" or "png".
+func (f *Frame) Format() string {
+	return f.d.format
+}
+
+// CompareFrames compares two decoded frames like ComputeContext compares
+// their encoded forms. The frames are not modified and can be compared
+// again.
+func CompareFrames(ctx context.Context, f1, f2 *Frame, opts ...Option) (*Result, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return compareFramesWith(ctx, f1, f2, o)
+}
+
+// compareFramesWith is CompareFrames with validated options.
+func compareFramesWith(ctx context.Context, f1, f2 *Frame, o *options) (*Result, error) {
+	if err := hashPrefilter(f1.d.img, f2.d.img, o); err != nil {
+		return nil, err
+	}
+	return compare(ctx, f1.d, f2.d, o)
+}
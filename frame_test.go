@@ -0,0 +1,62 @@
+package psnr
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestCompareFrames(t *testing.T) {
+	ctx := context.Background()
+	data1, err := os.ReadFile("testdata/test_original.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data2, err := os.ReadFile("testdata/test_quality_85.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f1, err := Decode(data1)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	f2, err := Decode(data2)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if f1.Format() != "png" || f1.Image().Bounds().Empty() {
+		t.Errorf("Unexpected frame: %s %v", f1.Format(), f1.Image().Bounds())
+	}
+
+	// The same frames are reused across option sets
+	for _, opts := range [][]Option{nil, {WithComponentPSNR()}, {WithMetadataDiff()}} {
+		want, err := ComputeDetailed(data1, data2, opts...)
+		if err != nil {
+			t.Fatalf("ComputeDetailed failed: %v", err)
+		}
+		got, err := CompareFrames(ctx, f1, f2, opts...)
+		if err != nil {
+			t.Fatalf("CompareFrames failed: %v", err)
+		}
+		if got.PSNR != want.PSNR || (got.Components == nil) != (want.Components == nil) || (got.Metadata == nil) != (want.Metadata == nil) {
+			t.Errorf("Expected %+v, got %+v", want, got)
+		}
+	}
+
+	got, err := New().CompareFrames(ctx, f1, f2)
+	if err != nil {
+		t.Fatalf("Comparer.CompareFrames failed: %v", err)
+	}
+	if again, _ := CompareFrames(ctx, f1, f2); got.PSNR != again.PSNR {
+		t.Errorf("Expected repeated comparisons to agree, got %v and %v", got.PSNR, again.PSNR)
+	}
+
+	if _, err := Decode([]byte("not an image")); err == nil {
+		t.Error("Expected error for invalid data")
+	}
+	if _, err := Decode(data1, WithMaxPixels(16)); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("Expected ErrImageTooLarge, got %v", err)
+	}
+}
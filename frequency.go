@@ -0,0 +1,98 @@
+package psnr
+
+import (
+	"image"
+	"runtime"
+)
+
+// FrequencyResult splits the error measured by WithFrequencySplit into a
+// low-frequency part, such as a color, levels or white balance shift, and a
+// high-frequency part, such as changed sharpening, noise or lost detail.
+type FrequencyResult struct {
+	// Sigma is the standard deviation in pixels of the Gaussian separating
+	// the two bands.
+	Sigma float64
+	// Low is the error of the Gaussian-blurred difference and High the
+	// error of what remains ("low" and "high"). Their MSEs add up to about
+	// the MSE of the whole difference.
+	Low, High PlaneResult
+	// Shift is the mean difference of the R, G and B samples, second image
+	// minus first, on the 0-255 scale: the uniform part of a color shift.
+	Shift [3]float64
+}
+
+// frequencySplit decomposes the RGB difference of two images of the same
+// size into the difference blurred with a Gaussian of the given sigma and
+// the residual, and measures the error of each.
+func frequencySplit(img1, img2 image.Image, sigma float64) *FrequencyResult {
+	rgba1, rgba2 := toRGBA(img1), toRGBA(img2)
+	width, height := rgba1.Rect.Dx(), rgba1.Rect.Dy()
+
+	// One plane of differences per color channel
+	var planes [3][]float64
+	for c := range planes {
+		planes[c] = make([]float64, width*height)
+	}
+	for y := 0; y < height; y++ {
+		pix1, pix2 := rgba1.Pix[y*rgba1.Stride:], rgba2.Pix[y*rgba2.Stride:]
+		for x := 0; x < width; x++ {
+			for c := range planes {
+				planes[c][y*width+x] = float64(pix2[x*4+c]) - float64(pix1[x*4+c])
+			}
+		}
+	}
+
+	kernel := gaussianKernel(sigma)
+	var low, high compensatedSum
+	result := &FrequencyResult{Sigma: sigma}
+	for c, plane := range planes {
+		var shift compensatedSum
+		for _, d := range plane {
+			shift.add(d)
+		}
+		result.Shift[c] = shift.value() / float64(max(len(plane), 1))
+
+		blurred := blurPlane(plane, width, height, kernel)
+		for i, d := range plane {
+			low.add(blurred[i] * blurred[i])
+			high.add((d - blurred[i]) * (d - blurred[i]))
+		}
+	}
+
+	samples := float64(max(3*width*height, 1))
+	lowMSE, highMSE := low.value()/samples, high.value()/samples
+	result.Low = PlaneResult{Name: "low", PSNR: psnrFromMSE(lowMSE), MSE: lowMSE}
+	result.High = PlaneResult{Name: "high", PSNR: psnrFromMSE(highMSE), MSE: highMSE}
+	return result
+}
+
+// blurPlane returns a plane of floats blurred with a separable kernel,
+// repeating edge samples beyond the border as gaussianBlur does.
+func blurPlane(plane []float64, width, height int, kernel []float64) []float64 {
+	radius := len(kernel) / 2
+	workers := min(runtime.GOMAXPROCS(0), max(height, 1))
+
+	tmp := make([]float64, len(plane))
+	parallel(workers, height, func(y int) {
+		row := plane[y*width : (y+1)*width]
+		for x := 0; x < width; x++ {
+			var sum float64
+			for k, w := range kernel {
+				sum += w * row[min(max(x+k-radius, 0), width-1)]
+			}
+			tmp[y*width+x] = sum
+		}
+	})
+
+	dst := make([]float64, len(plane))
+	parallel(workers, height, func(y int) {
+		for x := 0; x < width; x++ {
+			var sum float64
+			for k, w := range kernel {
+				sum += w * tmp[min(max(y+k-radius, 0), height-1)*width+x]
+			}
+			dst[y*width+x] = sum
+		}
+	})
+	return dst
+}
@@ -0,0 +1,51 @@
+package psnr
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestWithFrequencySplit(t *testing.T) {
+	ctx := context.Background()
+	img := GradientImage(image.Rect(0, 0, 64, 48), color.RGBA{40, 60, 80, 255}, color.RGBA{200, 180, 160, 255})
+
+	// A color shift is all low frequency
+	shifted := image.NewRGBA(img.Rect)
+	copy(shifted.Pix, img.Pix)
+	for i := 0; i < len(shifted.Pix); i += 4 {
+		shifted.Pix[i] += 12
+	}
+	result, err := CompareImages(ctx, img, shifted, WithFrequencySplit(2))
+	if err != nil {
+		t.Fatalf("CompareImages failed: %v", err)
+	}
+	f := result.Frequency
+	if f == nil || f.Sigma != 2 || math.Abs(f.Low.MSE-48) > 1e-9 || f.High.MSE > 1e-9 {
+		t.Fatalf("Expected only low-frequency error, got %+v", f)
+	}
+	if f.Shift != [3]float64{12, 0, 0} {
+		t.Errorf("Expected a shift of 12 in red, got %v", f.Shift)
+	}
+	if math.Abs(f.Low.MSE+f.High.MSE-result.MSE) > 1e-9 {
+		t.Errorf("Expected the bands to add up to %v, got %v", result.MSE, f.Low.MSE+f.High.MSE)
+	}
+
+	// Noise is mostly high frequency
+	result, err = CompareImages(ctx, img, NoisyImage(img, 5, 1), WithFrequencySplit(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := result.Frequency; f.High.MSE < 10*f.Low.MSE || f.High.Name != "high" || f.Low.Name != "low" {
+		t.Errorf("Expected mostly high-frequency error, got %+v", f)
+	}
+
+	if result, _ := CompareImages(ctx, img, img); result.Frequency != nil {
+		t.Error("Expected no frequency split without the option")
+	}
+	if _, err := CompareImages(ctx, img, img, WithFrequencySplit(-1)); err == nil {
+		t.Error("Expected error for a negative sigma")
+	}
+}
@@ -0,0 +1,140 @@
+package psnr
+
+import (
+	"bytes"
+	"image"
+)
+
+// pathGrayAlpha names the 8-bit gray+alpha PNG code path in debug logs.
+const pathGrayAlpha = "grayalpha"
+
+// isGrayAlphaPNG reports whether data is an 8-bit gray+alpha PNG, which
+// image/png decodes to an *image.NRGBA whose color samples are all equal.
+// IHDR is always the first chunk, so its fields sit at fixed offsets.
+func isGrayAlphaPNG(data []byte) bool {
+	return len(data) >= 26 && bytes.HasPrefix(data, pngSignature) &&
+		string(data[12:16]) == "IHDR" && data[24] == 8 && data[25] == pngColorGrayAlpha
+}
+
+// grayAlphaImage returns the pixels of a decoded gray+alpha PNG.
+func grayAlphaImage(d *decoded) (*image.NRGBA, bool) {
+	if !d.grayAlpha {
+		return nil, false
+	}
+	img, ok := d.img.(*image.NRGBA)
+	return img, ok
+}
+
+// opaqueNRGBA reports whether every pixel of img is fully opaque. Unlike the
+// sampled detection it inspects each alpha sample, which for gray+alpha
+// images costs no more than the sampling's interface calls.
+func opaqueNRGBA(img *image.NRGBA) bool {
+	width := img.Rect.Dx() * 4
+	for y := 0; y < img.Rect.Dy(); y++ {
+		row := img.Pix[y*img.Stride : y*img.Stride+width]
+		for i := 3; i < len(row); i += 4 {
+			if row[i] != 0xff {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// selectDecodedKernel is selectKernel with the 2-channel path for 8-bit
+// gray+alpha PNGs compared with gray, gray+alpha or RGBA images, which would
+// otherwise take the generic path. img1 and img2 are the images of d1 and d2
+// after any color conversion.
+func selectDecodedKernel(d1, d2 *decoded, img1, img2 image.Image, hasAlpha bool) (rowKernel, string) {
+	if ga, ok := grayAlphaImage(d1); ok && ga == img1 {
+		if kernel := grayAlphaKernel(ga, d2, img2, hasAlpha); kernel != nil {
+			return kernel, pathGrayAlpha
+		}
+	}
+	if ga, ok := grayAlphaImage(d2); ok && ga == img2 {
+		if kernel := grayAlphaKernel(ga, d1, img1, hasAlpha); kernel != nil {
+			return kernel, pathGrayAlpha
+		}
+	}
+	return selectKernel(img1, img2, hasAlpha)
+}
+
+// grayAlphaKernel returns a kernel comparing a gray+alpha image with other,
+// or nil when other has no tight loop. It reads only the gray and alpha
+// samples of ga and gives the same sums as the generic path, which compares
+// the premultiplied gray of ga with other's premultiplied samples.
+func grayAlphaKernel(ga *image.NRGBA, d *decoded, other image.Image, hasAlpha bool) rowKernel {
+	width := ga.Rect.Dx()
+	switch other := other.(type) {
+	case *image.NRGBA:
+		if otherGA, ok := grayAlphaImage(d); !ok || otherGA != other {
+			// Same-type NRGBA images already have a fast path
+			return nil
+		}
+		// Both straight gray, as the NRGBA path compares them
+		return func(y0, y1 int) uint64 {
+			var sum uint64
+			for y := y0; y < y1; y++ {
+				row1 := ga.Pix[y*ga.Stride : y*ga.Stride+width*4]
+				row2 := other.Pix[y*other.Stride : y*other.Stride+width*4]
+				for i := 0; i < len(row1); i += 4 {
+					diff := int32(row1[i]) - int32(row2[i])
+					sum += 3 * uint64(diff*diff)
+					if hasAlpha {
+						diffA := int32(row1[i+3]) - int32(row2[i+3])
+						sum += uint64(diffA * diffA)
+					}
+				}
+			}
+			return sum
+		}
+	case *image.Gray:
+		return func(y0, y1 int) uint64 {
+			var sum uint64
+			for y := y0; y < y1; y++ {
+				row1 := ga.Pix[y*ga.Stride : y*ga.Stride+width*4]
+				row2 := other.Pix[y*other.Stride : y*other.Stride+width]
+				for x, g := range row2 {
+					diff := int32(premultiply(row1[4*x], row1[4*x+3])) - int32(g)
+					sum += 3 * uint64(diff*diff)
+					if hasAlpha {
+						diffA := int32(row1[4*x+3]) - 0xff
+						sum += uint64(diffA * diffA)
+					}
+				}
+			}
+			return sum
+		}
+	case *image.RGBA:
+		return func(y0, y1 int) uint64 {
+			var sum uint64
+			for y := y0; y < y1; y++ {
+				row1 := ga.Pix[y*ga.Stride : y*ga.Stride+width*4]
+				row2 := other.Pix[y*other.Stride : y*other.Stride+width*4]
+				for i := 0; i < len(row1); i += 4 {
+					gray := int32(premultiply(row1[i], row1[i+3]))
+					diffR := gray - int32(row2[i])
+					diffG := gray - int32(row2[i+1])
+					diffB := gray - int32(row2[i+2])
+					sum += uint64(diffR*diffR) + uint64(diffG*diffG) + uint64(diffB*diffB)
+					if hasAlpha {
+						diffA := int32(row1[i+3]) - int32(row2[i+3])
+						sum += uint64(diffA * diffA)
+					}
+				}
+			}
+			return sum
+		}
+	}
+	return nil
+}
+
+// premultiply returns the 8-bit premultiplied value of a straight sample,
+// rounded as color.NRGBA.RGBA followed by a shift of 8 bits.
+func premultiply(v, alpha uint8) uint8 {
+	c := uint32(v)
+	c |= c << 8
+	c *= uint32(alpha)
+	c /= 0xff
+	return uint8(c >> 8)
+}
@@ -0,0 +1,143 @@
+package psnr
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"image"
+	"log/slog"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// encodeGrayAlphaPNG encodes the gray and alpha samples of img as an 8-bit
+// gray+alpha PNG, which image/png cannot write.
+func encodeGrayAlphaPNG(t *testing.T, img *image.NRGBA) []byte {
+	t.Helper()
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	var raw bytes.Buffer
+	for y := 0; y < height; y++ {
+		raw.WriteByte(0)
+		for x := 0; x < width; x++ {
+			c := img.NRGBAAt(x, y)
+			raw.Write([]byte{c.R, c.A})
+		}
+	}
+	var idat bytes.Buffer
+	zw := zlib.NewWriter(&idat)
+	zw.Write(raw.Bytes())
+	zw.Close()
+
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:4], uint32(width))
+	binary.BigEndian.PutUint32(ihdr[4:8], uint32(height))
+	ihdr[8], ihdr[9] = 8, pngColorGrayAlpha
+
+	var buf bytes.Buffer
+	buf.Write(pngSignature)
+	writePNGChunk(&buf, "IHDR", ihdr)
+	writePNGChunk(&buf, "IDAT", idat.Bytes())
+	writePNGChunk(&buf, "IEND", nil)
+	return buf.Bytes()
+}
+
+// randomGrayAlpha returns a gray+alpha image with random samples.
+func randomGrayAlpha(rng *rand.Rand, width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < len(img.Pix); i += 4 {
+		gray := uint8(rng.Intn(256))
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = gray, gray, gray, uint8(rng.Intn(256))
+	}
+	return img
+}
+
+func TestGrayAlphaKernel(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	data := encodeGrayAlphaPNG(t, randomGrayAlpha(rng, 37, 23))
+	d, err := (&options{}).decode(data)
+	if err != nil {
+		t.Fatalf("Failed to decode gray+alpha PNG: %v", err)
+	}
+	if !d.grayAlpha {
+		t.Fatal("Expected the decoded PNG to be marked gray+alpha")
+	}
+	other, err := (&options{}).decode(encodeGrayAlphaPNG(t, randomGrayAlpha(rng, 37, 23)))
+	if err != nil {
+		t.Fatalf("Failed to decode gray+alpha PNG: %v", err)
+	}
+
+	gray := image.NewGray(image.Rect(0, 0, 37, 23))
+	rng.Read(gray.Pix)
+	rgba := image.NewRGBA(image.Rect(0, 0, 37, 23))
+	rng.Read(rgba.Pix)
+	for i := 0; i < len(rgba.Pix); i += 4 {
+		// Keep the samples valid premultiplied colors
+		a := rgba.Pix[i+3]
+		rgba.Pix[i], rgba.Pix[i+1], rgba.Pix[i+2] = min(rgba.Pix[i], a), min(rgba.Pix[i+1], a), min(rgba.Pix[i+2], a)
+	}
+
+	for _, tt := range []struct {
+		name  string
+		other *decoded
+	}{
+		{"gray", &decoded{img: gray, format: "png"}},
+		{"rgba", &decoded{img: rgba, format: "png"}},
+		{"grayalpha", other},
+	} {
+		for _, hasAlpha := range []bool{false, true} {
+			for _, swap := range []bool{false, true} {
+				d1, d2 := d, tt.other
+				if swap {
+					d1, d2 = d2, d1
+				}
+				kernel, path := selectDecodedKernel(d1, d2, d1.img, d2.img, hasAlpha)
+				if path != pathGrayAlpha {
+					t.Fatalf("%s: expected the gray+alpha path, got %s", tt.name, path)
+				}
+				// Same-type NRGBA images are compared straight, like the NRGBA path
+				want := computeMSEGeneric(d1.img, d2.img, hasAlpha, 0, 23)
+				if tt.name == "grayalpha" {
+					want = computeMSENRGBA(d1.img.(*image.NRGBA), d2.img.(*image.NRGBA), hasAlpha, 0, 23)
+				}
+				if got := kernel(0, 23); got != want {
+					t.Errorf("%s (alpha %t, swapped %t): got %d, want %d", tt.name, hasAlpha, swap, got, want)
+				}
+			}
+		}
+	}
+
+	// Converted images no longer qualify
+	if _, path := selectDecodedKernel(d, &decoded{img: gray}, toRGBA(d.img), gray, false); path == pathGrayAlpha {
+		t.Error("Expected converted images to leave the gray+alpha path")
+	}
+}
+
+func TestGrayAlphaDetectsSingleTranslucentPixel(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 100, 100))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	gray := image.NewGray(img.Rect)
+	for i := range gray.Pix {
+		gray.Pix[i] = 0xff
+	}
+	// Off the sampled grid
+	img.Pix[img.PixOffset(33, 51)+3] = 0
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	result, err := ComputeContext(context.Background(), encodeGrayAlphaPNG(t, img), encodePNG(t, gray), WithLogger(logger))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	// Premultiplied white over transparent is black: 3 color samples and
+	// alpha differ by 255 out of 4 samples per pixel
+	if want := 255.0 * 255 * 4 / (100 * 100 * 4); result.MSE != want {
+		t.Errorf("Expected MSE %v with alpha compared, got %v", want, result.MSE)
+	}
+	if !strings.Contains(logs.String(), "path=grayalpha") || !strings.Contains(logs.String(), "alpha=true") {
+		t.Errorf("Expected the gray+alpha path with alpha, got logs:\n%s", logs.String())
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+
+	psnrgrpc "github.com/ideamans/go-psnr/grpc"
+	"github.com/ideamans/go-psnr/grpc/psnrpb"
+	"google.golang.org/grpc"
+)
+
+func main() {
+	addr := flag.String("addr", ":50051", "address to listen on")
+	flag.Parse()
+
+	lis, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	s := grpc.NewServer()
+	psnrpb.RegisterPSNRServiceServer(s, psnrgrpc.NewServer())
+
+	log.Printf("PSNR gRPC server listening on %s", lis.Addr())
+	if err := s.Serve(lis); err != nil {
+		log.Fatal(err)
+	}
+}
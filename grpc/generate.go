@@ -0,0 +1,3 @@
+package psnrgrpc
+
+//go:generate protoc -I proto --go_out=. --go_opt=module=github.com/ideamans/go-psnr/grpc --go-grpc_out=. --go-grpc_opt=module=github.com/ideamans/go-psnr/grpc psnr/v1/psnr.proto
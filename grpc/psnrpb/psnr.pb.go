@@ -0,0 +1,518 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        (unknown)
+// source: psnr/v1/psnr.proto
+
+package psnrpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ComputePSNRRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Image1 []byte `protobuf:"bytes,1,opt,name=image1,proto3" json:"image1,omitempty"`
+	Image2 []byte `protobuf:"bytes,2,opt,name=image2,proto3" json:"image2,omitempty"`
+}
+
+func (x *ComputePSNRRequest) Reset() {
+	*x = ComputePSNRRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_psnr_v1_psnr_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ComputePSNRRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ComputePSNRRequest) ProtoMessage() {}
+
+func (x *ComputePSNRRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_psnr_v1_psnr_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ComputePSNRRequest.ProtoReflect.Descriptor instead.
+func (*ComputePSNRRequest) Descriptor() ([]byte, []int) {
+	return file_psnr_v1_psnr_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ComputePSNRRequest) GetImage1() []byte {
+	if x != nil {
+		return x.Image1
+	}
+	return nil
+}
+
+func (x *ComputePSNRRequest) GetImage2() []byte {
+	if x != nil {
+		return x.Image2
+	}
+	return nil
+}
+
+type ComputePSNRResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Psnr      float64 `protobuf:"fixed64,1,opt,name=psnr,proto3" json:"psnr,omitempty"`
+	Mse       float64 `protobuf:"fixed64,2,opt,name=mse,proto3" json:"mse,omitempty"`
+	Identical bool    `protobuf:"varint,3,opt,name=identical,proto3" json:"identical,omitempty"`
+}
+
+func (x *ComputePSNRResponse) Reset() {
+	*x = ComputePSNRResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_psnr_v1_psnr_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ComputePSNRResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ComputePSNRResponse) ProtoMessage() {}
+
+func (x *ComputePSNRResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_psnr_v1_psnr_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ComputePSNRResponse.ProtoReflect.Descriptor instead.
+func (*ComputePSNRResponse) Descriptor() ([]byte, []int) {
+	return file_psnr_v1_psnr_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ComputePSNRResponse) GetPsnr() float64 {
+	if x != nil {
+		return x.Psnr
+	}
+	return 0
+}
+
+func (x *ComputePSNRResponse) GetMse() float64 {
+	if x != nil {
+		return x.Mse
+	}
+	return 0
+}
+
+func (x *ComputePSNRResponse) GetIdentical() bool {
+	if x != nil {
+		return x.Identical
+	}
+	return false
+}
+
+type FramePair struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Reference []byte `protobuf:"bytes,1,opt,name=reference,proto3" json:"reference,omitempty"`
+	Distorted []byte `protobuf:"bytes,2,opt,name=distorted,proto3" json:"distorted,omitempty"`
+}
+
+func (x *FramePair) Reset() {
+	*x = FramePair{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_psnr_v1_psnr_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FramePair) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FramePair) ProtoMessage() {}
+
+func (x *FramePair) ProtoReflect() protoreflect.Message {
+	mi := &file_psnr_v1_psnr_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FramePair.ProtoReflect.Descriptor instead.
+func (*FramePair) Descriptor() ([]byte, []int) {
+	return file_psnr_v1_psnr_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *FramePair) GetReference() []byte {
+	if x != nil {
+		return x.Reference
+	}
+	return nil
+}
+
+func (x *FramePair) GetDistorted() []byte {
+	if x != nil {
+		return x.Distorted
+	}
+	return nil
+}
+
+type FrameResult struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Index uint32  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Psnr  float64 `protobuf:"fixed64,2,opt,name=psnr,proto3" json:"psnr,omitempty"`
+	Mse   float64 `protobuf:"fixed64,3,opt,name=mse,proto3" json:"mse,omitempty"`
+}
+
+func (x *FrameResult) Reset() {
+	*x = FrameResult{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_psnr_v1_psnr_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FrameResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FrameResult) ProtoMessage() {}
+
+func (x *FrameResult) ProtoReflect() protoreflect.Message {
+	mi := &file_psnr_v1_psnr_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FrameResult.ProtoReflect.Descriptor instead.
+func (*FrameResult) Descriptor() ([]byte, []int) {
+	return file_psnr_v1_psnr_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *FrameResult) GetIndex() uint32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *FrameResult) GetPsnr() float64 {
+	if x != nil {
+		return x.Psnr
+	}
+	return 0
+}
+
+func (x *FrameResult) GetMse() float64 {
+	if x != nil {
+		return x.Mse
+	}
+	return 0
+}
+
+type FramesSummary struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Frames          []*FrameResult `protobuf:"bytes,1,rep,name=frames,proto3" json:"frames,omitempty"`
+	FrameCount      uint32         `protobuf:"varint,2,opt,name=frame_count,json=frameCount,proto3" json:"frame_count,omitempty"`
+	MeanPsnr        float64        `protobuf:"fixed64,3,opt,name=mean_psnr,json=meanPsnr,proto3" json:"mean_psnr,omitempty"`
+	AveragePsnr     float64        `protobuf:"fixed64,4,opt,name=average_psnr,json=averagePsnr,proto3" json:"average_psnr,omitempty"`
+	MinPsnr         float64        `protobuf:"fixed64,5,opt,name=min_psnr,json=minPsnr,proto3" json:"min_psnr,omitempty"`
+	IdenticalFrames uint32         `protobuf:"varint,6,opt,name=identical_frames,json=identicalFrames,proto3" json:"identical_frames,omitempty"`
+}
+
+func (x *FramesSummary) Reset() {
+	*x = FramesSummary{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_psnr_v1_psnr_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FramesSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FramesSummary) ProtoMessage() {}
+
+func (x *FramesSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_psnr_v1_psnr_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FramesSummary.ProtoReflect.Descriptor instead.
+func (*FramesSummary) Descriptor() ([]byte, []int) {
+	return file_psnr_v1_psnr_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *FramesSummary) GetFrames() []*FrameResult {
+	if x != nil {
+		return x.Frames
+	}
+	return nil
+}
+
+func (x *FramesSummary) GetFrameCount() uint32 {
+	if x != nil {
+		return x.FrameCount
+	}
+	return 0
+}
+
+func (x *FramesSummary) GetMeanPsnr() float64 {
+	if x != nil {
+		return x.MeanPsnr
+	}
+	return 0
+}
+
+func (x *FramesSummary) GetAveragePsnr() float64 {
+	if x != nil {
+		return x.AveragePsnr
+	}
+	return 0
+}
+
+func (x *FramesSummary) GetMinPsnr() float64 {
+	if x != nil {
+		return x.MinPsnr
+	}
+	return 0
+}
+
+func (x *FramesSummary) GetIdenticalFrames() uint32 {
+	if x != nil {
+		return x.IdenticalFrames
+	}
+	return 0
+}
+
+var File_psnr_v1_psnr_proto protoreflect.FileDescriptor
+
+var file_psnr_v1_psnr_proto_rawDesc = []byte{
+	0x0a, 0x12, 0x70, 0x73, 0x6e, 0x72, 0x2f, 0x76, 0x31, 0x2f, 0x70, 0x73, 0x6e, 0x72, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x12, 0x07, 0x70, 0x73, 0x6e, 0x72, 0x2e, 0x76, 0x31, 0x22, 0x44, 0x0a,
+	0x12, 0x43, 0x6f, 0x6d, 0x70, 0x75, 0x74, 0x65, 0x50, 0x53, 0x4e, 0x52, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x69, 0x6d, 0x61, 0x67, 0x65, 0x31, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0c, 0x52, 0x06, 0x69, 0x6d, 0x61, 0x67, 0x65, 0x31, 0x12, 0x16, 0x0a, 0x06, 0x69,
+	0x6d, 0x61, 0x67, 0x65, 0x32, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x69, 0x6d, 0x61,
+	0x67, 0x65, 0x32, 0x22, 0x59, 0x0a, 0x13, 0x43, 0x6f, 0x6d, 0x70, 0x75, 0x74, 0x65, 0x50, 0x53,
+	0x4e, 0x52, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x73,
+	0x6e, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x01, 0x52, 0x04, 0x70, 0x73, 0x6e, 0x72, 0x12, 0x10,
+	0x0a, 0x03, 0x6d, 0x73, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x6d, 0x73, 0x65,
+	0x12, 0x1c, 0x0a, 0x09, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x63, 0x61, 0x6c, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x09, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x63, 0x61, 0x6c, 0x22, 0x47,
+	0x0a, 0x09, 0x46, 0x72, 0x61, 0x6d, 0x65, 0x50, 0x61, 0x69, 0x72, 0x12, 0x1c, 0x0a, 0x09, 0x72,
+	0x65, 0x66, 0x65, 0x72, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09,
+	0x72, 0x65, 0x66, 0x65, 0x72, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x64, 0x69, 0x73,
+	0x74, 0x6f, 0x72, 0x74, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x64, 0x69,
+	0x73, 0x74, 0x6f, 0x72, 0x74, 0x65, 0x64, 0x22, 0x49, 0x0a, 0x0b, 0x46, 0x72, 0x61, 0x6d, 0x65,
+	0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x12, 0x0a, 0x04,
+	0x70, 0x73, 0x6e, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x04, 0x70, 0x73, 0x6e, 0x72,
+	0x12, 0x10, 0x0a, 0x03, 0x6d, 0x73, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x6d,
+	0x73, 0x65, 0x22, 0xe4, 0x01, 0x0a, 0x0d, 0x46, 0x72, 0x61, 0x6d, 0x65, 0x73, 0x53, 0x75, 0x6d,
+	0x6d, 0x61, 0x72, 0x79, 0x12, 0x2c, 0x0a, 0x06, 0x66, 0x72, 0x61, 0x6d, 0x65, 0x73, 0x18, 0x01,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x70, 0x73, 0x6e, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x46,
+	0x72, 0x61, 0x6d, 0x65, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x52, 0x06, 0x66, 0x72, 0x61, 0x6d,
+	0x65, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x66, 0x72, 0x61, 0x6d, 0x65, 0x5f, 0x63, 0x6f, 0x75, 0x6e,
+	0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0a, 0x66, 0x72, 0x61, 0x6d, 0x65, 0x43, 0x6f,
+	0x75, 0x6e, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x6d, 0x65, 0x61, 0x6e, 0x5f, 0x70, 0x73, 0x6e, 0x72,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x08, 0x6d, 0x65, 0x61, 0x6e, 0x50, 0x73, 0x6e, 0x72,
+	0x12, 0x21, 0x0a, 0x0c, 0x61, 0x76, 0x65, 0x72, 0x61, 0x67, 0x65, 0x5f, 0x70, 0x73, 0x6e, 0x72,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0b, 0x61, 0x76, 0x65, 0x72, 0x61, 0x67, 0x65, 0x50,
+	0x73, 0x6e, 0x72, 0x12, 0x19, 0x0a, 0x08, 0x6d, 0x69, 0x6e, 0x5f, 0x70, 0x73, 0x6e, 0x72, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x01, 0x52, 0x07, 0x6d, 0x69, 0x6e, 0x50, 0x73, 0x6e, 0x72, 0x12, 0x29,
+	0x0a, 0x10, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x63, 0x61, 0x6c, 0x5f, 0x66, 0x72, 0x61, 0x6d,
+	0x65, 0x73, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0f, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69,
+	0x63, 0x61, 0x6c, 0x46, 0x72, 0x61, 0x6d, 0x65, 0x73, 0x32, 0x9a, 0x01, 0x0a, 0x0b, 0x50, 0x53,
+	0x4e, 0x52, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x48, 0x0a, 0x0b, 0x43, 0x6f, 0x6d,
+	0x70, 0x75, 0x74, 0x65, 0x50, 0x53, 0x4e, 0x52, 0x12, 0x1b, 0x2e, 0x70, 0x73, 0x6e, 0x72, 0x2e,
+	0x76, 0x31, 0x2e, 0x43, 0x6f, 0x6d, 0x70, 0x75, 0x74, 0x65, 0x50, 0x53, 0x4e, 0x52, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1c, 0x2e, 0x70, 0x73, 0x6e, 0x72, 0x2e, 0x76, 0x31, 0x2e,
+	0x43, 0x6f, 0x6d, 0x70, 0x75, 0x74, 0x65, 0x50, 0x53, 0x4e, 0x52, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x41, 0x0a, 0x11, 0x43, 0x6f, 0x6d, 0x70, 0x75, 0x74, 0x65, 0x46, 0x72,
+	0x61, 0x6d, 0x65, 0x73, 0x50, 0x53, 0x4e, 0x52, 0x12, 0x12, 0x2e, 0x70, 0x73, 0x6e, 0x72, 0x2e,
+	0x76, 0x31, 0x2e, 0x46, 0x72, 0x61, 0x6d, 0x65, 0x50, 0x61, 0x69, 0x72, 0x1a, 0x16, 0x2e, 0x70,
+	0x73, 0x6e, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x72, 0x61, 0x6d, 0x65, 0x73, 0x53, 0x75, 0x6d,
+	0x6d, 0x61, 0x72, 0x79, 0x28, 0x01, 0x42, 0x29, 0x5a, 0x27, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
+	0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x69, 0x64, 0x65, 0x61, 0x6d, 0x61, 0x6e, 0x73, 0x2f, 0x67, 0x6f,
+	0x2d, 0x70, 0x73, 0x6e, 0x72, 0x2f, 0x67, 0x72, 0x70, 0x63, 0x2f, 0x70, 0x73, 0x6e, 0x72, 0x70,
+	0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_psnr_v1_psnr_proto_rawDescOnce sync.Once
+	file_psnr_v1_psnr_proto_rawDescData = file_psnr_v1_psnr_proto_rawDesc
+)
+
+func file_psnr_v1_psnr_proto_rawDescGZIP() []byte {
+	file_psnr_v1_psnr_proto_rawDescOnce.Do(func() {
+		file_psnr_v1_psnr_proto_rawDescData = protoimpl.X.CompressGZIP(file_psnr_v1_psnr_proto_rawDescData)
+	})
+	return file_psnr_v1_psnr_proto_rawDescData
+}
+
+var file_psnr_v1_psnr_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_psnr_v1_psnr_proto_goTypes = []any{
+	(*ComputePSNRRequest)(nil),  // 0: psnr.v1.ComputePSNRRequest
+	(*ComputePSNRResponse)(nil), // 1: psnr.v1.ComputePSNRResponse
+	(*FramePair)(nil),           // 2: psnr.v1.FramePair
+	(*FrameResult)(nil),         // 3: psnr.v1.FrameResult
+	(*FramesSummary)(nil),       // 4: psnr.v1.FramesSummary
+}
+var file_psnr_v1_psnr_proto_depIdxs = []int32{
+	3, // 0: psnr.v1.FramesSummary.frames:type_name -> psnr.v1.FrameResult
+	0, // 1: psnr.v1.PSNRService.ComputePSNR:input_type -> psnr.v1.ComputePSNRRequest
+	2, // 2: psnr.v1.PSNRService.ComputeFramesPSNR:input_type -> psnr.v1.FramePair
+	1, // 3: psnr.v1.PSNRService.ComputePSNR:output_type -> psnr.v1.ComputePSNRResponse
+	4, // 4: psnr.v1.PSNRService.ComputeFramesPSNR:output_type -> psnr.v1.FramesSummary
+	3, // [3:5] is the sub-list for method output_type
+	1, // [1:3] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_psnr_v1_psnr_proto_init() }
+func file_psnr_v1_psnr_proto_init() {
+	if File_psnr_v1_psnr_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_psnr_v1_psnr_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*ComputePSNRRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_psnr_v1_psnr_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*ComputePSNRResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_psnr_v1_psnr_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*FramePair); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_psnr_v1_psnr_proto_msgTypes[3].Exporter = func(v any, i int) any {
+			switch v := v.(*FrameResult); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_psnr_v1_psnr_proto_msgTypes[4].Exporter = func(v any, i int) any {
+			switch v := v.(*FramesSummary); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_psnr_v1_psnr_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_psnr_v1_psnr_proto_goTypes,
+		DependencyIndexes: file_psnr_v1_psnr_proto_depIdxs,
+		MessageInfos:      file_psnr_v1_psnr_proto_msgTypes,
+	}.Build()
+	File_psnr_v1_psnr_proto = out.File
+	file_psnr_v1_psnr_proto_rawDesc = nil
+	file_psnr_v1_psnr_proto_goTypes = nil
+	file_psnr_v1_psnr_proto_depIdxs = nil
+}
@@ -0,0 +1,183 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             (unknown)
+// source: psnr/v1/psnr.proto
+
+package psnrpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	PSNRService_ComputePSNR_FullMethodName       = "/psnr.v1.PSNRService/ComputePSNR"
+	PSNRService_ComputeFramesPSNR_FullMethodName = "/psnr.v1.PSNRService/ComputeFramesPSNR"
+)
+
+// PSNRServiceClient is the client API for PSNRService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PSNRServiceClient interface {
+	ComputePSNR(ctx context.Context, in *ComputePSNRRequest, opts ...grpc.CallOption) (*ComputePSNRResponse, error)
+	ComputeFramesPSNR(ctx context.Context, opts ...grpc.CallOption) (PSNRService_ComputeFramesPSNRClient, error)
+}
+
+type pSNRServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPSNRServiceClient(cc grpc.ClientConnInterface) PSNRServiceClient {
+	return &pSNRServiceClient{cc}
+}
+
+func (c *pSNRServiceClient) ComputePSNR(ctx context.Context, in *ComputePSNRRequest, opts ...grpc.CallOption) (*ComputePSNRResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ComputePSNRResponse)
+	err := c.cc.Invoke(ctx, PSNRService_ComputePSNR_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pSNRServiceClient) ComputeFramesPSNR(ctx context.Context, opts ...grpc.CallOption) (PSNRService_ComputeFramesPSNRClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PSNRService_ServiceDesc.Streams[0], PSNRService_ComputeFramesPSNR_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &pSNRServiceComputeFramesPSNRClient{ClientStream: stream}
+	return x, nil
+}
+
+type PSNRService_ComputeFramesPSNRClient interface {
+	Send(*FramePair) error
+	CloseAndRecv() (*FramesSummary, error)
+	grpc.ClientStream
+}
+
+type pSNRServiceComputeFramesPSNRClient struct {
+	grpc.ClientStream
+}
+
+func (x *pSNRServiceComputeFramesPSNRClient) Send(m *FramePair) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *pSNRServiceComputeFramesPSNRClient) CloseAndRecv() (*FramesSummary, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(FramesSummary)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// PSNRServiceServer is the server API for PSNRService service.
+// All implementations must embed UnimplementedPSNRServiceServer
+// for forward compatibility
+type PSNRServiceServer interface {
+	ComputePSNR(context.Context, *ComputePSNRRequest) (*ComputePSNRResponse, error)
+	ComputeFramesPSNR(PSNRService_ComputeFramesPSNRServer) error
+	mustEmbedUnimplementedPSNRServiceServer()
+}
+
+// UnimplementedPSNRServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedPSNRServiceServer struct {
+}
+
+func (UnimplementedPSNRServiceServer) ComputePSNR(context.Context, *ComputePSNRRequest) (*ComputePSNRResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ComputePSNR not implemented")
+}
+func (UnimplementedPSNRServiceServer) ComputeFramesPSNR(PSNRService_ComputeFramesPSNRServer) error {
+	return status.Errorf(codes.Unimplemented, "method ComputeFramesPSNR not implemented")
+}
+func (UnimplementedPSNRServiceServer) mustEmbedUnimplementedPSNRServiceServer() {}
+
+// UnsafePSNRServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PSNRServiceServer will
+// result in compilation errors.
+type UnsafePSNRServiceServer interface {
+	mustEmbedUnimplementedPSNRServiceServer()
+}
+
+func RegisterPSNRServiceServer(s grpc.ServiceRegistrar, srv PSNRServiceServer) {
+	s.RegisterService(&PSNRService_ServiceDesc, srv)
+}
+
+func _PSNRService_ComputePSNR_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ComputePSNRRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PSNRServiceServer).ComputePSNR(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PSNRService_ComputePSNR_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PSNRServiceServer).ComputePSNR(ctx, req.(*ComputePSNRRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PSNRService_ComputeFramesPSNR_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(PSNRServiceServer).ComputeFramesPSNR(&pSNRServiceComputeFramesPSNRServer{ServerStream: stream})
+}
+
+type PSNRService_ComputeFramesPSNRServer interface {
+	SendAndClose(*FramesSummary) error
+	Recv() (*FramePair, error)
+	grpc.ServerStream
+}
+
+type pSNRServiceComputeFramesPSNRServer struct {
+	grpc.ServerStream
+}
+
+func (x *pSNRServiceComputeFramesPSNRServer) SendAndClose(m *FramesSummary) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *pSNRServiceComputeFramesPSNRServer) Recv() (*FramePair, error) {
+	m := new(FramePair)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// PSNRService_ServiceDesc is the grpc.ServiceDesc for PSNRService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PSNRService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "psnr.v1.PSNRService",
+	HandlerType: (*PSNRServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ComputePSNR",
+			Handler:    _PSNRService_ComputePSNR_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ComputeFramesPSNR",
+			Handler:       _PSNRService_ComputeFramesPSNR_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "psnr/v1/psnr.proto",
+}
@@ -0,0 +1,110 @@
+// Package psnrgrpc exposes PSNR computation as a gRPC service, so encode
+// farms can embed quality evaluation without linking the library directly.
+package psnrgrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+
+	psnr "github.com/ideamans/go-psnr"
+	"github.com/ideamans/go-psnr/grpc/psnrpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements psnrpb.PSNRServiceServer on top of the psnr package.
+type Server struct {
+	psnrpb.UnimplementedPSNRServiceServer
+
+	opts []psnr.Option
+}
+
+// NewServer returns a Server that applies opts to every comparison.
+func NewServer(opts ...psnr.Option) *Server {
+	return &Server{opts: opts}
+}
+
+// ComputePSNR compares a single pair of encoded images. Canceling the
+// request stops the comparison.
+func (s *Server) ComputePSNR(ctx context.Context, req *psnrpb.ComputePSNRRequest) (*psnrpb.ComputePSNRResponse, error) {
+	result, err := psnr.ComputeContext(ctx, req.GetImage1(), req.GetImage2(), s.opts...)
+	if err != nil {
+		return nil, status.Error(errorCode(err), err.Error())
+	}
+
+	return &psnrpb.ComputePSNRResponse{
+		Psnr:      result.PSNR,
+		Mse:       result.MSE,
+		Identical: math.IsInf(result.PSNR, 1),
+	}, nil
+}
+
+// ComputeFramesPSNR compares every frame pair received on the stream and
+// replies with per-frame results and aggregates once the client is done.
+func (s *Server) ComputeFramesPSNR(stream psnrpb.PSNRService_ComputeFramesPSNRServer) error {
+	summary := &psnrpb.FramesSummary{MinPsnr: math.Inf(1)}
+	var sumPSNR, sumMSE float64
+
+	for {
+		pair, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		index := summary.FrameCount
+		result, err := psnr.ComputeContext(stream.Context(), pair.GetReference(), pair.GetDistorted(), s.opts...)
+		if err != nil {
+			return status.Errorf(errorCode(err), "frame %d: %v", index, err)
+		}
+
+		summary.Frames = append(summary.Frames, &psnrpb.FrameResult{
+			Index: index,
+			Psnr:  result.PSNR,
+			Mse:   result.MSE,
+		})
+		summary.FrameCount++
+		sumPSNR += result.PSNR
+		sumMSE += result.MSE
+		if math.IsInf(result.PSNR, 1) {
+			summary.IdenticalFrames++
+		}
+		summary.MinPsnr = math.Min(summary.MinPsnr, result.PSNR)
+	}
+
+	if summary.FrameCount == 0 {
+		return status.Error(codes.InvalidArgument, "no frames received")
+	}
+
+	n := float64(summary.FrameCount)
+	summary.MeanPsnr = sumPSNR / n
+	summary.AveragePsnr = math.Inf(1)
+	if mse := sumMSE / n; mse > 0 {
+		summary.AveragePsnr = 10 * math.Log10(65025.0/mse)
+	}
+
+	return stream.SendAndClose(summary)
+}
+
+// errorCode maps a comparison error to a gRPC status code: InvalidArgument
+// for inputs the client sent that cannot be compared, ResourceExhausted for
+// images over the decode limits, the context's code when the call was
+// canceled, and Internal for anything else, such as a failing backend.
+func errorCode(err error) codes.Code {
+	var decodeErr *psnr.DecodeError
+	var mismatchErr *psnr.MismatchError
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Code()
+	case errors.Is(err, psnr.ErrImageTooLarge):
+		return codes.ResourceExhausted
+	case errors.As(err, &decodeErr), errors.As(err, &mismatchErr),
+		errors.Is(err, psnr.ErrDimensionMismatch), errors.Is(err, psnr.ErrHashMismatch):
+		return codes.InvalidArgument
+	}
+	return codes.Internal
+}
@@ -0,0 +1,163 @@
+package psnrgrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"net"
+	"os"
+	"testing"
+
+	psnr "github.com/ideamans/go-psnr"
+	"github.com/ideamans/go-psnr/grpc/psnrpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestClient starts a Server on an in-memory listener and returns a
+// client connected to it.
+func newTestClient(t *testing.T) psnrpb.PSNRServiceClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	psnrpb.RegisterPSNRServiceServer(s, NewServer())
+	go func() {
+		_ = s.Serve(lis)
+	}()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return psnrpb.NewPSNRServiceClient(conn)
+}
+
+func readTestFile(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("../testdata/" + name)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", name, err)
+	}
+	return data
+}
+
+func TestComputePSNR(t *testing.T) {
+	client := newTestClient(t)
+	original := readTestFile(t, "test_original.jpg")
+	compressed := readTestFile(t, "quality_50.jpg")
+
+	resp, err := client.ComputePSNR(context.Background(), &psnrpb.ComputePSNRRequest{Image1: original, Image2: compressed})
+	if err != nil {
+		t.Fatalf("ComputePSNR failed: %v", err)
+	}
+	t.Logf("PSNR = %.2f dB", resp.GetPsnr())
+	if resp.GetPsnr() < 30 || resp.GetPsnr() > 50 || resp.GetIdentical() {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+
+	resp, err = client.ComputePSNR(context.Background(), &psnrpb.ComputePSNRRequest{Image1: original, Image2: original})
+	if err != nil {
+		t.Fatalf("ComputePSNR failed: %v", err)
+	}
+	if !resp.GetIdentical() || !math.IsInf(resp.GetPsnr(), 1) {
+		t.Errorf("Expected identical result, got %+v", resp)
+	}
+
+	_, err = client.ComputePSNR(context.Background(), &psnrpb.ComputePSNRRequest{Image1: original, Image2: []byte("not an image")})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument, got %v", err)
+	}
+}
+
+func TestComputeFramesPSNR(t *testing.T) {
+	client := newTestClient(t)
+	original := readTestFile(t, "test_original.jpg")
+	compressed := readTestFile(t, "quality_50.jpg")
+
+	stream, err := client.ComputeFramesPSNR(context.Background())
+	if err != nil {
+		t.Fatalf("ComputeFramesPSNR failed: %v", err)
+	}
+	frames := []*psnrpb.FramePair{
+		{Reference: original, Distorted: compressed},
+		{Reference: original, Distorted: original},
+		{Reference: original, Distorted: compressed},
+	}
+	for _, frame := range frames {
+		if err := stream.Send(frame); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+	}
+
+	summary, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatalf("CloseAndRecv failed: %v", err)
+	}
+
+	if summary.GetFrameCount() != 3 || len(summary.GetFrames()) != 3 {
+		t.Fatalf("Expected 3 frames, got %d", summary.GetFrameCount())
+	}
+	if !math.IsInf(summary.GetFrames()[1].GetPsnr(), 1) {
+		t.Errorf("Expected Inf for identical frame, got %f", summary.GetFrames()[1].GetPsnr())
+	}
+	if math.IsInf(summary.GetAveragePsnr(), 0) || summary.GetAveragePsnr() <= summary.GetMinPsnr() {
+		t.Errorf("Expected finite average PSNR above the minimum, got %+v", summary)
+	}
+	if summary.GetIdenticalFrames() != 1 || !math.IsInf(summary.GetMeanPsnr(), 1) {
+		t.Errorf("Expected one identical frame flagging the infinite mean, got %+v", summary)
+	}
+}
+
+func TestComputeFramesPSNREmpty(t *testing.T) {
+	client := newTestClient(t)
+
+	stream, err := client.ComputeFramesPSNR(context.Background())
+	if err != nil {
+		t.Fatalf("ComputeFramesPSNR failed: %v", err)
+	}
+	if _, err := stream.CloseAndRecv(); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for empty stream, got %v", err)
+	}
+}
+
+func TestComputePSNRCanceled(t *testing.T) {
+	original := readTestFile(t, "test_original.jpg")
+	compressed := readTestFile(t, "quality_50.jpg")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := NewServer().ComputePSNR(ctx, &psnrpb.ComputePSNRRequest{Image1: original, Image2: compressed})
+	if status.Code(err) != codes.Canceled {
+		t.Errorf("Expected Canceled, got %v", err)
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want codes.Code
+	}{
+		{&psnr.DecodeError{Input: "first image", Err: image.ErrFormat}, codes.InvalidArgument},
+		{fmt.Errorf("%w: 1x1 vs 2x2", psnr.ErrDimensionMismatch), codes.InvalidArgument},
+		{&psnr.MismatchError{Err: psnr.ErrAlphaMismatch}, codes.InvalidArgument},
+		{&psnr.DecodeError{Input: "first image", Err: psnr.ErrImageTooLarge}, codes.ResourceExhausted},
+		{context.DeadlineExceeded, codes.DeadlineExceeded},
+		{errors.New("backend failure"), codes.Internal},
+	}
+	for _, tt := range tests {
+		if got := errorCode(tt.err); got != tt.want {
+			t.Errorf("errorCode(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
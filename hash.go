@@ -0,0 +1,227 @@
+package psnr
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"math/bits"
+	"slices"
+)
+
+// ErrHashMismatch is returned when WithHashPrefilter finds the perceptual
+// hashes of two images too far apart to be worth comparing.
+var ErrHashMismatch = errors.New("perceptual hashes differ")
+
+// HashKind selects a perceptual hash algorithm.
+type HashKind int
+
+const (
+	// HashDHash is the difference hash: each bit records whether a pixel of a
+	// 9x8 grayscale thumbnail is brighter than its right neighbor. It is the
+	// cheaper of the two.
+	HashDHash HashKind = iota
+	// HashPHash is the DCT hash: each bit records whether one of the 8x8
+	// lowest frequencies of a 32x32 grayscale thumbnail is above their
+	// median. It is more robust to compression and resampling.
+	HashPHash
+)
+
+// DHash returns the 64-bit difference hash of an encoded image.
+func DHash(data []byte) (uint64, error) {
+	return computeHash(data, HashDHash)
+}
+
+// PHash returns the 64-bit DCT-based perceptual hash of an encoded image.
+func PHash(data []byte) (uint64, error) {
+	return computeHash(data, HashPHash)
+}
+
+// HashDistance returns the Hamming distance between two perceptual hashes.
+// Visually similar images differ in a few bits; unrelated images differ in
+// about half of them.
+func HashDistance(hash1, hash2 uint64) int {
+	return bits.OnesCount64(hash1 ^ hash2)
+}
+
+// computeHash decodes data and hashes it with kind.
+func computeHash(data []byte, kind HashKind) (uint64, error) {
+	d, err := (&options{}).decode(data)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return imageHash(d.img, kind), nil
+}
+
+// imageHash hashes a decoded image with kind.
+func imageHash(img image.Image, kind HashKind) uint64 {
+	if kind == HashPHash {
+		return pHash(img)
+	}
+	return dHash(img)
+}
+
+// dHash computes the difference hash of img.
+func dHash(img image.Image) uint64 {
+	gray := grayThumbnail(img, 9, 8)
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if gray[y*9+x] > gray[y*9+x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// pHash computes the DCT hash of img.
+func pHash(img image.Image) uint64 {
+	const size = 32
+	gray := grayThumbnail(img, size, size)
+
+	// Separable DCT-II, keeping only the 8x8 lowest frequencies
+	var cosines [8][size]float64
+	for u := range cosines {
+		for x := range cosines[u] {
+			cosines[u][x] = math.Cos(float64(2*x+1) * float64(u) * math.Pi / (2 * size))
+		}
+	}
+	var rows [size][8]float64
+	for y := 0; y < size; y++ {
+		for u := 0; u < 8; u++ {
+			var sum float64
+			for x := 0; x < size; x++ {
+				sum += gray[y*size+x] * cosines[u][x]
+			}
+			rows[y][u] = sum
+		}
+	}
+	var coefficients [64]float64
+	for v := 0; v < 8; v++ {
+		for u := 0; u < 8; u++ {
+			var sum float64
+			for y := 0; y < size; y++ {
+				sum += rows[y][u] * cosines[v][y]
+			}
+			coefficients[v*8+u] = sum
+		}
+	}
+
+	// The DC term only reflects overall brightness and is left out of the
+	// median
+	sorted := slices.Clone(coefficients[1:])
+	slices.Sort(sorted)
+	median := (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+
+	var hash uint64
+	for _, c := range coefficients {
+		hash <<= 1
+		if c > median {
+			hash |= 1
+		}
+	}
+	return hash
+}
+
+// grayThumbnail shrinks img to width x height luma samples by averaging the
+// source pixels that fall into each cell. It reads the decoded pixels in
+// place rather than converting the image first.
+func grayThumbnail(img image.Image, width, height int) []float64 {
+	bounds := img.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+	luma := lumaReader(img)
+	gray := make([]float64, width*height)
+
+	for cy := 0; cy < height; cy++ {
+		y0 := cy * srcHeight / height
+		y1 := max((cy+1)*srcHeight/height, y0+1)
+		for cx := 0; cx < width; cx++ {
+			x0 := cx * srcWidth / width
+			x1 := max((cx+1)*srcWidth/width, x0+1)
+
+			var sum float64
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					sum += luma(bounds.Min.X+x, bounds.Min.Y+y)
+				}
+			}
+			gray[cy*width+cx] = sum / float64((y1-y0)*(x1-x0))
+		}
+	}
+
+	return gray
+}
+
+// lumaReader returns a function reading the BT.601 luma of img's pixels,
+// premultiplied by alpha, from the decoder's own buffers where it can, so
+// no converted copy of the image is made.
+func lumaReader(img image.Image) func(x, y int) float64 {
+	rgbLuma := func(r, g, b uint8) float64 {
+		return 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+	}
+	switch img := img.(type) {
+	case *image.YCbCr:
+		return func(x, y int) float64 {
+			c := img.COffset(x, y)
+			return rgbLuma(color.YCbCrToRGB(img.Y[img.YOffset(x, y)], img.Cb[c], img.Cr[c]))
+		}
+	case *image.Gray:
+		return func(x, y int) float64 {
+			return float64(img.Pix[img.PixOffset(x, y)])
+		}
+	case *image.RGBA:
+		return func(x, y int) float64 {
+			p := img.Pix[img.PixOffset(x, y):]
+			return rgbLuma(p[0], p[1], p[2])
+		}
+	case *image.NRGBA:
+		return func(x, y int) float64 {
+			p := img.Pix[img.PixOffset(x, y):]
+			return rgbLuma(p[0], p[1], p[2]) * float64(p[3]) / 0xff
+		}
+	}
+	return func(x, y int) float64 {
+		r, g, b, _ := img.At(x, y).RGBA()
+		return rgbLuma(uint8(r>>8), uint8(g>>8), uint8(b>>8))
+	}
+}
+
+// hashPrefilter returns ErrHashMismatch when WithHashPrefilter is set and
+// the hashes of the two images are further apart than allowed.
+func hashPrefilter(img1, img2 image.Image, o *options) error {
+	if o.hashFilter == nil {
+		return nil
+	}
+	return o.hashFilter.check(imageHash(img1, o.hashFilter.kind), imageHash(img2, o.hashFilter.kind))
+}
+
+// hashFilter is the configuration of WithHashPrefilter.
+type hashFilter struct {
+	kind        HashKind
+	maxDistance int
+}
+
+// check compares two hashes against the allowed distance.
+func (f *hashFilter) check(hash1, hash2 uint64) error {
+	if distance := HashDistance(hash1, hash2); distance > f.maxDistance {
+		return fmt.Errorf("%w: distance %d exceeds %d", ErrHashMismatch, distance, f.maxDistance)
+	}
+	return nil
+}
+
+// validate reports an invalid hash prefilter.
+func (f *hashFilter) validate() error {
+	if f == nil {
+		return nil
+	}
+	if f.kind != HashDHash && f.kind != HashPHash {
+		return fmt.Errorf("unknown hash kind %d", f.kind)
+	}
+	if f.maxDistance < 0 || f.maxDistance > 64 {
+		return fmt.Errorf("invalid hash distance: %d", f.maxDistance)
+	}
+	return nil
+}
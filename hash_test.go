@@ -0,0 +1,146 @@
+package psnr
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color/palette"
+	"image/png"
+	"math"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+// hashTestImages returns a PNG, a JPEG encoding of it and a PNG of the same
+// image shifted circularly by half its width, which has the same size but a
+// different structure.
+func hashTestImages(t *testing.T) (original, compressed, shifted []byte) {
+	t.Helper()
+	original, err := os.ReadFile("testdata/test_image.png")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+	compressed, err = os.ReadFile("testdata/test_image_q75.jpg")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+
+	img, err := png.Decode(bytes.NewReader(original))
+	if err != nil {
+		t.Fatalf("Failed to decode test image: %v", err)
+	}
+	bounds := img.Bounds()
+	rotated := image.NewNRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			rotated.Set(bounds.Min.X+(x-bounds.Min.X+bounds.Dx()/2)%bounds.Dx(), y, img.At(x, y))
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, rotated); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+	return original, compressed, buf.Bytes()
+}
+
+func TestPerceptualHash(t *testing.T) {
+	original, compressed, shifted := hashTestImages(t)
+
+	for _, tt := range []struct {
+		name string
+		hash func([]byte) (uint64, error)
+	}{
+		{"DHash", DHash},
+		{"PHash", PHash},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			h1, err := tt.hash(original)
+			if err != nil {
+				t.Fatalf("Error hashing: %v", err)
+			}
+			h2, err := tt.hash(compressed)
+			if err != nil {
+				t.Fatalf("Error hashing: %v", err)
+			}
+			h3, err := tt.hash(shifted)
+			if err != nil {
+				t.Fatalf("Error hashing: %v", err)
+			}
+
+			if d := HashDistance(h1, h2); d > 8 {
+				t.Errorf("Expected a small distance for a recompressed image, got %d", d)
+			}
+			if d := HashDistance(h1, h3); d < 16 {
+				t.Errorf("Expected a large distance for unrelated content, got %d", d)
+			}
+
+			if _, err := tt.hash([]byte("not an image")); err == nil {
+				t.Error("Expected decode error")
+			}
+		})
+	}
+}
+
+func TestWithHashPrefilter(t *testing.T) {
+	original, compressed, shifted := hashTestImages(t)
+
+	want, err := ComputeDetailed(original, compressed)
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	got, err := ComputeDetailed(original, compressed, WithHashPrefilter(HashPHash, 10))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if got.PSNR != want.PSNR {
+		t.Errorf("Expected %f for a pair passing the prefilter, got %f", want.PSNR, got.PSNR)
+	}
+
+	if _, err := ComputeDetailed(original, shifted, WithHashPrefilter(HashDHash, 10)); !errors.Is(err, ErrHashMismatch) {
+		t.Errorf("Expected ErrHashMismatch, got %v", err)
+	}
+
+	matrix, err := ComputeMatrix([][]byte{original, compressed, shifted}, WithHashPrefilter(HashDHash, 10))
+	if err != nil {
+		t.Fatalf("Error computing matrix: %v", err)
+	}
+	if matrix[0][1] != want.PSNR {
+		t.Errorf("Expected %f, got %f", want.PSNR, matrix[0][1])
+	}
+	if !math.IsNaN(matrix[0][2]) || !math.IsNaN(matrix[2][1]) {
+		t.Errorf("Expected NaN for skipped pairs, got %f and %f", matrix[0][2], matrix[2][1])
+	}
+
+	for _, opt := range []Option{WithHashPrefilter(HashKind(7), 10), WithHashPrefilter(HashDHash, 65)} {
+		if _, err := ComputeDetailed(original, compressed, opt); err == nil {
+			t.Error("Expected error for invalid prefilter")
+		}
+	}
+}
+
+func TestGrayThumbnailInPlace(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	translucent := image.NewNRGBA(image.Rect(0, 0, 40, 30))
+	for i := range translucent.Pix {
+		translucent.Pix[i] = uint8(rng.Intn(256))
+	}
+	gray := image.NewGray(image.Rect(0, 0, 40, 30))
+	copy(gray.Pix, translucent.Pix)
+
+	// Reading the decoded buffers gives the luma of the converted copy
+	for _, img := range []image.Image{
+		randomYCbCr(rng, 40, 30, image.YCbCrSubsampleRatio420),
+		translucent,
+		gray,
+		image.NewPaletted(image.Rect(0, 0, 40, 30), palette.WebSafe),
+	} {
+		got, want := grayThumbnail(img, 9, 8), grayThumbnail(toRGBA(img), 9, 8)
+		for i := range want {
+			if math.Abs(got[i]-want[i]) > 1 {
+				t.Errorf("%T: cell %d: expected %.2f, got %.2f", img, i, want[i], got[i])
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package psnr
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"runtime"
+)
+
+// TransferFunction is the transfer function that maps the code values of an
+// image to light.
+type TransferFunction int
+
+const (
+	// TransferAuto reads the transfer function of PNGs from their cICP
+	// chunk and of AVIF and HEIF images from their nclx colr property, and
+	// treats every other image as sRGB.
+	TransferAuto TransferFunction = iota
+	// TransferSRGB is the SDR sRGB curve. SDR white is displayed at
+	// HDROptions.SDRWhite, and the BT.709 primaries are converted to BT.2020
+	// so SDR renditions line up with HDR masters.
+	TransferSRGB
+	// TransferPQ is the SMPTE ST 2084 perceptual quantizer of HDR10, with
+	// absolute luminance up to 10,000 nits and BT.2020 primaries.
+	TransferPQ
+	// TransferHLG is the ARIB STD-B67 hybrid log-gamma curve with BT.2020
+	// primaries, displayed with the BT.2100 system gamma for a display of
+	// HDROptions.HLGPeak nits.
+	TransferHLG
+)
+
+// cICP transfer characteristics codes (ITU-T H.273).
+const (
+	cicpTransferPQ  = 16
+	cicpTransferHLG = 18
+)
+
+// Defaults of HDROptions.
+const (
+	// defaultSDRWhite is the BT.2408 reference white, in nits.
+	defaultSDRWhite = 203
+	// defaultHLGPeak is the nominal peak of HLG displays, in nits.
+	defaultHLGPeak = 1000
+	// maxLuminance is the peak of the PQ curve, in nits.
+	maxLuminance = 10000
+)
+
+// HDROptions configures WithHDR. The zero value detects the transfer
+// functions and compares linear light.
+type HDROptions struct {
+	// Transfer1 and Transfer2 are the transfer functions of the first and
+	// second image.
+	Transfer1, Transfer2 TransferFunction
+	// SDRWhite is the luminance of SDR white in nits; 203 when zero.
+	SDRWhite float64
+	// HLGPeak is the peak luminance of the HLG display in nits; 1000 when
+	// zero.
+	HLGPeak float64
+	// PU21 encodes luminance with the PU21 perceptually uniform encoding
+	// before comparing, so errors in shadows and highlights weigh as they
+	// are perceived instead of in proportion to their luminance.
+	PU21 bool
+}
+
+// HDRResult is the error measured by WithHDR.
+type HDRResult struct {
+	// Transfer1 and Transfer2 are the transfer functions used, after
+	// detection.
+	Transfer1, Transfer2 TransferFunction
+	// PU21 reports whether samples were PU21-encoded.
+	PU21 bool
+	// Peak is the value PSNR is measured against: 10,000 nits, or its PU21
+	// encoding.
+	Peak float64
+	// PSNR and MSE compare the R, G and B channels in nits, or in PU21
+	// units.
+	PSNR float64
+	MSE  float64
+}
+
+// validate reports an invalid setting.
+func (h *HDROptions) validate() error {
+	for _, tf := range []TransferFunction{h.Transfer1, h.Transfer2} {
+		if tf < TransferAuto || tf > TransferHLG {
+			return fmt.Errorf("unknown transfer function %d", tf)
+		}
+	}
+	for _, v := range []float64{h.SDRWhite, h.HLGPeak} {
+		if v < 0 || v > maxLuminance || math.IsNaN(v) {
+			return fmt.Errorf("invalid HDR luminance: %g", v)
+		}
+	}
+	return nil
+}
+
+// WithHDR additionally compares both images in absolute linear light after
+// decoding their PQ, HLG or sRGB code values to nits, optionally encoded
+// with PU21, so HDR masters can be compared with each other and with SDR
+// tone-mapped renditions. The error is reported in Result.HDR; Result.PSNR
+// still compares the code values. 16-bit samples keep their precision, but
+// options that produce 8-bit images, such as WithGaussianBlur, reduce
+// them to 8 bits first. Translucent pixels are composited over black.
+func WithHDR(h HDROptions) Option {
+	return func(o *options) {
+		o.hdr = &h
+	}
+}
+
+// resolveTransfer returns the transfer function of d, reading the cICP chunk
+// of PNGs and the nclx colr property of AVIF and HEIF images for
+// TransferAuto.
+func resolveTransfer(tf TransferFunction, d *decoded) TransferFunction {
+	if tf != TransferAuto {
+		return tf
+	}
+	if d.format == "png" {
+		chunks, _ := readPNGChunks(d.data)
+		for _, chunk := range chunks {
+			if chunk.typ == "IDAT" {
+				break
+			}
+			if chunk.typ == "cICP" && len(chunk.data) >= 2 {
+				return cicpTransfer(uint16(chunk.data[1]))
+			}
+		}
+	}
+	if code, ok := heifTransfer(d.data); ok {
+		return cicpTransfer(code)
+	}
+	return TransferSRGB
+}
+
+// cicpTransfer maps H.273 transfer characteristics to a transfer function,
+// treating every code but PQ and HLG as sRGB.
+func cicpTransfer(code uint16) TransferFunction {
+	switch code {
+	case cicpTransferPQ:
+		return TransferPQ
+	case cicpTransferHLG:
+		return TransferHLG
+	}
+	return TransferSRGB
+}
+
+// hdrResult decodes img1 and img2, whose origins are matched with offset,
+// to light and compares their overlap.
+func hdrResult(d1, d2 *decoded, img1, img2 image.Image, offset image.Point, h *HDROptions) *HDRResult {
+	result := &HDRResult{
+		Transfer1: resolveTransfer(h.Transfer1, d1),
+		Transfer2: resolveTransfer(h.Transfer2, d2),
+		PU21:      h.PU21,
+		Peak:      maxLuminance,
+	}
+	sdrWhite, hlgPeak := h.SDRWhite, h.HLGPeak
+	if sdrWhite == 0 {
+		sdrWhite = defaultSDRWhite
+	}
+	if hlgPeak == 0 {
+		hlgPeak = defaultHLGPeak
+	}
+	if h.PU21 {
+		result.Peak = pu21(maxLuminance)
+	}
+
+	rgba1, rgba2 := toRGBA64(img1), toRGBA64(img2)
+	r := rgba1.Rect.Intersect(rgba2.Rect.Sub(offset))
+	origin2 := r.Min.Add(offset)
+	width, height := r.Dx(), r.Dy()
+
+	rows := make([]float64, height)
+	workers := min(runtime.GOMAXPROCS(0), max(height, 1))
+	parallel(workers, height, func(y int) {
+		line1 := make([]float64, width*3)
+		line2 := make([]float64, width*3)
+		toLight(line1, rgba1, r.Min.X, r.Min.Y+y, result.Transfer1, sdrWhite, hlgPeak, h.PU21)
+		toLight(line2, rgba2, origin2.X, origin2.Y+y, result.Transfer2, sdrWhite, hlgPeak, h.PU21)
+		var sum float64
+		for i := range line1 {
+			diff := line1[i] - line2[i]
+			sum += diff * diff
+		}
+		rows[y] = sum
+	})
+
+	var sum compensatedSum
+	for _, row := range rows {
+		sum.add(row)
+	}
+	result.MSE = sum.value() / float64(max(width*height*3, 1))
+	result.PSNR = math.Inf(1)
+	if result.MSE > 0 {
+		result.PSNR = 10 * math.Log10(result.Peak*result.Peak/result.MSE)
+	}
+	return result
+}
+
+// toRGBA64 converts img to an *image.RGBA64 anchored at the origin.
+func toRGBA64(img image.Image) *image.RGBA64 {
+	if rgba, ok := img.(*image.RGBA64); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	bounds := img.Bounds()
+	dst := image.NewRGBA64(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Rect, img, bounds.Min, draw.Src)
+	return dst
+}
+
+// toLight fills dst with the R, G and B light in nits of the pixels of img
+// starting at (x, y), or with their PU21 encoding.
+func toLight(dst []float64, img *image.RGBA64, x, y int, tf TransferFunction, sdrWhite, hlgPeak float64, encode bool) {
+	pix := img.Pix[img.PixOffset(x, y):]
+	for i := 0; i < len(dst); i += 3 {
+		p := pix[i/3*8:]
+		var c [3]float64
+		for k := range c {
+			c[k] = float64(uint16(p[2*k])<<8|uint16(p[2*k+1])) / 0xffff
+		}
+
+		c = decodeLight(c, tf, sdrWhite, hlgPeak)
+		for k := range c {
+			if encode {
+				c[k] = pu21(c[k])
+			}
+			dst[i+k] = c[k]
+		}
+	}
+}
+
+// decodeLight returns the BT.2020 R, G and B light in nits of samples from
+// 0 to 1 encoded with tf.
+func decodeLight(c [3]float64, tf TransferFunction, sdrWhite, hlgPeak float64) [3]float64 {
+	switch tf {
+	case TransferPQ:
+		for k := range c {
+			c[k] = pqEOTF(c[k])
+		}
+		return c
+	case TransferHLG:
+		return hlgEOTF(c, hlgPeak)
+	}
+	c = bt709ToBT2020([3]float64{srgbEOTF(c[0]), srgbEOTF(c[1]), srgbEOTF(c[2])})
+	for k := range c {
+		c[k] *= sdrWhite
+	}
+	return c
+}
+
+// srgbEOTF returns the linear value of an sRGB sample, both from 0 to 1.
+func srgbEOTF(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// bt709ToBT2020 converts linear BT.709 RGB to BT.2020 primaries.
+func bt709ToBT2020(c [3]float64) [3]float64 {
+	return [3]float64{
+		0.6274039*c[0] + 0.3292830*c[1] + 0.0433131*c[2],
+		0.0690973*c[0] + 0.9195404*c[1] + 0.0113623*c[2],
+		0.0163914*c[0] + 0.0880133*c[1] + 0.8955953*c[2],
+	}
+}
+
+// Constants of the PQ curve.
+const (
+	pqM1 = 2610.0 / 16384
+	pqM2 = 2523.0 / 4096 * 128
+	pqC1 = 3424.0 / 4096
+	pqC2 = 2413.0 / 4096 * 32
+	pqC3 = 2392.0 / 4096 * 32
+)
+
+// pqEOTF returns the luminance in nits of a PQ sample from 0 to 1.
+func pqEOTF(v float64) float64 {
+	e := math.Pow(v, 1/pqM2)
+	return maxLuminance * math.Pow(max(e-pqC1, 0)/(pqC2-pqC3*e), 1/pqM1)
+}
+
+// pqInverseEOTF returns the PQ sample from 0 to 1 of a luminance in nits.
+func pqInverseEOTF(luminance float64) float64 {
+	y := math.Pow(min(max(luminance/maxLuminance, 0), 1), pqM1)
+	return math.Pow((pqC1+pqC2*y)/(1+pqC3*y), pqM2)
+}
+
+// hlgEOTF returns the displayed light in nits of HLG samples from 0 to 1:
+// the inverse OETF followed by the BT.2100 OOTF for a display of the given
+// peak luminance.
+func hlgEOTF(c [3]float64, peak float64) [3]float64 {
+	const (
+		a = 0.17883277
+		b = 1 - 4*a
+	)
+	cc := 0.5 - a*math.Log(4*a)
+	for k, v := range c {
+		if v <= 0.5 {
+			c[k] = v * v / 3
+		} else {
+			c[k] = (math.Exp((v-cc)/a) + b) / 12
+		}
+	}
+	gamma := 1.2 + 0.42*math.Log10(peak/1000)
+	luminance := 0.2627*c[0] + 0.6780*c[1] + 0.0593*c[2]
+	scale := peak * math.Pow(luminance, gamma-1)
+	if luminance == 0 {
+		scale = 0
+	}
+	return [3]float64{scale * c[0], scale * c[1], scale * c[2]}
+}
+
+// pu21 returns the PU21 encoding (banding with glare) of a luminance in
+// nits, clamped to the 0.005-10,000 nits range of the encoding.
+func pu21(luminance float64) float64 {
+	const (
+		p0 = 0.353487901
+		p1 = 0.3734658629
+		p2 = 8.277049286e-05
+		p3 = 0.9062562627
+		p4 = 0.09150303166
+		p5 = 0.9099517204
+		p6 = 596.3148142
+	)
+	y := math.Pow(min(max(luminance, 0.005), maxLuminance), p3)
+	return max(p6*(math.Pow((p0+p1*y)/(1+p2*y), p4)-p5), 0)
+}
@@ -0,0 +1,151 @@
+package psnr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestTransferFunctions(t *testing.T) {
+	tests := []struct {
+		name      string
+		got, want float64
+	}{
+		{"PQ black", pqEOTF(0), 0},
+		{"PQ peak", pqEOTF(1), 10000},
+		{"PQ 100 nits", pqEOTF(0.5081), 100},
+		{"HLG 75%", hlgEOTF([3]float64{0.75, 0.75, 0.75}, 1000)[1], 203},
+		{"HLG peak", hlgEOTF([3]float64{1, 1, 1}, 1000)[0], 1000},
+		{"PU21 peak", pu21(10000), 595.3},
+	}
+	for _, tt := range tests {
+		if math.Abs(tt.got-tt.want) > 0.01*math.Max(tt.want, 1) {
+			t.Errorf("%s: got %f, want %f", tt.name, tt.got, tt.want)
+		}
+	}
+	if pu21(1) >= pu21(10) || pu21(0) != pu21(0.005) {
+		t.Error("Expected PU21 to grow with luminance from its 0.005 nits floor")
+	}
+}
+
+// encodeCICPPNG encodes img as a PNG tagged with the given cICP transfer
+// characteristics and BT.2020 primaries.
+func encodeCICPPNG(t *testing.T, img image.Image, transfer byte) []byte {
+	t.Helper()
+	data := encodePNG(t, img)
+	// IHDR is 25 bytes with its header and CRC, after the signature
+	split := len(pngSignature) + 25
+	var buf bytes.Buffer
+	buf.Write(data[:split])
+	writePNGChunk(&buf, "cICP", []byte{9, transfer, 0, 1})
+	buf.Write(data[split:])
+	return buf.Bytes()
+}
+
+func TestWithHDR(t *testing.T) {
+	// SDR white is 203 nits, which PQ encodes near 0.5807
+	sdr := image.NewGray(image.Rect(0, 0, 8, 8))
+	pq := image.NewGray16(sdr.Rect)
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			sdr.SetGray(x, y, color.Gray{Y: 0xff})
+			pq.SetGray16(x, y, color.Gray16{Y: uint16(math.Round(0.5807 * 0xffff))})
+		}
+	}
+	sdrData, pqData := encodePNG(t, sdr), encodeCICPPNG(t, pq, cicpTransferPQ)
+
+	result, err := ComputeDetailed(sdrData, pqData, WithHDR(HDROptions{}))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	hdr := result.HDR
+	if hdr == nil || hdr.Transfer1 != TransferSRGB || hdr.Transfer2 != TransferPQ || hdr.Peak != 10000 {
+		t.Fatalf("Unexpected HDR result: %+v", hdr)
+	}
+	t.Logf("code values %.2f dB, light %.2f dB", result.PSNR, hdr.PSNR)
+	if result.PSNR > 15 || hdr.PSNR < 50 {
+		t.Errorf("Expected matching light despite different code values, got %f and %+v", result.PSNR, hdr)
+	}
+
+	pu, err := ComputeDetailed(sdrData, pqData, WithHDR(HDROptions{PU21: true}))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if !pu.HDR.PU21 || pu.HDR.Peak != pu21(10000) || pu.HDR.MSE <= 0 {
+		t.Errorf("Unexpected PU21 result: %+v", pu.HDR)
+	}
+
+	// Read as HLG, the same code value is much brighter
+	hlg, err := ComputeDetailed(sdrData, pqData, WithHDR(HDROptions{Transfer2: TransferHLG}))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if hlg.HDR.Transfer2 != TransferHLG || hlg.HDR.PSNR >= hdr.PSNR {
+		t.Errorf("Expected a larger error when decoding as HLG, got %+v", hlg.HDR)
+	}
+
+	same, err := ComputeDetailed(pqData, pqData, WithHDR(HDROptions{}))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if same.HDR == nil || !math.IsInf(same.HDR.PSNR, 1) {
+		t.Errorf("Expected Inf for identical images, got %+v", same.HDR)
+	}
+
+	for _, h := range []HDROptions{{Transfer1: 9}, {SDRWhite: -1}, {HLGPeak: math.NaN()}} {
+		if _, err := ComputeDetailed(sdrData, pqData, WithHDR(h)); err == nil {
+			t.Errorf("Expected error for %+v", h)
+		}
+	}
+}
+
+// isoBoxBytes encodes an ISO base media box.
+func isoBoxBytes(typ string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	box := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+	return append(append(box, typ...), body...)
+}
+
+// heifWithTransfer returns the boxes of an AVIF file, without image data,
+// whose nclx colr property declares the given transfer characteristics.
+func heifWithTransfer(transfer uint16) []byte {
+	nclx := []byte("nclx")
+	nclx = binary.BigEndian.AppendUint16(nclx, 9)
+	nclx = binary.BigEndian.AppendUint16(nclx, transfer)
+	nclx = binary.BigEndian.AppendUint16(nclx, 9)
+	nclx = append(nclx, 0x80)
+	return append(
+		isoBoxBytes("ftyp", []byte("avif"), make([]byte, 4), []byte("mif1")),
+		isoBoxBytes("meta", make([]byte, 4),
+			isoBoxBytes("hdlr", make([]byte, 24)),
+			isoBoxBytes("iprp",
+				isoBoxBytes("ipco",
+					isoBoxBytes("ispe", make([]byte, 12)),
+					isoBoxBytes("colr", nclx))))...)
+}
+
+func TestResolveTransferHEIF(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want TransferFunction
+	}{
+		{"PQ", heifWithTransfer(cicpTransferPQ), TransferPQ},
+		{"HLG", heifWithTransfer(cicpTransferHLG), TransferHLG},
+		{"sRGB", heifWithTransfer(13), TransferSRGB},
+		{"truncated", heifWithTransfer(cicpTransferPQ)[:40], TransferSRGB},
+		{"not ISOBMFF", []byte("not an image"), TransferSRGB},
+	}
+	for _, tt := range tests {
+		if got := resolveTransfer(TransferAuto, &decoded{format: "avif", data: tt.data}); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+	// An explicit transfer function wins
+	if got := resolveTransfer(TransferHLG, &decoded{format: "avif", data: heifWithTransfer(cicpTransferPQ)}); got != TransferHLG {
+		t.Errorf("Expected the explicit HLG, got %v", got)
+	}
+}
@@ -0,0 +1,50 @@
+package psnr
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// Heatmap renders the per-pixel error between two images of the same size:
+// black where they match, through red to yellow and white for the largest
+// channel difference. It compares the overlapping area when the sizes
+// differ.
+func Heatmap(img1, img2 image.Image) *image.RGBA {
+	b1, b2 := img1.Bounds(), img2.Bounds()
+	width := min(b1.Dx(), b2.Dx())
+	height := min(b1.Dy(), b2.Dy())
+	heatmap := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c1 := color.NRGBAModel.Convert(img1.At(b1.Min.X+x, b1.Min.Y+y)).(color.NRGBA)
+			c2 := color.NRGBAModel.Convert(img2.At(b2.Min.X+x, b2.Min.Y+y)).(color.NRGBA)
+			diff := max(absDiff(c1.R, c2.R), absDiff(c1.G, c2.G), absDiff(c1.B, c2.B), absDiff(c1.A, c2.A))
+			heatmap.SetRGBA(x, y, heatColor(diff))
+		}
+	}
+
+	return heatmap
+}
+
+// absDiff returns |a-b|.
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// heatColor maps a difference to a black-red-yellow-white ramp. The square
+// root stretches small differences, which are the common case.
+func heatColor(diff uint8) color.RGBA {
+	if diff == 0 {
+		return color.RGBA{A: 255}
+	}
+	level := math.Sqrt(float64(diff)/255) * 3
+	channel := func(v float64) uint8 {
+		return uint8(math.Round(math.Max(0, math.Min(1, v)) * 255))
+	}
+	return color.RGBA{R: channel(level), G: channel(level - 1), B: channel(level - 2), A: 255}
+}
@@ -0,0 +1,28 @@
+package psnr
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestHeatmap(t *testing.T) {
+	img1 := image.NewGray(image.Rect(0, 0, 3, 2))
+	img2 := image.NewGray(image.Rect(0, 0, 4, 1))
+	img2.Pix[1] = 16
+	img2.Pix[2] = 255
+
+	heatmap := Heatmap(img1, img2)
+	if heatmap.Rect != image.Rect(0, 0, 3, 1) {
+		t.Fatalf("Expected the overlapping area, got %v", heatmap.Rect)
+	}
+	if c := heatmap.RGBAAt(0, 0); c != (color.RGBA{A: 255}) {
+		t.Errorf("Expected black for equal pixels, got %v", c)
+	}
+	if c := heatmap.RGBAAt(1, 0); c.R != 192 || c.G != 0 {
+		t.Errorf("Expected red for a small difference, got %v", c)
+	}
+	if c := heatmap.RGBAAt(2, 0); c != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("Expected white for the largest difference, got %v", c)
+	}
+}
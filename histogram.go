@@ -0,0 +1,69 @@
+package psnr
+
+// ErrorHistogram counts absolute differences between the two images, as
+// collected by WithErrorHistogram.
+type ErrorHistogram struct {
+	// Samples[d] is the number of compared samples (R, G, B and, when
+	// compared, A) whose absolute difference is d.
+	Samples [256]uint64
+	// Pixels[d] is the number of pixels whose largest absolute sample
+	// difference is d.
+	Pixels [256]uint64
+}
+
+// Percentile returns the smallest absolute difference that at least p
+// percent (0-100) of the samples do not exceed, e.g. Percentile(99) for the
+// p99 absolute error.
+func (h *ErrorHistogram) Percentile(p float64) int {
+	var total uint64
+	for _, n := range h.Samples {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+	rank := p / 100 * float64(total)
+	var count uint64
+	for d, n := range h.Samples {
+		count += n
+		if float64(count) >= rank {
+			return d
+		}
+	}
+	return len(h.Samples) - 1
+}
+
+// PixelsWithin returns the fraction of pixels whose samples all differ by
+// at most d, e.g. PixelsWithin(1) for the share of pixels within ±1.
+func (h *ErrorHistogram) PixelsWithin(d int) float64 {
+	var total, within uint64
+	for diff, n := range h.Pixels {
+		total += n
+		if diff <= d {
+			within += n
+		}
+	}
+	if total == 0 {
+		return 1
+	}
+	return float64(within) / float64(total)
+}
+
+// histogramVisitor fills an ErrorHistogram from the fused pass.
+type histogramVisitor struct {
+	histogram    *ErrorHistogram
+	channelCount int
+}
+
+func (v histogramVisitor) visit(_, _ int, p1, p2 []uint8) {
+	var largest uint8
+	for c := 0; c < v.channelCount; c++ {
+		d := p1[c] - p2[c]
+		if p2[c] > p1[c] {
+			d = p2[c] - p1[c]
+		}
+		v.histogram.Samples[d]++
+		largest = max(largest, d)
+	}
+	v.histogram.Pixels[largest]++
+}
@@ -0,0 +1,73 @@
+package psnr
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+)
+
+func TestWithErrorHistogram(t *testing.T) {
+	encode := func(img image.Image) []byte {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			t.Fatalf("Failed to encode image: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	img1 := image.NewRGBA(image.Rect(0, 0, 4, 1))
+	for i := range img1.Pix {
+		img1.Pix[i] = 100
+	}
+	for i := 3; i < len(img1.Pix); i += 4 {
+		img1.Pix[i] = 255
+	}
+	img2 := image.NewRGBA(img1.Rect)
+	copy(img2.Pix, img1.Pix)
+	img2.Pix[4] += 1   // pixel 1: R +1
+	img2.Pix[8] -= 3   // pixel 2: R -3
+	img2.Pix[9] += 1   //          G +1
+	img2.Pix[14] += 10 // pixel 3: B +10
+	data1, data2 := encode(img1), encode(img2)
+
+	result, err := ComputeDetailed(data1, data2, WithErrorHistogram())
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	plain, err := ComputeDetailed(data1, data2)
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if result.MSE != plain.MSE {
+		t.Errorf("Expected MSE %f, got %f", plain.MSE, result.MSE)
+	}
+
+	h := result.Histogram
+	if h == nil {
+		t.Fatal("Expected a histogram")
+	}
+	for d, want := range map[int]uint64{0: 8, 1: 2, 3: 1, 10: 1} {
+		if h.Samples[d] != want {
+			t.Errorf("Expected %d samples with difference %d, got %d", want, d, h.Samples[d])
+		}
+	}
+	for d, want := range map[int]uint64{0: 1, 1: 1, 3: 1, 10: 1} {
+		if h.Pixels[d] != want {
+			t.Errorf("Expected %d pixels with difference %d, got %d", want, d, h.Pixels[d])
+		}
+	}
+
+	for p, want := range map[float64]int{50: 0, 90: 3, 100: 10} {
+		if got := h.Percentile(p); got != want {
+			t.Errorf("Expected p%.0f = %d, got %d", p, want, got)
+		}
+	}
+	if got := h.PixelsWithin(1); got != 0.5 {
+		t.Errorf("Expected half of the pixels within ±1, got %f", got)
+	}
+
+	if plain.Histogram != nil {
+		t.Error("Expected no histogram without the option")
+	}
+}
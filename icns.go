@@ -0,0 +1,175 @@
+package psnr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+)
+
+// icnsPNGTypes are the ICNS element types holding PNG (or JPEG 2000) data,
+// with their pixel size. Entries are keyed by the decoded size, so Retina
+// variants such as ic11 (16x16@2x) match 32x32 elements.
+var icnsPNGTypes = map[string]int{
+	"icp4": 16, "icp5": 32, "icp6": 64,
+	"ic07": 128, "ic08": 256, "ic09": 512, "ic10": 1024,
+	"ic11": 32, "ic12": 64, "ic13": 256, "ic14": 512,
+}
+
+// icnsRLETypes are the ICNS element types holding RLE-compressed pixels,
+// with their pixel size. ARGB elements carry their own alpha; RGB elements
+// take it from the mask element of the same size.
+var icnsRLETypes = map[string]int{
+	"is32": 16, "il32": 32, "ih32": 48, "it32": 128,
+	"ic04": 16, "ic05": 32,
+}
+
+// icnsMaskTypes are the 8-bit mask elements of the RGB element types.
+var icnsMaskTypes = map[string]int{
+	"s8mk": 16, "l8mk": 32, "h8mk": 48, "t8mk": 128,
+}
+
+// isICNS reports whether data starts with an ICNS header.
+func isICNS(data []byte) bool {
+	return len(data) >= 8 && string(data[:4]) == "icns"
+}
+
+// decodeICNS decodes the PNG, ARGB and masked RGB elements of an ICNS file.
+// Elements it does not know, such as the table of contents, are skipped.
+func decodeICNS(data []byte, limits decodeLimits) (iconSet, error) {
+	total := int(binary.BigEndian.Uint32(data[4:8]))
+	if total > len(data) || total < 8 {
+		return nil, errors.New("icns: truncated file")
+	}
+
+	icons := make(iconSet)
+	masks := make(map[int][]byte)
+	var rgb []*image.NRGBA
+	for offset := 8; offset < total; {
+		if offset+8 > total {
+			return nil, errors.New("icns: truncated element header")
+		}
+		kind := string(data[offset : offset+4])
+		length := int(binary.BigEndian.Uint32(data[offset+4 : offset+8]))
+		if length < 8 || offset+length > total {
+			return nil, fmt.Errorf("icns: invalid length of %q element", kind)
+		}
+		payload := data[offset+8 : offset+length]
+		offset += length
+
+		if _, ok := icnsPNGTypes[kind]; ok {
+			if !bytes.HasPrefix(payload, pngSignature) {
+				return nil, fmt.Errorf("icns: %q element is not a PNG (JPEG 2000 elements are not supported)", kind)
+			}
+			if err := limits.check(payload); err != nil {
+				return nil, fmt.Errorf("icns: %q element: %w", kind, err)
+			}
+			img, err := png.Decode(bytes.NewReader(payload))
+			if err != nil {
+				return nil, fmt.Errorf("icns: %q element: %w", kind, err)
+			}
+			icons.add(img, 32)
+			continue
+		}
+		if size, ok := icnsMaskTypes[kind]; ok {
+			if len(payload) != size*size {
+				return nil, fmt.Errorf("icns: %q mask has %d bytes", kind, len(payload))
+			}
+			masks[size] = payload
+			continue
+		}
+		size, ok := icnsRLETypes[kind]
+		if !ok {
+			continue
+		}
+		channels := 3
+		switch {
+		case kind == "ic04" || kind == "ic05":
+			if !bytes.HasPrefix(payload, []byte("ARGB")) {
+				return nil, fmt.Errorf("icns: %q element is not ARGB", kind)
+			}
+			payload, channels = payload[4:], 4
+		case kind == "it32":
+			// it32 data is preceded by four zero bytes
+			if len(payload) < 4 {
+				return nil, errors.New("icns: truncated it32 element")
+			}
+			payload = payload[4:]
+		}
+		img, err := decodeICNSRLE(payload, size, channels)
+		if err != nil {
+			return nil, fmt.Errorf("icns: %q element: %w", kind, err)
+		}
+		if channels == 4 {
+			icons.add(img, 32)
+		} else {
+			rgb = append(rgb, img)
+		}
+	}
+
+	// RGB elements are opaque unless a mask of their size is present
+	for _, img := range rgb {
+		size := img.Rect.Dx()
+		if mask, ok := masks[size]; ok {
+			for i, alpha := range mask {
+				img.Pix[4*i+3] = alpha
+			}
+			icons.add(img, 32)
+		} else {
+			icons.add(img, 24)
+		}
+	}
+	return icons, nil
+}
+
+// decodeICNSRLE decodes size x size pixels stored as consecutive
+// run-length-encoded channel planes (alpha first when there are four): a
+// control byte below 0x80 copies the next n+1 bytes, and one at or above
+// 0x80 repeats the next byte n-0x80+3 times.
+func decodeICNSRLE(data []byte, size, channels int) (*image.NRGBA, error) {
+	pixels := size * size
+	planes := make([]byte, 0, pixels*channels)
+	for len(planes) < cap(planes) {
+		if len(data) == 0 {
+			return nil, errors.New("truncated pixel data")
+		}
+		control := int(data[0])
+		if control < 0x80 {
+			n := control + 1
+			if len(data) < 1+n || len(planes)+n > cap(planes) {
+				return nil, errors.New("invalid literal run")
+			}
+			planes = append(planes, data[1:1+n]...)
+			data = data[1+n:]
+			continue
+		}
+		n := control - 0x80 + 3
+		if len(data) < 2 || len(planes)+n > cap(planes) {
+			return nil, errors.New("invalid repeat run")
+		}
+		for i := 0; i < n; i++ {
+			planes = append(planes, data[1])
+		}
+		data = data[2:]
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	// Alpha, when present, is the first plane
+	order := []int{0, 1, 2}
+	alpha := -1
+	if channels == 4 {
+		order, alpha = []int{1, 2, 3}, 0
+	}
+	for i := 0; i < pixels; i++ {
+		for c, plane := range order {
+			img.Pix[4*i+c] = planes[plane*pixels+i]
+		}
+		img.Pix[4*i+3] = 0xff
+		if alpha >= 0 {
+			img.Pix[4*i+3] = planes[alpha*pixels+i]
+		}
+	}
+	return img, nil
+}
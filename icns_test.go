@@ -0,0 +1,123 @@
+package psnr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"math"
+	"testing"
+)
+
+// icnsElement is an element of a test ICNS file.
+type icnsElement struct {
+	kind    string
+	payload []byte
+}
+
+// encodeICNS assembles an ICNS file from its elements.
+func encodeICNS(elements ...icnsElement) []byte {
+	var body bytes.Buffer
+	for _, e := range elements {
+		body.WriteString(e.kind)
+		binary.Write(&body, binary.BigEndian, uint32(8+len(e.payload)))
+		body.Write(e.payload)
+	}
+	var buf bytes.Buffer
+	buf.WriteString("icns")
+	binary.Write(&buf, binary.BigEndian, uint32(8+body.Len()))
+	buf.Write(body.Bytes())
+	return buf.Bytes()
+}
+
+// encodeICNSRLE packs the channel planes of img with literal runs only,
+// alpha first when withAlpha is set.
+func encodeICNSRLE(img *image.NRGBA, withAlpha bool) []byte {
+	channels := []int{0, 1, 2}
+	if withAlpha {
+		channels = []int{3, 0, 1, 2}
+	}
+	var planes []byte
+	for _, c := range channels {
+		for i := c; i < len(img.Pix); i += 4 {
+			planes = append(planes, img.Pix[i])
+		}
+	}
+	var buf bytes.Buffer
+	for len(planes) > 0 {
+		n := min(len(planes), 128)
+		buf.WriteByte(byte(n - 1))
+		buf.Write(planes[:n])
+		planes = planes[n:]
+	}
+	return buf.Bytes()
+}
+
+// icnsMask returns the alpha channel of img.
+func icnsMask(img *image.NRGBA) []byte {
+	var mask []byte
+	for i := 3; i < len(img.Pix); i += 4 {
+		mask = append(mask, img.Pix[i])
+	}
+	return mask
+}
+
+func TestCompareICNS(t *testing.T) {
+	small := iconImageRGBA(16, 50)
+	medium := iconImageRGBA(32, 50)
+	icns1 := encodeICNS(
+		icnsElement{"TOC ", make([]byte, 8)},
+		icnsElement{"is32", encodeICNSRLE(small, false)},
+		icnsElement{"s8mk", icnsMask(small)},
+		icnsElement{"ic05", append([]byte("ARGB"), encodeICNSRLE(medium, true)...)},
+	)
+	icns2 := encodeICNS(
+		icnsElement{"icp4", encodePNG(t, small)},
+		icnsElement{"ic11", encodePNG(t, medium)},
+		icnsElement{"ic07", encodePNG(t, iconImageRGBA(128, 50))},
+	)
+
+	comparison, err := CompareIcons(icns1, icns2)
+	if err != nil {
+		t.Fatalf("CompareIcons failed: %v", err)
+	}
+	if len(comparison.Sizes) != 2 {
+		t.Fatalf("Expected 2 matching sizes, got %+v", comparison.Sizes)
+	}
+	for _, s := range comparison.Sizes {
+		if !math.IsInf(s.PSNR, 1) {
+			t.Errorf("Expected identical %dx%d elements, got %v dB", s.Width, s.Height, s.PSNR)
+		}
+	}
+	if len(comparison.OnlyInSecond) != 1 || comparison.OnlyInSecond[0] != image.Pt(128, 128) {
+		t.Errorf("Unexpected sizes only in the second icon: %v", comparison.OnlyInSecond)
+	}
+
+	// ICNS and ICO files can be compared with each other
+	ico := encodeICO(iconEntry{16, 16, 32, encodeDIB32(small)})
+	comparison, err = CompareIcons(icns1, ico)
+	if err != nil {
+		t.Fatalf("CompareIcons failed: %v", err)
+	}
+	if len(comparison.Sizes) != 1 || !math.IsInf(comparison.Sizes[0].PSNR, 1) {
+		t.Errorf("Expected an identical 16x16 entry, got %+v", comparison.Sizes)
+	}
+}
+
+func TestDecodeICNSRLE(t *testing.T) {
+	// A repeat run of 5 and a literal run of 4 fill a 3x3 plane
+	img, err := decodeICNSRLE([]byte{0x82, 7, 3, 1, 2, 3, 4, 0x86, 0, 0x86, 0}, 3, 3)
+	if err != nil {
+		t.Fatalf("decodeICNSRLE failed: %v", err)
+	}
+	var red []uint8
+	for i := 0; i < len(img.Pix); i += 4 {
+		red = append(red, img.Pix[i])
+	}
+	if !bytes.Equal(red, []byte{7, 7, 7, 7, 7, 1, 2, 3, 4}) {
+		t.Errorf("Unexpected red plane: %v", red)
+	}
+
+	if _, err := decodeICNSRLE([]byte{0x82, 7}, 3, 3); err == nil {
+		t.Error("Expected error for truncated data")
+	}
+}
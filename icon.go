@@ -0,0 +1,261 @@
+package psnr
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"sort"
+)
+
+// ErrNotIcon is returned by CompareIcons for data that is neither an ICO,
+// CUR nor ICNS file.
+var ErrNotIcon = errors.New("not an ICO or ICNS file")
+
+// IconComparison is the result of comparing two icon containers.
+type IconComparison struct {
+	// Sizes holds the comparison of every resolution present in both
+	// containers, from the smallest to the largest.
+	Sizes []IconSizeResult
+	// OnlyInFirst and OnlyInSecond list the resolutions, as width and
+	// height, that have no counterpart in the other container.
+	OnlyInFirst  []image.Point
+	OnlyInSecond []image.Point
+}
+
+// IconSizeResult is the comparison of one resolution of two icons.
+type IconSizeResult struct {
+	Width, Height int
+	*Result
+}
+
+// CompareIcons compares the images of matching resolutions in two icon
+// containers: Windows ICO and CUR files, whose entries are PNG or BMP
+// bitmaps, and macOS ICNS files, whose entries are PNG or RLE-compressed
+// RGB with an 8-bit mask. When a container holds several images of the same
+// resolution, the one with the highest color depth is compared. Alpha is
+// always part of the comparison.
+func CompareIcons(data1, data2 []byte, opts ...Option) (*IconComparison, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	icons1, err := decodeIcon(data1, o.limits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode first icon: %w", err)
+	}
+	icons2, err := decodeIcon(data2, o.limits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode second icon: %w", err)
+	}
+
+	var comparison IconComparison
+	for _, size := range sortedIconSizes(icons1) {
+		entry2, ok := icons2[size]
+		if !ok {
+			comparison.OnlyInFirst = append(comparison.OnlyInFirst, size)
+			continue
+		}
+		result, err := compare(context.Background(), &decoded{img: icons1[size].img, format: memoryFormat}, &decoded{img: entry2.img, format: memoryFormat}, o)
+		if err != nil {
+			return nil, fmt.Errorf("%dx%d: %w", size.X, size.Y, err)
+		}
+		comparison.Sizes = append(comparison.Sizes, IconSizeResult{Width: size.X, Height: size.Y, Result: result})
+	}
+	for _, size := range sortedIconSizes(icons2) {
+		if _, ok := icons1[size]; !ok {
+			comparison.OnlyInSecond = append(comparison.OnlyInSecond, size)
+		}
+	}
+	return &comparison, nil
+}
+
+// iconImage is a decoded entry of an icon container.
+type iconImage struct {
+	img image.Image
+	// depth is the bits per pixel of the entry, used to choose between
+	// entries of the same resolution.
+	depth int
+}
+
+// iconSet maps resolutions to the best entry of an icon container.
+type iconSet map[image.Point]iconImage
+
+// add keeps img unless an entry of the same size has a higher depth.
+func (s iconSet) add(img image.Image, depth int) {
+	size := img.Bounds().Size()
+	if existing, ok := s[size]; ok && existing.depth >= depth {
+		return
+	}
+	s[size] = iconImage{img: img, depth: depth}
+}
+
+// sortedIconSizes returns the resolutions of a set by area, then width.
+func sortedIconSizes(s iconSet) []image.Point {
+	sizes := make([]image.Point, 0, len(s))
+	for size := range s {
+		sizes = append(sizes, size)
+	}
+	sort.Slice(sizes, func(i, j int) bool {
+		ai, aj := sizes[i].X*sizes[i].Y, sizes[j].X*sizes[j].Y
+		if ai != aj {
+			return ai < aj
+		}
+		return sizes[i].X < sizes[j].X
+	})
+	return sizes
+}
+
+// decodeIcon decodes every entry of an ICO, CUR or ICNS container. PNG
+// entries are checked against limits before they are decoded; the other
+// entry types are bounded by their formats.
+func decodeIcon(data []byte, limits decodeLimits) (iconSet, error) {
+	switch {
+	case isICNS(data):
+		return decodeICNS(data, limits)
+	case isICO(data):
+		return decodeICO(data, limits)
+	default:
+		return nil, ErrNotIcon
+	}
+}
+
+// isICO reports whether data starts with an ICO or CUR header.
+func isICO(data []byte) bool {
+	return len(data) >= 6 && data[0] == 0 && data[1] == 0 &&
+		(data[2] == 1 || data[2] == 2) && data[3] == 0
+}
+
+// decodeICO decodes the entries of an ICO or CUR file.
+func decodeICO(data []byte, limits decodeLimits) (iconSet, error) {
+	count := int(binary.LittleEndian.Uint16(data[4:6]))
+	if count == 0 || len(data) < 6+16*count {
+		return nil, errors.New("ico: truncated directory")
+	}
+	icons := make(iconSet, count)
+	for i := 0; i < count; i++ {
+		entry := data[6+16*i : 6+16*(i+1)]
+		size := binary.LittleEndian.Uint32(entry[8:12])
+		offset := binary.LittleEndian.Uint32(entry[12:16])
+		if uint64(offset)+uint64(size) > uint64(len(data)) {
+			return nil, fmt.Errorf("ico: entry %d is out of bounds", i)
+		}
+		payload := data[offset : offset+size]
+
+		if bytes.HasPrefix(payload, pngSignature) {
+			if err := limits.check(payload); err != nil {
+				return nil, fmt.Errorf("ico: entry %d: %w", i, err)
+			}
+			img, err := png.Decode(bytes.NewReader(payload))
+			if err != nil {
+				return nil, fmt.Errorf("ico: entry %d: %w", i, err)
+			}
+			icons.add(img, 32)
+			continue
+		}
+		img, depth, err := decodeDIB(payload)
+		if err != nil {
+			return nil, fmt.Errorf("ico: entry %d: %w", i, err)
+		}
+		icons.add(img, depth)
+	}
+	return icons, nil
+}
+
+// decodeDIB decodes an ICO bitmap: a BITMAPINFOHEADER with a doubled height,
+// an optional palette, the bottom-up color rows and the 1-bit AND mask that
+// marks transparent pixels. 32-bit bitmaps use their alpha channel unless it
+// is entirely zero.
+func decodeDIB(data []byte) (*image.NRGBA, int, error) {
+	if len(data) < 40 {
+		return nil, 0, errors.New("truncated bitmap header")
+	}
+	headerSize := int(binary.LittleEndian.Uint32(data[0:4]))
+	width := int(int32(binary.LittleEndian.Uint32(data[4:8])))
+	height := int(int32(binary.LittleEndian.Uint32(data[8:12]))) / 2
+	depth := int(binary.LittleEndian.Uint16(data[14:16]))
+	compression := binary.LittleEndian.Uint32(data[16:20])
+	colorsUsed := int(binary.LittleEndian.Uint32(data[32:36]))
+	if headerSize < 40 || headerSize > len(data) {
+		return nil, 0, fmt.Errorf("invalid bitmap header size: %d", headerSize)
+	}
+	if width <= 0 || height <= 0 || width > 1024 || height > 1024 {
+		return nil, 0, fmt.Errorf("invalid bitmap size: %dx%d", width, height)
+	}
+	if compression != 0 {
+		return nil, 0, fmt.Errorf("unsupported bitmap compression: %d", compression)
+	}
+
+	var palette color.Palette
+	offset := headerSize
+	switch depth {
+	case 1, 4, 8:
+		if colorsUsed == 0 || colorsUsed > 1<<depth {
+			colorsUsed = 1 << depth
+		}
+		if offset+4*colorsUsed > len(data) {
+			return nil, 0, errors.New("truncated bitmap palette")
+		}
+		palette = make(color.Palette, 1<<depth)
+		for i := range palette {
+			palette[i] = color.NRGBA{A: 0xff}
+		}
+		for i := 0; i < colorsUsed; i++ {
+			p := data[offset+4*i:]
+			palette[i] = color.NRGBA{R: p[2], G: p[1], B: p[0], A: 0xff}
+		}
+		offset += 4 * colorsUsed
+	case 24, 32:
+	default:
+		return nil, 0, fmt.Errorf("unsupported bitmap depth: %d", depth)
+	}
+
+	stride := (width*depth + 31) / 32 * 4
+	if offset+stride*height > len(data) {
+		return nil, 0, errors.New("truncated bitmap")
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	hasAlpha := false
+	for y := 0; y < height; y++ {
+		row := data[offset+(height-1-y)*stride:]
+		pix := img.Pix[y*img.Stride:]
+		for x := 0; x < width; x++ {
+			var c color.NRGBA
+			switch depth {
+			case 32:
+				c = color.NRGBA{R: row[4*x+2], G: row[4*x+1], B: row[4*x], A: row[4*x+3]}
+				hasAlpha = hasAlpha || c.A != 0
+			case 24:
+				c = color.NRGBA{R: row[3*x+2], G: row[3*x+1], B: row[3*x], A: 0xff}
+			default:
+				bit := x * depth
+				index := row[bit/8] >> (8 - depth - bit%8) & (1<<depth - 1)
+				c = palette[index].(color.NRGBA)
+			}
+			pix[4*x], pix[4*x+1], pix[4*x+2], pix[4*x+3] = c.R, c.G, c.B, c.A
+		}
+	}
+	if depth == 32 && hasAlpha {
+		return img, depth, nil
+	}
+
+	// The AND mask may be missing from 32-bit bitmaps, which are then opaque
+	offset += stride * height
+	maskStride := (width + 31) / 32 * 4
+	hasMask := offset+maskStride*height <= len(data)
+	for y := 0; y < height; y++ {
+		pix := img.Pix[y*img.Stride:]
+		for x := 0; x < width; x++ {
+			alpha := uint8(0xff)
+			if hasMask && data[offset+(height-1-y)*maskStride+x/8]&(0x80>>(x%8)) != 0 {
+				alpha = 0
+			}
+			pix[4*x+3] = alpha
+		}
+	}
+	return img, depth, nil
+}
@@ -0,0 +1,159 @@
+package psnr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// iconEntry is an entry of a test ICO file.
+type iconEntry struct {
+	width, height, depth int
+	payload              []byte
+}
+
+// encodeICO assembles an ICO file from its entries.
+func encodeICO(entries ...iconEntry) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, [3]uint16{0, 1, uint16(len(entries))})
+	offset := 6 + 16*len(entries)
+	for _, e := range entries {
+		buf.Write([]byte{byte(e.width), byte(e.height), 0, 0})
+		binary.Write(&buf, binary.LittleEndian, [2]uint16{1, uint16(e.depth)})
+		binary.Write(&buf, binary.LittleEndian, [2]uint32{uint32(len(e.payload)), uint32(offset)})
+		offset += len(e.payload)
+	}
+	for _, e := range entries {
+		buf.Write(e.payload)
+	}
+	return buf.Bytes()
+}
+
+// encodeDIB32 encodes img as a 32-bit ICO bitmap with an empty AND mask.
+func encodeDIB32(img *image.NRGBA) []byte {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, struct {
+		Size          uint32
+		Width, Height int32
+		Planes, Depth uint16
+		Rest          [6]uint32
+	}{Size: 40, Width: int32(w), Height: int32(2 * h), Planes: 1, Depth: 32})
+	for y := h - 1; y >= 0; y-- {
+		for x := 0; x < w; x++ {
+			c := img.NRGBAAt(x, y)
+			buf.Write([]byte{c.B, c.G, c.R, c.A})
+		}
+	}
+	buf.Write(make([]byte, (w+31)/32*4*h))
+	return buf.Bytes()
+}
+
+// encodeDIB1 encodes a 1-bit black and white ICO bitmap whose AND mask makes
+// the left half transparent.
+func encodeDIB1(size int) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, struct {
+		Size          uint32
+		Width, Height int32
+		Planes, Depth uint16
+		Rest          [6]uint32
+	}{Size: 40, Width: int32(size), Height: int32(2 * size), Planes: 1, Depth: 1})
+	buf.Write([]byte{0, 0, 0, 0, 0xff, 0xff, 0xff, 0})
+	stride := (size + 31) / 32 * 4
+	for y := 0; y < size; y++ {
+		row := make([]byte, stride)
+		row[0] = 0xaa // alternating black and white
+		buf.Write(row)
+	}
+	for y := 0; y < size; y++ {
+		row := make([]byte, stride)
+		row[0] = 0xf0 // the first four pixels are transparent
+		buf.Write(row)
+	}
+	return buf.Bytes()
+}
+
+// iconImageRGBA returns a translucent gradient of the given size.
+func iconImageRGBA(size int, shade uint8) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 8), G: uint8(y * 8), B: shade, A: uint8(128 + x*4)})
+		}
+	}
+	return img
+}
+
+func TestCompareIcons(t *testing.T) {
+	icon1 := encodeICO(
+		iconEntry{16, 16, 32, encodePNG(t, iconImageRGBA(16, 100))},
+		iconEntry{32, 32, 1, encodeDIB1(32)},
+		iconEntry{32, 32, 32, encodeDIB32(iconImageRGBA(32, 100))},
+		iconEntry{48, 48, 32, encodeDIB32(iconImageRGBA(48, 100))},
+	)
+	icon2 := encodeICO(
+		iconEntry{16, 16, 32, encodeDIB32(iconImageRGBA(16, 100))},
+		iconEntry{32, 32, 32, encodePNG(t, iconImageRGBA(32, 110))},
+		iconEntry{0, 0, 32, encodePNG(t, iconImageRGBA(256, 100))},
+	)
+
+	comparison, err := CompareIcons(icon1, icon2)
+	if err != nil {
+		t.Fatalf("CompareIcons failed: %v", err)
+	}
+	if len(comparison.Sizes) != 2 {
+		t.Fatalf("Expected 2 matching sizes, got %+v", comparison.Sizes)
+	}
+	// PNG and BMP entries of the same pixels are identical
+	if s := comparison.Sizes[0]; s.Width != 16 || s.Height != 16 || !math.IsInf(s.PSNR, 1) {
+		t.Errorf("Expected identical 16x16 entries, got %dx%d %v dB", s.Width, s.Height, s.PSNR)
+	}
+	// The 32-bit 32x32 entry is preferred over the 1-bit one
+	if s := comparison.Sizes[1]; s.Width != 32 || math.IsInf(s.PSNR, 1) || s.PSNR < 20 {
+		t.Errorf("Expected a small difference at 32x32, got %dx%d %v dB", s.Width, s.Height, s.PSNR)
+	}
+	if len(comparison.OnlyInFirst) != 1 || comparison.OnlyInFirst[0] != image.Pt(48, 48) {
+		t.Errorf("Unexpected sizes only in the first icon: %v", comparison.OnlyInFirst)
+	}
+	if len(comparison.OnlyInSecond) != 1 || comparison.OnlyInSecond[0] != image.Pt(256, 256) {
+		t.Errorf("Unexpected sizes only in the second icon: %v", comparison.OnlyInSecond)
+	}
+
+	if _, err := CompareIcons(encodePNG(t, iconImageRGBA(16, 0)), icon2); !errors.Is(err, ErrNotIcon) {
+		t.Errorf("Expected ErrNotIcon, got %v", err)
+	}
+
+	// PNG entries are held to the decode limits like standalone images
+	bomb := encodeICO(iconEntry{0, 0, 32, pngHeader(50000, 50000)})
+	if _, err := CompareIcons(icon1, bomb, WithMaxPixels(100_000_000)); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("Expected ErrImageTooLarge, got %v", err)
+	}
+	if _, err := CompareIcons(icon1, icon2, WithMaxPixels(256*256)); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestDecodeDIBMask(t *testing.T) {
+	img, depth, err := decodeDIB(encodeDIB1(8))
+	if err != nil {
+		t.Fatalf("decodeDIB failed: %v", err)
+	}
+	if depth != 1 || img.Rect != image.Rect(0, 0, 8, 8) {
+		t.Fatalf("Unexpected bitmap: depth %d, bounds %v", depth, img.Rect)
+	}
+	want := []color.NRGBA{
+		{A: 0}, {A: 0}, {A: 0}, {A: 0},
+		{R: 0xff, G: 0xff, B: 0xff, A: 0xff}, {A: 0xff}, {R: 0xff, G: 0xff, B: 0xff, A: 0xff}, {A: 0xff},
+	}
+	for x, w := range want {
+		got := img.NRGBAAt(x, 3)
+		if got.A != w.A || (w.A != 0 && got != w) {
+			t.Errorf("Pixel %d: got %v, want %v", x, got, w)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package psnr
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+)
+
+// InputInfo describes an input image as reported by WithInputInfo.
+type InputInfo struct {
+	// Format is the name of the format the image was decoded from, such as
+	// "jpeg" or "png", or "memory" for images passed to CompareImages.
+	Format string
+	Width  int
+	Height int
+	// BitDepth is the number of bits per sample as stored, or zero when it
+	// is unknown.
+	BitDepth int
+	// ColorModel is the stored color model: "gray", "gray+alpha", "rgb",
+	// "rgba", "paletted", "ycbcr", "ycbcr+alpha", "cmyk", "alpha" or
+	// "other".
+	ColorModel string
+	// Subsampling is the chroma subsampling of Y'CbCr images, such as
+	// "4:2:0", and empty for other color models.
+	Subsampling string
+	// Quality is the estimated quality of JPEG files, as reported by
+	// InspectJPEG, and zero for other formats.
+	Quality int
+	// PaletteSize is the number of palette entries of paletted images, such
+	// as PNG8 files, and zero for other images.
+	PaletteSize int
+	// Bytes is the size of the encoded image, or zero for images passed to
+	// CompareImages.
+	Bytes int
+}
+
+// describeInput reports the properties of a decoded input. The headers of
+// JPEG and PNG files take precedence over the decoded image, which decoders
+// may have converted.
+func describeInput(d *decoded) InputInfo {
+	bounds := d.img.Bounds()
+	info := InputInfo{Format: d.format, Width: bounds.Dx(), Height: bounds.Dy(), Bytes: len(d.data)}
+	info.ColorModel, info.BitDepth = imageColorModel(d.img)
+	switch img := d.img.(type) {
+	case *image.Paletted:
+		info.PaletteSize = len(img.Palette)
+	case *image.YCbCr:
+		info.Subsampling = subsamplingName(img.SubsampleRatio)
+	case *image.NYCbCrA:
+		info.Subsampling = subsamplingName(img.SubsampleRatio)
+	}
+
+	switch {
+	case bytes.HasPrefix(d.data, []byte{0xff, 0xd8}):
+		if frame, ok := readJPEGFrame(d.data); ok {
+			info.BitDepth = frame.precision
+			info.ColorModel, info.Subsampling = frame.colorModel(), frame.subsampling()
+		}
+		info.Quality, _ = estimateJPEGQuality(readJPEGQuantTables(d.data))
+	case bytes.HasPrefix(d.data, pngSignature):
+		if png, err := InspectPNG(d.data); err == nil {
+			info.BitDepth, info.ColorModel, info.Subsampling = png.BitDepth, png.ColorType, ""
+			info.PaletteSize = png.PaletteSize
+		}
+	}
+	return info
+}
+
+// pngColorModels names the PNG color types.
+var pngColorModels = map[byte]string{
+	pngColorGray:      "gray",
+	pngColorRGB:       "rgb",
+	pngColorPaletted:  "paletted",
+	pngColorGrayAlpha: "gray+alpha",
+	pngColorRGBA:      "rgba",
+}
+
+// imageColorModel returns the color model and bit depth of a decoded image
+// from its type.
+func imageColorModel(img image.Image) (string, int) {
+	switch img.(type) {
+	case *image.Gray:
+		return "gray", 8
+	case *image.Gray16:
+		return "gray", 16
+	case *image.RGBA, *image.NRGBA:
+		return "rgba", 8
+	case *image.RGBA64, *image.NRGBA64:
+		return "rgba", 16
+	case *image.Paletted:
+		return "paletted", 8
+	case *image.YCbCr:
+		return "ycbcr", 8
+	case *image.NYCbCrA:
+		return "ycbcr+alpha", 8
+	case *image.CMYK:
+		return "cmyk", 8
+	case *image.Alpha:
+		return "alpha", 8
+	case *image.Alpha16:
+		return "alpha", 16
+	}
+	return "other", 0
+}
+
+// subsamplingName returns the J:a:b notation of a subsample ratio.
+func subsamplingName(ratio image.YCbCrSubsampleRatio) string {
+	switch ratio {
+	case image.YCbCrSubsampleRatio444:
+		return "4:4:4"
+	case image.YCbCrSubsampleRatio422:
+		return "4:2:2"
+	case image.YCbCrSubsampleRatio420:
+		return "4:2:0"
+	case image.YCbCrSubsampleRatio440:
+		return "4:4:0"
+	case image.YCbCrSubsampleRatio411:
+		return "4:1:1"
+	case image.YCbCrSubsampleRatio410:
+		return "4:1:0"
+	}
+	return ""
+}
+
+// jpegFrame holds the fields of a JPEG start-of-frame segment.
+type jpegFrame struct {
+	// precision is the sample precision in bits.
+	precision int
+	// sampling holds the horizontal and vertical sampling factors of each
+	// component.
+	sampling [][2]int
+}
+
+// readJPEGFrame reads the start-of-frame segment of a JPEG stream.
+func readJPEGFrame(data []byte) (jpegFrame, bool) {
+	var frame jpegFrame
+	var found bool
+	jpegSegments(data, func(marker byte, payload []byte) bool {
+		// SOF0 to SOF15, except DHT, JPG and DAC
+		if marker < 0xc0 || marker > 0xcf || marker == 0xc4 || marker == 0xc8 || marker == 0xcc {
+			return true
+		}
+		if len(payload) < 6 || len(payload) < 6+3*int(payload[5]) {
+			return false
+		}
+		frame.precision = int(payload[0])
+		for i := 0; i < int(payload[5]); i++ {
+			factors := payload[6+3*i+1]
+			frame.sampling = append(frame.sampling, [2]int{int(factors >> 4), int(factors & 0x0f)})
+		}
+		found = len(frame.sampling) > 0
+		return false
+	})
+	return frame, found
+}
+
+// colorModel names the color model implied by the number of components.
+func (f jpegFrame) colorModel() string {
+	switch len(f.sampling) {
+	case 1:
+		return "gray"
+	case 3:
+		return "ycbcr"
+	case 4:
+		return "cmyk"
+	}
+	return "other"
+}
+
+// subsampling returns the chroma subsampling of a three-component frame
+// from the luma and first chroma sampling factors, or "" for other frames.
+func (f jpegFrame) subsampling() string {
+	if len(f.sampling) != 3 {
+		return ""
+	}
+	luma, chroma := f.sampling[0], f.sampling[1]
+	if chroma[0] == 0 || chroma[1] == 0 || luma[0]%chroma[0] != 0 || luma[1]%chroma[1] != 0 {
+		return fmt.Sprintf("%dx%d,%dx%d", luma[0], luma[1], chroma[0], chroma[1])
+	}
+	switch [2]int{luma[0] / chroma[0], luma[1] / chroma[1]} {
+	case [2]int{1, 1}:
+		return "4:4:4"
+	case [2]int{2, 1}:
+		return "4:2:2"
+	case [2]int{2, 2}:
+		return "4:2:0"
+	case [2]int{1, 2}:
+		return "4:4:0"
+	case [2]int{4, 1}:
+		return "4:1:1"
+	case [2]int{4, 2}:
+		return "4:1:0"
+	}
+	return fmt.Sprintf("%dx%d,%dx%d", luma[0], luma[1], chroma[0], chroma[1])
+}
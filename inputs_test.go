@@ -0,0 +1,78 @@
+package psnr
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"os"
+	"testing"
+)
+
+func TestWithInputInfo(t *testing.T) {
+	jpegData, err := os.ReadFile("testdata/test_original.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(jpegData))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ComputeDetailed(jpegData, jpegData, WithInputInfo())
+	if err != nil {
+		t.Fatalf("ComputeDetailed failed: %v", err)
+	}
+	jpegInfo, err := InspectJPEG(jpegData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := InputInfo{Format: "jpeg", Width: config.Width, Height: config.Height, BitDepth: 8, ColorModel: "ycbcr", Subsampling: "4:2:0", Quality: jpegInfo.Quality, Bytes: len(jpegData)}
+	if len(result.Inputs) != 2 || result.Inputs[0] != want || result.Inputs[1] != want {
+		t.Errorf("Expected %+v for both inputs, got %+v", want, result.Inputs)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	result, err = ComputeDetailed(buf.Bytes(), buf.Bytes(), WithInputInfo())
+	if err != nil {
+		t.Fatalf("ComputeDetailed failed: %v", err)
+	}
+	if want := (InputInfo{Format: "png", Width: 4, Height: 4, BitDepth: 8, ColorModel: "gray", Bytes: buf.Len()}); result.Inputs[1] != want {
+		t.Errorf("Expected %+v, got %+v", want, result.Inputs[1])
+	}
+
+	if result, _ := ComputeDetailed(jpegData, jpegData); result.Inputs != nil {
+		t.Errorf("Expected no inputs by default, got %+v", result.Inputs)
+	}
+
+	gray := image.NewGray16(image.Rect(0, 0, 3, 2))
+	result, err = CompareImages(context.Background(), gray, gray, WithInputInfo())
+	if err != nil {
+		t.Fatalf("CompareImages failed: %v", err)
+	}
+	if want := (InputInfo{Format: "memory", Width: 3, Height: 2, BitDepth: 16, ColorModel: "gray"}); result.Inputs[0] != want {
+		t.Errorf("Expected %+v, got %+v", want, result.Inputs[0])
+	}
+}
+
+func TestJPEGFrameSubsampling(t *testing.T) {
+	tests := []struct {
+		sampling [][2]int
+		want     string
+	}{
+		{[][2]int{{1, 1}, {1, 1}, {1, 1}}, "4:4:4"},
+		{[][2]int{{2, 1}, {1, 1}, {1, 1}}, "4:2:2"},
+		{[][2]int{{2, 2}, {1, 1}, {1, 1}}, "4:2:0"},
+		{[][2]int{{4, 1}, {1, 1}, {1, 1}}, "4:1:1"},
+		{[][2]int{{3, 1}, {2, 1}, {2, 1}}, "3x1,2x1"},
+		{[][2]int{{1, 1}}, ""},
+	}
+	for _, tt := range tests {
+		if got := (jpegFrame{precision: 8, sampling: tt.sampling}).subsampling(); got != tt.want {
+			t.Errorf("subsampling(%v) = %q, want %q", tt.sampling, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package psnr
+
+import "encoding/binary"
+
+// isoBox is a box of an ISO base media file, such as an AVIF or HEIF image.
+type isoBox struct {
+	typ  string
+	data []byte
+}
+
+// readISOBoxes splits data into consecutive boxes. It stops at the first
+// malformed box header and returns the boxes read so far.
+func readISOBoxes(data []byte) []isoBox {
+	var boxes []isoBox
+	for len(data) >= 8 {
+		size := uint64(binary.BigEndian.Uint32(data[:4]))
+		typ := string(data[4:8])
+		header := uint64(8)
+		switch size {
+		case 0:
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return boxes
+			}
+			size, header = binary.BigEndian.Uint64(data[8:16]), 16
+		}
+		if size < header || size > uint64(len(data)) {
+			return boxes
+		}
+		boxes = append(boxes, isoBox{typ: typ, data: data[header:size]})
+		data = data[size:]
+	}
+	return boxes
+}
+
+// findISOBox returns the payload of the first box of type typ in boxes.
+func findISOBox(boxes []isoBox, typ string) ([]byte, bool) {
+	for _, box := range boxes {
+		if box.typ == typ {
+			return box.data, true
+		}
+	}
+	return nil, false
+}
+
+// heifTransfer returns the transfer characteristics of the first nclx colr
+// property of an AVIF or HEIF image, found in meta/iprp/ipco, and false when
+// data is not such an image or declares no nclx color.
+func heifTransfer(data []byte) (uint16, bool) {
+	top := readISOBoxes(data)
+	if len(top) == 0 || top[0].typ != "ftyp" {
+		return 0, false
+	}
+	meta, ok := findISOBox(top, "meta")
+	// meta is a full box: a version and flags precede its children
+	if !ok || len(meta) < 4 {
+		return 0, false
+	}
+	iprp, ok := findISOBox(readISOBoxes(meta[4:]), "iprp")
+	if !ok {
+		return 0, false
+	}
+	ipco, ok := findISOBox(readISOBoxes(iprp), "ipco")
+	if !ok {
+		return 0, false
+	}
+	for _, box := range readISOBoxes(ipco) {
+		// colour_type, then colour_primaries, transfer_characteristics,
+		// matrix_coefficients and the full range flag
+		if box.typ == "colr" && len(box.data) >= 11 && string(box.data[:4]) == "nclx" {
+			return binary.BigEndian.Uint16(box.data[6:8]), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,140 @@
+package psnr
+
+import (
+	"bytes"
+	"errors"
+	"math"
+)
+
+// JPEGInfo describes how a JPEG file was encoded.
+type JPEGInfo struct {
+	// Subsampling is the chroma subsampling, such as "4:2:0", or empty for
+	// grayscale and CMYK files.
+	Subsampling string
+	// Quality is the quality (1-100) of the libjpeg scaling of the standard
+	// quantization tables closest to the file's tables, or 0 when the file
+	// has none.
+	Quality int
+	// StandardTables reports whether the tables are exactly the standard
+	// ones scaled to Quality, as written by libjpeg, image/jpeg and most
+	// tools. Otherwise Quality is only an approximation.
+	StandardTables bool
+}
+
+// ErrNotJPEG is returned by InspectJPEG for data that is not a JPEG stream.
+var ErrNotJPEG = errors.New("not a JPEG stream")
+
+// InspectJPEG reports the chroma subsampling and estimated quality of a JPEG
+// file from its headers, without decoding it. The two explain most PSNR
+// differences between JPEG variants of the same image.
+func InspectJPEG(data []byte) (*JPEGInfo, error) {
+	if !bytes.HasPrefix(data, []byte{0xff, 0xd8}) {
+		return nil, ErrNotJPEG
+	}
+	frame, ok := readJPEGFrame(data)
+	if !ok {
+		return nil, errors.New("JPEG stream has no frame header")
+	}
+	info := &JPEGInfo{Subsampling: frame.subsampling()}
+	info.Quality, info.StandardTables = estimateJPEGQuality(readJPEGQuantTables(data))
+	return info, nil
+}
+
+// jpegStandardTables are the luminance and chrominance quantization tables
+// of the JPEG specification (Annex K), in zigzag order as stored in DQT
+// segments.
+var jpegStandardTables = [2][64]uint16{
+	{
+		16, 11, 12, 14, 12, 10, 16, 14,
+		13, 14, 18, 17, 16, 19, 24, 40,
+		26, 24, 22, 22, 24, 49, 35, 37,
+		29, 40, 58, 51, 61, 60, 57, 51,
+		56, 55, 64, 72, 92, 78, 64, 68,
+		87, 69, 55, 56, 80, 109, 81, 87,
+		95, 98, 103, 104, 103, 62, 77, 113,
+		121, 112, 100, 120, 92, 101, 103, 99,
+	},
+	{
+		17, 18, 18, 24, 21, 24, 47, 26,
+		26, 47, 99, 66, 56, 66, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+	},
+}
+
+// readJPEGQuantTables returns the quantization tables defined by the DQT
+// segments of a JPEG stream, indexed by table ID.
+func readJPEGQuantTables(data []byte) map[int][64]uint16 {
+	tables := make(map[int][64]uint16)
+	jpegSegments(data, func(marker byte, payload []byte) bool {
+		if marker != 0xdb {
+			return true
+		}
+		for len(payload) > 0 {
+			wide, id := payload[0]>>4 != 0, int(payload[0]&0x0f)
+			size := 65
+			if wide {
+				size = 129
+			}
+			if len(payload) < size {
+				return false
+			}
+			var table [64]uint16
+			for i := range table {
+				if wide {
+					table[i] = uint16(payload[1+2*i])<<8 | uint16(payload[2+2*i])
+				} else {
+					table[i] = uint16(payload[1+i])
+				}
+			}
+			tables[id] = table
+			payload = payload[size:]
+		}
+		return true
+	})
+	return tables
+}
+
+// estimateJPEGQuality finds the libjpeg quality whose scaled standard tables
+// are closest to tables 0 (luminance) and 1 (chrominance), and reports
+// whether they match exactly.
+func estimateJPEGQuality(tables map[int][64]uint16) (int, bool) {
+	if len(tables) == 0 {
+		return 0, false
+	}
+	best, bestError := 0, math.MaxInt
+	for quality := 100; quality >= 1; quality-- {
+		var sumError int
+		for id, standard := range jpegStandardTables {
+			table, ok := tables[id]
+			if !ok {
+				continue
+			}
+			for i, q := range scaleQuantTable(standard, quality) {
+				sumError += abs(int(table[i]) - int(q))
+			}
+		}
+		if sumError < bestError {
+			best, bestError = quality, sumError
+		}
+	}
+	return best, bestError == 0
+}
+
+// scaleQuantTable scales a standard table to a quality as libjpeg does with
+// baseline tables.
+func scaleQuantTable(table [64]uint16, quality int) [64]uint16 {
+	scale := 200 - 2*quality
+	if quality < 50 {
+		scale = 5000 / quality
+	}
+	var scaled [64]uint16
+	for i, q := range table {
+		scaled[i] = uint16(min(max((int(q)*scale+50)/100, 1), 255))
+	}
+	return scaled
+}
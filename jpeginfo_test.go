@@ -0,0 +1,59 @@
+package psnr
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+func TestInspectJPEG(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 7)
+	}
+	for _, quality := range []int{5, 30, 50, 75, 90, 100} {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			t.Fatal(err)
+		}
+		info, err := InspectJPEG(buf.Bytes())
+		if err != nil {
+			t.Fatalf("InspectJPEG failed: %v", err)
+		}
+		if info.Quality != quality || !info.StandardTables || info.Subsampling != "4:2:0" {
+			t.Errorf("Quality %d: got %+v", quality, info)
+		}
+	}
+
+	var buf bytes.Buffer
+	gray := image.NewGray(image.Rect(0, 0, 8, 8))
+	gray.SetGray(1, 1, color.Gray{Y: 200})
+	if err := jpeg.Encode(&buf, gray, &jpeg.Options{Quality: 60}); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	info, err := InspectJPEG(data)
+	if err != nil {
+		t.Fatalf("InspectJPEG failed: %v", err)
+	}
+	if info.Quality != 60 || info.Subsampling != "" {
+		t.Errorf("Unexpected grayscale info: %+v", info)
+	}
+
+	// Custom tables only approximate a quality
+	dqt := bytes.Index(data, []byte{0xff, 0xdb})
+	data[dqt+5+10]++
+	if info, err := InspectJPEG(data); err != nil || info.Quality != 60 || info.StandardTables {
+		t.Errorf("Expected an approximate quality of 60, got %+v, %v", info, err)
+	}
+
+	if _, err := InspectJPEG([]byte("\x89PNG")); !errors.Is(err, ErrNotJPEG) {
+		t.Errorf("Expected ErrNotJPEG, got %v", err)
+	}
+	if _, err := InspectJPEG([]byte{0xff, 0xd8, 0xff, 0xd9}); err == nil {
+		t.Error("Expected error for a JPEG without a frame header")
+	}
+}
@@ -0,0 +1,163 @@
+package psnr
+
+import (
+	"image"
+	"math"
+	"runtime"
+)
+
+// ColorSpace selects the space in which WithColorSpace measures the error.
+type ColorSpace int
+
+const (
+	// ColorSpaceRGB compares the encoded RGB samples only, which is the
+	// default.
+	ColorSpaceRGB ColorSpace = iota
+	// ColorSpaceOKLab additionally compares the images in OKLab, whose
+	// lightness ranges from 0 to 1.
+	ColorSpaceOKLab
+	// ColorSpaceCIELAB additionally compares the images in CIE 1976 L*a*b*
+	// with a D65 white point, whose lightness ranges from 0 to 100.
+	ColorSpaceCIELAB
+)
+
+// ColorSpaceResult is the error measured by WithColorSpace.
+type ColorSpaceResult struct {
+	// Space is the color space of the comparison.
+	Space ColorSpace
+	// Peak is the lightness range of the space: 1 for OKLab and 100 for
+	// CIELAB. PSNR values are measured against it.
+	Peak float64
+	// PSNR and MSE combine the three channels; MSE is in the units of the
+	// space.
+	PSNR float64
+	MSE  float64
+	// Planes holds the error of each channel: "l", "a" and "b".
+	Planes []PlaneResult
+}
+
+// srgbToLinear maps 8-bit sRGB samples to linear light.
+var srgbToLinear = func() (table [256]float64) {
+	for i := range table {
+		table[i] = srgbEOTF(float64(i) / 255)
+	}
+	return table
+}()
+
+// peak returns the lightness range of s.
+func (s ColorSpace) peak() float64 {
+	if s == ColorSpaceCIELAB {
+		return 100
+	}
+	return 1
+}
+
+// convert returns the coordinates of an 8-bit sRGB color in s.
+func (s ColorSpace) convert(r, g, b uint8) [3]float64 {
+	lr, lg, lb := srgbToLinear[r], srgbToLinear[g], srgbToLinear[b]
+	if s == ColorSpaceCIELAB {
+		// D65 XYZ, relative to the white point
+		x := (0.4124564*lr + 0.3575761*lg + 0.1804375*lb) / 0.95047
+		y := 0.2126729*lr + 0.7151522*lg + 0.0721750*lb
+		z := (0.0193339*lr + 0.1191920*lg + 0.9503041*lb) / 1.08883
+		fx, fy, fz := labF(x), labF(y), labF(z)
+		return [3]float64{116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)}
+	}
+	l := math.Cbrt(0.4122214708*lr + 0.5363325363*lg + 0.0514459929*lb)
+	m := math.Cbrt(0.2119034982*lr + 0.6806995451*lg + 0.1073969566*lb)
+	sh := math.Cbrt(0.0883024619*lr + 0.2817188376*lg + 0.6299787005*lb)
+	return [3]float64{
+		0.2104542553*l + 0.7936177850*m - 0.0040720468*sh,
+		1.9779984951*l - 2.4285922050*m + 0.4505937099*sh,
+		0.0259040371*l + 0.7827717662*m - 0.8086757660*sh,
+	}
+}
+
+// labF is the CIELAB companding function.
+func labF(t float64) float64 {
+	const delta = 6.0 / 29
+	if t > delta*delta*delta {
+		return math.Cbrt(t)
+	}
+	return t/(3*delta*delta) + 4.0/29
+}
+
+// colorSpaceResult converts the region r of img1 and the same-sized region
+// of img2 at origin2 to space s and measures their error per channel. The
+// samples are converted as compared, premultiplied unless straight alpha
+// was requested, and alpha itself takes no part.
+func colorSpaceResult(img1, img2 *image.RGBA, r image.Rectangle, origin2 image.Point, s ColorSpace) *ColorSpaceResult {
+	width, height := r.Dx(), r.Dy()
+	rows := make([][3]float64, height)
+	workers := min(runtime.GOMAXPROCS(0), max(height, 1))
+	parallel(workers, height, func(y int) {
+		pix1 := img1.Pix[img1.PixOffset(r.Min.X, r.Min.Y+y):]
+		pix2 := img2.Pix[img2.PixOffset(origin2.X, origin2.Y+y):]
+		var sums [3]float64
+		for i := 0; i < width*4; i += 4 {
+			if pix1[i] == pix2[i] && pix1[i+1] == pix2[i+1] && pix1[i+2] == pix2[i+2] {
+				continue
+			}
+			c1 := s.convert(pix1[i], pix1[i+1], pix1[i+2])
+			c2 := s.convert(pix2[i], pix2[i+1], pix2[i+2])
+			for c := range sums {
+				diff := c1[c] - c2[c]
+				sums[c] += diff * diff
+			}
+		}
+		rows[y] = sums
+	})
+
+	// Summing the rows in order keeps the result independent of scheduling
+	var totals [3]compensatedSum
+	for _, row := range rows {
+		for c := range totals {
+			totals[c].add(row[c])
+		}
+	}
+	sums := [3]float64{totals[0].value(), totals[1].value(), totals[2].value()}
+
+	peak := s.peak()
+	pixels := float64(max(width*height, 1))
+	result := &ColorSpaceResult{Space: s, Peak: peak}
+	for c, name := range []string{"l", "a", "b"} {
+		mse := sums[c] / pixels
+		result.Planes = append(result.Planes, PlaneResult{Name: name, PSNR: rescalePSNR(psnrFromMSE(mse), peak), MSE: mse})
+	}
+	result.MSE = (sums[0] + sums[1] + sums[2]) / (3 * pixels)
+	result.PSNR = rescalePSNR(psnrFromMSE(result.MSE), peak)
+	return result
+}
+
+// deltaE76 returns the mean and largest CIE 1976 color difference ΔE*ab
+// between the pixels of two images of the same size, converted from their
+// RGB samples as compared.
+func deltaE76(img1, img2 *image.RGBA) (mean, largest float64) {
+	width, height := img1.Rect.Dx(), img1.Rect.Dy()
+	type rowSum struct{ sum, max float64 }
+	rows := make([]rowSum, height)
+	workers := min(runtime.GOMAXPROCS(0), max(height, 1))
+	parallel(workers, height, func(y int) {
+		pix1 := img1.Pix[img1.PixOffset(img1.Rect.Min.X, img1.Rect.Min.Y+y):]
+		pix2 := img2.Pix[img2.PixOffset(img2.Rect.Min.X, img2.Rect.Min.Y+y):]
+		var row rowSum
+		for i := 0; i < width*4; i += 4 {
+			if pix1[i] == pix2[i] && pix1[i+1] == pix2[i+1] && pix1[i+2] == pix2[i+2] {
+				continue
+			}
+			c1 := ColorSpaceCIELAB.convert(pix1[i], pix1[i+1], pix1[i+2])
+			c2 := ColorSpaceCIELAB.convert(pix2[i], pix2[i+1], pix2[i+2])
+			d := math.Sqrt((c1[0]-c2[0])*(c1[0]-c2[0]) + (c1[1]-c2[1])*(c1[1]-c2[1]) + (c1[2]-c2[2])*(c1[2]-c2[2]))
+			row.sum += d
+			row.max = max(row.max, d)
+		}
+		rows[y] = row
+	})
+
+	var total compensatedSum
+	for _, row := range rows {
+		total.add(row.sum)
+		largest = max(largest, row.max)
+	}
+	return total.value() / float64(max(width*height, 1)), largest
+}
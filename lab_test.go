@@ -0,0 +1,99 @@
+package psnr
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestColorSpaceConvert(t *testing.T) {
+	tests := []struct {
+		space      ColorSpace
+		r, g, b    uint8
+		l, a, labB float64
+	}{
+		{ColorSpaceCIELAB, 255, 255, 255, 100, 0, 0},
+		{ColorSpaceCIELAB, 0, 0, 0, 0, 0, 0},
+		{ColorSpaceCIELAB, 255, 0, 0, 53.24, 80.09, 67.20},
+		{ColorSpaceOKLab, 255, 255, 255, 1, 0, 0},
+		{ColorSpaceOKLab, 255, 0, 0, 0.6280, 0.2249, 0.1258},
+	}
+	for _, tt := range tests {
+		got := tt.space.convert(tt.r, tt.g, tt.b)
+		tolerance := 0.01 * tt.space.peak()
+		if math.Abs(got[0]-tt.l) > tolerance || math.Abs(got[1]-tt.a) > tolerance || math.Abs(got[2]-tt.labB) > tolerance {
+			t.Errorf("space %d: (%d, %d, %d) = %v, want [%g %g %g]", tt.space, tt.r, tt.g, tt.b, got, tt.l, tt.a, tt.labB)
+		}
+	}
+}
+
+func TestWithColorSpace(t *testing.T) {
+	img1 := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	img2 := image.NewRGBA(img1.Rect)
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			img1.SetRGBA(x, y, color.RGBA{R: 200, G: 40, B: 40, A: 255})
+			// Only the hue of every other row changes
+			c := color.RGBA{R: 200, G: 40, B: 40, A: 255}
+			if y%2 == 1 {
+				c.B = 80
+			}
+			img2.SetRGBA(x, y, c)
+		}
+	}
+	data1, data2 := encodePNG(t, img1), encodePNG(t, img2)
+
+	for _, space := range []ColorSpace{ColorSpaceOKLab, ColorSpaceCIELAB} {
+		result, err := ComputeDetailed(data1, data2, WithColorSpace(space))
+		if err != nil {
+			t.Fatalf("Error computing PSNR: %v", err)
+		}
+		cs := result.ColorSpace
+		if cs == nil || cs.Space != space || cs.Peak != space.peak() || len(cs.Planes) != 3 {
+			t.Fatalf("Unexpected color space result: %+v", cs)
+		}
+		if cs.MSE <= 0 || math.IsInf(cs.PSNR, 1) {
+			t.Errorf("Expected an error, got %+v", cs)
+		}
+		// The change is mostly chromatic
+		if cs.Planes[0].MSE >= cs.Planes[2].MSE {
+			t.Errorf("Expected a larger error in b than in L, got %+v", cs.Planes)
+		}
+		if math.Abs(cs.MSE-(cs.Planes[0].MSE+cs.Planes[1].MSE+cs.Planes[2].MSE)/3) > 1e-12 {
+			t.Errorf("Expected the MSE to average the planes, got %+v", cs)
+		}
+
+		same, err := ComputeDetailed(data1, data1, WithColorSpace(space))
+		if err != nil {
+			t.Fatalf("Error computing PSNR: %v", err)
+		}
+		if same.ColorSpace == nil || !math.IsInf(same.ColorSpace.PSNR, 1) {
+			t.Errorf("Expected Inf for identical images, got %+v", same.ColorSpace)
+		}
+	}
+
+	plain, err := ComputeDetailed(data1, data2)
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if plain.ColorSpace != nil {
+		t.Error("Expected no color space result by default")
+	}
+
+	if _, err := ComputeDetailed(data1, data2, WithColorSpace(ColorSpace(7))); err == nil {
+		t.Error("Expected error for an unknown color space")
+	}
+}
+
+func TestDeltaE76(t *testing.T) {
+	white := SolidImage(image.Rect(0, 0, 4, 4), color.White)
+	black := SolidImage(image.Rect(0, 0, 4, 4), color.Black)
+	if mean, largest := deltaE76(white, white); mean != 0 || largest != 0 {
+		t.Errorf("Expected no difference, got %v, %v", mean, largest)
+	}
+	// Black and white differ only in lightness, by 100
+	if mean, largest := deltaE76(white, black); mean < 99.9 || mean > 100.1 || largest != mean {
+		t.Errorf("Expected ΔE 100, got %v, %v", mean, largest)
+	}
+}
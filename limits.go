@@ -0,0 +1,102 @@
+package psnr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// ErrImageTooLarge is returned, wrapped with the image position, when an
+// image's header declares a size beyond the limits set by WithMaxPixels,
+// WithMaxDimensions or WithMaxDecodeMemory. The image is not decoded, unless
+// its header is one image.DecodeConfig cannot read; such images are checked
+// right after decoding instead.
+var ErrImageTooLarge = errors.New("image exceeds decode limits")
+
+// decodeLimits bounds the images that will be decoded; zero fields are
+// unlimited.
+type decodeLimits struct {
+	maxPixels int64
+	maxWidth  int
+	maxHeight int
+	maxMemory int64
+}
+
+// enabled reports whether any limit is set.
+func (l decodeLimits) enabled() bool {
+	return l.maxPixels > 0 || l.maxWidth > 0 || l.maxHeight > 0 || l.maxMemory > 0
+}
+
+// validate reports a negative limit.
+func (l decodeLimits) validate() error {
+	if l.maxPixels < 0 || l.maxWidth < 0 || l.maxHeight < 0 || l.maxMemory < 0 {
+		return fmt.Errorf("invalid decode limits: negative value")
+	}
+	return nil
+}
+
+// check reads the image header from data and returns ErrImageTooLarge when
+// the declared size exceeds a limit. Headers that cannot be parsed, such as
+// those of sniffed backends, are checked by checkImage once decoded.
+func (l decodeLimits) check(data []byte) error {
+	if !l.enabled() {
+		return nil
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	return l.checkConfig(config)
+}
+
+// checkImage returns ErrImageTooLarge when a decoded image exceeds a limit.
+// It catches the images whose headers check could not read.
+func (l decodeLimits) checkImage(img image.Image) error {
+	if !l.enabled() {
+		return nil
+	}
+	size := img.Bounds().Size()
+	return l.checkConfig(image.Config{ColorModel: img.ColorModel(), Width: size.X, Height: size.Y})
+}
+
+// checkConfig returns ErrImageTooLarge when an image of the given size and
+// color model exceeds a limit.
+func (l decodeLimits) checkConfig(config image.Config) error {
+	width, height := config.Width, config.Height
+	pixels := int64(width) * int64(height)
+	switch {
+	case l.maxWidth > 0 && width > l.maxWidth, l.maxHeight > 0 && height > l.maxHeight:
+		return fmt.Errorf("%w: %dx%d exceeds %dx%d", ErrImageTooLarge, width, height, l.maxWidth, l.maxHeight)
+	case l.maxPixels > 0 && pixels > l.maxPixels:
+		return fmt.Errorf("%w: %d pixels exceeds %d", ErrImageTooLarge, pixels, l.maxPixels)
+	}
+	if l.maxMemory > 0 {
+		if memory := decodeMemory(config); memory > l.maxMemory {
+			return fmt.Errorf("%w: decoding needs about %d bytes, limit is %d", ErrImageTooLarge, memory, l.maxMemory)
+		}
+	}
+	return nil
+}
+
+// decodeMemory estimates the bytes a decoder allocates for the pixels of an
+// image with the given header.
+func decodeMemory(config image.Config) int64 {
+	pixels := int64(config.Width) * int64(config.Height)
+	switch config.ColorModel {
+	case color.GrayModel, color.AlphaModel:
+		return pixels
+	case color.YCbCrModel:
+		// Up to 4:4:4, i.e. three full planes
+		return pixels * 3
+	case color.Gray16Model, color.Alpha16Model:
+		return pixels * 2
+	case color.RGBA64Model, color.NRGBA64Model:
+		return pixels * 8
+	}
+	if _, ok := config.ColorModel.(color.Palette); ok {
+		return pixels
+	}
+	return pixels * 4
+}
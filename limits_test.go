@@ -0,0 +1,97 @@
+package psnr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"os"
+	"testing"
+)
+
+// pngHeader returns a PNG that declares width x height RGBA pixels in its
+// header but carries no image data, like a decompression bomb's header.
+func pngHeader(width, height uint32) []byte {
+	var buf bytes.Buffer
+	buf.Write(pngSignature)
+	writeChunk := func(typ string, data []byte) {
+		binary.Write(&buf, binary.BigEndian, uint32(len(data)))
+		buf.WriteString(typ)
+		buf.Write(data)
+		crc := crc32.NewIEEE()
+		crc.Write([]byte(typ))
+		crc.Write(data)
+		binary.Write(&buf, binary.BigEndian, crc.Sum32())
+	}
+
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], width)
+	binary.BigEndian.PutUint32(ihdr[4:], height)
+	ihdr[8], ihdr[9] = 8, pngColorRGBA
+	writeChunk("IHDR", ihdr)
+	writeChunk("IEND", nil)
+	return buf.Bytes()
+}
+
+func TestDecodeLimits(t *testing.T) {
+	data, err := os.ReadFile("testdata/test_original.png")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+	bomb := pngHeader(50000, 50000)
+
+	tests := []struct {
+		name   string
+		data   []byte
+		opts   []Option
+		tooBig bool
+	}{
+		{"bomb over pixel limit", bomb, []Option{WithMaxPixels(100_000_000)}, true},
+		{"bomb over dimensions", bomb, []Option{WithMaxDimensions(16384, 16384)}, true},
+		{"bomb over memory", bomb, []Option{WithMaxDecodeMemory(1 << 30)}, true},
+		{"width only", data, []Option{WithMaxDimensions(10, 0)}, true},
+		{"tiny memory", data, []Option{WithMaxDecodeMemory(100)}, true},
+		{"within limits", data, []Option{WithMaxPixels(100_000_000), WithMaxDimensions(16384, 16384), WithMaxDecodeMemory(1 << 30)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ComputeDetailed(data, tt.data, tt.opts...)
+			if tt.tooBig {
+				if !errors.Is(err, ErrImageTooLarge) {
+					t.Errorf("Expected ErrImageTooLarge, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+
+	if _, err := ComputeDetailed(data, data, WithMaxPixels(-1)); err == nil {
+		t.Error("Expected error for negative limit")
+	}
+}
+
+func TestDecodeLimitsSniffedBackend(t *testing.T) {
+	// Sniffed formats have headers image.DecodeConfig cannot read, so they
+	// are checked once decoded
+	registerSniffedDecoder("test_sniffed_raw", "testraw", func(data []byte) bool {
+		return bytes.HasPrefix(data, []byte("TESTRAW"))
+	}, func([]byte) (image.Image, error) {
+		return image.NewRGBA(image.Rect(0, 0, 100, 100)), nil
+	})
+	data := []byte("TESTRAW")
+
+	if _, err := ComputeDetailed(data, data, WithDecoder("test_sniffed_raw"), WithMaxPixels(1000)); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("Expected ErrImageTooLarge, got %v", err)
+	}
+	if _, err := ComputeDetailed(data, data, WithDecoder("test_sniffed_raw"), WithMaxDecodeMemory(100*100*4-1)); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("Expected ErrImageTooLarge, got %v", err)
+	}
+	if _, err := ComputeDetailed(data, data, WithDecoder("test_sniffed_raw"), WithMaxPixels(100*100)); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package psnr
+
+import (
+	"fmt"
+	"image"
+)
+
+// unionMask marks in a the pixels marked in b and returns it, or returns b
+// when a is nil.
+func unionMask(a, b []bool) []bool {
+	if a == nil {
+		return b
+	}
+	for i, v := range b {
+		a[i] = a[i] || v
+	}
+	return a
+}
+
+// maskedMSE returns the MSE over the region r of img1 and the same-sized
+// region of img2 at origin2, leaving out the pixels marked in excluded,
+// which holds one flag per pixel of r in row-major order. It fails when
+// every pixel is excluded, as nothing is left to measure.
+func maskedMSE(img1, img2 *image.RGBA, r image.Rectangle, origin2 image.Point, hasAlpha bool, excluded []bool) (float64, error) {
+	channels := 3
+	if hasAlpha {
+		channels = 4
+	}
+	width := r.Dx()
+	var sum, samples uint64
+	for y := 0; y < r.Dy(); y++ {
+		row1 := img1.Pix[img1.PixOffset(r.Min.X, r.Min.Y+y):]
+		row2 := img2.Pix[img2.PixOffset(origin2.X, origin2.Y+y):]
+		for x := 0; x < width; x++ {
+			if excluded[y*width+x] {
+				continue
+			}
+			for c := 0; c < channels; c++ {
+				diff := int(row1[x*4+c]) - int(row2[x*4+c])
+				sum += uint64(diff * diff)
+			}
+			samples += uint64(channels)
+		}
+	}
+	if samples == 0 {
+		return 0, fmt.Errorf("every pixel was excluded from the comparison")
+	}
+	return float64(sum) / float64(samples), nil
+}
+
+// countMask returns the number of pixels marked in mask.
+func countMask(mask []bool) int {
+	n := 0
+	for _, v := range mask {
+		if v {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,38 @@
+package psnr
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestMaskedMSE(t *testing.T) {
+	img1 := image.NewRGBA(image.Rect(0, 0, 3, 1))
+	img2 := image.NewRGBA(image.Rect(0, 0, 4, 1))
+	for x := 0; x < 4; x++ {
+		img2.SetRGBA(x, 0, color.RGBA{A: 255})
+	}
+	img1.SetRGBA(0, 0, color.RGBA{A: 255})
+	img1.SetRGBA(1, 0, color.RGBA{R: 30, A: 255})
+	img1.SetRGBA(2, 0, color.RGBA{R: 90, A: 255})
+
+	// The second image is compared from x=1
+	got, err := maskedMSE(img1, img2, img1.Rect, image.Point{1, 0}, false, []bool{false, true, false})
+	if want := 90.0 * 90 / 6; got != want || err != nil {
+		t.Errorf("Expected MSE %v, got %v, %v", want, got, err)
+	}
+	if _, err := maskedMSE(img1, img2, img1.Rect, image.Point{}, true, []bool{true, true, true}); err == nil {
+		t.Error("Expected an error with every pixel excluded")
+	}
+}
+
+func TestUnionMask(t *testing.T) {
+	b := []bool{false, true, false}
+	if got := unionMask(nil, b); &got[0] != &b[0] {
+		t.Error("Expected the second mask when the first is nil")
+	}
+	got := unionMask([]bool{true, false, false}, b)
+	if !got[0] || !got[1] || got[2] || countMask(got) != 2 {
+		t.Errorf("Unexpected union: %v", got)
+	}
+}
@@ -0,0 +1,118 @@
+package psnr
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+)
+
+// ComputeMatrix returns the PSNR between every pair of images as an N x N
+// matrix, where m[i][j] compares images[i] against images[j], e.g. to
+// cluster near-duplicate assets. Each image is decoded once and pairs are
+// compared in parallel on runtime.GOMAXPROCS(0) workers. The diagonal is
+// +Inf and pairs whose dimensions differ are NaN. Unless the options make
+// the comparison asymmetric (weighting by the first image, a reference peak
+// or a compatibility mode), only one triangle is computed and mirrored.
+// Pairs that WithHashPrefilter skips are NaN as well.
+// WithProgress reports the number of completed pairs.
+func ComputeMatrix(images [][]byte, opts ...Option) ([][]float64, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	progress := o.progress
+	pairOptions := *o
+	pairOptions.progress = nil
+
+	n := len(images)
+	workers := min(runtime.GOMAXPROCS(0), max(n, 1))
+
+	decodedImages := make([]*decoded, n)
+	hashes := make([]uint64, n)
+	errs := make([]error, n)
+	parallel(workers, n, func(i int) {
+		decodedImages[i], errs[i] = o.decode(images[i])
+		if errs[i] == nil && o.hashFilter != nil {
+			hashes[i] = imageHash(decodedImages[i].img, o.hashFilter.kind)
+		}
+	})
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode image %d: %w", i, err)
+		}
+	}
+
+	matrix := make([][]float64, n)
+	for i := range matrix {
+		matrix[i] = make([]float64, n)
+		matrix[i][i] = math.Inf(1)
+	}
+
+	symmetric := !o.needsRGBA() && o.compat == CompatibilityDefault && o.peak.kind != peakReferenceMax
+	type pair struct{ i, j int }
+	var pairs []pair
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if i != j && (!symmetric || i < j) {
+				pairs = append(pairs, pair{i, j})
+			}
+		}
+	}
+
+	var mu sync.Mutex
+	var firstErr error
+	done := 0
+	parallel(workers, len(pairs), func(k int) {
+		p := pairs[k]
+		value := math.NaN()
+		var err error
+		skip := o.hashFilter != nil && o.hashFilter.check(hashes[p.i], hashes[p.j]) != nil
+		if !skip && checkDimensions(decodedImages[p.i].img, decodedImages[p.j].img) == nil {
+			var result *Result
+			if result, err = compare(context.Background(), decodedImages[p.i], decodedImages[p.j], &pairOptions); err == nil {
+				value = result.PSNR
+			}
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to compare images %d and %d: %w", p.i, p.j, err)
+		}
+		matrix[p.i][p.j] = value
+		if symmetric {
+			matrix[p.j][p.i] = value
+		}
+		done++
+		if progress != nil {
+			progress(done, len(pairs))
+		}
+	})
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return matrix, nil
+}
+
+// parallel calls fn for every index in [0, n) on the given number of
+// goroutines and waits for all calls to return.
+func parallel(workers, n int, fn func(i int)) {
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
@@ -0,0 +1,78 @@
+package psnr
+
+import (
+	"math"
+	"os"
+	"sync/atomic"
+	"testing"
+)
+
+func TestComputeMatrix(t *testing.T) {
+	var images [][]byte
+	for _, file := range []string{
+		"testdata/test_original.jpg",
+		"testdata/quality_50.jpg",
+		"testdata/test_original.jpg",
+		"testdata/size1.jpg",
+	} {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", file, err)
+		}
+		images = append(images, data)
+	}
+
+	var calls atomic.Int32
+	matrix, err := ComputeMatrix(images, WithProgress(func(done, total int) {
+		calls.Add(1)
+		if total != 6 {
+			t.Errorf("Expected 6 pairs, got %d", total)
+		}
+	}))
+	if err != nil {
+		t.Fatalf("Error computing matrix: %v", err)
+	}
+	if calls.Load() != 6 {
+		t.Errorf("Expected 6 progress reports, got %d", calls.Load())
+	}
+
+	want, err := Compute(images[0], images[1])
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	for i := range matrix {
+		if !math.IsInf(matrix[i][i], 1) {
+			t.Errorf("Expected Inf on the diagonal at %d, got %f", i, matrix[i][i])
+		}
+		for j := range matrix {
+			if math.Float64bits(matrix[i][j]) != math.Float64bits(matrix[j][i]) {
+				t.Errorf("Expected a symmetric matrix at (%d, %d): %f vs %f", i, j, matrix[i][j], matrix[j][i])
+			}
+		}
+	}
+	if matrix[0][1] != want || matrix[2][1] != want {
+		t.Errorf("Expected %.6f, got %.6f and %.6f", want, matrix[0][1], matrix[2][1])
+	}
+	if !math.IsInf(matrix[0][2], 1) {
+		t.Errorf("Expected Inf for identical images, got %f", matrix[0][2])
+	}
+	if !math.IsNaN(matrix[0][3]) {
+		t.Errorf("Expected NaN for different dimensions, got %f", matrix[0][3])
+	}
+
+	// Weighting by the first image makes the matrix asymmetric
+	weighted, err := ComputeMatrix(images[:2], WithEdgeWeighting())
+	if err != nil {
+		t.Fatalf("Error computing matrix: %v", err)
+	}
+	if weighted[0][1] != want || weighted[1][0] != want {
+		t.Errorf("Expected raw PSNR %.6f both ways, got %.6f and %.6f", want, weighted[0][1], weighted[1][0])
+	}
+
+	if _, err := ComputeMatrix(append(images, []byte("not an image"))); err == nil {
+		t.Error("Expected error for undecodable image")
+	}
+	if matrix, err := ComputeMatrix(nil); err != nil || len(matrix) != 0 {
+		t.Errorf("Expected empty matrix, got %v, %v", matrix, err)
+	}
+}
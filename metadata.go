@@ -0,0 +1,227 @@
+package psnr
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Metadata kinds reported by WithMetadataDiff.
+const (
+	MetadataEXIF = "exif"
+	MetadataXMP  = "xmp"
+	MetadataICC  = "icc"
+)
+
+// metadataKinds lists the metadata kinds in report order.
+var metadataKinds = []string{MetadataEXIF, MetadataXMP, MetadataICC}
+
+// MetadataDiff reports how the EXIF, XMP and ICC metadata of the second
+// image differs from the first, as requested with WithMetadataDiff. Each
+// list holds metadata kinds (MetadataEXIF, MetadataXMP, MetadataICC).
+type MetadataDiff struct {
+	// Dropped lists the kinds present only in the first image.
+	Dropped []string
+	// Added lists the kinds present only in the second image.
+	Added []string
+	// Changed lists the kinds present in both images with different
+	// contents.
+	Changed []string
+	// Kept lists the kinds present in both images with equal contents.
+	Kept []string
+}
+
+// diffMetadata compares the metadata of two encoded images. Images without
+// encoded data, such as those passed to CompareImages, have no metadata.
+func diffMetadata(data1, data2 []byte) *MetadataDiff {
+	m1, m2 := readMetadata(data1), readMetadata(data2)
+	diff := &MetadataDiff{}
+	for _, kind := range metadataKinds {
+		v1, ok1 := m1[kind]
+		v2, ok2 := m2[kind]
+		switch {
+		case ok1 && !ok2:
+			diff.Dropped = append(diff.Dropped, kind)
+		case !ok1 && ok2:
+			diff.Added = append(diff.Added, kind)
+		case ok1 && !bytes.Equal(v1, v2):
+			diff.Changed = append(diff.Changed, kind)
+		case ok1:
+			diff.Kept = append(diff.Kept, kind)
+		}
+	}
+	return diff
+}
+
+// readMetadata extracts the metadata blocks of a JPEG, PNG or WebP file by
+// kind. Unknown formats have none.
+func readMetadata(data []byte) map[string][]byte {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xff, 0xd8}):
+		return readJPEGMetadata(data)
+	case bytes.HasPrefix(data, pngSignature):
+		return readPNGMetadata(data)
+	case len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return readWebPMetadata(data)
+	}
+	return nil
+}
+
+// JPEG APP segment signatures.
+var (
+	jpegEXIFSignature        = []byte("Exif\x00\x00")
+	jpegXMPSignature         = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	jpegExtendedXMPSignature = []byte("http://ns.adobe.com/xmp/extension/\x00")
+	jpegICCSignature         = []byte("ICC_PROFILE\x00")
+)
+
+// readJPEGMetadata reads the APP1 EXIF and XMP segments and the APP2 ICC
+// profile, which may span several segments, up to the first scan.
+func readJPEGMetadata(data []byte) map[string][]byte {
+	metadata := make(map[string][]byte)
+	jpegSegments(data, func(marker byte, payload []byte) bool {
+		switch {
+		case marker == 0xe1 && bytes.HasPrefix(payload, jpegEXIFSignature):
+			metadata[MetadataEXIF] = payload[len(jpegEXIFSignature):]
+		case marker == 0xe1 && bytes.HasPrefix(payload, jpegXMPSignature):
+			metadata[MetadataXMP] = append(metadata[MetadataXMP], payload[len(jpegXMPSignature):]...)
+		case marker == 0xe1 && bytes.HasPrefix(payload, jpegExtendedXMPSignature):
+			metadata[MetadataXMP] = append(metadata[MetadataXMP], payload[len(jpegExtendedXMPSignature):]...)
+		case marker == 0xe2 && bytes.HasPrefix(payload, jpegICCSignature) && len(payload) >= len(jpegICCSignature)+2:
+			// Segments carry their sequence number and count before the data
+			metadata[MetadataICC] = append(metadata[MetadataICC], payload[len(jpegICCSignature)+2:]...)
+		}
+		return true
+	})
+	return metadata
+}
+
+// jpegSegments calls fn with the marker and payload of every JPEG segment
+// up to the first scan, stopping early when fn returns false or the stream
+// is malformed.
+func jpegSegments(data []byte, fn func(marker byte, payload []byte) bool) {
+	for offset := 2; offset+4 <= len(data) && data[offset] == 0xff; {
+		marker := data[offset+1]
+		if marker == 0xd8 || marker >= 0xd0 && marker <= 0xd7 || marker == 0xff {
+			offset++
+			continue
+		}
+		if marker == 0xda || marker == 0xd9 {
+			return
+		}
+		length := int(binary.BigEndian.Uint16(data[offset+2 : offset+4]))
+		if length < 2 || offset+2+length > len(data) {
+			return
+		}
+		payload := data[offset+4 : offset+2+length]
+		offset += 2 + length
+		if !fn(marker, payload) {
+			return
+		}
+	}
+}
+
+// pngXMPKeyword is the iTXt keyword of XMP packets.
+const pngXMPKeyword = "XML:com.adobe.xmp"
+
+// readPNGMetadata reads the eXIf chunk, the XMP iTXt chunk and the iCCP
+// profile. Compressed XMP text and the profile are decompressed so that
+// recompression does not count as a change.
+func readPNGMetadata(data []byte) map[string][]byte {
+	metadata := make(map[string][]byte)
+	chunks, _ := readPNGChunks(data)
+	for _, chunk := range chunks {
+		switch chunk.typ {
+		case "eXIf":
+			metadata[MetadataEXIF] = chunk.data
+		case "iTXt":
+			if keyword, text, ok := readPNGiTXt(chunk.data); ok && keyword == pngXMPKeyword {
+				metadata[MetadataXMP] = text
+			}
+		case "iCCP":
+			// The profile name is followed by the compression method
+			if _, rest, ok := bytes.Cut(chunk.data, []byte{0}); ok && len(rest) > 0 {
+				profile, err := inflate(rest[1:])
+				if err != nil {
+					profile = rest[1:]
+				}
+				metadata[MetadataICC] = profile
+			}
+		}
+	}
+	return metadata
+}
+
+// readPNGiTXt returns the keyword and text of an iTXt chunk, inflating
+// compressed text. The keyword is followed by the compression flag and
+// method, the language tag and the translated keyword. Text that cannot be
+// inflated is returned as stored.
+func readPNGiTXt(data []byte) (string, []byte, bool) {
+	keyword, rest, ok := bytes.Cut(data, []byte{0})
+	if !ok || len(rest) < 2 {
+		return "", nil, false
+	}
+	compressed := rest[0] == 1
+	_, rest, ok = bytes.Cut(rest[2:], []byte{0})
+	if !ok {
+		return "", nil, false
+	}
+	_, text, ok := bytes.Cut(rest, []byte{0})
+	if !ok {
+		return "", nil, false
+	}
+	if compressed {
+		if inflated, err := inflate(text); err == nil {
+			text = inflated
+		}
+	}
+	return string(keyword), text, true
+}
+
+// maxInflatedMetadata caps the decompressed size of a metadata chunk so a
+// small compressed chunk cannot expand without bound.
+const maxInflatedMetadata = 16 << 20
+
+// inflate decompresses a zlib stream, failing when the output exceeds
+// maxInflatedMetadata.
+func inflate(data []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	out, err := io.ReadAll(io.LimitReader(r, maxInflatedMetadata+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > maxInflatedMetadata {
+		return nil, fmt.Errorf("inflated metadata exceeds %d bytes", maxInflatedMetadata)
+	}
+	return out, nil
+}
+
+// readWebPMetadata reads the EXIF, XMP and ICCP chunks of a WebP file.
+func readWebPMetadata(data []byte) map[string][]byte {
+	metadata := make(map[string][]byte)
+	for offset := 12; offset+8 <= len(data); {
+		fourCC := string(data[offset : offset+4])
+		size := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+		if size < 0 || offset+8+size > len(data) {
+			break
+		}
+		payload := data[offset+8 : offset+8+size]
+		// Chunks are padded to an even size
+		offset += 8 + size + size%2
+
+		switch fourCC {
+		case "EXIF":
+			metadata[MetadataEXIF] = payload
+		case "XMP ":
+			metadata[MetadataXMP] = payload
+		case "ICCP":
+			metadata[MetadataICC] = payload
+		}
+	}
+	return metadata
+}
@@ -0,0 +1,163 @@
+package psnr
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"image"
+	"math"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// insertJPEGSegment inserts an APPn segment right after the SOI marker.
+func insertJPEGSegment(data []byte, marker byte, payload []byte) []byte {
+	segment := []byte{0xff, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	out := append([]byte{}, data[:2]...)
+	out = append(out, segment...)
+	out = append(out, payload...)
+	return append(out, data[2:]...)
+}
+
+// insertPNGChunk inserts a chunk right after IHDR.
+func insertPNGChunk(data []byte, typ string, payload []byte) []byte {
+	split := len(pngSignature) + 25
+	var buf bytes.Buffer
+	buf.Write(data[:split])
+	writePNGChunk(&buf, typ, payload)
+	buf.Write(data[split:])
+	return buf.Bytes()
+}
+
+func TestWithMetadataDiffJPEG(t *testing.T) {
+	data, err := os.ReadFile("testdata/test_original.jpg")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+	exif := append([]byte("Exif\x00\x00"), "MM\x00\x2a\x00\x00\x00\x08"...)
+	icc := append([]byte("ICC_PROFILE\x00\x01\x01"), "profile"...)
+	xmp := append([]byte("http://ns.adobe.com/xap/1.0/\x00"), "<x:xmpmeta/>"...)
+	original := insertJPEGSegment(insertJPEGSegment(insertJPEGSegment(data, 0xe1, exif), 0xe2, icc), 0xe1, xmp)
+
+	changedXMP := append([]byte("http://ns.adobe.com/xap/1.0/\x00"), "<x:xmpmeta a='1'/>"...)
+	optimized := insertJPEGSegment(insertJPEGSegment(data, 0xe2, icc), 0xe1, changedXMP)
+
+	result, err := ComputeDetailed(original, optimized, WithMetadataDiff())
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	want := &MetadataDiff{Dropped: []string{MetadataEXIF}, Changed: []string{MetadataXMP}, Kept: []string{MetadataICC}}
+	if !reflect.DeepEqual(result.Metadata, want) {
+		t.Errorf("Expected %+v, got %+v", want, result.Metadata)
+	}
+	if !math.IsInf(result.PSNR, 1) {
+		t.Errorf("Expected metadata not to affect the pixels, got %f", result.PSNR)
+	}
+
+	same, err := ComputeDetailed(original, original, WithMetadataDiff())
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if want := []string{MetadataEXIF, MetadataXMP, MetadataICC}; !reflect.DeepEqual(same.Metadata.Kept, want) {
+		t.Errorf("Expected every kind kept for identical files, got %+v", same.Metadata)
+	}
+
+	plain, err := ComputeDetailed(original, optimized)
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if plain.Metadata != nil {
+		t.Error("Expected no metadata report by default")
+	}
+}
+
+func TestWithMetadataDiffPNG(t *testing.T) {
+	data := encodePNG(t, image.NewGray(image.Rect(0, 0, 4, 4)))
+	compress := func(level int) []byte {
+		var buf bytes.Buffer
+		zw, _ := zlib.NewWriterLevel(&buf, level)
+		zw.Write(bytes.Repeat([]byte("profile"), 20))
+		zw.Close()
+		return buf.Bytes()
+	}
+	iccp := func(level int) []byte {
+		return append([]byte("sRGB\x00\x00"), compress(level)...)
+	}
+	first := insertPNGChunk(data, "iCCP", iccp(zlib.BestCompression))
+	// Recompressing the profile keeps it
+	second := insertPNGChunk(insertPNGChunk(data, "iCCP", iccp(zlib.NoCompression)), "eXIf", []byte("MM\x00\x2a"))
+
+	result, err := ComputeDetailed(first, second, WithMetadataDiff())
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	want := &MetadataDiff{Added: []string{MetadataEXIF}, Kept: []string{MetadataICC}}
+	if !reflect.DeepEqual(result.Metadata, want) {
+		t.Errorf("Expected %+v, got %+v", want, result.Metadata)
+	}
+}
+
+func TestReadWebPMetadata(t *testing.T) {
+	var body bytes.Buffer
+	body.WriteString("WEBP")
+	for _, chunk := range []struct{ fourCC, payload string }{
+		{"VP8X", "0123456789"},
+		{"ICCP", "odd"},
+		{"XMP ", "<x/>"},
+	} {
+		body.WriteString(chunk.fourCC)
+		binary.Write(&body, binary.LittleEndian, uint32(len(chunk.payload)))
+		body.WriteString(chunk.payload)
+		if len(chunk.payload)%2 == 1 {
+			body.WriteByte(0)
+		}
+	}
+	data := append([]byte("RIFF\x00\x00\x00\x00"), body.Bytes()...)
+	binary.LittleEndian.PutUint32(data[4:8], uint32(body.Len()))
+
+	metadata := readMetadata(data)
+	if string(metadata[MetadataICC]) != "odd" || string(metadata[MetadataXMP]) != "<x/>" || metadata[MetadataEXIF] != nil {
+		t.Errorf("Unexpected metadata: %q", metadata)
+	}
+}
+
+func TestReadPNGiTXt(t *testing.T) {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write([]byte("<x:xmpmeta/>"))
+	zw.Close()
+
+	data := encodePNG(t, image.NewGray(image.Rect(0, 0, 4, 4)))
+	plain := insertPNGChunk(data, "iTXt", []byte(pngXMPKeyword+"\x00\x00\x00\x00\x00<x:xmpmeta/>"))
+	// A compressed packet with a language tag and translated keyword
+	packed := insertPNGChunk(data, "iTXt", append([]byte(pngXMPKeyword+"\x00\x01\x00en\x00XMP\x00"), compressed.Bytes()...))
+
+	for _, file := range [][]byte{plain, packed} {
+		if got := readMetadata(file)[MetadataXMP]; string(got) != "<x:xmpmeta/>" {
+			t.Errorf("Expected the XMP text, got %q", got)
+		}
+	}
+	if _, _, ok := readPNGiTXt([]byte(pngXMPKeyword + "\x00\x00\x00en")); ok {
+		t.Error("Expected a truncated iTXt chunk to be rejected")
+	}
+}
+
+func TestInflateLimit(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(make([]byte, maxInflatedMetadata+1))
+	zw.Close()
+	if _, err := inflate(buf.Bytes()); err == nil {
+		t.Error("Expected an error for output over the limit")
+	}
+
+	buf.Reset()
+	zw = zlib.NewWriter(&buf)
+	zw.Write(make([]byte, maxInflatedMetadata))
+	zw.Close()
+	if out, err := inflate(buf.Bytes()); err != nil || len(out) != maxInflatedMetadata {
+		t.Errorf("Expected %d bytes at the limit, got %d, %v", maxInflatedMetadata, len(out), err)
+	}
+}
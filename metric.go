@@ -0,0 +1,163 @@
+package psnr
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+	"sync"
+)
+
+// Metric is a custom quality measure computed in the same pixel pass as
+// PSNR. A new instance is created for every computation, so implementations
+// need not be safe for concurrent use.
+type Metric interface {
+	// Name identifies the metric in Result.Metrics.
+	Name() string
+	// Accumulate is called once per compared pixel with the 8-bit samples
+	// of both images that the AlphaMode compares: premultiplied, or
+	// straight under AlphaStraight and for two *image.NRGBA under
+	// AlphaAuto. Straight samples are passed in a color.RGBA as they are,
+	// so they must not be un-premultiplied.
+	Accumulate(p1, p2 color.RGBA)
+	// Result returns the metric value after all pixels were accumulated.
+	Result() float64
+}
+
+// ImageMetric is a quality measure computed from whole images rather than
+// pixel by pixel, such as a learned perceptual metric. One instance serves
+// every computation, so implementations must be safe for concurrent use.
+type ImageMetric interface {
+	// Name identifies the metric in Result.Metrics.
+	Name() string
+	// Compute measures two images of the same size.
+	Compute(ctx context.Context, img1, img2 image.Image) (float64, error)
+}
+
+var (
+	metricsMu    sync.RWMutex
+	metrics      = make(map[string]func() Metric)
+	imageMetrics = make(map[string]ImageMetric)
+)
+
+// RegisterMetric makes a metric available to WithMetrics under the name
+// reported by the instances newMetric creates. It is typically called from
+// an init function, like image.RegisterFormat. Registering a name twice
+// replaces the earlier constructor.
+func RegisterMetric(newMetric func() Metric) {
+	name := newMetric().Name()
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	delete(imageMetrics, name)
+	metrics[name] = newMetric
+}
+
+// RegisterImageMetric makes a whole-image metric available to WithMetrics
+// under its name, replacing any metric of the same name. Unlike
+// RegisterMetric it takes an instance, as such metrics usually hold
+// resources like a loaded model.
+func RegisterImageMetric(m ImageMetric) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	delete(metrics, m.Name())
+	imageMetrics[m.Name()] = m
+}
+
+// RegisteredMetrics returns the sorted names of all registered metrics.
+func RegisteredMetrics() []string {
+	metricsMu.RLock()
+	defer metricsMu.RUnlock()
+	names := make([]string, 0, len(metrics)+len(imageMetrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	for name := range imageMetrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newMetrics instantiates the named pixel metrics and looks up the named
+// whole-image metrics.
+func newMetrics(names []string) ([]Metric, []ImageMetric, error) {
+	metricsMu.RLock()
+	defer metricsMu.RUnlock()
+	instances := make([]Metric, 0, len(names))
+	var whole []ImageMetric
+	for _, name := range names {
+		if m, ok := imageMetrics[name]; ok {
+			whole = append(whole, m)
+			continue
+		}
+		newMetric, ok := metrics[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown metric: %s", name)
+		}
+		instances = append(instances, newMetric())
+	}
+	return instances, whole, nil
+}
+
+// pixelVisitor receives every compared pixel of a fused pass. x and y are
+// in the coordinates of the first image; p1 and p2 are the RGBA samples.
+type pixelVisitor interface {
+	visit(x, y int, p1, p2 []uint8)
+}
+
+// metricVisitor feeds a public Metric from the fused pass.
+type metricVisitor struct {
+	metric Metric
+}
+
+func (v metricVisitor) visit(_, _ int, p1, p2 []uint8) {
+	v.metric.Accumulate(
+		color.RGBA{p1[0], p1[1], p1[2], p1[3]},
+		color.RGBA{p2[0], p2[1], p2[2], p2[3]},
+	)
+}
+
+// fusedPass accumulates squared differences over region r of img1 and the
+// same-sized region of img2 at origin2 while handing every pixel to the
+// visitors. Like sumSquaredDiff it works in bands, reporting progress and
+// honoring cancellation.
+func fusedPass(ctx context.Context, img1, img2 *image.RGBA, r image.Rectangle, origin2 image.Point, hasAlpha bool, visitors []pixelVisitor, progress func(done, total int)) (uint64, error) {
+	var sumSquaredDiff uint64
+	height := r.Dy()
+
+	for band := 0; band < height; band += bandHeight {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		end := min(band+bandHeight, height)
+
+		for y := band; y < end; y++ {
+			i := img1.PixOffset(r.Min.X, r.Min.Y+y)
+			j := img2.PixOffset(origin2.X, origin2.Y+y)
+			for x := 0; x < r.Dx(); x++ {
+				p1 := img1.Pix[i+x*4 : i+x*4+4]
+				p2 := img2.Pix[j+x*4 : j+x*4+4]
+
+				diffR := int32(p1[0]) - int32(p2[0])
+				diffG := int32(p1[1]) - int32(p2[1])
+				diffB := int32(p1[2]) - int32(p2[2])
+				sumSquaredDiff += uint64(diffR*diffR) + uint64(diffG*diffG) + uint64(diffB*diffB)
+				if hasAlpha {
+					diffA := int32(p1[3]) - int32(p2[3])
+					sumSquaredDiff += uint64(diffA * diffA)
+				}
+
+				for _, v := range visitors {
+					v.visit(r.Min.X+x, r.Min.Y+y, p1, p2)
+				}
+			}
+		}
+
+		if progress != nil {
+			progress(end, height)
+		}
+	}
+
+	return sumSquaredDiff, nil
+}
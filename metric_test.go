@@ -0,0 +1,142 @@
+package psnr
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"os"
+	"testing"
+)
+
+// maxAbsDiff is a test metric reporting the largest absolute sample
+// difference.
+type maxAbsDiff struct {
+	max int
+}
+
+func (m *maxAbsDiff) Name() string { return "test_max_abs_diff" }
+
+func (m *maxAbsDiff) Accumulate(p1, p2 color.RGBA) {
+	for _, d := range []int{
+		int(p1.R) - int(p2.R),
+		int(p1.G) - int(p2.G),
+		int(p1.B) - int(p2.B),
+	} {
+		m.max = max(m.max, abs(d))
+	}
+}
+
+func (m *maxAbsDiff) Result() float64 { return float64(m.max) }
+
+// pixelCount is a test metric counting the pixels it saw.
+type pixelCount struct {
+	n int
+}
+
+func (m *pixelCount) Name() string               { return "test_pixel_count" }
+func (m *pixelCount) Accumulate(_, _ color.RGBA) { m.n++ }
+func (m *pixelCount) Result() float64            { return float64(m.n) }
+
+// imageArea is a test whole-image metric reporting the area of the first
+// image, or failing when it is empty.
+type imageArea struct{}
+
+func (imageArea) Name() string { return "test_image_area" }
+
+func (imageArea) Compute(ctx context.Context, img1, img2 image.Image) (float64, error) {
+	if img1.Bounds().Empty() || img2.Bounds() != img1.Bounds() {
+		return 0, errors.New("unexpected images")
+	}
+	return float64(img1.Bounds().Dx() * img1.Bounds().Dy()), nil
+}
+
+func init() {
+	RegisterMetric(func() Metric { return &maxAbsDiff{} })
+	RegisterMetric(func() Metric { return &pixelCount{} })
+	RegisterImageMetric(imageArea{})
+}
+
+func TestWithMetrics(t *testing.T) {
+	data1, err := os.ReadFile("testdata/test_original.jpg")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+	data2, err := os.ReadFile("testdata/quality_50.jpg")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+
+	plain, err := ComputeDetailed(data1, data2)
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	result, err := ComputeDetailed(data1, data2, WithMetrics("test_max_abs_diff", "test_pixel_count"))
+	if err != nil {
+		t.Fatalf("Error computing PSNR with metrics: %v", err)
+	}
+
+	t.Logf("metrics: %v", result.Metrics)
+
+	if result.PSNR != plain.PSNR {
+		t.Errorf("Fused pass changed PSNR: %.6f vs %.6f", result.PSNR, plain.PSNR)
+	}
+	if result.Metrics["test_max_abs_diff"] <= 0 || result.Metrics["test_max_abs_diff"] > 255 {
+		t.Errorf("Unexpected max abs diff: %v", result.Metrics["test_max_abs_diff"])
+	}
+	d1, _, err := decodePair(data1, data2, &options{})
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if want := float64(d1.img.Bounds().Dx() * d1.img.Bounds().Dy()); result.Metrics["test_pixel_count"] != want {
+		t.Errorf("Expected %v pixels, got %v", want, result.Metrics["test_pixel_count"])
+	}
+}
+
+func TestWithMetricsAligned(t *testing.T) {
+	data1 := encodePNG(t, shiftedPattern(48, 48, 0, 0))
+	data2 := encodePNG(t, shiftedPattern(48, 48, 1, 0))
+
+	result, err := ComputeDetailed(data1, data2, WithAlignment(2), WithMetrics("test_max_abs_diff", "test_pixel_count"))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if result.Metrics["test_max_abs_diff"] != 0 {
+		t.Errorf("Expected no difference on the aligned overlap, got %v", result.Metrics["test_max_abs_diff"])
+	}
+	if result.Metrics["test_pixel_count"] != 47*48 {
+		t.Errorf("Expected metrics over the %d-pixel overlap, got %v", 47*48, result.Metrics["test_pixel_count"])
+	}
+}
+
+func TestWithMetricsUnknown(t *testing.T) {
+	data := encodePNG(t, shiftedPattern(8, 8, 0, 0))
+	if _, err := ComputeDetailed(data, data, WithMetrics("no_such_metric")); err == nil {
+		t.Error("Expected error for unknown metric")
+	}
+}
+
+func TestRegisteredMetrics(t *testing.T) {
+	names := RegisteredMetrics()
+	found := 0
+	for _, name := range names {
+		if name == "test_max_abs_diff" || name == "test_pixel_count" || name == "test_image_area" {
+			found++
+		}
+	}
+	if found != 3 {
+		t.Errorf("Expected test metrics to be registered, got %v", names)
+	}
+}
+
+func TestWithImageMetrics(t *testing.T) {
+	data1 := encodePNG(t, shiftedPattern(12, 10, 0, 0))
+	data2 := encodePNG(t, shiftedPattern(12, 10, 1, 0))
+	result, err := ComputeDetailed(data1, data2, WithMetrics("test_image_area", "test_pixel_count"))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if result.Metrics["test_image_area"] != 120 || result.Metrics["test_pixel_count"] != 120 {
+		t.Errorf("Expected both kinds of metrics, got %v", result.Metrics)
+	}
+}
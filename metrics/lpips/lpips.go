@@ -0,0 +1,124 @@
+// Package lpips computes the LPIPS learned perceptual distance with an ONNX
+// model and ONNX Runtime, so deep-metric evaluation can run alongside PSNR:
+//
+//	model, err := lpips.Open("lpips_alex.onnx", lpips.Options{})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer model.Close()
+//	psnr.RegisterImageMetric(model)
+//
+//	result, err := psnr.ComputeDetailed(data1, data2, psnr.WithMetrics(lpips.Name))
+//	fmt.Println(result.PSNR, result.Metrics[lpips.Name])
+//
+// The runtime is linked through cgo and requires the onnxruntime build tag
+// and the ONNX Runtime shared library and headers; without them Open returns
+// ErrUnavailable. The model must take two NCHW float32 RGB tensors scaled to
+// [-1, 1] with dynamic spatial dimensions and return the distance as its
+// first output value, as the usual exports of the reference implementation
+// do. Images are fed at their own resolution; resize them with
+// psnr.WithPreprocess for models with a fixed input size.
+package lpips
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+)
+
+// Name is the metric name of a Model in psnr.Result.Metrics.
+const Name = "lpips"
+
+// ErrUnavailable is returned by Open when the package was built without
+// ONNX Runtime support.
+var ErrUnavailable = errors.New("lpips: built without ONNX Runtime support (build with -tags onnxruntime)")
+
+// Options configures the model's tensor names.
+type Options struct {
+	// Input1 and Input2 name the two image inputs; "in0" and "in1" when
+	// empty.
+	Input1, Input2 string
+	// Output names the distance output; "out" when empty.
+	Output string
+}
+
+// withDefaults fills in the default tensor names.
+func (o Options) withDefaults() Options {
+	if o.Input1 == "" {
+		o.Input1 = "in0"
+	}
+	if o.Input2 == "" {
+		o.Input2 = "in1"
+	}
+	if o.Output == "" {
+		o.Output = "out"
+	}
+	return o
+}
+
+// Model is a loaded LPIPS model. It implements psnr.ImageMetric and is safe
+// for concurrent use.
+type Model struct {
+	session *session
+	options Options
+}
+
+// Open loads the ONNX model at path.
+func Open(path string, opts Options) (*Model, error) {
+	s, err := openSession(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Model{session: s, options: opts.withDefaults()}, nil
+}
+
+// Name returns Name.
+func (m *Model) Name() string {
+	return Name
+}
+
+// Compute returns the LPIPS distance between two images of the same size:
+// 0 for identical images, growing with perceived difference. Translucent
+// pixels are composited over black.
+func (m *Model) Compute(ctx context.Context, img1, img2 image.Image) (float64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	size := img1.Bounds().Size()
+	if img2.Bounds().Size() != size {
+		return 0, fmt.Errorf("lpips: images have different dimensions: %v vs %v", size, img2.Bounds().Size())
+	}
+	if size.X == 0 || size.Y == 0 {
+		return 0, errors.New("lpips: empty image")
+	}
+	distance, err := m.session.run(tensor(img1), tensor(img2), size.Y, size.X, m.options)
+	if err != nil {
+		return 0, err
+	}
+	return float64(distance), nil
+}
+
+// Close releases the model.
+func (m *Model) Close() error {
+	return m.session.close()
+}
+
+// tensor converts img to a 1x3xHxW tensor of premultiplied RGB samples
+// scaled from 0-255 to [-1, 1].
+func tensor(img image.Image) []float32 {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	plane := width * height
+	t := make([]float32, 3*plane)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			i := y*width + x
+			t[i] = float32(r>>8)/127.5 - 1
+			t[plane+i] = float32(g>>8)/127.5 - 1
+			t[2*plane+i] = float32(b>>8)/127.5 - 1
+		}
+	}
+	return t
+}
@@ -0,0 +1,72 @@
+package lpips
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"os"
+	"testing"
+
+	"github.com/ideamans/go-psnr"
+)
+
+func TestTensor(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(2, 3, 4, 4))
+	img.SetNRGBA(2, 3, color.NRGBA{R: 255, G: 0, B: 51, A: 255})
+	img.SetNRGBA(3, 3, color.NRGBA{R: 255, G: 255, B: 255, A: 0})
+
+	got := tensor(img)
+	// Planes of two pixels each; the transparent pixel is black
+	want := []float32{1, -1, -1, -1, 51/127.5 - 1, -1}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d values, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Value %d: got %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+// TestModel runs a real model named by LPIPS_MODEL in builds with ONNX
+// Runtime support.
+func TestModel(t *testing.T) {
+	path := os.Getenv("LPIPS_MODEL")
+	if path == "" {
+		if _, err := Open("missing.onnx", Options{}); errors.Is(err, ErrUnavailable) {
+			t.Skip("built without ONNX Runtime support")
+		}
+		t.Skip("LPIPS_MODEL is not set")
+	}
+	model, err := Open(path, Options{})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer model.Close()
+	psnr.RegisterImageMetric(model)
+
+	data1, err := os.ReadFile("../../testdata/test_original.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data2, err := os.ReadFile("../../testdata/test_quality_85.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	same, err := psnr.ComputeDetailed(data1, data1, psnr.WithMetrics(Name))
+	if err != nil {
+		t.Fatalf("ComputeDetailed failed: %v", err)
+	}
+	different, err := psnr.ComputeDetailed(data1, data2, psnr.WithMetrics(Name))
+	if err != nil {
+		t.Fatalf("ComputeDetailed failed: %v", err)
+	}
+	if same.Metrics[Name] > 1e-4 || different.Metrics[Name] <= same.Metrics[Name] {
+		t.Errorf("Expected a larger distance for different images, got %v and %v", same.Metrics[Name], different.Metrics[Name])
+	}
+
+	if _, err := model.Compute(context.Background(), image.NewGray(image.Rect(0, 0, 2, 2)), image.NewGray(image.Rect(0, 0, 3, 2))); err == nil {
+		t.Error("Expected error for mismatched sizes")
+	}
+}
@@ -0,0 +1,172 @@
+//go:build onnxruntime && cgo
+
+package lpips
+
+/*
+#cgo LDFLAGS: -lonnxruntime
+#include <stdlib.h>
+#include <string.h>
+#include <onnxruntime_c_api.h>
+
+static const OrtApi *psnr_ort_api(void) {
+	return OrtGetApiBase()->GetApi(ORT_API_VERSION);
+}
+
+// psnr_ort_error releases status and returns a malloc'ed copy of its
+// message, or NULL for success.
+static char *psnr_ort_error(const OrtApi *api, OrtStatus *status) {
+	if (status == NULL) {
+		return NULL;
+	}
+	char *message = strdup(api->GetErrorMessage(status));
+	api->ReleaseStatus(status);
+	return message;
+}
+
+static char *psnr_lpips_open(const char *path, OrtEnv **env, OrtSession **session) {
+	const OrtApi *api = psnr_ort_api();
+	if (api == NULL) {
+		return strdup("unsupported ONNX Runtime API version");
+	}
+	OrtSessionOptions *options = NULL;
+	char *err = psnr_ort_error(api, api->CreateEnv(ORT_LOGGING_LEVEL_WARNING, "go-psnr", env));
+	if (err == NULL) {
+		err = psnr_ort_error(api, api->CreateSessionOptions(&options));
+	}
+	if (err == NULL) {
+		err = psnr_ort_error(api, api->CreateSession(*env, path, options, session));
+	}
+	if (options != NULL) {
+		api->ReleaseSessionOptions(options);
+	}
+	if (err != NULL && *env != NULL) {
+		api->ReleaseEnv(*env);
+		*env = NULL;
+	}
+	return err;
+}
+
+// psnr_lpips_run feeds two 1x3xHxW tensors to the session and stores the
+// first value of the output in *distance.
+static char *psnr_lpips_run(OrtSession *session, float *in0, float *in1, int64_t height, int64_t width,
+		const char *name0, const char *name1, const char *output_name, float *distance) {
+	const OrtApi *api = psnr_ort_api();
+	OrtMemoryInfo *memory = NULL;
+	OrtValue *inputs[2] = {NULL, NULL};
+	OrtValue *output = NULL;
+	int64_t shape[4] = {1, 3, height, width};
+	size_t bytes = (size_t)(3 * height * width) * sizeof(float);
+	const char *input_names[2] = {name0, name1};
+	const char *output_names[1] = {output_name};
+
+	char *err = psnr_ort_error(api, api->CreateCpuMemoryInfo(OrtArenaAllocator, OrtMemTypeDefault, &memory));
+	if (err == NULL) {
+		err = psnr_ort_error(api, api->CreateTensorWithDataAsOrtValue(memory, in0, bytes, shape, 4,
+			ONNX_TENSOR_ELEMENT_DATA_TYPE_FLOAT, &inputs[0]));
+	}
+	if (err == NULL) {
+		err = psnr_ort_error(api, api->CreateTensorWithDataAsOrtValue(memory, in1, bytes, shape, 4,
+			ONNX_TENSOR_ELEMENT_DATA_TYPE_FLOAT, &inputs[1]));
+	}
+	if (err == NULL) {
+		err = psnr_ort_error(api, api->Run(session, NULL, input_names, (const OrtValue *const *)inputs, 2,
+			output_names, 1, &output));
+	}
+	if (err == NULL) {
+		float *data = NULL;
+		err = psnr_ort_error(api, api->GetTensorMutableData(output, (void **)&data));
+		if (err == NULL) {
+			*distance = data[0];
+		}
+	}
+
+	if (output != NULL) {
+		api->ReleaseValue(output);
+	}
+	for (int i = 0; i < 2; i++) {
+		if (inputs[i] != NULL) {
+			api->ReleaseValue(inputs[i]);
+		}
+	}
+	if (memory != NULL) {
+		api->ReleaseMemoryInfo(memory);
+	}
+	return err;
+}
+
+static void psnr_lpips_close(OrtEnv *env, OrtSession *session) {
+	const OrtApi *api = psnr_ort_api();
+	if (session != NULL) {
+		api->ReleaseSession(session);
+	}
+	if (env != NULL) {
+		api->ReleaseEnv(env);
+	}
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"sync"
+	"unsafe"
+)
+
+// session is an ONNX Runtime session. Runs are safe for concurrent use;
+// the mutex only guards closing.
+type session struct {
+	mu      sync.RWMutex
+	env     *C.OrtEnv
+	session *C.OrtSession
+}
+
+func openSession(path string) (*session, error) {
+	cPath := C.CString(path)
+	defer C.free(unsafe.Pointer(cPath))
+
+	s := &session{}
+	if err := ortError(C.psnr_lpips_open(cPath, &s.env, &s.session)); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *session) run(in0, in1 []float32, height, width int, opts Options) (float32, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.session == nil {
+		return 0, errors.New("lpips: model is closed")
+	}
+
+	name0, name1, output := C.CString(opts.Input1), C.CString(opts.Input2), C.CString(opts.Output)
+	defer C.free(unsafe.Pointer(name0))
+	defer C.free(unsafe.Pointer(name1))
+	defer C.free(unsafe.Pointer(output))
+
+	var distance C.float
+	err := ortError(C.psnr_lpips_run(s.session,
+		(*C.float)(unsafe.Pointer(&in0[0])), (*C.float)(unsafe.Pointer(&in1[0])),
+		C.int64_t(height), C.int64_t(width), name0, name1, output, &distance))
+	if err != nil {
+		return 0, err
+	}
+	return float32(distance), nil
+}
+
+func (s *session) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	C.psnr_lpips_close(s.env, s.session)
+	s.env, s.session = nil, nil
+	return nil
+}
+
+// ortError converts a message returned by the C helpers into an error and
+// frees it.
+func ortError(message *C.char) error {
+	if message == nil {
+		return nil
+	}
+	defer C.free(unsafe.Pointer(message))
+	return errors.New("lpips: " + C.GoString(message))
+}
@@ -0,0 +1,19 @@
+//go:build !(onnxruntime && cgo)
+
+package lpips
+
+// session is the ONNX Runtime session of a model, which this build cannot
+// create.
+type session struct{}
+
+func openSession(string) (*session, error) {
+	return nil, ErrUnavailable
+}
+
+func (*session) run(_, _ []float32, _, _ int, _ Options) (float32, error) {
+	return 0, ErrUnavailable
+}
+
+func (*session) close() error {
+	return nil
+}
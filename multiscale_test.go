@@ -0,0 +1,83 @@
+package psnr
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"testing"
+)
+
+func TestComputeMultiScale(t *testing.T) {
+	data1, err := os.ReadFile("testdata/test_image.png")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+	data2, err := os.ReadFile("testdata/test_image_q75.jpg")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+
+	results, err := ComputeMultiScale(data1, data2)
+	if err != nil {
+		t.Fatalf("Error computing multi-scale PSNR: %v", err)
+	}
+	if len(results) != len(multiScaleFactors) {
+		t.Fatalf("Expected %d levels, got %d", len(multiScaleFactors), len(results))
+	}
+
+	full, err := Compute(data1, data2)
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+
+	for i, r := range results {
+		t.Logf("1/%d (%dx%d): PSNR = %.2f dB", r.Factor, r.Width, r.Height, r.PSNR)
+		if r.Factor != multiScaleFactors[i] {
+			t.Errorf("Level %d: expected factor %d, got %d", i, multiScaleFactors[i], r.Factor)
+		}
+		// Box filtering averages out compression noise, so PSNR should not drop
+		if i > 0 && r.PSNR < results[i-1].PSNR {
+			t.Errorf("PSNR decreased from 1/%d to 1/%d", results[i-1].Factor, r.Factor)
+		}
+	}
+
+	if results[0].PSNR != full {
+		t.Errorf("Full-resolution level %.6f does not match Compute %.6f", results[0].PSNR, full)
+	}
+}
+
+func TestComputeMultiScaleStraight(t *testing.T) {
+	img1, img2 := translucentPair(40, 24)
+	data1, data2 := encodePNG(t, img1), encodePNG(t, img2)
+	results, err := ComputeMultiScale(data1, data2)
+	if err != nil {
+		t.Fatalf("Error computing multi-scale PSNR: %v", err)
+	}
+	full, err := Compute(data1, data2)
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if results[0].PSNR != full {
+		t.Errorf("Full-resolution level %.6f does not match Compute %.6f", results[0].PSNR, full)
+	}
+}
+
+func TestComputeMultiScaleSmallImage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 5, 5))
+	for i := range img.Pix {
+		img.Pix[i] = 200
+	}
+	other := image.NewRGBA(img.Rect)
+	copy(other.Pix, img.Pix)
+	other.SetRGBA(0, 0, color.RGBA{0, 0, 0, 255})
+
+	results, err := ComputeMultiScale(encodePNG(t, img), encodePNG(t, other))
+	if err != nil {
+		t.Fatalf("Error computing multi-scale PSNR: %v", err)
+	}
+
+	// 5x5 supports 1/1, 1/2 and 1/4 only
+	if len(results) != 3 {
+		t.Errorf("Expected 3 levels for a 5x5 image, got %d", len(results))
+	}
+}
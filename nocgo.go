@@ -0,0 +1,6 @@
+//go:build !cgo
+
+package psnr
+
+// cgoEnabled reports whether the package was built with cgo.
+const cgoEnabled = false
@@ -0,0 +1,80 @@
+package psnr
+
+import "image"
+
+// Normalization describes the per-channel linear fit removed by
+// WithNormalization and the PSNR measured after removing it.
+type Normalization struct {
+	// Gain and Bias map the second image onto the first per channel (R, G,
+	// B): first ≈ Gain*second + Bias.
+	Gain [3]float64
+	Bias [3]float64
+	// MSE and PSNR are measured after applying the fit to the second image.
+	MSE  float64
+	PSNR float64
+}
+
+// fitNormalization fits a least-squares gain and bias per color channel that
+// maps region r of img2 (top-left at origin2) onto region r of img1, and
+// measures the error that remains. Alpha, when compared, is left untouched.
+func fitNormalization(img1, img2 *image.RGBA, r image.Rectangle, origin2 image.Point, hasAlpha bool) *Normalization {
+	var sumX, sumY, sumXX, sumXY [3]uint64
+	width := r.Dx()
+
+	for y := 0; y < r.Dy(); y++ {
+		i := img1.PixOffset(r.Min.X, r.Min.Y+y)
+		j := img2.PixOffset(origin2.X, origin2.Y+y)
+		for k := 0; k < width*4; k += 4 {
+			for c := 0; c < 3; c++ {
+				vy := uint64(img1.Pix[i+k+c])
+				vx := uint64(img2.Pix[j+k+c])
+				sumX[c] += vx
+				sumY[c] += vy
+				sumXX[c] += vx * vx
+				sumXY[c] += vx * vy
+			}
+		}
+	}
+
+	n := float64(width * r.Dy())
+	norm := &Normalization{}
+	for c := 0; c < 3; c++ {
+		meanX := float64(sumX[c]) / n
+		meanY := float64(sumY[c]) / n
+		varX := float64(sumXX[c])/n - meanX*meanX
+		covXY := float64(sumXY[c])/n - meanX*meanY
+
+		// A flat channel carries no contrast to fit, so only remove the offset
+		gain := 1.0
+		if varX > 1e-9 {
+			gain = covXY / varX
+		}
+		norm.Gain[c] = gain
+		norm.Bias[c] = meanY - gain*meanX
+	}
+
+	var sumSquaredDiff float64
+	var alphaSquaredDiff uint64
+	for y := 0; y < r.Dy(); y++ {
+		i := img1.PixOffset(r.Min.X, r.Min.Y+y)
+		j := img2.PixOffset(origin2.X, origin2.Y+y)
+		for k := 0; k < width*4; k += 4 {
+			for c := 0; c < 3; c++ {
+				diff := float64(img1.Pix[i+k+c]) - (norm.Gain[c]*float64(img2.Pix[j+k+c]) + norm.Bias[c])
+				sumSquaredDiff += diff * diff
+			}
+			if hasAlpha {
+				diffA := int32(img1.Pix[i+k+3]) - int32(img2.Pix[j+k+3])
+				alphaSquaredDiff += uint64(diffA * diffA)
+			}
+		}
+	}
+
+	channelCount := 3.0
+	if hasAlpha {
+		channelCount = 4
+	}
+	norm.MSE = (sumSquaredDiff + float64(alphaSquaredDiff)) / (n * channelCount)
+	norm.PSNR = psnrFromMSE(norm.MSE)
+	return norm
+}
@@ -0,0 +1,62 @@
+package psnr
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestComputeDetailedNormalization(t *testing.T) {
+	original := shiftedPattern(64, 64, 0, 0)
+	adjusted := image.NewRGBA(original.Rect)
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			c := original.RGBAAt(x, y)
+			adjusted.SetRGBA(x, y, color.RGBA{
+				R: uint8(math.Round(float64(c.R)*0.8 + 20)),
+				G: uint8(math.Round(float64(c.G)*0.8 + 20)),
+				B: uint8(math.Round(float64(c.B)*0.8 + 20)),
+				A: 255,
+			})
+		}
+	}
+
+	result, err := ComputeDetailed(encodePNG(t, original), encodePNG(t, adjusted), WithNormalization())
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if result.Normalized == nil {
+		t.Fatal("Expected normalization result")
+	}
+
+	t.Logf("raw PSNR = %.2f dB, normalized PSNR = %.2f dB, gain = %v, bias = %v",
+		result.PSNR, result.Normalized.PSNR, result.Normalized.Gain, result.Normalized.Bias)
+
+	if result.Normalized.PSNR < 45 {
+		t.Errorf("Expected normalized PSNR above 45 dB, got %.2f", result.Normalized.PSNR)
+	}
+	if result.PSNR > 30 {
+		t.Errorf("Expected raw PSNR below 30 dB, got %.2f", result.PSNR)
+	}
+	for c := 0; c < 3; c++ {
+		if math.Abs(result.Normalized.Gain[c]-1.25) > 0.01 {
+			t.Errorf("Channel %d: expected gain 1.25, got %.4f", c, result.Normalized.Gain[c])
+		}
+		if math.Abs(result.Normalized.Bias[c]+25) > 1 {
+			t.Errorf("Channel %d: expected bias -25, got %.4f", c, result.Normalized.Bias[c])
+		}
+	}
+}
+
+func TestComputeDetailedNormalizationIdentical(t *testing.T) {
+	data := encodePNG(t, shiftedPattern(32, 32, 0, 0))
+
+	result, err := ComputeDetailed(data, data, WithNormalization())
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if result.Normalized == nil || !math.IsInf(result.Normalized.PSNR, 1) {
+		t.Errorf("Expected Inf normalized PSNR for identical images, got %+v", result.Normalized)
+	}
+}
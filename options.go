@@ -0,0 +1,612 @@
+package psnr
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"log/slog"
+	"math"
+	"time"
+)
+
+// Option configures a detailed PSNR computation.
+type Option func(*options)
+
+// options holds the settings collected from Option values.
+type options struct {
+	maxShift      int
+	normalize     bool
+	edgeWeighting bool
+	saliency      image.Image
+	spherical     bool
+	logger        *slog.Logger
+	progress      func(done, total int)
+	metrics       []string
+	compat        Compatibility
+	decoderName   string
+	matrix        ColorMatrix
+	colorRange    ColorRange
+	peak          PeakMode
+	deterministic bool
+	limits        decodeLimits
+	timeout       time.Duration
+	tolerant      bool
+	hashFilter    *hashFilter
+	diff          bool
+	histogram     bool
+	components    bool
+	preprocess    func(image.Image) image.Image
+	blurSigma     float64
+	cropSearch    bool
+	background    color.Color
+	alphaMode     AlphaMode
+	ditherBox     int
+	colorSpace    ColorSpace
+	hdr           *HDROptions
+	toneMap       *ToneMapOptions
+	metadata      bool
+	antiAliasing  AntiAliasingMode
+	ignoreColor   *ignoreColor
+	regions       []Region
+	inputInfo     bool
+	evalLongEdge  int
+	orientation   bool
+	borderCrop    bool
+	frequency     float64
+	strict        bool
+	stats         bool
+
+	// decoder is resolved from decoderName by newOptions.
+	decoder *decoderBackend
+}
+
+// newOptions applies opts over the defaults and validates the result.
+func newOptions(opts []Option) (*options, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	decoder, err := lookupDecoder(o.decoderName)
+	if err != nil {
+		return nil, err
+	}
+	o.decoder = decoder
+	return o, nil
+}
+
+// validate reports the first invalid setting.
+func (o *options) validate() error {
+	if o.maxShift < 0 {
+		return fmt.Errorf("invalid alignment search range: %d", o.maxShift)
+	}
+	if _, _, err := newMetrics(o.metrics); err != nil {
+		return err
+	}
+	if o.compat < CompatibilityDefault || o.compat > CompatibilityOpenCV {
+		return fmt.Errorf("unknown compatibility mode %d", o.compat)
+	}
+	if o.matrix < MatrixBT601 || o.matrix > MatrixBT2020 {
+		return fmt.Errorf("unknown color matrix %d", o.matrix)
+	}
+	if o.colorRange != RangeFull && o.colorRange != RangeLimited {
+		return fmt.Errorf("unknown color range %d", o.colorRange)
+	}
+	if o.timeout < 0 {
+		return fmt.Errorf("invalid timeout: %v", o.timeout)
+	}
+	if err := o.limits.validate(); err != nil {
+		return err
+	}
+	if err := o.peak.validate(); err != nil {
+		return err
+	}
+	if o.evalLongEdge < 0 {
+		return fmt.Errorf("invalid evaluation size: %d", o.evalLongEdge)
+	}
+	if o.blurSigma < 0 || o.blurSigma > maxBlurSigma || math.IsNaN(o.blurSigma) {
+		return fmt.Errorf("invalid blur sigma: %g", o.blurSigma)
+	}
+	if o.frequency < 0 || o.frequency > maxBlurSigma || math.IsNaN(o.frequency) {
+		return fmt.Errorf("invalid frequency split sigma: %g", o.frequency)
+	}
+	if o.ditherBox != 0 && (o.ditherBox < 2 || o.ditherBox > maxDitherBox) {
+		return fmt.Errorf("invalid dither box size: %d", o.ditherBox)
+	}
+	if o.colorSpace < ColorSpaceRGB || o.colorSpace > ColorSpaceCIELAB {
+		return fmt.Errorf("unknown color space %d", o.colorSpace)
+	}
+	if o.antiAliasing < AntiAliasingOff || o.antiAliasing > AntiAliasingExclude {
+		return fmt.Errorf("unknown anti-aliasing mode %d", o.antiAliasing)
+	}
+	if err := o.ignoreColor.validate(); err != nil {
+		return err
+	}
+	if err := validateRegions(o.regions); err != nil {
+		return err
+	}
+	if o.hdr != nil {
+		if err := o.hdr.validate(); err != nil {
+			return err
+		}
+	}
+	if o.toneMap != nil {
+		if err := o.toneMap.validate(); err != nil {
+			return err
+		}
+		if o.hdr != nil {
+			return fmt.Errorf("tone mapping cannot be combined with HDR comparison")
+		}
+	}
+	if o.cropSearch && o.maxShift > 0 {
+		return fmt.Errorf("crop search cannot be combined with alignment")
+	}
+	if o.orientation && o.cropSearch {
+		return fmt.Errorf("orientation search cannot be combined with crop search")
+	}
+	if o.alphaMode < AlphaAuto || o.alphaMode > AlphaStraight {
+		return fmt.Errorf("unknown alpha mode %d", o.alphaMode)
+	}
+	if o.background != nil {
+		if _, _, _, a := o.background.RGBA(); a != 0xffff {
+			return fmt.Errorf("flatten background must be opaque")
+		}
+	}
+	if err := o.hashFilter.validate(); err != nil {
+		return err
+	}
+	if o.compat != CompatibilityDefault && (o.needsRGBA() || o.peak.kind != peakBitDepth || o.background != nil || o.alphaMode != AlphaAuto || o.hdr != nil || o.toneMap != nil) {
+		return fmt.Errorf("compatibility modes cannot be combined with other comparison options")
+	}
+	return nil
+}
+
+// needsRGBA reports whether the options operate on images converted to
+// *image.RGBA rather than on the decoded images directly.
+func (o *options) needsRGBA() bool {
+	return o.maxShift > 0 || o.normalize || o.edgeWeighting || o.saliency != nil || o.spherical ||
+		len(o.metrics) > 0 || o.histogram || o.components || o.ditherBox > 0 || o.colorSpace != ColorSpaceRGB ||
+		o.antiAliasing != AntiAliasingOff || o.ignoreColor != nil || len(o.regions) > 0
+}
+
+// debug logs at debug level when a logger is configured.
+func (o *options) debug(msg string, args ...any) {
+	if o.logger != nil {
+		o.logger.Debug(msg, args...)
+	}
+}
+
+// WithAlignment searches integer translations of the second image within
+// ±maxShift pixels on both axes and reports the PSNR of the best-aligned
+// placement. Only the overlapping region is compared, and the detected
+// translation is returned in Result.Offset.
+func WithAlignment(maxShift int) Option {
+	return func(o *options) {
+		o.maxShift = maxShift
+	}
+}
+
+// WithNormalization fits and removes a per-channel linear gain and offset
+// between the images before measuring, so a uniform brightness or contrast
+// change does not dominate the error. The raw PSNR is still reported in
+// Result.PSNR; the normalized values are in Result.Normalized.
+func WithNormalization() Option {
+	return func(o *options) {
+		o.normalize = true
+	}
+}
+
+// WithEdgeWeighting weights each pixel's squared error by the Sobel edge
+// magnitude of the first image, so errors on detail count more than errors
+// in flat backgrounds. The weighted values are reported in
+// Result.WeightedPSNR and Result.WeightedMSE.
+func WithEdgeWeighting() Option {
+	return func(o *options) {
+		o.edgeWeighting = true
+	}
+}
+
+// WithSaliencyMap weights each pixel's squared error by the gray level of
+// the corresponding pixel in m, from 0 (ignored) to white (full weight). The
+// map must have the same dimensions as the images, and a map that is black
+// everywhere fails, as no pixel is left to weigh. It combines
+// multiplicatively with WithEdgeWeighting.
+func WithSaliencyMap(m image.Image) Option {
+	return func(o *options) {
+		o.saliency = m
+	}
+}
+
+// WithSphericalWeighting computes weighted-to-spherically-uniform PSNR
+// (WS-PSNR) for equirectangular 360° images: each row is weighted by the
+// cosine of its latitude so the oversampled poles do not dominate. The
+// result is reported in Result.WeightedPSNR and combines multiplicatively
+// with other weightings.
+func WithSphericalWeighting() Option {
+	return func(o *options) {
+		o.spherical = true
+	}
+}
+
+// WithLogger logs decode formats, the MSE code path taken (including
+// fallbacks to the slower generic path) and timings at debug level, to help
+// explain why a comparison was slow or inaccurate.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithProgress calls fn after each band of rows of the main MSE pass with
+// the number of rows done and the total, so UIs can show progress. Combine
+// it with ComputeContext to cancel long computations.
+func WithProgress(fn func(done, total int)) Option {
+	return func(o *options) {
+		o.progress = fn
+	}
+}
+
+// WithMetrics computes the named registered metrics in the same pixel pass
+// as PSNR, or on the compared images for whole-image metrics, and reports
+// them in Result.Metrics. See RegisterMetric and RegisterImageMetric.
+func WithMetrics(names ...string) Option {
+	return func(o *options) {
+		o.metrics = append(o.metrics, names...)
+	}
+}
+
+// WithCompatibility reproduces another tool's PSNR definition exactly
+// instead of this package's default. Compatibility modes replace the whole
+// computation and cannot be combined with options that change what is
+// compared, such as alignment or weighting.
+func WithCompatibility(c Compatibility) Option {
+	return func(o *options) {
+		o.compat = c
+	}
+}
+
+// WithDecoder decodes inputs with the named backend registered through
+// RegisterDecoder instead of the standard library, for the format that
+// backend handles. Building with -tags libjpeg registers "libjpeg", which
+// decodes JPEGs with the library ImageMagick and other libjpeg-based tools
+// use, so combined with WithCompatibility most of the decoder difference of
+// image/jpeg disappears; what remains depends on the libjpeg build and its
+// IDCT and upsampling settings.
+func WithDecoder(name string) Option {
+	return func(o *options) {
+		o.decoderName = name
+	}
+}
+
+// WithColorMatrix converts Y'CbCr inputs to RGB with m instead of the
+// BT.601 matrix image/jpeg assumes, for content such as video frames tagged
+// BT.709 or BT.2020. It has no effect on RGB inputs or in
+// CompatibilityFFmpeg mode, which compares Y'CbCr planes directly.
+func WithColorMatrix(m ColorMatrix) Option {
+	return func(o *options) {
+		o.matrix = m
+	}
+}
+
+// WithColorRange sets the quantization range of Y'CbCr inputs; use
+// RangeLimited for video-range (16-235) content. Like WithColorMatrix it
+// only affects Y'CbCr to RGB conversion.
+func WithColorRange(r ColorRange) Option {
+	return func(o *options) {
+		o.colorRange = r
+	}
+}
+
+// WithPeakMode measures PSNR against the peak selected by m instead of 255.
+// The peak used is reported in Result.Peak and applies to the normalized and
+// weighted PSNR as well.
+func WithPeakMode(m PeakMode) Option {
+	return func(o *options) {
+		o.peak = m
+	}
+}
+
+// WithDeterministic makes results bit-identical across machines: both
+// images are converted to canonical 8-bit RGBA samples and compared by one
+// integer kernel, instead of fast paths chosen by image type whose rounding
+// of translucent pixels differs slightly. The samples are those the
+// AlphaMode compares, so the option never changes Result.PSNR by itself; as
+// AlphaAuto picks straight or premultiplied samples by image type, set
+// AlphaStraight or AlphaPremultiplied to make results independent of the
+// input types as well. Floating-point results (normalization, weighting,
+// color spaces, HDR and the ImageMagick compatible error) are summed per
+// row and the rows combined in order with compensated summation in every
+// mode, so they never depend on GOMAXPROCS or scheduling. Decoding is not
+// affected, so keep the decoder the same too.
+func WithDeterministic() Option {
+	return func(o *options) {
+		o.deterministic = true
+	}
+}
+
+// WithMaxPixels rejects images whose header declares more than n pixels with
+// ErrImageTooLarge before decoding them, protecting services that compare
+// untrusted uploads from decompression bombs.
+func WithMaxPixels(n int64) Option {
+	return func(o *options) {
+		o.limits.maxPixels = n
+	}
+}
+
+// WithMaxDimensions rejects images wider than width or taller than height
+// with ErrImageTooLarge before decoding them. Zero leaves a side unlimited.
+func WithMaxDimensions(width, height int) Option {
+	return func(o *options) {
+		o.limits.maxWidth = width
+		o.limits.maxHeight = height
+	}
+}
+
+// WithMaxDecodeMemory rejects images whose decoded pixels would need more
+// than n bytes, estimated from the header's size and color model, with
+// ErrImageTooLarge before decoding them.
+func WithMaxDecodeMemory(n int64) Option {
+	return func(o *options) {
+		o.limits.maxMemory = n
+	}
+}
+
+// WithTimeout bounds decoding and comparison together to d and returns
+// ErrTimeout when it is exceeded, independently of any context deadline.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithTolerantDecode compares truncated JPEG or PNG images over the rows
+// that could be decoded instead of failing. Result.Coverage reports the
+// fraction of rows compared. Progressive JPEGs and interlaced PNGs spread
+// every row across the whole stream, so they rarely recover anything.
+func WithTolerantDecode() Option {
+	return func(o *options) {
+		o.tolerant = true
+	}
+}
+
+// WithHashPrefilter computes a perceptual hash of both decoded images and
+// returns ErrHashMismatch without measuring the error when their Hamming
+// distance exceeds maxDistance (0 to 64), so batch jobs skip obviously
+// unrelated pairs. ComputeMatrix hashes each image once and reports skipped
+// pairs as NaN.
+func WithHashPrefilter(kind HashKind, maxDistance int) Option {
+	return func(o *options) {
+		o.hashFilter = &hashFilter{kind: kind, maxDistance: maxDistance}
+	}
+}
+
+// WithDiffReport reports where the decoded images differ in Result.Diff:
+// the first differing pixel, the number of differing pixels and their
+// bounding box, e.g. to locate the damage when a supposedly lossless
+// transform is not. Pixels are compared in the samples the alpha mode
+// selects, so a pixel is reported exactly when it adds to the unweighted
+// MSE.
+func WithDiffReport() Option {
+	return func(o *options) {
+		o.diff = true
+	}
+}
+
+// WithErrorHistogram collects a histogram of the absolute per-sample and
+// per-pixel differences in the same pass as the MSE and reports it in
+// Result.Histogram, from which percentiles such as the p99 absolute error
+// and the share of pixels within ±1 are derived.
+func WithErrorHistogram() Option {
+	return func(o *options) {
+		o.histogram = true
+	}
+}
+
+// WithComponentPSNR reports separate PSNR values for luma, for the two
+// chroma channels combined and for alpha in Result.Components. Colors are
+// converted with the matrix set by WithColorMatrix (BT.601 by default).
+func WithComponentPSNR() Option {
+	return func(o *options) {
+		o.components = true
+	}
+}
+
+// WithPreprocess applies fn to both decoded images before they are compared,
+// e.g. to crop letterbox bars, convert colors or blur. fn must not modify
+// its argument and is called once per image and comparison.
+func WithPreprocess(fn func(image.Image) image.Image) Option {
+	return func(o *options) {
+		o.preprocess = fn
+	}
+}
+
+// maxBlurSigma bounds WithGaussianBlur; larger blurs erase the content being
+// compared.
+const maxBlurSigma = 16
+
+// WithGaussianBlur blurs both images with a Gaussian of the given standard
+// deviation in pixels (0 to 16) before measuring, so sensor noise does not
+// swamp differences in content. The sigma is reported in Result.BlurSigma,
+// as results measured with different blurs are not comparable.
+func WithGaussianBlur(sigma float64) Option {
+	return func(o *options) {
+		o.blurSigma = sigma
+	}
+}
+
+// WithCropSearch compares images of different sizes when one is a crop of
+// the other: the smaller image is located inside the larger one with a
+// coarse-to-fine search, and the PSNR is measured at the best placement,
+// which is reported in Result.Offset. It cannot be combined with
+// WithAlignment.
+func WithCropSearch() Option {
+	return func(o *options) {
+		o.cropSearch = true
+	}
+}
+
+// WithFlattenOver composites both images over the opaque color c before
+// comparing them, as a viewer would display them. Use it to compare a
+// transparent PNG with a JPEG export that was flattened onto the same
+// background, where the difference in the transparent areas would
+// otherwise dominate the error. Alpha then takes no part in the comparison.
+func WithFlattenOver(c color.Color) Option {
+	return func(o *options) {
+		o.background = c
+	}
+}
+
+// WithAlphaMode compares the color samples of both images premultiplied by
+// alpha or straight, regardless of how they were decoded. By default
+// (AlphaAuto) two PNGs with alpha are compared straight and every other
+// pair premultiplied.
+func WithAlphaMode(m AlphaMode) Option {
+	return func(o *options) {
+		o.alphaMode = m
+	}
+}
+
+// WithDitherTolerance additionally compares both images after averaging
+// every box x box window (2 to 8 pixels), so ordered or error-diffusion
+// dithering, which changes individual pixels but preserves local averages,
+// is not punished like real damage. The raw PSNR is still reported in
+// Result.PSNR; the filtered values are in Result.Dither.
+func WithDitherTolerance(box int) Option {
+	return func(o *options) {
+		o.ditherBox = box
+	}
+}
+
+// WithColorSpace additionally compares both images in OKLab or CIELAB, where
+// distances track perceived color differences more closely than in RGB,
+// e.g. to judge palette quantization or chroma subsampling. The error is
+// reported in Result.ColorSpace with a PSNR measured against the lightness
+// range of the space; Result.PSNR still compares RGB samples.
+func WithColorSpace(s ColorSpace) Option {
+	return func(o *options) {
+		o.colorSpace = s
+	}
+}
+
+// WithMetadataDiff reports in Result.Metadata which EXIF, XMP and ICC
+// metadata of the first image was dropped, added or changed in the second,
+// e.g. to check that an optimizer preserves or strips it as intended. JPEG,
+// PNG and WebP metadata is recognized.
+func WithMetadataDiff() Option {
+	return func(o *options) {
+		o.metadata = true
+	}
+}
+
+// WithAntiAliasing classifies the differing pixels as anti-aliasing
+// artifacts or real changes, as pixelmatch does, and counts each category in
+// Result.AntiAliasing. With AntiAliasingExclude the anti-aliased pixels are
+// also left out of the MSE and PSNR, e.g. to compare renderings whose font
+// smoothing differs.
+func WithAntiAliasing(m AntiAliasingMode) Option {
+	return func(o *options) {
+		o.antiAliasing = m
+	}
+}
+
+// WithIgnoreColor leaves out of the MSE and PSNR the pixels of the first
+// image whose samples are all within tolerance (0-255) of the key color,
+// e.g. a placeholder color marking a region of a template that is meant to
+// change. The number of pixels left out is reported in
+// Result.IgnoredPixels. The comparison fails when no pixel is left.
+func WithIgnoreColor(key color.Color, tolerance int) Option {
+	return func(o *options) {
+		o.ignoreColor = &ignoreColor{key: color.RGBAModel.Convert(key).(color.RGBA), tolerance: tolerance}
+	}
+}
+
+// WithRegions weights or excludes labeled regions of the first image, such
+// as text boxes from an OCR detector: excluded regions are left out of the
+// MSE and PSNR, and the weights of the others scale WeightedPSNR. The error
+// within each region is reported in Result.Regions, in the order given.
+func WithRegions(regions ...Region) Option {
+	return func(o *options) {
+		o.regions = append(o.regions, regions...)
+	}
+}
+
+// WithInputInfo reports the format, dimensions, bit depth, color model,
+// chroma subsampling and encoded size of both inputs in Result.Inputs, so
+// logged results are self-describing without probing the files again.
+func WithInputInfo() Option {
+	return func(o *options) {
+		o.inputInfo = true
+	}
+}
+
+// WithEvaluationSize downscales both images with a Lanczos filter so their
+// long edge is at most longEdge pixels before comparing them, for teams
+// that standardize metrics at a fixed evaluation resolution such as 1024.
+// Smaller images are compared as they are. The compared size is reported in
+// Result.EvaluationSize.
+func WithEvaluationSize(longEdge int) Option {
+	return func(o *options) {
+		o.evalLongEdge = longEdge
+	}
+}
+
+// WithOrientationSearch compares the first image with each of the eight
+// rotations and mirrorings of the second, for pipelines that rotate
+// derivatives by 90° or 180° or mirror them. The orientations are ranked on
+// small thumbnails and the best ones compared at full resolution; the PSNR
+// of the best orientation is reported, with the transform applied to the
+// second image in Result.Orientation. It cannot be combined with
+// WithCropSearch.
+func WithOrientationSearch() Option {
+	return func(o *options) {
+		o.orientation = true
+	}
+}
+
+// WithBorderCrop detects uniform bars along the edges of both images, such as
+// the black bars a letterboxed or pillarboxed video thumbnail gains, and
+// crops them before comparing. Images of the same size are cut to the area
+// inside the bars of both. The detected margins are reported in
+// Result.Borders. A uniform area of content at an edge, such as a clear sky,
+// is treated as a bar too.
+func WithBorderCrop() Option {
+	return func(o *options) {
+		o.borderCrop = true
+	}
+}
+
+// WithFrequencySplit decomposes the difference between both images into a
+// low-frequency part, the difference blurred with a Gaussian of the given
+// standard deviation in pixels (up to 16), and the high-frequency rest, and
+// reports the error of each in Result.Frequency. A broken color
+// management or levels step shows up as low-frequency error, changed
+// sharpening or denoising as high-frequency error. A sigma of 2 to 4 suits
+// most photos.
+func WithFrequencySplit(sigma float64) Option {
+	return func(o *options) {
+		o.frequency = sigma
+	}
+}
+
+// WithStrict refuses to compare inputs that differ in alpha channel, color
+// model, bit depth or ICC profile as stored, such as a JPEG and a PNG of
+// the same picture, instead of silently normalizing them to RGBA. The
+// error is a *MismatchError wrapping ErrAlphaMismatch,
+// ErrColorModelMismatch, ErrBitDepthMismatch or ErrICCMismatch.
+func WithStrict() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
+
+// WithStats reports in Result.Stats the time spent decoding and comparing,
+// the decoders and kernel used, the pixels compared per second and the
+// bytes allocated, for capacity planning and for tracking the performance
+// of the library from production telemetry.
+func WithStats() Option {
+	return func(o *options) {
+		o.stats = true
+	}
+}
@@ -0,0 +1,214 @@
+package psnr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"log/slog"
+	"math"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWithLogger(t *testing.T) {
+	tests := []struct {
+		name  string
+		file1 string
+		file2 string
+		path  string
+	}{
+		{
+			name:  "YCbCr fast path",
+			file1: "testdata/test_original.jpg",
+			file2: "testdata/quality_50.jpg",
+			path:  "path=ycbcr",
+		},
+		{
+			name:  "generic fallback",
+			file1: "testdata/test_image.png",
+			file2: "testdata/test_image_q95.jpg",
+			path:  "path=generic",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data1, err := os.ReadFile(tt.file1)
+			if err != nil {
+				t.Fatalf("Failed to read %s: %v", tt.file1, err)
+			}
+			data2, err := os.ReadFile(tt.file2)
+			if err != nil {
+				t.Fatalf("Failed to read %s: %v", tt.file2, err)
+			}
+
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			if _, err := ComputeDetailed(data1, data2, WithLogger(logger)); err != nil {
+				t.Fatalf("Error computing PSNR: %v", err)
+			}
+
+			logs := buf.String()
+			t.Log(logs)
+			for _, want := range []string{"decoded images", "format1=", "computed MSE", tt.path, "duration="} {
+				if !strings.Contains(logs, want) {
+					t.Errorf("Expected logs to contain %q", want)
+				}
+			}
+			if tt.path == "path=generic" && !strings.Contains(logs, "using generic path") {
+				t.Error("Expected the generic fallback to be logged")
+			}
+		})
+	}
+}
+
+func TestWithLoggerBelowDebug(t *testing.T) {
+	data, err := os.ReadFile("testdata/test_original.jpg")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	if _, err := ComputeDetailed(data, data, WithLogger(logger)); err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected no output at info level, got %q", buf.String())
+	}
+}
+
+func TestWithProgress(t *testing.T) {
+	data1 := encodePNG(t, shiftedPattern(40, 300, 0, 0))
+	data2 := encodePNG(t, shiftedPattern(40, 300, 1, 0))
+
+	var calls [][2]int
+	_, err := ComputeDetailed(data1, data2, WithProgress(func(done, total int) {
+		calls = append(calls, [2]int{done, total})
+	}))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+
+	want := [][2]int{{64, 300}, {128, 300}, {192, 300}, {256, 300}, {300, 300}}
+	if len(calls) != len(want) {
+		t.Fatalf("Expected %d progress calls, got %v", len(want), calls)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("Call %d: expected %v, got %v", i, want[i], calls[i])
+		}
+	}
+}
+
+func TestComputeContextCancel(t *testing.T) {
+	data1 := encodePNG(t, shiftedPattern(40, 300, 0, 0))
+	data2 := encodePNG(t, shiftedPattern(40, 300, 1, 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	calls := 0
+	_, err := ComputeContext(ctx, data1, data2, WithProgress(func(done, total int) {
+		calls++
+		cancel()
+	}))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected computation to stop after the first band, got %d calls", calls)
+	}
+}
+
+func TestWithDeterministic(t *testing.T) {
+	// Translucent NRGBA pixels, where the NRGBA fast path and the canonical
+	// premultiplied samples round differently
+	img1 := image.NewNRGBA(image.Rect(0, 0, 32, 32))
+	img2 := image.NewNRGBA(image.Rect(0, 0, 32, 32))
+	for i := range img1.Pix {
+		img1.Pix[i] = uint8(i * 7)
+		img2.Pix[i] = uint8(i*7 + i%5)
+	}
+	data1, data2 := encodePNG(t, img1), encodePNG(t, img2)
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	result, err := ComputeDetailed(data1, data2, WithDeterministic(), WithLogger(logger))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if !strings.Contains(logs.String(), "path=rgba") {
+		t.Errorf("Expected the canonical RGBA path, got logs:\n%s", logs.String())
+	}
+	// The canonical samples are those of the alpha mode, straight here
+	plain, err := ComputeDetailed(data1, data2)
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if result.MSE != plain.MSE {
+		t.Errorf("Expected WithDeterministic to keep MSE %v, got %v", plain.MSE, result.MSE)
+	}
+
+	// With an explicit alpha mode, the same pixels supplied as another image
+	// type give identical bits
+	if result, err = ComputeDetailed(data1, data2, WithDeterministic(), WithAlphaMode(AlphaPremultiplied)); err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	o, err := newOptions([]Option{WithDeterministic(), WithAlphaMode(AlphaPremultiplied)})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	generic := &decoded{img: genericImage{img1}, format: "png"}
+	other, err := compare(context.Background(), generic, &decoded{img: genericImage{img2}, format: "png"}, o)
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if math.Float64bits(result.PSNR) != math.Float64bits(other.PSNR) || result.MSE != other.MSE {
+		t.Errorf("Expected identical results, got %.17g and %.17g", result.PSNR, other.PSNR)
+	}
+}
+
+// genericImage hides the concrete type of an image so no fast path applies.
+type genericImage struct {
+	image.Image
+}
+
+func TestWithPreprocess(t *testing.T) {
+	// The images differ only in a 4-pixel bar at the top
+	img1 := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	img2 := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for i := range img1.Pix {
+		img1.Pix[i] = uint8(i)
+		img2.Pix[i] = uint8(i)
+	}
+	for i := 0; i < 4*img2.Stride; i++ {
+		img2.Pix[i] = 0
+	}
+	data1, data2 := encodePNG(t, img1), encodePNG(t, img2)
+
+	calls := 0
+	cropBar := func(img image.Image) image.Image {
+		calls++
+		bounds := img.Bounds()
+		return img.(interface {
+			SubImage(image.Rectangle) image.Image
+		}).SubImage(image.Rect(bounds.Min.X, bounds.Min.Y+4, bounds.Max.X, bounds.Max.Y))
+	}
+	result, err := ComputeDetailed(data1, data2, WithPreprocess(cropBar))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected the preprocessing function to run on both images, got %d calls", calls)
+	}
+	if !math.IsInf(result.PSNR, 1) {
+		t.Errorf("Expected Inf after cropping the bar, got %f", result.PSNR)
+	}
+
+	if _, err := ComputeDetailed(data1, data2, WithPreprocess(func(image.Image) image.Image { return nil })); err == nil {
+		t.Error("Expected error when preprocessing returns no image")
+	}
+}
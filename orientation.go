@@ -0,0 +1,176 @@
+package psnr
+
+import (
+	"fmt"
+	"image"
+	"sort"
+)
+
+// Orientation is one of the eight rotations and mirrorings of an image,
+// numbered as the values of the EXIF Orientation tag. Each names the
+// transform applied to an image to display it upright.
+type Orientation int
+
+// The eight orientations; the zero value means none was determined.
+const (
+	OrientationNormal         Orientation = 1
+	OrientationFlipHorizontal Orientation = 2
+	OrientationRotate180      Orientation = 3
+	OrientationFlipVertical   Orientation = 4
+	// OrientationTranspose mirrors the image across its top-left to
+	// bottom-right diagonal.
+	OrientationTranspose Orientation = 5
+	// OrientationRotate90 rotates the image 90° clockwise.
+	OrientationRotate90 Orientation = 6
+	// OrientationTransverse mirrors the image across its top-right to
+	// bottom-left diagonal.
+	OrientationTransverse Orientation = 7
+	// OrientationRotate270 rotates the image 270° clockwise, i.e. 90°
+	// counterclockwise.
+	OrientationRotate270 Orientation = 8
+)
+
+const (
+	// orientationSearchSize is the long edge of the thumbnails all
+	// orientations are compared on.
+	orientationSearchSize = 64
+	// orientationCandidates is the number of best orientations on the
+	// thumbnails that are compared again at full resolution, so a near tie
+	// between mirror images is settled by the fine detail.
+	orientationCandidates = 2
+)
+
+// String returns the name of the orientation, such as "rotate90".
+func (o Orientation) String() string {
+	switch o {
+	case OrientationNormal:
+		return "normal"
+	case OrientationFlipHorizontal:
+		return "flip-horizontal"
+	case OrientationRotate180:
+		return "rotate180"
+	case OrientationFlipVertical:
+		return "flip-vertical"
+	case OrientationTranspose:
+		return "transpose"
+	case OrientationRotate90:
+		return "rotate90"
+	case OrientationTransverse:
+		return "transverse"
+	case OrientationRotate270:
+		return "rotate270"
+	}
+	return fmt.Sprintf("Orientation(%d)", int(o))
+}
+
+// swapsAxes reports whether the orientation exchanges width and height.
+func (o Orientation) swapsAxes() bool {
+	return o >= OrientationTranspose
+}
+
+// orient returns img transformed by o, anchored at the origin.
+func orient(img *image.RGBA, o Orientation) *image.RGBA {
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	size := image.Pt(width, height)
+	if o.swapsAxes() {
+		size = image.Pt(height, width)
+	}
+	dst := image.NewRGBA(image.Rectangle{Max: size})
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			var sx, sy int
+			switch o {
+			case OrientationFlipHorizontal:
+				sx, sy = width-1-x, y
+			case OrientationRotate180:
+				sx, sy = width-1-x, height-1-y
+			case OrientationFlipVertical:
+				sx, sy = x, height-1-y
+			case OrientationTranspose:
+				sx, sy = y, x
+			case OrientationRotate90:
+				sx, sy = y, height-1-x
+			case OrientationTransverse:
+				sx, sy = width-1-y, height-1-x
+			case OrientationRotate270:
+				sx, sy = width-1-y, x
+			default:
+				sx, sy = x, y
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):][:4], img.Pix[img.PixOffset(img.Rect.Min.X+sx, img.Rect.Min.Y+sy):][:4])
+		}
+	}
+	return dst
+}
+
+// orientationMatch is the error of one orientation of the second image.
+type orientationMatch struct {
+	orientation Orientation
+	ssd         uint64
+}
+
+// matchOrientation finds the orientation of the second image that best
+// matches the first and returns the second image transformed by it, or
+// unchanged when it is already upright. All orientations with matching
+// dimensions are compared on thumbnails; the best candidates are then
+// compared at full resolution. Ties keep the lower orientation, so
+// symmetric images are left as they are. Candidates are compared and built
+// in the samples mode compares, so a straight comparison stays straight.
+func matchOrientation(d1, d2 *decoded, mode AlphaMode) (*decoded, Orientation, error) {
+	straight := comparesStraight(d1.img, d2.img, mode)
+	img1, img2 := comparisonRGBA(d1.img, d2.img, mode)
+	size1, size2 := img1.Rect.Size(), img2.Rect.Size()
+	thumb := func(img *image.RGBA) *image.RGBA {
+		size := evaluationSize(img.Rect.Size(), orientationSearchSize)
+		if size == img.Rect.Size() {
+			return img
+		}
+		small := downscaleLanczos(comparisonImage(img, straight), size.X, size.Y)
+		if straight {
+			return straightRGBA(small)
+		}
+		return toRGBA(small)
+	}
+	thumb1, thumb2 := thumb(img1), thumb(img2)
+
+	var matches []orientationMatch
+	for o := OrientationNormal; o <= OrientationRotate270; o++ {
+		oriented := size2
+		if o.swapsAxes() {
+			oriented = image.Pt(size2.Y, size2.X)
+		}
+		if oriented != size1 {
+			continue
+		}
+		candidate := orient(thumb2, o)
+		if candidate.Rect != thumb1.Rect {
+			continue
+		}
+		matches = append(matches, orientationMatch{o, blockSSD(thumb1, candidate, thumb1.Rect, image.Point{}, true)})
+	}
+	if len(matches) == 0 {
+		return nil, 0, fmt.Errorf("no orientation of the second image matches the size of the first: %dx%d vs %dx%d",
+			size1.X, size1.Y, size2.X, size2.Y)
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].ssd < matches[j].ssd
+	})
+
+	var best *image.RGBA
+	var bestOrientation Orientation
+	var bestSSD uint64
+	for _, m := range matches[:min(orientationCandidates, len(matches))] {
+		candidate := img2
+		if m.orientation != OrientationNormal {
+			candidate = orient(img2, m.orientation)
+		}
+		ssd := blockSSD(img1, candidate, img1.Rect, image.Point{}, true)
+		if best == nil || ssd < bestSSD || (ssd == bestSSD && m.orientation < bestOrientation) {
+			best, bestOrientation, bestSSD = candidate, m.orientation, ssd
+		}
+	}
+	if bestOrientation == OrientationNormal {
+		return d2, bestOrientation, nil
+	}
+	return d2.withImage(comparisonImage(best, straight)), bestOrientation, nil
+}
@@ -0,0 +1,147 @@
+package psnr
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"testing"
+)
+
+func orientationTestImage(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetRGBA(x, y, color.RGBA{uint8(x * 5), uint8(y * 9), uint8(x * y % 256), 255})
+		}
+	}
+	return img
+}
+
+func TestOrient(t *testing.T) {
+	// 0 1 2
+	// 3 4 5
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	for i := 0; i < 6; i++ {
+		img.Pix[i*4] = uint8(i)
+	}
+	tests := []struct {
+		orientation Orientation
+		want        [][]uint8
+	}{
+		{OrientationNormal, [][]uint8{{0, 1, 2}, {3, 4, 5}}},
+		{OrientationFlipHorizontal, [][]uint8{{2, 1, 0}, {5, 4, 3}}},
+		{OrientationRotate180, [][]uint8{{5, 4, 3}, {2, 1, 0}}},
+		{OrientationFlipVertical, [][]uint8{{3, 4, 5}, {0, 1, 2}}},
+		{OrientationTranspose, [][]uint8{{0, 3}, {1, 4}, {2, 5}}},
+		{OrientationRotate90, [][]uint8{{3, 0}, {4, 1}, {5, 2}}},
+		{OrientationTransverse, [][]uint8{{5, 2}, {4, 1}, {3, 0}}},
+		{OrientationRotate270, [][]uint8{{2, 5}, {1, 4}, {0, 3}}},
+	}
+	for _, tt := range tests {
+		got := orient(img, tt.orientation)
+		if got.Rect.Dx() != len(tt.want[0]) || got.Rect.Dy() != len(tt.want) {
+			t.Errorf("%v: got size %v", tt.orientation, got.Rect.Size())
+			continue
+		}
+		for y, row := range tt.want {
+			for x, v := range row {
+				if got.RGBAAt(x, y).R != v {
+					t.Errorf("%v: got %d at (%d, %d), want %d", tt.orientation, got.RGBAAt(x, y).R, x, y, v)
+				}
+			}
+		}
+	}
+}
+
+func TestWithOrientationSearch(t *testing.T) {
+	ctx := context.Background()
+	img := orientationTestImage(40, 24)
+	inverse := map[Orientation]Orientation{OrientationRotate90: OrientationRotate270, OrientationRotate270: OrientationRotate90}
+
+	for o := OrientationNormal; o <= OrientationRotate270; o++ {
+		result, err := CompareImages(ctx, img, orient(img, o), WithOrientationSearch())
+		if err != nil {
+			t.Fatalf("%v: CompareImages failed: %v", o, err)
+		}
+		want := o
+		if inv, ok := inverse[o]; ok {
+			want = inv
+		}
+		if result.Orientation != want || !math.IsInf(result.PSNR, 1) {
+			t.Errorf("%v: got %v at %v dB, want %v at +Inf", o, result.Orientation, result.PSNR, want)
+		}
+	}
+
+	// A degraded copy still finds its orientation
+	noisy := NoisyImage(orient(img, OrientationRotate90), 6, 1)
+	result, err := CompareImages(ctx, img, noisy, WithOrientationSearch())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Orientation != OrientationRotate270 || math.Abs(result.PSNR-NoisePSNR(6)) > 1 {
+		t.Errorf("Expected rotate270 at about %.1f dB, got %v at %.1f dB", NoisePSNR(6), result.Orientation, result.PSNR)
+	}
+
+	if _, err := CompareImages(ctx, img, orient(img, OrientationRotate90)); err == nil {
+		t.Error("Expected a size mismatch without orientation search")
+	}
+	if _, err := CompareImages(ctx, img, orientationTestImage(30, 30), WithOrientationSearch()); err == nil {
+		t.Error("Expected error when no orientation matches the size")
+	}
+	if _, err := CompareImages(ctx, img, img, WithOrientationSearch(), WithCropSearch()); err == nil {
+		t.Error("Expected error combining orientation and crop search")
+	}
+}
+
+func TestWithOrientationSearchIdentical(t *testing.T) {
+	data, err := os.ReadFile("testdata/test_original.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ComputeDetailed(data, data, WithOrientationSearch())
+	if err != nil {
+		t.Fatalf("ComputeDetailed failed: %v", err)
+	}
+	if result.Orientation != OrientationNormal {
+		t.Errorf("Expected normal orientation, got %v", result.Orientation)
+	}
+}
+
+func TestMatchOrientationDecoded(t *testing.T) {
+	img := orientationTestImage(40, 24)
+	d1 := &decoded{img: img, format: "png"}
+	upright := &decoded{img: img, format: "jpeg", data: []byte{1}, partialRows: 3, grayAlpha: true, backend: "libjpeg"}
+	got, o, err := matchOrientation(d1, upright, AlphaAuto)
+	if err != nil || o != OrientationNormal || got != upright {
+		t.Errorf("Expected the upright image unchanged, got %v, %v", o, err)
+	}
+
+	rotated := *upright
+	rotated.img = orient(img, OrientationRotate90)
+	got, o, err = matchOrientation(d1, &rotated, AlphaAuto)
+	if err != nil || o != OrientationRotate270 {
+		t.Fatalf("Expected rotate270, got %v, %v", o, err)
+	}
+	if got.format != rotated.format || got.partialRows != rotated.partialRows || got.grayAlpha != rotated.grayAlpha || got.backend != rotated.backend {
+		t.Errorf("Expected the decoder fields of %+v, got %+v", rotated, *got)
+	}
+}
+
+func TestWithOrientationSearchStraight(t *testing.T) {
+	ctx := context.Background()
+	img1, img2 := translucentPair(40, 24)
+	upright, err := CompareImages(ctx, img1, img2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mirrored := comparisonImage(orient(straightRGBA(img2), OrientationFlipHorizontal), true)
+	result, err := CompareImages(ctx, img1, mirrored, WithOrientationSearch())
+	if err != nil {
+		t.Fatalf("CompareImages failed: %v", err)
+	}
+	if result.Orientation != OrientationFlipHorizontal || result.PSNR != upright.PSNR {
+		t.Errorf("Expected flip-horizontal at %v dB as compared upright, got %v at %v dB", upright.PSNR, result.Orientation, result.PSNR)
+	}
+}
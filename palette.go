@@ -0,0 +1,241 @@
+package psnr
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+)
+
+// maxPaletteColors is the most colors a palette, such as that of a PNG8
+// file, can hold.
+const maxPaletteColors = 256
+
+// PaletteAnalysis reports whether an image could be stored with a palette,
+// as computed by AnalyzePalette.
+type PaletteAnalysis struct {
+	// Result compares the first and second image.
+	Result *Result
+	// ReferenceColors and TestColors are the numbers of distinct colors,
+	// alpha included, in the first and second image.
+	ReferenceColors int
+	TestColors      int
+	// Colors is the palette size the reference was quantized to.
+	Colors int
+	// QuantizedPSNR is the PSNR of the reference quantized to Colors colors
+	// against the reference itself: +Inf when it has no more colors than
+	// that, and otherwise an estimate of the best a PNG8 conversion
+	// without dithering can reach.
+	QuantizedPSNR float64
+}
+
+// Lossless reports whether the reference fits the palette exactly.
+func (a *PaletteAnalysis) Lossless() bool {
+	return a.ReferenceColors <= a.Colors
+}
+
+// AnalyzePalette compares two images and reports, together with the result,
+// the number of distinct colors in each and the PSNR achievable by
+// quantizing the first to a palette of the given number of colors (1-256,
+// or 256 when zero), to decide whether a derivative can be a PNG8. The reference is
+// quantized with median cut and mapped to the nearest palette color; opts
+// configure both comparisons.
+func AnalyzePalette(ctx context.Context, data1, data2 []byte, colors int, opts ...Option) (*PaletteAnalysis, error) {
+	if colors == 0 {
+		colors = maxPaletteColors
+	}
+	if colors < 1 || colors > maxPaletteColors {
+		return nil, fmt.Errorf("invalid palette size: %d", colors)
+	}
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	d1, d2, err := decodePair(data1, data2, o)
+	if err != nil {
+		return nil, err
+	}
+	result, err := compare(ctx, d1, d2, o)
+	if err != nil {
+		return nil, err
+	}
+
+	ref := toRGBA(d1.img)
+	analysis := &PaletteAnalysis{
+		Result:          result,
+		ReferenceColors: CountColors(ref),
+		TestColors:      CountColors(d2.img),
+		Colors:          colors,
+	}
+	quantized := d1.withImage(quantize(ref, colors))
+	compared, err := compare(ctx, d1.withImage(ref), quantized, o)
+	if err != nil {
+		return nil, err
+	}
+	analysis.QuantizedPSNR = compared.PSNR
+	return analysis, nil
+}
+
+// CountColors returns the number of distinct colors in img, alpha included.
+func CountColors(img image.Image) int {
+	return len(colorHistogram(toRGBA(img)))
+}
+
+// colorHistogram counts the pixels of each premultiplied RGBA color, packed
+// into a uint32.
+func colorHistogram(img *image.RGBA) map[uint32]int {
+	histogram := make(map[uint32]int)
+	for y := 0; y < img.Rect.Dy(); y++ {
+		row := img.Pix[y*img.Stride:][:img.Rect.Dx()*4]
+		for i := 0; i < len(row); i += 4 {
+			histogram[uint32(row[i])<<24|uint32(row[i+1])<<16|uint32(row[i+2])<<8|uint32(row[i+3])]++
+		}
+	}
+	return histogram
+}
+
+// colorCount is a color of the histogram and its number of pixels.
+type colorCount struct {
+	c     [4]uint8
+	count int
+}
+
+// quantize returns img with every pixel replaced by the nearest color of a
+// palette of at most n colors built by median cut. Images with n colors or
+// fewer are returned as they are.
+func quantize(img *image.RGBA, n int) *image.RGBA {
+	if len(colorHistogram(img)) <= n {
+		return img
+	}
+	return toRGBA(quantizeMedianCut(img, n))
+}
+
+// quantizeMedianCut maps img to a palette of at most n (1-256) colors built
+// by median cut, every pixel taking the nearest palette color. Images with
+// n colors or fewer keep their exact colors.
+func quantizeMedianCut(img *image.RGBA, n int) *image.Paletted {
+	histogram := colorHistogram(img)
+	// Sort the colors, as map order is random, so the palette is reproducible
+	packed := make([]uint32, 0, len(histogram))
+	for c := range histogram {
+		packed = append(packed, c)
+	}
+	sort.Slice(packed, func(i, j int) bool { return packed[i] < packed[j] })
+	entries := make([]colorCount, len(packed))
+	for i, c := range packed {
+		entries[i] = colorCount{[4]uint8{uint8(c >> 24), uint8(c >> 16), uint8(c >> 8), uint8(c)}, histogram[c]}
+	}
+	palette := medianCut(entries, n)
+
+	colors := make(color.Palette, len(palette))
+	for i, p := range palette {
+		colors[i] = color.RGBA{p[0], p[1], p[2], p[3]}
+	}
+	dst := image.NewPaletted(image.Rectangle{Max: img.Rect.Size()}, colors)
+	nearest := make(map[[4]uint8]uint8, len(histogram))
+	for y := 0; y < dst.Rect.Dy(); y++ {
+		row := img.Pix[y*img.Stride:][:dst.Rect.Dx()*4]
+		for x := 0; x < dst.Rect.Dx(); x++ {
+			c := [4]uint8(row[x*4 : x*4+4])
+			index, ok := nearest[c]
+			if !ok {
+				index = nearestColor(palette, c)
+				nearest[c] = index
+			}
+			dst.Pix[y*dst.Stride+x] = index
+		}
+	}
+	return dst
+}
+
+// medianCut splits the colors into at most n boxes, always splitting the
+// box with the widest channel range at the pixel median of that channel,
+// and returns the pixel-weighted mean color of each box.
+func medianCut(entries []colorCount, n int) [][4]uint8 {
+	boxes := [][]colorCount{entries}
+	for len(boxes) < n {
+		best, bestChannel, bestRange := -1, 0, 0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			if channel, r := widestChannel(box); r > bestRange {
+				best, bestChannel, bestRange = i, channel, r
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		box := boxes[best]
+		sort.SliceStable(box, func(i, j int) bool {
+			return box[i].c[bestChannel] < box[j].c[bestChannel]
+		})
+		var total, half int
+		for _, e := range box {
+			total += e.count
+		}
+		split := 1
+		for i, e := range box[:len(box)-1] {
+			half += e.count
+			split = i + 1
+			if 2*half >= total {
+				break
+			}
+		}
+		boxes[best] = box[:split]
+		boxes = append(boxes, box[split:])
+	}
+
+	palette := make([][4]uint8, len(boxes))
+	for i, box := range boxes {
+		var sum [4]int
+		var total int
+		for _, e := range box {
+			for c := range sum {
+				sum[c] += int(e.c[c]) * e.count
+			}
+			total += e.count
+		}
+		for c := range sum {
+			palette[i][c] = uint8((sum[c] + total/2) / total)
+		}
+	}
+	return palette
+}
+
+// widestChannel returns the channel with the largest range of values in box
+// and that range.
+func widestChannel(box []colorCount) (int, int) {
+	lo, hi := box[0].c, box[0].c
+	for _, e := range box[1:] {
+		for c := range lo {
+			lo[c], hi[c] = min(lo[c], e.c[c]), max(hi[c], e.c[c])
+		}
+	}
+	channel, widest := 0, 0
+	for c := range lo {
+		if r := int(hi[c]) - int(lo[c]); r > widest {
+			channel, widest = c, r
+		}
+	}
+	return channel, widest
+}
+
+// nearestColor returns the index of the palette color closest to c in
+// squared RGBA distance.
+func nearestColor(palette [][4]uint8, c [4]uint8) uint8 {
+	best, bestDistance := 0, -1
+	for i, p := range palette {
+		var distance int
+		for k := range p {
+			d := int(p[k]) - int(c[k])
+			distance += d * d
+		}
+		if bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = i, distance
+		}
+	}
+	return uint8(best)
+}
@@ -0,0 +1,83 @@
+package psnr
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestCountColors(t *testing.T) {
+	if n := CountColors(SolidImage(image.Rect(0, 0, 8, 8), color.White)); n != 1 {
+		t.Errorf("Expected 1 color, got %d", n)
+	}
+	gradient := GradientImage(image.Rect(0, 0, 256, 4), color.Black, color.White)
+	if n := CountColors(gradient); n != 256 {
+		t.Errorf("Expected 256 colors, got %d", n)
+	}
+}
+
+func TestQuantize(t *testing.T) {
+	gradient := GradientImage(image.Rect(0, 0, 256, 4), color.Black, color.White)
+	quantized := quantize(gradient, 16)
+	if n := CountColors(quantized); n != 16 {
+		t.Errorf("Expected 16 colors, got %d", n)
+	}
+	// Uniform buckets of 16 levels leave an error of about 4.6 per sample
+	if result, err := CompareImages(context.Background(), gradient, quantized); err != nil || result.MSE > 25 {
+		t.Errorf("Expected a small quantization error, got %+v, %v", result, err)
+	}
+	if quantize(gradient, 256) != gradient {
+		t.Error("Expected an image that fits the palette to be kept")
+	}
+}
+
+func TestAnalyzePalette(t *testing.T) {
+	ctx := context.Background()
+	data1, err := os.ReadFile("testdata/test_original.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data2, err := os.ReadFile("testdata/quality_50.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	analysis, err := AnalyzePalette(ctx, data1, data2, 0)
+	if err != nil {
+		t.Fatalf("AnalyzePalette failed: %v", err)
+	}
+	if analysis.Colors != 256 || analysis.ReferenceColors <= 256 || analysis.TestColors <= 256 || analysis.Lossless() {
+		t.Errorf("Expected a photo to exceed 256 colors, got %+v", analysis)
+	}
+	if analysis.Result == nil || analysis.Result.PSNR < 25 {
+		t.Errorf("Expected the comparison result, got %+v", analysis.Result)
+	}
+	few, err := AnalyzePalette(ctx, data1, data2, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.IsInf(analysis.QuantizedPSNR, 1) || few.QuantizedPSNR >= analysis.QuantizedPSNR {
+		t.Errorf("Expected fewer colors to lose more, got %v dB at 256 and %v dB at 8", analysis.QuantizedPSNR, few.QuantizedPSNR)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, GradientImage(image.Rect(0, 0, 64, 4), color.Black, color.White)); err != nil {
+		t.Fatal(err)
+	}
+	graphic, err := AnalyzePalette(ctx, buf.Bytes(), buf.Bytes(), 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !graphic.Lossless() || !math.IsInf(graphic.QuantizedPSNR, 1) || graphic.ReferenceColors != 64 {
+		t.Errorf("Expected a 64-color image to fit the palette, got %+v", graphic)
+	}
+
+	if _, err := AnalyzePalette(ctx, data1, data2, -1); err == nil {
+		t.Error("Expected error for a negative palette size")
+	}
+}
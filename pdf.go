@@ -0,0 +1,113 @@
+package psnr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+)
+
+// maxPDFDPI bounds the resolution of ComparePDFs; an A4 page at 1200 dpi is
+// already 140 megapixels.
+const maxPDFDPI = 1200
+
+var (
+	// ErrNotPDF is returned by ComparePDFs for data that is not a PDF.
+	ErrNotPDF = errors.New("not a PDF file")
+	// ErrPDFUnsupported is returned by ComparePDFs when no PDF renderer was
+	// built in.
+	ErrPDFUnsupported = errors.New("PDF rendering requires building with -tags mupdf")
+)
+
+// PDFComparison is the result of comparing two PDF documents page by page.
+type PDFComparison struct {
+	// Pages holds the comparison of every page present in both documents,
+	// in page order.
+	Pages []PDFPageResult
+	// PageCount1 and PageCount2 are the page counts of the documents; pages
+	// beyond the shorter document are not compared.
+	PageCount1, PageCount2 int
+}
+
+// PDFPageResult is the comparison of one page of two documents.
+type PDFPageResult struct {
+	// Page is the 1-based page number.
+	Page int
+	*Result
+}
+
+// pdfDocument is an open PDF document of a renderer backend.
+type pdfDocument interface {
+	pageCount() int
+	// renderPage rasterizes the 0-based page at dpi to an opaque RGB image
+	// on a white background.
+	renderPage(page int, dpi float64) (image.Image, error)
+	close()
+}
+
+// openPDF opens a PDF with the renderer compiled in, and is nil when there
+// is none.
+var openPDF func(data []byte) (pdfDocument, error)
+
+// ComparePDFs renders the corresponding pages of two PDF documents at dpi
+// (up to 1200) and compares them, e.g. to check a document conversion.
+// Rendering requires MuPDF and the mupdf build tag; without it
+// ComparePDFs returns ErrPDFUnsupported. Pages whose sizes differ fail the
+// comparison.
+func ComparePDFs(ctx context.Context, data1, data2 []byte, dpi float64, opts ...Option) (*PDFComparison, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if !(dpi > 0 && dpi <= maxPDFDPI) {
+		return nil, fmt.Errorf("invalid PDF resolution: %g dpi", dpi)
+	}
+	if !isPDF(data1) {
+		return nil, fmt.Errorf("first document: %w", ErrNotPDF)
+	}
+	if !isPDF(data2) {
+		return nil, fmt.Errorf("second document: %w", ErrNotPDF)
+	}
+	if openPDF == nil {
+		return nil, ErrPDFUnsupported
+	}
+
+	doc1, err := openPDF(data1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open first document: %w", err)
+	}
+	defer doc1.close()
+	doc2, err := openPDF(data2)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open second document: %w", err)
+	}
+	defer doc2.close()
+
+	comparison := &PDFComparison{PageCount1: doc1.pageCount(), PageCount2: doc2.pageCount()}
+	for page := 0; page < min(comparison.PageCount1, comparison.PageCount2); page++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		img1, err := doc1.renderPage(page, dpi)
+		if err != nil {
+			return nil, fmt.Errorf("page %d of first document: %w", page+1, err)
+		}
+		img2, err := doc2.renderPage(page, dpi)
+		if err != nil {
+			return nil, fmt.Errorf("page %d of second document: %w", page+1, err)
+		}
+		result, err := compare(ctx, &decoded{img: img1, format: memoryFormat}, &decoded{img: img2, format: memoryFormat}, o)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", page+1, err)
+		}
+		comparison.Pages = append(comparison.Pages, PDFPageResult{Page: page + 1, Result: result})
+	}
+	return comparison, nil
+}
+
+// isPDF reports whether data starts with a PDF header. The specification
+// allows up to 1024 bytes of leading garbage.
+func isPDF(data []byte) bool {
+	return bytes.Contains(data[:min(len(data), 1024+5)], []byte("%PDF-"))
+}
@@ -0,0 +1,154 @@
+//go:build mupdf && cgo
+
+package psnr
+
+/*
+#cgo LDFLAGS: -lmupdf -lmupdf-third -lm
+#include <stdlib.h>
+#include <string.h>
+#include <mupdf/fitz.h>
+
+// psnr_pdf is an open document with its own context, as MuPDF contexts
+// must not be shared between threads without locking callbacks.
+typedef struct {
+	fz_context *ctx;
+	fz_document *doc;
+	int pages;
+} psnr_pdf;
+
+// psnr_pdf_error returns a malloc'ed copy of the caught error message.
+static char *psnr_pdf_error(fz_context *ctx) {
+	return strdup(fz_caught_message(ctx));
+}
+
+// psnr_pdf_open opens a PDF held in data, which must outlive the document.
+static char *psnr_pdf_open(unsigned char *data, size_t size, psnr_pdf *pdf) {
+	pdf->ctx = fz_new_context(NULL, NULL, FZ_STORE_DEFAULT);
+	if (pdf->ctx == NULL) {
+		return strdup("cannot create MuPDF context");
+	}
+	fz_context *ctx = pdf->ctx;
+	fz_stream *stream = NULL;
+	char *err = NULL;
+	fz_var(stream);
+	fz_try(ctx) {
+		fz_register_document_handlers(ctx);
+		stream = fz_open_memory(ctx, data, size);
+		pdf->doc = fz_open_document_with_stream(ctx, "application/pdf", stream);
+		pdf->pages = fz_count_pages(ctx, pdf->doc);
+	}
+	fz_always(ctx) {
+		fz_drop_stream(ctx, stream);
+	}
+	fz_catch(ctx) {
+		err = psnr_pdf_error(ctx);
+	}
+	return err;
+}
+
+// psnr_pdf_render renders a page to 8-bit RGB samples in a malloc'ed
+// buffer without padding.
+static char *psnr_pdf_render(psnr_pdf *pdf, int page, float zoom, unsigned char **pixels, int *width, int *height) {
+	fz_context *ctx = pdf->ctx;
+	fz_pixmap *pix = NULL;
+	char *err = NULL;
+	fz_var(pix);
+	fz_try(ctx) {
+		pix = fz_new_pixmap_from_page_number(ctx, pdf->doc, page, fz_scale(zoom, zoom), fz_device_rgb(ctx), 0);
+		int w = fz_pixmap_width(ctx, pix), h = fz_pixmap_height(ctx, pix);
+		int n = fz_pixmap_components(ctx, pix);
+		ptrdiff_t stride = fz_pixmap_stride(ctx, pix);
+		unsigned char *samples = fz_pixmap_samples(ctx, pix);
+		unsigned char *out = malloc((size_t)w * h * 3);
+		if (out == NULL) {
+			fz_throw(ctx, FZ_ERROR_GENERIC, "out of memory");
+		}
+		for (int y = 0; y < h; y++) {
+			for (int x = 0; x < w; x++) {
+				memcpy(out + ((size_t)y * w + x) * 3, samples + y * stride + x * n, 3);
+			}
+		}
+		*pixels = out;
+		*width = w;
+		*height = h;
+	}
+	fz_always(ctx) {
+		fz_drop_pixmap(ctx, pix);
+	}
+	fz_catch(ctx) {
+		err = psnr_pdf_error(ctx);
+	}
+	return err;
+}
+
+static void psnr_pdf_close(psnr_pdf *pdf) {
+	if (pdf->ctx != NULL) {
+		fz_drop_document(pdf->ctx, pdf->doc);
+		fz_drop_context(pdf->ctx);
+	}
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"image"
+	"unsafe"
+)
+
+func init() {
+	openPDF = openMuPDF
+}
+
+// muPDFDocument is a document opened with MuPDF.
+type muPDFDocument struct {
+	pdf  C.psnr_pdf
+	data unsafe.Pointer
+}
+
+// openMuPDF opens a PDF with MuPDF, which reads it from a C copy of data.
+func openMuPDF(data []byte) (pdfDocument, error) {
+	doc := &muPDFDocument{data: C.CBytes(data)}
+	if message := C.psnr_pdf_open((*C.uchar)(doc.data), C.size_t(len(data)), &doc.pdf); message != nil {
+		doc.close()
+		return nil, muPDFError(message)
+	}
+	return doc, nil
+}
+
+func (d *muPDFDocument) pageCount() int {
+	return int(d.pdf.pages)
+}
+
+func (d *muPDFDocument) renderPage(page int, dpi float64) (image.Image, error) {
+	var pixels *C.uchar
+	var width, height C.int
+	// PDF user space has 72 units per inch
+	if message := C.psnr_pdf_render(&d.pdf, C.int(page), C.float(dpi/72), &pixels, &width, &height); message != nil {
+		return nil, muPDFError(message)
+	}
+	defer C.free(unsafe.Pointer(pixels))
+
+	w, h := int(width), int(height)
+	rgb := unsafe.Slice((*uint8)(unsafe.Pointer(pixels)), w*h*3)
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i, j := 0, 0; i < len(rgb); i, j = i+3, j+4 {
+		img.Pix[j] = rgb[i]
+		img.Pix[j+1] = rgb[i+1]
+		img.Pix[j+2] = rgb[i+2]
+		img.Pix[j+3] = 0xff
+	}
+	return img, nil
+}
+
+func (d *muPDFDocument) close() {
+	C.psnr_pdf_close(&d.pdf)
+	C.free(d.data)
+}
+
+// muPDFError converts a message returned by the C helpers into an error and
+// frees it.
+func muPDFError(message *C.char) error {
+	defer C.free(unsafe.Pointer(message))
+	return errors.New("mupdf: " + C.GoString(message))
+}
@@ -0,0 +1,79 @@
+package psnr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// minimalPDF builds a PDF whose pages are 72x72 points and draw the given
+// content streams.
+func minimalPDF(pages ...string) []byte {
+	var objects []string
+	kids := ""
+	for i, content := range pages {
+		page := 3 + 2*i
+		kids += fmt.Sprintf("%d 0 R ", page)
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 72 72] /Contents %d 0 R >>", page+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+	objects = append([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", kids, len(pages)),
+	}, objects...)
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func TestComparePDFs(t *testing.T) {
+	square := "1 0 0 rg 18 18 36 36 re f"
+	doc1 := minimalPDF(square, square, square)
+	doc2 := minimalPDF(square, "1 0 0 rg 20 18 36 36 re f")
+
+	if _, err := ComparePDFs(context.Background(), []byte("not a pdf"), doc2, 72); !errors.Is(err, ErrNotPDF) {
+		t.Errorf("Expected ErrNotPDF, got %v", err)
+	}
+	for _, dpi := range []float64{0, -72, 5000, math.NaN()} {
+		if _, err := ComparePDFs(context.Background(), doc1, doc2, dpi); err == nil {
+			t.Errorf("Expected error for %g dpi", dpi)
+		}
+	}
+
+	comparison, err := ComparePDFs(context.Background(), doc1, doc2, 144)
+	if openPDF == nil {
+		if !errors.Is(err, ErrPDFUnsupported) {
+			t.Errorf("Expected ErrPDFUnsupported without a renderer, got %v", err)
+		}
+		t.Skip("built without a PDF renderer")
+	}
+	if err != nil {
+		t.Fatalf("ComparePDFs failed: %v", err)
+	}
+	if comparison.PageCount1 != 3 || comparison.PageCount2 != 2 || len(comparison.Pages) != 2 {
+		t.Fatalf("Unexpected comparison: %+v", comparison)
+	}
+	first, second := comparison.Pages[0], comparison.Pages[1]
+	if first.Page != 1 || !math.IsInf(first.PSNR, 1) {
+		t.Errorf("Expected identical first pages, got %+v", first)
+	}
+	if second.Page != 2 || math.IsInf(second.PSNR, 1) {
+		t.Errorf("Expected the shifted square to differ, got %+v", second)
+	}
+}
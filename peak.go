@@ -0,0 +1,92 @@
+package psnr
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// maxSampleValue is the largest 8-bit sample value and the default peak.
+const maxSampleValue = 255.0
+
+// peakKind enumerates the PeakMode variants.
+type peakKind int
+
+const (
+	peakBitDepth peakKind = iota
+	peakFixed
+	peakReferenceMax
+)
+
+// PeakMode selects the peak signal value that PSNR is measured against.
+// Create one with PeakBitDepth, PeakFixed or PeakReferenceMax.
+type PeakMode struct {
+	kind  peakKind
+	value float64
+}
+
+// PeakBitDepth measures against the largest code value of the sample bit
+// depth, 2^n-1. Samples are compared on the 8-bit scale, so this is 255, the
+// default.
+func PeakBitDepth() PeakMode {
+	return PeakMode{kind: peakBitDepth}
+}
+
+// PeakFixed measures against value, given on the 0-255 scale of Result.MSE.
+func PeakFixed(value float64) PeakMode {
+	return PeakMode{kind: peakFixed, value: value}
+}
+
+// PeakReferenceMax measures against the largest R, G or B sample present in
+// the first image, as some standards define PSNR. A completely black
+// reference has a peak of 0 and reports -Inf for any difference.
+func PeakReferenceMax() PeakMode {
+	return PeakMode{kind: peakReferenceMax}
+}
+
+// validate reports an invalid peak mode.
+func (m PeakMode) validate() error {
+	switch m.kind {
+	case peakBitDepth, peakReferenceMax:
+		return nil
+	case peakFixed:
+		if m.value <= 0 || math.IsInf(m.value, 0) || math.IsNaN(m.value) {
+			return fmt.Errorf("invalid fixed peak: %v", m.value)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown peak mode %d", m.kind)
+}
+
+// resolve returns the peak for a comparison against reference.
+func (m PeakMode) resolve(reference image.Image) float64 {
+	switch m.kind {
+	case peakFixed:
+		return m.value
+	case peakReferenceMax:
+		return float64(referenceMax(toRGBA(reference)))
+	}
+	return maxSampleValue
+}
+
+// referenceMax returns the largest color sample of img.
+func referenceMax(img *image.RGBA) uint8 {
+	var peak uint8
+	width := img.Rect.Dx() * 4
+	for y := 0; y < img.Rect.Dy(); y++ {
+		row := img.Pix[y*img.Stride : y*img.Stride+width]
+		for i := 0; i < len(row); i += 4 {
+			peak = max(peak, row[i], row[i+1], row[i+2])
+		}
+	}
+	return peak
+}
+
+// rescalePSNR converts a PSNR measured against 255 to one measured against
+// peak, which only shifts it by 20*log10(peak/255).
+func rescalePSNR(psnr, peak float64) float64 {
+	if math.IsInf(psnr, 1) || peak == maxSampleValue {
+		return psnr
+	}
+	return psnr + 20*math.Log10(peak/maxSampleValue)
+}
@@ -0,0 +1,90 @@
+package psnr
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestPeakMode(t *testing.T) {
+	data1, err := os.ReadFile("testdata/test_original.jpg")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+	data2, err := os.ReadFile("testdata/quality_50.jpg")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+
+	standard, err := ComputeDetailed(data1, data2)
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if standard.Peak != 255 {
+		t.Errorf("Expected default peak 255, got %f", standard.Peak)
+	}
+
+	bitDepth, err := ComputeDetailed(data1, data2, WithPeakMode(PeakBitDepth()))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if bitDepth.PSNR != standard.PSNR || bitDepth.Peak != 255 {
+		t.Errorf("Expected bit depth peak to match the default, got %+v", bitDepth)
+	}
+
+	fixed, err := ComputeDetailed(data1, data2, WithPeakMode(PeakFixed(100)), WithEdgeWeighting())
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	want := 10 * math.Log10(100*100/fixed.MSE)
+	if fixed.Peak != 100 || math.Abs(fixed.PSNR-want) > 1e-9 {
+		t.Errorf("Expected peak 100 and PSNR %.6f, got %f and %.6f", want, fixed.Peak, fixed.PSNR)
+	}
+	wantWeighted := 10 * math.Log10(100*100/fixed.WeightedMSE)
+	if math.Abs(fixed.WeightedPSNR-wantWeighted) > 1e-9 {
+		t.Errorf("Expected weighted PSNR %.6f, got %.6f", wantWeighted, fixed.WeightedPSNR)
+	}
+
+	if _, err := ComputeDetailed(data1, data2, WithPeakMode(PeakFixed(0))); err == nil {
+		t.Error("Expected error for zero peak")
+	}
+	if _, err := ComputeDetailed(data1, data2, WithPeakMode(PeakFixed(1)), WithCompatibility(CompatibilityOpenCV)); err == nil {
+		t.Error("Expected error when combining a peak mode with a compatibility mode")
+	}
+}
+
+func TestPeakReferenceMax(t *testing.T) {
+	// A dark reference whose brightest sample is 80
+	img1 := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	img2 := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			img1.SetRGBA(x, y, color.RGBA{uint8(x * 10), 20, uint8(y * 5), 255})
+			img2.SetRGBA(x, y, color.RGBA{uint8(x*10 + 2), 20, uint8(y * 5), 255})
+		}
+	}
+	img1.SetRGBA(3, 3, color.RGBA{10, 80, 10, 255})
+	img2.SetRGBA(3, 3, color.RGBA{12, 80, 10, 255})
+
+	result, err := ComputeDetailed(encodePNG(t, img1), encodePNG(t, img2), WithPeakMode(PeakReferenceMax()))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if result.Peak != 80 {
+		t.Errorf("Expected peak 80, got %f", result.Peak)
+	}
+	want := 10 * math.Log10(80*80/result.MSE)
+	if math.Abs(result.PSNR-want) > 1e-9 {
+		t.Errorf("Expected PSNR %.6f, got %.6f", want, result.PSNR)
+	}
+
+	identical, err := ComputeDetailed(encodePNG(t, img1), encodePNG(t, img1), WithPeakMode(PeakReferenceMax()))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if !math.IsInf(identical.PSNR, 1) {
+		t.Errorf("Expected Inf for identical images, got %f", identical.PSNR)
+	}
+}
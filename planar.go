@@ -0,0 +1,129 @@
+package psnr
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// PlanarSample is the sample type of a PlanarImage.
+type PlanarSample interface {
+	uint8 | uint16 | float32
+}
+
+// PlanarImage is an image stored as one plane per channel, for data that
+// image.Image cannot hold, such as multispectral captures with more than
+// four bands.
+type PlanarImage[T PlanarSample] struct {
+	Width, Height int
+	// Planes holds one slice of Width*Height samples per channel, row by
+	// row.
+	Planes [][]T
+}
+
+// PlanarResult is the comparison of two planar images.
+type PlanarResult struct {
+	// PSNR and MSE cover all planes, with MSE on the scale of the samples.
+	PSNR float64
+	MSE  float64
+	// Peak is the peak signal value PSNR is measured against.
+	Peak float64
+	// Planes holds the result of each plane in order, named by its index.
+	Planes []PlaneResult
+}
+
+// ComparePlanar calculates PSNR between two planar images over all of their
+// channels and for each plane. peak is the largest possible sample value; 0
+// selects 255 for uint8, 65535 for uint16 and 1 for float32 samples, which
+// are taken to be normalized.
+func ComparePlanar[T PlanarSample](ctx context.Context, img1, img2 *PlanarImage[T], peak float64) (*PlanarResult, error) {
+	if err := checkPlanar(img1, img2); err != nil {
+		return nil, err
+	}
+	if peak == 0 {
+		peak = defaultPlanarPeak[T]()
+	}
+	if peak < 0 || math.IsInf(peak, 0) || math.IsNaN(peak) {
+		return nil, fmt.Errorf("invalid peak: %v", peak)
+	}
+
+	result := &PlanarResult{Peak: peak, Planes: make([]PlaneResult, len(img1.Planes))}
+	var total compensatedSum
+	for i := range img1.Planes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		mse := planarMSE(img1.Planes[i], img2.Planes[i], img1.Width)
+		result.Planes[i] = PlaneResult{Name: strconv.Itoa(i), PSNR: planarPSNR(mse, peak), MSE: mse}
+		total.add(mse)
+	}
+	result.MSE = total.value() / float64(len(img1.Planes))
+	result.PSNR = planarPSNR(result.MSE, peak)
+	return result, nil
+}
+
+// checkPlanar reports planar images that cannot be compared.
+func checkPlanar[T PlanarSample](img1, img2 *PlanarImage[T]) error {
+	if img1.Width <= 0 || img1.Height <= 0 {
+		return fmt.Errorf("invalid planar image size: %dx%d", img1.Width, img1.Height)
+	}
+	if img1.Width != img2.Width || img1.Height != img2.Height {
+		return fmt.Errorf("images have different dimensions: %dx%d vs %dx%d",
+			img1.Width, img1.Height, img2.Width, img2.Height)
+	}
+	if len(img1.Planes) == 0 || len(img1.Planes) != len(img2.Planes) {
+		return fmt.Errorf("images have different channel counts: %d vs %d", len(img1.Planes), len(img2.Planes))
+	}
+	size := img1.Width * img1.Height
+	for i := range img1.Planes {
+		if len(img1.Planes[i]) != size || len(img2.Planes[i]) != size {
+			return fmt.Errorf("plane %d has %d and %d samples, want %d", i, len(img1.Planes[i]), len(img2.Planes[i]), size)
+		}
+	}
+	return nil
+}
+
+// planarMSE computes the mean squared error between two planes. Integer
+// samples are summed exactly by integerMSE; float samples are summed in
+// float64 row by row, combining the rows with compensated summation.
+func planarMSE[T PlanarSample](plane1, plane2 []T, width int) float64 {
+	switch p1 := any(plane1).(type) {
+	case []uint8:
+		return integerMSE(p1, any(plane2).([]uint8))
+	case []uint16:
+		return integerMSE(p1, any(plane2).([]uint16))
+	}
+
+	var sum compensatedSum
+	for start := 0; start < len(plane1); start += width {
+		row1, row2 := plane1[start:start+width], plane2[start:start+width]
+		var rowSum float64
+		for x := range row1 {
+			diff := float64(row1[x]) - float64(row2[x])
+			rowSum += diff * diff
+		}
+		sum.add(rowSum)
+	}
+	return sum.value() / float64(len(plane1))
+}
+
+// planarPSNR converts an MSE to PSNR against peak.
+func planarPSNR(mse, peak float64) float64 {
+	if mse == 0 {
+		return math.Inf(1)
+	}
+	return 10 * math.Log10(peak*peak/mse)
+}
+
+// defaultPlanarPeak returns the peak of samples of type T.
+func defaultPlanarPeak[T PlanarSample]() float64 {
+	var zero T
+	switch any(zero).(type) {
+	case uint8:
+		return 255
+	case uint16:
+		return 65535
+	}
+	return 1
+}
@@ -0,0 +1,60 @@
+package psnr
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestComparePlanar(t *testing.T) {
+	ctx := context.Background()
+	// Five bands of 2x2 samples; only band 3 differs
+	img1 := &PlanarImage[uint16]{Width: 2, Height: 2, Planes: make([][]uint16, 5)}
+	img2 := &PlanarImage[uint16]{Width: 2, Height: 2, Planes: make([][]uint16, 5)}
+	for i := range img1.Planes {
+		img1.Planes[i] = []uint16{1000, 2000, 3000, 4000}
+		img2.Planes[i] = []uint16{1000, 2000, 3000, 4000}
+	}
+	img2.Planes[3] = []uint16{1100, 2000, 3000, 4000}
+
+	result, err := ComparePlanar(ctx, img1, img2, 0)
+	if err != nil {
+		t.Fatalf("ComparePlanar failed: %v", err)
+	}
+	if result.Peak != 65535 || len(result.Planes) != 5 {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+	if !math.IsInf(result.Planes[0].PSNR, 1) || result.Planes[3].MSE != 2500 || result.Planes[3].Name != "3" {
+		t.Errorf("Unexpected planes: %+v", result.Planes)
+	}
+	if want := 10 * math.Log10(65535.0*65535/500); result.MSE != 500 || math.Abs(result.PSNR-want) > 1e-9 {
+		t.Errorf("Expected %v dB at MSE 500, got %v dB at %v", want, result.PSNR, result.MSE)
+	}
+
+	f1 := &PlanarImage[float32]{Width: 1, Height: 1, Planes: [][]float32{{0.5}}}
+	f2 := &PlanarImage[float32]{Width: 1, Height: 1, Planes: [][]float32{{0.25}}}
+	result, err = ComparePlanar(ctx, f1, f2, 0)
+	if err != nil {
+		t.Fatalf("ComparePlanar failed: %v", err)
+	}
+	if want := 10 * math.Log10(1/0.0625); result.Peak != 1 || math.Abs(result.PSNR-want) > 1e-9 {
+		t.Errorf("Expected %v dB against a peak of 1, got %+v", want, result)
+	}
+	if result, _ := ComparePlanar(ctx, f1, f2, 2); math.Abs(result.PSNR-10*math.Log10(4/0.0625)) > 1e-9 {
+		t.Errorf("Expected an explicit peak to apply, got %+v", result)
+	}
+
+	invalid := []*PlanarImage[uint16]{
+		{Width: 2, Height: 2, Planes: img1.Planes[:4]},
+		{Width: 4, Height: 1, Planes: img1.Planes},
+		{Width: 2, Height: 2, Planes: [][]uint16{{1}, {1}, {1}, {1}, {1}}},
+	}
+	for i, img := range invalid {
+		if _, err := ComparePlanar(ctx, img1, img, 0); err == nil {
+			t.Errorf("Expected error for invalid image %d", i)
+		}
+	}
+	if _, err := ComparePlanar(ctx, img1, img2, -1); err == nil {
+		t.Error("Expected error for a negative peak")
+	}
+}
@@ -0,0 +1,90 @@
+package psnr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+)
+
+// pngSignature starts every PNG file.
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// PNG color types from the IHDR chunk.
+const (
+	pngColorGray      = 0
+	pngColorRGB       = 2
+	pngColorPaletted  = 3
+	pngColorGrayAlpha = 4
+	pngColorRGBA      = 6
+)
+
+// pngChunk is one chunk of a PNG stream; data aliases the input.
+type pngChunk struct {
+	typ  string
+	data []byte
+}
+
+var errInvalidPNG = errors.New("invalid PNG stream")
+
+// readPNGChunks splits a PNG stream into chunks without validating CRCs. It
+// stops after IEND. When the stream is truncated it returns the chunks read
+// so far, the last one holding whatever data is left, along with an error.
+func readPNGChunks(data []byte) ([]pngChunk, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, errInvalidPNG
+	}
+
+	var chunks []pngChunk
+	rest := data[len(pngSignature):]
+	for len(rest) >= 8 {
+		length := binary.BigEndian.Uint32(rest[:4])
+		typ := string(rest[4:8])
+		if uint64(len(rest)) < 12+uint64(length) {
+			end := min(uint64(len(rest)), 8+uint64(length))
+			chunks = append(chunks, pngChunk{typ: typ, data: rest[8:end]})
+			return chunks, errInvalidPNG
+		}
+		chunks = append(chunks, pngChunk{typ: typ, data: rest[8 : 8+length]})
+		rest = rest[12+length:]
+		if typ == "IEND" {
+			return chunks, nil
+		}
+	}
+	return chunks, errInvalidPNG
+}
+
+// pngHasAlphaChannel reports whether a PNG stream declares an alpha channel,
+// either through its color type or a tRNS chunk.
+func pngHasAlphaChannel(data []byte) bool {
+	chunks, _ := readPNGChunks(data)
+	for _, c := range chunks {
+		switch c.typ {
+		case "IHDR":
+			if len(c.data) >= 10 && (c.data[9] == pngColorGrayAlpha || c.data[9] == pngColorRGBA) {
+				return true
+			}
+		case "tRNS":
+			return true
+		case "IDAT":
+			// tRNS must precede the image data
+			return false
+		}
+	}
+	return false
+}
+
+// writePNGChunk appends a chunk with its length and CRC to buf.
+func writePNGChunk(buf *bytes.Buffer, typ string, data []byte) {
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(len(data)))
+	copy(header[4:], typ)
+	buf.Write(header[:])
+	buf.Write(data)
+
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(data)
+	binary.BigEndian.PutUint32(header[:4], crc.Sum32())
+	buf.Write(header[:4])
+}
@@ -0,0 +1,47 @@
+package psnr
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestPNGHasAlphaChannel(t *testing.T) {
+	rect := image.Rect(0, 0, 2, 2)
+	opaquePalette := color.Palette{color.RGBA{0, 0, 0, 255}, color.RGBA{255, 255, 255, 255}}
+	transparentPalette := color.Palette{color.RGBA{0, 0, 0, 0}, color.RGBA{255, 255, 255, 255}}
+
+	tests := []struct {
+		name string
+		img  image.Image
+		want bool
+	}{
+		{"gray", image.NewGray(rect), false},
+		{"opaque RGBA", opaqueRGBA(rect), false},
+		{"translucent NRGBA", image.NewNRGBA(rect), true},
+		{"opaque palette", image.NewPaletted(rect, opaquePalette), false},
+		{"palette with tRNS", image.NewPaletted(rect, transparentPalette), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pngHasAlphaChannel(encodePNG(t, tt.img)); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	if pngHasAlphaChannel([]byte("not a png")) {
+		t.Error("Expected false for invalid data")
+	}
+}
+
+// opaqueRGBA returns a fully opaque image, which the PNG encoder writes
+// without an alpha channel.
+func opaqueRGBA(r image.Rectangle) *image.RGBA {
+	img := image.NewRGBA(r)
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 255
+	}
+	return img
+}
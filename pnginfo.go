@@ -0,0 +1,54 @@
+package psnr
+
+import (
+	"bytes"
+	"errors"
+)
+
+// PNGInfo describes how a PNG file was encoded.
+type PNGInfo struct {
+	// ColorType names the IHDR color type: "gray", "rgb", "paletted",
+	// "gray+alpha" or "rgba".
+	ColorType string
+	// BitDepth is the number of bits per sample, or per palette index for
+	// paletted files.
+	BitDepth int
+	// PaletteSize is the number of PLTE entries, or zero without a palette.
+	PaletteSize int
+	// Transparency reports whether the file has an alpha channel or a tRNS
+	// chunk.
+	Transparency bool
+}
+
+// ErrNotPNG is returned by InspectPNG for data that is not a PNG stream.
+var ErrNotPNG = errors.New("not a PNG stream")
+
+// InspectPNG reports the color type, bit depth and palette size of a PNG
+// file from its chunks, without decoding it, so PSNR drops can be traced to
+// palette quantization.
+func InspectPNG(data []byte) (*PNGInfo, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, ErrNotPNG
+	}
+	chunks, _ := readPNGChunks(data)
+	if len(chunks) == 0 || chunks[0].typ != "IHDR" || len(chunks[0].data) < 13 {
+		return nil, errors.New("PNG stream has no header")
+	}
+	header := chunks[0].data
+	colorType, ok := pngColorModels[header[9]]
+	if !ok {
+		return nil, errors.New("PNG stream has an invalid color type")
+	}
+	info := &PNGInfo{
+		ColorType:    colorType,
+		BitDepth:     int(header[8]),
+		Transparency: pngHasAlphaChannel(data),
+	}
+	for _, c := range chunks {
+		if c.typ == "PLTE" {
+			info.PaletteSize = len(c.data) / 3
+			break
+		}
+	}
+	return info, nil
+}
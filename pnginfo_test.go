@@ -0,0 +1,59 @@
+package psnr
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestInspectPNG(t *testing.T) {
+	encode := func(img image.Image) []byte {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	palette := color.Palette{color.Black, color.White, color.Gray{Y: 128}, color.Transparent}
+	paletted := encode(image.NewPaletted(image.Rect(0, 0, 4, 4), palette))
+	nrgba := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	nrgba.Pix[3] = 128
+
+	tests := []struct {
+		data []byte
+		want PNGInfo
+	}{
+		{paletted, PNGInfo{ColorType: "paletted", BitDepth: 2, PaletteSize: 4, Transparency: true}},
+		{encode(image.NewGray(image.Rect(0, 0, 4, 4))), PNGInfo{ColorType: "gray", BitDepth: 8}},
+		{encode(image.NewGray16(image.Rect(0, 0, 4, 4))), PNGInfo{ColorType: "gray", BitDepth: 16}},
+		{encode(nrgba), PNGInfo{ColorType: "rgba", BitDepth: 8, Transparency: true}},
+	}
+	for i, tt := range tests {
+		info, err := InspectPNG(tt.data)
+		if err != nil {
+			t.Fatalf("InspectPNG failed: %v", err)
+		}
+		if *info != tt.want {
+			t.Errorf("Test %d: expected %+v, got %+v", i, tt.want, *info)
+		}
+	}
+
+	result, err := ComputeDetailed(paletted, paletted, WithInputInfo())
+	if err != nil {
+		t.Fatalf("ComputeDetailed failed: %v", err)
+	}
+	if in := result.Inputs[0]; in.ColorModel != "paletted" || in.BitDepth != 2 || in.PaletteSize != 4 {
+		t.Errorf("Unexpected input: %+v", in)
+	}
+
+	if _, err := InspectPNG([]byte{0xff, 0xd8}); !errors.Is(err, ErrNotPNG) {
+		t.Errorf("Expected ErrNotPNG, got %v", err)
+	}
+	if _, err := InspectPNG(pngSignature); err == nil {
+		t.Error("Expected error for a PNG without a header")
+	}
+}
@@ -5,13 +5,17 @@ package psnr
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"image"
 	"image/color"
 	"image/jpeg"
 	"image/png"
+	"io/fs"
 	"math"
 	"os"
+	"time"
 )
 
 // ComputeFiles calculates PSNR between two image files.
@@ -29,85 +33,757 @@ func ComputeFiles(path1, path2 string) (float64, error) {
 	return Compute(data1, data2)
 }
 
+// ComputeFS calculates PSNR between two image files read from fsys, such as
+// an embed.FS, a zip archive or a test fixture filesystem.
+func ComputeFS(fsys fs.FS, path1, path2 string) (float64, error) {
+	data1, err := fs.ReadFile(fsys, path1)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", path1, err)
+	}
+
+	data2, err := fs.ReadFile(fsys, path2)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", path2, err)
+	}
+
+	return Compute(data1, data2)
+}
+
 // Compute calculates PSNR between two images provided as byte slices.
 func Compute(image1Bytes, image2Bytes []byte) (float64, error) {
-	img1, format1, err := image.Decode(bytes.NewReader(image1Bytes))
+	result, err := ComputeDetailed(image1Bytes, image2Bytes)
+	if err != nil {
+		return 0, err
+	}
+	return result.PSNR, nil
+}
+
+// ComputeFilesDetailed calculates PSNR between two image files and returns
+// the detailed result.
+func ComputeFilesDetailed(path1, path2 string, opts ...Option) (*Result, error) {
+	data1, err := os.ReadFile(path1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path1, err)
+	}
+
+	data2, err := os.ReadFile(path2)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path2, err)
+	}
+
+	return ComputeDetailed(data1, data2, opts...)
+}
+
+// ComputeDetailed calculates PSNR between two images provided as byte slices,
+// applying the given options, and returns the detailed result.
+func ComputeDetailed(image1Bytes, image2Bytes []byte, opts ...Option) (*Result, error) {
+	return ComputeContext(context.Background(), image1Bytes, image2Bytes, opts...)
+}
+
+// ComputeContext is like ComputeDetailed but stops early and returns the
+// context's error when ctx is canceled. Cancellation is checked between
+// bands of rows, so large comparisons respond promptly.
+func ComputeContext(ctx context.Context, image1Bytes, image2Bytes []byte, opts ...Option) (*Result, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return computeWith(ctx, image1Bytes, image2Bytes, o)
+}
+
+// computeWith is ComputeContext with validated options.
+func computeWith(ctx context.Context, image1Bytes, image2Bytes []byte, o *options) (*Result, error) {
+	if result, ok, err := identicalResult(image1Bytes, image2Bytes, o); ok || err != nil {
+		return result, err
+	}
+	if o.timeout > 0 {
+		return computeWithTimeout(ctx, image1Bytes, image2Bytes, o)
+	}
+	return computeDecoded(ctx, image1Bytes, image2Bytes, o)
+}
+
+// CompareImages calculates PSNR between two already decoded images, such as
+// video frames or images produced in memory, applying the given options.
+// Alpha is compared when either image has translucent pixels.
+func CompareImages(ctx context.Context, img1, img2 image.Image, opts ...Option) (*Result, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return compareImagesWith(ctx, img1, img2, o)
+}
+
+// compareImagesWith is CompareImages with validated options.
+func compareImagesWith(ctx context.Context, img1, img2 image.Image, o *options) (*Result, error) {
+	if err := hashPrefilter(img1, img2, o); err != nil {
+		return nil, err
+	}
+	return compare(ctx, &decoded{img: img1, format: memoryFormat}, &decoded{img: img2, format: memoryFormat}, o)
+}
+
+// memoryFormat is the format of images passed to CompareImages, which have
+// no encoded form.
+const memoryFormat = "memory"
+
+// identicalResult skips the second decode and the comparison when both
+// inputs are byte-identical and the options cannot make the result anything
+// but +Inf. It still decodes the first image, so invalid, truncated or
+// oversized images fail as usual, and reports false when the full pipeline
+// must run.
+func identicalResult(image1Bytes, image2Bytes []byte, o *options) (*Result, bool, error) {
+	if o.needsRGBA() || o.peak.kind == peakReferenceMax || o.hdr != nil || o.inputInfo || o.borderCrop || o.frequency > 0 ||
+		(o.compat != CompatibilityDefault && o.compat != CompatibilityImageMagick) {
+		return nil, false, nil
+	}
+	if !bytes.Equal(image1Bytes, image2Bytes) {
+		return nil, false, nil
+	}
+	start := time.Now()
+	d, err := o.decode(image1Bytes)
+	if err != nil {
+		return nil, false, &DecodeError{Input: "first image", Err: err}
+	}
+	decodeTime := time.Since(start)
+	// Recovered truncated images are compared over their decoded rows only
+	if d.partialRows > 0 {
+		return nil, false, nil
+	}
+	size := d.img.Bounds().Size()
+
+	o.debug("inputs are byte-identical, skipping comparison", "bytes", len(image1Bytes))
+	peak := maxSampleValue
+	if o.peak.kind == peakFixed {
+		peak = o.peak.value
+	}
+	result := &Result{PSNR: math.Inf(1), Peak: peak, Coverage: 1, BlurSigma: o.blurSigma}
+	if o.diff {
+		result.Diff = &DiffReport{}
+	}
+	if o.metadata {
+		result.Metadata = diffMetadata(image1Bytes, image2Bytes)
+	}
+	if o.evalLongEdge > 0 {
+		result.EvaluationSize = evaluationSize(size, o.evalLongEdge)
+	}
+	if o.orientation {
+		result.Orientation = OrientationNormal
+	}
+	if o.stats {
+		result.Stats = &Stats{Path: pathIdentical, DecodeTime: decodeTime, Pixels: size.X * size.Y, Decoders: [2]string{d.backendName(), d.backendName()}}
+	}
+	return result, true, nil
+}
+
+// computeDecoded decodes both images and compares them.
+func computeDecoded(ctx context.Context, image1Bytes, image2Bytes []byte, o *options) (*Result, error) {
+	var allocated uint64
+	if o.stats {
+		allocated = allocatedBytes()
+	}
+	start := time.Now()
+	d1, d2, err := decodePair(image1Bytes, image2Bytes, o)
+	if err != nil {
+		return nil, err
+	}
+	decodeTime := time.Since(start)
+	o.debug("decoded images",
+		"format1", d1.format, "type1", fmt.Sprintf("%T", d1.img),
+		"format2", d2.format, "type2", fmt.Sprintf("%T", d2.img),
+		"duration", decodeTime)
+
+	if err := hashPrefilter(d1.img, d2.img, o); err != nil {
+		return nil, err
+	}
+	result, err := compare(ctx, d1, d2, o)
+	if err != nil {
+		return nil, err
+	}
+	if o.stats {
+		result.Stats.DecodeTime = decodeTime
+		result.Stats.AllocatedBytes = allocatedBytes() - allocated
+	}
+	return result, nil
+}
+
+// decoded is a decoded input image together with its encoded form.
+type decoded struct {
+	img    image.Image
+	format string
+	data   []byte
+	// partialRows is the number of rows decoded from real data when
+	// WithTolerantDecode recovered a truncated image, and zero otherwise.
+	partialRows int
+	// grayAlpha is set for 8-bit gray+alpha PNGs decoded by image/png.
+	grayAlpha bool
+	// backend names the decoder that produced img, as reported in
+	// Stats.Decoders, or is empty for images passed in decoded.
+	backend string
+}
+
+// backendName returns the decoder reported for d in Stats.Decoders.
+func (d *decoded) backendName() string {
+	if d.backend == "" {
+		return memoryFormat
+	}
+	return d.backend
+}
+
+// withImage returns a copy of d holding img, for steps that transform the
+// pixels but keep the encoded form and what the decoder reported.
+func (d *decoded) withImage(img image.Image) *decoded {
+	c := *d
+	c.img = img
+	return &c
+}
+
+// decodePair decodes both images as configured by o, wrapping errors with
+// the image position.
+func decodePair(image1Bytes, image2Bytes []byte, o *options) (*decoded, *decoded, error) {
+	d1, err := o.decode(image1Bytes)
+	if err != nil {
+		return nil, nil, &DecodeError{Input: "first image", Err: err}
+	}
+
+	d2, err := o.decode(image2Bytes)
+	if err != nil {
+		return nil, nil, &DecodeError{Input: "second image", Err: err}
+	}
+
+	return d1, d2, nil
+}
+
+// compare runs the comparison pipeline on two decoded images, restricted to
+// the decoded area of truncated ones.
+func compare(ctx context.Context, d1, d2 *decoded, o *options) (*Result, error) {
+	if o.strict {
+		if err := checkStrict(d1, d2); err != nil {
+			return nil, err
+		}
+	}
+
+	var allocated uint64
+	var start time.Time
+	var decoders [2]string
+	if o.stats {
+		allocated = allocatedBytes()
+		start = time.Now()
+		decoders = [2]string{d1.backendName(), d2.backendName()}
+	}
+
+	var inputs []InputInfo
+	if o.inputInfo {
+		inputs = []InputInfo{describeInput(d1), describeInput(d2)}
+	}
+
+	coverage := 1.0
+	if d1.partialRows > 0 || d2.partialRows > 0 {
+		var err error
+		if d1, d2, coverage, err = cropPartial(d1, d2); err != nil {
+			return nil, err
+		}
+	}
+
+	if o.toneMap != nil {
+		d1, d2 = toneMap(d1, o.toneMap.Transfer1, o.toneMap), toneMap(d2, o.toneMap.Transfer2, o.toneMap)
+	}
+
+	if o.background != nil {
+		d1, d2 = flatten(d1, o.background), flatten(d2, o.background)
+	}
+
+	if o.preprocess != nil {
+		var err error
+		if d1, d2, err = preprocess(d1, d2, o.preprocess); err != nil {
+			return nil, err
+		}
+	}
+
+	var orientation Orientation
+	if o.orientation {
+		var err error
+		if d2, orientation, err = matchOrientation(d1, d2, o.alphaMode); err != nil {
+			return nil, err
+		}
+	}
+
+	var borders []Margins
+	if o.borderCrop {
+		d1, d2, borders = cropBorders(d1, d2)
+	}
+
+	var cropOffset image.Point
+	cropped := o.cropSearch && d1.img.Bounds().Size() != d2.img.Bounds().Size()
+	if cropped {
+		var err error
+		if d1, d2, cropOffset, err = matchCrop(d1, d2); err != nil {
+			return nil, err
+		}
+	}
+
+	var resampled bool
+	if o.evalLongEdge > 0 {
+		s1, s2 := d1, d2
+		d1, d2 = downscaleDecoded(d1, o.evalLongEdge), downscaleDecoded(d2, o.evalLongEdge)
+		resampled = d1 != s1 || d2 != s2
+	}
+
+	if o.blurSigma > 0 {
+		d1 = d1.withImage(gaussianBlur(d1.img, o.blurSigma))
+		d2 = d2.withImage(gaussianBlur(d2.img, o.blurSigma))
+	}
+
+	result, err := compareImages(ctx, d1, d2, o)
 	if err != nil {
-		return 0, fmt.Errorf("failed to decode first image: %w", err)
+		return nil, err
+	}
+	var warnings []Warning
+	if coverage < 1 {
+		warnings = append(warnings, newWarning(WarningPartialDecode, "only %.1f%% of the rows were decoded and compared", 100*coverage))
+	}
+	if orientation > OrientationNormal {
+		warnings = append(warnings, newWarning(WarningReoriented, "the second image was transformed by %v", orientation))
+	}
+	if len(borders) == 2 && !(borders[0].IsZero() && borders[1].IsZero()) {
+		warnings = append(warnings, newWarning(WarningCropped, "border bars were cropped: %+v in the first image, %+v in the second", borders[0], borders[1]))
+	}
+	if cropped {
+		warnings = append(warnings, newWarning(WarningCropped, "the larger image was cropped to the smaller at offset %v", cropOffset))
+	}
+	if resampled {
+		warnings = append(warnings, newWarning(WarningResampled, "both images were downscaled to %v", d1.img.Bounds().Size()))
+	}
+	result.Warnings = append(warnings, result.Warnings...)
+	result.Coverage = coverage
+	result.BlurSigma = o.blurSigma
+	if o.evalLongEdge > 0 {
+		result.EvaluationSize = d1.img.Bounds().Size()
+	}
+	result.Orientation = orientation
+	result.Borders = borders
+	if o.cropSearch {
+		result.Offset = cropOffset
+	}
+	if o.diff {
+		result.Diff = diffReport(d1.img, d2.img, o.alphaMode, detectAlpha(d1, d2))
+	}
+	if o.metadata {
+		result.Metadata = diffMetadata(d1.data, d2.data)
+	}
+	if o.frequency > 0 {
+		result.Frequency = frequencySplit(d1.img, d2.img, o.frequency)
+	}
+	result.Inputs = inputs
+	if o.stats {
+		// The compatibility modes return before compareImages sets a path
+		if result.Stats == nil {
+			result.Stats = &Stats{Path: compatPath(o.compat)}
+		}
+		stats := result.Stats
+		stats.CompareTime = time.Since(start)
+		stats.Decoders = decoders
+		stats.Pixels = d1.img.Bounds().Dx() * d1.img.Bounds().Dy()
+		if seconds := stats.CompareTime.Seconds(); seconds > 0 {
+			stats.PixelsPerSecond = float64(stats.Pixels) / seconds
+		}
+		stats.AllocatedBytes = allocatedBytes() - allocated
+	}
+	return result, nil
+}
+
+// preprocess applies fn to both decoded images. The encoded form is kept, so
+// alpha detection still follows the source format.
+func preprocess(d1, d2 *decoded, fn func(image.Image) image.Image) (*decoded, *decoded, error) {
+	img1, img2 := fn(d1.img), fn(d2.img)
+	if img1 == nil || img2 == nil {
+		return nil, nil, fmt.Errorf("preprocess returned no image")
+	}
+	return d1.withImage(img1), d2.withImage(img2), nil
+}
+
+// compareImages runs the comparison pipeline proper.
+func compareImages(ctx context.Context, d1, d2 *decoded, o *options) (*Result, error) {
+	img1, img2 := d1.img, d2.img
+	bounds1 := img1.Bounds()
+	if err := checkDimensions(img1, img2); err != nil {
+		return nil, err
+	}
+
+	if o.compat == CompatibilityFFmpeg {
+		// ffmpeg compares the Y'CbCr planes themselves
+		return compareFFmpeg(ctx, img1, img2, o)
+	}
+
+	if o.convertsYCbCr() {
+		d1, d2 = convertYCbCr(d1, o), convertYCbCr(d2, o)
+		img1, img2 = d1.img, d2.img
+		bounds1 = img1.Bounds()
+	}
+
+	switch o.compat {
+	case CompatibilityImageMagick:
+		return compareImageMagick(ctx, d1, d2, o)
+	case CompatibilityOpenCV:
+		return compareOpenCV(ctx, img1, img2, o)
+	}
+
+	hasAlpha, alphaSampled := alphaPresence(d1, d2)
+	channelCount := 3
+	if hasAlpha {
+		channelCount = 4
 	}
+	img1, img2 = normalizeAlpha(img1, o.alphaMode), normalizeAlpha(img2, o.alphaMode)
 
-	img2, format2, err := image.Decode(bytes.NewReader(image2Bytes))
+	start := time.Now()
+	var rgba1, rgba2 *image.RGBA
+	if o.needsRGBA() || o.deterministic {
+		rgba1, rgba2 = comparisonRGBA(img1, img2, o.alphaMode)
+	}
+	kernel1, kernel2 := img1, img2
+	if o.deterministic {
+		// Every path then runs the same integer kernel on canonical samples
+		kernel1, kernel2 = rgba1, rgba2
+	}
+
+	metrics, imageMetrics, err := newMetrics(o.metrics)
 	if err != nil {
-		return 0, fmt.Errorf("failed to decode second image: %w", err)
+		return nil, err
+	}
+	visitors := make([]pixelVisitor, 0, len(metrics))
+	for _, m := range metrics {
+		visitors = append(visitors, metricVisitor{m})
+	}
+	var histogram *ErrorHistogram
+	if o.histogram {
+		histogram = &ErrorHistogram{}
+		visitors = append(visitors, histogramVisitor{histogram: histogram, channelCount: channelCount})
+	}
+	var components *componentVisitor
+	if o.components {
+		v := newComponentVisitor(o.matrix)
+		components = &v
+		visitors = append(visitors, v)
+	}
+
+	var result *Result
+	var path string
+	if o.maxShift > 0 {
+		result = compareAligned(rgba1, rgba2, hasAlpha, o.maxShift)
+		path = pathAligned
+		o.debug("computed aligned MSE", "offset", result.Offset, "alpha", hasAlpha, "duration", time.Since(start))
+	} else if len(visitors) > 0 {
+		sumSquaredDiff, err := fusedPass(ctx, rgba1, rgba2, rgba1.Rect, image.Point{}, hasAlpha, visitors, o.progress)
+		if err != nil {
+			return nil, err
+		}
+		totalSamples := uint64(bounds1.Dx() * bounds1.Dy() * channelCount)
+		result = newResult(sumSquaredDiff, totalSamples)
+		path = pathFused
+		o.debug("computed MSE", "path", path, "alpha", hasAlpha, "duration", time.Since(start))
+	} else {
+		var kernel rowKernel
+		kernel, path = selectDecodedKernel(d1, d2, kernel1, kernel2, hasAlpha)
+		sumSquaredDiff, err := sumSquaredDiff(ctx, kernel, bounds1.Dy(), o.progress)
+		if err != nil {
+			return nil, err
+		}
+		if path == pathGeneric {
+			o.debug("no fast path for image types, using generic path",
+				"type1", fmt.Sprintf("%T", kernel1), "type2", fmt.Sprintf("%T", kernel2))
+		}
+		totalSamples := uint64(bounds1.Dx() * bounds1.Dy() * channelCount)
+		result = newResult(sumSquaredDiff, totalSamples)
+		o.debug("computed MSE", "path", path, "alpha", hasAlpha, "duration", time.Since(start))
+	}
+
+	weighted := false
+	if rgba1 != nil {
+		overlap := rgba1.Rect.Intersect(rgba2.Rect.Sub(result.Offset))
+		origin2 := overlap.Min.Add(result.Offset)
+
+		// Alignment searched without visitors, so feed them the final overlap
+		if o.maxShift > 0 && len(visitors) > 0 {
+			if _, err := fusedPass(ctx, rgba1, rgba2, overlap, origin2, hasAlpha, visitors, nil); err != nil {
+				return nil, err
+			}
+		}
+
+		if o.normalize {
+			result.Normalized = fitNormalization(rgba1, rgba2, overlap, origin2, hasAlpha)
+		}
+		if o.ditherBox > 0 {
+			result.Dither = ditherResult(rgba1, rgba2, overlap, origin2, hasAlpha, o.ditherBox)
+		}
+		if o.colorSpace != ColorSpaceRGB {
+			result.ColorSpace = colorSpaceResult(rgba1, rgba2, overlap, origin2, o.colorSpace)
+		}
+		var excluded []bool
+		if o.ignoreColor != nil {
+			excluded = keyMask(rgba1, overlap, o.ignoreColor)
+			result.IgnoredPixels = countMask(excluded)
+		}
+		if len(o.regions) > 0 {
+			excluded = unionMask(excluded, regionMask(o.regions, overlap))
+			result.Regions = regionResults(rgba1, rgba2, overlap, origin2, hasAlpha, o.regions)
+		}
+		if o.antiAliasing != AntiAliasingOff {
+			var aliased []bool
+			result.AntiAliasing, aliased = classifyAntiAliasing(rgba1, rgba2, overlap, origin2, excluded)
+			if o.antiAliasing == AntiAliasingExclude {
+				excluded = unionMask(excluded, aliased)
+			}
+		}
+		if excluded != nil {
+			if result.MSE, err = maskedMSE(rgba1, rgba2, overlap, origin2, hasAlpha, excluded); err != nil {
+				return nil, err
+			}
+			result.PSNR = psnrFromMSE(result.MSE)
+		}
+
+		weights, err := buildWeights(o, rgba1)
+		if err != nil {
+			return nil, err
+		}
+		if weights != nil {
+			if result.WeightedMSE, err = weightedMSE(rgba1, rgba2, overlap, origin2, hasAlpha, weights); err != nil {
+				return nil, err
+			}
+			result.WeightedPSNR = psnrFromMSE(result.WeightedMSE)
+			weighted = true
+		}
+	}
+
+	if o.hdr != nil {
+		result.HDR = hdrResult(d1, d2, img1, img2, result.Offset, o.hdr)
 	}
 
+	if o.peak.kind != peakBitDepth {
+		peak := o.peak.resolve(img1)
+		result.Peak = peak
+		result.PSNR = rescalePSNR(result.PSNR, peak)
+		if result.Normalized != nil {
+			result.Normalized.PSNR = rescalePSNR(result.Normalized.PSNR, peak)
+		}
+		if result.Dither != nil {
+			result.Dither.PSNR = rescalePSNR(result.Dither.PSNR, peak)
+		}
+		if weighted {
+			result.WeightedPSNR = rescalePSNR(result.WeightedPSNR, peak)
+		}
+		for i := range result.Regions {
+			result.Regions[i].PSNR = rescalePSNR(result.Regions[i].PSNR, peak)
+		}
+	}
+	if components != nil {
+		result.Components = components.result()
+		for _, plane := range []*PlaneResult{&result.Components.Luma, &result.Components.Chroma, &result.Components.Alpha} {
+			plane.PSNR = rescalePSNR(plane.PSNR, result.Peak)
+		}
+	}
+
+	if len(metrics)+len(imageMetrics) > 0 {
+		result.Metrics = make(map[string]float64, len(metrics)+len(imageMetrics))
+		for _, m := range metrics {
+			result.Metrics[m.Name()] = m.Result()
+		}
+		for _, m := range imageMetrics {
+			value, err := m.Compute(ctx, img1, img2)
+			if err != nil {
+				return nil, fmt.Errorf("metric %s: %w", m.Name(), err)
+			}
+			result.Metrics[m.Name()] = value
+		}
+	}
+	if o.stats {
+		result.Stats = &Stats{Path: path}
+	}
+	if alphaSampled && !hasAlpha {
+		result.Warnings = append(result.Warnings, newWarning(WarningAlphaSampled, "alpha was sampled every few pixels, found opaque and not compared"))
+	}
+	result.Histogram = histogram
+
+	return result, nil
+}
+
+// ErrDimensionMismatch is returned when the images to compare differ in
+// size.
+var ErrDimensionMismatch = errors.New("images have different dimensions")
+
+// checkDimensions returns an error wrapping ErrDimensionMismatch unless both
+// images have the same size.
+func checkDimensions(img1, img2 image.Image) error {
 	bounds1 := img1.Bounds()
 	bounds2 := img2.Bounds()
 
 	if bounds1.Dx() != bounds2.Dx() || bounds1.Dy() != bounds2.Dy() {
-		return 0, fmt.Errorf("images have different dimensions: %dx%d vs %dx%d",
+		return fmt.Errorf("%w: %dx%d vs %dx%d", ErrDimensionMismatch,
 			bounds1.Dx(), bounds1.Dy(), bounds2.Dx(), bounds2.Dy())
 	}
+	return nil
+}
+
+// mayHaveAlpha reports whether images of format can carry alpha.
+func mayHaveAlpha(format string) bool {
+	return format == "png" || format == memoryFormat
+}
+
+// detectAlpha reports whether either image carries meaningful alpha, in
+// which case the alpha channel takes part in the comparison.
+func detectAlpha(d1, d2 *decoded) bool {
+	hasAlpha, _ := alphaPresence(d1, d2)
+	return hasAlpha
+}
+
+// alphaPresence implements detectAlpha, and also reports whether the answer
+// was found by sampling pixels rather than exactly.
+func alphaPresence(d1, d2 *decoded) (hasAlpha, sampled bool) {
+	if !mayHaveAlpha(d1.format) && !mayHaveAlpha(d2.format) {
+		return false, false
+	}
+
+	// Gray+alpha and paletted images are checked exactly; a single
+	// translucent pixel outside the sampled grid would otherwise be missed
+	translucent1, exact1 := exactAlpha(d1)
+	translucent2, exact2 := exactAlpha(d2)
+	if translucent1 || translucent2 {
+		return true, false
+	}
+	if exact1 && exact2 {
+		return false, false
+	}
 
+	img1, img2 := d1.img, d2.img
+	bounds1 := img1.Bounds()
+	bounds2 := img2.Bounds()
 	width := bounds1.Dx()
 	height := bounds1.Dy()
-	totalPixels := width * height
 
-	// Use integer arithmetic for better performance
-	var sumSquaredDiff uint64
-	channelCount := 3
+	// Sample every 16th pixel for faster alpha detection
+	step := 16
+	if width < 64 || height < 64 {
+		step = 4 // Use smaller step for small images
+	}
+	for y := 0; y < height; y += step {
+		for x := 0; x < width; x += step {
+			_, _, _, a1 := img1.At(x+bounds1.Min.X, y+bounds1.Min.Y).RGBA()
+			_, _, _, a2 := img2.At(x+bounds2.Min.X, y+bounds2.Min.Y).RGBA()
+			if a1 != 0xffff || a2 != 0xffff {
+				return true, true
+			}
+		}
+	}
+	return false, true
+}
 
-	// Optimize alpha channel detection by sampling
-	hasAlpha := false
-	if format1 == "png" || format2 == "png" {
-		// Sample every 16th pixel for faster alpha detection
-		step := 16
-		if width < 64 || height < 64 {
-			step = 4 // Use smaller step for small images
-		}
-		for y := 0; y < height && !hasAlpha; y += step {
-			for x := 0; x < width && !hasAlpha; x += step {
-				_, _, _, a1 := img1.At(x+bounds1.Min.X, y+bounds1.Min.Y).RGBA()
-				_, _, _, a2 := img2.At(x+bounds2.Min.X, y+bounds2.Min.Y).RGBA()
-				if a1 != 0xffff || a2 != 0xffff {
-					hasAlpha = true
-					channelCount = 4
-				}
+// exactAlpha reports whether an image has translucent pixels when that can
+// be determined cheaply without sampling, and whether it could be.
+func exactAlpha(d *decoded) (translucent, exact bool) {
+	if ga, ok := grayAlphaImage(d); ok {
+		return !opaqueNRGBA(ga), true
+	}
+	if paletted, ok := d.img.(*image.Paletted); ok {
+		return palettedTranslucent(paletted), true
+	}
+	return false, false
+}
+
+// palettedTranslucent reports whether any pixel of img uses a palette entry
+// that is not fully opaque, such as those a PNG tRNS chunk makes
+// transparent. Images with an opaque palette are answered without looking
+// at the pixels.
+func palettedTranslucent(img *image.Paletted) bool {
+	var translucent [256]bool
+	found := false
+	for i, c := range img.Palette {
+		if _, _, _, a := c.RGBA(); a != 0xffff && i < len(translucent) {
+			translucent[i] = true
+			found = true
+		}
+	}
+	if !found {
+		return false
+	}
+	width := img.Rect.Dx()
+	for y := 0; y < img.Rect.Dy(); y++ {
+		for _, index := range img.Pix[y*img.Stride : y*img.Stride+width] {
+			if translucent[index] {
+				return true
 			}
 		}
 	}
+	return false
+}
+
+// Names of the MSE code paths, as reported in debug logs.
+const (
+	pathRGBA    = "rgba"
+	pathNRGBA   = "nrgba"
+	pathYCbCr   = "ycbcr"
+	pathGeneric = "generic"
+	pathFused   = "fused"
+	pathAligned = "aligned"
+)
 
-	// Try fast path for common image types
+// bandHeight is the number of rows accumulated between progress reports and
+// cancellation checks.
+const bandHeight = 64
+
+// rowKernel accumulates squared sample differences over rows [y0, y1),
+// counted from the top of the images.
+type rowKernel func(y0, y1 int) uint64
+
+// selectKernel picks a fast path for common image types and returns it with
+// the name of the path.
+func selectKernel(img1, img2 image.Image, hasAlpha bool) (rowKernel, string) {
 	switch img1Type := img1.(type) {
 	case *image.RGBA:
 		if img2RGBA, ok := img2.(*image.RGBA); ok {
 			// Fast path for RGBA images
-			sumSquaredDiff = computeMSERGBA(img1Type, img2RGBA, hasAlpha)
-		} else {
-			sumSquaredDiff = computeMSEGeneric(img1, img2, bounds1, bounds2, width, height, hasAlpha)
+			return func(y0, y1 int) uint64 {
+				return computeMSERGBA(img1Type, img2RGBA, hasAlpha, y0, y1)
+			}, pathRGBA
 		}
 	case *image.NRGBA:
 		if img2NRGBA, ok := img2.(*image.NRGBA); ok {
 			// Fast path for NRGBA images (common PNG format)
-			sumSquaredDiff = computeMSENRGBA(img1Type, img2NRGBA, hasAlpha)
-		} else {
-			sumSquaredDiff = computeMSEGeneric(img1, img2, bounds1, bounds2, width, height, hasAlpha)
+			return func(y0, y1 int) uint64 {
+				return computeMSENRGBA(img1Type, img2NRGBA, hasAlpha, y0, y1)
+			}, pathNRGBA
 		}
 	case *image.YCbCr:
 		if img2YCbCr, ok := img2.(*image.YCbCr); ok {
 			// Fast path for YCbCr (JPEG) images
-			sumSquaredDiff = computeMSEYCbCr(img1Type, img2YCbCr)
-		} else {
-			sumSquaredDiff = computeMSEGeneric(img1, img2, bounds1, bounds2, width, height, hasAlpha)
+			return func(y0, y1 int) uint64 {
+				return computeMSEYCbCr(img1Type, img2YCbCr, y0, y1)
+			}, pathYCbCr
+		}
+	}
+	return func(y0, y1 int) uint64 {
+		return computeMSEGeneric(img1, img2, hasAlpha, y0, y1)
+	}, pathGeneric
+}
+
+// sumSquaredDiff runs kernel over height rows band by band, reporting
+// progress and stopping early when ctx is canceled.
+func sumSquaredDiff(ctx context.Context, kernel rowKernel, height int, progress func(done, total int)) (uint64, error) {
+	var sumSquaredDiff uint64
+	for y := 0; y < height; y += bandHeight {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		end := min(y+bandHeight, height)
+		sumSquaredDiff += kernel(y, end)
+		if progress != nil {
+			progress(end, height)
 		}
-	default:
-		sumSquaredDiff = computeMSEGeneric(img1, img2, bounds1, bounds2, width, height, hasAlpha)
 	}
 
-	// Convert to MSE
-	totalSamples := uint64(totalPixels * channelCount)
+	return sumSquaredDiff, nil
+}
+
+// newResult converts an accumulated squared difference into a Result.
+func newResult(sumSquaredDiff, totalSamples uint64) *Result {
 	if sumSquaredDiff == 0 {
-		return math.Inf(1), nil
+		return &Result{PSNR: math.Inf(1), Peak: maxSampleValue}
 	}
 
 	mse := float64(sumSquaredDiff) / float64(totalSamples)
@@ -118,17 +794,27 @@ func Compute(image1Bytes, image2Bytes []byte) (float64, error) {
 	// - IDCT (Inverse Discrete Cosine Transform) algorithms
 	// This can result in small PSNR variations (typically < 1-2%)
 
+	return &Result{PSNR: psnrFromMSE(mse), MSE: mse, Peak: maxSampleValue}
+}
+
+// psnrFromMSE converts an 8-bit MSE into PSNR.
+func psnrFromMSE(mse float64) float64 {
+	if mse == 0 {
+		return math.Inf(1)
+	}
 	// Fast PSNR calculation
 	// PSNR = 10 * log10(255^2 / MSE) = 10 * log10(65025 / MSE)
-	psnr := 10 * math.Log10(65025.0/mse)
-	return psnr, nil
+	return 10 * math.Log10(65025.0/mse)
 }
 
 // computeMSEGeneric calculates MSE for any image type
-func computeMSEGeneric(img1, img2 image.Image, bounds1, bounds2 image.Rectangle, width, height int, hasAlpha bool) uint64 {
+func computeMSEGeneric(img1, img2 image.Image, hasAlpha bool, y0, y1 int) uint64 {
 	var sumSquaredDiff uint64
+	bounds1 := img1.Bounds()
+	bounds2 := img2.Bounds()
+	width := bounds1.Dx()
 
-	for y := 0; y < height; y++ {
+	for y := y0; y < y1; y++ {
 		for x := 0; x < width; x++ {
 			r1, g1, b1, a1 := img1.At(x+bounds1.Min.X, y+bounds1.Min.Y).RGBA()
 			r2, g2, b2, a2 := img2.At(x+bounds2.Min.X, y+bounds2.Min.Y).RGBA()
@@ -156,45 +842,36 @@ func computeMSEGeneric(img1, img2 image.Image, bounds1, bounds2 image.Rectangle,
 }
 
 // computeMSERGBA performs fast MSE calculation for RGBA images
-func computeMSERGBA(img1, img2 *image.RGBA, hasAlpha bool) uint64 {
-	var sumSquaredDiff uint64
-	pix1 := img1.Pix
-	pix2 := img2.Pix
-
-	// Process 4 bytes at a time (RGBA)
-	for i := 0; i < len(pix1); i += 4 {
-		diffR := int32(pix1[i]) - int32(pix2[i])
-		diffG := int32(pix1[i+1]) - int32(pix2[i+1])
-		diffB := int32(pix1[i+2]) - int32(pix2[i+2])
-
-		sumSquaredDiff += uint64(diffR*diffR) + uint64(diffG*diffG) + uint64(diffB*diffB)
-
-		if hasAlpha {
-			diffA := int32(pix1[i+3]) - int32(pix2[i+3])
-			sumSquaredDiff += uint64(diffA * diffA)
-		}
-	}
-
-	return sumSquaredDiff
+func computeMSERGBA(img1, img2 *image.RGBA, hasAlpha bool, y0, y1 int) uint64 {
+	return sumSquaredDiffPix(img1.Pix, img2.Pix, img1.Stride, img2.Stride, img1.Rect.Dx(), hasAlpha, y0, y1)
 }
 
 // computeMSENRGBA performs fast MSE calculation for NRGBA images (non-premultiplied alpha)
-func computeMSENRGBA(img1, img2 *image.NRGBA, hasAlpha bool) uint64 {
+func computeMSENRGBA(img1, img2 *image.NRGBA, hasAlpha bool, y0, y1 int) uint64 {
+	return sumSquaredDiffPix(img1.Pix, img2.Pix, img1.Stride, img2.Stride, img1.Rect.Dx(), hasAlpha, y0, y1)
+}
+
+// sumSquaredDiffPix accumulates squared differences between two 4-byte
+// per pixel buffers over rows [y0, y1).
+func sumSquaredDiffPix(pix1, pix2 []uint8, stride1, stride2, width int, hasAlpha bool, y0, y1 int) uint64 {
 	var sumSquaredDiff uint64
-	pix1 := img1.Pix
-	pix2 := img2.Pix
 
-	// Process 4 bytes at a time (NRGBA)
-	for i := 0; i < len(pix1); i += 4 {
-		diffR := int32(pix1[i]) - int32(pix2[i])
-		diffG := int32(pix1[i+1]) - int32(pix2[i+1])
-		diffB := int32(pix1[i+2]) - int32(pix2[i+2])
+	for y := y0; y < y1; y++ {
+		row1 := pix1[y*stride1 : y*stride1+width*4]
+		row2 := pix2[y*stride2 : y*stride2+width*4]
 
-		sumSquaredDiff += uint64(diffR*diffR) + uint64(diffG*diffG) + uint64(diffB*diffB)
+		// Process 4 bytes at a time (RGBA)
+		for i := 0; i < len(row1); i += 4 {
+			diffR := int32(row1[i]) - int32(row2[i])
+			diffG := int32(row1[i+1]) - int32(row2[i+1])
+			diffB := int32(row1[i+2]) - int32(row2[i+2])
+
+			sumSquaredDiff += uint64(diffR*diffR) + uint64(diffG*diffG) + uint64(diffB*diffB)
 
-		if hasAlpha {
-			diffA := int32(pix1[i+3]) - int32(pix2[i+3])
-			sumSquaredDiff += uint64(diffA * diffA)
+			if hasAlpha {
+				diffA := int32(row1[i+3]) - int32(row2[i+3])
+				sumSquaredDiff += uint64(diffA * diffA)
+			}
 		}
 	}
 
@@ -202,15 +879,17 @@ func computeMSENRGBA(img1, img2 *image.NRGBA, hasAlpha bool) uint64 {
 }
 
 // computeMSEYCbCr performs fast MSE calculation for YCbCr (JPEG) images
-func computeMSEYCbCr(img1, img2 *image.YCbCr) uint64 {
+func computeMSEYCbCr(img1, img2 *image.YCbCr, y0, y1 int) uint64 {
 	var sumSquaredDiff uint64
 	bounds := img1.Bounds()
 
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+	for y := bounds.Min.Y + y0; y < bounds.Min.Y+y1; y++ {
 		for x := bounds.Min.X; x < bounds.Max.X; x++ {
 			// Convert YCbCr to RGB for both images
-			r1, g1, b1 := color.YCbCrToRGB(img1.YCbCrAt(x, y).Y, img1.YCbCrAt(x, y).Cb, img1.YCbCrAt(x, y).Cr)
-			r2, g2, b2 := color.YCbCrToRGB(img2.YCbCrAt(x, y).Y, img2.YCbCrAt(x, y).Cb, img2.YCbCrAt(x, y).Cr)
+			c1 := img1.YCbCrAt(x, y)
+			c2 := img2.YCbCrAt(x, y)
+			r1, g1, b1 := color.YCbCrToRGB(c1.Y, c1.Cb, c1.Cr)
+			r2, g2, b2 := color.YCbCrToRGB(c2.Y, c2.Cb, c2.Cr)
 
 			diffR := int32(r1) - int32(r2)
 			diffG := int32(g1) - int32(g2)
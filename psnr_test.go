@@ -1,10 +1,19 @@
 package psnr
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log/slog"
 	"math"
 	"os"
+	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 type testCase struct {
@@ -343,3 +352,145 @@ func TestPNGPalettePSNR(t *testing.T) {
 		})
 	}
 }
+
+func TestComputeFS(t *testing.T) {
+	original, err := os.ReadFile("testdata/test_original.jpg")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+	compressed, err := os.ReadFile("testdata/quality_50.jpg")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+
+	fsys := fstest.MapFS{
+		"images/original.jpg": {Data: original},
+		"images/q50.jpg":      {Data: compressed},
+	}
+
+	got, err := ComputeFS(fsys, "images/original.jpg", "images/q50.jpg")
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	want, err := Compute(original, compressed)
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if got != want {
+		t.Errorf("Expected %.6f, got %.6f", want, got)
+	}
+
+	if _, err := ComputeFS(os.DirFS("testdata"), "test_original.jpg", "missing.jpg"); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+// encodePNG encodes img as PNG for tests that build images in memory.
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestIdenticalBytesSkipDecode(t *testing.T) {
+	data, err := os.ReadFile("testdata/test_original.png")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	result, err := ComputeDetailed(data, bytes.Clone(data), WithLogger(logger))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if !math.IsInf(result.PSNR, 1) || result.Peak != 255 {
+		t.Errorf("Expected Inf against peak 255, got %+v", result)
+	}
+	if !strings.Contains(logs.String(), "skipping comparison") || strings.Contains(logs.String(), "decoded images") {
+		t.Errorf("Expected decoding to be skipped, got logs:\n%s", logs.String())
+	}
+
+	// Options that need the pixels still run the full pipeline
+	logs.Reset()
+	if _, err := ComputeDetailed(data, data, WithLogger(logger), WithAlignment(1)); err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if strings.Contains(logs.String(), "skipping comparison") {
+		t.Errorf("Expected a full comparison with alignment, got logs:\n%s", logs.String())
+	}
+
+	// Identical invalid or oversized inputs still fail
+	garbage := []byte("not an image")
+	if _, err := Compute(garbage, garbage); err == nil {
+		t.Error("Expected error for identical invalid inputs")
+	}
+	if _, err := ComputeDetailed(data, data, WithMaxPixels(10)); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("Expected ErrImageTooLarge, got %v", err)
+	}
+
+	// A truncated image has a valid header but cannot be decoded
+	truncated := data[:len(data)/2]
+	if _, err := Compute(truncated, bytes.Clone(truncated)); err == nil {
+		t.Error("Expected error for identical truncated inputs")
+	}
+	result, err = ComputeDetailed(truncated, truncated, WithTolerantDecode())
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if !math.IsInf(result.PSNR, 1) || result.Coverage >= 1 {
+		t.Errorf("Expected Inf over the decoded rows only, got %+v", result)
+	}
+}
+
+func TestCompareImages(t *testing.T) {
+	img1 := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	img2 := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for i := 0; i < len(img1.Pix); i += 4 {
+		img1.Pix[i], img1.Pix[i+3] = 200, 255
+		img2.Pix[i], img2.Pix[i+3] = 200, uint8(i%255)
+	}
+
+	result, err := CompareImages(context.Background(), img1, img2)
+	if err != nil {
+		t.Fatalf("CompareImages failed: %v", err)
+	}
+	// The images differ only in alpha, which in-memory images carry like PNGs
+	if math.IsInf(result.PSNR, 1) || result.MSE == 0 {
+		t.Errorf("Expected alpha differences to count, got %+v", result)
+	}
+
+	if _, err := CompareImages(context.Background(), img1, image.NewNRGBA(image.Rect(0, 0, 8, 8))); err == nil {
+		t.Error("Expected error for mismatched sizes")
+	}
+}
+
+func TestDetectAlphaPalette(t *testing.T) {
+	white := image.NewGray(image.Rect(0, 0, 100, 100))
+	for i := range white.Pix {
+		white.Pix[i] = 0xff
+	}
+	paletted := image.NewPaletted(white.Rect, color.Palette{color.White, color.Transparent})
+
+	// The transparent entry is unused, so only color is compared
+	result, err := ComputeDetailed(encodePNG(t, paletted), encodePNG(t, white))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if !math.IsInf(result.PSNR, 1) {
+		t.Errorf("Expected identical images, got %+v", result)
+	}
+
+	// A single transparent pixel off the sampled grid enables alpha
+	paletted.SetColorIndex(33, 51, 1)
+	result, err = ComputeDetailed(encodePNG(t, paletted), encodePNG(t, white))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if want := 255.0 * 255 * 4 / (100 * 100 * 4); result.MSE != want {
+		t.Errorf("Expected MSE %v with alpha compared, got %v", want, result.MSE)
+	}
+}
@@ -0,0 +1,122 @@
+// Package psnrtest provides golden-image assertions for Go tests built on
+// the psnr package.
+//
+//	func TestRender(t *testing.T) {
+//		img := render()
+//		psnrtest.AssertAtLeast(t, img, "testdata/render.golden.png", 45)
+//	}
+//
+// On failure the rendered image and a heatmap of the per-pixel error are
+// written to the directory named by the PSNRTEST_ARTIFACTS environment
+// variable, or to a new temporary directory, and their paths are reported.
+package psnrtest
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/ideamans/go-psnr"
+)
+
+// ArtifactsEnv is the environment variable naming the directory failure
+// artifacts are written to.
+const ArtifactsEnv = "PSNRTEST_ARTIFACTS"
+
+// AssertAtLeast fails t unless the PSNR between got and the golden image at
+// goldenPath is at least minPSNR dB. It reports whether the assertion held.
+func AssertAtLeast(t testing.TB, got image.Image, goldenPath string, minPSNR float64, opts ...psnr.Option) bool {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, got); err != nil {
+		t.Errorf("psnrtest: failed to encode image: %v", err)
+		return false
+	}
+	return AssertBytesAtLeast(t, buf.Bytes(), goldenPath, minPSNR, opts...)
+}
+
+// AssertBytesAtLeast is like AssertAtLeast for an encoded JPEG or PNG image.
+func AssertBytesAtLeast(t testing.TB, got []byte, goldenPath string, minPSNR float64, opts ...psnr.Option) bool {
+	t.Helper()
+	golden, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Errorf("psnrtest: failed to read golden image: %v", err)
+		return false
+	}
+
+	result, err := psnr.ComputeDetailed(golden, got, opts...)
+	if err != nil {
+		t.Errorf("psnrtest: failed to compare with %s: %v", goldenPath, err)
+		return false
+	}
+	if result.PSNR >= minPSNR {
+		return true
+	}
+
+	message := fmt.Sprintf("psnrtest: PSNR against %s is %.2f dB, want at least %.2f dB", goldenPath, result.PSNR, minPSNR)
+	dir, err := writeArtifacts(t, golden, got)
+	if err != nil {
+		t.Errorf("%s (failed to write artifacts: %v)", message, err)
+	} else {
+		t.Errorf("%s; got image and diff heatmap written to %s", message, dir)
+	}
+	return false
+}
+
+// unsafeName matches characters that are replaced in artifact file names.
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// writeArtifacts writes the got image and the diff heatmap for a failing
+// assertion and returns the directory they were written to.
+func writeArtifacts(t testing.TB, golden, got []byte) (string, error) {
+	dir := os.Getenv(ArtifactsEnv)
+	if dir == "" {
+		var err error
+		if dir, err = os.MkdirTemp("", "psnrtest-"); err != nil {
+			return "", err
+		}
+	} else if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	name := unsafeName.ReplaceAllString(t.Name(), "_")
+
+	if err := os.WriteFile(filepath.Join(dir, name+".got"+extension(got)), got, 0o644); err != nil {
+		return "", err
+	}
+
+	img1, _, err := image.Decode(bytes.NewReader(golden))
+	if err != nil {
+		return "", err
+	}
+	img2, _, err := image.Decode(bytes.NewReader(got))
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, Heatmap(img1, img2)); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".diff.png"), buf.Bytes(), 0o644); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// extension returns the file extension matching an encoded image.
+func extension(data []byte) string {
+	if _, format, err := image.DecodeConfig(bytes.NewReader(data)); err == nil && format == "jpeg" {
+		return ".jpg"
+	}
+	return ".png"
+}
+
+// Heatmap renders the per-pixel error between two images as psnr.Heatmap
+// does.
+func Heatmap(img1, img2 image.Image) *image.RGBA {
+	return psnr.Heatmap(img1, img2)
+}
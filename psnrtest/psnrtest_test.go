@@ -0,0 +1,99 @@
+package psnrtest
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// recorder is a testing.TB that records failures instead of failing.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Name() string { return "TestRender/case 1" }
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+const goldenPath = "../testdata/test_image.png"
+
+func loadGolden(t *testing.T) image.Image {
+	t.Helper()
+	data, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("Failed to read golden image: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to decode golden image: %v", err)
+	}
+	return img
+}
+
+func TestAssertAtLeast(t *testing.T) {
+	golden := loadGolden(t)
+	dir := t.TempDir()
+	t.Setenv(ArtifactsEnv, dir)
+
+	r := &recorder{TB: t}
+	if !AssertAtLeast(r, golden, goldenPath, 60) || len(r.errors) != 0 {
+		t.Errorf("Expected the golden image to pass, got %v", r.errors)
+	}
+
+	// Damage a block of the image
+	damaged := image.NewNRGBA(golden.Bounds())
+	for y := golden.Bounds().Min.Y; y < golden.Bounds().Max.Y; y++ {
+		for x := golden.Bounds().Min.X; x < golden.Bounds().Max.X; x++ {
+			damaged.Set(x, y, golden.At(x, y))
+		}
+	}
+	for y := 10; y < 30; y++ {
+		for x := 10; x < 30; x++ {
+			damaged.Set(x, y, color.White)
+		}
+	}
+
+	r = &recorder{TB: t}
+	if AssertAtLeast(r, damaged, goldenPath, 45) || len(r.errors) != 1 {
+		t.Fatalf("Expected one failure for a damaged image, got %v", r.errors)
+	}
+
+	for _, name := range []string{"TestRender_case_1.got.png", "TestRender_case_1.diff.png"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Expected artifact %s: %v", name, err)
+		}
+	}
+
+	r = &recorder{TB: t}
+	if AssertAtLeast(r, golden, "missing.png", 45) || len(r.errors) != 1 {
+		t.Errorf("Expected a failure for a missing golden image, got %v", r.errors)
+	}
+}
+
+func TestHeatmap(t *testing.T) {
+	img1 := image.NewGray(image.Rect(0, 0, 3, 1))
+	img2 := image.NewGray(image.Rect(0, 0, 3, 1))
+	img2.Pix[1] = 16
+	img2.Pix[2] = 255
+
+	heatmap := Heatmap(img1, img2)
+	if c := heatmap.RGBAAt(0, 0); c != (color.RGBA{A: 255}) {
+		t.Errorf("Expected black for equal pixels, got %v", c)
+	}
+	if c := heatmap.RGBAAt(1, 0); c.R != 192 || c.G != 0 {
+		t.Errorf("Expected red for a small difference, got %v", c)
+	}
+	if c := heatmap.RGBAAt(2, 0); c != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("Expected white for the largest difference, got %v", c)
+	}
+}
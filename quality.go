@@ -0,0 +1,238 @@
+package psnr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"math"
+)
+
+// ErrTargetUnreachable is returned by quality searches when even the
+// highest parameter does not reach the target PSNR.
+var ErrTargetUnreachable = errors.New("target PSNR is not reachable")
+
+// Encoder encodes an image with an integer quality parameter, such as a
+// JPEG or WebP quality or the number of colors of a PNG quantizer. Quality
+// searches assume PSNR grows with the parameter; wrap encoders whose
+// parameter works the other way round, such as quantizers, to invert it.
+type Encoder interface {
+	Encode(img image.Image, param int) ([]byte, error)
+}
+
+// EncoderFunc adapts a function to the Encoder interface.
+type EncoderFunc func(img image.Image, param int) ([]byte, error)
+
+// Encode calls f(img, param).
+func (f EncoderFunc) Encode(img image.Image, param int) ([]byte, error) {
+	return f(img, param)
+}
+
+// JPEGEncoder encodes with image/jpeg, using the parameter as quality.
+var JPEGEncoder Encoder = EncoderFunc(encodeJPEG)
+
+// SearchStrategy selects how a quality search picks parameters to try.
+type SearchStrategy int
+
+const (
+	// SearchBinary bisects the parameter range, assuming PSNR never drops
+	// as the parameter grows. It needs about log2(Max-Min) encodes.
+	SearchBinary SearchStrategy = iota
+	// SearchGoldenSection narrows the range around the parameter whose PSNR
+	// is closest to the target by golden-section search, then returns the
+	// lowest evaluated parameter that meets the target. It tolerates encoders
+	// whose PSNR is only roughly monotonic.
+	SearchGoldenSection
+)
+
+// SearchOptions configures SearchQuality.
+type SearchOptions struct {
+	// Min and Max bound the parameter. When both are zero the range is 1 to
+	// 100; otherwise both are used as given.
+	Min, Max int
+	// Strategy defaults to SearchBinary.
+	Strategy SearchStrategy
+	// Options configure each PSNR evaluation.
+	Options []Option
+	// OnIteration, if set, is called after every evaluated parameter.
+	OnIteration func(QualityResult)
+}
+
+// QualityResult is the outcome of a quality search or one of its
+// iterations.
+type QualityResult struct {
+	// Quality is the encoder parameter: for a search result, the lowest one
+	// that meets the target.
+	Quality int
+	// PSNR is the PSNR of Data against the original.
+	PSNR float64
+	// Data is the original encoded at Quality.
+	Data []byte
+	// Metrics holds the values of the metrics requested with WithMetrics
+	// in the search options, keyed by name.
+	Metrics map[string]float64
+}
+
+// SearchJPEGQuality binary-searches the JPEG quality for the lowest one whose
+// output still has at least targetPSNR against original, and returns it with
+// the encoded bytes. encode receives qualities from 1 to 100 and defaults to
+// image/jpeg when nil; opts configure each PSNR evaluation.
+func SearchJPEGQuality(original []byte, targetPSNR float64, encode EncoderFunc, opts ...Option) (*QualityResult, error) {
+	encoder := JPEGEncoder
+	if encode != nil {
+		encoder = encode
+	}
+	return SearchQuality(original, encoder, targetPSNR, SearchOptions{Options: opts})
+}
+
+// SearchQuality searches the encoder parameter for the lowest one whose
+// output still has at least targetPSNR against original, and returns it with
+// the encoded bytes. The original is decoded once and every parameter is
+// encoded and evaluated at most once.
+func SearchQuality(original []byte, encoder Encoder, targetPSNR float64, opts SearchOptions) (*QualityResult, error) {
+	if math.IsNaN(targetPSNR) {
+		return nil, fmt.Errorf("invalid target PSNR: %v", targetPSNR)
+	}
+	lo, hi := opts.Min, opts.Max
+	if lo == 0 && hi == 0 {
+		lo, hi = 1, 100
+	}
+	if lo > hi {
+		return nil, fmt.Errorf("invalid parameter range: %d-%d", lo, hi)
+	}
+
+	o, err := newOptions(opts.Options)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := o.decode(original)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode original: %w", err)
+	}
+
+	evaluated := make(map[int]*QualityResult)
+	evaluate := func(param int) (*QualityResult, error) {
+		if result, ok := evaluated[param]; ok {
+			return result, nil
+		}
+		data, err := encoder.Encode(ref.img, param)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode with parameter %d: %w", param, err)
+		}
+		candidate, err := o.decode(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode parameter %d: %w", param, err)
+		}
+		compared, err := compare(context.Background(), ref, candidate, o)
+		if err != nil {
+			return nil, err
+		}
+
+		result := &QualityResult{Quality: param, PSNR: compared.PSNR, Data: data, Metrics: compared.Metrics}
+		evaluated[param] = result
+		o.debug("evaluated parameter", "param", param, "psnr", result.PSNR, "bytes", len(data))
+		if opts.OnIteration != nil {
+			opts.OnIteration(*result)
+		}
+		return result, nil
+	}
+
+	best, err := evaluate(hi)
+	if err != nil {
+		return nil, err
+	}
+	if best.PSNR < targetPSNR {
+		return nil, fmt.Errorf("%w: %.2f dB at parameter %d", ErrTargetUnreachable, best.PSNR, hi)
+	}
+
+	switch opts.Strategy {
+	case SearchBinary:
+		return binarySearch(lo, best, targetPSNR, evaluate)
+	case SearchGoldenSection:
+		if err := goldenSectionSearch(lo, hi, targetPSNR, evaluate); err != nil {
+			return nil, err
+		}
+		for _, result := range evaluated {
+			if result.PSNR >= targetPSNR && result.Quality < best.Quality {
+				best = result
+			}
+		}
+		return best, nil
+	}
+	return nil, fmt.Errorf("unknown search strategy %d", opts.Strategy)
+}
+
+// binarySearch narrows [lo, best.Quality] to the lowest parameter meeting
+// the target, given that best meets it.
+func binarySearch(lo int, best *QualityResult, targetPSNR float64, evaluate func(int) (*QualityResult, error)) (*QualityResult, error) {
+	// Invariant: best meets the target and every parameter below lo fails it
+	for lo < best.Quality {
+		mid := (lo + best.Quality - 1) / 2
+		result, err := evaluate(mid)
+		if err != nil {
+			return nil, err
+		}
+		if result.PSNR >= targetPSNR {
+			best = result
+		} else {
+			lo = mid + 1
+		}
+	}
+	return best, nil
+}
+
+// invPhi is 1/φ, the golden-section ratio.
+var invPhi = (math.Sqrt(5) - 1) / 2
+
+// goldenSectionSearch minimizes |PSNR - target| over the integer range
+// [lo, hi], evaluating the probes it needs.
+func goldenSectionSearch(lo, hi int, targetPSNR float64, evaluate func(int) (*QualityResult, error)) error {
+	distance := func(param int) (float64, error) {
+		result, err := evaluate(param)
+		if err != nil {
+			return 0, err
+		}
+		if math.IsInf(result.PSNR, 1) {
+			return math.Inf(1), nil
+		}
+		return math.Abs(result.PSNR - targetPSNR), nil
+	}
+
+	for hi-lo > 2 {
+		step := int(math.Round(float64(hi-lo) * invPhi))
+		left, right := hi-step, lo+step
+		if left >= right {
+			left, right = (lo+hi)/2, (lo+hi)/2+1
+		}
+		dl, err := distance(left)
+		if err != nil {
+			return err
+		}
+		dr, err := distance(right)
+		if err != nil {
+			return err
+		}
+		if dl <= dr {
+			hi = right
+		} else {
+			lo = left
+		}
+	}
+	for param := lo; param <= hi; param++ {
+		if _, err := evaluate(param); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeJPEG encodes img with image/jpeg.
+func encodeJPEG(img image.Image, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
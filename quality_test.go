@@ -0,0 +1,151 @@
+package psnr
+
+import (
+	"errors"
+	"image"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestSearchJPEGQuality(t *testing.T) {
+	original, err := os.ReadFile("testdata/test_image.png")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+
+	result, err := SearchJPEGQuality(original, 38, nil)
+	if err != nil {
+		t.Fatalf("Error searching quality: %v", err)
+	}
+	t.Logf("quality %d: %.4f dB, %d bytes", result.Quality, result.PSNR, len(result.Data))
+	if result.PSNR < 38 {
+		t.Errorf("Expected at least 38 dB, got %.4f", result.PSNR)
+	}
+	got, err := Compute(original, result.Data)
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if got != result.PSNR {
+		t.Errorf("Expected the returned bytes to measure %.6f, got %.6f", result.PSNR, got)
+	}
+
+	withMetrics, err := SearchJPEGQuality(original, 38, nil, WithMetrics("test_pixel_count"))
+	if err != nil {
+		t.Fatalf("Error searching quality: %v", err)
+	}
+	if withMetrics.Quality != result.Quality || withMetrics.Metrics["test_pixel_count"] == 0 {
+		t.Errorf("Expected the requested metrics, got %+v", withMetrics.Metrics)
+	}
+
+	// The next lower quality misses the target
+	if result.Quality > 1 {
+		lower, err := encodeJPEG(decodeTestImage(t, original), result.Quality-1)
+		if err != nil {
+			t.Fatalf("Failed to encode: %v", err)
+		}
+		psnr, err := Compute(original, lower)
+		if err != nil {
+			t.Fatalf("Error computing PSNR: %v", err)
+		}
+		if psnr >= 38 {
+			t.Errorf("Expected quality %d to miss the target, got %.4f dB", result.Quality-1, psnr)
+		}
+	}
+}
+
+func TestSearchJPEGQualityEncoder(t *testing.T) {
+	original, err := os.ReadFile("testdata/test_image.png")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+
+	var qualities []int
+	encode := func(img image.Image, quality int) ([]byte, error) {
+		qualities = append(qualities, quality)
+		return encodeJPEG(img, quality)
+	}
+	if _, err := SearchJPEGQuality(original, 30, encode); err != nil {
+		t.Fatalf("Error searching quality: %v", err)
+	}
+	if len(qualities) > 8 {
+		t.Errorf("Expected a binary search, got %d encodes: %v", len(qualities), qualities)
+	}
+
+	if _, err := SearchJPEGQuality(original, 200, nil); !errors.Is(err, ErrTargetUnreachable) {
+		t.Errorf("Expected ErrTargetUnreachable, got %v", err)
+	}
+}
+
+// decodeTestImage decodes data for tests that work on image.Image values.
+func decodeTestImage(t *testing.T, data []byte) image.Image {
+	t.Helper()
+	d, err := (&options{}).decode(data)
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	return d.img
+}
+
+func TestSearchQualityStrategies(t *testing.T) {
+	original, err := os.ReadFile("testdata/test_image.png")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+
+	// Keeps the top param bits of every sample, so PSNR strictly grows
+	posterize := EncoderFunc(func(img image.Image, param int) ([]byte, error) {
+		rgba := toRGBA(img)
+		out := image.NewRGBA(rgba.Rect)
+		mask := uint8(0xff << (8 - param))
+		for i, v := range rgba.Pix {
+			out.Pix[i] = v & mask
+		}
+		for i := 3; i < len(out.Pix); i += 4 {
+			out.Pix[i] = 0xff
+		}
+		return encodePNG(t, out), nil
+	})
+
+	for _, strategy := range []SearchStrategy{SearchBinary, SearchGoldenSection} {
+		iterations := 0
+		result, err := SearchQuality(original, posterize, 30, SearchOptions{
+			Min:         1,
+			Max:         8,
+			Strategy:    strategy,
+			OnIteration: func(QualityResult) { iterations++ },
+		})
+		if err != nil {
+			t.Fatalf("Strategy %d: error searching quality: %v", strategy, err)
+		}
+		t.Logf("strategy %d: %d bits, %.4f dB after %d encodes", strategy, result.Quality, result.PSNR, iterations)
+		if result.PSNR < 30 {
+			t.Errorf("Strategy %d: expected at least 30 dB, got %.4f", strategy, result.PSNR)
+		}
+		if result.Quality > 1 {
+			lower, err := posterize.Encode(decodeTestImage(t, original), result.Quality-1)
+			if err != nil {
+				t.Fatalf("Failed to encode: %v", err)
+			}
+			if psnr, _ := Compute(original, lower); psnr >= 30 {
+				t.Errorf("Strategy %d: expected %d bits to miss the target, got %.4f dB", strategy, result.Quality-1, psnr)
+			}
+		}
+	}
+
+	jpegResult, err := SearchQuality(original, JPEGEncoder, 38, SearchOptions{Strategy: SearchGoldenSection})
+	if err != nil {
+		t.Fatalf("Error searching quality: %v", err)
+	}
+	if jpegResult.PSNR < 38 {
+		t.Errorf("Expected at least 38 dB, got %.4f", jpegResult.PSNR)
+	}
+
+	if _, err := SearchQuality(original, JPEGEncoder, 38, SearchOptions{Min: 50, Max: 10}); err == nil {
+		t.Error("Expected error for an empty range")
+	}
+	// A NaN target compares false against every PSNR
+	if _, err := SearchQuality(original, posterize, math.NaN(), SearchOptions{Min: 1, Max: 8}); err == nil {
+		t.Error("Expected error for a NaN target")
+	}
+}
@@ -0,0 +1,102 @@
+package psnr
+
+import (
+	"context"
+	"fmt"
+	"image"
+)
+
+// Quantizer reduces an image to a palette of at most colors colors, as a
+// PNG8 converter does. Wrap bindings to libimagequant or other quantizers
+// with QuantizerFunc to verify them with VerifyQuantizer.
+type Quantizer interface {
+	Quantize(img image.Image, colors int) (*image.Paletted, error)
+}
+
+// QuantizerFunc adapts a function to the Quantizer interface.
+type QuantizerFunc func(img image.Image, colors int) (*image.Paletted, error)
+
+// Quantize calls f(img, colors).
+func (f QuantizerFunc) Quantize(img image.Image, colors int) (*image.Paletted, error) {
+	return f(img, colors)
+}
+
+// MedianCutQuantizer quantizes with median cut and maps every pixel to the
+// nearest palette color, without dithering. It is the quantizer
+// AnalyzePalette uses.
+var MedianCutQuantizer Quantizer = QuantizerFunc(func(img image.Image, colors int) (*image.Paletted, error) {
+	if colors < 1 || colors > maxPaletteColors {
+		return nil, fmt.Errorf("invalid palette size: %d", colors)
+	}
+	return quantizeMedianCut(toRGBA(img), colors), nil
+})
+
+// QuantizeThreshold is the quality a quantized image must reach to pass
+// VerifyQuantizer. Zero fields are not checked.
+type QuantizeThreshold struct {
+	// MinPSNR is the lowest acceptable PSNR against the original.
+	MinPSNR float64
+	// MaxMeanDeltaE is the highest acceptable mean CIE 1976 color
+	// difference ΔE*ab against the original.
+	MaxMeanDeltaE float64
+}
+
+// QuantizeVerification is the outcome of VerifyQuantizer.
+type QuantizeVerification struct {
+	// Image is the quantized image.
+	Image *image.Paletted
+	// Colors is the size of the palette the quantizer produced.
+	Colors int
+	// PSNR is the PSNR of the quantized image against the original.
+	PSNR float64
+	// MeanDeltaE and MaxDeltaE are the mean and largest CIE 1976 color
+	// difference ΔE*ab between the pixels of the original and the quantized
+	// image. A ΔE of about 2.3 is just noticeable.
+	MeanDeltaE float64
+	MaxDeltaE  float64
+	// Passed reports whether the quantized image meets the threshold.
+	Passed bool
+}
+
+// VerifyQuantizer decodes original, reduces it to at most colors colors
+// with q and reports the PSNR and color difference of the result against
+// the original, and whether they meet the threshold, as PNG8 conversion
+// services check before serving a palette image. opts configure the PSNR
+// evaluation.
+func VerifyQuantizer(ctx context.Context, original []byte, q Quantizer, colors int, threshold QuantizeThreshold, opts ...Option) (*QuantizeVerification, error) {
+	if colors < 1 || colors > maxPaletteColors {
+		return nil, fmt.Errorf("invalid palette size: %d", colors)
+	}
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := o.decode(original)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode original: %w", err)
+	}
+
+	paletted, err := q.Quantize(ref.img, colors)
+	if err != nil {
+		return nil, fmt.Errorf("failed to quantize: %w", err)
+	}
+	if paletted == nil {
+		return nil, fmt.Errorf("quantizer returned no image")
+	}
+	if len(paletted.Palette) > colors {
+		return nil, fmt.Errorf("quantizer returned %d colors, more than %d", len(paletted.Palette), colors)
+	}
+	if err := checkDimensions(ref.img, paletted); err != nil {
+		return nil, err
+	}
+
+	compared, err := compare(ctx, ref, ref.withImage(paletted), o)
+	if err != nil {
+		return nil, err
+	}
+	verification := &QuantizeVerification{Image: paletted, Colors: len(paletted.Palette), PSNR: compared.PSNR}
+	verification.MeanDeltaE, verification.MaxDeltaE = deltaE76(toRGBA(ref.img), toRGBA(paletted))
+	verification.Passed = (threshold.MinPSNR == 0 || compared.PSNR >= threshold.MinPSNR) &&
+		(threshold.MaxMeanDeltaE == 0 || verification.MeanDeltaE <= threshold.MaxMeanDeltaE)
+	return verification, nil
+}
@@ -0,0 +1,58 @@
+package psnr
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"os"
+	"testing"
+)
+
+func TestVerifyQuantizer(t *testing.T) {
+	ctx := context.Background()
+	original, err := os.ReadFile("testdata/test_image.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	threshold := QuantizeThreshold{MinPSNR: 25, MaxMeanDeltaE: 5}
+	result, err := VerifyQuantizer(ctx, original, MedianCutQuantizer, 256, threshold)
+	if err != nil {
+		t.Fatalf("VerifyQuantizer failed: %v", err)
+	}
+	t.Logf("%d colors: %.2f dB, mean ΔE %.2f, max ΔE %.2f", result.Colors, result.PSNR, result.MeanDeltaE, result.MaxDeltaE)
+	if !result.Passed || result.Colors > 256 || result.MeanDeltaE <= 0 || result.MaxDeltaE < result.MeanDeltaE {
+		t.Errorf("Expected 256 colors to pass, got %+v", result)
+	}
+	coarse, err := VerifyQuantizer(ctx, original, MedianCutQuantizer, 4, threshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if coarse.Passed || coarse.PSNR >= result.PSNR || coarse.MeanDeltaE <= result.MeanDeltaE {
+		t.Errorf("Expected 4 colors to fail, got %+v", coarse)
+	}
+
+	gray := QuantizerFunc(func(img image.Image, colors int) (*image.Paletted, error) {
+		return image.NewPaletted(img.Bounds(), color.Palette{color.Gray{128}}), nil
+	})
+	if result, err := VerifyQuantizer(ctx, original, gray, 256, QuantizeThreshold{}); err != nil || !result.Passed || result.Colors != 1 {
+		t.Errorf("Expected no threshold to pass, got %+v, %v", result, err)
+	}
+
+	failing := QuantizerFunc(func(image.Image, int) (*image.Paletted, error) {
+		return nil, errors.New("out of colors")
+	})
+	if _, err := VerifyQuantizer(ctx, original, failing, 256, threshold); err == nil {
+		t.Error("Expected the quantizer error")
+	}
+	tooMany := QuantizerFunc(func(img image.Image, colors int) (*image.Paletted, error) {
+		return image.NewPaletted(img.Bounds(), make(color.Palette, colors+1)), nil
+	})
+	if _, err := VerifyQuantizer(ctx, original, tooMany, 16, threshold); err == nil {
+		t.Error("Expected error for a palette larger than requested")
+	}
+	if _, err := VerifyQuantizer(ctx, original, MedianCutQuantizer, 257, threshold); err == nil {
+		t.Error("Expected error for more than 256 colors")
+	}
+}
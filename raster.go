@@ -0,0 +1,118 @@
+package psnr
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// toRGBA converts img to an *image.RGBA anchored at the origin. RGBA samples
+// are premultiplied, matching the 8-bit values the generic path derives from
+// color.Color.RGBA.
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Rect, img, bounds.Min, draw.Src)
+	return dst
+}
+
+// subImage returns the part r of img, in the coordinates of img. Images
+// with a SubImage method share their pixels and keep their type, so the
+// alpha representation AlphaAuto compares is preserved; others are copied
+// to an *image.RGBA.
+func subImage(img image.Image, r image.Rectangle) image.Image {
+	if sub, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(r)
+	}
+	return toRGBA(img).SubImage(r.Sub(img.Bounds().Min))
+}
+
+// downscaleRGBA shrinks img by an integer factor using a box filter. Partial
+// blocks at the right and bottom edges are dropped.
+func downscaleRGBA(img *image.RGBA, factor int) *image.RGBA {
+	width := img.Rect.Dx() / factor
+	height := img.Rect.Dy() / factor
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	area := uint32(factor * factor)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var sum [4]uint32
+			for by := 0; by < factor; by++ {
+				i := img.PixOffset(x*factor, y*factor+by)
+				for bx := 0; bx < factor; bx++ {
+					sum[0] += uint32(img.Pix[i])
+					sum[1] += uint32(img.Pix[i+1])
+					sum[2] += uint32(img.Pix[i+2])
+					sum[3] += uint32(img.Pix[i+3])
+					i += 4
+				}
+			}
+			j := dst.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				dst.Pix[j+c] = uint8((sum[c] + area/2) / area)
+			}
+		}
+	}
+
+	return dst
+}
+
+// blockSSD sums squared differences between the region r of img1 and the
+// same-sized region of img2 whose top-left corner is at origin2.
+func blockSSD(img1, img2 *image.RGBA, r image.Rectangle, origin2 image.Point, hasAlpha bool) uint64 {
+	var sumSquaredDiff uint64
+	width := r.Dx()
+
+	for y := 0; y < r.Dy(); y++ {
+		i := img1.PixOffset(r.Min.X, r.Min.Y+y)
+		j := img2.PixOffset(origin2.X, origin2.Y+y)
+		pix1 := img1.Pix[i : i+width*4]
+		pix2 := img2.Pix[j : j+width*4]
+
+		for k := 0; k < len(pix1); k += 4 {
+			diffR := int32(pix1[k]) - int32(pix2[k])
+			diffG := int32(pix1[k+1]) - int32(pix2[k+1])
+			diffB := int32(pix1[k+2]) - int32(pix2[k+2])
+
+			sumSquaredDiff += uint64(diffR*diffR) + uint64(diffG*diffG) + uint64(diffB*diffB)
+
+			if hasAlpha {
+				diffA := int32(pix1[k+3]) - int32(pix2[k+3])
+				sumSquaredDiff += uint64(diffA * diffA)
+			}
+		}
+	}
+
+	return sumSquaredDiff
+}
+
+// toNRGBA converts img to an *image.NRGBA anchored at the origin, keeping
+// straight (non-premultiplied) 8-bit samples.
+func toNRGBA(img image.Image) *image.NRGBA {
+	if nrgba, ok := img.(*image.NRGBA); ok && nrgba.Rect.Min == (image.Point{}) {
+		return nrgba
+	}
+
+	bounds := img.Bounds()
+	switch img.(type) {
+	case *image.YCbCr, *image.Gray:
+		// Opaque sources have identical premultiplied and straight samples,
+		// so use draw's fast conversion
+		rgba := toRGBA(img)
+		return &image.NRGBA{Pix: rgba.Pix, Stride: rgba.Stride, Rect: rgba.Rect}
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			dst.SetNRGBA(x, y, color.NRGBAModel.Convert(img.At(x+bounds.Min.X, y+bounds.Min.Y)).(color.NRGBA))
+		}
+	}
+	return dst
+}
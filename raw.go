@@ -0,0 +1,179 @@
+package psnr
+
+import (
+	"context"
+	"fmt"
+	"image"
+)
+
+// RawFormat is the memory layout of an uncompressed Y'CbCr frame.
+type RawFormat int
+
+const (
+	// RawI420 is planar 4:2:0: a Y plane followed by the Cb and Cr planes
+	// (also called YUV420p or IYUV).
+	RawI420 RawFormat = iota
+	// RawNV12 is semi-planar 4:2:0: a Y plane followed by one plane of
+	// interleaved Cb, Cr pairs, as produced by most hardware decoders.
+	RawNV12
+	// RawNV21 is RawNV12 with Cr before Cb, as produced by Android cameras.
+	RawNV21
+	// RawYUYV is packed 4:2:2 with bytes Y0 Cb Y1 Cr per pixel pair (also
+	// called YUY2), the common V4L2 webcam format.
+	RawYUYV
+	// RawUYVY is packed 4:2:2 with bytes Cb Y0 Cr Y1 per pixel pair.
+	RawUYVY
+)
+
+// String returns the conventional name of the format.
+func (f RawFormat) String() string {
+	switch f {
+	case RawI420:
+		return "i420"
+	case RawNV12:
+		return "nv12"
+	case RawNV21:
+		return "nv21"
+	case RawYUYV:
+		return "yuyv"
+	case RawUYVY:
+		return "uyvy"
+	default:
+		return fmt.Sprintf("RawFormat(%d)", int(f))
+	}
+}
+
+// RawFrame is an uncompressed 8-bit Y'CbCr frame, such as a camera, V4L2 or
+// hardware decoder buffer.
+type RawFrame struct {
+	Data   []byte
+	Format RawFormat
+	Width  int
+	Height int
+	// Stride is the number of bytes per row of the Y plane, or of the packed
+	// rows, including any padding. The chroma planes of RawI420 use half of
+	// it, those of RawNV12 and RawNV21 the same stride. Zero means tightly
+	// packed rows.
+	Stride int
+}
+
+// YCbCr returns the frame as an *image.YCbCr with 4:2:0 or 4:2:2
+// subsampling. Planar frames share Data; semi-planar and packed frames are
+// copied into planes.
+func (f RawFrame) YCbCr() (*image.YCbCr, error) {
+	if f.Width <= 0 || f.Height <= 0 {
+		return nil, fmt.Errorf("invalid raw frame size: %dx%d", f.Width, f.Height)
+	}
+	cw, ch := (f.Width+1)/2, (f.Height+1)/2
+
+	switch f.Format {
+	case RawI420, RawNV12, RawNV21:
+		// Chroma rows hold cw samples (planar) or cw pairs (semi-planar)
+		chromaRow := cw * 2
+		if f.Format == RawI420 {
+			chromaRow = cw
+		}
+		stride, cStride := f.Width, chromaRow
+		if f.Stride > 0 {
+			stride, cStride = f.Stride, f.Stride
+			if f.Format == RawI420 {
+				cStride = (f.Stride + 1) / 2
+			}
+		}
+		if stride < f.Width || cStride < chromaRow {
+			return nil, fmt.Errorf("raw %s stride %d is too small for width %d", f.Format, f.Stride, f.Width)
+		}
+
+		ySize := stride * f.Height
+		cSize := cStride * ch
+		need := ySize + cSize
+		if f.Format == RawI420 {
+			need += cSize
+		}
+		if len(f.Data) < need {
+			return nil, fmt.Errorf("raw %s frame needs %d bytes, got %d", f.Format, need, len(f.Data))
+		}
+
+		img := &image.YCbCr{
+			Y:              f.Data[:ySize],
+			YStride:        stride,
+			CStride:        cw,
+			SubsampleRatio: image.YCbCrSubsampleRatio420,
+			Rect:           image.Rect(0, 0, f.Width, f.Height),
+		}
+		if f.Format == RawI420 {
+			img.CStride = cStride
+			img.Cb = f.Data[ySize : ySize+cSize]
+			img.Cr = f.Data[ySize+cSize : ySize+2*cSize]
+			return img, nil
+		}
+
+		img.Cb = make([]uint8, cw*ch)
+		img.Cr = make([]uint8, cw*ch)
+		cb, cr := img.Cb, img.Cr
+		if f.Format == RawNV21 {
+			cb, cr = cr, cb
+		}
+		for y := 0; y < ch; y++ {
+			row := f.Data[ySize+y*cStride:]
+			for x := 0; x < cw; x++ {
+				cb[y*cw+x] = row[2*x]
+				cr[y*cw+x] = row[2*x+1]
+			}
+		}
+		return img, nil
+
+	case RawYUYV, RawUYVY:
+		stride := cw * 4
+		if f.Stride > 0 {
+			stride = f.Stride
+		}
+		if stride < cw*4 {
+			return nil, fmt.Errorf("raw %s stride %d is too small for width %d", f.Format, f.Stride, f.Width)
+		}
+		if need := stride * f.Height; len(f.Data) < need {
+			return nil, fmt.Errorf("raw %s frame needs %d bytes, got %d", f.Format, need, len(f.Data))
+		}
+
+		img := image.NewYCbCr(image.Rect(0, 0, f.Width, f.Height), image.YCbCrSubsampleRatio422)
+		yIndex, cbIndex, crIndex := 0, 1, 3
+		if f.Format == RawUYVY {
+			yIndex, cbIndex, crIndex = 1, 0, 2
+		}
+		for y := 0; y < f.Height; y++ {
+			row := f.Data[y*stride:]
+			for x := 0; x < f.Width; x++ {
+				pair := row[(x/2)*4:]
+				img.Y[y*img.YStride+x] = pair[yIndex+(x%2)*2]
+			}
+			for x := 0; x < cw; x++ {
+				img.Cb[y*img.CStride+x] = row[x*4+cbIndex]
+				img.Cr[y*img.CStride+x] = row[x*4+crIndex]
+			}
+		}
+		return img, nil
+
+	default:
+		return nil, fmt.Errorf("unknown raw format %d", int(f.Format))
+	}
+}
+
+// ComputeRaw calculates the PSNR between two raw Y'CbCr frames of the same
+// size, which may use different layouts. The frames are compared like
+// decoded JPEGs: converted to RGB by default, or plane by plane with
+// CompatibilityFFmpeg when both have the same subsampling.
+func ComputeRaw(frame1, frame2 RawFrame, opts ...Option) (*Result, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	img1, err := frame1.YCbCr()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read first frame: %w", err)
+	}
+	img2, err := frame2.YCbCr()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read second frame: %w", err)
+	}
+	return compare(context.Background(), &decoded{img: img1, format: "raw"}, &decoded{img: img2, format: "raw"}, o)
+}
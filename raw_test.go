@@ -0,0 +1,163 @@
+package psnr
+
+import (
+	"image"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// randomYCbCr returns a YCbCr image with random samples.
+func randomYCbCr(rng *rand.Rand, w, h int, ratio image.YCbCrSubsampleRatio) *image.YCbCr {
+	img := image.NewYCbCr(image.Rect(0, 0, w, h), ratio)
+	for _, plane := range [][]uint8{img.Y, img.Cb, img.Cr} {
+		for i := range plane {
+			plane[i] = uint8(rng.Intn(256))
+		}
+	}
+	return img
+}
+
+// encodeRaw lays img out in format with the given Y stride (0 for tight).
+func encodeRaw(img *image.YCbCr, format RawFormat, stride int) RawFrame {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	cw, ch := (w+1)/2, (h+1)/2
+	var data []byte
+	switch format {
+	case RawI420, RawNV12, RawNV21:
+		yStride, cStride := w, cw*2
+		if format == RawI420 {
+			cStride = cw
+		}
+		if stride > 0 {
+			yStride, cStride = stride, stride
+			if format == RawI420 {
+				cStride = (stride + 1) / 2
+			}
+		}
+		planes := 1
+		if format == RawI420 {
+			planes = 2
+		}
+		data = make([]byte, yStride*h+planes*cStride*ch)
+		for y := 0; y < h; y++ {
+			copy(data[y*yStride:], img.Y[y*img.YStride:y*img.YStride+w])
+		}
+		chroma := data[yStride*h:]
+		for y := 0; y < ch; y++ {
+			for x := 0; x < cw; x++ {
+				cb, cr := img.Cb[y*img.CStride+x], img.Cr[y*img.CStride+x]
+				switch format {
+				case RawI420:
+					chroma[y*cStride+x] = cb
+					chroma[cStride*ch+y*cStride+x] = cr
+				case RawNV12:
+					chroma[y*cStride+2*x], chroma[y*cStride+2*x+1] = cb, cr
+				case RawNV21:
+					chroma[y*cStride+2*x], chroma[y*cStride+2*x+1] = cr, cb
+				}
+			}
+		}
+	case RawYUYV, RawUYVY:
+		rowStride := cw * 4
+		if stride > 0 {
+			rowStride = stride
+		}
+		data = make([]byte, rowStride*h)
+		for y := 0; y < h; y++ {
+			for x := 0; x < cw; x++ {
+				y0 := img.Y[y*img.YStride+2*x]
+				y1 := y0
+				if 2*x+1 < w {
+					y1 = img.Y[y*img.YStride+2*x+1]
+				}
+				cb, cr := img.Cb[y*img.CStride+x], img.Cr[y*img.CStride+x]
+				pair := data[y*rowStride+x*4:]
+				if format == RawYUYV {
+					pair[0], pair[1], pair[2], pair[3] = y0, cb, y1, cr
+				} else {
+					pair[0], pair[1], pair[2], pair[3] = cb, y0, cr, y1
+				}
+			}
+		}
+	}
+	return RawFrame{Data: data, Format: format, Width: w, Height: h, Stride: stride}
+}
+
+func TestRawFrameYCbCr(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, tt := range []struct {
+		ratio   image.YCbCrSubsampleRatio
+		formats []RawFormat
+	}{
+		{image.YCbCrSubsampleRatio420, []RawFormat{RawI420, RawNV12, RawNV21}},
+		{image.YCbCrSubsampleRatio422, []RawFormat{RawYUYV, RawUYVY}},
+	} {
+		for _, size := range []image.Point{{8, 6}, {5, 3}} {
+			src := randomYCbCr(rng, size.X, size.Y, tt.ratio)
+			for _, format := range tt.formats {
+				for _, stride := range []int{0, 64} {
+					img, err := encodeRaw(src, format, stride).YCbCr()
+					if err != nil {
+						t.Fatalf("%s %v stride %d: unexpected error: %v", format, size, stride, err)
+					}
+					if img.SubsampleRatio != tt.ratio {
+						t.Errorf("%s: expected subsampling %v, got %v", format, tt.ratio, img.SubsampleRatio)
+					}
+					for y := 0; y < size.Y; y++ {
+						for x := 0; x < size.X; x++ {
+							if img.YCbCrAt(x, y) != src.YCbCrAt(x, y) {
+								t.Fatalf("%s %v stride %d: pixel (%d, %d) is %v, want %v",
+									format, size, stride, x, y, img.YCbCrAt(x, y), src.YCbCrAt(x, y))
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	short := encodeRaw(randomYCbCr(rng, 8, 6, image.YCbCrSubsampleRatio420), RawNV12, 0)
+	short.Data = short.Data[:len(short.Data)-1]
+	if _, err := short.YCbCr(); err == nil {
+		t.Error("Expected error for a short buffer")
+	}
+	if _, err := (RawFrame{Data: make([]byte, 100), Format: RawYUYV, Width: 8, Height: 2, Stride: 8}).YCbCr(); err == nil {
+		t.Error("Expected error for a stride smaller than a row")
+	}
+}
+
+func TestComputeRaw(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	src := randomYCbCr(rng, 16, 8, image.YCbCrSubsampleRatio420)
+	distorted := randomYCbCr(rng, 16, 8, image.YCbCrSubsampleRatio420)
+
+	// Different layouts of the same frame are identical
+	result, err := ComputeRaw(encodeRaw(src, RawI420, 0), encodeRaw(src, RawNV21, 32))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if !math.IsInf(result.PSNR, 1) {
+		t.Errorf("Expected Inf, got %f", result.PSNR)
+	}
+
+	result, err = ComputeRaw(encodeRaw(src, RawNV12, 0), encodeRaw(distorted, RawI420, 0), WithCompatibility(CompatibilityFFmpeg))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if len(result.Planes) != 3 || result.Planes[1].Name != "u" {
+		t.Fatalf("Expected Y, U and V planes, got %+v", result.Planes)
+	}
+	var ySum float64
+	for i := range src.Y {
+		d := float64(src.Y[i]) - float64(distorted.Y[i])
+		ySum += d * d
+	}
+	if want := ySum / float64(len(src.Y)); math.Abs(result.Planes[0].MSE-want) > 1e-9 {
+		t.Errorf("Expected Y MSE %f, got %f", want, result.Planes[0].MSE)
+	}
+
+	if _, err := ComputeRaw(RawFrame{Format: RawI420}, encodeRaw(src, RawI420, 0)); err == nil {
+		t.Error("Expected error for an empty frame")
+	}
+}
@@ -0,0 +1,93 @@
+package psnr
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// RDPoint is one point of a rate-distortion curve.
+type RDPoint struct {
+	// Param is the encoder parameter.
+	Param int
+	// Bytes is the size of the encoded image.
+	Bytes int
+	// BitsPerPixel is the bitrate: Bytes*8 divided by the pixel count.
+	BitsPerPixel float64
+	PSNR         float64
+	MSE          float64
+}
+
+// RDCurve encodes original with every parameter in params and returns the
+// resulting (size, PSNR) points in the order of params, so codec
+// comparisons can be generated programmatically. opts configure each PSNR
+// evaluation; WithProgress reports the number of completed points.
+func RDCurve(original []byte, encoder Encoder, params []int, opts ...Option) ([]RDPoint, error) {
+	return RDCurveParallel(original, encoder, params, 1, opts...)
+}
+
+// RDCurveParallel is like RDCurve but encodes and compares up to workers
+// parameters concurrently; workers <= 0 means runtime.GOMAXPROCS(0). The
+// encoder must be safe for concurrent use.
+func RDCurveParallel(original []byte, encoder Encoder, params []int, workers int, opts ...Option) ([]RDPoint, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := o.decode(original)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode original: %w", err)
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	progress := o.progress
+	pointOptions := *o
+	pointOptions.progress = nil
+	pixels := float64(ref.img.Bounds().Dx() * ref.img.Bounds().Dy())
+
+	var mu sync.Mutex
+	done := 0
+	points := make([]RDPoint, len(params))
+	errs := make([]error, len(params))
+	parallel(min(workers, max(len(params), 1)), len(params), func(i int) {
+		param := params[i]
+		data, err := encoder.Encode(ref.img, param)
+		if err != nil {
+			errs[i] = fmt.Errorf("failed to encode with parameter %d: %w", param, err)
+			return
+		}
+		candidate, err := o.decode(data)
+		if err != nil {
+			errs[i] = fmt.Errorf("failed to decode parameter %d: %w", param, err)
+			return
+		}
+		result, err := compare(context.Background(), ref, candidate, &pointOptions)
+		if err != nil {
+			errs[i] = fmt.Errorf("failed to compare parameter %d: %w", param, err)
+			return
+		}
+		points[i] = RDPoint{
+			Param:        param,
+			Bytes:        len(data),
+			BitsPerPixel: float64(len(data)*8) / pixels,
+			PSNR:         result.PSNR,
+			MSE:          result.MSE,
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		done++
+		if progress != nil {
+			progress(done, len(params))
+		}
+	})
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return points, nil
+}
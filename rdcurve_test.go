@@ -0,0 +1,45 @@
+package psnr
+
+import (
+	"errors"
+	"image"
+	"os"
+	"testing"
+)
+
+func TestRDCurve(t *testing.T) {
+	original, err := os.ReadFile("testdata/test_image.png")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+	params := []int{20, 50, 80, 95}
+
+	points, err := RDCurve(original, JPEGEncoder, params)
+	if err != nil {
+		t.Fatalf("Error computing RD curve: %v", err)
+	}
+	parallelPoints, err := RDCurveParallel(original, JPEGEncoder, params, 0)
+	if err != nil {
+		t.Fatalf("Error computing RD curve: %v", err)
+	}
+
+	for i, p := range points {
+		t.Logf("q=%d: %d bytes, %.3f bpp, %.4f dB", p.Param, p.Bytes, p.BitsPerPixel, p.PSNR)
+		if p.Param != params[i] {
+			t.Errorf("Expected parameter %d at %d, got %d", params[i], i, p.Param)
+		}
+		if parallelPoints[i] != p {
+			t.Errorf("Expected parallel point %+v, got %+v", p, parallelPoints[i])
+		}
+		if i > 0 && (p.Bytes <= points[i-1].Bytes || p.PSNR <= points[i-1].PSNR) {
+			t.Errorf("Expected size and PSNR to grow with quality, got %+v after %+v", p, points[i-1])
+		}
+	}
+
+	failing := EncoderFunc(func(img image.Image, param int) ([]byte, error) {
+		return nil, errors.New("boom")
+	})
+	if _, err := RDCurve(original, failing, params); err == nil {
+		t.Error("Expected encoder error")
+	}
+}
@@ -0,0 +1,131 @@
+package psnr
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// Region is a labeled area of the first image, such as a text box found by
+// an OCR or text detector. Its rectangle is in pixels from the top-left
+// corner of the image.
+type Region struct {
+	Label string
+	Rect  image.Rectangle
+	// Weight scales the weight of the region's pixels in WeightedPSNR, e.g.
+	// 4 to make errors in text count four times as much. Zero counts as 1,
+	// so regions can be labeled for reporting only.
+	Weight float64
+	// Exclude leaves the region's pixels out of the MSE, PSNR and
+	// WeightedPSNR.
+	Exclude bool
+}
+
+// RegionResult is the error measured within a Region.
+type RegionResult struct {
+	Label string
+	// Pixels is the number of pixels of the region within the compared
+	// area.
+	Pixels int
+	PSNR   float64
+	MSE    float64
+}
+
+// validateRegions reports a region with an invalid weight.
+func validateRegions(regions []Region) error {
+	for _, region := range regions {
+		if region.Weight < 0 || math.IsNaN(region.Weight) || math.IsInf(region.Weight, 0) {
+			return fmt.Errorf("invalid weight %g for region %q", region.Weight, region.Label)
+		}
+	}
+	return nil
+}
+
+// weight returns the weight of the region's pixels.
+func (region Region) weight() float64 {
+	if region.Weight == 0 {
+		return 1
+	}
+	return region.Weight
+}
+
+// hasRegionWeights reports whether any region changes the pixel weights.
+func hasRegionWeights(regions []Region) bool {
+	for _, region := range regions {
+		if !region.Exclude && region.weight() != 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// applyRegionWeights scales the weights of the pixels within the regions,
+// indexed by y*width+x. A pixel covered by several regions takes the weight
+// of the last one, and pixels of excluded regions weigh nothing, as they
+// are left out of the MSE.
+func applyRegionWeights(weights []float64, width, height int, regions []Region) {
+	scale := make([]float64, len(weights))
+	for i := range scale {
+		scale[i] = 1
+	}
+	for _, excluded := range []bool{false, true} {
+		for _, region := range regions {
+			if region.Exclude != excluded {
+				continue
+			}
+			w := region.weight()
+			if excluded {
+				w = 0
+			}
+			r := region.Rect.Intersect(image.Rect(0, 0, width, height))
+			for y := r.Min.Y; y < r.Max.Y; y++ {
+				for x := r.Min.X; x < r.Max.X; x++ {
+					scale[y*width+x] = w
+				}
+			}
+		}
+	}
+	for i, s := range scale {
+		weights[i] *= s
+	}
+}
+
+// regionMask marks the pixels of the area r that lie in excluded regions,
+// in row-major order, or returns nil when no region is excluded.
+func regionMask(regions []Region, r image.Rectangle) []bool {
+	var mask []bool
+	for _, region := range regions {
+		if !region.Exclude {
+			continue
+		}
+		if mask == nil {
+			mask = make([]bool, r.Dx()*r.Dy())
+		}
+		clipped := region.Rect.Intersect(r)
+		for y := clipped.Min.Y; y < clipped.Max.Y; y++ {
+			for x := clipped.Min.X; x < clipped.Max.X; x++ {
+				mask[(y-r.Min.Y)*r.Dx()+x-r.Min.X] = true
+			}
+		}
+	}
+	return mask
+}
+
+// regionResults measures the error within every region clipped to the
+// region r of img1, compared with the same-sized region of img2 at origin2.
+func regionResults(img1, img2 *image.RGBA, r image.Rectangle, origin2 image.Point, hasAlpha bool, regions []Region) []RegionResult {
+	results := make([]RegionResult, len(regions))
+	for i, region := range regions {
+		clipped := region.Rect.Intersect(r)
+		results[i] = RegionResult{Label: region.Label, Pixels: clipped.Dx() * clipped.Dy()}
+		if results[i].Pixels == 0 {
+			results[i].PSNR = math.NaN()
+			continue
+		}
+		origin := origin2.Add(clipped.Min.Sub(r.Min))
+		// Nothing is excluded, so the region always has pixels to measure
+		results[i].MSE, _ = maskedMSE(img1, img2, clipped, origin, hasAlpha, make([]bool, results[i].Pixels))
+		results[i].PSNR = psnrFromMSE(results[i].MSE)
+	}
+	return results
+}
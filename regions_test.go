@@ -0,0 +1,80 @@
+package psnr
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestWithRegions(t *testing.T) {
+	ctx := context.Background()
+	img1 := image.NewGray(image.Rect(0, 0, 10, 10))
+	img2 := image.NewGray(image.Rect(0, 0, 10, 10))
+	// A damaged text line and one slightly different pixel elsewhere
+	for x := 0; x < 4; x++ {
+		img2.SetGray(x, 0, color.Gray{Y: 100})
+	}
+	img2.SetGray(9, 9, color.Gray{Y: 10})
+
+	text := Region{Label: "text", Rect: image.Rect(0, 0, 4, 1), Exclude: true}
+	result, err := CompareImages(ctx, img1, img2, WithRegions(text, Region{Label: "outside", Rect: image.Rect(20, 20, 30, 30)}))
+	if err != nil {
+		t.Fatalf("CompareImages failed: %v", err)
+	}
+	if want := 100.0 / 96; math.Abs(result.MSE-want) > 1e-9 {
+		t.Errorf("Expected MSE %v without the excluded region, got %v", want, result.MSE)
+	}
+	if len(result.Regions) != 2 {
+		t.Fatalf("Expected 2 region results, got %+v", result.Regions)
+	}
+	if r := result.Regions[0]; r.Label != "text" || r.Pixels != 4 || r.MSE != 10000 {
+		t.Errorf("Unexpected text region result: %+v", r)
+	}
+	if r := result.Regions[1]; r.Pixels != 0 || !math.IsNaN(r.PSNR) {
+		t.Errorf("Expected a NaN PSNR outside the image, got %+v", r)
+	}
+	if result.WeightedPSNR != 0 {
+		t.Errorf("Expected no weighting without weights, got %v", result.WeightedPSNR)
+	}
+
+	// Weighting the text line raises its share of the weighted error
+	text.Exclude, text.Weight = false, 4
+	result, err = CompareImages(ctx, img1, img2, WithRegions(text))
+	if err != nil {
+		t.Fatalf("CompareImages failed: %v", err)
+	}
+	if want := (4*4*10000.0 + 100) / (4*4 + 96); math.Abs(result.WeightedMSE-want) > 1e-9 {
+		t.Errorf("Expected weighted MSE %v, got %v", want, result.WeightedMSE)
+	}
+	if want := (4*10000.0 + 100) / 100; math.Abs(result.MSE-want) > 1e-9 {
+		t.Errorf("Expected MSE %v over every pixel, got %v", want, result.MSE)
+	}
+
+	// Excluded pixels weigh nothing in the weighted error either
+	text.Exclude = true
+	corner := Region{Label: "corner", Rect: image.Rect(9, 9, 10, 10), Weight: 2}
+	result, err = CompareImages(ctx, img1, img2, WithRegions(text, corner))
+	if err != nil {
+		t.Fatalf("CompareImages failed: %v", err)
+	}
+	if want := 2 * 100.0 / (2 + 95); math.Abs(result.WeightedMSE-want) > 1e-9 {
+		t.Errorf("Expected weighted MSE %v without the excluded region, got %v", want, result.WeightedMSE)
+	}
+
+	text.Exclude, text.Weight = false, -1
+	if _, err := CompareImages(ctx, img1, img2, WithRegions(text)); err == nil {
+		t.Error("Expected error for a negative weight")
+	}
+}
+
+func TestRegionMask(t *testing.T) {
+	if regionMask([]Region{{Rect: image.Rect(0, 0, 2, 2)}}, image.Rect(0, 0, 4, 4)) != nil {
+		t.Error("Expected no mask without excluded regions")
+	}
+	mask := regionMask([]Region{{Rect: image.Rect(-1, -1, 2, 2), Exclude: true}}, image.Rect(1, 1, 4, 4))
+	if len(mask) != 9 || !mask[0] || countMask(mask) != 1 {
+		t.Errorf("Expected only the first pixel of the area, got %v", mask)
+	}
+}
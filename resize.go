@@ -0,0 +1,142 @@
+package psnr
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// lanczosLobes is the number of lobes of the Lanczos filter used by
+// WithEvaluationSize.
+const lanczosLobes = 3
+
+// evaluationSize returns the dimensions an image of the given size is
+// downscaled to so that its long edge is at most longEdge, keeping the
+// aspect ratio. Images that already fit keep their size.
+func evaluationSize(size image.Point, longEdge int) image.Point {
+	long := max(size.X, size.Y)
+	if long <= longEdge {
+		return size
+	}
+	scale := float64(longEdge) / float64(long)
+	return image.Point{
+		X: max(int(math.Round(float64(size.X)*scale)), 1),
+		Y: max(int(math.Round(float64(size.Y)*scale)), 1),
+	}
+}
+
+// downscaleDecoded applies WithEvaluationSize to a decoded image.
+func downscaleDecoded(d *decoded, longEdge int) *decoded {
+	size := d.img.Bounds().Size()
+	target := evaluationSize(size, longEdge)
+	if target == size {
+		return d
+	}
+	return d.withImage(downscaleLanczos(d.img, target.X, target.Y))
+}
+
+// downscaleLanczos resizes img to width x height with a separable Lanczos-3
+// filter widened by the scale factor, so detail finer than the output grid
+// is averaged out rather than aliased. It filters premultiplied 16-bit
+// samples and returns straight ones, so translucent colors and deep images
+// keep their precision.
+func downscaleLanczos(img image.Image, width, height int) *image.NRGBA64 {
+	src := toRGBA64(img)
+	srcWidth, srcHeight := src.Rect.Dx(), src.Rect.Dy()
+
+	// Horizontal pass into a float buffer of width x srcHeight
+	columns := lanczosTaps(srcWidth, width)
+	tmp := make([]float32, width*srcHeight*4)
+	for y := 0; y < srcHeight; y++ {
+		row := src.Pix[y*src.Stride:]
+		for x, taps := range columns {
+			var sum [4]float32
+			for k, w := range taps.weights {
+				p := row[(taps.first+k)*8:]
+				for c := range sum {
+					sum[c] += w * float32(uint16(p[2*c])<<8|uint16(p[2*c+1]))
+				}
+			}
+			copy(tmp[(y*width+x)*4:], sum[:])
+		}
+	}
+
+	// Vertical pass into the result
+	rows := lanczosTaps(srcHeight, height)
+	dst := image.NewNRGBA64(image.Rect(0, 0, width, height))
+	for y, taps := range rows {
+		for x := 0; x < width; x++ {
+			var sum [4]float32
+			for k, w := range taps.weights {
+				p := tmp[((taps.first+k)*width+x)*4:]
+				sum[0] += w * p[0]
+				sum[1] += w * p[1]
+				sum[2] += w * p[2]
+				sum[3] += w * p[3]
+			}
+			alpha := clampSample16(sum[3])
+			var c color.NRGBA64
+			if alpha > 0 {
+				// Premultiplied color cannot exceed alpha
+				a := float32(alpha)
+				c = color.NRGBA64{
+					R: clampSample16(min(sum[0], a) * 0xffff / a),
+					G: clampSample16(min(sum[1], a) * 0xffff / a),
+					B: clampSample16(min(sum[2], a) * 0xffff / a),
+					A: alpha,
+				}
+			}
+			dst.SetNRGBA64(x, y, c)
+		}
+	}
+	return dst
+}
+
+// lanczosTap lists the normalized weights of the source samples
+// contributing to one output sample, starting at first.
+type lanczosTap struct {
+	first   int
+	weights []float32
+}
+
+// lanczosTaps computes the taps of each of dstSize output samples resampled
+// from srcSize input samples.
+func lanczosTaps(srcSize, dstSize int) []lanczosTap {
+	scale := float64(srcSize) / float64(dstSize)
+	filterScale := max(scale, 1)
+	support := lanczosLobes * filterScale
+
+	taps := make([]lanczosTap, dstSize)
+	for i := range taps {
+		center := (float64(i)+0.5)*scale - 0.5
+		first := max(int(math.Floor(center-support))+1, 0)
+		last := min(int(math.Ceil(center+support))-1, srcSize-1)
+		weights := make([]float32, 0, last-first+1)
+		var total float64
+		for j := first; j <= last; j++ {
+			total += lanczos((float64(j) - center) / filterScale)
+		}
+		for j := first; j <= last; j++ {
+			weights = append(weights, float32(lanczos((float64(j)-center)/filterScale)/total))
+		}
+		taps[i] = lanczosTap{first: first, weights: weights}
+	}
+	return taps
+}
+
+// lanczos evaluates the Lanczos kernel at x.
+func lanczos(x float64) float64 {
+	switch {
+	case x == 0:
+		return 1
+	case x <= -lanczosLobes || x >= lanczosLobes:
+		return 0
+	}
+	px := math.Pi * x
+	return lanczosLobes * math.Sin(px) * math.Sin(px/lanczosLobes) / (px * px)
+}
+
+// clampSample16 rounds v to the nearest 16-bit sample value.
+func clampSample16(v float32) uint16 {
+	return uint16(min(max(math.Round(float64(v)), 0), 0xffff))
+}
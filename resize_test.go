@@ -0,0 +1,103 @@
+package psnr
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"testing"
+)
+
+func TestEvaluationSize(t *testing.T) {
+	tests := []struct {
+		size     image.Point
+		longEdge int
+		want     image.Point
+	}{
+		{image.Pt(4000, 3000), 1024, image.Pt(1024, 768)},
+		{image.Pt(3000, 4000), 1024, image.Pt(768, 1024)},
+		{image.Pt(800, 600), 1024, image.Pt(800, 600)},
+		{image.Pt(3000, 1), 1024, image.Pt(1024, 1)},
+	}
+	for _, tt := range tests {
+		if got := evaluationSize(tt.size, tt.longEdge); got != tt.want {
+			t.Errorf("evaluationSize(%v, %d) = %v, want %v", tt.size, tt.longEdge, got, tt.want)
+		}
+	}
+}
+
+func TestDownscaleLanczos(t *testing.T) {
+	for _, c := range []color.NRGBA{{200, 100, 50, 255}, {200, 100, 50, 3}} {
+		solid := SolidImage(image.Rect(0, 0, 37, 23), c)
+		want := color.NRGBA64Model.Convert(solid.At(0, 0)).(color.NRGBA64)
+		small := downscaleLanczos(solid, 10, 6)
+		for y := 0; y < 6; y++ {
+			for x := 0; x < 10; x++ {
+				got := small.NRGBA64At(x, y)
+				if got.A != want.A || absDiff16(got.R, want.R) > 1 || absDiff16(got.G, want.G) > 1 || absDiff16(got.B, want.B) > 1 {
+					t.Fatalf("Expected a solid image to stay %v, got %v at (%d, %d)", want, got, x, y)
+				}
+			}
+		}
+	}
+
+	// A one-pixel checkerboard averages to gray rather than aliasing
+	checker := image.NewGray(image.Rect(0, 0, 64, 64))
+	for i := range checker.Pix {
+		if (i%64+i/64)%2 == 0 {
+			checker.Pix[i] = 255
+		}
+	}
+	gray := downscaleLanczos(checker, 16, 16)
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			if v := gray.NRGBA64At(x, y).R >> 8; v < 120 || v > 135 {
+				t.Fatalf("Expected mid gray, got %d", v)
+			}
+		}
+	}
+}
+
+// absDiff16 returns |a-b|.
+func absDiff16(a, b uint16) uint16 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+func TestWithEvaluationSize(t *testing.T) {
+	data1, err := os.ReadFile("testdata/test_original.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data2, err := os.ReadFile("testdata/quality_50.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	full, err := ComputeDetailed(data1, data2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ComputeDetailed(data1, data2, WithEvaluationSize(320))
+	if err != nil {
+		t.Fatalf("ComputeDetailed failed: %v", err)
+	}
+	if result.EvaluationSize != image.Pt(320, 240) {
+		t.Errorf("Expected an evaluation size of 320x240, got %v", result.EvaluationSize)
+	}
+	if result.PSNR <= full.PSNR {
+		t.Errorf("Expected downscaling to hide compression noise, got %v dB vs %v dB", result.PSNR, full.PSNR)
+	}
+
+	identical, err := ComputeDetailed(data1, data1, WithEvaluationSize(320))
+	if err != nil || identical.EvaluationSize != image.Pt(320, 240) {
+		t.Errorf("Expected the evaluation size for identical inputs, got %+v, %v", identical, err)
+	}
+	if unchanged, _ := ComputeDetailed(data1, data2, WithEvaluationSize(1024)); unchanged.PSNR != full.PSNR || unchanged.EvaluationSize != image.Pt(640, 480) {
+		t.Errorf("Expected small images to be compared as they are, got %+v", unchanged)
+	}
+	if _, err := ComputeDetailed(data1, data2, WithEvaluationSize(-1)); err == nil {
+		t.Error("Expected error for a negative size")
+	}
+}
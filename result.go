@@ -0,0 +1,101 @@
+package psnr
+
+import "image"
+
+// Result holds the outcome of a detailed PSNR computation.
+type Result struct {
+	// PSNR is the peak signal-to-noise ratio in dB, +Inf for identical images.
+	PSNR float64
+	// MSE is the mean squared error per sample on the 0-255 scale.
+	MSE float64
+	// Peak is the peak signal value PSNR was measured against, on the same
+	// scale as MSE: 255 unless WithPeakMode selected another.
+	Peak float64
+	// Coverage is the fraction of rows compared: 1, unless WithTolerantDecode
+	// recovered a truncated image and only its decoded area was compared.
+	Coverage float64
+	// Offset is the translation detected by WithAlignment or
+	// WithCropSearch: pixel (x, y) of the first image was matched with pixel
+	// (x+Offset.X, y+Offset.Y) of the second image.
+	Offset image.Point
+	// Normalized holds the fit and PSNR measured by WithNormalization, or
+	// nil when normalization was not requested.
+	Normalized *Normalization
+	// WeightedPSNR and WeightedMSE are measured with the per-pixel weights
+	// from WithEdgeWeighting, WithSaliencyMap or WithSphericalWeighting; both
+	// are zero otherwise.
+	WeightedPSNR float64
+	WeightedMSE  float64
+	// Metrics holds the values of the metrics requested with WithMetrics,
+	// keyed by name.
+	Metrics map[string]float64
+	// Planes holds the per-plane results of CompatibilityFFmpeg (Y, U, V,
+	// or R, G, B for inputs compared as planar RGB), or nil in other modes.
+	Planes []PlaneResult
+	// Diff locates the differing pixels when WithDiffReport was used, and is
+	// nil otherwise.
+	Diff *DiffReport
+	// Histogram holds the absolute differences collected by
+	// WithErrorHistogram, or nil when it was not requested.
+	Histogram *ErrorHistogram
+	// Components holds the luma, chroma and alpha errors measured by
+	// WithComponentPSNR, or nil when they were not requested.
+	Components *ComponentResult
+	// Dither holds the box-filtered error measured by WithDitherTolerance,
+	// or nil when it was not requested.
+	Dither *DitherResult
+	// AntiAliasing counts the differing pixels classified by
+	// WithAntiAliasing, or is nil when it was not requested.
+	AntiAliasing *AntiAliasingResult
+	// IgnoredPixels is the number of pixels left out of the MSE and PSNR by
+	// WithIgnoreColor.
+	IgnoredPixels int
+	// Regions holds the error within each region passed to WithRegions, in
+	// the same order, or is nil when none were. Regions outside the compared
+	// area have a NaN PSNR.
+	Regions []RegionResult
+	// ColorSpace holds the error measured in the color space selected by
+	// WithColorSpace, or nil when none was.
+	ColorSpace *ColorSpaceResult
+	// HDR holds the error in light measured by WithHDR, or nil when it was
+	// not requested.
+	HDR *HDRResult
+	// Metadata compares the metadata of the encoded images when
+	// WithMetadataDiff was used, and is nil otherwise.
+	Metadata *MetadataDiff
+	// Inputs describes the first and second image when WithInputInfo was
+	// used, and is nil otherwise.
+	Inputs []InputInfo
+	// BlurSigma is the standard deviation of the Gaussian blur applied to
+	// both images by WithGaussianBlur, or zero.
+	BlurSigma float64
+	// EvaluationSize is the size of the first image as compared after
+	// WithEvaluationSize downscaled it, or zero without that option.
+	EvaluationSize image.Point
+	// Orientation is the transform WithOrientationSearch applied to the
+	// second image to match the first, or zero without that option.
+	Orientation Orientation
+	// Borders holds the margins of the bars WithBorderCrop detected in the
+	// first and second image, or is nil without that option.
+	Borders []Margins
+	// Frequency splits the error into low and high frequencies when
+	// WithFrequencySplit was used, and is nil otherwise.
+	Frequency *FrequencyResult
+	// Warnings lists the approximations applied to compute the result, in
+	// pipeline order, or is nil when the whole of both images was compared
+	// as decoded.
+	Warnings []Warning
+	// Stats measures the decoding and comparison when WithStats was used,
+	// and is nil otherwise.
+	Stats *Stats
+}
+
+// PlaneResult is the PSNR of a single image plane.
+type PlaneResult struct {
+	// Name is the plane name: "y", "u" or "v" for YCbCr planes, "r", "g"
+	// or "b" for the RGB planes CompatibilityFFmpeg compares, "low" or
+	// "high" in a FrequencyResult, or the index of a PlanarImage plane.
+	Name string
+	PSNR float64
+	MSE  float64
+}
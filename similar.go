@@ -0,0 +1,122 @@
+package psnr
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// similarSampleStride is the row stride of Similar's sampling pass.
+const similarSampleStride = 16
+
+// similarGridStride is the spacing, in both directions, of the pixels of
+// Similar's downscale prefilter: one pixel in 64 is compared.
+const similarGridStride = 8
+
+// Similar reports whether two images have a PSNR of at least minPSNR, as
+// ComputeDetailed would measure it, and is optimized for deduplication jobs
+// that only need the yes/no answer. Byte-identical inputs return true after
+// decoding only one of them, and images whose headers declare different
+// dimensions return false without decoding. Otherwise a nearest-neighbor
+// downscale of both images is compared first, then every 16th row: the
+// squared error of those pixels alone is a lower bound of the total, so
+// clearly different images are rejected after reading a fraction of them.
+// The remaining rows are then accumulated until the error budget for
+// minPSNR is exceeded. Every shortcut is exact, so the answer never differs
+// from comparing the full PSNR. A NaN minPSNR is an error.
+func Similar(data1, data2 []byte, minPSNR float64) (bool, error) {
+	if math.IsNaN(minPSNR) {
+		return false, fmt.Errorf("invalid minimum PSNR: %v", minPSNR)
+	}
+	o := &options{}
+	if _, ok, err := identicalResult(data1, data2, o); ok || err != nil {
+		return ok, err
+	}
+	config1, _, err1 := image.DecodeConfig(bytes.NewReader(data1))
+	config2, _, err2 := image.DecodeConfig(bytes.NewReader(data2))
+	if err1 == nil && err2 == nil && (config1.Width != config2.Width || config1.Height != config2.Height) {
+		return false, nil
+	}
+
+	d1, d2, err := decodePair(data1, data2, o)
+	if err != nil {
+		return false, err
+	}
+	if checkDimensions(d1.img, d2.img) != nil {
+		return false, nil
+	}
+	return similarWithin(d1, d2, 65025/math.Pow(10, minPSNR/10)), nil
+}
+
+// similarWithin reports whether the MSE of two decoded images of the same
+// size is at most maxMSE, stopping as soon as the answer is known.
+func similarWithin(d1, d2 *decoded, maxMSE float64) bool {
+	hasAlpha := detectAlpha(d1, d2)
+	channelCount := 3
+	if hasAlpha {
+		channelCount = 4
+	}
+	bounds := d1.img.Bounds()
+	height := bounds.Dy()
+	budget := maxMSE * float64(bounds.Dx()*height*channelCount)
+	if float64(downscaleErrorBound(d1.img, d2.img, hasAlpha)) > budget {
+		return false
+	}
+	kernel, _ := selectDecodedKernel(d1, d2, d1.img, d2.img, hasAlpha)
+
+	var sum uint64
+	for y := 0; y < height; y += similarSampleStride {
+		sum += kernel(y, y+1)
+		if float64(sum) > budget {
+			return false
+		}
+	}
+	for y := 0; y < height; y += similarSampleStride {
+		sum += kernel(min(y+1, height), min(y+similarSampleStride, height))
+		if float64(sum) > budget {
+			return false
+		}
+	}
+	return true
+}
+
+// downscaleErrorBound compares every similarGridStride-th pixel of every
+// similarGridStride-th row of two images of the same size, in the samples
+// the MSE kernels compare. Those samples may be rounded differently from the
+// kernels' by one step per image, so each difference is reduced by two
+// before it is squared, which keeps the sum a lower bound of the kernels'.
+func downscaleErrorBound(img1, img2 image.Image, hasAlpha bool) uint64 {
+	model := color.RGBAModel
+	if comparesStraight(img1, img2, AlphaAuto) {
+		model = color.NRGBAModel
+	}
+	sample := func(img image.Image, x, y int) [4]int32 {
+		r, g, b, a := model.Convert(img.At(x, y)).RGBA()
+		return [4]int32{int32(r >> 8), int32(g >> 8), int32(b >> 8), int32(a >> 8)}
+	}
+	channels := 3
+	if hasAlpha {
+		channels = 4
+	}
+
+	b1, b2 := img1.Bounds(), img2.Bounds()
+	var sum uint64
+	for y := 0; y < b1.Dy(); y += similarGridStride {
+		for x := 0; x < b1.Dx(); x += similarGridStride {
+			p := sample(img1, b1.Min.X+x, b1.Min.Y+y)
+			q := sample(img2, b2.Min.X+x, b2.Min.Y+y)
+			for c := 0; c < channels; c++ {
+				d := p[c] - q[c]
+				if d < 0 {
+					d = -d
+				}
+				if d > 2 {
+					sum += uint64((d - 2) * (d - 2))
+				}
+			}
+		}
+	}
+	return sum
+}
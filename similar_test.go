@@ -0,0 +1,98 @@
+package psnr
+
+import (
+	"image"
+	"math"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+func TestSimilar(t *testing.T) {
+	read := func(file string) []byte {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", file, err)
+		}
+		return data
+	}
+	original := read("testdata/test_original.jpg")
+	compressed := read("testdata/quality_50.jpg")
+	fullcolor := read("testdata/fullcolor.png")
+	palette := read("testdata/palette256.png")
+
+	tests := []struct {
+		name         string
+		data1, data2 []byte
+	}{
+		{"identical", original, original},
+		{"JPEG quality 50", original, compressed},
+		{"palette PNG", fullcolor, palette},
+		{"different sizes", read("testdata/size1.jpg"), read("testdata/size2.jpg")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			psnr, err := Compute(tt.data1, tt.data2)
+			dimensionsDiffer := err != nil
+
+			for _, threshold := range []float64{20, 30, 40, 42, 45, 60} {
+				got, err := Similar(tt.data1, tt.data2, threshold)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				want := !dimensionsDiffer && psnr >= threshold
+				if got != want {
+					t.Errorf("Threshold %.0f: expected %v for PSNR %.4f, got %v", threshold, want, psnr, got)
+				}
+			}
+		})
+	}
+
+	if _, err := Similar([]byte("not an image"), original, 30); err == nil {
+		t.Error("Expected decode error")
+	}
+	// NaN compares false against every PSNR, which would accept any pair
+	if _, err := Similar(original, compressed, math.NaN()); err == nil {
+		t.Error("Expected error for a NaN minimum PSNR")
+	}
+}
+
+func TestDownscaleErrorBound(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	translucent := func() *image.NRGBA {
+		img := image.NewNRGBA(image.Rect(0, 0, 40, 24))
+		for i := range img.Pix {
+			img.Pix[i] = uint8(rng.Intn(256))
+		}
+		return img
+	}
+	pairs := [][2]image.Image{
+		{randomYCbCr(rng, 40, 24, image.YCbCrSubsampleRatio420), randomYCbCr(rng, 40, 24, image.YCbCrSubsampleRatio420)},
+		{randomYCbCr(rng, 40, 24, image.YCbCrSubsampleRatio444), toRGBA(randomYCbCr(rng, 40, 24, image.YCbCrSubsampleRatio444))},
+		{translucent(), translucent()},
+		{translucent(), toRGBA(translucent())},
+	}
+
+	// The prefilter must never exceed the error the kernels measure
+	for i, pair := range pairs {
+		for _, hasAlpha := range []bool{false, true} {
+			d1, d2 := &decoded{img: pair[0]}, &decoded{img: pair[1]}
+			kernel, _ := selectDecodedKernel(d1, d2, pair[0], pair[1], hasAlpha)
+			bound, total := downscaleErrorBound(pair[0], pair[1], hasAlpha), kernel(0, 24)
+			if bound == 0 || bound > total {
+				t.Errorf("Pair %d (alpha %v): expected a bound in (0, %d], got %d", i, hasAlpha, total, bound)
+			}
+		}
+	}
+
+	// A global shift is caught by the downscale alone
+	img1 := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	img2 := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for i := range img1.Pix {
+		img1.Pix[i], img2.Pix[i] = 100, 120
+	}
+	if bound := downscaleErrorBound(img1, img2, false); bound != 64*3*18*18 {
+		t.Errorf("Expected a bound of %d, got %d", 64*3*18*18, bound)
+	}
+}
@@ -0,0 +1,52 @@
+package psnr
+
+import "math"
+
+// ComputeSNR calculates the signal-to-noise ratio in dB between a reference
+// image and a distorted one: the energy of the reference samples divided by
+// the energy of the error, instead of a fixed peak. It is +Inf for
+// identical images and -Inf when an all-black reference is compared with a
+// different image. Samples are compared like Compute does, straight when
+// both images are non-premultiplied, with alpha only when either image has
+// it.
+func ComputeSNR(image1Bytes, image2Bytes []byte) (float64, error) {
+	d1, d2, err := decodePair(image1Bytes, image2Bytes, &options{})
+	if err != nil {
+		return 0, err
+	}
+	if err := checkDimensions(d1.img, d2.img); err != nil {
+		return 0, err
+	}
+
+	hasAlpha := detectAlpha(d1, d2)
+	channelCount := 3
+	if hasAlpha {
+		channelCount = 4
+	}
+	rgba1, rgba2 := comparisonRGBA(d1.img, d2.img, AlphaAuto)
+	width, height := rgba1.Rect.Dx(), rgba1.Rect.Dy()
+
+	var signal, noise uint64
+	for y := 0; y < height; y++ {
+		row1 := rgba1.Pix[rgba1.PixOffset(0, y):]
+		row2 := rgba2.Pix[rgba2.PixOffset(0, y):]
+		for x := 0; x < width; x++ {
+			for c := 0; c < channelCount; c++ {
+				s := uint64(row1[x*4+c])
+				diff := int64(row1[x*4+c]) - int64(row2[x*4+c])
+				signal += s * s
+				noise += uint64(diff * diff)
+			}
+		}
+	}
+
+	return snrFromEnergy(signal, noise), nil
+}
+
+// snrFromEnergy converts signal and noise energies into dB.
+func snrFromEnergy(signal, noise uint64) float64 {
+	if noise == 0 {
+		return math.Inf(1)
+	}
+	return 10 * math.Log10(float64(signal)/float64(noise))
+}
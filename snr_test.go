@@ -0,0 +1,89 @@
+package psnr
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestComputeSNR(t *testing.T) {
+	encode := func(img image.Image) []byte {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			t.Fatalf("Failed to encode image: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	// Every sample of the reference is 100 and differs by 10: SNR = 20 dB
+	img1 := image.NewGray(image.Rect(0, 0, 4, 4))
+	img2 := image.NewGray(img1.Rect)
+	for i := range img1.Pix {
+		img1.Pix[i] = 100
+		img2.Pix[i] = 90
+	}
+	snr, err := ComputeSNR(encode(img1), encode(img2))
+	if err != nil {
+		t.Fatalf("Error computing SNR: %v", err)
+	}
+	if math.Abs(snr-20) > 1e-9 {
+		t.Errorf("Expected 20 dB, got %f", snr)
+	}
+
+	// Unlike PSNR, SNR depends on the signal level
+	psnr, err := Compute(encode(img1), encode(img2))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if want := 20 * math.Log10(255.0/100); math.Abs(psnr-snr-want) > 1e-9 {
+		t.Errorf("Expected PSNR - SNR = %f, got %f", want, psnr-snr)
+	}
+
+	snr, err = ComputeSNR(encode(img1), encode(img1))
+	if err != nil {
+		t.Fatalf("Error computing SNR: %v", err)
+	}
+	if !math.IsInf(snr, 1) {
+		t.Errorf("Expected Inf for identical images, got %f", snr)
+	}
+
+	black := image.NewGray(img1.Rect)
+	snr, err = ComputeSNR(encode(black), encode(img1))
+	if err != nil {
+		t.Fatalf("Error computing SNR: %v", err)
+	}
+	if !math.IsInf(snr, -1) {
+		t.Errorf("Expected -Inf for a black reference, got %f", snr)
+	}
+
+	// Translucent NRGBA images are compared straight, as Compute does: the
+	// noise is the same MSE over the same samples
+	nrgba1 := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	nrgba2 := image.NewNRGBA(nrgba1.Rect)
+	nrgba1.SetNRGBA(0, 0, color.NRGBA{200, 100, 50, 64})
+	nrgba2.SetNRGBA(0, 0, color.NRGBA{180, 100, 50, 64})
+	nrgba1.SetNRGBA(1, 0, color.NRGBA{10, 20, 30, 255})
+	nrgba2.SetNRGBA(1, 0, color.NRGBA{10, 20, 30, 255})
+	snr, err = ComputeSNR(encode(nrgba1), encode(nrgba2))
+	if err != nil {
+		t.Fatalf("Error computing SNR: %v", err)
+	}
+	result, err := ComputeDetailed(encode(nrgba1), encode(nrgba2))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	signal := 200.0*200 + 100*100 + 50*50 + 64*64 + 10*10 + 20*20 + 30*30 + 255*255
+	if want := 10 * math.Log10(signal/(result.MSE*8)); math.Abs(snr-want) > 1e-9 {
+		t.Errorf("Expected %f dB, got %f", want, snr)
+	}
+
+	size1, _ := os.ReadFile("testdata/size1.jpg")
+	size2, _ := os.ReadFile("testdata/size2.jpg")
+	if _, err := ComputeSNR(size1, size2); err == nil {
+		t.Error("Expected error for different dimensions")
+	}
+}
@@ -0,0 +1,74 @@
+package psnr
+
+import (
+	runtimemetrics "runtime/metrics"
+	"time"
+)
+
+// pathIdentical is the Stats.Path of byte-identical inputs, which are
+// reported as +Inf after decoding only the first.
+const pathIdentical = "identical"
+
+// pathCached is the Stats.Path of results served from a batch cache.
+const pathCached = "cached"
+
+// Stats measures the work done for one comparison, as reported in
+// Result.Stats by WithStats, to track the performance of the library
+// itself in production.
+type Stats struct {
+	// DecodeTime is the time spent decoding both inputs, and zero when they
+	// were passed decoded or not decoded at all.
+	DecodeTime time.Duration
+	// CompareTime is the time spent preparing and comparing the decoded
+	// images, including resizing, cropping and blurring.
+	CompareTime time.Duration
+	// Decoders names the decoder of the first and second image: a
+	// registered backend such as "libjpeg", "image/" and the format for
+	// the standard library, "tolerant" for a recovered truncated image, or
+	// "memory" for images passed decoded.
+	Decoders [2]string
+	// Path names the comparison kernel, such as "rgba", "ycbcr" or
+	// "generic", the compatibility mode, "identical" when the inputs were
+	// byte-identical, or "cached" when the result came from a batch cache,
+	// in which case the times and allocations are those of the lookup.
+	Path string
+	// Pixels is the number of pixels compared per image.
+	Pixels int
+	// PixelsPerSecond is Pixels divided by CompareTime.
+	PixelsPerSecond float64
+	// AllocatedBytes is the number of bytes allocated on the heap during
+	// the call. It is read from the runtime's metrics without stopping the
+	// world, so allocations by other goroutines running at the same time are
+	// counted too, and small allocations may be counted a little late.
+	AllocatedBytes uint64
+}
+
+// heapAllocsMetric is the runtime metric of the cumulative bytes allocated
+// on the heap. Unlike runtime.ReadMemStats, reading it does not stop the
+// world.
+const heapAllocsMetric = "/gc/heap/allocs:bytes"
+
+// allocatedBytes returns the total number of bytes allocated on the heap so
+// far.
+func allocatedBytes() uint64 {
+	sample := []runtimemetrics.Sample{{Name: heapAllocsMetric}}
+	runtimemetrics.Read(sample)
+	if sample[0].Value.Kind() != runtimemetrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
+
+// compatPath returns the Stats.Path of the comparisons done by a
+// compatibility mode rather than by the kernels of compareImages.
+func compatPath(mode Compatibility) string {
+	switch mode {
+	case CompatibilityFFmpeg:
+		return "ffmpeg"
+	case CompatibilityImageMagick:
+		return "imagemagick"
+	case CompatibilityOpenCV:
+		return "opencv"
+	}
+	return pathGeneric
+}
@@ -0,0 +1,84 @@
+package psnr
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"os"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	jpeg1, err := os.ReadFile("testdata/test_original.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	jpeg2, err := os.ReadFile("testdata/quality_50.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ComputeDetailed(jpeg1, jpeg2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Stats != nil {
+		t.Errorf("Expected no stats without WithStats, got %+v", result.Stats)
+	}
+
+	result, err = ComputeDetailed(jpeg1, jpeg2, WithStats())
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := result.Stats
+	if st == nil {
+		t.Fatal("Expected stats with WithStats")
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(jpeg1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Pixels != config.Width*config.Height {
+		t.Errorf("Expected %d pixels, got %d", config.Width*config.Height, st.Pixels)
+	}
+	if st.DecodeTime <= 0 || st.CompareTime <= 0 || st.PixelsPerSecond <= 0 {
+		t.Errorf("Expected positive times and throughput, got %+v", st)
+	}
+	if st.AllocatedBytes == 0 {
+		t.Errorf("Expected decoding to allocate, got %+v", st)
+	}
+	for i, name := range st.Decoders {
+		if name == "" || name == memoryFormat {
+			t.Errorf("Expected a decoder name for image %d, got %q", i+1, name)
+		}
+	}
+	if st.Path == "" {
+		t.Error("Expected a kernel path")
+	}
+
+	result, err = ComputeDetailed(jpeg1, jpeg1, WithStats())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Stats == nil || result.Stats.Path != pathIdentical || result.Stats.Pixels != st.Pixels {
+		t.Errorf("Expected identical stats, got %+v", result.Stats)
+	}
+
+	img1 := orientationTestImage(8, 8)
+	img2 := perturb(img1, 0, 4, 10)
+	result, err = CompareImages(context.Background(), img1, img2, WithStats())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st := result.Stats; st == nil || st.Decoders != [2]string{memoryFormat, memoryFormat} || st.DecodeTime != 0 || st.Pixels != 64 {
+		t.Errorf("Unexpected stats for decoded images: %+v", result.Stats)
+	}
+
+	result, err = CompareImages(context.Background(), img1, img2, WithStats(), WithCompatibility(CompatibilityOpenCV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Stats == nil || result.Stats.Path != "opencv" {
+		t.Errorf("Expected the compatibility mode as path, got %+v", result.Stats)
+	}
+}
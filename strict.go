@@ -0,0 +1,89 @@
+package psnr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var (
+	// ErrAlphaMismatch is returned by WithStrict when only one input has an
+	// alpha channel.
+	ErrAlphaMismatch = errors.New("inputs differ in alpha channel")
+	// ErrColorModelMismatch is returned by WithStrict when the inputs are
+	// stored in different color models, such as Y'CbCr and RGB.
+	ErrColorModelMismatch = errors.New("inputs differ in color model")
+	// ErrBitDepthMismatch is returned by WithStrict when the inputs are
+	// stored with different bits per sample.
+	ErrBitDepthMismatch = errors.New("inputs differ in bit depth")
+	// ErrICCMismatch is returned by WithStrict when the inputs embed
+	// different ICC profiles, or only one embeds a profile.
+	ErrICCMismatch = errors.New("inputs differ in ICC profile")
+)
+
+// MismatchError is the error WithStrict returns for inputs that would need
+// a conversion to be compared. It wraps one of ErrAlphaMismatch,
+// ErrColorModelMismatch, ErrBitDepthMismatch or ErrICCMismatch, for use
+// with errors.Is.
+type MismatchError struct {
+	Err error
+	// First and Second describe the property of each input, such as "ycbcr"
+	// and "rgb".
+	First, Second string
+}
+
+// Error returns the mismatch and both values.
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("%v: %s vs %s", e.Err, e.First, e.Second)
+}
+
+// Unwrap returns the sentinel error of the mismatch.
+func (e *MismatchError) Unwrap() error {
+	return e.Err
+}
+
+// checkStrict returns a MismatchError when the inputs differ in alpha
+// channel, color model, bit depth or ICC profile as stored, before any
+// normalization.
+func checkStrict(d1, d2 *decoded) error {
+	if a1, a2 := hasAlphaChannel(d1), hasAlphaChannel(d2); a1 != a2 {
+		return &MismatchError{Err: ErrAlphaMismatch, First: alphaName(a1), Second: alphaName(a2)}
+	}
+	in1, in2 := describeInput(d1), describeInput(d2)
+	if in1.ColorModel != in2.ColorModel {
+		return &MismatchError{Err: ErrColorModelMismatch, First: in1.ColorModel, Second: in2.ColorModel}
+	}
+	if in1.BitDepth != in2.BitDepth {
+		return &MismatchError{Err: ErrBitDepthMismatch, First: bitDepthName(in1.BitDepth), Second: bitDepthName(in2.BitDepth)}
+	}
+	icc1, icc2 := readMetadata(d1.data)[MetadataICC], readMetadata(d2.data)[MetadataICC]
+	if !bytes.Equal(icc1, icc2) {
+		return &MismatchError{Err: ErrICCMismatch, First: iccName(icc1), Second: iccName(icc2)}
+	}
+	return nil
+}
+
+// alphaName describes the presence of an alpha channel.
+func alphaName(alpha bool) string {
+	if alpha {
+		return "alpha"
+	}
+	return "no alpha"
+}
+
+// bitDepthName describes a bit depth, which is zero when unknown.
+func bitDepthName(depth int) string {
+	if depth == 0 {
+		return "unknown"
+	}
+	return strconv.Itoa(depth) + "-bit"
+}
+
+// iccName describes an embedded ICC profile.
+func iccName(profile []byte) string {
+	if profile == nil {
+		return "none"
+	}
+	return fmt.Sprintf("%d-byte profile", len(profile))
+}
@@ -0,0 +1,61 @@
+package psnr
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"image"
+	"image/color"
+	"os"
+	"testing"
+)
+
+func TestWithStrict(t *testing.T) {
+	jpeg1, err := os.ReadFile("testdata/test_original.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	jpeg2, err := os.ReadFile("testdata/quality_50.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ComputeDetailed(jpeg1, jpeg2, WithStrict()); err != nil {
+		t.Errorf("Expected matching JPEGs to compare, got %v", err)
+	}
+
+	bounds := image.Rect(0, 0, 8, 8)
+	gray := encodePNG(t, image.NewGray(bounds))
+	gray16 := encodePNG(t, image.NewGray16(bounds))
+	rgb := encodePNG(t, SolidImage(bounds, color.RGBA{10, 20, 30, 255}))
+	rgba := encodePNG(t, SolidImage(bounds, color.RGBA{10, 20, 30, 128}))
+	var profile bytes.Buffer
+	zw := zlib.NewWriter(&profile)
+	zw.Write(bytes.Repeat([]byte("profile"), 20))
+	zw.Close()
+	tagged := insertPNGChunk(gray, "iCCP", append([]byte("sRGB\x00\x00"), profile.Bytes()...))
+
+	tests := []struct {
+		name         string
+		data1, data2 []byte
+		want         error
+	}{
+		{"alpha", rgb, rgba, ErrAlphaMismatch},
+		{"color model", gray, rgb, ErrColorModelMismatch},
+		{"bit depth", gray, gray16, ErrBitDepthMismatch},
+		{"ICC profile", gray, tagged, ErrICCMismatch},
+	}
+	for _, tt := range tests {
+		_, err := ComputeDetailed(tt.data1, tt.data2, WithStrict())
+		var mismatch *MismatchError
+		if !errors.Is(err, tt.want) || !errors.As(err, &mismatch) || mismatch.First == mismatch.Second {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, err)
+		}
+		if _, err := ComputeDetailed(tt.data1, tt.data2); err != nil {
+			t.Errorf("%s: expected a normalized comparison without strict mode, got %v", tt.name, err)
+		}
+	}
+
+	if _, err := ComputeDetailed(jpeg1, encodePNG(t, decodeTestImage(t, jpeg1)), WithStrict()); !errors.Is(err, ErrColorModelMismatch) {
+		t.Errorf("Expected a JPEG and a PNG to differ in color model, got %v", err)
+	}
+}
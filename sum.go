@@ -0,0 +1,28 @@
+package psnr
+
+import "math"
+
+// compensatedSum adds float64 values with Neumaier's variant of Kahan
+// summation, which carries the rounding error of every addition, so the
+// total of many small terms is accurate regardless of their magnitude.
+// Callers sum each row plainly and combine the row totals in row order, so
+// results do not depend on how rows were split between goroutines.
+type compensatedSum struct {
+	sum, compensation float64
+}
+
+// add adds v to the sum.
+func (s *compensatedSum) add(v float64) {
+	t := s.sum + v
+	if math.Abs(s.sum) >= math.Abs(v) {
+		s.compensation += (s.sum - t) + v
+	} else {
+		s.compensation += (v - t) + s.sum
+	}
+	s.sum = t
+}
+
+// value returns the compensated total.
+func (s *compensatedSum) value() float64 {
+	return s.sum + s.compensation
+}
@@ -0,0 +1,60 @@
+package psnr
+
+import (
+	"os"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestCompensatedSum(t *testing.T) {
+	// Plain summation loses both ones to rounding
+	var s compensatedSum
+	for _, v := range []float64{1, 1e100, 1, -1e100} {
+		s.add(v)
+	}
+	if got := s.value(); got != 2 {
+		t.Errorf("Expected 2, got %v", got)
+	}
+
+	var tenths compensatedSum
+	for i := 0; i < 1e6; i++ {
+		tenths.add(0.1)
+	}
+	if got := tenths.value(); got != 100000 {
+		t.Errorf("Expected 100000, got %v", got)
+	}
+}
+
+func TestFloatResultsIndependentOfGOMAXPROCS(t *testing.T) {
+	data1, err := os.ReadFile("testdata/test_original.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data2, err := os.ReadFile("testdata/test_quality_85.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+
+	for name, opts := range map[string][]Option{
+		"colorspace":  {WithColorSpace(ColorSpaceOKLab), WithEdgeWeighting(), WithDeterministic()},
+		"hdr":         {WithHDR(HDROptions{PU21: true})},
+		"imagemagick": {WithCompatibility(CompatibilityImageMagick)},
+	} {
+		var results []*Result
+		for _, procs := range []int{1, 3, 8} {
+			runtime.GOMAXPROCS(procs)
+			result, err := ComputeDetailed(data1, data2, opts...)
+			if err != nil {
+				t.Fatalf("%s: ComputeDetailed failed: %v", name, err)
+			}
+			results = append(results, result)
+		}
+		for _, result := range results[1:] {
+			if !reflect.DeepEqual(result, results[0]) {
+				t.Errorf("%s: results differ across GOMAXPROCS: %+v and %+v", name, results[0], result)
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package psnr
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"math/rand/v2"
+)
+
+// SolidImage returns an image of the given bounds filled with c.
+func SolidImage(bounds image.Rectangle, c color.Color) *image.RGBA {
+	img := image.NewRGBA(bounds)
+	draw.Draw(img, bounds, image.NewUniform(c), image.Point{}, draw.Src)
+	return img
+}
+
+// GradientImage returns an image of the given bounds shading linearly from
+// the color from at its left edge to the color to at its right edge.
+func GradientImage(bounds image.Rectangle, from, to color.Color) *image.RGBA {
+	img := image.NewRGBA(bounds)
+	c1 := color.RGBAModel.Convert(from).(color.RGBA)
+	c2 := color.RGBAModel.Convert(to).(color.RGBA)
+	lerp := func(a, b uint8, t float64) uint8 {
+		return uint8(math.Round(float64(a) + (float64(b)-float64(a))*t))
+	}
+	width := bounds.Dx()
+	for x := bounds.Min.X; x < bounds.Max.X; x++ {
+		t := 0.0
+		if width > 1 {
+			t = float64(x-bounds.Min.X) / float64(width-1)
+		}
+		c := color.RGBA{lerp(c1.R, c2.R, t), lerp(c1.G, c2.G, t), lerp(c1.B, c2.B, t), lerp(c1.A, c2.A, t)}
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// NoisyImage returns a copy of img with Gaussian noise of standard
+// deviation sigma added to every straight (non-premultiplied) R, G and B
+// sample, rounded and clamped to 0-255; alpha is kept. The same seed always
+// yields the same noise. Away from clamping, comparing the result with an
+// opaque img gives an MSE of about sigma²+1/12 and the PSNR of
+// NoisePSNR(sigma).
+func NoisyImage(img image.Image, sigma float64, seed uint64) *image.NRGBA {
+	src := toNRGBA(img)
+	dst := image.NewNRGBA(src.Rect)
+	rng := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+	for i := 0; i < len(src.Pix); i += 4 {
+		for c := 0; c < 3; c++ {
+			v := float64(src.Pix[i+c]) + rng.NormFloat64()*sigma
+			dst.Pix[i+c] = uint8(min(max(math.Round(v), 0), 255))
+		}
+		dst.Pix[i+3] = src.Pix[i+3]
+	}
+	return dst
+}
+
+// NoisePSNR returns the PSNR expected between an image and a copy with
+// additive Gaussian noise of standard deviation sigma on the 0-255 scale,
+// ignoring clamping and rounding.
+func NoisePSNR(sigma float64) float64 {
+	return psnrFromMSE(sigma * sigma)
+}
+
+// CompareSolid compares img against a solid image of the same bounds
+// filled with c, e.g. to check that a rendering is blank or to calibrate a
+// threshold with a known MSE.
+func CompareSolid(ctx context.Context, img image.Image, c color.Color, opts ...Option) (*Result, error) {
+	return CompareImages(ctx, SolidImage(img.Bounds(), c), img, opts...)
+}
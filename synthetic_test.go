@@ -0,0 +1,67 @@
+package psnr
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestSyntheticReferences(t *testing.T) {
+	ctx := context.Background()
+	bounds := image.Rect(0, 0, 64, 64)
+
+	// A uniform offset of 10 on every color sample gives an MSE of exactly 100
+	result, err := CompareSolid(ctx, SolidImage(bounds, color.RGBA{110, 110, 110, 255}), color.RGBA{100, 100, 100, 255})
+	if err != nil {
+		t.Fatalf("CompareSolid failed: %v", err)
+	}
+	if want := 10 * math.Log10(65025/100.0); result.MSE != 100 || math.Abs(result.PSNR-want) > 1e-9 {
+		t.Errorf("Expected MSE 100 and %v dB, got %+v", want, result)
+	}
+
+	gradient := GradientImage(bounds, color.Black, color.White)
+	if gradient.RGBAAt(0, 5) != (color.RGBA{0, 0, 0, 255}) || gradient.RGBAAt(63, 5) != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("Unexpected gradient ends: %v %v", gradient.RGBAAt(0, 5), gradient.RGBAAt(63, 5))
+	}
+	if left, right := gradient.RGBAAt(20, 0).R, gradient.RGBAAt(40, 0).R; left >= right {
+		t.Errorf("Expected the gradient to brighten, got %d then %d", left, right)
+	}
+
+	gray := SolidImage(image.Rect(0, 0, 256, 256), color.Gray{Y: 128})
+	noisy := NoisyImage(gray, 5, 1)
+	result, err = CompareImages(ctx, gray, noisy)
+	if err != nil {
+		t.Fatalf("CompareImages failed: %v", err)
+	}
+	if want := 25 + 1.0/12; math.Abs(result.MSE-want)/want > 0.05 {
+		t.Errorf("Expected MSE near %v, got %v", want, result.MSE)
+	}
+	if math.Abs(result.PSNR-NoisePSNR(5)) > 0.25 {
+		t.Errorf("Expected about %v dB, got %v", NoisePSNR(5), result.PSNR)
+	}
+	if again := NoisyImage(gray, 5, 1); string(again.Pix) != string(noisy.Pix) {
+		t.Error("Expected the same seed to yield the same noise")
+	}
+
+	// Noise is added to straight samples, so faint pixels get the full sigma
+	faint := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	for i := 0; i < len(faint.Pix); i += 4 {
+		faint.Pix[i], faint.Pix[i+1], faint.Pix[i+2], faint.Pix[i+3] = 128, 128, 128, 8
+	}
+	var sum float64
+	noisyFaint := NoisyImage(faint, 5, 1)
+	for i := 0; i < len(faint.Pix); i += 4 {
+		if noisyFaint.Pix[i+3] != 8 {
+			t.Fatalf("Expected alpha to be kept, got %d", noisyFaint.Pix[i+3])
+		}
+		for c := 0; c < 3; c++ {
+			d := float64(noisyFaint.Pix[i+c]) - 128
+			sum += d * d
+		}
+	}
+	if mse := sum / float64(len(faint.Pix)/4*3); math.Abs(mse-25)/25 > 0.1 {
+		t.Errorf("Expected a straight-color MSE near 25, got %v", mse)
+	}
+}
@@ -0,0 +1,70 @@
+package psnr
+
+import (
+	"context"
+	"fmt"
+	"image"
+)
+
+// ThumbnailResult is the verification of one derivative by VerifyThumbnails.
+type ThumbnailResult struct {
+	// Index is the position of the derivative in the input slice.
+	Index int
+	// Size is the size of the derivative, which the original was resized
+	// to as the reference.
+	Size   image.Point
+	Result *Result
+	// Err is the error for this derivative; others are unaffected by it.
+	Err error
+}
+
+// VerifyThumbnails verifies a resize chain, such as the thumbnails a CDN
+// derives from an original: the original is resized to the size of each
+// derivative with the Lanczos filter of WithEvaluationSize as the
+// reference, and each derivative is compared with its reference. A
+// resampler that aliases, blurs or shifts the image scores well below a
+// re-encode of the reference at the same quality. The original is decoded
+// once; errors of single derivatives are reported in their results, and
+// opts configure every comparison.
+func VerifyThumbnails(ctx context.Context, original []byte, derivatives [][]byte, opts ...Option) ([]ThumbnailResult, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := o.decode(original)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode original: %w", err)
+	}
+
+	references := make(map[image.Point]*decoded)
+	results := make([]ThumbnailResult, len(derivatives))
+	for i, data := range derivatives {
+		results[i].Index = i
+		if err := ctx.Err(); err != nil {
+			results[i].Err = err
+			continue
+		}
+		d, err := o.decode(data)
+		if err != nil {
+			results[i].Err = fmt.Errorf("failed to decode derivative: %w", err)
+			continue
+		}
+		size := d.img.Bounds().Size()
+		results[i].Size = size
+		if size.X == 0 || size.Y == 0 {
+			results[i].Err = fmt.Errorf("derivative is empty")
+			continue
+		}
+
+		reference, ok := references[size]
+		if !ok {
+			reference = ref
+			if size != ref.img.Bounds().Size() {
+				reference = ref.withImage(downscaleLanczos(ref.img, size.X, size.Y))
+			}
+			references[size] = reference
+		}
+		results[i].Result, results[i].Err = compare(ctx, reference, d, o)
+	}
+	return results, nil
+}
@@ -0,0 +1,67 @@
+package psnr
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"testing"
+)
+
+func TestVerifyThumbnails(t *testing.T) {
+	encode := func(img image.Image) []byte {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	// Fine detail that a resampler must average out
+	img := NoisyImage(GradientImage(image.Rect(0, 0, 128, 96), color.Black, color.White), 30, 1)
+	original := encode(img)
+	size := img.Bounds().Size()
+
+	// A nearest-neighbour resampler aliases
+	width, height := size.X/4, size.Y/4
+	nearest := image.NewRGBA(image.Rect(0, 0, width, height))
+	src := toRGBA(img)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			nearest.SetRGBA(x, y, src.RGBAAt(x*4, y*4))
+		}
+	}
+	derivatives := [][]byte{
+		encode(downscaleLanczos(img, size.X/2, size.Y/2)),
+		encode(nearest),
+		[]byte("not an image"),
+		original,
+	}
+
+	results, err := VerifyThumbnails(context.Background(), original, derivatives)
+	if err != nil {
+		t.Fatalf("VerifyThumbnails failed: %v", err)
+	}
+	if len(results) != len(derivatives) {
+		t.Fatalf("Expected %d results, got %d", len(derivatives), len(results))
+	}
+	if r := results[0]; r.Err != nil || r.Size != image.Pt(size.X/2, size.Y/2) || !math.IsInf(r.Result.PSNR, 1) {
+		t.Errorf("Expected the reference filter to match exactly, got %+v", r)
+	}
+	if r := results[1]; r.Err != nil || r.Size != image.Pt(width, height) || r.Result.PSNR > 25 {
+		t.Errorf("Expected aliasing to lower the PSNR, got %+v", r)
+	} else {
+		t.Logf("nearest neighbour: %.2f dB", r.Result.PSNR)
+	}
+	if r := results[2]; r.Err == nil || r.Index != 2 {
+		t.Errorf("Expected an error for invalid data, got %+v", r)
+	}
+	if r := results[3]; r.Err != nil || !math.IsInf(r.Result.PSNR, 1) {
+		t.Errorf("Expected the original to match itself, got %+v", r)
+	}
+
+	if _, err := VerifyThumbnails(context.Background(), []byte("not an image"), derivatives); err == nil {
+		t.Error("Expected error for an invalid original")
+	}
+}
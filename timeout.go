@@ -0,0 +1,42 @@
+package psnr
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrTimeout is returned when a comparison exceeds the duration set by
+// WithTimeout.
+var ErrTimeout = errors.New("comparison timed out")
+
+// computeWithTimeout runs computeDecoded under the WithTimeout deadline. The
+// standard decoders cannot be interrupted, so a decode that overruns keeps
+// running in the background until it finishes and its result is discarded;
+// comparison itself stops at the next band of rows.
+func computeWithTimeout(ctx context.Context, image1Bytes, image2Bytes []byte, o *options) (*Result, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, o.timeout)
+	defer cancel()
+
+	type outcome struct {
+		result *Result
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := computeDecoded(timeoutCtx, image1Bytes, image2Bytes, o)
+		done <- outcome{result, err}
+	}()
+
+	select {
+	case out := <-done:
+		if out.err != nil && ctx.Err() == nil && errors.Is(out.err, context.DeadlineExceeded) {
+			return nil, ErrTimeout
+		}
+		return out.result, out.err
+	case <-timeoutCtx.Done():
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrTimeout
+	}
+}
@@ -0,0 +1,47 @@
+package psnr
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWithTimeout(t *testing.T) {
+	data1, err := os.ReadFile("testdata/test_original.png")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+	data2, err := os.ReadFile("testdata/test_quality_85.png")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+
+	if _, err := ComputeDetailed(data1, data2, WithTimeout(time.Nanosecond)); !errors.Is(err, ErrTimeout) {
+		t.Errorf("Expected ErrTimeout, got %v", err)
+	}
+
+	result, err := ComputeDetailed(data1, data2, WithTimeout(time.Minute))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	want, err := ComputeDetailed(data1, data2)
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if result.PSNR != want.PSNR {
+		t.Errorf("Expected %.6f, got %.6f", want.PSNR, result.PSNR)
+	}
+
+	// Cancellation of the caller's context is not reported as a timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ComputeContext(ctx, data1, data2, WithTimeout(time.Minute)); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	if _, err := ComputeDetailed(data1, data2, WithTimeout(-time.Second)); err == nil {
+		t.Error("Expected error for negative timeout")
+	}
+}
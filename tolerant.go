@@ -0,0 +1,296 @@
+package psnr
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+// errNothingDecoded is returned when a truncated image has no usable rows.
+var errNothingDecoded = errors.New("no rows could be decoded")
+
+// tolerantDecode decodes a truncated JPEG or PNG as far as its data goes. It
+// returns the full-size image, its format and the number of rows from the
+// top that were decoded from real data; the rows below are filler.
+func tolerantDecode(data []byte) (image.Image, string, int, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	var img image.Image
+	var rows int
+	switch format {
+	case "jpeg":
+		img, rows, err = decodeTruncatedJPEG(data, config)
+	case "png":
+		img, rows, err = decodeTruncatedPNG(data)
+	default:
+		return nil, format, 0, fmt.Errorf("tolerant decoding does not support %s", format)
+	}
+	if err != nil {
+		return nil, format, 0, err
+	}
+	if rows == 0 {
+		return nil, format, 0, errNothingDecoded
+	}
+	return img, format, rows, nil
+}
+
+// decodeTruncatedJPEG pads the entropy-coded data of a truncated JPEG with
+// zero bits so the decoder can finish the remaining blocks. To find where
+// the real data ended, it decodes a second time with the last meaningful
+// byte removed: rows above the first difference between the two decodes did
+// not depend on the missing data. The boundary is rounded down to a whole
+// row of MCUs.
+func decodeTruncatedJPEG(data []byte, config image.Config) (image.Image, int, error) {
+	// Zero padding decodes as short Huffman codes; allow generously for
+	// every 8x8 block of three components
+	padding := (config.Width*config.Height*3/64 + 1) * 32
+
+	img, err := jpeg.Decode(bytes.NewReader(padJPEG(data, padding)))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Trailing zero bytes are indistinguishable from the padding, so drop
+	// them together with the last non-zero byte
+	end := len(data)
+	for end > 0 && data[end-1] == 0 {
+		end--
+	}
+	shorter, err := jpeg.Decode(bytes.NewReader(padJPEG(data[:max(end-1, 0)], padding)))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	mcuHeight := 8
+	if ycbcr, ok := img.(*image.YCbCr); ok {
+		switch ycbcr.SubsampleRatio {
+		case image.YCbCrSubsampleRatio420, image.YCbCrSubsampleRatio440, image.YCbCrSubsampleRatio410:
+			mcuHeight = 16
+		}
+	}
+	rows := firstDifferentRow(img, shorter)
+	if rows < img.Bounds().Dy() {
+		rows -= rows % mcuHeight
+	}
+	return img, rows, nil
+}
+
+// padJPEG returns data followed by padding zero bytes and an EOI marker.
+func padJPEG(data []byte, padding int) []byte {
+	padded := make([]byte, len(data)+padding+2)
+	copy(padded, data)
+	padded[len(padded)-2] = 0xff
+	padded[len(padded)-1] = 0xd9
+	return padded
+}
+
+// firstDifferentRow returns the index of the first row in which two
+// same-sized images differ, or their height when they are equal.
+func firstDifferentRow(img1, img2 image.Image) int {
+	bounds := img1.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if img1.At(x, y) != img2.At(x, y) {
+				return y - bounds.Min.Y
+			}
+		}
+	}
+	return bounds.Dy()
+}
+
+// decodeTruncatedPNG inflates whatever image data a truncated PNG holds,
+// keeps the complete scanlines, fills the missing ones with zeros and
+// decodes the repaired stream. For interlaced images the decoded rows are
+// those whose pixels all come from complete scanlines of the Adam7 passes,
+// which in practice requires the data to reach the last two passes.
+func decodeTruncatedPNG(data []byte) (image.Image, int, error) {
+	chunks, _ := readPNGChunks(data)
+	if len(chunks) == 0 || chunks[0].typ != "IHDR" || len(chunks[0].data) < 13 {
+		return nil, 0, errInvalidPNG
+	}
+	ihdr := chunks[0].data
+	width := int(binary.BigEndian.Uint32(ihdr[0:4]))
+	height := int(binary.BigEndian.Uint32(ihdr[4:8]))
+	bitDepth, colorType, interlace := int(ihdr[8]), ihdr[9], ihdr[12]
+	if interlace > 1 {
+		return nil, 0, errInvalidPNG
+	}
+
+	channels := map[byte]int{
+		pngColorGray: 1, pngColorRGB: 3, pngColorPaletted: 1, pngColorGrayAlpha: 2, pngColorRGBA: 4,
+	}[colorType]
+	if channels == 0 {
+		return nil, 0, errInvalidPNG
+	}
+	bitsPerPixel := channels * bitDepth
+	passes := []adam7Pass{{width: width, height: height, xStep: 1, yStep: 1}}
+	if interlace == 1 {
+		passes = adam7Passes(width, height)
+	}
+	var total int64
+	for _, pass := range passes {
+		total += pass.size(bitsPerPixel)
+	}
+
+	// Keep the chunks that precede the image data, such as PLTE and tRNS
+	var header bytes.Buffer
+	var compressed []byte
+	seenIDAT := false
+	for _, c := range chunks {
+		switch {
+		case c.typ == "IDAT":
+			compressed = append(compressed, c.data...)
+			seenIDAT = true
+		case !seenIDAT:
+			writePNGChunk(&header, c.typ, c.data)
+		}
+	}
+
+	// Inflate as much as the data allows; the error only says where it ends
+	zr, err := zlib.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, 0, errNothingDecoded
+	}
+	raw, _ := io.ReadAll(io.LimitReader(zr, total))
+	complete, rows := completeScanlines(passes, bitsPerPixel, len(raw), height)
+
+	filled := make([]byte, total)
+	copy(filled, raw[:complete])
+	var idat bytes.Buffer
+	zw := zlib.NewWriter(&idat)
+	zw.Write(filled)
+	zw.Close()
+
+	var repaired bytes.Buffer
+	repaired.Write(pngSignature)
+	repaired.Write(header.Bytes())
+	writePNGChunk(&repaired, "IDAT", idat.Bytes())
+	writePNGChunk(&repaired, "IEND", nil)
+
+	img, err := png.Decode(&repaired)
+	if err != nil {
+		return nil, 0, err
+	}
+	return img, rows, nil
+}
+
+// adam7Pass is the reduced image of one interlacing pass: the pixels at
+// (xStart + i*xStep, yStart + j*yStep). A non-interlaced image is a single
+// pass with steps of 1.
+type adam7Pass struct {
+	width, height  int
+	xStart, yStart int
+	xStep, yStep   int
+}
+
+// adam7Passes returns the seven passes of an interlaced image; passes that
+// hold no pixels have a zero width or height.
+func adam7Passes(width, height int) []adam7Pass {
+	layout := [7][4]int{
+		{0, 0, 8, 8}, {4, 0, 8, 8}, {0, 4, 4, 8}, {2, 0, 4, 4},
+		{0, 2, 2, 4}, {1, 0, 2, 2}, {0, 1, 1, 2},
+	}
+	passes := make([]adam7Pass, len(layout))
+	for i, l := range layout {
+		passes[i] = adam7Pass{
+			width:  max(0, (width-l[0]+l[2]-1)/l[2]),
+			height: max(0, (height-l[1]+l[3]-1)/l[3]),
+			xStart: l[0], yStart: l[1], xStep: l[2], yStep: l[3],
+		}
+	}
+	return passes
+}
+
+// rowBytes returns the length of a scanline of the pass, filter byte
+// included.
+func (p adam7Pass) rowBytes(bitsPerPixel int) int {
+	return 1 + (p.width*bitsPerPixel+7)/8
+}
+
+// size returns the length of the pass's filtered data; empty passes have
+// none.
+func (p adam7Pass) size(bitsPerPixel int) int64 {
+	if p.width == 0 || p.height == 0 {
+		return 0
+	}
+	return int64(p.rowBytes(bitsPerPixel)) * int64(p.height)
+}
+
+// completeScanlines returns how many bytes of n inflated bytes make up whole
+// scanlines, and the number of image rows from the top whose pixels all lie
+// on those scanlines.
+func completeScanlines(passes []adam7Pass, bitsPerPixel, n, height int) (int, int) {
+	// passRows[i] is the number of complete scanlines of pass i
+	passRows := make([]int, len(passes))
+	complete := 0
+	for i, pass := range passes {
+		size := int(pass.size(bitsPerPixel))
+		if n-complete >= size {
+			passRows[i] = pass.height
+			complete += size
+			continue
+		}
+		if size > 0 {
+			passRows[i] = (n - complete) / pass.rowBytes(bitsPerPixel)
+			complete += passRows[i] * pass.rowBytes(bitsPerPixel)
+		}
+		break
+	}
+
+	for y := 0; y < height; y++ {
+		for i, pass := range passes {
+			if pass.width == 0 || y < pass.yStart || (y-pass.yStart)%pass.yStep != 0 {
+				continue
+			}
+			if (y-pass.yStart)/pass.yStep >= passRows[i] {
+				return complete, y
+			}
+		}
+	}
+	return complete, height
+}
+
+// cropPartial crops both images to the rows that were decoded from real data
+// in both and returns the fraction of the image they cover.
+func cropPartial(d1, d2 *decoded) (*decoded, *decoded, float64, error) {
+	if err := checkDimensions(d1.img, d2.img); err != nil {
+		return nil, nil, 0, err
+	}
+	height := d1.img.Bounds().Dy()
+	rows := height
+	for _, d := range []*decoded{d1, d2} {
+		if d.partialRows > 0 {
+			rows = min(rows, d.partialRows)
+		}
+	}
+
+	crop := func(d *decoded) (*decoded, error) {
+		sub, ok := d.img.(interface {
+			SubImage(image.Rectangle) image.Image
+		})
+		if !ok {
+			return nil, fmt.Errorf("cannot crop %T", d.img)
+		}
+		bounds := d.img.Bounds()
+		bounds.Max.Y = bounds.Min.Y + rows
+		return d.withImage(sub.SubImage(bounds)), nil
+	}
+	c1, err := crop(d1)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	c2, err := crop(d2)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return c1, c2, float64(rows) / float64(height), nil
+}
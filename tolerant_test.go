@@ -0,0 +1,189 @@
+package psnr
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"image"
+	"math"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+func TestWithTolerantDecode(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+	}{
+		{"JPEG", "testdata/test_original.jpg"},
+		{"PNG", "testdata/test_original.png"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := os.ReadFile(tt.file)
+			if err != nil {
+				t.Fatalf("Failed to read %s: %v", tt.file, err)
+			}
+			truncated := data[:len(data)*6/10]
+
+			if _, err := ComputeDetailed(data, truncated); err == nil {
+				t.Fatal("Expected error for truncated image without tolerant decoding")
+			}
+
+			result, err := ComputeDetailed(data, truncated, WithTolerantDecode())
+			if err != nil {
+				t.Fatalf("Error computing PSNR: %v", err)
+			}
+			t.Logf("coverage %.3f", result.Coverage)
+			if result.Coverage <= 0.2 || result.Coverage >= 1 {
+				t.Errorf("Expected partial coverage, got %f", result.Coverage)
+			}
+
+			// Every compared row was decoded from the original bytes
+			if !math.IsInf(result.PSNR, 1) {
+				t.Errorf("Expected Inf over the decoded area, got %f", result.PSNR)
+			}
+		})
+	}
+}
+
+func TestWithTolerantDecodeCandidate(t *testing.T) {
+	data1, err := os.ReadFile("testdata/test_original.jpg")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+	data2, err := os.ReadFile("testdata/quality_50.jpg")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+
+	full, err := ComputeDetailed(data1, data2, WithTolerantDecode())
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if full.Coverage != 1 {
+		t.Errorf("Expected full coverage for complete images, got %f", full.Coverage)
+	}
+
+	partial, err := ComputeDetailed(data1, data2[:len(data2)/2], WithTolerantDecode())
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	t.Logf("full %.4f dB, partial %.4f dB over %.3f", full.PSNR, partial.PSNR, partial.Coverage)
+	if math.Abs(partial.PSNR-full.PSNR) > 5 {
+		t.Errorf("Expected a PSNR near %.4f over the decoded area, got %.4f", full.PSNR, partial.PSNR)
+	}
+
+	if _, err := ComputeDetailed(data1, data2[:200], WithTolerantDecode()); err == nil {
+		t.Error("Expected error when nothing can be decoded")
+	}
+}
+
+// encodeInterlacedPNG encodes img as an 8-bit RGBA Adam7-interlaced PNG,
+// which image/png cannot write.
+func encodeInterlacedPNG(t *testing.T, img *image.NRGBA) []byte {
+	t.Helper()
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	var raw bytes.Buffer
+	for _, pass := range adam7Passes(width, height) {
+		if pass.width == 0 {
+			continue
+		}
+		for j := 0; j < pass.height; j++ {
+			raw.WriteByte(0)
+			for i := 0; i < pass.width; i++ {
+				offset := img.PixOffset(pass.xStart+i*pass.xStep, pass.yStart+j*pass.yStep)
+				raw.Write(img.Pix[offset : offset+4])
+			}
+		}
+	}
+	var idat bytes.Buffer
+	zw := zlib.NewWriter(&idat)
+	zw.Write(raw.Bytes())
+	zw.Close()
+
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:4], uint32(width))
+	binary.BigEndian.PutUint32(ihdr[4:8], uint32(height))
+	ihdr[8], ihdr[9], ihdr[12] = 8, pngColorRGBA, 1
+
+	var buf bytes.Buffer
+	buf.Write(pngSignature)
+	writePNGChunk(&buf, "IHDR", ihdr)
+	writePNGChunk(&buf, "IDAT", idat.Bytes())
+	writePNGChunk(&buf, "IEND", nil)
+	return buf.Bytes()
+}
+
+func TestWithTolerantDecodeInterlaced(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	img := image.NewNRGBA(image.Rect(0, 0, 61, 45))
+	rng.Read(img.Pix)
+	data := encodeInterlacedPNG(t, img)
+	reference := encodePNG(t, img)
+
+	full, err := ComputeDetailed(reference, data)
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if !math.IsInf(full.PSNR, 1) {
+		t.Fatalf("Expected the interlaced encoding to be lossless, got %f dB", full.PSNR)
+	}
+
+	// Random pixels barely compress, so 80% of the file ends within the
+	// last pass, which holds the odd rows
+	result, err := ComputeDetailed(reference, data[:len(data)*8/10], WithTolerantDecode())
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	t.Logf("coverage %.3f", result.Coverage)
+	if result.Coverage <= 0.4 || result.Coverage >= 0.8 {
+		t.Errorf("Expected partial coverage, got %f", result.Coverage)
+	}
+	if !math.IsInf(result.PSNR, 1) {
+		t.Errorf("Expected Inf over the decoded area, got %f", result.PSNR)
+	}
+
+	// Within the sixth pass only the first row is complete
+	result, err = ComputeDetailed(reference, data[:len(data)*4/10], WithTolerantDecode())
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if result.Coverage != 1.0/45 || !math.IsInf(result.PSNR, 1) {
+		t.Errorf("Expected the first row to match, got %+v", result)
+	}
+}
+
+func TestCompleteScanlines(t *testing.T) {
+	passes := adam7Passes(8, 8)
+	// Rows are complete once the sixth pass, which holds the even rows, and
+	// the seventh, which holds the odd rows, reach them
+	var offsets []int
+	sum := 0
+	for _, pass := range passes {
+		sum += int(pass.size(32))
+		offsets = append(offsets, sum)
+	}
+	tests := []struct {
+		n, complete, rows int
+	}{
+		{0, 0, 0},
+		{offsets[5], offsets[5], 1},
+		{offsets[5] + passes[6].rowBytes(32) - 1, offsets[5], 1},
+		{offsets[5] + 2*passes[6].rowBytes(32), offsets[5] + 2*passes[6].rowBytes(32), 5},
+		{offsets[6], offsets[6], 8},
+	}
+	for _, tt := range tests {
+		complete, rows := completeScanlines(passes, 32, tt.n, 8)
+		if complete != tt.complete || rows != tt.rows {
+			t.Errorf("completeScanlines(%d) = %d, %d, want %d, %d", tt.n, complete, rows, tt.complete, tt.rows)
+		}
+	}
+
+	single := []adam7Pass{{width: 10, height: 4, xStep: 1, yStep: 1}}
+	if complete, rows := completeScanlines(single, 8, 25, 4); complete != 22 || rows != 2 {
+		t.Errorf("Expected 2 complete rows of a non-interlaced image, got %d bytes, %d rows", complete, rows)
+	}
+}
@@ -0,0 +1,170 @@
+package psnr
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// ToneMapOperator selects how WithToneMapping compresses HDR highlights into
+// the SDR range.
+type ToneMapOperator int
+
+const (
+	// ToneMapBT2390 applies the ITU-R BT.2390 EETF: a Hermite roll-off in
+	// the PQ domain that keeps shadows and midtones and compresses
+	// highlights between the knee and the source peak into the SDR peak.
+	ToneMapBT2390 ToneMapOperator = iota
+	// ToneMapClip clips everything brighter than SDR white.
+	ToneMapClip
+)
+
+// defaultSourcePeak is the assumed mastering peak of HDR content, in nits.
+const defaultSourcePeak = 1000
+
+// ToneMapOptions configures WithToneMapping. The zero value detects the
+// transfer functions and applies BT.2390 to a 1000-nit source.
+type ToneMapOptions struct {
+	// Operator defaults to ToneMapBT2390.
+	Operator ToneMapOperator
+	// Transfer1 and Transfer2 are the transfer functions of the first and
+	// second image; only PQ and HLG images are tone-mapped.
+	Transfer1, Transfer2 TransferFunction
+	// SourcePeak is the peak luminance of the HDR content in nits, such as
+	// its mastering display peak; 1000 when zero.
+	SourcePeak float64
+	// SDRWhite is the luminance in nits that becomes SDR white; 203 when
+	// zero.
+	SDRWhite float64
+	// HLGPeak is the peak luminance of the HLG display in nits; 1000 when
+	// zero.
+	HLGPeak float64
+}
+
+// validate reports an invalid setting.
+func (t *ToneMapOptions) validate() error {
+	if t.Operator < ToneMapBT2390 || t.Operator > ToneMapClip {
+		return fmt.Errorf("unknown tone-mapping operator %d", t.Operator)
+	}
+	h := HDROptions{Transfer1: t.Transfer1, Transfer2: t.Transfer2, SDRWhite: t.SDRWhite, HLGPeak: t.HLGPeak}
+	if err := h.validate(); err != nil {
+		return err
+	}
+	if t.SourcePeak < 0 || t.SourcePeak > maxLuminance || math.IsNaN(t.SourcePeak) {
+		return fmt.Errorf("invalid HDR luminance: %g", t.SourcePeak)
+	}
+	return nil
+}
+
+// WithToneMapping tone-maps PQ and HLG inputs to 8-bit sRGB before they are
+// compared, so an HDR master can be compared with its SDR derivative.
+// Highlights are compressed by the operator on the largest of R, G and B,
+// which keeps hues, and colors are converted from BT.2020 to BT.709. SDR
+// inputs are compared unchanged. It cannot be combined with WithHDR, which
+// compares the light of the untouched images.
+func WithToneMapping(t ToneMapOptions) Option {
+	return func(o *options) {
+		o.toneMap = &t
+	}
+}
+
+// toneMap returns d tone-mapped to SDR when its transfer function is PQ or
+// HLG, and d itself otherwise.
+func toneMap(d *decoded, tf TransferFunction, t *ToneMapOptions) *decoded {
+	tf = resolveTransfer(tf, d)
+	if tf != TransferPQ && tf != TransferHLG {
+		return d
+	}
+	sourcePeak, sdrWhite, hlgPeak := t.SourcePeak, t.SDRWhite, t.HLGPeak
+	if sourcePeak == 0 {
+		sourcePeak = defaultSourcePeak
+	}
+	if sdrWhite == 0 {
+		sdrWhite = defaultSDRWhite
+	}
+	if hlgPeak == 0 {
+		hlgPeak = defaultHLGPeak
+	}
+	eetf := newBT2390(sourcePeak, sdrWhite)
+
+	bounds := d.img.Bounds()
+	src := image.NewNRGBA64(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Rect, d.img, bounds.Min, draw.Src)
+	dst := image.NewNRGBA(src.Rect)
+	for y := 0; y < src.Rect.Dy(); y++ {
+		for x := 0; x < src.Rect.Dx(); x++ {
+			p := src.NRGBA64At(x, y)
+			c := decodeLight([3]float64{float64(p.R) / 0xffff, float64(p.G) / 0xffff, float64(p.B) / 0xffff}, tf, sdrWhite, hlgPeak)
+
+			if peak := max(c[0], c[1], c[2]); peak > 0 {
+				mapped := min(peak, sdrWhite)
+				if t.Operator == ToneMapBT2390 {
+					mapped = eetf.apply(peak)
+				}
+				for k := range c {
+					c[k] *= mapped / peak
+				}
+			}
+
+			c = bt2020ToBT709([3]float64{c[0] / sdrWhite, c[1] / sdrWhite, c[2] / sdrWhite})
+			dst.SetNRGBA(x, y, color.NRGBA{
+				R: clampToByte(255 * srgbInverseEOTF(c[0])),
+				G: clampToByte(255 * srgbInverseEOTF(c[1])),
+				B: clampToByte(255 * srgbInverseEOTF(c[2])),
+				A: uint8(p.A >> 8),
+			})
+		}
+	}
+	return d.withImage(dst)
+}
+
+// bt2390 is the BT.2390 EETF from a source peak to a target peak, both in
+// nits, with a black level of zero.
+type bt2390 struct {
+	sourcePQ float64
+	// maxLum and knee are the target peak and the start of the roll-off,
+	// as fractions of the source range in the PQ domain.
+	maxLum, knee float64
+}
+
+func newBT2390(sourcePeak, targetPeak float64) bt2390 {
+	sourcePQ := pqInverseEOTF(sourcePeak)
+	maxLum := pqInverseEOTF(targetPeak) / sourcePQ
+	return bt2390{sourcePQ: sourcePQ, maxLum: maxLum, knee: 1.5*maxLum - 0.5}
+}
+
+// apply maps a luminance in nits.
+func (e bt2390) apply(luminance float64) float64 {
+	if e.knee >= 1 {
+		// The target covers the source range
+		return luminance
+	}
+	e1 := min(pqInverseEOTF(luminance)/e.sourcePQ, 1)
+	e2 := e1
+	if e1 > e.knee {
+		t := (e1 - e.knee) / (1 - e.knee)
+		t2, t3 := t*t, t*t*t
+		e2 = (2*t3-3*t2+1)*e.knee + (t3-2*t2+t)*(1-e.knee) + (-2*t3+3*t2)*e.maxLum
+	}
+	return pqEOTF(e2 * e.sourcePQ)
+}
+
+// bt2020ToBT709 converts linear BT.2020 RGB to BT.709 primaries.
+func bt2020ToBT709(c [3]float64) [3]float64 {
+	return [3]float64{
+		1.6604910*c[0] - 0.5876411*c[1] - 0.0728499*c[2],
+		-0.1245505*c[0] + 1.1328999*c[1] - 0.0083494*c[2],
+		-0.0181508*c[0] - 0.1005789*c[1] + 1.1187297*c[2],
+	}
+}
+
+// srgbInverseEOTF returns the sRGB sample of a linear value, clipped to 0-1.
+func srgbInverseEOTF(v float64) float64 {
+	v = min(max(v, 0), 1)
+	if v <= 0.0031308 {
+		return 12.92 * v
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
@@ -0,0 +1,84 @@
+package psnr
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestBT2390(t *testing.T) {
+	e := newBT2390(1000, 203)
+	if got := e.apply(1000); math.Abs(got-203) > 0.5 {
+		t.Errorf("Expected the source peak to map to the target peak, got %f", got)
+	}
+	if got := e.apply(20); math.Abs(got-20) > 1e-9 {
+		t.Errorf("Expected luminance below the knee to be kept, got %f", got)
+	}
+	previous := 0.0
+	for luminance := 1.0; luminance <= 4000; luminance *= 1.5 {
+		got := e.apply(luminance)
+		if got < previous || got > 203+1e-9 {
+			t.Errorf("Expected a monotonic curve up to 203 nits, got %f at %f", got, luminance)
+		}
+		previous = got
+	}
+	if got := newBT2390(100, 203).apply(80); math.Abs(got-80) > 1e-9 {
+		t.Errorf("Expected no mapping for a source within the target, got %f", got)
+	}
+}
+
+func TestWithToneMapping(t *testing.T) {
+	// The left half is a 20-nit midtone, the right half a 1000-nit highlight
+	pq := image.NewGray16(image.Rect(0, 0, 8, 8))
+	sdr := image.NewGray(pq.Rect)
+	midtone := clampToByte(255 * srgbInverseEOTF(20.0/defaultSDRWhite))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			luminance, value := 20.0, midtone
+			if x >= 4 {
+				luminance, value = 1000, 0xff
+			}
+			pq.SetGray16(x, y, color.Gray16{Y: uint16(math.Round(pqInverseEOTF(luminance) * 0xffff))})
+			sdr.SetGray(x, y, color.Gray{Y: value})
+		}
+	}
+	pqData, sdrData := encodeCICPPNG(t, pq, cicpTransferPQ), encodePNG(t, sdr)
+
+	direct, err := ComputeDetailed(pqData, sdrData)
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	for _, op := range []ToneMapOperator{ToneMapBT2390, ToneMapClip} {
+		mapped, err := ComputeDetailed(pqData, sdrData, WithToneMapping(ToneMapOptions{Operator: op}))
+		if err != nil {
+			t.Fatalf("Error computing PSNR: %v", err)
+		}
+		t.Logf("operator %d: direct %.2f dB, tone-mapped %.2f dB", op, direct.PSNR, mapped.PSNR)
+		if mapped.PSNR < 45 || mapped.PSNR <= direct.PSNR {
+			t.Errorf("Expected the tone-mapped HDR image to match, got %f dB", mapped.PSNR)
+		}
+	}
+
+	// SDR inputs pass through unchanged
+	same, err := ComputeDetailed(sdrData, sdrData, WithToneMapping(ToneMapOptions{}))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if !math.IsInf(same.PSNR, 1) {
+		t.Errorf("Expected Inf, got %f", same.PSNR)
+	}
+
+	for _, opts := range []Option{
+		WithToneMapping(ToneMapOptions{Operator: 5}),
+		WithToneMapping(ToneMapOptions{SourcePeak: -1}),
+		WithToneMapping(ToneMapOptions{Transfer2: 8}),
+	} {
+		if _, err := ComputeDetailed(pqData, sdrData, opts); err == nil {
+			t.Error("Expected error for invalid tone-mapping options")
+		}
+	}
+	if _, err := ComputeDetailed(pqData, sdrData, WithToneMapping(ToneMapOptions{}), WithHDR(HDROptions{})); err == nil {
+		t.Error("Expected error when combined with WithHDR")
+	}
+}
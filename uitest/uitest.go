@@ -0,0 +1,150 @@
+// Package uitest compares UI screenshots with golden images in Go tests,
+// tolerating the anti-aliasing differences between platforms.
+//
+//	var profiles = uitest.Profiles{
+//		"default": {MinPSNR: 40, IgnoreAntiAliasing: true},
+//		"windows": {MinPSNR: 35, IgnoreAntiAliasing: true},
+//	}
+//
+//	func TestDialog(t *testing.T) {
+//		uitest.Assert(t, screenshot(), "testdata/dialog.golden.png", profiles.Current())
+//	}
+//
+// On failure the screenshot and a heatmap of the remaining error are
+// written to the directory named by psnrtest.ArtifactsEnv, or to a new
+// temporary directory, and their paths are reported.
+package uitest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"testing"
+
+	"github.com/ideamans/go-psnr"
+	"github.com/ideamans/go-psnr/psnrtest"
+)
+
+// Profile holds the thresholds for screenshots rendered on one platform.
+type Profile struct {
+	// MinPSNR is the lowest PSNR in dB that passes.
+	MinPSNR float64
+	// IgnoreAntiAliasing excludes the differing pixels that
+	// psnr.WithAntiAliasing classifies as anti-aliasing, as font smoothing
+	// produces across platforms.
+	IgnoreAntiAliasing bool
+	// Options are passed to psnr.CompareImages.
+	Options []psnr.Option
+}
+
+// Profiles maps platforms to profiles. Keys are "GOOS/GOARCH" or "GOOS"
+// values, such as "darwin/arm64" or "linux", with "default" as the
+// fallback.
+type Profiles map[string]Profile
+
+// Select returns the profile for a platform: the most specific of
+// "goos/goarch", "goos" and "default". It reports false when none is
+// defined.
+func (p Profiles) Select(goos, goarch string) (Profile, bool) {
+	for _, key := range []string{goos + "/" + goarch, goos, "default"} {
+		if profile, ok := p[key]; ok {
+			return profile, true
+		}
+	}
+	return Profile{}, false
+}
+
+// defaultProfile is the profile Current falls back to when none is defined
+// for the running platform.
+var defaultProfile = Profile{MinPSNR: 40, IgnoreAntiAliasing: true}
+
+// Current returns the profile for the running platform, or a profile
+// requiring 40 dB with anti-aliasing ignored when none is defined, so an
+// empty Profiles does not pass every comparison.
+func (p Profiles) Current() Profile {
+	if profile, ok := p.Select(runtime.GOOS, runtime.GOARCH); ok {
+		return profile
+	}
+	return defaultProfile
+}
+
+// Assert fails t unless the screenshot got matches the golden PNG image at
+// goldenPath with the thresholds of profile. It reports whether the
+// assertion held.
+func Assert(t testing.TB, got image.Image, goldenPath string, profile Profile) bool {
+	t.Helper()
+	data, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Errorf("uitest: failed to read golden image: %v", err)
+		return false
+	}
+	golden, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Errorf("uitest: failed to decode golden image: %v", err)
+		return false
+	}
+	if golden.Bounds().Size() != got.Bounds().Size() {
+		t.Errorf("uitest: screenshot is %v, golden image %s is %v", got.Bounds().Size(), goldenPath, golden.Bounds().Size())
+		return false
+	}
+
+	options := profile.Options
+	if profile.IgnoreAntiAliasing {
+		options = append(options[:len(options):len(options)], psnr.WithAntiAliasing(psnr.AntiAliasingExclude))
+	}
+	result, err := psnr.CompareImages(context.Background(), golden, got, options...)
+	if err != nil {
+		t.Errorf("uitest: failed to compare with %s: %v", goldenPath, err)
+		return false
+	}
+	if result.PSNR >= profile.MinPSNR {
+		return true
+	}
+
+	message := fmt.Sprintf("uitest: PSNR against %s is %.2f dB, want at least %.2f dB", goldenPath, result.PSNR, profile.MinPSNR)
+	if profile.IgnoreAntiAliasing {
+		message += fmt.Sprintf(" (%d anti-aliased pixels ignored)", result.AntiAliasing.AntiAliased)
+	}
+	dir, err := writeArtifacts(t, got, psnr.Heatmap(golden, got))
+	if err != nil {
+		t.Errorf("%s (failed to write artifacts: %v)", message, err)
+	} else {
+		t.Errorf("%s; screenshot and diff heatmap written to %s", message, dir)
+	}
+	return false
+}
+
+// unsafeName matches characters that are replaced in artifact file names.
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// writeArtifacts writes the screenshot and the diff heatmap for a failing
+// assertion and returns the directory they were written to.
+func writeArtifacts(t testing.TB, got, heatmap image.Image) (string, error) {
+	dir := os.Getenv(psnrtest.ArtifactsEnv)
+	if dir == "" {
+		var err error
+		if dir, err = os.MkdirTemp("", "uitest-"); err != nil {
+			return "", err
+		}
+	} else if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	name := unsafeName.ReplaceAllString(t.Name(), "_")
+
+	for suffix, img := range map[string]image.Image{".got.png": got, ".diff.png": heatmap} {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(filepath.Join(dir, name+suffix), buf.Bytes(), 0o644); err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
@@ -0,0 +1,127 @@
+package uitest
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// recorder is a testing.TB that records failures instead of failing.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Name() string { return "TestDialog/light" }
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+// screenshot draws a black square at x on a white 32x32 canvas.
+func screenshot(x int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	draw.Draw(img, img.Rect, image.White, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(x, 8, x+12, 20), image.Black, image.Point{}, draw.Src)
+	return img
+}
+
+// fringed draws screenshot(x) with a gray anti-aliasing fringe along the
+// left edge of the square.
+func fringed(x int) *image.RGBA {
+	img := screenshot(x)
+	draw.Draw(img, image.Rect(x-1, 8, x, 20), image.NewUniform(color.Gray{128}), image.Point{}, draw.Src)
+	return img
+}
+
+func writeGolden(t *testing.T, img image.Image) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "golden.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestProfilesSelect(t *testing.T) {
+	profiles := Profiles{
+		"default":      {MinPSNR: 40},
+		"darwin":       {MinPSNR: 35},
+		"darwin/arm64": {MinPSNR: 30},
+	}
+	tests := []struct {
+		goos, goarch string
+		want         float64
+	}{
+		{"darwin", "arm64", 30},
+		{"darwin", "amd64", 35},
+		{"linux", "amd64", 40},
+	}
+	for _, tt := range tests {
+		if profile, ok := profiles.Select(tt.goos, tt.goarch); !ok || profile.MinPSNR != tt.want {
+			t.Errorf("%s/%s: got %v, %v", tt.goos, tt.goarch, profile.MinPSNR, ok)
+		}
+	}
+	if _, ok := (Profiles{"windows": {}}).Select("linux", "amd64"); ok {
+		t.Error("Expected no profile without a default")
+	}
+	if got := (Profiles{}).Current(); !reflect.DeepEqual(got, defaultProfile) || got.MinPSNR == 0 {
+		t.Errorf("Expected the default profile without a match, got %+v", got)
+	}
+}
+
+func TestAssert(t *testing.T) {
+	golden := writeGolden(t, screenshot(8))
+	dir := t.TempDir()
+	t.Setenv("PSNRTEST_ARTIFACTS", dir)
+	strict := Profile{MinPSNR: 40}
+	tolerant := Profile{MinPSNR: 40, IgnoreAntiAliasing: true}
+
+	r := &recorder{TB: t}
+	if !Assert(r, screenshot(8), golden, strict) || len(r.errors) != 0 {
+		t.Errorf("Expected the golden image to pass, got %v", r.errors)
+	}
+
+	// Smoothing adds a gray fringe along an edge of the square
+	r = &recorder{TB: t}
+	if !Assert(r, fringed(8), golden, tolerant) || len(r.errors) != 0 {
+		t.Errorf("Expected edge differences to be ignored, got %v", r.errors)
+	}
+	r = &recorder{TB: t}
+	if Assert(r, fringed(8), golden, strict) || len(r.errors) != 1 {
+		t.Fatalf("Expected one failure without anti-aliasing tolerance, got %v", r.errors)
+	}
+	for _, name := range []string{"TestDialog_light.got.png", "TestDialog_light.diff.png"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Expected artifact %s: %v", name, err)
+		}
+	}
+
+	// A moved square is a real change
+	r = &recorder{TB: t}
+	if Assert(r, screenshot(14), golden, tolerant) || len(r.errors) != 1 {
+		t.Errorf("Expected one failure for a moved square, got %v", r.errors)
+	}
+
+	r = &recorder{TB: t}
+	if Assert(r, image.NewRGBA(image.Rect(0, 0, 16, 16)), golden, tolerant) || len(r.errors) != 1 {
+		t.Errorf("Expected one failure for a different size, got %v", r.errors)
+	}
+	r = &recorder{TB: t}
+	if Assert(r, screenshot(8), "missing.png", tolerant) || len(r.errors) != 1 {
+		t.Errorf("Expected one failure for a missing golden image, got %v", r.errors)
+	}
+}
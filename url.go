@@ -0,0 +1,112 @@
+package psnr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// DefaultMaxURLBytes is the per-image download limit used by ComputeURLs
+// when URLOptions.MaxBytes is zero.
+const DefaultMaxURLBytes = 64 << 20
+
+// ErrResponseTooLarge is returned by ComputeURLs when a response exceeds the
+// configured size limit.
+var ErrResponseTooLarge = errors.New("response exceeds size limit")
+
+// URLOptions configures ComputeURLs.
+type URLOptions struct {
+	// Client performs the requests. http.DefaultClient is used when nil.
+	Client *http.Client
+	// Timeout bounds fetching both images. Zero means no timeout beyond the
+	// context and the client's own settings.
+	Timeout time.Duration
+	// MaxBytes limits the size of each response body. DefaultMaxURLBytes is
+	// used when zero.
+	MaxBytes int64
+}
+
+// ComputeURLs fetches two images over HTTP(S) concurrently and calculates
+// the PSNR between them with opts like ComputeContext, e.g. to compare an
+// origin image with its CDN-transformed variant. The first failed fetch
+// cancels the other one and its error is returned.
+func ComputeURLs(ctx context.Context, url1, url2 string, urlOpts URLOptions, opts ...Option) (float64, error) {
+	fetchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if urlOpts.Timeout > 0 {
+		fetchCtx, cancel = context.WithTimeout(fetchCtx, urlOpts.Timeout)
+		defer cancel()
+	}
+
+	type fetchResult struct {
+		index int
+		data  []byte
+		err   error
+	}
+	urls := [2]string{url1, url2}
+	results := make(chan fetchResult, len(urls))
+	for i, url := range urls {
+		go func() {
+			data, err := fetchURL(fetchCtx, url, urlOpts)
+			results <- fetchResult{i, data, err}
+		}()
+	}
+
+	var data [2][]byte
+	for range urls {
+		r := <-results
+		if r.err != nil {
+			return 0, r.err
+		}
+		data[r.index] = r.data
+	}
+
+	result, err := ComputeContext(ctx, data[0], data[1], opts...)
+	if err != nil {
+		return 0, err
+	}
+	return result.PSNR, nil
+}
+
+// fetchURL downloads url, enforcing the status code and size limit.
+func fetchURL(ctx context.Context, url string, opts URLOptions) ([]byte, error) {
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	maxBytes := opts.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxURLBytes
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("failed to fetch %s: unexpected status %s", url, resp.Status)
+	}
+	if resp.ContentLength > maxBytes {
+		return nil, fmt.Errorf("failed to fetch %s: %w (%d > %d bytes)", url, ErrResponseTooLarge, resp.ContentLength, maxBytes)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, fmt.Errorf("failed to fetch %s: %w (more than %d bytes)", url, ErrResponseTooLarge, maxBytes)
+	}
+
+	return data, nil
+}
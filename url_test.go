@@ -0,0 +1,100 @@
+package psnr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func newImageServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow.jpg", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	})
+	mux.Handle("/", http.FileServer(http.Dir("testdata")))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestComputeURLs(t *testing.T) {
+	server := newImageServer(t)
+
+	got, err := ComputeURLs(context.Background(),
+		server.URL+"/test_original.jpg", server.URL+"/quality_50.jpg",
+		URLOptions{Client: server.Client()})
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+
+	want, err := ComputeFiles("testdata/test_original.jpg", "testdata/quality_50.jpg")
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if got != want {
+		t.Errorf("Expected %.6f, got %.6f", want, got)
+	}
+
+	got, err = ComputeURLs(context.Background(),
+		server.URL+"/test_original.jpg", server.URL+"/quality_50.jpg",
+		URLOptions{Client: server.Client()}, WithNormalization())
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	detailed, err := ComputeFilesDetailed("testdata/test_original.jpg", "testdata/quality_50.jpg", WithNormalization())
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	if got != detailed.PSNR {
+		t.Errorf("Expected %.6f with options, got %.6f", detailed.PSNR, got)
+	}
+}
+
+func TestComputeURLsErrors(t *testing.T) {
+	server := newImageServer(t)
+	original := server.URL + "/test_original.jpg"
+
+	info, err := os.Stat("testdata/test_original.jpg")
+	if err != nil {
+		t.Fatalf("Failed to stat test image: %v", err)
+	}
+
+	t.Run("not found", func(t *testing.T) {
+		if _, err := ComputeURLs(context.Background(), original, server.URL+"/missing.jpg", URLOptions{}); err == nil {
+			t.Error("Expected error for 404 response")
+		}
+	})
+
+	t.Run("size limit", func(t *testing.T) {
+		_, err := ComputeURLs(context.Background(), original, original, URLOptions{MaxBytes: info.Size() - 1})
+		if !errors.Is(err, ErrResponseTooLarge) {
+			t.Errorf("Expected ErrResponseTooLarge, got %v", err)
+		}
+	})
+
+	t.Run("cancel on failure", func(t *testing.T) {
+		start := time.Now()
+		_, err := ComputeURLs(context.Background(), server.URL+"/slow.jpg", server.URL+"/missing.jpg", URLOptions{})
+		if err == nil || errors.Is(err, context.Canceled) {
+			t.Errorf("Expected the 404 error, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Expected the slow fetch to be canceled, took %v", elapsed)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		_, err := ComputeURLs(context.Background(), original, server.URL+"/slow.jpg", URLOptions{Timeout: 50 * time.Millisecond})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected deadline exceeded, got %v", err)
+		}
+	})
+}
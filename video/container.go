@@ -0,0 +1,36 @@
+package video
+
+import "bytes"
+
+// videoBrands are the ISO base media file brands of MP4 and QuickTime
+// videos, as opposed to AVIF and HEIF images.
+var videoBrands = []string{"isom", "iso2", "iso4", "iso5", "iso6", "mp41", "mp42", "avc1", "dash", "M4V ", "qt  "}
+
+// ebmlMagic starts WebM and Matroska files.
+var ebmlMagic = []byte{0x1a, 0x45, 0xdf, 0xa3}
+
+// isVideoContainer reports whether data is an MP4, QuickTime, WebM or
+// Matroska video, returning the container name.
+func isVideoContainer(data []byte) (string, bool) {
+	if bytes.HasPrefix(data, ebmlMagic) {
+		// The DocType is declared in the EBML header
+		header := data[:min(len(data), 64)]
+		if bytes.Contains(header, []byte("webm")) {
+			return "webm", true
+		}
+		return "matroska", true
+	}
+	if len(data) < 12 || string(data[4:8]) != "ftyp" {
+		return "", false
+	}
+	if _, ok := isImageSequence(data); ok {
+		return "", false
+	}
+	major := string(data[8:12])
+	for _, brand := range videoBrands {
+		if major == brand {
+			return "mp4", true
+		}
+	}
+	return "", false
+}
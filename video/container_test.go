@@ -0,0 +1,37 @@
+package video
+
+import "testing"
+
+func TestIsVideoContainer(t *testing.T) {
+	ftyp := func(major string, compatible ...string) []byte {
+		box := []byte{0, 0, 0, byte(16 + 4*len(compatible))}
+		box = append(box, "ftyp"+major+"\x00\x00\x00\x00"...)
+		for _, brand := range compatible {
+			box = append(box, brand...)
+		}
+		return box
+	}
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"mp4", ftyp("isom", "iso2", "avc1", "mp41"), "mp4"},
+		{"quicktime", ftyp("qt  "), "mp4"},
+		{"webm", []byte("\x1a\x45\xdf\xa3\x9f\x42\x86\x81\x01\x42\x82\x84webm"), "webm"},
+		{"matroska", []byte("\x1a\x45\xdf\xa3\xa3\x42\x82\x88matroska"), "matroska"},
+		{"avif sequence", ftyp("avis", "msf1", "iso8"), ""},
+		{"avif", ftyp("avif", "mif1"), ""},
+		{"png", []byte("\x89PNG\r\n\x1a\n"), ""},
+	}
+	for _, tt := range tests {
+		got, ok := isVideoContainer(tt.data)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("%s: got %q, %v", tt.name, got, ok)
+		}
+	}
+
+	if _, err := Open(ftyp("isom", "mp41")); err == nil {
+		t.Error("Expected error for an MP4 header without content")
+	}
+}
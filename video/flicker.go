@@ -0,0 +1,105 @@
+package video
+
+import (
+	"image"
+	"image/draw"
+	"math"
+)
+
+// FlickerResult is the temporal error measured by WithFlicker: how much the
+// change from one frame to the next differs between the sequences. Encoders
+// that refresh quality unevenly make static content flicker or pump, which
+// per-frame PSNR only shows as an average.
+type FlickerResult struct {
+	// MSE is the mean squared difference, per RGB sample on the 0-255
+	// scale, between the frame-to-frame changes of the two sequences, and
+	// PSNR expresses it in dB. Both are measured from the second pair on.
+	MSE  float64
+	PSNR float64
+	// Activity1 and Activity2 are the mean squared frame-to-frame changes
+	// of each sequence. A second sequence much busier than the first
+	// flickers; a much calmer one drops motion.
+	Activity1, Activity2 float64
+}
+
+// flickerMeter accumulates the temporal error of consecutive frame pairs.
+// Skipped pairs are kept as the previous pair, so the change is always
+// measured from the pair decoded just before, not the last one compared.
+type flickerMeter struct {
+	previous1, previous2 image.Image
+	sum                  float64
+	activity1, activity2 float64
+	pairs                int
+}
+
+// skip records frame1 and frame2 as the previous pair without measuring
+// them, for pairs that sampling or a selection leaves out.
+func (m *flickerMeter) skip(frame1, frame2 image.Image) {
+	m.previous1, m.previous2 = frame1, frame2
+}
+
+// add measures the change from the previous pair to frame1 and frame2 and
+// returns the temporal MSE of the pair, which is zero for the first one.
+func (m *flickerMeter) add(frame1, frame2 image.Image) float64 {
+	current1, current2 := rgba(frame1), rgba(frame2)
+	if m.previous1 == nil {
+		m.previous1, m.previous2 = current1, current2
+		return 0
+	}
+	previous1, previous2 := rgba(m.previous1), rgba(m.previous2)
+	m.previous1, m.previous2 = current1, current2
+	if previous1.Rect != current1.Rect || previous2.Rect != current2.Rect || current1.Rect.Size() != current2.Rect.Size() {
+		return 0
+	}
+
+	var sum, activity1, activity2 uint64
+	width, height := current1.Rect.Dx(), current1.Rect.Dy()
+	for y := 0; y < height; y++ {
+		p1, c1 := previous1.Pix[y*previous1.Stride:], current1.Pix[y*current1.Stride:]
+		p2, c2 := previous2.Pix[y*previous2.Stride:], current2.Pix[y*current2.Stride:]
+		for i := 0; i < width*4; i++ {
+			if i%4 == 3 {
+				continue
+			}
+			delta1 := int64(c1[i]) - int64(p1[i])
+			delta2 := int64(c2[i]) - int64(p2[i])
+			diff := delta1 - delta2
+			sum += uint64(diff * diff)
+			activity1 += uint64(delta1 * delta1)
+			activity2 += uint64(delta2 * delta2)
+		}
+	}
+	samples := float64(width * height * 3)
+	mse := float64(sum) / samples
+	m.sum += mse
+	m.activity1 += float64(activity1) / samples
+	m.activity2 += float64(activity2) / samples
+	m.pairs++
+	return mse
+}
+
+// result returns the averages over every measured pair, or nil when fewer
+// than two frames were compared.
+func (m *flickerMeter) result() *FlickerResult {
+	if m.pairs == 0 {
+		return nil
+	}
+	n := float64(m.pairs)
+	r := &FlickerResult{MSE: m.sum / n, Activity1: m.activity1 / n, Activity2: m.activity2 / n}
+	r.PSNR = math.Inf(1)
+	if r.MSE > 0 {
+		r.PSNR = 10 * math.Log10(255*255/r.MSE)
+	}
+	return r
+}
+
+// rgba converts img to an *image.RGBA anchored at the origin.
+func rgba(img image.Image) *image.RGBA {
+	if r, ok := img.(*image.RGBA); ok && r.Rect.Min == (image.Point{}) {
+		return r
+	}
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Rect, img, bounds.Min, draw.Src)
+	return dst
+}
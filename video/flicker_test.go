@@ -0,0 +1,73 @@
+package video
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCompareWithFlicker(t *testing.T) {
+	// A static scene against one that alternates between two grays, both
+	// with the same mean error per frame
+	still := encodeGIF(t, 5, 2, 2, 2, 2)
+	flickering := encodeGIF(t, 5, 1, 0, 1, 0)
+	steady := encodeGIF(t, 5, 1, 1, 1, 1)
+
+	result, err := Compare(still, flickering, WithFlicker())
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	f := result.Flicker
+	if f == nil || f.MSE == 0 || f.Activity1 != 0 || f.Activity2 == 0 || math.IsInf(f.PSNR, 1) {
+		t.Fatalf("Expected flicker, got %+v", f)
+	}
+	if result.Frames[0].Flicker != 0 || result.Frames[1].Flicker == 0 {
+		t.Errorf("Expected per-frame flicker from the second frame on, got %+v", result.Frames)
+	}
+
+	result, err = Compare(still, steady, WithFlicker())
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if result.Flicker == nil || result.Flicker.MSE != 0 || !math.IsInf(result.Flicker.PSNR, 1) {
+		t.Errorf("Expected no flicker for a steady error, got %+v", result.Flicker)
+	}
+
+	plain, err := Compare(still, flickering)
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if plain.Flicker != nil || plain.Frames[1].Flicker != 0 {
+		t.Error("Expected no flicker measurement by default")
+	}
+	single, err := Compare(encodeGIF(t, 5, 0), encodeGIF(t, 5, 1), WithFlicker())
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if single.Flicker != nil {
+		t.Errorf("Expected no flicker result for a single frame, got %+v", single.Flicker)
+	}
+}
+
+func TestCompareWithFlickerSampled(t *testing.T) {
+	// Every other frame alternates, so the sampled frames all match, but
+	// each is still measured against the frame decoded just before it
+	still := encodeGIF(t, 5, 2, 2, 2, 2, 2)
+	flickering := encodeGIF(t, 5, 1, 0, 1, 0, 1)
+
+	full, err := Compare(still, flickering, WithFlicker())
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	for _, opt := range []Option{WithSampling(Sampling{Every: 2}), WithFrameList(0, 2, 4)} {
+		result, err := Compare(still, flickering, WithFlicker(), opt)
+		if err != nil {
+			t.Fatalf("Compare failed: %v", err)
+		}
+		if len(result.Frames) != 3 || result.Frames[1].Flicker != full.Frames[2].Flicker || result.Frames[2].Flicker != full.Frames[4].Flicker {
+			t.Errorf("Expected the flicker of frames 2 and 4, got %+v", result.Frames)
+		}
+		if f := result.Flicker; f == nil || f.MSE != full.Frames[2].Flicker || f.Activity1 != 0 || f.Activity2 == 0 {
+			t.Errorf("Expected the flicker of the compared frames, got %+v", f)
+		}
+	}
+}
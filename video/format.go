@@ -0,0 +1,151 @@
+package video
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	// Still images fall back to the standard decoders
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// ErrUnsupportedFormat is returned when no decoder recognizes a sequence.
+var ErrUnsupportedFormat = errors.New("unsupported sequence format")
+
+// OpenFunc decodes an encoded animation into a Sequence.
+type OpenFunc func(data []byte) (Sequence, error)
+
+// sequenceFormat is a sequence decoder registered with RegisterFormat.
+type sequenceFormat struct {
+	name  string
+	sniff func(data []byte) bool
+	open  OpenFunc
+}
+
+var (
+	formatsMu sync.RWMutex
+	formats   []sequenceFormat
+)
+
+// RegisterFormat adds a decoder for an animation format, recognized by
+// sniff. Registered formats are tried before the built-in GIF and still image
+// decoders, in the order they were registered, so a backend can also take
+// over a built-in format. Registering a name twice replaces the earlier
+// decoder.
+//
+// AVIF image sequences are decoded with libavif in builds with the libavif
+// tag; HEIF image sequences have no built-in decoder. A decoder registered
+// for them, for example one built on libheif, should report each frame's
+// timestamp and duration from the container's sample timing.
+func RegisterFormat(name string, sniff func(data []byte) bool, open OpenFunc) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	for i, format := range formats {
+		if format.name == name {
+			formats[i] = sequenceFormat{name: name, sniff: sniff, open: open}
+			return
+		}
+	}
+	formats = append(formats, sequenceFormat{name: name, sniff: sniff, open: open})
+}
+
+// Open decodes an encoded animation with the first registered format that
+// recognizes it, then as an animated GIF, then as a one-frame still image.
+func Open(data []byte) (Sequence, error) {
+	formatsMu.RLock()
+	registered := formats
+	formatsMu.RUnlock()
+	for _, format := range registered {
+		if format.sniff(data) {
+			return format.open(data)
+		}
+	}
+
+	if isGIF(data) {
+		return openGIF(data)
+	}
+	if brand, ok := isImageSequence(data); ok {
+		if isAVIFSequence(data) {
+			return nil, fmt.Errorf("%w: %q image sequence requires building with -tags libavif or a decoder registered with RegisterFormat", ErrUnsupportedFormat, brand)
+		}
+		return nil, fmt.Errorf("%w: %q image sequence requires a decoder registered with RegisterFormat", ErrUnsupportedFormat, brand)
+	}
+	if container, ok := isVideoContainer(data); ok {
+		return nil, fmt.Errorf("%w: %s video requires building with -tags libav or a decoder registered with RegisterFormat", ErrUnsupportedFormat, container)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			return nil, ErrUnsupportedFormat
+		}
+		return nil, err
+	}
+	return &stillSequence{img: img}, nil
+}
+
+// imageSequenceBrands are the ISO base media file brands of AVIF and HEIF
+// image sequences, as opposed to still images.
+var imageSequenceBrands = []string{"avis", "msf1", "hevc", "hevx"}
+
+// ftypBrands returns the major brand and then the compatible brands of the
+// ftyp box at the start of an ISO base media file, or nil when data does
+// not start with one.
+func ftypBrands(data []byte) []string {
+	if len(data) < 16 || string(data[4:8]) != "ftyp" {
+		return nil
+	}
+	size := int(data[0])<<24 | int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+	if size < 16 || size > len(data) {
+		return nil
+	}
+	// The minor version separates the major and compatible brands
+	brands := []string{string(data[8:12])}
+	for i := 16; i+4 <= size; i += 4 {
+		brands = append(brands, string(data[i:i+4]))
+	}
+	return brands
+}
+
+// isImageSequence reports whether data is an AVIF or HEIF image sequence by
+// the brands of its ftyp box, returning the brand that matched.
+func isImageSequence(data []byte) (string, bool) {
+	for _, brand := range ftypBrands(data) {
+		if slices.Contains(imageSequenceBrands, brand) {
+			return brand, true
+		}
+	}
+	return "", false
+}
+
+// isAVIFSequence reports whether data is an AVIF image sequence: branded
+// "avis", or an AVIF file that is also a HEIF image sequence ("msf1").
+func isAVIFSequence(data []byte) bool {
+	brands := ftypBrands(data)
+	return slices.Contains(brands, "avis") || slices.Contains(brands, "avif") && slices.Contains(brands, "msf1")
+}
+
+// stillSequence presents a still image as a one-frame sequence.
+type stillSequence struct {
+	img  image.Image
+	done bool
+}
+
+func (s *stillSequence) Next() (*Frame, error) {
+	if s.done {
+		return nil, io.EOF
+	}
+	s.done = true
+	return &Frame{Image: s.img}, nil
+}
+
+// seconds converts seconds, as reported by the cgo decoders, to a duration.
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Round(s * float64(time.Second)))
+}
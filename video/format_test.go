@@ -0,0 +1,108 @@
+package video
+
+import (
+	"errors"
+	"image"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ftyp builds an ftyp box with a major brand and compatible brands.
+func ftyp(major string, compatible ...string) []byte {
+	body := major + "\x00\x00\x00\x00" + strings.Join(compatible, "")
+	size := 8 + len(body)
+	return append([]byte{0, 0, 0, byte(size), 'f', 't', 'y', 'p'}, body...)
+}
+
+func TestIsImageSequence(t *testing.T) {
+	tests := []struct {
+		data  []byte
+		brand string
+		ok    bool
+	}{
+		{ftyp("avis", "avif", "mif1"), "avis", true},
+		{ftyp("avif", "mif1", "msf1"), "msf1", true},
+		{ftyp("heic", "mif1", "hevc"), "hevc", true},
+		{ftyp("avif", "mif1"), "", false},
+		{ftyp("heic", "mif1", "heic"), "", false},
+		{[]byte("GIF89a"), "", false},
+	}
+	for _, tt := range tests {
+		brand, ok := isImageSequence(tt.data)
+		if brand != tt.brand || ok != tt.ok {
+			t.Errorf("isImageSequence(%q) = %q, %t, want %q, %t", tt.data, brand, ok, tt.brand, tt.ok)
+		}
+	}
+}
+
+func TestIsAVIFSequence(t *testing.T) {
+	tests := []struct {
+		data []byte
+		want bool
+	}{
+		{ftyp("avis", "avif", "mif1"), true},
+		{ftyp("avif", "mif1", "msf1"), true},
+		{ftyp("heic", "mif1", "msf1"), false},
+		{ftyp("avif", "mif1"), false},
+		{[]byte("GIF89a"), false},
+	}
+	for _, tt := range tests {
+		if got := isAVIFSequence(tt.data); got != tt.want {
+			t.Errorf("isAVIFSequence(%q) = %t, want %t", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestOpenImageSequenceWithoutDecoder(t *testing.T) {
+	// HEIF sequences have no decoder in any build
+	_, err := Open(ftyp("heic", "mif1", "hevc"))
+	if !errors.Is(err, ErrUnsupportedFormat) || !strings.Contains(err.Error(), "hevc") {
+		t.Errorf("Expected ErrUnsupportedFormat naming the brand, got %v", err)
+	}
+	if _, err := Open([]byte("not an image")); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("Expected ErrUnsupportedFormat, got %v", err)
+	}
+}
+
+// testSequence yields the same image at fixed timestamps.
+type testSequence struct {
+	timestamps []time.Duration
+}
+
+func (s *testSequence) Next() (*Frame, error) {
+	if len(s.timestamps) == 0 {
+		return nil, io.EOF
+	}
+	timestamp := s.timestamps[0]
+	s.timestamps = s.timestamps[1:]
+	return &Frame{Image: image.NewGray(image.Rect(0, 0, 4, 4)), Timestamp: timestamp}, nil
+}
+
+func TestRegisterFormat(t *testing.T) {
+	t.Cleanup(func() {
+		formatsMu.Lock()
+		formats = nil
+		formatsMu.Unlock()
+	})
+	RegisterFormat("test-avis", func(data []byte) bool {
+		brand, ok := isImageSequence(data)
+		return ok && brand == "avis"
+	}, func(data []byte) (Sequence, error) {
+		return &testSequence{timestamps: []time.Duration{0, 40 * time.Millisecond}}, nil
+	})
+
+	data := ftyp("avis", "avif")
+	result, err := Compare(data, data)
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if len(result.Frames) != 2 || result.Frames[1].Timestamp != 40*time.Millisecond {
+		t.Errorf("Expected frames from the registered decoder, got %+v", result.Frames)
+	}
+
+	if _, err := Open(ftyp("heic", "hevc")); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("Expected other sequences to stay unsupported, got %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package video
+
+import (
+	"bytes"
+	"image"
+	"image/draw"
+	"image/gif"
+	"io"
+	"time"
+)
+
+// minGIFDelay is the delay browsers substitute for GIF frames with a delay
+// of 0 or 1 hundredths of a second.
+const minGIFDelay = 10
+
+// isGIF reports whether data starts with a GIF signature.
+func isGIF(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("GIF87a")) || bytes.HasPrefix(data, []byte("GIF89a"))
+}
+
+// gifSequence composites the frames of an animated GIF onto a canvas the
+// size of the logical screen, honoring each frame's disposal method.
+type gifSequence struct {
+	gif       *gif.GIF
+	canvas    *image.RGBA
+	index     int
+	timestamp time.Duration
+}
+
+// openGIF decodes every frame of an animated GIF.
+func openGIF(data []byte) (Sequence, error) {
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if bounds.Empty() && len(g.Image) > 0 {
+		bounds = g.Image[0].Bounds()
+	}
+	return &gifSequence{gif: g, canvas: image.NewRGBA(bounds)}, nil
+}
+
+func (s *gifSequence) Next() (*Frame, error) {
+	if s.index >= len(s.gif.Image) {
+		return nil, io.EOF
+	}
+	paletted := s.gif.Image[s.index]
+	var disposal byte
+	if s.index < len(s.gif.Disposal) {
+		disposal = s.gif.Disposal[s.index]
+	}
+
+	var previous *image.RGBA
+	if disposal == gif.DisposalPrevious {
+		previous = cloneRGBA(s.canvas)
+	}
+	draw.Draw(s.canvas, paletted.Bounds(), paletted, paletted.Bounds().Min, draw.Over)
+	frame := &Frame{
+		Image:     cloneRGBA(s.canvas),
+		Timestamp: s.timestamp,
+		Duration:  gifDelay(s.gif, s.index),
+	}
+
+	switch disposal {
+	case gif.DisposalBackground:
+		// Browsers clear to transparent rather than the background color
+		draw.Draw(s.canvas, paletted.Bounds(), image.Transparent, image.Point{}, draw.Src)
+	case gif.DisposalPrevious:
+		s.canvas = previous
+	}
+	s.timestamp += frame.Duration
+	s.index++
+	return frame, nil
+}
+
+// gifDelay returns the display duration of frame index.
+func gifDelay(g *gif.GIF, index int) time.Duration {
+	delay := minGIFDelay
+	if index < len(g.Delay) && g.Delay[index] > 1 {
+		delay = g.Delay[index]
+	}
+	return time.Duration(delay) * 10 * time.Millisecond
+}
+
+// cloneRGBA returns a copy of img.
+func cloneRGBA(img *image.RGBA) *image.RGBA {
+	clone := image.NewRGBA(img.Rect)
+	copy(clone.Pix, img.Pix)
+	return clone
+}
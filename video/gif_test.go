@@ -0,0 +1,68 @@
+package video
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestGIFSequence(t *testing.T) {
+	full := image.NewPaletted(image.Rect(0, 0, 4, 4), testPalette)
+	for i := range full.Pix {
+		full.Pix[i] = 1
+	}
+	// A black 2x2 patch that is restored to the white frame after display,
+	// then a transparent patch that keeps the white frame showing through
+	patch := image.NewPaletted(image.Rect(2, 2, 4, 4), testPalette)
+	overlay := image.NewPaletted(image.Rect(0, 0, 2, 2), testPalette)
+	for i := range overlay.Pix {
+		overlay.Pix[i] = 3
+	}
+	g := &gif.GIF{
+		Image:    []*image.Paletted{full, patch, overlay},
+		Delay:    []int{0, 20, 7},
+		Disposal: []byte{gif.DisposalNone, gif.DisposalPrevious, gif.DisposalNone},
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+
+	seq, err := Open(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	want := []struct {
+		timestamp, duration time.Duration
+		corner              color.Color
+	}{
+		{0, 100 * time.Millisecond, color.White},
+		{100 * time.Millisecond, 200 * time.Millisecond, color.Black},
+		{300 * time.Millisecond, 70 * time.Millisecond, color.White},
+	}
+	for i, w := range want {
+		frame, err := seq.Next()
+		if err != nil {
+			t.Fatalf("Frame %d: %v", i, err)
+		}
+		if frame.Timestamp != w.timestamp || frame.Duration != w.duration {
+			t.Errorf("Frame %d: timing %v+%v, want %v+%v", i, frame.Timestamp, frame.Duration, w.timestamp, w.duration)
+		}
+		if frame.Image.Bounds() != image.Rect(0, 0, 4, 4) {
+			t.Errorf("Frame %d: bounds %v", i, frame.Image.Bounds())
+		}
+		if got := color.RGBAModel.Convert(frame.Image.At(3, 3)); got != color.RGBAModel.Convert(w.corner) {
+			t.Errorf("Frame %d: corner %v, want %v", i, got, w.corner)
+		}
+		if got := color.RGBAModel.Convert(frame.Image.At(0, 0)); got != color.RGBAModel.Convert(color.White) {
+			t.Errorf("Frame %d: origin %v, want white", i, got)
+		}
+	}
+	if _, err := seq.Next(); err != io.EOF {
+		t.Errorf("Expected io.EOF after the last frame, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package video
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/ideamans/go-psnr"
+)
+
+// identicalFrames reports whether two frames have the same size and pixels.
+// Frames of the same buffered type are compared in place; others are
+// compared as RGBA.
+func identicalFrames(img1, img2 image.Image) bool {
+	b1, b2 := img1.Bounds(), img2.Bounds()
+	if b1.Size() != b2.Size() {
+		return false
+	}
+	switch a := img1.(type) {
+	case *image.YCbCr:
+		if b, ok := img2.(*image.YCbCr); ok && a.SubsampleRatio == b.SubsampleRatio {
+			for y := 0; y < b1.Dy(); y++ {
+				y1, y2 := b1.Min.Y+y, b2.Min.Y+y
+				yi, yj := a.YOffset(b1.Min.X, y1), b.YOffset(b2.Min.X, y2)
+				if !bytes.Equal(a.Y[yi:yi+b1.Dx()], b.Y[yj:yj+b1.Dx()]) {
+					return false
+				}
+				ci, cj := a.COffset(b1.Min.X, y1), b.COffset(b2.Min.X, y2)
+				n := a.COffset(b1.Max.X-1, y1) - ci + 1
+				if !bytes.Equal(a.Cb[ci:ci+n], b.Cb[cj:cj+n]) || !bytes.Equal(a.Cr[ci:ci+n], b.Cr[cj:cj+n]) {
+					return false
+				}
+			}
+			return true
+		}
+	case *image.Paletted:
+		if b, ok := img2.(*image.Paletted); ok && samePalette(a.Palette, b.Palette) {
+			return equalRows(a.Pix[a.PixOffset(b1.Min.X, b1.Min.Y):], a.Stride, b.Pix[b.PixOffset(b2.Min.X, b2.Min.Y):], b.Stride, b1.Dx(), b1.Dy())
+		}
+	case *image.RGBA:
+		if b, ok := img2.(*image.RGBA); ok {
+			return equalRows(a.Pix[a.PixOffset(b1.Min.X, b1.Min.Y):], a.Stride, b.Pix[b.PixOffset(b2.Min.X, b2.Min.Y):], b.Stride, b1.Dx()*4, b1.Dy())
+		}
+	case *image.NRGBA:
+		if b, ok := img2.(*image.NRGBA); ok {
+			return equalRows(a.Pix[a.PixOffset(b1.Min.X, b1.Min.Y):], a.Stride, b.Pix[b.PixOffset(b2.Min.X, b2.Min.Y):], b.Stride, b1.Dx()*4, b1.Dy())
+		}
+	}
+	p1, p2 := rgba(img1), rgba(img2)
+	return equalRows(p1.Pix, p1.Stride, p2.Pix, p2.Stride, b1.Dx()*4, b1.Dy())
+}
+
+// equalRows compares height rows of width bytes from two buffers.
+func equalRows(pix1 []byte, stride1 int, pix2 []byte, stride2 int, width, height int) bool {
+	for y := 0; y < height; y++ {
+		if !bytes.Equal(pix1[y*stride1:y*stride1+width], pix2[y*stride2:y*stride2+width]) {
+			return false
+		}
+	}
+	return true
+}
+
+// samePalette reports whether two palettes hold the same colors in order.
+func samePalette(p1, p2 []color.Color) bool {
+	if len(p1) != len(p2) {
+		return false
+	}
+	for i := range p1 {
+		r1, g1, b1, a1 := p1[i].RGBA()
+		r2, g2, b2, a2 := p2[i].RGBA()
+		if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+			return false
+		}
+	}
+	return true
+}
+
+// identicalFrame is the result recorded for frames whose pixels match.
+func identicalFrame() *psnr.Result {
+	return &psnr.Result{PSNR: math.Inf(1), Peak: 255, Coverage: 1}
+}
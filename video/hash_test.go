@@ -0,0 +1,61 @@
+package video
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestIdenticalFrames(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	offset := image.NewNRGBA(image.Rect(3, 5, 7, 7))
+	if !identicalFrames(img, offset) {
+		t.Error("Expected equal frames for the same pixels at another origin")
+	}
+	if identicalFrames(img, image.NewRGBA(image.Rect(0, 0, 2, 4))) {
+		t.Error("Expected different frames for different sizes")
+	}
+	img.SetRGBA(3, 1, color.RGBA{A: 1})
+	if identicalFrames(img, offset) {
+		t.Error("Expected different frames for different pixels")
+	}
+	if !identicalFrames(img, img.SubImage(img.Rect)) {
+		t.Error("Expected a frame to equal itself")
+	}
+
+	y1 := image.NewYCbCr(image.Rect(0, 0, 5, 3), image.YCbCrSubsampleRatio420)
+	y2 := image.NewYCbCr(image.Rect(2, 2, 7, 5), image.YCbCrSubsampleRatio420)
+	if !identicalFrames(y1, y2) {
+		t.Error("Expected equal YCbCr frames")
+	}
+	y2.Cr[len(y2.Cr)-1] = 1
+	if identicalFrames(y1, y2) {
+		t.Error("Expected different YCbCr frames for a different chroma sample")
+	}
+}
+
+func TestCompareWithFrameHashing(t *testing.T) {
+	data1 := encodeGIF(t, 5, 0, 1, 2, 1)
+	data2 := encodeGIF(t, 5, 0, 1, 0, 1)
+
+	hashed, err := Compare(data1, data2, WithFrameHashing())
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	plain, err := Compare(data1, data2)
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if hashed.HashMatches != 3 || plain.HashMatches != 0 {
+		t.Errorf("Expected 3 hash matches, got %d and %d", hashed.HashMatches, plain.HashMatches)
+	}
+	for i := range plain.Frames {
+		if hashed.Frames[i] != plain.Frames[i] {
+			t.Errorf("Frame %d: got %+v, want %+v", i, hashed.Frames[i], plain.Frames[i])
+		}
+	}
+	if hashed.Summary != plain.Summary || math.IsInf(hashed.Summary.Min, 1) {
+		t.Errorf("Unexpected summary: %+v", hashed.Summary)
+	}
+}
@@ -0,0 +1,293 @@
+//go:build libav && cgo
+
+package video
+
+/*
+#cgo pkg-config: libavformat libavcodec libavutil libswscale
+#include <stdlib.h>
+#include <string.h>
+#include <libavformat/avformat.h>
+#include <libavcodec/avcodec.h>
+#include <libavutil/avutil.h>
+#include <libswscale/swscale.h>
+
+// psnr_video is an open video with the decoder of its best video stream,
+// reading from a buffer that must outlive it.
+typedef struct {
+	const uint8_t *data;
+	size_t size;
+	size_t pos;
+	AVIOContext *io;
+	AVFormatContext *format;
+	AVCodecContext *codec;
+	struct SwsContext *sws;
+	AVPacket *packet;
+	AVFrame *frame;
+	int stream;
+} psnr_video;
+
+static int psnr_video_read(void *opaque, uint8_t *buf, int n) {
+	psnr_video *v = opaque;
+	size_t left = v->size - v->pos;
+	if (left == 0) {
+		return AVERROR_EOF;
+	}
+	if ((size_t)n > left) {
+		n = (int)left;
+	}
+	memcpy(buf, v->data + v->pos, n);
+	v->pos += n;
+	return n;
+}
+
+static int64_t psnr_video_seek(void *opaque, int64_t offset, int whence) {
+	psnr_video *v = opaque;
+	int64_t pos;
+	switch (whence & ~AVSEEK_FORCE) {
+	case AVSEEK_SIZE:
+		return v->size;
+	case SEEK_SET:
+		pos = offset;
+		break;
+	case SEEK_CUR:
+		pos = v->pos + offset;
+		break;
+	case SEEK_END:
+		pos = v->size + offset;
+		break;
+	default:
+		return AVERROR(EINVAL);
+	}
+	if (pos < 0 || pos > (int64_t)v->size) {
+		return AVERROR(EINVAL);
+	}
+	v->pos = pos;
+	return pos;
+}
+
+static void psnr_video_close(psnr_video *v) {
+	sws_freeContext(v->sws);
+	v->sws = NULL;
+	av_frame_free(&v->frame);
+	av_packet_free(&v->packet);
+	avcodec_free_context(&v->codec);
+	avformat_close_input(&v->format);
+	// Custom I/O contexts are left to the caller by libavformat
+	if (v->io != NULL) {
+		av_freep(&v->io->buffer);
+		avio_context_free(&v->io);
+	}
+}
+
+// psnr_video_open demuxes data and opens the decoder of its best video
+// stream. v must be zeroed and is closed on failure.
+static int psnr_video_open(psnr_video *v, const uint8_t *data, size_t size) {
+	const int buffer_size = 1 << 16;
+	const AVCodec *decoder = NULL;
+	int err = AVERROR(ENOMEM);
+	v->data = data;
+	v->size = size;
+	uint8_t *buffer = av_malloc(buffer_size);
+	if (buffer == NULL) {
+		goto fail;
+	}
+	v->io = avio_alloc_context(buffer, buffer_size, 0, v, psnr_video_read, NULL, psnr_video_seek);
+	if (v->io == NULL) {
+		av_free(buffer);
+		goto fail;
+	}
+	v->format = avformat_alloc_context();
+	if (v->format == NULL) {
+		goto fail;
+	}
+	v->format->pb = v->io;
+	if ((err = avformat_open_input(&v->format, NULL, NULL, NULL)) < 0) {
+		goto fail;
+	}
+	if ((err = avformat_find_stream_info(v->format, NULL)) < 0) {
+		goto fail;
+	}
+	if ((err = av_find_best_stream(v->format, AVMEDIA_TYPE_VIDEO, -1, -1, &decoder, 0)) < 0) {
+		goto fail;
+	}
+	v->stream = err;
+	err = AVERROR(ENOMEM);
+	v->codec = avcodec_alloc_context3(decoder);
+	v->packet = av_packet_alloc();
+	v->frame = av_frame_alloc();
+	if (v->codec == NULL || v->packet == NULL || v->frame == NULL) {
+		goto fail;
+	}
+	if ((err = avcodec_parameters_to_context(v->codec, v->format->streams[v->stream]->codecpar)) < 0) {
+		goto fail;
+	}
+	if ((err = avcodec_open2(v->codec, decoder, NULL)) < 0) {
+		goto fail;
+	}
+	return 0;
+fail:
+	psnr_video_close(v);
+	return err;
+}
+
+// psnr_video_next decodes the next frame, returning AVERROR_EOF after the
+// last one once the decoder has been drained.
+static int psnr_video_next(psnr_video *v) {
+	for (;;) {
+		int err = avcodec_receive_frame(v->codec, v->frame);
+		if (err != AVERROR(EAGAIN)) {
+			return err;
+		}
+		err = av_read_frame(v->format, v->packet);
+		if (err == AVERROR_EOF) {
+			if ((err = avcodec_send_packet(v->codec, NULL)) < 0) {
+				return err;
+			}
+			continue;
+		}
+		if (err < 0) {
+			return err;
+		}
+		if (v->packet->stream_index == v->stream) {
+			err = avcodec_send_packet(v->codec, v->packet);
+		}
+		av_packet_unref(v->packet);
+		if (err < 0) {
+			return err;
+		}
+	}
+}
+
+static int psnr_video_is_eof(int err) {
+	return err == AVERROR_EOF;
+}
+
+// psnr_video_times returns the presentation time of the decoded frame from
+// the start of the stream and its duration, in seconds.
+static void psnr_video_times(psnr_video *v, double *timestamp, double *duration) {
+	AVStream *stream = v->format->streams[v->stream];
+	double base = av_q2d(stream->time_base);
+	int64_t pts = v->frame->best_effort_timestamp;
+	*timestamp = 0;
+	if (pts != AV_NOPTS_VALUE) {
+		if (stream->start_time != AV_NOPTS_VALUE) {
+			pts -= stream->start_time;
+		}
+		*timestamp = pts * base;
+	}
+#if LIBAVUTIL_VERSION_INT >= AV_VERSION_INT(57, 30, 100)
+	*duration = v->frame->duration * base;
+#else
+	*duration = v->frame->pkt_duration * base;
+#endif
+}
+
+// psnr_video_rgba converts the decoded frame to 8-bit RGBA in out, which
+// holds width*height*4 bytes.
+static int psnr_video_rgba(psnr_video *v, uint8_t *out) {
+	AVFrame *f = v->frame;
+	v->sws = sws_getCachedContext(v->sws, f->width, f->height, f->format,
+		f->width, f->height, AV_PIX_FMT_RGBA, SWS_BICUBIC, NULL, NULL, NULL);
+	if (v->sws == NULL) {
+		return AVERROR(EINVAL);
+	}
+	uint8_t *planes[4] = {out, NULL, NULL, NULL};
+	int strides[4] = {f->width * 4, 0, 0, 0};
+	sws_scale(v->sws, (const uint8_t *const *)f->data, f->linesize, 0, f->height, planes, strides);
+	return 0;
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"runtime"
+	"unsafe"
+)
+
+func init() {
+	RegisterFormat("libav", func(data []byte) bool {
+		_, ok := isVideoContainer(data)
+		return ok
+	}, openLibav)
+}
+
+// libavSequence decodes the frames of a video lazily.
+type libavSequence struct {
+	video *C.psnr_video
+	// data is the C copy of the encoded video read by the demuxer.
+	data unsafe.Pointer
+}
+
+// openLibav demuxes an MP4, WebM or Matroska video and opens the decoder of
+// its best video stream.
+func openLibav(data []byte) (Sequence, error) {
+	if len(data) == 0 {
+		return nil, ErrUnsupportedFormat
+	}
+	s := &libavSequence{
+		video: (*C.psnr_video)(C.calloc(1, C.size_t(unsafe.Sizeof(C.psnr_video{})))),
+		data:  C.CBytes(data),
+	}
+	if err := C.psnr_video_open(s.video, (*C.uint8_t)(s.data), C.size_t(len(data))); err < 0 {
+		s.Close()
+		return nil, libavError(err)
+	}
+	runtime.SetFinalizer(s, (*libavSequence).Close)
+	return s, nil
+}
+
+func (s *libavSequence) Next() (*Frame, error) {
+	if s.video == nil {
+		return nil, io.EOF
+	}
+	if err := C.psnr_video_next(s.video); err < 0 {
+		s.Close()
+		if C.psnr_video_is_eof(err) != 0 {
+			return nil, io.EOF
+		}
+		return nil, libavError(err)
+	}
+
+	width, height := int(s.video.frame.width), int(s.video.frame.height)
+	if width <= 0 || height <= 0 {
+		s.Close()
+		return nil, fmt.Errorf("libav: invalid frame size %dx%d", width, height)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	if err := C.psnr_video_rgba(s.video, (*C.uint8_t)(unsafe.Pointer(&img.Pix[0]))); err < 0 {
+		s.Close()
+		return nil, libavError(err)
+	}
+	var timestamp, duration C.double
+	C.psnr_video_times(s.video, &timestamp, &duration)
+	return &Frame{
+		Image:     img,
+		Timestamp: seconds(float64(timestamp)),
+		Duration:  seconds(float64(duration)),
+	}, nil
+}
+
+// Close releases the decoder. It is called after the last frame and can be
+// called again.
+func (s *libavSequence) Close() error {
+	if s.video != nil {
+		C.psnr_video_close(s.video)
+		C.free(unsafe.Pointer(s.video))
+		s.video = nil
+	}
+	if s.data != nil {
+		C.free(s.data)
+		s.data = nil
+	}
+	return nil
+}
+
+// libavError describes a libav error code.
+func libavError(code C.int) error {
+	var buf [C.AV_ERROR_MAX_STRING_SIZE]C.char
+	C.av_strerror(code, &buf[0], C.size_t(len(buf)))
+	return fmt.Errorf("libav: %s", C.GoString(&buf[0]))
+}
@@ -0,0 +1,37 @@
+//go:build libav && cgo
+
+package video
+
+import (
+	"math"
+	"os"
+	"testing"
+)
+
+// TestCompareLibav compares a real video named by LIBAV_SAMPLE, such as an
+// MP4 or WebM file, with itself.
+func TestCompareLibav(t *testing.T) {
+	path := os.Getenv("LIBAV_SAMPLE")
+	if path == "" {
+		t.Skip("LIBAV_SAMPLE is not set")
+	}
+	result, err := CompareFiles(path, path)
+	if err != nil {
+		t.Fatalf("CompareFiles failed: %v", err)
+	}
+	if len(result.Frames) == 0 {
+		t.Fatal("Expected decoded frames")
+	}
+	for i, frame := range result.Frames {
+		if !math.IsInf(frame.PSNR, 1) {
+			t.Errorf("Frame %d: expected identical frames, got %v dB", i, frame.PSNR)
+		}
+		if i > 0 && frame.Timestamp <= result.Frames[i-1].Timestamp {
+			t.Errorf("Frame %d: expected increasing timestamps, got %v after %v", i, frame.Timestamp, result.Frames[i-1].Timestamp)
+		}
+	}
+
+	if _, err := Open([]byte("\x1a\x45\xdf\xa3webm")); err == nil {
+		t.Error("Expected error for a truncated WebM file")
+	}
+}
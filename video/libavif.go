@@ -0,0 +1,149 @@
+//go:build libavif && cgo
+
+package video
+
+/*
+#cgo LDFLAGS: -lavif
+#include <stdlib.h>
+#include <avif/avif.h>
+
+// psnr_avis_open parses an AVIF image sequence held in data, which must
+// outlive the decoder. On failure it returns the libavif error message.
+static const char *psnr_avis_open(const uint8_t *data, size_t size, avifDecoder **out) {
+	avifDecoder *decoder = avifDecoderCreate();
+	if (decoder == NULL) {
+		return avifResultToString(AVIF_RESULT_OUT_OF_MEMORY);
+	}
+	avifResult result = avifDecoderSetIOMemory(decoder, data, size);
+	if (result == AVIF_RESULT_OK) {
+		result = avifDecoderParse(decoder);
+	}
+	if (result != AVIF_RESULT_OK) {
+		avifDecoderDestroy(decoder);
+		return avifResultToString(result);
+	}
+	*out = decoder;
+	return NULL;
+}
+
+// psnr_avis_next decodes the next image of the sequence, returning
+// AVIF_RESULT_NO_IMAGES_REMAINING after the last one.
+static avifResult psnr_avis_next(avifDecoder *decoder) {
+	return avifDecoderNextImage(decoder);
+}
+
+// psnr_avis_rgba converts the decoded image to straight RGBA samples of
+// depth 8 or 16 bits, in native byte order, into pixels without padding.
+static avifResult psnr_avis_rgba(avifDecoder *decoder, void *pixels, int depth) {
+	avifRGBImage rgb;
+	avifRGBImageSetDefaults(&rgb, decoder->image);
+	rgb.format = AVIF_RGB_FORMAT_RGBA;
+	rgb.depth = depth;
+	rgb.pixels = pixels;
+	rgb.rowBytes = rgb.width * 4 * (depth / 8);
+	return avifImageYUVToRGB(decoder->image, &rgb);
+}
+
+static const char *psnr_avis_error(avifResult result) {
+	return avifResultToString(result);
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"runtime"
+	"unsafe"
+)
+
+func init() {
+	RegisterFormat("libavif", isAVIFSequence, openLibavif)
+}
+
+// libavifSequence decodes the frames of an AVIF image sequence lazily.
+type libavifSequence struct {
+	decoder *C.avifDecoder
+	// data is the C copy of the encoded sequence read by the decoder.
+	data unsafe.Pointer
+}
+
+// openLibavif parses an AVIF image sequence.
+func openLibavif(data []byte) (Sequence, error) {
+	if len(data) == 0 {
+		return nil, ErrUnsupportedFormat
+	}
+	s := &libavifSequence{data: C.CBytes(data)}
+	if msg := C.psnr_avis_open((*C.uint8_t)(s.data), C.size_t(len(data)), &s.decoder); msg != nil {
+		s.Close()
+		return nil, fmt.Errorf("libavif: %s", C.GoString(msg))
+	}
+	runtime.SetFinalizer(s, (*libavifSequence).Close)
+	return s, nil
+}
+
+// Next decodes the next frame to an *image.NRGBA, or an *image.NRGBA64 for
+// images deeper than 8 bits, timed by the sequence's sample timing.
+func (s *libavifSequence) Next() (*Frame, error) {
+	if s.decoder == nil {
+		return nil, io.EOF
+	}
+	if result := C.psnr_avis_next(s.decoder); result != C.AVIF_RESULT_OK {
+		s.Close()
+		if result == C.AVIF_RESULT_NO_IMAGES_REMAINING {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("libavif: %s", C.GoString(C.psnr_avis_error(result)))
+	}
+
+	decoded := s.decoder.image
+	width, height := int(decoded.width), int(decoded.height)
+	if width <= 0 || height <= 0 {
+		s.Close()
+		return nil, fmt.Errorf("libavif: invalid frame size %dx%d", width, height)
+	}
+	rect := image.Rect(0, 0, width, height)
+	var img image.Image
+	var result C.avifResult
+	if decoded.depth > 8 {
+		samples := make([]uint16, width*height*4)
+		result = C.psnr_avis_rgba(s.decoder, unsafe.Pointer(&samples[0]), 16)
+		// NRGBA64 stores big-endian samples
+		nrgba := image.NewNRGBA64(rect)
+		for i, v := range samples {
+			nrgba.Pix[2*i] = uint8(v >> 8)
+			nrgba.Pix[2*i+1] = uint8(v)
+		}
+		img = nrgba
+	} else {
+		nrgba := image.NewNRGBA(rect)
+		result = C.psnr_avis_rgba(s.decoder, unsafe.Pointer(&nrgba.Pix[0]), 8)
+		img = nrgba
+	}
+	if result != C.AVIF_RESULT_OK {
+		s.Close()
+		return nil, fmt.Errorf("libavif: %s", C.GoString(C.psnr_avis_error(result)))
+	}
+
+	timing := s.decoder.imageTiming
+	return &Frame{
+		Image:     img,
+		Timestamp: seconds(float64(timing.pts)),
+		Duration:  seconds(float64(timing.duration)),
+	}, nil
+}
+
+// Close releases the decoder. It is called after the last frame and can be
+// called again.
+func (s *libavifSequence) Close() error {
+	if s.decoder != nil {
+		C.avifDecoderDestroy(s.decoder)
+		s.decoder = nil
+	}
+	if s.data != nil {
+		C.free(s.data)
+		s.data = nil
+	}
+	return nil
+}
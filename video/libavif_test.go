@@ -0,0 +1,37 @@
+//go:build libavif && cgo
+
+package video
+
+import (
+	"math"
+	"os"
+	"testing"
+)
+
+// TestCompareLibavif compares a real AVIF image sequence named by
+// LIBAVIF_SAMPLE with itself.
+func TestCompareLibavif(t *testing.T) {
+	path := os.Getenv("LIBAVIF_SAMPLE")
+	if path == "" {
+		t.Skip("LIBAVIF_SAMPLE is not set")
+	}
+	result, err := CompareFiles(path, path)
+	if err != nil {
+		t.Fatalf("CompareFiles failed: %v", err)
+	}
+	if len(result.Frames) == 0 {
+		t.Fatal("Expected decoded frames")
+	}
+	for i, frame := range result.Frames {
+		if !math.IsInf(frame.PSNR, 1) {
+			t.Errorf("Frame %d: expected identical frames, got %v dB", i, frame.PSNR)
+		}
+		if i > 0 && frame.Timestamp <= result.Frames[i-1].Timestamp {
+			t.Errorf("Frame %d: expected increasing timestamps, got %v after %v", i, frame.Timestamp, result.Frames[i-1].Timestamp)
+		}
+	}
+
+	if _, err := Open(ftyp("avis", "avif", "msf1")); err == nil {
+		t.Error("Expected error for a truncated AVIF sequence")
+	}
+}
@@ -0,0 +1,151 @@
+package video
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/ideamans/go-psnr"
+)
+
+// Option configures a sequence comparison.
+type Option func(*options)
+
+// options holds the settings collected from Option values.
+type options struct {
+	frameOptions []psnr.Option
+	pairing      Pairing
+	flicker      bool
+	frameHashing bool
+	sampling     Sampling
+	selection    selection
+	// sceneThreshold is the WithSceneCuts threshold in dB; scenes are not
+	// detected when it is zero.
+	sceneThreshold float64
+	// worstCount frame pairs are written to worstDir.
+	worstCount int
+	worstDir   string
+}
+
+// newOptions applies opts over the defaults and validates the result.
+func newOptions(opts []Option) (*options, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.pairing < PairByIndex || o.pairing > PairHold {
+		return nil, fmt.Errorf("unknown frame pairing %d", o.pairing)
+	}
+	if err := validateSceneThreshold(o.sceneThreshold); err != nil {
+		return nil, err
+	}
+	if err := o.sampling.validate(); err != nil {
+		return nil, err
+	}
+	if err := o.selection.validate(); err != nil {
+		return nil, err
+	}
+	if o.worstCount < 0 || o.worstCount > 0 && o.worstDir == "" {
+		return nil, fmt.Errorf("invalid worst frames: %d to %q", o.worstCount, o.worstDir)
+	}
+	return o, nil
+}
+
+// WithFrameOptions applies opts to the comparison of every frame pair.
+func WithFrameOptions(opts ...psnr.Option) Option {
+	return func(o *options) {
+		o.frameOptions = append(o.frameOptions, opts...)
+	}
+}
+
+// WithPairing selects how frames of the second sequence are matched with
+// frames of the first. By default (PairByIndex) they are paired by
+// position.
+func WithPairing(p Pairing) Option {
+	return func(o *options) {
+		o.pairing = p
+	}
+}
+
+// WithFlicker measures how differently the frames change over time in the
+// two sequences, reporting each pair's temporal error in
+// FrameResult.Flicker and the averages in Result.Flicker. The change is
+// measured from the frames decoded just before, also when WithSampling or
+// a selection skips them.
+func WithFlicker() Option {
+	return func(o *options) {
+		o.flicker = true
+	}
+}
+
+// WithSceneCuts splits the comparison into scenes, cutting wherever two
+// consecutive frames of the first sequence have a PSNR below threshold dB
+// (around 20 dB separates cuts from motion in typical footage), and
+// reports a summary per scene in Result.Scenes. Cuts are detected on every
+// decoded frame, also when WithSampling or a selection leaves it out of the
+// comparison, and scenes without compared frames are omitted.
+func WithSceneCuts(threshold float64) Option {
+	return func(o *options) {
+		o.sceneThreshold = threshold
+	}
+}
+
+// WithWorstFrames writes the k frame pairs with the lowest PSNR to dir,
+// which is created if needed, as PNG files named after the frame index:
+// frame-00042.first.png, frame-00042.second.png and a frame-00042.diff.png
+// heatmap. Identical pairs are never written. The files are listed in
+// Result.WorstFrames. The kept frames stay in memory until the comparison
+// ends.
+func WithWorstFrames(k int, dir string) Option {
+	return func(o *options) {
+		o.worstCount = k
+		o.worstDir = dir
+	}
+}
+
+// WithFrameHashing checks the decoded pixels of every frame pair for
+// equality first and compares only the pairs that differ, which speeds up
+// the verification of long, mostly identical transcodes. Equal pairs are
+// recorded as identical (+Inf dB, MSE 0) without running the options of
+// WithFrameOptions, and are counted in Result.HashMatches.
+func WithFrameHashing() Option {
+	return func(o *options) {
+		o.frameHashing = true
+	}
+}
+
+// WithSampling compares only the frames of the first sequence that s
+// selects, e.g. Sampling{Every: 10} or Sampling{PerSecond: 2}. The others
+// are decoded but not compared, though flicker and scene cuts are still
+// measured between consecutive decoded frames. The scheme is recorded in
+// Result.Sampling.
+func WithSampling(s Sampling) Option {
+	return func(o *options) {
+		o.sampling = s
+	}
+}
+
+// WithTimeRange compares only the frames of the first sequence whose
+// timestamps lie in [from, to), or from from to the end when to is zero, to
+// re-check a known problem area. The selection filters rather than seeks:
+// frames before from are still decoded and discarded, as are the frames
+// between listed indices. Decoding stops at to, so later frames are neither
+// decoded nor checked for a frame count mismatch.
+func WithTimeRange(from, to time.Duration) Option {
+	return func(o *options) {
+		o.selection.from = from
+		o.selection.to = to
+	}
+}
+
+// WithFrameList compares only the frames of the first sequence at the given
+// indices. The frames in between are decoded and discarded, and decoding
+// stops after the last of them. Combined with WithTimeRange, a frame must be
+// selected by both.
+func WithFrameList(indices ...int) Option {
+	return func(o *options) {
+		frames := append(slices.Clone(o.selection.frames), indices...)
+		slices.Sort(frames)
+		o.selection.frames = slices.Compact(frames)
+	}
+}
@@ -0,0 +1,85 @@
+package video
+
+import (
+	"io"
+	"time"
+)
+
+// Pairing selects how frames of two sequences are matched.
+type Pairing int
+
+const (
+	// PairByIndex pairs frames by position and requires both sequences to
+	// have the same number of frames.
+	PairByIndex Pairing = iota
+	// PairNearest pairs every frame of the first sequence with the frame of
+	// the second whose timestamp is closest, the earlier one on ties, so
+	// sequences with different frame rates can be compared.
+	PairNearest
+	// PairHold pairs every frame of the first sequence with the frame of the
+	// second that is on screen at its timestamp, as a viewer would see them
+	// side by side: the last frame that started at or before it.
+	PairHold
+)
+
+// timeline matches timestamps with the frames of a sequence, reading ahead
+// by one frame.
+type timeline struct {
+	seq     Sequence
+	index   int
+	current *Frame
+	next    *Frame
+	err     error
+}
+
+// newTimeline reads the first two frames of seq.
+func newTimeline(seq Sequence) (*timeline, error) {
+	t := &timeline{seq: seq}
+	first, err := seq.Next()
+	if err != nil {
+		return nil, err
+	}
+	t.current = first
+	t.readNext()
+	return t, nil
+}
+
+// readNext reads the frame after the current one, keeping it nil at the end
+// of the sequence.
+func (t *timeline) readNext() {
+	t.next, t.err = t.seq.Next()
+	if t.err == io.EOF {
+		t.next, t.err = nil, nil
+	}
+}
+
+// match advances to the frame pairing selects for timestamp and returns it
+// with its index. Timestamps must not decrease between calls.
+func (t *timeline) match(timestamp time.Duration, pairing Pairing) (*Frame, int, error) {
+	for t.next != nil {
+		var advance bool
+		if pairing == PairHold {
+			advance = t.next.Timestamp <= timestamp
+		} else {
+			advance = absDuration(t.next.Timestamp-timestamp) < absDuration(t.current.Timestamp-timestamp)
+		}
+		if !advance {
+			break
+		}
+		t.current = t.next
+		t.index++
+		t.readNext()
+	}
+	if t.err != nil {
+		return nil, 0, t.err
+	}
+	return t.current, t.index, nil
+}
+
+// absDuration returns the absolute value of d.
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,75 @@
+package video
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ideamans/go-psnr"
+)
+
+func TestCompareWithPairing(t *testing.T) {
+	// Six 40 ms frames against three 60 ms frames of the same scenes
+	data1 := encodeGIF(t, 4, 0, 0, 1, 1, 2, 2)
+	data2 := encodeGIF(t, 6, 0, 1, 2)
+
+	if _, err := Compare(data1, data2); !errors.Is(err, ErrFrameCountMismatch) {
+		t.Errorf("Expected ErrFrameCountMismatch by index, got %v", err)
+	}
+
+	tests := []struct {
+		pairing Pairing
+		matched []int
+	}{
+		// Timestamps 0, 40, 80, 120, 160, 200 against 0, 60, 120
+		{PairNearest, []int{0, 1, 1, 2, 2, 2}},
+		{PairHold, []int{0, 0, 1, 2, 2, 2}},
+	}
+	for _, tt := range tests {
+		result, err := Compare(data1, data2, WithPairing(tt.pairing))
+		if err != nil {
+			t.Fatalf("Compare failed: %v", err)
+		}
+		if len(result.Frames) != len(tt.matched) {
+			t.Fatalf("Expected %d frames, got %d", len(tt.matched), len(result.Frames))
+		}
+		for i, frame := range result.Frames {
+			if frame.Index != i || frame.MatchedIndex != tt.matched[i] ||
+				frame.MatchedTimestamp != time.Duration(tt.matched[i])*60*time.Millisecond {
+				t.Errorf("Pairing %d, frame %d: unexpected match %+v", tt.pairing, i, frame)
+			}
+		}
+	}
+
+	// Holding the displayed frame reproduces the scenes exactly
+	held, err := Compare(data1, data2, WithPairing(PairHold))
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	for _, frame := range held.Frames {
+		if math.IsInf(frame.PSNR, 1) == (frame.Index == 3) {
+			t.Errorf("Expected only frame 3 to differ from the frame on screen, got %f dB for frame %d", frame.PSNR, frame.Index)
+		}
+	}
+
+	if _, err := Compare(data1, data2, WithPairing(Pairing(9))); err == nil {
+		t.Error("Expected error for an unknown pairing")
+	}
+}
+
+func TestCompareWithFrameOptions(t *testing.T) {
+	data1 := encodeGIF(t, 5, 0, 1)
+	data2 := encodeGIF(t, 5, 0, 2)
+	result, err := Compare(data1, data2, WithFrameOptions(psnr.WithPeakMode(psnr.PeakFixed(128))))
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	plain, err := Compare(data1, data2)
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if result.Frames[1].PSNR >= plain.Frames[1].PSNR {
+		t.Errorf("Expected a lower peak to lower the PSNR, got %f and %f", result.Frames[1].PSNR, plain.Frames[1].PSNR)
+	}
+}
@@ -0,0 +1,51 @@
+package video
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Sampling selects the frames of the first sequence that are compared, so
+// long videos can be measured without comparing every frame. The zero
+// Sampling compares every frame; when both fields are set a frame must
+// satisfy both.
+type Sampling struct {
+	// Every compares every Nth frame, starting with the first.
+	Every int
+	// PerSecond compares at most this many frames per second of
+	// presentation time: the first frame at or after each interval of
+	// 1/PerSecond seconds.
+	PerSecond float64
+}
+
+// validate reports an invalid sampling.
+func (s Sampling) validate() error {
+	if s.Every < 0 || s.PerSecond < 0 || math.IsInf(s.PerSecond, 0) || math.IsNaN(s.PerSecond) {
+		return fmt.Errorf("invalid frame sampling: %+v", s)
+	}
+	return nil
+}
+
+// sampler decides which frames a Sampling selects.
+type sampler struct {
+	Sampling
+	// next is the earliest timestamp of the next frame sampled per second.
+	next time.Duration
+}
+
+// sample reports whether the frame at index with timestamp is compared.
+// Frames must be passed in presentation order.
+func (s *sampler) sample(index int, timestamp time.Duration) bool {
+	if s.Every > 1 && index%s.Every != 0 {
+		return false
+	}
+	if s.PerSecond > 0 {
+		if timestamp < s.next {
+			return false
+		}
+		interval := max(time.Duration(float64(time.Second)/s.PerSecond), 1)
+		s.next = (timestamp/interval + 1) * interval
+	}
+	return true
+}
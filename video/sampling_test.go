@@ -0,0 +1,54 @@
+package video
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCompareWithSampling(t *testing.T) {
+	data1 := encodeGIF(t, 5, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+	data2 := encodeGIF(t, 5, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1)
+
+	tests := []struct {
+		sampling Sampling
+		indices  []int
+	}{
+		{Sampling{}, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{Sampling{Every: 4}, []int{0, 4, 8}},
+		// Frames are 50ms apart, so every 250ms is every fifth frame
+		{Sampling{PerSecond: 4}, []int{0, 5}},
+		{Sampling{Every: 2, PerSecond: 8}, []int{0, 4, 6, 8}},
+	}
+	for _, tt := range tests {
+		result, err := Compare(data1, data2, WithSampling(tt.sampling))
+		if err != nil {
+			t.Fatalf("%+v: Compare failed: %v", tt.sampling, err)
+		}
+		if result.Sampling != tt.sampling || result.Summary.Count != len(tt.indices) {
+			t.Errorf("%+v: unexpected result %+v", tt.sampling, result)
+		}
+		for i, frame := range result.Frames {
+			if i >= len(tt.indices) || frame.Index != tt.indices[i] {
+				t.Errorf("%+v: unexpected frame %d: %+v", tt.sampling, i, frame)
+			}
+		}
+	}
+
+	if _, err := Compare(data1, data2, WithSampling(Sampling{Every: -1})); err == nil {
+		t.Error("Expected error for a negative interval")
+	}
+}
+
+func TestSamplerUnevenTimestamps(t *testing.T) {
+	s := sampler{Sampling: Sampling{PerSecond: 1}}
+	var sampled []int
+	for i, ms := range []int{0, 400, 1100, 1200, 2900, 3000} {
+		if s.sample(i, time.Duration(ms)*time.Millisecond) {
+			sampled = append(sampled, i)
+		}
+	}
+	if want := []int{0, 2, 4, 5}; !slices.Equal(sampled, want) {
+		t.Errorf("Expected frames %v, got %v", want, sampled)
+	}
+}
@@ -0,0 +1,109 @@
+package video
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"time"
+
+	"github.com/ideamans/go-psnr"
+)
+
+// Scene is a run of frames between two scene cuts of the first sequence,
+// as detected by WithSceneCuts.
+type Scene struct {
+	// Start and End are the indices of the first frame and of the frame
+	// after the last one in Result.Frames.
+	Start, End int
+	// Timestamp is the presentation time of the frame that starts the
+	// scene, which sampling or a selection may have left out of
+	// Result.Frames.
+	Timestamp time.Duration
+	// Summary aggregates the PSNR of the scene's frames.
+	Summary psnr.Summary
+}
+
+// validateSceneThreshold reports an invalid WithSceneCuts threshold.
+func validateSceneThreshold(threshold float64) error {
+	if threshold < 0 || math.IsInf(threshold, 0) || math.IsNaN(threshold) {
+		return fmt.Errorf("invalid scene cut threshold: %g dB", threshold)
+	}
+	return nil
+}
+
+// sceneDetector splits a comparison into scenes where consecutive frames of
+// the first sequence differ by more than a threshold.
+type sceneDetector struct {
+	threshold  float64
+	previous   *image.RGBA
+	scenes     []Scene
+	aggregator psnr.Aggregator
+}
+
+// observe checks every decoded frame of the first sequence for a cut from
+// the previous one, compared or not, starting a new scene at index, the
+// position the next compared frame takes in Result.Frames. A scene none of
+// whose frames were compared is replaced by the next one.
+func (d *sceneDetector) observe(index int, frame *Frame) {
+	current := rgba(frame.Image)
+	if d.previous == nil || d.isCut(d.previous, current) {
+		if n := len(d.scenes); n > 0 && d.scenes[n-1].Start == index {
+			d.scenes[n-1].Timestamp = frame.Timestamp
+		} else {
+			d.finish(index)
+			d.scenes = append(d.scenes, Scene{Start: index, Timestamp: frame.Timestamp})
+		}
+	}
+	d.previous = current
+}
+
+// add records the result r of a compared frame in the current scene.
+func (d *sceneDetector) add(r *psnr.Result) {
+	d.aggregator.Add(r)
+}
+
+// isCut reports whether the PSNR between two consecutive frames falls below
+// the threshold. Frames of different sizes always cut.
+func (d *sceneDetector) isCut(previous, current *image.RGBA) bool {
+	if previous.Rect != current.Rect {
+		return true
+	}
+	var sum uint64
+	width, height := current.Rect.Dx(), current.Rect.Dy()
+	for y := 0; y < height; y++ {
+		p, c := previous.Pix[y*previous.Stride:], current.Pix[y*current.Stride:]
+		for i := 0; i < width*4; i++ {
+			if i%4 == 3 {
+				continue
+			}
+			diff := int64(c[i]) - int64(p[i])
+			sum += uint64(diff * diff)
+		}
+	}
+	if sum == 0 {
+		return false
+	}
+	mse := float64(sum) / float64(width*height*3)
+	return 10*math.Log10(255*255/mse) < d.threshold
+}
+
+// result closes the last scene before frame end and returns the scenes,
+// dropping a last scene none of whose frames were compared.
+func (d *sceneDetector) result(end int) []Scene {
+	d.finish(end)
+	if n := len(d.scenes); n > 0 && d.scenes[n-1].Start == end {
+		return d.scenes[:n-1]
+	}
+	return d.scenes
+}
+
+// finish closes the current scene before frame end.
+func (d *sceneDetector) finish(end int) {
+	if len(d.scenes) == 0 {
+		return
+	}
+	scene := &d.scenes[len(d.scenes)-1]
+	scene.End = end
+	scene.Summary = d.aggregator.Summary()
+	d.aggregator = psnr.Aggregator{}
+}
@@ -0,0 +1,75 @@
+package video
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCompareWithSceneCuts(t *testing.T) {
+	// Black then white scenes; only the second one is damaged
+	data1 := encodeGIF(t, 5, 0, 0, 1, 1, 1)
+	data2 := encodeGIF(t, 5, 0, 0, 1, 2, 2)
+
+	result, err := Compare(data1, data2, WithSceneCuts(20))
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if len(result.Scenes) != 2 {
+		t.Fatalf("Expected 2 scenes, got %+v", result.Scenes)
+	}
+	first, second := result.Scenes[0], result.Scenes[1]
+	if first.Start != 0 || first.End != 2 || first.Summary.Count != 2 || !math.IsInf(first.Summary.Mean, 1) {
+		t.Errorf("Unexpected first scene: %+v", first)
+	}
+	if second.Start != 2 || second.End != 5 || second.Timestamp != 100*time.Millisecond ||
+		second.Summary.Count != 3 || second.Summary.InfCount != 1 {
+		t.Errorf("Unexpected second scene: %+v", second)
+	}
+
+	plain, err := Compare(data1, data2)
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if plain.Scenes != nil {
+		t.Error("Expected no scenes by default")
+	}
+	if _, err := Compare(data1, data2, WithSceneCuts(-1)); err == nil {
+		t.Error("Expected error for a negative threshold")
+	}
+}
+
+func TestCompareWithSceneCutsSampled(t *testing.T) {
+	// Frame 1 is a scene of its own between the sampled frames, and the
+	// white scene from frame 5 on is sampled from its second frame
+	data := encodeGIF(t, 5, 0, 1, 0, 0, 0, 1, 1, 1)
+
+	result, err := Compare(data, data, WithSceneCuts(20), WithSampling(Sampling{Every: 2}))
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	want := []Scene{
+		{Start: 0, End: 1},
+		{Start: 1, End: 3, Timestamp: 100 * time.Millisecond},
+		{Start: 3, End: 4, Timestamp: 250 * time.Millisecond},
+	}
+	if len(result.Scenes) != len(want) {
+		t.Fatalf("Expected %d scenes, got %+v", len(want), result.Scenes)
+	}
+	for i, scene := range result.Scenes {
+		if scene.Start != want[i].Start || scene.End != want[i].End || scene.Timestamp != want[i].Timestamp ||
+			scene.Summary.Count != scene.End-scene.Start {
+			t.Errorf("Scene %d: expected %+v, got %+v", i, want[i], scene)
+		}
+	}
+
+	// A cut after the last compared frame starts no scene
+	data = encodeGIF(t, 5, 0, 1, 0, 1)
+	result, err = Compare(data, data, WithSceneCuts(20), WithSampling(Sampling{Every: 2}))
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if len(result.Scenes) != 2 || result.Scenes[1].End != 2 {
+		t.Errorf("Expected 2 scenes, got %+v", result.Scenes)
+	}
+}
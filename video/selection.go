@@ -0,0 +1,48 @@
+package video
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// errDone stops pairing once no later frame can be selected.
+var errDone = errors.New("no more frames selected")
+
+// selection restricts a comparison to a time range and a list of frames of
+// the first sequence, set by WithTimeRange and WithFrameList. Sequences are
+// read from the start, so unselected frames are decoded and then skipped.
+type selection struct {
+	from, to time.Duration
+	// frames is sorted; nil selects every frame.
+	frames []int
+}
+
+// validate reports an invalid range or frame index.
+func (s *selection) validate() error {
+	if s.from < 0 || s.to < 0 || (s.to > 0 && s.to <= s.from) {
+		return fmt.Errorf("invalid time range: %v to %v", s.from, s.to)
+	}
+	if len(s.frames) > 0 && s.frames[0] < 0 {
+		return fmt.Errorf("invalid frame index: %d", s.frames[0])
+	}
+	return nil
+}
+
+// selects reports whether the frame at index with timestamp is compared,
+// or returns errDone when it and every later frame lie beyond the
+// selection.
+func (s *selection) selects(index int, timestamp time.Duration) (bool, error) {
+	if (s.to > 0 && timestamp >= s.to) || (s.frames != nil && index > s.frames[len(s.frames)-1]) {
+		return false, errDone
+	}
+	if timestamp < s.from {
+		return false, nil
+	}
+	if s.frames != nil {
+		_, found := slices.BinarySearch(s.frames, index)
+		return found, nil
+	}
+	return true, nil
+}
@@ -0,0 +1,88 @@
+package video
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+// countingSequence counts the frames decoded from a sequence.
+type countingSequence struct {
+	Sequence
+	decoded int
+}
+
+func (s *countingSequence) Next() (*Frame, error) {
+	frame, err := s.Sequence.Next()
+	if err != io.EOF {
+		s.decoded++
+	}
+	return frame, err
+}
+
+func TestCompareWithTimeRange(t *testing.T) {
+	data1 := encodeGIF(t, 10, 0, 0, 0, 0, 0, 0)
+	data2 := encodeGIF(t, 10, 0, 1, 2, 1, 0, 1)
+	seq1, err := Open(data1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seq2, err := Open(data2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counting := &countingSequence{Sequence: seq1}
+
+	// Frames are 100ms apart
+	result, err := CompareSequences(context.Background(), counting, seq2, WithTimeRange(100*time.Millisecond, 300*time.Millisecond))
+	if err != nil {
+		t.Fatalf("CompareSequences failed: %v", err)
+	}
+	if len(result.Frames) != 2 || result.Frames[0].Index != 1 || result.Frames[1].Index != 2 {
+		t.Errorf("Expected frames 1 and 2, got %+v", result.Frames)
+	}
+	if counting.decoded != 4 {
+		t.Errorf("Expected decoding to stop after frame 3, decoded %d frames", counting.decoded)
+	}
+
+	open, err := Compare(data1, data2, WithTimeRange(400*time.Millisecond, 0), WithPairing(PairNearest))
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if len(open.Frames) != 2 || open.Frames[0].Index != 4 {
+		t.Errorf("Expected the last two frames, got %+v", open.Frames)
+	}
+
+	for _, r := range [][2]time.Duration{{-1, 0}, {time.Second, time.Second}} {
+		if _, err := Compare(data1, data2, WithTimeRange(r[0], r[1])); err == nil {
+			t.Errorf("Expected error for range %v", r)
+		}
+	}
+}
+
+func TestCompareWithFrameList(t *testing.T) {
+	data1 := encodeGIF(t, 10, 0, 0, 0, 0, 0, 0)
+	data2 := encodeGIF(t, 10, 0, 1, 2, 1, 0, 1)
+
+	result, err := Compare(data1, data2, WithFrameList(3, 1), WithFrameList(3))
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if len(result.Frames) != 2 || result.Frames[0].Index != 1 || result.Frames[1].Index != 3 {
+		t.Errorf("Expected frames 1 and 3, got %+v", result.Frames)
+	}
+
+	// Both selections must hold
+	result, err = Compare(data1, data2, WithFrameList(1, 3, 5), WithTimeRange(200*time.Millisecond, 0))
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if len(result.Frames) != 2 || result.Frames[0].Index != 3 || result.Frames[1].Index != 5 {
+		t.Errorf("Expected frames 3 and 5, got %+v", result.Frames)
+	}
+
+	if _, err := Compare(data1, data2, WithFrameList(-1)); err == nil {
+		t.Error("Expected error for a negative index")
+	}
+}
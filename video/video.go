@@ -0,0 +1,336 @@
+// Package video compares animations and image sequences frame by frame with
+// the psnr package.
+//
+//	result, err := video.CompareFiles("original.gif", "optimized.gif")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	fmt.Printf("mean PSNR: %.2f dB over %d frames\n", result.Summary.Mean, result.Summary.Count)
+//
+// Animated GIFs are decoded natively and still images are treated as
+// one-frame sequences. MP4, WebM and Matroska videos are decoded with libav
+// in builds with the libav tag, and AVIF image sequences with libavif in
+// builds with the libavif tag. Other containers need a decoder registered
+// with RegisterFormat; HEIF image sequences are recognized by their brands
+// and rejected with ErrUnsupportedFormat unless one is registered.
+package video
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ideamans/go-psnr"
+)
+
+// ErrFrameCountMismatch is returned when two sequences have a different
+// number of frames.
+var ErrFrameCountMismatch = errors.New("frame counts differ")
+
+// Frame is a decoded frame of a sequence.
+type Frame struct {
+	// Image is the fully composited frame as it is displayed.
+	Image image.Image
+	// Timestamp is the presentation time of the frame from the start of the
+	// sequence.
+	Timestamp time.Duration
+	// Duration is how long the frame is displayed.
+	Duration time.Duration
+}
+
+// Sequence yields the frames of an animation in presentation order. Next
+// returns io.EOF after the last frame. Sequences holding decoder resources,
+// such as the libav decoder, also implement io.Closer; Compare closes them.
+type Sequence interface {
+	Next() (*Frame, error)
+}
+
+// FrameResult is the comparison of one pair of frames.
+type FrameResult struct {
+	// Index is the position of the frame in the first sequence.
+	Index int
+	// Timestamp is the presentation time of the frame in the first sequence.
+	Timestamp time.Duration
+	// MatchedIndex and MatchedTimestamp locate the frame of the second
+	// sequence it was compared with; with PairByIndex they equal Index and
+	// the second frame's timestamp.
+	MatchedIndex     int
+	MatchedTimestamp time.Duration
+	// PSNR and MSE are measured as by psnr.CompareImages.
+	PSNR float64
+	MSE  float64
+	// Flicker is the temporal MSE measured by WithFlicker between the
+	// changes from the previously decoded frame in both sequences, zero for
+	// the first frame.
+	Flicker float64
+}
+
+// Result is the comparison of two sequences.
+type Result struct {
+	// Frames holds one result per frame pair in presentation order.
+	Frames []FrameResult
+	// Summary aggregates the per-frame results with psnr.Aggregator.
+	Summary psnr.Summary
+	// Flicker summarizes the temporal error when WithFlicker was used with
+	// at least two frames, and is nil otherwise.
+	Flicker *FlickerResult
+	// Scenes holds one summary per scene when WithSceneCuts was used, and
+	// is nil otherwise.
+	Scenes []Scene
+	// WorstFrames lists the frame pairs written by WithWorstFrames, from the
+	// lowest PSNR.
+	WorstFrames []WorstFrame
+	// HashMatches is the number of frame pairs WithFrameHashing found
+	// identical without comparing them.
+	HashMatches int
+	// Sampling is the scheme set by WithSampling, or the zero Sampling when
+	// every frame was compared.
+	Sampling Sampling
+}
+
+// CompareFiles reads and compares two animation files.
+func CompareFiles(path1, path2 string, opts ...Option) (*Result, error) {
+	data1, err := os.ReadFile(path1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path1, err)
+	}
+	data2, err := os.ReadFile(path2)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path2, err)
+	}
+	return Compare(data1, data2, opts...)
+}
+
+// Compare decodes two encoded animations and compares them frame by frame.
+func Compare(data1, data2 []byte, opts ...Option) (*Result, error) {
+	seq1, err := Open(data1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open first sequence: %w", err)
+	}
+	defer closeSequence(seq1)
+	seq2, err := Open(data2)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open second sequence: %w", err)
+	}
+	defer closeSequence(seq2)
+	return CompareSequences(context.Background(), seq1, seq2, opts...)
+}
+
+// closeSequence releases the resources of a sequence that holds any.
+func closeSequence(seq Sequence) {
+	if c, ok := seq.(io.Closer); ok {
+		c.Close()
+	}
+}
+
+// CompareSequences compares two sequences frame by frame. Frames are paired
+// as selected by WithPairing: by position by default, in which case it
+// returns ErrFrameCountMismatch when one sequence ends before the other, or
+// by timestamp, in which case every frame of the first sequence is compared
+// and the second sequence's remaining frames are ignored.
+func CompareSequences(ctx context.Context, seq1, seq2 Sequence, opts ...Option) (*Result, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return compareSequences(ctx, seq1, seq2, o, nil)
+}
+
+// Stream is a comparison started by CompareSequencesStream.
+type Stream struct {
+	// Frames delivers each frame result as soon as it is measured, in
+	// presentation order, and is closed when the comparison ends.
+	Frames <-chan FrameResult
+
+	done   chan struct{}
+	result *Result
+	err    error
+}
+
+// Wait discards the frames not yet received from Frames, waits for the
+// comparison to end and returns its result as CompareSequences would.
+func (s *Stream) Wait() (*Result, error) {
+	for range s.Frames {
+	}
+	<-s.done
+	return s.result, s.err
+}
+
+// CompareSequencesStream compares two sequences like CompareSequences in a
+// new goroutine, delivering each frame result on Stream.Frames while the
+// rest are still being decoded and compared, so a UI can show progress and
+// a service can act on an early bad frame. Frames must be drained, or Wait
+// called, for the comparison to finish; canceling ctx stops it early.
+func CompareSequencesStream(ctx context.Context, seq1, seq2 Sequence, opts ...Option) *Stream {
+	frames := make(chan FrameResult)
+	s := &Stream{Frames: frames, done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		defer close(frames)
+		o, err := newOptions(opts)
+		if err != nil {
+			s.err = err
+			return
+		}
+		s.result, s.err = compareSequences(ctx, seq1, seq2, o, func(frame FrameResult) error {
+			select {
+			case frames <- frame:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+	return s
+}
+
+// compareSequences implements CompareSequences, passing every frame result
+// to emit when it is not nil.
+func compareSequences(ctx context.Context, seq1, seq2 Sequence, o *options, emit func(FrameResult) error) (*Result, error) {
+	var (
+		err        error
+		result     Result
+		aggregator psnr.Aggregator
+		flicker    flickerMeter
+		scenes     = sceneDetector{threshold: o.sceneThreshold}
+		worst      = worstFrames{k: o.worstCount, dir: o.worstDir}
+		sampled    = sampler{Sampling: o.sampling}
+	)
+	result.Sampling = o.sampling
+	compareFrames := func(index, matchedIndex int, frame1, frame2 *Frame) error {
+		selected, err := o.selection.selects(index, frame1.Timestamp)
+		if err != nil {
+			return err
+		}
+		if o.sceneThreshold > 0 {
+			scenes.observe(len(result.Frames), frame1)
+		}
+		if !selected || !sampled.sample(index, frame1.Timestamp) {
+			if o.flicker {
+				flicker.skip(frame1.Image, frame2.Image)
+			}
+			return nil
+		}
+		var r *psnr.Result
+		if o.frameHashing && identicalFrames(frame1.Image, frame2.Image) {
+			r = identicalFrame()
+			result.HashMatches++
+		} else {
+			r, err = psnr.CompareImages(ctx, frame1.Image, frame2.Image, o.frameOptions...)
+			if err != nil {
+				return fmt.Errorf("frame %d: %w", index, err)
+			}
+		}
+		aggregator.Add(r)
+		frame := FrameResult{
+			Index:            index,
+			Timestamp:        frame1.Timestamp,
+			MatchedIndex:     matchedIndex,
+			MatchedTimestamp: frame2.Timestamp,
+			PSNR:             r.PSNR,
+			MSE:              r.MSE,
+		}
+		if o.flicker {
+			frame.Flicker = flicker.add(frame1.Image, frame2.Image)
+		}
+		if o.sceneThreshold > 0 {
+			scenes.add(r)
+		}
+		if o.worstCount > 0 {
+			worst.add(len(result.Frames), r.PSNR, frame1.Image, frame2.Image)
+		}
+		result.Frames = append(result.Frames, frame)
+		if emit != nil {
+			return emit(frame)
+		}
+		return nil
+	}
+	if o.pairing == PairByIndex {
+		err = pairByIndex(ctx, seq1, seq2, compareFrames)
+	} else {
+		err = pairByTimestamp(ctx, seq1, seq2, o.pairing, compareFrames)
+	}
+	if err != nil && err != errDone {
+		return nil, err
+	}
+	result.Summary = aggregator.Summary()
+	result.Flicker = flicker.result()
+	if o.sceneThreshold > 0 {
+		result.Scenes = scenes.result(len(result.Frames))
+	}
+	if o.worstCount > 0 {
+		if result.WorstFrames, err = worst.write(); err != nil {
+			return nil, fmt.Errorf("failed to write worst frames: %w", err)
+		}
+	}
+	return &result, nil
+}
+
+// pairFunc is called with every pair of frames to compare and the indices
+// of both frames.
+type pairFunc func(index, matchedIndex int, frame1, frame2 *Frame) error
+
+// pairByIndex calls fn with frames at the same position in both sequences.
+func pairByIndex(ctx context.Context, seq1, seq2 Sequence, fn pairFunc) error {
+	for index := 0; ; index++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		frame1, err1 := seq1.Next()
+		frame2, err2 := seq2.Next()
+		if err1 == io.EOF && err2 == io.EOF {
+			return nil
+		}
+		if err1 != nil && err1 != io.EOF {
+			return fmt.Errorf("failed to decode frame %d of first sequence: %w", index, err1)
+		}
+		if err2 != nil && err2 != io.EOF {
+			return fmt.Errorf("failed to decode frame %d of second sequence: %w", index, err2)
+		}
+		if err1 == io.EOF || err2 == io.EOF {
+			return fmt.Errorf("%w: one sequence ends after %d frames", ErrFrameCountMismatch, index)
+		}
+		if err := fn(index, index, frame1, frame2); err != nil {
+			return err
+		}
+	}
+}
+
+// pairByTimestamp calls fn with every frame of the first sequence and the
+// frame of the second that pairing matches with its timestamp.
+func pairByTimestamp(ctx context.Context, seq1, seq2 Sequence, pairing Pairing, fn pairFunc) error {
+	var line *timeline
+	for index := 0; ; index++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		frame1, err := seq1.Next()
+		if err == io.EOF {
+			if index == 0 {
+				return errors.New("first sequence has no frames")
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to decode frame %d of first sequence: %w", index, err)
+		}
+		if line == nil {
+			if line, err = newTimeline(seq2); err == io.EOF {
+				return errors.New("second sequence has no frames")
+			} else if err != nil {
+				return fmt.Errorf("failed to decode frame 0 of second sequence: %w", err)
+			}
+		}
+		frame2, matchedIndex, err := line.match(frame1.Timestamp, pairing)
+		if err != nil {
+			return fmt.Errorf("failed to decode frame %d of second sequence: %w", line.index+1, err)
+		}
+		if err := fn(index, matchedIndex, frame1, frame2); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package video
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"math"
+	"testing"
+	"time"
+)
+
+// testPalette has enough grays for the test animations.
+var testPalette = color.Palette{color.Black, color.White, color.Gray{Y: 128}, color.Transparent}
+
+// encodeGIF encodes frames filled with the given palette indices, each
+// displayed for delay hundredths of a second.
+func encodeGIF(t *testing.T, delay int, fills ...uint8) []byte {
+	t.Helper()
+	g := &gif.GIF{}
+	for _, fill := range fills {
+		frame := image.NewPaletted(image.Rect(0, 0, 8, 8), testPalette)
+		for i := range frame.Pix {
+			frame.Pix[i] = fill
+		}
+		g.Image = append(g.Image, frame)
+		g.Delay = append(g.Delay, delay)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatalf("Failed to encode GIF: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestCompare(t *testing.T) {
+	data1 := encodeGIF(t, 5, 0, 1, 2)
+	data2 := encodeGIF(t, 5, 0, 1, 1)
+
+	result, err := Compare(data1, data2)
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if len(result.Frames) != 3 {
+		t.Fatalf("Expected 3 frames, got %d", len(result.Frames))
+	}
+	for i, frame := range result.Frames {
+		if frame.Index != i || frame.Timestamp != time.Duration(i)*50*time.Millisecond {
+			t.Errorf("Unexpected frame %d: %+v", i, frame)
+		}
+	}
+	if !math.IsInf(result.Frames[0].PSNR, 1) || !math.IsInf(result.Frames[1].PSNR, 1) {
+		t.Errorf("Expected identical first frames, got %+v", result.Frames)
+	}
+	if math.IsInf(result.Frames[2].PSNR, 1) || result.Frames[2].MSE == 0 {
+		t.Errorf("Expected a difference in the last frame, got %+v", result.Frames[2])
+	}
+	if result.Summary.Count != 3 || result.Summary.InfCount != 2 || result.Summary.Min != result.Frames[2].PSNR {
+		t.Errorf("Unexpected summary: %+v", result.Summary)
+	}
+}
+
+func TestCompareFrameCountMismatch(t *testing.T) {
+	_, err := Compare(encodeGIF(t, 5, 0, 1), encodeGIF(t, 5, 0))
+	if !errors.Is(err, ErrFrameCountMismatch) {
+		t.Errorf("Expected ErrFrameCountMismatch, got %v", err)
+	}
+}
+
+func TestCompareStillImages(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 8, 8))
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	result, err := Compare(buf.Bytes(), encodeGIF(t, 5, 0))
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if len(result.Frames) != 1 || !math.IsInf(result.Frames[0].PSNR, 1) {
+		t.Errorf("Expected one identical frame, got %+v", result.Frames)
+	}
+}
+
+func TestCompareSequencesCanceled(t *testing.T) {
+	seq1, err := Open(encodeGIF(t, 5, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seq2, err := Open(encodeGIF(t, 5, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := CompareSequences(ctx, seq1, seq2); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestCompareSequencesStream(t *testing.T) {
+	open := func(data []byte) Sequence {
+		seq, err := Open(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return seq
+	}
+
+	stream := CompareSequencesStream(context.Background(), open(encodeGIF(t, 5, 0, 1, 2)), open(encodeGIF(t, 5, 0, 1, 1)))
+	var frames []FrameResult
+	for frame := range stream.Frames {
+		frames = append(frames, frame)
+	}
+	result, err := stream.Wait()
+	if err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if len(frames) != 3 || len(result.Frames) != 3 {
+		t.Fatalf("Expected 3 streamed frames, got %d of %d", len(frames), len(result.Frames))
+	}
+	for i := range frames {
+		if frames[i] != result.Frames[i] {
+			t.Errorf("Streamed frame %d differs: %+v vs %+v", i, frames[i], result.Frames[i])
+		}
+	}
+
+	// Frames compared before the failure are still delivered
+	stream = CompareSequencesStream(context.Background(), open(encodeGIF(t, 5, 0, 1)), open(encodeGIF(t, 5, 0)))
+	if frame, ok := <-stream.Frames; !ok || frame.Index != 0 {
+		t.Errorf("Expected frame 0 before the error, got %+v", frame)
+	}
+	if _, err := stream.Wait(); !errors.Is(err, ErrFrameCountMismatch) {
+		t.Errorf("Expected ErrFrameCountMismatch, got %v", err)
+	}
+
+	stream = CompareSequencesStream(context.Background(), open(encodeGIF(t, 5, 0)), open(encodeGIF(t, 5, 0)), WithSampling(Sampling{Every: -1}))
+	if _, err := stream.Wait(); err == nil {
+		t.Error("Expected an options error")
+	}
+}
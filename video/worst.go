@@ -0,0 +1,100 @@
+package video
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/ideamans/go-psnr"
+)
+
+// WorstFrame locates the images WithWorstFrames wrote for one frame pair.
+type WorstFrame struct {
+	// Index is the position of the frame in Result.Frames.
+	Index int
+	PSNR  float64
+	// First, Second and Heatmap are the paths of the PNG files holding the
+	// frame of each sequence and their psnr.Heatmap.
+	First, Second, Heatmap string
+}
+
+// worstFrames keeps the k frame pairs with the lowest PSNR.
+type worstFrames struct {
+	k       int
+	dir     string
+	entries []worstEntry
+}
+
+// worstEntry is a kept frame pair.
+type worstEntry struct {
+	index          int
+	psnr           float64
+	image1, image2 image.Image
+}
+
+// add considers a compared pair. Identical pairs have nothing to inspect.
+func (w *worstFrames) add(index int, psnr float64, img1, img2 image.Image) {
+	if math.IsInf(psnr, 1) {
+		return
+	}
+	// Entries are ordered from the lowest PSNR; ties keep the earlier frame
+	i := sort.Search(len(w.entries), func(i int) bool { return w.entries[i].psnr > psnr })
+	if i >= w.k {
+		return
+	}
+	w.entries = append(w.entries, worstEntry{})
+	copy(w.entries[i+1:], w.entries[i:])
+	w.entries[i] = worstEntry{index: index, psnr: psnr, image1: img1, image2: img2}
+	if len(w.entries) > w.k {
+		w.entries = w.entries[:w.k]
+	}
+}
+
+// write saves the kept pairs and their heatmaps, from the lowest PSNR.
+func (w *worstFrames) write() ([]WorstFrame, error) {
+	if err := os.MkdirAll(w.dir, 0o755); err != nil {
+		return nil, err
+	}
+	frames := make([]WorstFrame, 0, len(w.entries))
+	for _, e := range w.entries {
+		base := filepath.Join(w.dir, fmt.Sprintf("frame-%05d", e.index))
+		frame := WorstFrame{
+			Index:   e.index,
+			PSNR:    e.psnr,
+			First:   base + ".first.png",
+			Second:  base + ".second.png",
+			Heatmap: base + ".diff.png",
+		}
+		for _, file := range []struct {
+			path string
+			img  image.Image
+		}{
+			{frame.First, e.image1},
+			{frame.Second, e.image2},
+			{frame.Heatmap, psnr.Heatmap(e.image1, e.image2)},
+		} {
+			if err := writePNG(file.path, file.img); err != nil {
+				return nil, err
+			}
+		}
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
+
+// writePNG encodes img to a new file at path.
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
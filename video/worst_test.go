@@ -0,0 +1,58 @@
+package video
+
+import (
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompareWithWorstFrames(t *testing.T) {
+	// An identical frame, two gray frames and a white one against black
+	data1 := encodeGIF(t, 5, 0, 0, 0, 0)
+	data2 := encodeGIF(t, 5, 0, 2, 1, 2)
+	dir := filepath.Join(t.TempDir(), "worst")
+
+	result, err := Compare(data1, data2, WithWorstFrames(2, dir))
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if len(result.WorstFrames) != 2 {
+		t.Fatalf("Expected 2 worst frames, got %+v", result.WorstFrames)
+	}
+	// The white frame first, then the earlier of the tied gray frames
+	for i, index := range []int{2, 1} {
+		frame := result.WorstFrames[i]
+		if frame.Index != index || frame.PSNR != result.Frames[index].PSNR {
+			t.Errorf("Worst frame %d: expected frame %d, got %+v", i, index, frame)
+		}
+		for _, path := range []string{frame.First, frame.Second, frame.Heatmap} {
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatalf("Expected a written file: %v", err)
+			}
+			img, err := png.Decode(f)
+			f.Close()
+			if err != nil {
+				t.Fatalf("Failed to decode %s: %v", path, err)
+			}
+			if img.Bounds().Dx() != 8 || img.Bounds().Dy() != 8 {
+				t.Errorf("Unexpected bounds of %s: %v", path, img.Bounds())
+			}
+		}
+	}
+	if filepath.Base(result.WorstFrames[0].Heatmap) != "frame-00002.diff.png" {
+		t.Errorf("Unexpected heatmap name: %s", result.WorstFrames[0].Heatmap)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 6 {
+		t.Errorf("Expected 6 files, got %d", len(entries))
+	}
+
+	if _, err := Compare(data1, data2, WithWorstFrames(1, "")); err == nil {
+		t.Error("Expected error without a directory")
+	}
+}
@@ -0,0 +1,44 @@
+package psnr
+
+import "fmt"
+
+// WarningCode identifies an approximation that changed what a result
+// measures.
+type WarningCode string
+
+const (
+	// WarningPartialDecode: WithTolerantDecode recovered a truncated image
+	// and only its decoded rows were compared (Result.Coverage).
+	WarningPartialDecode WarningCode = "partial-decode"
+	// WarningAlphaSampled: alpha detection sampled a grid of pixels and
+	// found them opaque, so alpha was left out of the comparison; a
+	// translucent pixel off the grid would have been missed.
+	WarningAlphaSampled WarningCode = "alpha-sampled"
+	// WarningResampled: WithEvaluationSize compared downscaled images
+	// (Result.EvaluationSize).
+	WarningResampled WarningCode = "resampled"
+	// WarningCropped: WithCropSearch or WithBorderCrop compared only part of
+	// one or both images (Result.Offset, Result.Borders).
+	WarningCropped WarningCode = "cropped"
+	// WarningReoriented: WithOrientationSearch rotated or mirrored the
+	// second image (Result.Orientation).
+	WarningReoriented WarningCode = "reoriented"
+)
+
+// Warning reports an approximation applied while computing a result, so
+// consumers can tell such results from exact full-image comparisons.
+type Warning struct {
+	Code WarningCode
+	// Message describes the approximation for people.
+	Message string
+}
+
+// String returns the code and message.
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: %s", w.Code, w.Message)
+}
+
+// newWarning returns a warning with a formatted message.
+func newWarning(code WarningCode, format string, args ...any) Warning {
+	return Warning{Code: code, Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,98 @@
+package psnr
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"os"
+	"testing"
+)
+
+// warningCodes returns the codes of the warnings of a result.
+func warningCodes(r *Result) []WarningCode {
+	var codes []WarningCode
+	for _, w := range r.Warnings {
+		codes = append(codes, w.Code)
+	}
+	return codes
+}
+
+func TestWarnings(t *testing.T) {
+	ctx := context.Background()
+	jpeg1, err := os.ReadFile("testdata/test_original.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	jpeg2, err := os.ReadFile("testdata/quality_50.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ComputeDetailed(jpeg1, jpeg2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Warnings != nil {
+		t.Errorf("Expected no warnings for a plain JPEG comparison, got %v", result.Warnings)
+	}
+
+	result, err = ComputeDetailed(jpeg1, jpeg2, WithEvaluationSize(320))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if codes := warningCodes(result); len(codes) != 1 || codes[0] != WarningResampled {
+		t.Errorf("Expected a resampled warning, got %v", result.Warnings)
+	}
+	if result, _ := ComputeDetailed(jpeg1, jpeg2, WithEvaluationSize(1024)); result.Warnings != nil {
+		t.Errorf("Expected no warning when nothing was resampled, got %v", result.Warnings)
+	}
+
+	// Opaque images that may carry alpha are only sampled
+	img := orientationTestImage(40, 24)
+	result, err = CompareImages(ctx, img, NoisyImage(img, 4, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if codes := warningCodes(result); len(codes) != 1 || codes[0] != WarningAlphaSampled {
+		t.Errorf("Expected an alpha-sampled warning, got %v", result.Warnings)
+	}
+	translucent := SolidImage(img.Rect, color.NRGBA{255, 0, 0, 128})
+	if result, _ := CompareImages(ctx, translucent, translucent); result.Warnings != nil {
+		t.Errorf("Expected no warning once alpha was found, got %v", result.Warnings)
+	}
+
+	result, err = CompareImages(ctx, img, orient(img, OrientationRotate90), WithOrientationSearch())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if codes := warningCodes(result); len(codes) != 2 || codes[0] != WarningReoriented || codes[1] != WarningAlphaSampled {
+		t.Errorf("Expected reoriented and alpha-sampled warnings in pipeline order, got %v", result.Warnings)
+	}
+
+	boxedImg := boxed(img, Margins{Top: 4, Bottom: 4}, color.Black)
+	result, err = CompareImages(ctx, boxedImg, boxedImg, WithBorderCrop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if codes := warningCodes(result); len(codes) == 0 || codes[0] != WarningCropped {
+		t.Errorf("Expected a cropped warning, got %v", result.Warnings)
+	}
+
+	crop := toRGBA(img.SubImage(image.Rect(5, 5, 30, 20)))
+	result, err = CompareImages(ctx, img, crop, WithCropSearch())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if codes := warningCodes(result); len(codes) == 0 || codes[0] != WarningCropped {
+		t.Errorf("Expected a cropped warning, got %v", result.Warnings)
+	}
+
+	truncated := jpeg1[:len(jpeg1)*6/10]
+	result, err = ComputeDetailed(jpeg1, truncated, WithTolerantDecode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if codes := warningCodes(result); len(codes) != 1 || codes[0] != WarningPartialDecode {
+		t.Errorf("Expected a partial-decode warning, got %v", result.Warnings)
+	}
+}
@@ -0,0 +1,37 @@
+package psnr
+
+import (
+	"errors"
+	"image"
+)
+
+// ErrWebPUnsupported is returned by WebPEncoder and SearchWebPQuality when no
+// WebP encoder was built in.
+var ErrWebPUnsupported = errors.New("WebP encoding requires building with -tags libwebp")
+
+// encodeWebP encodes img as a lossy WebP at a quality from 0 to 100 with
+// the encoder compiled in, and is nil when there is none.
+var encodeWebP func(img image.Image, quality int) ([]byte, error)
+
+// WebPEncoder encodes lossy WebP with libwebp, using the parameter as
+// quality (0-100). It requires libwebp and the libwebp build tag, which
+// also registers a WebP decoder with the image package; without it, it
+// returns ErrWebPUnsupported.
+var WebPEncoder Encoder = EncoderFunc(func(img image.Image, quality int) ([]byte, error) {
+	if encodeWebP == nil {
+		return nil, ErrWebPUnsupported
+	}
+	return encodeWebP(img, quality)
+})
+
+// SearchWebPQuality binary-searches the WebP quality for the lowest one whose
+// output still has at least targetPSNR against original, and returns it with
+// the encoded bytes, the achieved PSNR and the values of any metrics
+// requested with WithMetrics. opts configure each PSNR evaluation. It
+// returns ErrWebPUnsupported unless built with the libwebp tag.
+func SearchWebPQuality(original []byte, targetPSNR float64, opts ...Option) (*QualityResult, error) {
+	if encodeWebP == nil {
+		return nil, ErrWebPUnsupported
+	}
+	return SearchQuality(original, WebPEncoder, targetPSNR, SearchOptions{Options: opts})
+}
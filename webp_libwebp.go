@@ -0,0 +1,84 @@
+//go:build libwebp && cgo
+
+package psnr
+
+/*
+#cgo LDFLAGS: -lwebp
+#include <stdlib.h>
+#include <webp/decode.h>
+#include <webp/encode.h>
+*/
+import "C"
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"unsafe"
+)
+
+func init() {
+	encodeWebP = encodeLibwebp
+	image.RegisterFormat("webp", "RIFF????WEBPVP8", decodeLibwebp, decodeLibwebpConfig)
+}
+
+// encodeLibwebp encodes img as a lossy WebP with libwebp's simple encoding
+// API, which keeps the alpha channel.
+func encodeLibwebp(img image.Image, quality int) ([]byte, error) {
+	if quality < 0 || quality > 100 {
+		return nil, errors.New("libwebp: quality must be between 0 and 100")
+	}
+	nrgba := toNRGBA(img)
+	width, height := nrgba.Rect.Dx(), nrgba.Rect.Dy()
+	if width == 0 || height == 0 {
+		return nil, errors.New("libwebp: empty image")
+	}
+
+	pixels := C.CBytes(nrgba.Pix)
+	defer C.free(pixels)
+	var output *C.uint8_t
+	size := C.WebPEncodeRGBA((*C.uint8_t)(pixels), C.int(width), C.int(height), C.int(nrgba.Stride), C.float(quality), &output)
+	if size == 0 || output == nil {
+		return nil, errors.New("libwebp: encoding failed")
+	}
+	defer C.WebPFree(unsafe.Pointer(output))
+	return C.GoBytes(unsafe.Pointer(output), C.int(size)), nil
+}
+
+// decodeLibwebp decodes a WebP image to straight RGBA.
+func decodeLibwebp(r io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	input := C.CBytes(data)
+	defer C.free(input)
+
+	var width, height C.int
+	pixels := C.WebPDecodeRGBA((*C.uint8_t)(input), C.size_t(len(data)), &width, &height)
+	if pixels == nil {
+		return nil, errors.New("libwebp: invalid WebP data")
+	}
+	defer C.WebPFree(unsafe.Pointer(pixels))
+
+	img := image.NewNRGBA(image.Rect(0, 0, int(width), int(height)))
+	copy(img.Pix, unsafe.Slice((*uint8)(unsafe.Pointer(pixels)), len(img.Pix)))
+	return img, nil
+}
+
+// decodeLibwebpConfig reads the dimensions of a WebP image.
+func decodeLibwebpConfig(r io.Reader) (image.Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	input := C.CBytes(data)
+	defer C.free(input)
+
+	var width, height C.int
+	if C.WebPGetInfo((*C.uint8_t)(input), C.size_t(len(data)), &width, &height) == 0 {
+		return image.Config{}, errors.New("libwebp: invalid WebP data")
+	}
+	return image.Config{ColorModel: color.NRGBAModel, Width: int(width), Height: int(height)}, nil
+}
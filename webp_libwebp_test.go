@@ -0,0 +1,52 @@
+//go:build libwebp && cgo
+
+package psnr
+
+import (
+	"bytes"
+	"image"
+	"os"
+	"testing"
+)
+
+func TestLibwebpRoundTrip(t *testing.T) {
+	original, err := os.ReadFile("testdata/test_image.png")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+	img := decodeTestImage(t, original)
+
+	data, err := WebPEncoder.Encode(img, 90)
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	decoded, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil || format != "webp" {
+		t.Fatalf("Failed to decode WebP: %q, %v", format, err)
+	}
+	if decoded.Bounds().Size() != img.Bounds().Size() {
+		t.Errorf("Expected size %v, got %v", img.Bounds().Size(), decoded.Bounds().Size())
+	}
+	if psnr, err := Compute(original, data); err != nil || psnr < 30 {
+		t.Errorf("Expected a faithful encode at quality 90, got %.2f dB, %v", psnr, err)
+	}
+}
+
+func TestSearchWebPQuality(t *testing.T) {
+	original, err := os.ReadFile("testdata/test_image.png")
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+
+	result, err := SearchWebPQuality(original, 36)
+	if err != nil {
+		t.Fatalf("Error searching quality: %v", err)
+	}
+	t.Logf("quality %d: %.4f dB, %d bytes", result.Quality, result.PSNR, len(result.Data))
+	if result.PSNR < 36 {
+		t.Errorf("Expected at least 36 dB, got %.4f", result.PSNR)
+	}
+	if got, err := Compute(original, result.Data); err != nil || got != result.PSNR {
+		t.Errorf("Expected the returned bytes to measure %.6f, got %.6f, %v", result.PSNR, got, err)
+	}
+}
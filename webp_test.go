@@ -0,0 +1,24 @@
+package psnr
+
+import (
+	"errors"
+	"image"
+	"os"
+	"testing"
+)
+
+func TestWebPUnsupported(t *testing.T) {
+	if encodeWebP != nil {
+		t.Skip("built with a WebP encoder")
+	}
+	original, err := os.ReadFile("testdata/test_image.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SearchWebPQuality(original, 38); !errors.Is(err, ErrWebPUnsupported) {
+		t.Errorf("Expected ErrWebPUnsupported, got %v", err)
+	}
+	if _, err := WebPEncoder.Encode(image.NewRGBA(image.Rect(0, 0, 1, 1)), 80); !errors.Is(err, ErrWebPUnsupported) {
+		t.Errorf("Expected ErrWebPUnsupported from the encoder, got %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package psnr
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// edgeBaseline is the weight flat regions keep under WithEdgeWeighting, so
+// errors there still count, just less than errors on edges.
+const edgeBaseline = 0.05
+
+// buildWeights returns the per-pixel weights requested by the options for an
+// image of the given size, indexed by y*width+x, or nil when no weighting
+// was requested. The reference image drives edge weighting.
+func buildWeights(o *options, reference *image.RGBA) ([]float64, error) {
+	if !o.edgeWeighting && o.saliency == nil && !o.spherical && !hasRegionWeights(o.regions) {
+		return nil, nil
+	}
+
+	width := reference.Rect.Dx()
+	height := reference.Rect.Dy()
+	weights := make([]float64, width*height)
+	for i := range weights {
+		weights[i] = 1
+	}
+
+	if o.edgeWeighting {
+		for i, w := range sobelMagnitude(reference) {
+			weights[i] *= w
+		}
+	}
+
+	if o.saliency != nil {
+		bounds := o.saliency.Bounds()
+		if bounds.Dx() != width || bounds.Dy() != height {
+			return nil, fmt.Errorf("saliency map has different dimensions: %dx%d vs %dx%d",
+				bounds.Dx(), bounds.Dy(), width, height)
+		}
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				g := color.Gray16Model.Convert(o.saliency.At(x+bounds.Min.X, y+bounds.Min.Y)).(color.Gray16)
+				weights[y*width+x] *= float64(g.Y) / 0xffff
+			}
+		}
+	}
+
+	if o.spherical {
+		for y := 0; y < height; y++ {
+			w := sphericalWeight(y, height)
+			for x := 0; x < width; x++ {
+				weights[y*width+x] *= w
+			}
+		}
+	}
+
+	if len(o.regions) > 0 {
+		applyRegionWeights(weights, width, height, o.regions)
+	}
+
+	return weights, nil
+}
+
+// sphericalWeight returns the WS-PSNR weight of row y in an equirectangular
+// image of the given height: the cosine of the row's latitude, which is
+// proportional to the area the row covers on the sphere.
+func sphericalWeight(y, height int) float64 {
+	return math.Cos((float64(y) + 0.5 - float64(height)/2) * math.Pi / float64(height))
+}
+
+// sobelMagnitude computes the Sobel gradient magnitude of the luma of img,
+// scaled so the strongest edge weighs 1 and flat areas weigh edgeBaseline.
+func sobelMagnitude(img *image.RGBA) []float64 {
+	width := img.Rect.Dx()
+	height := img.Rect.Dy()
+
+	luma := lumaPlane(img)
+
+	// Clamp at the borders so edge pixels get a gradient too
+	at := func(x, y int) float64 {
+		x = min(max(x, 0), width-1)
+		y = min(max(y, 0), height-1)
+		return luma[y*width+x]
+	}
+
+	magnitude := make([]float64, width*height)
+	var maxMagnitude float64
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			gx := at(x+1, y-1) + 2*at(x+1, y) + at(x+1, y+1) - at(x-1, y-1) - 2*at(x-1, y) - at(x-1, y+1)
+			gy := at(x-1, y+1) + 2*at(x, y+1) + at(x+1, y+1) - at(x-1, y-1) - 2*at(x, y-1) - at(x+1, y-1)
+			m := math.Hypot(gx, gy)
+			magnitude[y*width+x] = m
+			maxMagnitude = max(maxMagnitude, m)
+		}
+	}
+
+	for i, m := range magnitude {
+		if maxMagnitude > 0 {
+			m /= maxMagnitude
+		}
+		magnitude[i] = edgeBaseline + (1-edgeBaseline)*m
+	}
+	return magnitude
+}
+
+// weightedMSE computes the weighted mean squared error between region r of
+// img1 and the same-sized region of img2 at origin2. Weights are indexed in
+// img1 coordinates with a row stride of img1's width. It fails when the
+// weights of the region sum to zero, such as under an all-black saliency
+// map, as there is then nothing to average.
+func weightedMSE(img1, img2 *image.RGBA, r image.Rectangle, origin2 image.Point, hasAlpha bool, weights []float64) (float64, error) {
+	channelCount := 3.0
+	if hasAlpha {
+		channelCount = 4
+	}
+
+	stride := img1.Rect.Dx()
+	var sumWeighted, sumWeights compensatedSum
+	for y := 0; y < r.Dy(); y++ {
+		i := img1.PixOffset(r.Min.X, r.Min.Y+y)
+		j := img2.PixOffset(origin2.X, origin2.Y+y)
+		row := weights[(r.Min.Y+y)*stride+r.Min.X:]
+		var rowWeighted, rowWeights float64
+		for x := 0; x < r.Dx(); x++ {
+			k := x * 4
+			diffR := int32(img1.Pix[i+k]) - int32(img2.Pix[j+k])
+			diffG := int32(img1.Pix[i+k+1]) - int32(img2.Pix[j+k+1])
+			diffB := int32(img1.Pix[i+k+2]) - int32(img2.Pix[j+k+2])
+			sum := diffR*diffR + diffG*diffG + diffB*diffB
+			if hasAlpha {
+				diffA := int32(img1.Pix[i+k+3]) - int32(img2.Pix[j+k+3])
+				sum += diffA * diffA
+			}
+			rowWeighted += row[x] * float64(sum)
+			rowWeights += row[x]
+		}
+		sumWeighted.add(rowWeighted)
+		sumWeights.add(rowWeights)
+	}
+
+	if sumWeights.value() == 0 {
+		return 0, fmt.Errorf("pixel weights sum to zero")
+	}
+	return sumWeighted.value() / (sumWeights.value() * channelCount), nil
+}
@@ -0,0 +1,148 @@
+package psnr
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// halfDetailImage returns an image that is flat on the left half and has a
+// checkerboard on the right half.
+func halfDetailImage(size int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			v := uint8(128)
+			if x >= size/2 && (x/2+y/2)%2 == 0 {
+				v = 230
+			}
+			img.SetRGBA(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+	return img
+}
+
+// perturb returns a copy of img with every other pixel in the given column
+// range brightened by delta.
+func perturb(img *image.RGBA, x0, x1 int, delta uint8) *image.RGBA {
+	out := image.NewRGBA(img.Rect)
+	copy(out.Pix, img.Pix)
+	for y := 0; y < img.Rect.Dy(); y += 2 {
+		for x := x0; x < x1; x++ {
+			c := out.RGBAAt(x, y)
+			out.SetRGBA(x, y, color.RGBA{c.R - delta, c.G - delta, c.B - delta, 255})
+		}
+	}
+	return out
+}
+
+func TestComputeDetailedEdgeWeighting(t *testing.T) {
+	const size = 64
+	reference := halfDetailImage(size)
+	flatError := perturb(reference, 0, size/2, 10)
+	edgeError := perturb(reference, size/2, size, 10)
+
+	data := encodePNG(t, reference)
+	flat, err := ComputeDetailed(data, encodePNG(t, flatError), WithEdgeWeighting())
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	edge, err := ComputeDetailed(data, encodePNG(t, edgeError), WithEdgeWeighting())
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+
+	t.Logf("flat error: PSNR = %.2f dB, weighted = %.2f dB", flat.PSNR, flat.WeightedPSNR)
+	t.Logf("edge error: PSNR = %.2f dB, weighted = %.2f dB", edge.PSNR, edge.WeightedPSNR)
+
+	if math.Abs(flat.PSNR-edge.PSNR) > 1e-9 {
+		t.Errorf("Expected equal raw PSNR, got %.4f and %.4f", flat.PSNR, edge.PSNR)
+	}
+	if flat.WeightedPSNR <= edge.WeightedPSNR {
+		t.Errorf("Expected errors on edges to weigh more: flat %.2f dB <= edge %.2f dB",
+			flat.WeightedPSNR, edge.WeightedPSNR)
+	}
+}
+
+func TestComputeDetailedSaliencyMap(t *testing.T) {
+	const size = 32
+	reference := shiftedPattern(size, size, 0, 0)
+	distorted := perturb(reference, size/2, size, 20)
+
+	saliency := image.NewGray(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size/2; x++ {
+			saliency.SetGray(x, y, color.Gray{Y: 255})
+		}
+	}
+
+	result, err := ComputeDetailed(encodePNG(t, reference), encodePNG(t, distorted), WithSaliencyMap(saliency))
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+
+	if math.IsInf(result.PSNR, 1) {
+		t.Error("Expected finite raw PSNR")
+	}
+	if !math.IsInf(result.WeightedPSNR, 1) {
+		t.Errorf("Expected Inf weighted PSNR when errors are outside the salient area, got %.2f", result.WeightedPSNR)
+	}
+
+	if _, err := ComputeDetailed(encodePNG(t, reference), encodePNG(t, distorted),
+		WithSaliencyMap(image.NewGray(image.Rect(0, 0, 8, 8)))); err == nil {
+		t.Error("Expected error for saliency map with different dimensions")
+	}
+	if _, err := ComputeDetailed(encodePNG(t, reference), encodePNG(t, distorted),
+		WithSaliencyMap(image.NewGray(image.Rect(0, 0, size, size)))); err == nil {
+		t.Error("Expected error for an all-black saliency map")
+	}
+}
+
+func TestComputeDetailedSphericalWeighting(t *testing.T) {
+	const width, height = 64, 32
+	reference := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := range reference.Pix {
+		reference.Pix[i] = 200
+	}
+
+	// Equal-sized errors near the pole and at the equator
+	pole := image.NewRGBA(reference.Rect)
+	copy(pole.Pix, reference.Pix)
+	equator := image.NewRGBA(reference.Rect)
+	copy(equator.Pix, reference.Pix)
+	for x := 0; x < width; x++ {
+		pole.SetRGBA(x, 0, color.RGBA{180, 180, 180, 255})
+		equator.SetRGBA(x, height/2, color.RGBA{180, 180, 180, 255})
+	}
+
+	data := encodePNG(t, reference)
+	atPole, err := ComputeDetailed(data, encodePNG(t, pole), WithSphericalWeighting())
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+	atEquator, err := ComputeDetailed(data, encodePNG(t, equator), WithSphericalWeighting())
+	if err != nil {
+		t.Fatalf("Error computing PSNR: %v", err)
+	}
+
+	t.Logf("pole: WS-PSNR = %.2f dB, equator: WS-PSNR = %.2f dB", atPole.WeightedPSNR, atEquator.WeightedPSNR)
+
+	if atPole.PSNR != atEquator.PSNR {
+		t.Errorf("Expected equal raw PSNR, got %.4f and %.4f", atPole.PSNR, atEquator.PSNR)
+	}
+	if atPole.WeightedPSNR <= atEquator.WeightedPSNR {
+		t.Errorf("Expected polar errors to weigh less: pole %.2f dB <= equator %.2f dB",
+			atPole.WeightedPSNR, atEquator.WeightedPSNR)
+	}
+}
+
+func TestSphericalWeight(t *testing.T) {
+	const height = 180
+	if w := sphericalWeight(height/2, height); math.Abs(w-1) > 1e-3 {
+		t.Errorf("Expected weight near 1 at the equator, got %f", w)
+	}
+	if w := sphericalWeight(0, height); w > 0.01 {
+		t.Errorf("Expected weight near 0 at the pole, got %f", w)
+	}
+}
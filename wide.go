@@ -0,0 +1,65 @@
+package psnr
+
+import (
+	"math/big"
+	"math/bits"
+)
+
+// maxWideRun is the most samples squaredDiffSum adds in one call. A squared
+// 16-bit difference is below 2^32, so the sum of this many fits in a uint64.
+const maxWideRun = 1<<31 - 1
+
+// integerSample is a sample type whose squared differences are summed
+// exactly.
+type integerSample interface {
+	uint8 | uint16
+}
+
+// squaredDiffSum returns the sum of the squared differences between row1 and
+// row2, which hold at most maxWideRun samples. Differences are taken in
+// int64: the square of a 16-bit difference reaches 65535², which overflows
+// int32.
+func squaredDiffSum[T integerSample](row1, row2 []T) uint64 {
+	var sum uint64
+	for x := range row1 {
+		d := int64(row1[x]) - int64(row2[x])
+		sum += uint64(d * d)
+	}
+	return sum
+}
+
+// wideSum is a 128-bit accumulator of squared differences, which cannot
+// overflow for any image that fits in memory.
+type wideSum struct {
+	hi, lo uint64
+}
+
+// add adds v to the sum.
+func (s *wideSum) add(v uint64) {
+	var carry uint64
+	s.lo, carry = bits.Add64(s.lo, v, 0)
+	s.hi += carry
+}
+
+// mean returns the sum divided by n, correctly rounded to float64.
+func (s wideSum) mean(n int) float64 {
+	if s.hi == 0 && s.lo < 1<<53 {
+		// Both operands are exact, so the division rounds once
+		return float64(s.lo) / float64(n)
+	}
+	sum := new(big.Int).Lsh(new(big.Int).SetUint64(s.hi), 64)
+	sum.Or(sum, new(big.Int).SetUint64(s.lo))
+	mean, _ := new(big.Float).SetPrec(53).Quo(new(big.Float).SetInt(sum), new(big.Float).SetInt64(int64(n))).Float64()
+	return mean
+}
+
+// integerMSE computes the mean squared error between two planes of integer
+// samples exactly, rounding only the final mean.
+func integerMSE[T integerSample](plane1, plane2 []T) float64 {
+	var sum wideSum
+	for start := 0; start < len(plane1); start += maxWideRun {
+		end := start + min(len(plane1)-start, maxWideRun)
+		sum.add(squaredDiffSum(plane1[start:end], plane2[start:end]))
+	}
+	return sum.mean(len(plane1))
+}
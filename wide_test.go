@@ -0,0 +1,109 @@
+package psnr
+
+import (
+	"context"
+	"math"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// referenceMSE computes the mean squared error with big.Float arithmetic,
+// exact up to the final rounding to float64.
+func referenceMSE[T integerSample](plane1, plane2 []T) float64 {
+	sum := new(big.Float).SetPrec(256)
+	for i := range plane1 {
+		d := new(big.Float).SetPrec(256).SetInt64(int64(plane1[i]) - int64(plane2[i]))
+		sum.Add(sum, d.Mul(d, d))
+	}
+	mean, _ := new(big.Float).SetPrec(53).Quo(sum, big.NewFloat(float64(len(plane1)))).Float64()
+	return mean
+}
+
+// randomPlanes returns two planes of n samples below 1<<depth, the second
+// either random or the bitwise complement of the first, which maximizes
+// the differences.
+func randomPlanes(rng *rand.Rand, n, depth int) ([]uint16, []uint16) {
+	plane1, plane2 := make([]uint16, n), make([]uint16, n)
+	maxValue := 1<<depth - 1
+	complement := rng.Intn(2) == 0
+	for i := range plane1 {
+		plane1[i] = uint16(rng.Intn(maxValue + 1))
+		if complement {
+			plane2[i] = uint16(maxValue) - plane1[i]
+		} else {
+			plane2[i] = uint16(rng.Intn(maxValue + 1))
+		}
+	}
+	return plane1, plane2
+}
+
+func TestIntegerMSEMatchesBigFloat(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for depth := 1; depth <= 16; depth++ {
+		for trial := 0; trial < 20; trial++ {
+			plane1, plane2 := randomPlanes(rng, 1+rng.Intn(4096), depth)
+			if got, want := integerMSE(plane1, plane2), referenceMSE(plane1, plane2); got != want {
+				t.Fatalf("%d-bit trial %d: expected MSE %v, got %v", depth, trial, want, got)
+			}
+			if depth > 8 {
+				continue
+			}
+			narrow1, narrow2 := make([]uint8, len(plane1)), make([]uint8, len(plane2))
+			for i := range plane1 {
+				narrow1[i], narrow2[i] = uint8(plane1[i]), uint8(plane2[i])
+			}
+			if got, want := integerMSE(narrow1, narrow2), referenceMSE(narrow1, narrow2); got != want {
+				t.Fatalf("%d-bit uint8 trial %d: expected MSE %v, got %v", depth, trial, want, got)
+			}
+		}
+	}
+}
+
+func TestSquaredDiffSumFullRange(t *testing.T) {
+	black, white := make([]uint16, 1000), make([]uint16, 1000)
+	for i := range white {
+		white[i] = 65535
+	}
+	// 65535² already overflows int32
+	if got, want := squaredDiffSum(black, white), uint64(1000*65535*65535); got != want {
+		t.Errorf("Expected %d, got %d", want, got)
+	}
+	if got := squaredDiffSum(white, black); got != uint64(1000*65535*65535) {
+		t.Errorf("Expected the sum to be symmetric, got %d", got)
+	}
+
+	result, err := ComparePlanar(context.Background(),
+		&PlanarImage[uint16]{Width: 100, Height: 10, Planes: [][]uint16{black}},
+		&PlanarImage[uint16]{Width: 100, Height: 10, Planes: [][]uint16{white}}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.MSE != 65535*65535 || result.PSNR != 0 {
+		t.Errorf("Expected MSE 65535² at 0 dB, got %+v", result)
+	}
+}
+
+func TestWideSumCarry(t *testing.T) {
+	var sum wideSum
+	for i := 0; i < 3; i++ {
+		sum.add(math.MaxUint64)
+	}
+	if sum.hi != 2 || sum.lo != math.MaxUint64-2 {
+		t.Fatalf("Expected a carry into the high word, got %+v", sum)
+	}
+
+	// 3 * (2^64 - 1) / 7, rounded once
+	total := new(big.Int).Mul(big.NewInt(3), new(big.Int).SetUint64(math.MaxUint64))
+	want, _ := new(big.Float).SetPrec(53).Quo(new(big.Float).SetInt(total), big.NewFloat(7)).Float64()
+	if got := sum.mean(7); got != want {
+		t.Errorf("Expected mean %v, got %v", want, got)
+	}
+
+	// Past 2^53 the sum itself would round when converted to float64
+	sum = wideSum{lo: 1<<53 + 1}
+	want, _ = new(big.Float).SetPrec(53).Quo(new(big.Float).SetUint64(1<<53+1), big.NewFloat(3)).Float64()
+	if got := sum.mean(3); got != want {
+		t.Errorf("Expected mean %v, got %v", want, got)
+	}
+}